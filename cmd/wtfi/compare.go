@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/kanywst/wtfi/internal/config"
+	"github.com/kanywst/wtfi/internal/history"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// compareNetwork is one network of the JSON form of "wtfi compare".
+type compareNetwork struct {
+	Name string `json:"name"`
+	Runs int    `json:"runs"`
+}
+
+// compareReport is the JSON form of "wtfi compare": the median of each
+// metric per network, in the order of Networks.
+type compareReport struct {
+	Networks []compareNetwork `json:"networks"`
+	Metrics  []historyTrend   `json:"metrics"`
+}
+
+// runCompare compares the typical values of every metric recorded in the
+// history at path on the Wi-Fi networks called names, or on the access
+// points with those BSSIDs when byBSSID is set.
+func runCompare(path string, names []string, byBSSID bool, output string) int {
+	if len(names) < 2 {
+		fmt.Fprintln(os.Stderr, "wtfi: usage: wtfi compare [--ssid | --bssid] <network> <network>...")
+		return 2
+	}
+	records, err := history.Load(path, math.MaxInt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		return 1
+	}
+
+	report := compareReport{Networks: make([]compareNetwork, len(names))}
+	index := map[string]int{}
+	for i, name := range names {
+		runs := history.OnNetwork(records, func(n ui.JSONNetwork) bool {
+			if byBSSID {
+				return strings.EqualFold(n.BSSID, name)
+			}
+			return n.SSID == name
+		})
+		if len(runs) == 0 {
+			fmt.Fprintf(os.Stderr, "wtfi: no runs recorded on %s; networks in the history: %s\n", name, strings.Join(knownNetworks(records, byBSSID), ", "))
+			return 1
+		}
+		report.Networks[i] = compareNetwork{Name: name, Runs: len(runs)}
+		for _, t := range history.Trends(runs) {
+			m, ok := index[t.Metric]
+			if !ok {
+				m = len(report.Metrics)
+				index[t.Metric] = m
+				report.Metrics = append(report.Metrics, historyTrend{Metric: t.Metric, Unit: t.Unit, Values: make([]*float64, len(names))})
+			}
+			if v := t.Median(); !math.IsNaN(v) {
+				report.Metrics[m].Values[i] = &v
+			}
+		}
+	}
+
+	if config.IsJSON(output) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+		return 0
+	}
+
+	headers := make([]string, len(names))
+	for i, n := range report.Networks {
+		headers[i] = fmt.Sprintf("%s (%d runs)", n.Name, n.Runs)
+	}
+	width := 16
+	for _, h := range headers {
+		width = max(width, len(h)+2)
+	}
+	fmt.Println("Typical (median) values from the history; the best is highlighted")
+	fmt.Println()
+	fmt.Printf("%-32s", "")
+	for _, h := range headers {
+		fmt.Printf("%-*s", width, h)
+	}
+	fmt.Println()
+	for _, m := range report.Metrics {
+		best := bestValue(m)
+		fmt.Printf("%-32s", m.Metric)
+		for i, v := range m.Values {
+			cell := fmt.Sprintf("%-*s", width, "-")
+			if v != nil {
+				cell = fmt.Sprintf("%-*s", width, trendValue(*v, m.Unit))
+			}
+			if i == best {
+				cell = color.New(color.FgGreen).Sprint(cell)
+			}
+			fmt.Print(cell)
+		}
+		fmt.Println()
+	}
+	return 0
+}
+
+// bestValue returns the index of the best of m's values, or -1 when fewer
+// than two networks measured it or the best is tied. Signal and throughput
+// are better higher, everything else lower.
+func bestValue(m historyTrend) int {
+	higher := m.Unit == "dBm" || m.Unit == "Mbps"
+	best, measured, tied := -1, 0, false
+	for i, v := range m.Values {
+		if v == nil {
+			continue
+		}
+		measured++
+		switch {
+		case best < 0 || (higher && *v > *m.Values[best]) || (!higher && *v < *m.Values[best]):
+			best, tied = i, false
+		case *v == *m.Values[best]:
+			tied = true
+		}
+	}
+	if measured < 2 || tied {
+		return -1
+	}
+	return best
+}
+
+// knownNetworks lists the SSIDs, or BSSIDs, recorded in records.
+func knownNetworks(records []ui.JSONRecord, byBSSID bool) []string {
+	var names []string
+	for _, rec := range records {
+		if rec.Network == nil {
+			continue
+		}
+		name := rec.Network.SSID
+		if byBSSID {
+			name = rec.Network.BSSID
+		}
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
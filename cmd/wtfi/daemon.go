@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/launchd"
+)
+
+// daemonInterval is the delay between runs of the background agent unless
+// --interval is given: often enough to catch an outage, rarely enough not
+// to load the network.
+const daemonInterval = 5 * time.Minute
+
+// daemonThrottle is the minimum number of seconds launchd waits before
+// restarting the agent.
+const daemonThrottle = 30
+
+// pathFlags take a file path, which is made absolute for the agent since
+// launchd starts it in /.
+var pathFlags = []string{"config", "history-file", "logfile", "baseline-file", "report", "undo-file"}
+
+// daemonArgs returns the command line of the background agent: wtfi in
+// watch mode with notifications, logging one JSON line per run to the
+// rotated logPath, followed by the flags given to "wtfi daemon install" so
+// they carry over.
+func daemonArgs(exe, logPath string, set []*flag.Flag) []string {
+	args := []string{exe, "-w", "--notify"}
+	interval, logfile := false, false
+	for _, f := range set {
+		value := f.Value.String()
+		if slices.Contains(pathFlags, f.Name) && value != "" {
+			if abs, err := filepath.Abs(value); err == nil {
+				value = abs
+			}
+		}
+		interval = interval || f.Name == "interval"
+		logfile = logfile || f.Name == "logfile"
+		args = append(args, "--"+f.Name+"="+value)
+	}
+	if !interval {
+		args = append(args, "--interval="+daemonInterval.String())
+	}
+	if !logfile {
+		args = append(args, "--logfile="+logPath)
+	}
+	return args
+}
+
+// installDaemon writes the launch agent and (re)loads it, so wtfi keeps
+// diagnosing in the background, records every run in the history and
+// notifies when a check degrades.
+func installDaemon() int {
+	if runtime.GOOS != "darwin" {
+		fmt.Fprintln(os.Stderr, "wtfi: daemon install needs macOS's launchd; run 'wtfi -w --notify' under your service manager instead")
+		return 2
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		return 1
+	}
+	var set []*flag.Flag
+	flag.Visit(func(f *flag.Flag) { set = append(set, f) })
+	logPath := launchd.DefaultLogPath()
+	agent := launchd.Agent{
+		Label:            launchd.Label,
+		Args:             daemonArgs(exe, logPath, set),
+		ErrorLogPath:     launchd.DefaultErrorLogPath(),
+		ThrottleInterval: daemonThrottle,
+	}
+	path := launchd.DefaultPath(launchd.Label)
+	for _, dir := range []string{filepath.Dir(path), filepath.Dir(logPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			return 1
+		}
+	}
+	// The arguments can carry webhook URLs with their secrets, so only the
+	// user may read the plist; WriteFile keeps an existing file's mode.
+	err = os.WriteFile(path, agent.Plist(), 0o600)
+	if err == nil {
+		err = os.Chmod(path, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		return 1
+	}
+
+	// Unload a previous install first, or bootstrap fails with "already
+	// loaded"; there being none is not an error.
+	_ = launchctl("bootout", guiDomain()+"/"+launchd.Label)
+	if err := launchctl("bootstrap", guiDomain(), path); err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: loading %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Installed %s\nwtfi now runs in the background; results go to the history and the log file (%s by default)\n", path, logPath)
+	fmt.Fprintln(os.Stderr, "Run 'wtfi daemon uninstall' to stop it.")
+	return 0
+}
+
+// uninstallDaemon unloads the launch agent and removes it.
+func uninstallDaemon() int {
+	path := launchd.DefaultPath(launchd.Label)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "wtfi: the daemon is not installed")
+		return 1
+	}
+	_ = launchctl("bootout", guiDomain()+"/"+launchd.Label)
+	if err := os.Remove(path); err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Removed %s\n", path)
+	return 0
+}
+
+// guiDomain is the launchd domain of the logged-in user's agents.
+func guiDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	slog.Debug("ran command", "cmd", "launchctl "+strings.Join(args, " "), "err", err, "output", string(out))
+	if err != nil && len(out) > 0 {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return err
+}
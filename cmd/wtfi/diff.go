@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/kanywst/wtfi/internal/baseline"
+	"github.com/kanywst/wtfi/internal/config"
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/redact"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// diffMarks label each kind of change in the text output.
+var diffMarks = map[baseline.ChangeKind]struct {
+	mark  string
+	color color.Attribute
+}{
+	baseline.Regressed: {"▲ worse  ", color.FgRed},
+	baseline.Changed:   {"~ changed", color.FgYellow},
+	baseline.Improved:  {"▼ better ", color.FgGreen},
+}
+
+// jsonChange is the machine-readable form of a baseline.Change.
+type jsonChange struct {
+	Check  string `json:"check"`
+	Kind   string `json:"kind"`
+	Change string `json:"change"`
+}
+
+// runDiff runs the checks without showing them and prints how the run
+// differs from base. It returns 1 when anything regressed, for scripts.
+func runDiff(ctx context.Context, steps []step, workers int, base *baseline.Baseline, maxRegression float64, output string, red *redact.Redactor) int {
+	start := time.Now()
+	if !config.IsJSON(output) {
+		fmt.Fprintf(os.Stderr, "Comparing %d checks with the baseline of %s...\n", len(steps), base.Timestamp())
+	}
+	// The baseline's SSIDs are masked too; redacting the discarded output
+	// teaches red the current one.
+	for _, ssid := range base.FactValues(diagnostic.FactSSID) {
+		red.AddSSID(ssid)
+	}
+	var discard ui.BufferSink
+	results := runSteps(ctx, steps, workers, nil, red, &discard)
+	changes := base.Diff(ui.NewJSONRecord(start, results), maxRegression)
+
+	code := 0
+	for _, c := range changes {
+		if c.Kind == baseline.Regressed {
+			code = 1
+		}
+	}
+
+	if config.IsJSON(output) {
+		out := make([]jsonChange, 0, len(changes))
+		for _, c := range changes {
+			out = append(out, jsonChange{Check: c.Check, Kind: c.Kind.String(), Change: red.Text(c.What)})
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+		return code
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No changes from the baseline.")
+		return code
+	}
+	for _, c := range changes {
+		m := diffMarks[c.Kind]
+		if _, err := color.New(m.color).Printf("%s  ", ui.Text(m.mark)); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+		fmt.Println(ui.Text(red.Text(c.String())))
+	}
+	return code
+}
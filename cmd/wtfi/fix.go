@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/redact"
+	"github.com/kanywst/wtfi/internal/ui"
+	"github.com/kanywst/wtfi/internal/undo"
+	"github.com/mattn/go-isatty"
+)
+
+// fixSession carries the settings of "wtfi fix".
+type fixSession struct {
+	// apply applies every fix without asking (--apply-fixes).
+	apply bool
+	// undoPath records how to revert the fixes that change settings.
+	undoPath string
+}
+
+// run diagnoses the network like a plain run, then offers the remedies for
+// what it found one at a time. Each remedy the user accepts is applied and
+// the checks it should cure run again to show whether it worked. The exit
+// code is 0 when every remedy was applied and worked.
+func (f fixSession) run(ctx context.Context, steps []step, workers int, opts ui.Options, red *redact.Redactor) int {
+	results := runText(ctx, steps, workers, opts, nil, red)
+	if ctx.Err() != nil {
+		return 1
+	}
+	remedies := diagnostic.PlanRemedies(ctx, results)
+	if len(remedies) == 0 {
+		fmt.Println("Nothing wtfi can fix automatically.")
+		return 0
+	}
+	if !f.apply && !isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintln(os.Stderr, "wtfi: not a terminal; listing the fixes without applying them (use --apply-fixes to apply them)")
+	}
+	unresolved := 0
+	for i, r := range remedies {
+		shown := r
+		shown.Why = red.Text(r.Why)
+		fmt.Println()
+		ui.PrintRemedy(i+1, len(remedies), shown)
+		if !f.apply && !confirm("Apply this fix?") {
+			unresolved++
+			continue
+		}
+		if err := f.applyRemedy(ctx, r); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			unresolved++
+			continue
+		}
+		if !verifyRemedy(ctx, r, opts, red) {
+			unresolved++
+		}
+		if ctx.Err() != nil {
+			return 1
+		}
+	}
+	if unresolved > 0 {
+		return 1
+	}
+	return 0
+}
+
+// applyRemedy runs the commands of r, records how to revert them and waits
+// for the network to settle.
+func (f fixSession) applyRemedy(ctx context.Context, r diagnostic.Remedy) error {
+	if err := runCommands(ctx, r.Commands, r.Privileged); err != nil {
+		return err
+	}
+	if r.Undo != nil {
+		e := undo.Entry{At: time.Now(), Title: r.Title, Commands: r.Undo, Privileged: r.Privileged}
+		if err := undo.Push(f.undoPath, e); err != nil {
+			slog.Error("could not record how to undo the fix", "err", err)
+		} else {
+			fmt.Println("   Run 'wtfi fix --undo' to revert this change.")
+		}
+	}
+	select {
+	case <-time.After(r.Settle):
+	case <-ctx.Done():
+	}
+	return ctx.Err()
+}
+
+// undo reverts the recorded fixes, latest first. An entry that fails to
+// revert stays recorded, with those before it.
+func (f fixSession) undo(ctx context.Context) int {
+	entries, err := undo.Load(f.undoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing to undo.")
+		return 0
+	}
+	for len(entries) > 0 {
+		e := entries[len(entries)-1]
+		if err := runCommands(ctx, e.Commands, e.Privileged); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: could not revert %q: %v\n", e.Title, err)
+			return 1
+		}
+		fmt.Printf("Reverted: %s\n", e.Title)
+		entries = entries[:len(entries)-1]
+		if err := undo.Save(f.undoPath, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// runCommands runs cmds in order on the terminal, through sudo when they
+// need root.
+func runCommands(ctx context.Context, cmds [][]string, privileged bool) error {
+	for _, args := range cmds {
+		if privileged && os.Geteuid() != 0 {
+			args = append([]string{"sudo"}, args...)
+		}
+		slog.Debug("running command", "cmd", strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// verifyRemedy re-runs the checks r should have cured, prints them and
+// reports whether all of them pass.
+func verifyRemedy(ctx context.Context, r diagnostic.Remedy, opts ui.Options, red *redact.Redactor) bool {
+	diagnostic.ResetCache()
+	fixed := true
+	for _, name := range r.Verify {
+		c, ok := diagnostic.LookupCheck(name)
+		if !ok {
+			continue
+		}
+		res := c.Checker().Run(ctx, diagnostic.Options{Verbose: opts.Verbose})
+		ui.PrintResult(red.Result(res), opts)
+		fixed = fixed && (res.Status == diagnostic.StatusOk || res.Status == diagnostic.StatusSkipped)
+	}
+	ui.PrintRemedyOutcome(fixed)
+	return fixed
+}
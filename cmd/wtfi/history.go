@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/config"
+	"github.com/kanywst/wtfi/internal/history"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// historyTrend is the JSON form of one trend of "wtfi history".
+type historyTrend struct {
+	Metric string     `json:"metric"`
+	Unit   string     `json:"unit"`
+	Values []*float64 `json:"values"`
+}
+
+// showHistory prints the trends of the last runs recorded at path: a
+// sparkline with min, average, max and latest value for people, or the raw
+// series for integrations.
+func showHistory(path string, runs int, output string) error {
+	records, err := history.Load(path, runs)
+	if err != nil {
+		return err
+	}
+	trends := history.Trends(records)
+
+	if config.IsJSON(output) {
+		out := make([]historyTrend, 0, len(trends))
+		for _, t := range trends {
+			ht := historyTrend{Metric: t.Metric, Unit: t.Unit, Values: make([]*float64, len(t.Values))}
+			for i, v := range t.Values {
+				if !math.IsNaN(v) {
+					ht.Values[i] = &v
+				}
+			}
+			out = append(out, ht)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No runs recorded yet in %s\n", path)
+		return nil
+	}
+	first, last := records[0].Timestamp, records[len(records)-1].Timestamp
+	fmt.Printf(ui.Text("Last %d run(s), %s → %s\n\n"), len(records), first.Local().Format(time.DateTime), last.Local().Format(time.DateTime))
+	for _, t := range trends {
+		lo, avg, hi, latest, n := t.Stats()
+		fmt.Printf("%-32s %-*s  min %s  avg %s  max %s  last %s  (%d runs)\n",
+			t.Metric, runs, ui.Sparkline(t.Measured()),
+			trendValue(lo, t.Unit), trendValue(avg, t.Unit), trendValue(hi, t.Unit), trendValue(latest, t.Unit), n)
+	}
+
+	if profiles := history.Profiles(records); len(profiles) > 0 {
+		fmt.Println("\nNetworks")
+		for _, p := range profiles {
+			fmt.Printf("  %-32s %3d run(s)  health %3.0f  %d with problems  last %s\n",
+				p.Name, p.Runs, p.Score, p.Problems, p.Last.Local().Format(time.DateTime))
+		}
+	}
+	return nil
+}
+
+// recentSamples starts the sparklines next to results from the last runs
+// recorded at path, so the first screen already shows a trend.
+func recentSamples(path string, noHistory bool) *ui.Samples {
+	samples := &ui.Samples{}
+	if noHistory || path == "" {
+		return samples
+	}
+	records, err := history.Load(path, ui.SparkSamples)
+	if err != nil {
+		return samples
+	}
+	for _, rec := range records {
+		samples.AddRecord(rec)
+	}
+	return samples
+}
+
+// trendValue formats v with its unit.
+func trendValue(v float64, unit string) string {
+	if unit == "ms" {
+		return time.Duration(v * float64(time.Millisecond)).Round(100 * time.Microsecond).String()
+	}
+	return fmt.Sprintf("%.1f %s", v, unit)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/kanywst/wtfi/internal/redact"
+)
+
+// setupLogging sends log records to stderr, keeping them out of the JSON
+// and Markdown output. Warnings and errors are logged by default, progress
+// with -v and, with -vv or --debug, every external command and its raw
+// output. With --redact, red masks the records too.
+func setupLogging(verbose, debug bool, red *redact.Redactor) {
+	level := slog.LevelWarn
+	switch {
+	case debug:
+		level = slog.LevelDebug
+	case verbose:
+		level = slog.LevelInfo
+	}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	if red != nil {
+		h = redactHandler{h, red}
+	}
+	slog.SetDefault(slog.New(h))
+}
+
+// redactHandler masks the message and the attribute values of every record
+// before passing it on, since command output names networks and addresses.
+type redactHandler struct {
+	slog.Handler
+	red *redact.Redactor
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, h.red.Text(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, masked)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.attr(a)
+	}
+	return redactHandler{h.Handler.WithAttrs(masked), h.red}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{h.Handler.WithGroup(name), h.red}
+}
+
+// attr masks a's value; strings, errors and anything else printed as text
+// are masked, numbers, durations and times pass through.
+func (h redactHandler) attr(a slog.Attr) slog.Attr {
+	switch v := a.Value.Resolve(); v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.red.Text(v.String()))
+	case slog.KindAny:
+		return slog.String(a.Key, h.red.Text(fmt.Sprint(v.Any())))
+	case slog.KindGroup:
+		group := v.Group()
+		masked := make([]any, len(group))
+		for i, g := range group {
+			masked[i] = h.attr(g)
+		}
+		return slog.Group(a.Key, masked...)
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
@@ -2,57 +2,685 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/kanywst/wtfi/internal/baseline"
+	"github.com/kanywst/wtfi/internal/config"
+	"github.com/kanywst/wtfi/internal/dashboard"
 	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/history"
+	"github.com/kanywst/wtfi/internal/logfile"
+	"github.com/kanywst/wtfi/internal/otlp"
+	"github.com/kanywst/wtfi/internal/redact"
 	"github.com/kanywst/wtfi/internal/ui"
+	"github.com/kanywst/wtfi/internal/undo"
+	"github.com/kanywst/wtfi/internal/watch"
 )
 
 // Version of the application.
 const Version = "1.0.0"
 
+// defaultRefreshInterval is the delay between runs in watch and dashboard
+// modes unless --interval is given.
+const defaultRefreshInterval = 2 * time.Second
+
+// defaultWorkers bounds how many checks --parallel runs at once, so a run
+// does not open dozens of sockets and subprocesses in the same instant.
+const defaultWorkers = 4
+
 func main() {
+	// An optional subcommand (e.g. "dashboard", "baseline save") precedes the flags.
+	var words []string
+	args := os.Args[1:]
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		words, args = append(words, args[0]), args[1:]
+	}
+	command := strings.Join(words, " ")
+
+	var watching bool
 	verbose := flag.Bool("v", false, "Enable verbose output with protocol details")
-	watch := flag.Bool("w", false, "Enable watch mode (real-time updates)")
+	veryVerbose := flag.Bool("vv", false, "Like -v, and log every external command with its raw output to stderr")
+	debug := flag.Bool("debug", false, "Log every external command with its raw output to stderr")
+	flag.BoolVar(&watching, "w", false, "Enable watch mode (real-time updates)")
+	flag.BoolVar(&watching, "watch", false, "Alias for -w")
+	refreshInterval := flag.Duration("interval", defaultRefreshInterval, "Delay between refreshes in watch and dashboard modes")
+	deadline := flag.Duration("deadline", 0, "Abort each run after this long, reporting unfinished checks as skipped (0 means no limit)")
+	redactOn := flag.Bool("redact", false, "Mask SSIDs, MAC and IP addresses in the output for sharing")
+	noColor := flag.Bool("no-color", false, "Disable colors (also when NO_COLOR is set or the output is not a terminal)")
+	asciiOut := flag.Bool("ascii", false, "Print plain ASCII instead of emoji and box-drawing characters (also when the output is not a terminal or the locale is not UTF-8)")
+	checkEnv := flag.Bool("check-env", false, "Report missing tools, the OS and privileges before running the checks")
+	explain := flag.Bool("explain", false, "Describe what each check measured and how it was graded")
+	commands := flag.Bool("commands", false, "Show copy-pasteable shell commands for suggested fixes")
+	applyFixes := flag.Bool("apply-fixes", false, "With 'wtfi fix', apply every fix without asking")
+	undoFixes := flag.Bool("undo", false, "With 'wtfi fix', revert the settings changed by earlier fixes")
+	undoPath := flag.String("undo-file", undo.DefaultPath(), "Where 'wtfi fix' records how to revert its changes")
+	openPortal := flag.Bool("open-portal", false, "Open the captive portal login page in the browser without asking")
+	notifyOn := flag.Bool("notify", false, "In watch mode, show a notification when the overall status degrades from OK and when it recovers")
+	bell := flag.Bool("bell", false, "With --notify, also ring the terminal bell")
+	parallel := flag.Bool("parallel", false, "Run checks concurrently, showing each result as soon as the ones above it are done")
+	workers := flag.Int("workers", defaultWorkers, "With --parallel, the most checks run at once")
+	speed := flag.Bool("speed", false, "Also measure download/upload throughput and bufferbloat (transfers data)")
+	// Flags mirroring config file keys are applied through config.Config.Set.
+	flag.String("upload-size", "2MB", "Payload size for the upload measurement")
+	flag.String("iperf-server", "", "Measure throughput with iperf3 against this server (host or host:port) instead of HTTP")
+	flag.Bool("json", false, "Emit results as JSON (one line per refresh in watch mode)")
+	flag.String("webhook", "", "In watch mode, post a JSON alert to these comma-separated URLs when a check degrades or recovers")
+	flag.String("slack-webhook", "", "In watch mode, post alerts to these comma-separated Slack incoming webhook URLs")
+	flag.String("otlp-endpoint", "", "Export each run as an OpenTelemetry trace and metrics to this OTLP/HTTP collector (e.g. http://localhost:4318)")
+	flag.String("format", "", "Output format: text, json, markdown (a table for pasting into issues and chats) or ndjson (one line per check as it finishes)")
+	flag.Duration("timeout", diagnostic.DefaultConfig().Timeout, "Timeout for individual network operations")
+	flag.Int("ping-size", 0, "ICMP payload size in bytes for latency and loss pings (0 uses ping's default of 56)")
+	flag.Duration("ping-interval", 0, "Interval between the loss and jitter pings (0 keeps 200ms; under 100ms needs root)")
+	flag.Int("samples", 0, "Ping the WAN target this many times (10 per second) and report latency percentiles")
+	flag.Int("burst", diagnostic.DefaultConfig().PingBurst, "Pings sent to the gateway and WAN target to measure loss and jitter")
+	flag.String("only", "", "Comma-separated checks to run instead of the default set (overrides the config's checks)")
+	flag.String("skip", "", "Comma-separated checks to leave out")
+	flag.String("trace-mode", diagnostic.DefaultConfig().TraceMode, "Traceroute probe protocol for -v: icmp, udp or tcp (port 443)")
+	flag.String("interface", "", "Discover the interface and gateway through this interface's default route (e.g. en7); traffic beyond the gateway still follows the routing table")
+	flag.String("tls-host", diagnostic.DefaultConfig().TLSHost, "Host used for the TLS handshake check")
+	flag.Float64("baseline-threshold", config.Default().BaselineRegression, "Percent a metric may regress from the baseline before warning")
+	compare := flag.Bool("baseline", false, "Compare each check against the saved baseline")
+	baselinePath := flag.String("baseline-file", baseline.DefaultPath(), "Baseline file written by 'wtfi baseline save'")
+	remote := flag.String("remote", "", "Run command-based checks on a remote Mac over ssh (user@host)")
+	logPath := flag.String("logfile", "", "Append JSON-lines results to this file")
+	historyPath := flag.String("history-file", history.DefaultPath(), "Where every run is recorded for 'wtfi history'")
+	noHistory := flag.Bool("no-history", false, "Do not record this run in the history")
+	runs := flag.Int("runs", 20, "Number of recent runs 'wtfi history' shows")
+	bySSID := flag.Bool("ssid", false, "With 'wtfi compare', compare Wi-Fi networks by SSID (the default)")
+	byBSSID := flag.Bool("bssid", false, "With 'wtfi compare', compare access points by BSSID, e.g. the 2.4 and 5 GHz radios of one network")
+	reportPath := flag.String("report", "", "Write a self-contained HTML report of the run to this file (implies -v)")
+	maxSize := flag.String("max-size", "10MB", "Rotate the log file once it reaches this size")
+	configPath := flag.String("config", "", "Path to a YAML config file (default ~/.config/wtfi/config.yaml or ~/.wtfi.yaml if present)")
 	version := flag.Bool("version", false, "Print version and exit")
-	flag.Parse()
+	if err := flag.CommandLine.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	if *veryVerbose {
+		*verbose = true
+	}
+	var red *redact.Redactor
+	if *redactOn {
+		red = &redact.Redactor{}
+	}
+	setupLogging(*verbose, *veryVerbose || *debug, red)
 
 	if *version {
 		fmt.Printf("wtfi version %s\n", Version)
 		os.Exit(0)
 	}
+	if *noColor {
+		disableColor()
+	}
+	ui.ASCII = wantASCII(*asciiOut)
 
-	for {
-		if *watch {
-			ui.ClearScreen()
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "wtfi: --workers must be at least 1, got %d\n", *workers)
+		os.Exit(2)
+	}
+	if *runs < 1 {
+		fmt.Fprintf(os.Stderr, "wtfi: --runs must be at least 1, got %d\n", *runs)
+		os.Exit(2)
+	}
+	inFlight := 1
+	if *parallel {
+		inFlight = *workers
+	}
+
+	if *deadline < 0 {
+		fmt.Fprintf(os.Stderr, "wtfi: --deadline must not be negative, got %v\n", *deadline)
+		os.Exit(2)
+	}
+
+	if *refreshInterval < time.Second {
+		fmt.Fprintf(os.Stderr, "wtfi: --interval must be at least 1s, got %v\n", *refreshInterval)
+		os.Exit(2)
+	}
+
+	// The report is meant for someone else to read, so it carries the
+	// verbose details such as the traceroute path.
+	if *reportPath != "" {
+		*verbose = true
+	}
+	// An audit lists the listening ports and needs the evil twin check, the
+	// per-process sample behind "wtfi top" is verbose-only too, and so is
+	// the traceroute the TUI draws.
+	if command == "audit" || command == "top" || command == "tui" {
+		*verbose = true
+	}
+
+	// Connect first: whether a short ping interval is allowed depends on
+	// whether the remote user is root.
+	if *remote != "" {
+		if err := diagnostic.UseRemote(*remote); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
 		}
+		slog.Info("running commands over ssh", "target", *remote)
+	}
 
-		ui.PrintHeader()
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		os.Exit(2)
+	}
+	diagnostic.SetConfig(cfg.Diagnostic)
 
-		// Refactor: Use closures only when necessary.
-		steps := []func() diagnostic.Result{
-			func() diagnostic.Result { return diagnostic.CheckL2WiFi(*verbose) },
-			diagnostic.CheckRoutingTable,
-			func() diagnostic.Result { return diagnostic.CheckL3Gateway(*verbose) },
-			diagnostic.CheckL3WAN,
-			diagnostic.CheckDNSBenchmark,
-			func() diagnostic.Result { return diagnostic.CheckPrivateRelay(*verbose) },
-			func() diagnostic.Result { return diagnostic.FastTraceroute(*verbose) },
-			func() diagnostic.Result { return diagnostic.CheckCaptivePortal(*verbose) },
+	// "wtfi check <name>" runs exactly that check, whatever the config enables,
+	// "wtfi speed" runs only the throughput and bufferbloat tests, "wtfi
+	// audit" only the security checks and "wtfi top" only the per-process
+	// throughput sample.
+	single, isSingle := strings.CutPrefix(command, "check ")
+	enabled := cfg.Checks
+	skip := cfg.Skip
+	switch {
+	case isSingle:
+		enabled, skip = []string{single}, nil
+	case command == "speed":
+		enabled, skip = speedChecks, nil
+	case command == "audit":
+		enabled, skip = auditChecks, nil
+	case command == "top":
+		enabled, skip = []string{"top-talkers"}, nil
+	}
+	steps, err := buildSteps(*verbose, enabled, skip, (*speed || command == "speed") && !isSingle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+		os.Exit(2)
+	}
+
+	// The guided fix asks before each step and changes this machine.
+	if command == "fix" && (*remote != "" || cfg.Output != config.OutputText) {
+		fmt.Fprintln(os.Stderr, "wtfi: fix is interactive and local; it cannot be combined with --remote, --json or --format")
+		os.Exit(2)
+	}
+	if command != "fix" && (*applyFixes || *undoFixes) {
+		fmt.Fprintln(os.Stderr, "wtfi: --apply-fixes and --undo only apply to 'wtfi fix'")
+		os.Exit(2)
+	}
+
+	var base *baseline.Baseline
+	if command == "diff" {
+		if base, err = baseline.Load(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v (run 'wtfi baseline save' first)\n", err)
+			os.Exit(1)
+		}
+	} else if *compare && command != "baseline save" {
+		if base, err = baseline.Load(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v (run 'wtfi baseline save' first)\n", err)
+			os.Exit(1)
 		}
+		steps = compareToBaseline(steps, base, cfg.BaselineRegression)
+	}
+
+	var exporter *otlp.Exporter
+	var timer *stepTimer
+	if cfg.OTLPEndpoint != "" {
+		exporter = &otlp.Exporter{Endpoint: cfg.OTLPEndpoint, Headers: cfg.OTLPHeaders, Version: Version}
+		timer = &stepTimer{}
+		steps = timer.wrap(steps)
+	}
 
-		for _, step := range steps {
-			ui.PrintResult(step(), *verbose)
+	var sess *session
+	if watching {
+		sess = &session{}
+		if *notifyOn {
+			sess.alerts = newAlerter(*bell)
 		}
+	}
+	if sess != nil {
+		sess.webhooks = newWebhookAlerter(cfg.AlertWebhooks, cfg.SlackWebhooks, cfg.AlertHold, cfg.AlertCooldown, red)
+	}
+	opts := ui.Options{Verbose: *verbose, FixCommands: *commands, Explain: *explain, CheckEnv: *checkEnv,
+		LatencyGood: cfg.LatencyGood, LatencyPoor: cfg.LatencyPoor}
+	if watching || *verbose {
+		opts.Samples = recentSamples(*historyPath, *noHistory)
+	}
+	if *checkEnv {
+		opts.Environment = diagnostic.ProbeEnvironment()
+		if config.IsJSON(cfg.Output) {
+			for _, w := range opts.Environment {
+				fmt.Fprintf(os.Stderr, "wtfi: %s\n", w)
+			}
+		}
+	}
+	// Ctrl-C cancels the checks in flight; a second one exits at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	run := func() []diagnostic.Result {
+		diagnostic.ResetCache()
+		runCtx, cancel := ctx, context.CancelFunc(func() {})
+		if *deadline > 0 {
+			runCtx, cancel = context.WithTimeout(ctx, *deadline)
+		}
+		defer cancel()
+		switch cfg.Output {
+		case config.OutputJSON:
+			return runJSON(runCtx, steps, inFlight, sess, red)
+		case config.OutputMarkdown:
+			return runMarkdown(runCtx, steps, inFlight, sess, red)
+		case config.OutputNDJSON:
+			return runNDJSON(runCtx, steps, inFlight, sess, red)
+		}
+		return runText(runCtx, steps, inFlight, opts, sess, red)
+	}
+
+	if isSingle {
+		os.Exit(runSingle(ctx, steps[0], cfg.Output, opts, red))
+	}
+
+	// "wtfi compare A B" and "wtfi compare --ssid A B" both name the
+	// networks, before or after the flags.
+	if len(words) > 0 && words[0] == "compare" {
+		if *bySSID && *byBSSID {
+			fmt.Fprintln(os.Stderr, "wtfi: --ssid and --bssid are mutually exclusive")
+			os.Exit(2)
+		}
+		os.Exit(runCompare(*historyPath, append(words[1:], flag.Args()...), *byBSSID, cfg.Output))
+	}
+
+	switch command {
+	case "", "speed", "audit", "top":
+	case "fix":
+		f := fixSession{apply: *applyFixes, undoPath: *undoPath}
+		if *undoFixes {
+			os.Exit(f.undo(ctx))
+		}
+		os.Exit(f.run(ctx, steps, inFlight, opts, red))
+	case "describe-checks":
+		describeChecks(cfg.Output)
+		return
+	case "diff":
+		os.Exit(runDiff(ctx, steps, inFlight, base, cfg.BaselineRegression, cfg.Output, red))
+	case "history":
+		if err := showHistory(*historyPath, *runs, cfg.Output); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "check":
+		fmt.Fprintf(os.Stderr, "wtfi: usage: wtfi check <name>, where name is one of: %s\n", strings.Join(checkNames(), ", "))
+		os.Exit(2)
+	case "baseline save":
+		start := time.Now()
+		if err := baseline.Save(*baselinePath, ui.NewJSONRecord(start, run())); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline saved to %s\n", *baselinePath)
+		return
+	case "daemon install":
+		os.Exit(installDaemon())
+	case "daemon uninstall":
+		os.Exit(uninstallDaemon())
+	case "dashboard":
+		if err := dashboard.Run(stepFuncs(steps), *refreshInterval); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "tui":
+		if err := dashboard.RunTUI(stepFuncs(steps), *refreshInterval); err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "wtfi: unknown command %q\n", command)
+		os.Exit(2)
+	}
 
-		ui.PrintFooter()
+	var logWriter *logfile.Writer
+	if *logPath != "" {
+		limit, err := logfile.ParseSize(*maxSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: --max-size: %v\n", err)
+			os.Exit(1)
+		}
+		logWriter, err = logfile.New(*logPath, limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := logWriter.Close(); err != nil {
+				slog.Error("could not write the log file", "err", err)
+			}
+		}()
+	}
 
-		if !*watch {
+	portal := portalOpener{always: *openPortal, ask: !watching && cfg.Output == config.OutputText}
+	sleeper := watch.NewSleepDetector(*refreshInterval)
+	for {
+		start := time.Now()
+		network := diagnostic.CurrentNetwork(ctx)
+		results := run()
+		portal.observe(results)
+		if sess != nil && sess.alerts != nil && ctx.Err() == nil {
+			sess.alerts.observeRun(time.Now(), results)
+		}
+		if base != nil && cfg.Output == config.OutputText {
+			missing := base.Missing(results)
+			for i, name := range missing {
+				missing[i] = red.Text(name)
+			}
+			ui.PrintBaselineMissing(missing)
+		}
+
+		if logWriter != nil {
+			if err := ui.WriteNDJSON(logWriter, ui.NewJSONRecord(start, results)); err != nil {
+				slog.Error("could not write the log file", "err", err)
+			}
+		}
+		if !*noHistory && *historyPath != "" && ctx.Err() == nil {
+			rec := ui.NewJSONRecord(start, results)
+			rec.Network = ui.NewJSONNetwork(network, results)
+			if err := history.Append(*historyPath, rec); err != nil {
+				slog.Error("could not record the run in the history", "err", err)
+			}
+		}
+		if exporter != nil {
+			exportRun(ctx, exporter, timer, start, results, red)
+		}
+		if *reportPath != "" {
+			if err := writeReport(*reportPath, start, results, red); err != nil {
+				fmt.Fprintf(os.Stderr, "wtfi: %v\n", err)
+			}
+		}
+
+		if !watching || ctx.Err() != nil {
+			break
+		}
+		// Only the pause is measured, so a slow run is not taken for sleep.
+		// A timer never fires a catch-up burst after waking, but what was
+		// discovered before the machine slept is suspect.
+		// A change of network cuts the pause short, so the new network is
+		// diagnosed, and recorded in the history, as soon as it is joined.
+		sleeper.Tick()
+		moved, changed := waitForNetworkChange(ctx, *refreshInterval, network)
+		if ctx.Err() != nil {
 			break
 		}
-		time.Sleep(2 * time.Second)
+		sess.wokeAfter, sess.movedFrom, sess.movedTo = 0, "", ""
+		if changed {
+			sess.moved(network, moved)
+		}
+		if gap, slept := sleeper.Tick(); slept {
+			sess.resume(gap)
+		}
+	}
+	if sess != nil && sess.webhooks != nil {
+		sess.webhooks.wait()
+	}
+}
+
+// step is one named entry of the diagnostic pipeline.
+type step struct {
+	name    string
+	explain string
+	run     func(context.Context) diagnostic.Result
+	// exclusive steps never overlap others, e.g. because they saturate the
+	// link and would distort every latency measured alongside them.
+	exclusive bool
+}
+
+// speedChecks are the opt-in checks enabled by --speed and run by "wtfi speed".
+var speedChecks = []string{"speed", "bufferbloat"}
+
+// auditChecks are the security checks run by "wtfi audit", including the
+// opt-in audit of this machine's listening ports.
+var auditChecks = []string{"wifi-security", "evil-twin", "exposure", "gateway-security", "dns-hijack", "tls-intercept"}
+
+// buildSteps returns the registered checks in display order, limited to
+// enabled when it is non-empty and without those in skip. Opt-in checks
+// such as the throughput and bufferbloat tests only run by default when speed
+// is set, since they transfer data.
+func buildSteps(verbose bool, enabled, skip []string, speed bool) ([]step, error) {
+	var optIn []string
+	if speed {
+		optIn = speedChecks
+	}
+	checks, err := diagnostic.SelectChecks(enabled, skip, optIn)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := diagnostic.Options{Verbose: verbose}
+	steps := make([]step, len(checks))
+	for i, c := range checks {
+		checker := c.Checker()
+		run := func(ctx context.Context) diagnostic.Result { return checker.Run(ctx, opts) }
+		steps[i] = step{c.Name, c.Explain, run, slices.Contains(speedChecks, c.Name)}
+	}
+	return steps, nil
+}
+
+// checkNames lists the registered check names in display order.
+func checkNames() []string {
+	var names []string
+	for _, c := range diagnostic.Checks() {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// describeChecks prints the check catalog: JSON for integrations, or the
+// names and methodology for people.
+func describeChecks(output string) {
+	if config.IsJSON(output) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(ui.DescribeChecks(diagnostic.Checks())); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+		return
+	}
+	for _, c := range diagnostic.Checks() {
+		fmt.Printf("%-18s %s\n", c.Name, c.Explain)
+	}
+}
+
+// stepFuncs strips the names from steps. The dashboard handles Ctrl-C
+// itself, so the steps run without cancellation.
+func stepFuncs(steps []step) []func() diagnostic.Result {
+	funcs := make([]func() diagnostic.Result, len(steps))
+	for i, s := range steps {
+		funcs[i] = func() diagnostic.Result { return s.run(context.Background()) }
+	}
+	return funcs
+}
+
+// compareToBaseline wraps each step so its result is annotated with the
+// deltas from base.
+func compareToBaseline(steps []step, base *baseline.Baseline, maxRegression float64) []step {
+	wrapped := make([]step, len(steps))
+	for i, s := range steps {
+		wrapped[i] = step{s.name, s.explain, func(ctx context.Context) diagnostic.Result {
+			r := s.run(ctx)
+			base.Annotate(&r, maxRegression)
+			return r
+		}, s.exclusive}
+	}
+	return wrapped
+}
+
+// loadConfig resolves the configuration: defaults, then the config file,
+// then any flags given explicitly on the command line.
+func loadConfig(path string) (*config.Config, error) {
+	cfg := config.Default()
+	if path == "" {
+		for _, def := range config.DefaultPaths() {
+			if _, err := os.Stat(def); err == nil {
+				path = def
+				break
+			}
+		}
+	}
+	if path != "" {
+		var err error
+		if cfg, err = config.LoadConfig(path); err != nil {
+			return nil, err
+		}
+		slog.Info("loaded config", "path", path)
+	}
+
+	var errSet error
+	flag.Visit(func(f *flag.Flag) {
+		if err := cfg.Set(f.Name, f.Value.String()); err != nil && errSet == nil {
+			errSet = err
+		}
+	})
+	if errSet != nil {
+		return nil, errSet
+	}
+	return cfg, cfg.Validate()
+}
+
+// runText renders each step to the terminal as soon as it and every step
+// before it have completed. sess is nil outside watch mode and red is nil
+// unless --redact is set; the returned results are never redacted.
+func runText(ctx context.Context, steps []step, workers int, opts ui.Options, sess *session, red *redact.Redactor) []diagnostic.Result {
+	if sess != nil {
+		ui.ClearScreen()
+	}
+
+	ui.PrintHeader()
+	if opts.CheckEnv {
+		ui.PrintEnvironment(opts.Environment)
+	}
+	explain := make([]string, len(steps))
+	for i, s := range steps {
+		explain[i] = s.explain
+	}
+	sink := &ui.TextSink{Options: opts, Explain: explain}
+	if sess != nil {
+		sink.Previous = sess.shown
+	}
+	results := runSteps(ctx, steps, workers, sess, red, sink)
+	ui.PrintFooter()
+	ui.PrintHealth(diagnostic.AssessHealth(results))
+	ui.PrintCauses(diagnostic.RootCauses(results))
+	if sess != nil {
+		sess.shown = sink.Shown
+	}
+	if sess != nil {
+		ui.PrintWake(sess.wokeAfter)
+		ui.PrintNetworkChange(red.Text(sess.movedFrom), red.Text(sess.movedTo))
+		events := sess.roams.History()
+		if red != nil {
+			for i := range events {
+				events[i].From, events[i].To = redact.MAC(events[i].From), redact.MAC(events[i].To)
+			}
+		}
+		ui.PrintRoamEvents(events)
+	}
+	return results
+}
+
+// runSingle runs one step for "wtfi check", printing only its result, and
+// returns the process exit code for its status.
+func runSingle(ctx context.Context, s step, output string, opts ui.Options, red *redact.Redactor) int {
+	start := time.Now()
+	r := s.run(ctx)
+	switch output {
+	case config.OutputJSON:
+		if err := ui.WriteNDJSON(os.Stdout, ui.NewJSONRecord(start, []diagnostic.Result{red.Result(r)})); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	case config.OutputMarkdown:
+		if err := ui.WriteMarkdown(os.Stdout, ui.NewJSONRecord(start, []diagnostic.Result{red.Result(r)})); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	case config.OutputNDJSON:
+		(&ui.NDJSONSink{W: os.Stdout}).Result(red.Result(r))
+		if err := ui.WriteRunEvent(os.Stdout, ui.NewJSONRecord(start, []diagnostic.Result{red.Result(r)})); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	default:
+		ui.PrintResult(red.Result(r), opts)
+		if opts.Explain {
+			ui.PrintExplanation(s.explain)
+		}
+	}
+	return exitCode(r.Status)
+}
+
+// exitCode maps a check status to the exit code of "wtfi check".
+func exitCode(s diagnostic.Status) int {
+	switch s {
+	case diagnostic.StatusWarning:
+		return 1
+	case diagnostic.StatusError:
+		return 2
+	case diagnostic.StatusSkipped:
+		return 3
+	}
+	return 0
+}
+
+// runJSON emits one NDJSON line for the whole run, without any terminal styling.
+func runJSON(ctx context.Context, steps []step, workers int, sess *session, red *redact.Redactor) []diagnostic.Result {
+	start := time.Now()
+	var shown ui.BufferSink
+	results := runSteps(ctx, steps, workers, sess, red, &shown)
+	if err := ui.WriteNDJSON(os.Stdout, ui.NewJSONRecord(start, shown.Results)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	return results
+}
+
+// runNDJSON streams one NDJSON line per step the moment it finishes, in
+// whatever order that is, then a line summing up the run, so wrappers can
+// render progressively without waiting for the slowest check.
+func runNDJSON(ctx context.Context, steps []step, workers int, sess *session, red *redact.Redactor) []diagnostic.Result {
+	start := time.Now()
+	results := runSteps(ctx, steps, workers, sess, red, &ui.NDJSONSink{W: os.Stdout})
+	shown := make([]diagnostic.Result, len(results))
+	for i, r := range results {
+		shown[i] = red.Result(r)
+	}
+	if err := ui.WriteRunEvent(os.Stdout, ui.NewJSONRecord(start, shown)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	return results
+}
+
+// runMarkdown prints the whole run as one Markdown table once every step is
+// done, since a table cannot be streamed row by row into a paste buffer.
+func runMarkdown(ctx context.Context, steps []step, workers int, sess *session, red *redact.Redactor) []diagnostic.Result {
+	start := time.Now()
+	var shown ui.BufferSink
+	results := runSteps(ctx, steps, workers, sess, red, &shown)
+	if err := ui.WriteMarkdown(os.Stdout, ui.NewJSONRecord(start, shown.Results)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	return results
+}
+
+// writeReport replaces the HTML report at path with the results of the run
+// that started at start, redacted by red.
+func writeReport(path string, start time.Time, results []diagnostic.Result, red *redact.Redactor) error {
+	redacted := make([]diagnostic.Result, len(results))
+	for i, r := range results {
+		redacted[i] = red.Result(r)
+	}
+	var buf bytes.Buffer
+	if err := ui.WriteHTMLReport(&buf, ui.NewJSONRecord(start, redacted), Version); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("report: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// networkPoll is how often watch mode looks at the default route between
+// runs, to re-run as soon as the laptop joins another network.
+const networkPoll = 5 * time.Second
+
+// waitForNetworkChange waits d, or until ctx is done, polling the network
+// meanwhile. It returns early with the new network when it differs from
+// current.
+func waitForNetworkChange(ctx context.Context, d time.Duration, current diagnostic.Network) (diagnostic.Network, bool) {
+	deadline := time.Now().Add(d)
+	for {
+		wait := min(time.Until(deadline), networkPoll)
+		if wait <= 0 {
+			return current, false
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return current, false
+		}
+		if n := diagnostic.CurrentNetwork(ctx); !n.Same(current) && ctx.Err() == nil {
+			return n, true
+		}
+	}
+}
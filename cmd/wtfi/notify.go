@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+	"github.com/kanywst/wtfi/internal/watch"
+)
+
+// notifyDebounce is the minimum time between two degrade notifications.
+const notifyDebounce = time.Minute
+
+// overallCheck is the key the overall status is tracked under.
+const overallCheck = "overall"
+
+// recoveredNames is how many recovered checks a notification names.
+const recoveredNames = 3
+
+// alerter raises a desktop notification, and optionally rings the terminal
+// bell, when the overall status degrades from OK during watch mode, and
+// another, naming the checks that recovered, when it is OK again.
+type alerter struct {
+	tracker watch.AlertTracker
+	bell    bool
+	// degraded are the checks that were not OK during the spell being
+	// tracked, in the order they first were.
+	degraded []string
+}
+
+func newAlerter(bell bool) *alerter {
+	return &alerter{tracker: watch.AlertTracker{Debounce: notifyDebounce, Recoveries: true}, bell: bell}
+}
+
+// observeRun feeds the results of a whole run.
+func (a *alerter) observeRun(at time.Time, results []diagnostic.Result) {
+	h := diagnostic.AssessHealth(results)
+	if h.Graded == 0 {
+		return
+	}
+	var blamed diagnostic.Result
+	if h.Blamed >= 0 {
+		blamed = results[h.Blamed]
+	}
+	for _, r := range results {
+		if name := ui.BaseName(r.Name); (r.Status == diagnostic.StatusWarning || r.Status == diagnostic.StatusError) && !slices.Contains(a.degraded, name) {
+			a.degraded = append(a.degraded, name)
+		}
+	}
+	alert, fire := a.tracker.Observe(at, overallCheck, h.Blamed < 0, blamed.Status == diagnostic.StatusError)
+	if !fire {
+		if h.Blamed < 0 {
+			a.degraded = nil
+		}
+		return
+	}
+
+	title, message := "wtfi: "+h.Verdict, blamed.Name+": "+blamed.Message
+	if alert.Recovered {
+		title, message = "wtfi", fmt.Sprintf("%s after %s", recoveryText(a.degraded), alert.At.Sub(alert.Since).Round(time.Second))
+		a.degraded = nil
+	}
+	if err := notify(title, message); err != nil {
+		slog.Warn("could not show the notification", "err", err)
+	}
+	if a.bell && !alert.Recovered {
+		// stderr keeps the bell out of --json output.
+		fmt.Fprint(os.Stderr, "\a")
+	}
+}
+
+// recoveryText names the checks that are OK again after a spell.
+func recoveryText(checks []string) string {
+	switch {
+	case len(checks) == 0:
+		return "Network back to normal"
+	case len(checks) > recoveredNames:
+		return fmt.Sprintf("%s and %d more recovered", strings.Join(checks[:recoveredNames], ", "), len(checks)-recoveredNames)
+	}
+	return strings.Join(checks, ", ") + " recovered"
+}
+
+// notify shows a macOS notification through osascript.
+func notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message), appleScriptQuote(title))
+	slog.Debug("running command", "cmd", "osascript -e "+script)
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// appleScriptQuote returns s as an AppleScript string literal.
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
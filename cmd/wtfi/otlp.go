@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/otlp"
+	"github.com/kanywst/wtfi/internal/redact"
+)
+
+// otlpTimeout bounds each export, so a dead collector does not stall watch
+// mode.
+const otlpTimeout = 5 * time.Second
+
+// stepTimer records when each step of a run started and finished, which
+// the OTLP export turns into span timestamps.
+type stepTimer struct {
+	mu    sync.Mutex
+	start []time.Time
+	end   []time.Time
+}
+
+// wrap returns steps timed by t.
+func (t *stepTimer) wrap(steps []step) []step {
+	t.start = make([]time.Time, len(steps))
+	t.end = make([]time.Time, len(steps))
+	wrapped := make([]step, len(steps))
+	for i, s := range steps {
+		wrapped[i] = step{s.name, s.explain, func(ctx context.Context) diagnostic.Result {
+			start := time.Now()
+			r := s.run(ctx)
+			t.mu.Lock()
+			t.start[i], t.end[i] = start, time.Now()
+			t.mu.Unlock()
+			return r
+		}, s.exclusive}
+	}
+	return wrapped
+}
+
+// exportRun sends the run that started at start to the collector, redacted
+// by red, and only logs a failure: the diagnosis matters more.
+func exportRun(ctx context.Context, exp *otlp.Exporter, t *stepTimer, start time.Time, results []diagnostic.Result, red *redact.Redactor) {
+	run := otlp.Run{Start: start, End: time.Now(), Checks: make([]otlp.Check, len(results))}
+	t.mu.Lock()
+	for i, r := range results {
+		run.Checks[i] = otlp.Check{Start: t.start[i], End: t.end[i], Result: red.Result(r)}
+	}
+	t.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), otlpTimeout)
+	defer cancel()
+	if err := exp.Export(ctx, run); err != nil {
+		slog.Warn("could not export the run over OTLP", "err", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/mattn/go-isatty"
+)
+
+// portalOpener opens the captive portal login page found by a run, at most
+// once per session.
+type portalOpener struct {
+	// always opens the page without asking (--open-portal).
+	always bool
+	// ask prompts on the terminal instead; it is off in watch mode and for
+	// JSON output, where nobody is there to answer.
+	ask    bool
+	opened bool
+}
+
+// observe looks for a captive portal login page in results and opens it
+// in the default browser if the user agrees.
+func (o *portalOpener) observe(results []diagnostic.Result) {
+	if o.opened || (!o.always && !o.ask) {
+		return
+	}
+	loginURL := ""
+	for _, r := range results {
+		if r.Reason == diagnostic.ReasonCaptivePortal && r.Facts[diagnostic.FactPortalURL] != "" {
+			loginURL = r.Facts[diagnostic.FactPortalURL]
+			break
+		}
+	}
+	if loginURL == "" {
+		return
+	}
+	if !o.always && !confirm("Open the network login page "+loginURL+" in your browser?") {
+		return
+	}
+	o.opened = true
+	if err := openBrowser(loginURL); err != nil {
+		fmt.Fprintf(os.Stderr, "wtfi: could not open %s: %v\n", loginURL, err)
+	}
+}
+
+// confirm asks question on the terminal and reports whether the user
+// answered yes. Without a terminal to ask on, the answer is no.
+func confirm(question string) bool {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	a := strings.ToLower(strings.TrimSpace(answer))
+	return a == "y" || a == "yes"
+}
+
+// openBrowser opens u in the default browser.
+func openBrowser(u string) error {
+	name := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		name = "open"
+	}
+	slog.Debug("running command", "cmd", name+" "+u)
+	return exec.Command(name, u).Run()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/redact"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// indexedResult is a step's result tagged with the step's position.
+type indexedResult struct {
+	i int
+	r diagnostic.Result
+}
+
+// runSteps runs steps and pushes each result, redacted by red, to sink in
+// step order, or as each step finishes when sink is a ui.UnorderedSink; the
+// returned results are raw and in step order. With workers above one, up to
+// that many steps run concurrently, except exclusive ones, which run alone
+// once the others are done. Session tracking happens here, on a single
+// goroutine.
+func runSteps(ctx context.Context, steps []step, workers int, sess *session, red *redact.Redactor, sink ui.OutputSink) []diagnostic.Result {
+	results := make([]diagnostic.Result, len(steps))
+	ordered := ui.OrderedSink{Next: sink}
+	_, unordered := sink.(ui.UnorderedSink)
+	finish := func(i int, r diagnostic.Result) {
+		if sess != nil {
+			sess.observe(steps[i].name, &r)
+		}
+		results[i] = r
+		if unordered {
+			sink.Result(red.Result(r))
+			return
+		}
+		ordered.Put(i, red.Result(r))
+	}
+
+	if workers <= 1 {
+		for i, s := range steps {
+			finish(i, s.run(ctx))
+		}
+		return results
+	}
+
+	done := make(chan indexedResult)
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, s := range steps {
+		if s.exclusive {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			r := s.run(ctx)
+			<-slots
+			done <- indexedResult{i, r}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for ir := range done {
+		finish(ir.i, ir.r)
+	}
+	for i, s := range steps {
+		if s.exclusive {
+			finish(i, s.run(ctx))
+		}
+	}
+	return results
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/watch"
+)
+
+// gatewayAlertHold is how long a gateway MAC change keeps being flagged.
+const gatewayAlertHold = 5 * time.Minute
+
+// session carries watch-mode state from one refresh to the next.
+type session struct {
+	roams         watch.RoamTracker
+	gateway       watch.MACTracker
+	gatewayChange *watch.MACChange
+	// wokeAfter is the sleep gap detected before the current run, if any.
+	wokeAfter time.Duration
+	// movedFrom and movedTo describe the networks left and joined just
+	// before the current run, if a network change triggered it.
+	movedFrom, movedTo string
+	// alerts is nil unless --notify was given.
+	alerts *alerter
+	// webhooks is nil unless a webhook or Slack webhook is configured.
+	webhooks *webhookAlerter
+	// shown is what the last refresh displayed, to highlight what changed.
+	shown []diagnostic.Result
+}
+
+// resume forgets network state that may not survive sleep: the Mac can wake
+// on a different network, whose gateway must not be flagged as spoofed.
+func (s *session) resume(gap time.Duration) {
+	s.gateway = watch.MACTracker{}
+	s.gatewayChange = nil
+	s.wokeAfter = gap
+}
+
+// moved forgets the gateway of the network left, whose replacement must
+// not be flagged as spoofed, and notes the move for the next run. Behind
+// the same gateway address, as when switching from Wi-Fi to the cable of
+// one LAN, the gateway keeps being tracked.
+func (s *session) moved(from, to diagnostic.Network) {
+	if from.Gateway != to.Gateway {
+		s.gateway = watch.MACTracker{}
+		s.gatewayChange = nil
+	}
+	s.movedFrom, s.movedTo = from.String(), to.String()
+}
+
+// observe feeds r, the result of the step called name, to the trackers and
+// annotates it with any finding.
+func (s *session) observe(name string, r *diagnostic.Result) {
+	now := time.Now()
+
+	if bssid, ok := r.Facts[diagnostic.FactBSSID]; ok {
+		rssi, _ := strconv.Atoi(r.Facts[diagnostic.FactRSSI])
+		s.roams.Observe(now, bssid, rssi)
+	}
+
+	if mac, ok := r.Facts[diagnostic.FactGatewayMAC]; ok {
+		if ch, changed := s.gateway.Observe(now, mac); changed {
+			s.gatewayChange = &ch
+		}
+		if ch := s.gatewayChange; ch != nil && now.Sub(ch.At) < gatewayAlertHold {
+			r.Status = max(r.Status, diagnostic.StatusWarning)
+			r.Message = "Gateway MAC address changed"
+			r.Reason = diagnostic.ReasonGatewayMACChanged
+			r.Fix = "On an untrusted network this may be ARP spoofing; otherwise the router was replaced."
+			r.AddDetails(
+				fmt.Sprintf("Previous MAC: %s (seen since %s)", ch.Old, ch.OldSince.Format(time.TimeOnly)),
+				fmt.Sprintf("Current MAC: %s (since %s)", ch.New, ch.At.Format(time.TimeOnly)),
+			)
+		}
+	}
+
+	if s.webhooks != nil {
+		s.webhooks.observe(now, name, *r)
+	}
+}
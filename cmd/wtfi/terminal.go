@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+)
+
+// disableColor turns color off in the text output and the dashboard alike.
+// fatih/color already does so by itself under NO_COLOR, TERM=dumb or when
+// stdout is not a terminal.
+func disableColor() {
+	color.NoColor = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// wantASCII reports whether the text output should be plain ASCII: when
+// asked to, when it goes to a pipe or file, or when the locale's character
+// set is not UTF-8 and emoji would print as mojibake.
+func wantASCII(forced bool) bool {
+	return forced || !isatty.IsTerminal(os.Stdout.Fd()) || !utf8Locale()
+}
+
+// utf8Locale reports whether the locale in effect uses UTF-8, taking
+// LC_ALL, LC_CTYPE and LANG in order of precedence. No locale at all counts
+// as UTF-8, as in macOS apps that do not set one.
+func utf8Locale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/redact"
+	"github.com/kanywst/wtfi/internal/watch"
+	"github.com/kanywst/wtfi/internal/webhook"
+)
+
+// webhookTimeout bounds each post, so a dead endpoint does not pile up
+// goroutines in watch mode.
+const webhookTimeout = 10 * time.Second
+
+// webhookAlerter posts to the configured webhooks when a check degrades
+// during watch mode, and again when it recovers.
+type webhookAlerter struct {
+	tracker watch.AlertTracker
+	sinks   []webhook.Sink
+	host    string
+	red     *redact.Redactor
+	pending sync.WaitGroup
+}
+
+// newWebhookAlerter returns nil when no webhook is configured.
+func newWebhookAlerter(webhooks, slack []string, hold, cooldown time.Duration, red *redact.Redactor) *webhookAlerter {
+	var sinks []webhook.Sink
+	for _, u := range webhooks {
+		sinks = append(sinks, webhook.Sink{URL: u})
+	}
+	for _, u := range slack {
+		sinks = append(sinks, webhook.Sink{URL: u, Slack: true})
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	host, _ := os.Hostname()
+	return &webhookAlerter{
+		tracker: watch.AlertTracker{Debounce: cooldown, Hold: hold, Recoveries: true},
+		sinks:   sinks,
+		host:    host,
+		red:     red,
+	}
+}
+
+// observe feeds the result of the step called name, posting in the
+// background so a slow endpoint does not delay the run.
+func (a *webhookAlerter) observe(at time.Time, name string, r diagnostic.Result) {
+	if r.Status == diagnostic.StatusSkipped {
+		return
+	}
+	alert, fire := a.tracker.Observe(at, name, r.Status == diagnostic.StatusOk, r.Status == diagnostic.StatusError)
+	if !fire {
+		return
+	}
+	r = a.red.Result(r)
+	ev := webhook.Event{
+		Check:     name,
+		Name:      r.Name,
+		Status:    r.Status.String(),
+		Message:   r.Message,
+		Reason:    string(r.Reason),
+		Recovered: alert.Recovered,
+		Since:     alert.Since,
+		At:        alert.At,
+		Host:      a.host,
+	}
+	for _, s := range a.sinks {
+		a.pending.Add(1)
+		go func() {
+			defer a.pending.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+			defer cancel()
+			if err := s.Send(ctx, ev); err != nil {
+				slog.Warn("could not post the alert", "err", err)
+			}
+		}()
+	}
+}
+
+// wait blocks until the posts in flight are done.
+func (a *webhookAlerter) wait() {
+	a.pending.Wait()
+}
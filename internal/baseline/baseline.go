@@ -0,0 +1,172 @@
+// Package baseline stores a known-good run and compares later runs against it.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// metric describes how to read one comparable number from a result.
+type metric struct {
+	name         string
+	unit         string
+	higherBetter bool
+	value        func(ui.JSONResult) (float64, bool)
+}
+
+func fact(key string) func(ui.JSONResult) (float64, bool) {
+	return func(r ui.JSONResult) (float64, bool) {
+		var v float64
+		if _, err := fmt.Sscan(r.Facts[key], &v); err != nil {
+			return 0, false
+		}
+		return v, true
+	}
+}
+
+var metrics = []metric{
+	{"latency", "ms", false, func(r ui.JSONResult) (float64, bool) { return r.LatencyMs, r.LatencyMs > 0 }},
+	{"signal quality", "%", true, fact(diagnostic.FactSignalQuality)},
+	{"download", "Mbps", true, fact(diagnostic.FactDownloadMbps)},
+	{"upload", "Mbps", true, fact(diagnostic.FactUploadMbps)},
+}
+
+// Delta is the change of one metric between the baseline and the current run.
+type Delta struct {
+	Metric   string
+	Unit     string
+	Baseline float64
+	Current  float64
+	// Change is the relative change in percent; positive means worse.
+	Change    float64
+	Regressed bool
+}
+
+func (d Delta) String() string {
+	change := (d.Current - d.Baseline) / math.Abs(d.Baseline) * 100
+	return fmt.Sprintf("Baseline %s: %.1f → %.1f %s (%+.0f%%)", d.Metric, d.Baseline, d.Current, d.Unit, change)
+}
+
+// Compare returns the deltas of every metric present in both results.
+// A metric regresses when it is more than maxRegression percent worse.
+func Compare(base, cur ui.JSONResult, maxRegression float64) []Delta {
+	var deltas []Delta
+	for _, m := range metrics {
+		b, okB := m.value(base)
+		c, okC := m.value(cur)
+		if !okB || !okC || b == 0 {
+			continue
+		}
+		worse := (c - b) / math.Abs(b) * 100
+		if m.higherBetter {
+			worse = -worse
+		}
+		deltas = append(deltas, Delta{
+			Metric:    m.name,
+			Unit:      m.unit,
+			Baseline:  b,
+			Current:   c,
+			Change:    worse,
+			Regressed: worse > maxRegression,
+		})
+	}
+	return deltas
+}
+
+// Baseline is a saved run that later runs are compared against.
+type Baseline struct {
+	record ui.JSONRecord
+}
+
+// DefaultPath returns ~/.wtfi-baseline.json.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".wtfi-baseline.json")
+}
+
+// Save writes rec to path as the new baseline.
+func Save(path string, rec ui.JSONRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Load reads a baseline written by Save.
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec ui.JSONRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Baseline{record: rec}, nil
+}
+
+// checkKey is what matches a result with its baseline entry: the name
+// without its qualifier, so "Gateway (192.168.1.1)" and "Gateway
+// (10.0.0.1)" are the same check.
+func checkKey(name string) string {
+	return ui.BaseName(name)
+}
+
+func (b *Baseline) find(name string) (ui.JSONResult, bool) {
+	key := checkKey(name)
+	i := slices.IndexFunc(b.record.Results, func(r ui.JSONResult) bool { return checkKey(r.Name) == key })
+	if i < 0 {
+		return ui.JSONResult{}, false
+	}
+	return b.record.Results[i], true
+}
+
+// Annotate adds the deltas from the baseline to r's details and raises it to
+// a warning when a metric regressed by more than maxRegression percent.
+func (b *Baseline) Annotate(r *diagnostic.Result, maxRegression float64) {
+	base, ok := b.find(r.Name)
+	if !ok {
+		r.AddDetails("Baseline: not recorded for this check")
+		return
+	}
+
+	var lines []string
+	regressed := false
+	for _, d := range Compare(base, ui.ToJSONResult(*r), maxRegression) {
+		lines = append(lines, d.String())
+		regressed = regressed || d.Regressed
+	}
+	if len(lines) == 0 {
+		return
+	}
+	r.AddDetails(lines...)
+	if regressed && r.Status == diagnostic.StatusOk {
+		r.Status = diagnostic.StatusWarning
+		r.Reason = diagnostic.ReasonBaselineRegression
+		r.Fix = fmt.Sprintf("Worse by more than %.0f%% than the baseline of %s; compare with what changed since.",
+			maxRegression, b.record.Timestamp.Format("2006-01-02 15:04"))
+	}
+}
+
+// Missing returns the baseline checks absent from results.
+func (b *Baseline) Missing(results []diagnostic.Result) []string {
+	var missing []string
+	for _, br := range b.record.Results {
+		key := checkKey(br.Name)
+		if !slices.ContainsFunc(results, func(r diagnostic.Result) bool { return checkKey(r.Name) == key }) {
+			missing = append(missing, br.Name)
+		}
+	}
+	return missing
+}
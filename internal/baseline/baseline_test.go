@@ -0,0 +1,96 @@
+package baseline
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+func TestCompare(t *testing.T) {
+	base := ui.JSONResult{Name: "Throughput", LatencyMs: 10, Facts: map[string]string{
+		diagnostic.FactDownloadMbps: "100.0",
+		diagnostic.FactUploadMbps:   "20.0",
+	}}
+	cur := ui.JSONResult{Name: "Throughput", LatencyMs: 12, Facts: map[string]string{
+		diagnostic.FactDownloadMbps: "40.0",
+		diagnostic.FactUploadMbps:   "30.0",
+	}}
+
+	deltas := Compare(base, cur, 50)
+	if len(deltas) != 3 {
+		t.Fatalf("Expected 3 deltas, got %+v", deltas)
+	}
+	want := map[string]bool{"latency": false, "download": true, "upload": false}
+	for _, d := range deltas {
+		if d.Regressed != want[d.Metric] {
+			t.Errorf("%s: expected regressed=%v, got %+v", d.Metric, want[d.Metric], d)
+		}
+	}
+	if deltas[1].Change != 60 {
+		t.Errorf("Expected download to be 60%% worse, got %v", deltas[1].Change)
+	}
+	if got := deltas[1].String(); got != "Baseline download: 100.0 → 40.0 Mbps (-60%)" {
+		t.Errorf("Unexpected rendering %q", got)
+	}
+
+	if deltas := Compare(ui.JSONResult{}, cur, 50); len(deltas) != 0 {
+		t.Errorf("Expected no deltas without baseline metrics, got %+v", deltas)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	b := &Baseline{record: ui.JSONRecord{
+		Timestamp: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+		Results: []ui.JSONResult{
+			{Name: "Gateway (192.168.1.1)", LatencyMs: 5},
+			{Name: "DNS", LatencyMs: 20},
+		},
+	}}
+
+	slow := diagnostic.Result{Name: "Gateway (10.0.0.1)", Latency: 50 * time.Millisecond, Status: diagnostic.StatusOk}
+	b.Annotate(&slow, 50)
+	if slow.Status != diagnostic.StatusWarning || slow.Reason != diagnostic.ReasonBaselineRegression || !strings.Contains(slow.Fix, "baseline") {
+		t.Errorf("Expected a regression warning, got %+v", slow)
+	}
+	if len(slow.Details) != 1 || !strings.HasPrefix(slow.Details[0], "└─ Baseline latency") {
+		t.Errorf("Expected the delta in details, got %v", slow.Details)
+	}
+
+	failed := diagnostic.Result{Name: "Gateway", Latency: 50 * time.Millisecond, Status: diagnostic.StatusError}
+	b.Annotate(&failed, 50)
+	if failed.Status != diagnostic.StatusError {
+		t.Errorf("Expected an error to stay an error, got %v", failed.Status)
+	}
+
+	fresh := diagnostic.Result{Name: "TLS"}
+	b.Annotate(&fresh, 50)
+	if len(fresh.Details) != 1 || !strings.Contains(fresh.Details[0], "not recorded") {
+		t.Errorf("Expected a note for a check missing from the baseline, got %v", fresh.Details)
+	}
+
+	if missing := b.Missing([]diagnostic.Result{slow, fresh}); len(missing) != 1 || missing[0] != "DNS" {
+		t.Errorf("Expected DNS to be missing from the run, got %v", missing)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	rec := ui.NewJSONRecord(time.Now(), []diagnostic.Result{{Name: "Gateway", Latency: 5 * time.Millisecond}})
+	if err := Save(path, rec); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	b, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if r, ok := b.find("Gateway"); !ok || r.LatencyMs != 5 {
+		t.Errorf("Expected the saved result back, got %+v", r)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing baseline")
+	}
+}
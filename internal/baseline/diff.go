@@ -0,0 +1,157 @@
+package baseline
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// ChangeKind classifies a Change.
+type ChangeKind int
+
+const (
+	// Improved means the current run is better than the baseline.
+	Improved ChangeKind = iota
+	// Changed means the network is different but not necessarily worse,
+	// such as a new gateway or DNS server.
+	Changed
+	// Regressed means the current run is worse than the baseline.
+	Regressed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Regressed:
+		return "regressed"
+	case Changed:
+		return "changed"
+	}
+	return "improved"
+}
+
+// Change is one difference between the baseline and the current run.
+type Change struct {
+	Check string
+	Kind  ChangeKind
+	What  string
+}
+
+func (c Change) String() string {
+	return c.Check + ": " + c.What
+}
+
+// watchedFacts are the facts whose change alters the network path.
+var watchedFacts = []struct{ key, label string }{
+	{diagnostic.FactGatewayMAC, "gateway MAC"},
+	{diagnostic.FactNameservers, "DNS servers"},
+	{diagnostic.FactPublicIP, "public IP"},
+	{diagnostic.FactNATType, "NAT type"},
+}
+
+// Diff compares the current run with the baseline check by check: status
+// changes, metrics that moved by more than maxRegression percent, a
+// different gateway, network or DNS path, and routers new to the traceroute.
+// Checks are matched by name without the qualifier, so "Gateway
+// (192.168.1.1)" and "Gateway (10.0.0.1)" are the same check. Regressions
+// come first.
+func (b *Baseline) Diff(cur ui.JSONRecord, maxRegression float64) []Change {
+	var changes []Change
+	add := func(check string, kind ChangeKind, format string, args ...any) {
+		changes = append(changes, Change{Check: check, Kind: kind, What: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]bool{}
+	for _, r := range cur.Results {
+		name := checkKey(r.Name)
+		seen[name] = true
+		i := slices.IndexFunc(b.record.Results, func(br ui.JSONResult) bool { return checkKey(br.Name) == name })
+		if i < 0 {
+			add(name, Changed, "not in the baseline")
+			continue
+		}
+		base := b.record.Results[i]
+
+		if base.Status != r.Status {
+			kind := Improved
+			if statusRank(r.Status) > statusRank(base.Status) {
+				kind = Regressed
+			}
+			add(name, kind, "%s → %s", base.Status, r.Status)
+		}
+		for _, d := range Compare(base, r, maxRegression) {
+			switch {
+			case d.Regressed:
+				add(name, Regressed, "%s", strings.TrimPrefix(d.String(), "Baseline "))
+			case -d.Change > maxRegression:
+				add(name, Improved, "%s", strings.TrimPrefix(d.String(), "Baseline "))
+			}
+		}
+		if base.Name != r.Name {
+			add(name, Changed, "was %q, now %q", base.Name, r.Name)
+		}
+		for _, f := range watchedFacts {
+			if old, now := base.Facts[f.key], r.Facts[f.key]; old != "" && now != "" && old != now {
+				add(name, Changed, "%s %s → %s", f.label, old, now)
+			}
+		}
+		if hops := newHops(base.Facts[diagnostic.FactTraceHops], r.Facts[diagnostic.FactTraceHops]); len(hops) > 0 {
+			add(name, Changed, "new hops %s", strings.Join(hops, ", "))
+		}
+	}
+	for _, br := range b.record.Results {
+		if name := checkKey(br.Name); !seen[name] {
+			add(name, Changed, "in the baseline but not in this run")
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b Change) int { return int(b.Kind) - int(a.Kind) })
+	return changes
+}
+
+// statusRank orders statuses from best to worst; skipped ranks with OK, as
+// it says nothing about the network.
+func statusRank(s string) int {
+	switch s {
+	case diagnostic.StatusWarning.String():
+		return 1
+	case diagnostic.StatusError.String():
+		return 2
+	}
+	return 0
+}
+
+// newHops returns the routers of the current path that the baseline path
+// did not cross, given both as FactTraceHops values.
+func newHops(base, cur string) []string {
+	if base == "" || cur == "" {
+		return nil
+	}
+	old := strings.Split(base, ",")
+	var hops []string
+	for _, h := range strings.Split(cur, ",") {
+		if h != "*" && !slices.Contains(old, h) {
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// FactValues returns the values of the fact key across the baseline's
+// results.
+func (b *Baseline) FactValues(key string) []string {
+	var values []string
+	for _, r := range b.record.Results {
+		if v := r.Facts[key]; v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Timestamp returns when the baseline was recorded.
+func (b *Baseline) Timestamp() string {
+	return b.record.Timestamp.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,42 @@
+package baseline
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+func TestDiff(t *testing.T) {
+	b := &Baseline{record: ui.JSONRecord{Results: []ui.JSONResult{
+		{Name: "Gateway (192.168.1.1)", Status: "ok", LatencyMs: 2, Facts: map[string]string{diagnostic.FactGatewayMAC: "aa:aa"}},
+		{Name: "DNS Benchmark", Status: "ok", LatencyMs: 40, Facts: map[string]string{diagnostic.FactNameservers: "192.168.1.1"}},
+		{Name: "Fast Trace", Status: "ok", Facts: map[string]string{diagnostic.FactTraceHops: "192.168.1.1,*,203.0.113.1"}},
+		{Name: "IPv6", Status: "warning"},
+		{Name: "TLS (cloudflare.com)", Status: "ok"},
+	}}}
+	cur := ui.JSONRecord{Results: []ui.JSONResult{
+		{Name: "Gateway (10.0.0.1)", Status: "ok", LatencyMs: 2, Facts: map[string]string{diagnostic.FactGatewayMAC: "bb:bb"}},
+		{Name: "DNS Benchmark", Status: "warning", LatencyMs: 200, Facts: map[string]string{diagnostic.FactNameservers: "10.0.0.1"}},
+		{Name: "Fast Trace", Status: "ok", Facts: map[string]string{diagnostic.FactTraceHops: "10.0.0.1,*,203.0.113.1,198.51.100.9"}},
+		{Name: "IPv6", Status: "ok"},
+		{Name: "Proxy", Status: "ok"},
+	}}
+
+	got := b.Diff(cur, 50)
+	want := []Change{
+		{"DNS Benchmark", Regressed, "ok → warning"},
+		{"DNS Benchmark", Regressed, "latency: 40.0 → 200.0 ms (+400%)"},
+		{"Gateway", Changed, `was "Gateway (192.168.1.1)", now "Gateway (10.0.0.1)"`},
+		{"Gateway", Changed, "gateway MAC aa:aa → bb:bb"},
+		{"DNS Benchmark", Changed, "DNS servers 192.168.1.1 → 10.0.0.1"},
+		{"Fast Trace", Changed, "new hops 10.0.0.1, 198.51.100.9"},
+		{"Proxy", Changed, "not in the baseline"},
+		{"TLS", Changed, "in the baseline but not in this run"},
+		{"IPv6", Improved, "warning → ok"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Unexpected diff:\n got %v\nwant %v", got, want)
+	}
+}
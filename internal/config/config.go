@@ -0,0 +1,455 @@
+// Package config loads wtfi settings from a YAML file and merges them with
+// built-in defaults and command-line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/logfile"
+)
+
+// Output formats.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	// OutputMarkdown is a GitHub-flavored Markdown table for pasting into
+	// issues and chats.
+	OutputMarkdown = "markdown"
+	// OutputNDJSON streams one JSON line per check as it finishes, then one
+	// for the run.
+	OutputNDJSON = "ndjson"
+)
+
+// IsJSON reports whether output is one of the JSON formats.
+func IsJSON(output string) bool {
+	return output == OutputJSON || output == OutputNDJSON
+}
+
+// Config is the fully resolved configuration of a wtfi run.
+type Config struct {
+	// Diagnostic holds the thresholds and targets used by the checks.
+	Diagnostic diagnostic.Config
+	// Output is OutputText, OutputJSON, OutputMarkdown or OutputNDJSON.
+	Output string
+	// Checks lists the enabled check names; empty means the default set.
+	Checks []string
+	// Skip lists check names removed from the enabled set.
+	Skip []string
+	// BaselineRegression is the percentage by which a metric may be worse
+	// than the saved baseline before it is flagged.
+	BaselineRegression float64
+	// LatencyGood and LatencyPoor bound the green, yellow and red tiers used
+	// to color latency values in the terminal.
+	LatencyGood time.Duration
+	LatencyPoor time.Duration
+	// OTLPEndpoint is the OpenTelemetry collector each run is exported to
+	// over OTLP/HTTP, e.g. http://localhost:4318; empty disables the export.
+	OTLPEndpoint string
+	// OTLPHeaders are sent with every export, e.g. an API key.
+	OTLPHeaders map[string]string
+	// AlertWebhooks receive a JSON post, and SlackWebhooks a Slack message,
+	// when a check degrades or recovers in watch mode.
+	AlertWebhooks []string
+	SlackWebhooks []string
+	// AlertHold is how long a check must stay degraded before it alerts, and
+	// AlertCooldown the minimum time between two alerts for the same check.
+	AlertHold     time.Duration
+	AlertCooldown time.Duration
+}
+
+// fileConfig mirrors the YAML layout. Pointers distinguish "unset" from zero.
+type fileConfig struct {
+	Timeout *time.Duration `yaml:"timeout"`
+	Output  *string        `yaml:"output"`
+	Checks  []string       `yaml:"checks"`
+	Skip    []string       `yaml:"skip"`
+	Targets struct {
+		TLSHost          *string  `yaml:"tls_host"`
+		TLSInspectHosts  []string `yaml:"tls_inspect_hosts"`
+		QUICHost         *string  `yaml:"quic_host"`
+		NTPServer        *string  `yaml:"ntp_server"`
+		FilterProbeHost  *string  `yaml:"filter_probe_host"`
+		WANHost          *string  `yaml:"wan_host"`
+		CaptivePortalURL *string  `yaml:"captive_portal_url"`
+	} `yaml:"targets"`
+	DNS struct {
+		Resolvers []string `yaml:"resolvers"`
+	} `yaml:"dns"`
+	Services   []string `yaml:"services"`
+	Thresholds struct {
+		MinSignalQuality *int           `yaml:"min_signal_quality"`
+		MinRSSI          *int           `yaml:"min_rssi"`
+		MaxLoss          *float64       `yaml:"max_loss"`
+		MaxJitter        *time.Duration `yaml:"max_jitter"`
+		MaxClockSkew     *time.Duration `yaml:"max_clock_skew"`
+		DNSSlow          *time.Duration `yaml:"dns_slow"`
+		WANSlow          *time.Duration `yaml:"wan_slow"`
+		MinUploadRatio   *float64       `yaml:"min_upload_ratio"`
+		MinDownloadMbps  *float64       `yaml:"min_download_mbps"`
+		MinUploadMbps    *float64       `yaml:"min_upload_mbps"`
+		CacheTTL         *time.Duration `yaml:"cache_ttl"`
+		Baseline         *float64       `yaml:"baseline_regression"`
+		LatencyGood      *time.Duration `yaml:"latency_good"`
+		LatencyPoor      *time.Duration `yaml:"latency_poor"`
+	} `yaml:"thresholds"`
+	Speed struct {
+		DownloadSize *string        `yaml:"download_size"`
+		UploadSize   *string        `yaml:"upload_size"`
+		Timeout      *time.Duration `yaml:"timeout"`
+		DownloadURL  *string        `yaml:"download_url"`
+		UploadURL    *string        `yaml:"upload_url"`
+		IperfServer  *string        `yaml:"iperf_server"`
+	} `yaml:"speed"`
+	Trace struct {
+		MaxTTL      *int    `yaml:"max_ttl"`
+		Probes      *int    `yaml:"probes"`
+		Concurrency *int    `yaml:"concurrency"`
+		Mode        *string `yaml:"mode"`
+	} `yaml:"trace"`
+	WiFi struct {
+		TxPower          *int    `yaml:"tx_power"`
+		PathLossExponent *int    `yaml:"path_loss_exponent"`
+		KnownAPsFile     *string `yaml:"known_aps_file"`
+	} `yaml:"wifi"`
+	Gateway struct {
+		KnownMACsFile *string `yaml:"known_macs_file"`
+	} `yaml:"gateway"`
+	Ping struct {
+		Size     *int           `yaml:"size"`
+		Interval *time.Duration `yaml:"interval"`
+		Samples  *int           `yaml:"samples"`
+		Burst    *int           `yaml:"burst"`
+	} `yaml:"ping"`
+	OTLP struct {
+		Endpoint *string           `yaml:"endpoint"`
+		Headers  map[string]string `yaml:"headers"`
+	} `yaml:"otlp"`
+	Alerts struct {
+		Webhooks []string       `yaml:"webhooks"`
+		Slack    []string       `yaml:"slack"`
+		Hold     *time.Duration `yaml:"hold"`
+		Cooldown *time.Duration `yaml:"cooldown"`
+	} `yaml:"alerts"`
+}
+
+// reInterfaceName matches the names --interface accepts, which end up on
+// command lines such as `route -n get -ifscope en7 default`.
+var reInterfaceName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Default returns the configuration used when no file or flags are given.
+func Default() *Config {
+	return &Config{
+		Diagnostic:         diagnostic.DefaultConfig(),
+		Output:             OutputText,
+		BaselineRegression: 50,
+		LatencyGood:        20 * time.Millisecond,
+		LatencyPoor:        100 * time.Millisecond,
+		AlertCooldown:      5 * time.Minute,
+	}
+}
+
+// DefaultPaths returns the locations searched for a config file, in order:
+// $XDG_CONFIG_HOME/wtfi/config.yaml (~/.config/wtfi/config.yaml when unset),
+// then the legacy ~/.wtfi.yaml.
+func DefaultPaths() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(home, ".config")
+	}
+	return []string{filepath.Join(dir, "wtfi", "config.yaml"), filepath.Join(home, ".wtfi.yaml")}
+}
+
+// LoadConfig reads the YAML file at path, merges it over the defaults and
+// validates the result. Unknown keys are rejected to catch typos.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	c := Default()
+	if err := c.merge(fc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *Config) merge(fc fileConfig) error {
+	d := &c.Diagnostic
+	setIf(&d.Timeout, fc.Timeout)
+	setIf(&c.Output, fc.Output)
+	if fc.Checks != nil {
+		c.Checks = fc.Checks
+	}
+	if fc.Skip != nil {
+		c.Skip = fc.Skip
+	}
+	setIf(&d.TLSHost, fc.Targets.TLSHost)
+	if fc.Targets.TLSInspectHosts != nil {
+		d.TLSInspectHosts = fc.Targets.TLSInspectHosts
+	}
+	setIf(&d.QUICHost, fc.Targets.QUICHost)
+	setIf(&d.NTPServer, fc.Targets.NTPServer)
+	setIf(&d.FilterProbeHost, fc.Targets.FilterProbeHost)
+	setIf(&d.WANHost, fc.Targets.WANHost)
+	setIf(&d.CaptivePortalURL, fc.Targets.CaptivePortalURL)
+	if fc.DNS.Resolvers != nil {
+		d.DNSResolvers = fc.DNS.Resolvers
+	}
+	if fc.Services != nil {
+		d.Services = fc.Services
+	}
+	setIf(&d.MinSignalQuality, fc.Thresholds.MinSignalQuality)
+	setIf(&d.MinRSSI, fc.Thresholds.MinRSSI)
+	setIf(&d.MaxLoss, fc.Thresholds.MaxLoss)
+	setIf(&d.MaxJitter, fc.Thresholds.MaxJitter)
+	setIf(&d.MaxClockSkew, fc.Thresholds.MaxClockSkew)
+	setIf(&d.DNSSlow, fc.Thresholds.DNSSlow)
+	setIf(&d.WANSlow, fc.Thresholds.WANSlow)
+	setIf(&d.MinUploadRatio, fc.Thresholds.MinUploadRatio)
+	setIf(&d.MinDownloadMbps, fc.Thresholds.MinDownloadMbps)
+	setIf(&d.MinUploadMbps, fc.Thresholds.MinUploadMbps)
+	setIf(&d.CacheTTL, fc.Thresholds.CacheTTL)
+	setIf(&c.BaselineRegression, fc.Thresholds.Baseline)
+	setIf(&c.LatencyGood, fc.Thresholds.LatencyGood)
+	setIf(&c.LatencyPoor, fc.Thresholds.LatencyPoor)
+	setIf(&d.SpeedTimeout, fc.Speed.Timeout)
+	setIf(&d.SpeedDownloadURL, fc.Speed.DownloadURL)
+	setIf(&d.SpeedUploadURL, fc.Speed.UploadURL)
+	setIf(&d.IperfServer, fc.Speed.IperfServer)
+	setIf(&d.TraceMaxTTL, fc.Trace.MaxTTL)
+	setIf(&d.TraceProbes, fc.Trace.Probes)
+	setIf(&d.TraceConcurrency, fc.Trace.Concurrency)
+	setIf(&d.TraceMode, fc.Trace.Mode)
+	setIf(&d.TxPower, fc.WiFi.TxPower)
+	setIf(&d.PathLossExponent, fc.WiFi.PathLossExponent)
+	setIf(&d.KnownAPsFile, fc.WiFi.KnownAPsFile)
+	setIf(&d.KnownGatewaysFile, fc.Gateway.KnownMACsFile)
+	setIf(&d.PingSize, fc.Ping.Size)
+	setIf(&d.PingInterval, fc.Ping.Interval)
+	setIf(&d.LatencySamples, fc.Ping.Samples)
+	setIf(&d.PingBurst, fc.Ping.Burst)
+	setIf(&c.OTLPEndpoint, fc.OTLP.Endpoint)
+	if fc.OTLP.Headers != nil {
+		c.OTLPHeaders = fc.OTLP.Headers
+	}
+	if fc.Alerts.Webhooks != nil {
+		c.AlertWebhooks = fc.Alerts.Webhooks
+	}
+	if fc.Alerts.Slack != nil {
+		c.SlackWebhooks = fc.Alerts.Slack
+	}
+	setIf(&c.AlertHold, fc.Alerts.Hold)
+	setIf(&c.AlertCooldown, fc.Alerts.Cooldown)
+	if fc.Speed.DownloadSize != nil {
+		if err := c.Set("download-size", *fc.Speed.DownloadSize); err != nil {
+			return err
+		}
+	}
+	if fc.Speed.UploadSize != nil {
+		if err := c.Set("upload-size", *fc.Speed.UploadSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setIf[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// Set applies a command-line flag by name, so flags take precedence over the
+// file. It reports an error for a bad value; flags that have no config
+// equivalent are ignored.
+func (c *Config) Set(name, value string) error {
+	d := &c.Diagnostic
+	var err error
+	switch name {
+	case "timeout":
+		d.Timeout, err = time.ParseDuration(value)
+	case "tls-host":
+		d.TLSHost = value
+	case "download-size":
+		d.DownloadBytes, err = logfile.ParseSize(value)
+	case "upload-size":
+		d.UploadBytes, err = logfile.ParseSize(value)
+	case "trace-mode":
+		d.TraceMode = value
+	case "iperf-server":
+		d.IperfServer = value
+	case "interface":
+		d.Interface = value
+	case "ping-size":
+		d.PingSize, err = strconv.Atoi(value)
+	case "ping-interval":
+		d.PingInterval, err = time.ParseDuration(value)
+	case "samples":
+		d.LatencySamples, err = strconv.Atoi(value)
+	case "burst":
+		d.PingBurst, err = strconv.Atoi(value)
+	case "only":
+		c.Checks = splitList(value)
+	case "skip":
+		c.Skip = splitList(value)
+	case "baseline-threshold":
+		c.BaselineRegression, err = strconv.ParseFloat(value, 64)
+	case "format":
+		c.Output = value
+	case "otlp-endpoint":
+		c.OTLPEndpoint = value
+	case "webhook":
+		c.AlertWebhooks = splitList(value)
+	case "slack-webhook":
+		c.SlackWebhooks = splitList(value)
+	case "json":
+		var on bool
+		if on, err = strconv.ParseBool(value); err == nil {
+			c.Output = OutputText
+			if on {
+				c.Output = OutputJSON
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate rejects values that would make the checks misbehave.
+func (c *Config) Validate() error {
+	d := c.Diagnostic
+	switch {
+	case d.Timeout <= 0:
+		return fmt.Errorf("timeout must be positive, got %v", d.Timeout)
+	case d.CacheTTL < 0:
+		return fmt.Errorf("cache_ttl must not be negative, got %v", d.CacheTTL)
+	case d.SpeedTimeout <= 0:
+		return fmt.Errorf("speed timeout must be positive, got %v", d.SpeedTimeout)
+	case d.MinSignalQuality < 0 || d.MinSignalQuality > 100:
+		return fmt.Errorf("min_signal_quality must be within 0-100, got %d", d.MinSignalQuality)
+	case d.MinUploadRatio < 0 || d.MinUploadRatio > 1:
+		return fmt.Errorf("min_upload_ratio must be within 0-1, got %g", d.MinUploadRatio)
+	case d.DownloadBytes <= 0 || d.UploadBytes <= 0:
+		return errors.New("speed sizes must be positive")
+	case d.MinDownloadMbps < 0 || d.MinUploadMbps < 0:
+		return errors.New("min_download_mbps and min_upload_mbps must not be negative")
+	case !isHTTPURL(d.SpeedDownloadURL) || !isHTTPURL(d.SpeedUploadURL):
+		return fmt.Errorf("speed download_url and upload_url must be http(s) URLs, got %q and %q", d.SpeedDownloadURL, d.SpeedUploadURL)
+	case d.TraceMaxTTL < 1 || d.TraceMaxTTL > 64:
+		return fmt.Errorf("trace max_ttl must be within 1-64, got %d", d.TraceMaxTTL)
+	case d.TraceProbes < 1 || d.TraceConcurrency < 1:
+		return errors.New("trace probes and concurrency must be positive")
+	case !slices.Contains([]string{diagnostic.TraceICMP, diagnostic.TraceUDP, diagnostic.TraceTCP}, d.TraceMode):
+		return fmt.Errorf("trace mode must be icmp, udp or tcp, got %q", d.TraceMode)
+	case d.PathLossExponent < 1:
+		return fmt.Errorf("wifi path_loss_exponent must be positive, got %d", d.PathLossExponent)
+	case d.LatencySamples < 0 || d.LatencySamples > 1000:
+		return fmt.Errorf("ping samples must be within 0-1000, got %d", d.LatencySamples)
+	case d.PingBurst < 2 || d.PingBurst > 100:
+		return fmt.Errorf("ping burst must be within 2-100, got %d", d.PingBurst)
+	case d.MaxLoss < 0 || d.MaxLoss > 100:
+		return fmt.Errorf("max_loss must be within 0-100, got %g", d.MaxLoss)
+	case d.MaxJitter <= 0:
+		return fmt.Errorf("max_jitter must be positive, got %v", d.MaxJitter)
+	case d.TLSHost == "" || d.QUICHost == "" || d.FilterProbeHost == "" || d.WANHost == "":
+		return errors.New("targets must not be empty")
+	case net.ParseIP(d.WANHost).To4() == nil:
+		return fmt.Errorf("wan_host must be an IPv4 address, got %q", d.WANHost)
+	case !isHTTPURL(d.CaptivePortalURL):
+		return fmt.Errorf("captive_portal_url must be an http(s) URL, got %q", d.CaptivePortalURL)
+	case d.Interface != "" && !reInterfaceName.MatchString(d.Interface):
+		return fmt.Errorf("interface must be an interface name such as en0, got %q", d.Interface)
+	case slices.Contains(d.DNSResolvers, ""):
+		return errors.New("dns resolvers must not be empty")
+	case slices.Contains(d.TLSInspectHosts, ""):
+		return errors.New("tls_inspect_hosts must not be empty")
+	case d.MinRSSI < -120 || d.MinRSSI > 0:
+		return fmt.Errorf("min_rssi must be within -120-0 dBm, got %d", d.MinRSSI)
+	case d.DNSSlow <= 0 || d.WANSlow <= 0:
+		return errors.New("dns_slow and wan_slow must be positive")
+	case d.MaxClockSkew <= 0:
+		return fmt.Errorf("max_clock_skew must be positive, got %v", d.MaxClockSkew)
+	case c.BaselineRegression <= 0:
+		return fmt.Errorf("baseline_regression must be positive, got %g", c.BaselineRegression)
+	case c.LatencyGood <= 0 || c.LatencyPoor < c.LatencyGood:
+		return fmt.Errorf("latency_good must be positive and not above latency_poor, got %v and %v", c.LatencyGood, c.LatencyPoor)
+	case c.Output != OutputText && c.Output != OutputJSON && c.Output != OutputMarkdown && c.Output != OutputNDJSON:
+		return fmt.Errorf("output must be %q, %q, %q or %q, got %q", OutputText, OutputJSON, OutputMarkdown, OutputNDJSON, c.Output)
+	case c.OTLPEndpoint != "" && !isHTTPURL(c.OTLPEndpoint):
+		return fmt.Errorf("otlp endpoint must be an http(s) URL, got %q", c.OTLPEndpoint)
+	case slices.ContainsFunc(c.AlertWebhooks, notHTTPURL) || slices.ContainsFunc(c.SlackWebhooks, notHTTPURL):
+		return errors.New("alert webhooks must be http(s) URLs")
+	case c.AlertHold < 0 || c.AlertCooldown < 0:
+		return errors.New("alert hold and cooldown must not be negative")
+	}
+	if err := diagnostic.ValidateServices(d.Services); err != nil {
+		return err
+	}
+	return validatePing(d.PingSize, d.PingInterval, diagnostic.IsRoot())
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL.
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func notHTTPURL(s string) bool { return !isHTTPURL(s) }
+
+// validatePing checks the ping size against the MTU and the interval against
+// the minimum macOS allows for non-root users. Zero means ping's default.
+func validatePing(size int, interval time.Duration, root bool) error {
+	switch {
+	case size != 0 && (size < diagnostic.MinPingSize || size > diagnostic.MaxPingSize):
+		return fmt.Errorf("ping size must be within %d-%d bytes to fit a %d-byte MTU, got %d",
+			diagnostic.MinPingSize, diagnostic.MaxPingSize, diagnostic.StandardMTU, size)
+	case interval < 0:
+		return fmt.Errorf("ping interval must not be negative, got %v", interval)
+	case interval != 0 && interval < diagnostic.MinPingInterval && !root:
+		return fmt.Errorf("ping interval below %v requires root, got %v", diagnostic.MinPingInterval, interval)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wtfi.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigMergesDefaults(t *testing.T) {
+	path := writeConfig(t, `
+timeout: 5s
+output: json
+checks: [wifi, gateway]
+targets:
+  tls_host: intranet.example.com
+thresholds:
+  min_signal_quality: 40
+speed:
+  upload_size: 5MB
+`)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if c.Diagnostic.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s, got %v", c.Diagnostic.Timeout)
+	}
+	if c.Output != OutputJSON {
+		t.Errorf("Expected json output, got %s", c.Output)
+	}
+	if len(c.Checks) != 2 {
+		t.Errorf("Expected 2 checks, got %v", c.Checks)
+	}
+	if c.Diagnostic.TLSHost != "intranet.example.com" || c.Diagnostic.MinSignalQuality != 40 {
+		t.Errorf("Expected file values to be applied, got %+v", c.Diagnostic)
+	}
+	if c.Diagnostic.UploadBytes != 5<<20 {
+		t.Errorf("Expected 5MB upload, got %d", c.Diagnostic.UploadBytes)
+	}
+
+	def := Default()
+	if c.Diagnostic.FilterProbeHost != def.Diagnostic.FilterProbeHost || c.Diagnostic.DownloadBytes != def.Diagnostic.DownloadBytes {
+		t.Errorf("Expected unset keys to keep defaults, got %+v", c.Diagnostic)
+	}
+}
+
+func TestFlagsOverrideFile(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, "timeout: 5s\ntargets:\n  tls_host: a.example\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := c.Set("timeout", "1s"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := c.Set("json", "true"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := c.Set("v", "true"); err != nil {
+		t.Errorf("Expected flags without config equivalent to be ignored, got %v", err)
+	}
+	if c.Diagnostic.Timeout != time.Second {
+		t.Errorf("Expected flag to win, got %v", c.Diagnostic.Timeout)
+	}
+	if c.Diagnostic.TLSHost != "a.example" {
+		t.Errorf("Expected file value to survive, got %s", c.Diagnostic.TLSHost)
+	}
+	if c.Output != OutputJSON {
+		t.Errorf("Expected --json to select json output, got %s", c.Output)
+	}
+	if err := c.Set("format", "markdown"); err != nil || c.Output != OutputMarkdown {
+		t.Errorf("Expected --format to select markdown output, got %s (%v)", c.Output, err)
+	}
+	if err := c.Set("only", "wifi, gateway,"); err != nil || strings.Join(c.Checks, "|") != "wifi|gateway" {
+		t.Errorf("Expected --only to replace the checks, got %v (%v)", c.Checks, err)
+	}
+	if err := c.Set("skip", "trace"); err != nil || len(c.Skip) != 1 {
+		t.Errorf("Expected --skip to be applied, got %v (%v)", c.Skip, err)
+	}
+	if err := c.Set("timeout", "soon"); err == nil {
+		t.Error("Expected error for a malformed flag value")
+	}
+	if err := c.Set("interface", "en7"); err != nil || c.Diagnostic.Interface != "en7" {
+		t.Errorf("Expected --interface to be applied, got %q (%v)", c.Diagnostic.Interface, err)
+	}
+	d := Default()
+	d.Diagnostic.Interface = "en7"
+	if err := d.Validate(); err != nil {
+		t.Errorf("Expected an interface name to be accepted, got %v", err)
+	}
+	if d.Diagnostic.Interface = "en7; reboot"; d.Validate() == nil {
+		t.Error("Expected an interface name with shell characters to be rejected")
+	}
+	d = Default()
+	if d.Output = OutputNDJSON; d.Validate() != nil || !IsJSON(d.Output) {
+		t.Errorf("Expected ndjson to be a valid JSON output, got %v", d.Validate())
+	}
+	if err := d.Set("otlp-endpoint", "collector:4318"); err != nil || d.Validate() == nil {
+		t.Error("Expected an OTLP endpoint without a scheme to be rejected")
+	}
+	d = Default()
+	if err := d.Set("slack-webhook", "https://hooks.slack.com/services/T/B/x,"); err != nil || len(d.SlackWebhooks) != 1 || d.Validate() != nil {
+		t.Errorf("Expected --slack-webhook to be applied, got %v (%v)", d.SlackWebhooks, d.Validate())
+	}
+	if err := d.Set("webhook", "hooks.example.com/wtfi"); err != nil || d.Validate() == nil {
+		t.Error("Expected a webhook without a scheme to be rejected")
+	}
+}
+
+func TestLoadConfigRejectsBadFiles(t *testing.T) {
+	tests := map[string]string{
+		"malformed yaml":   "timeout: [5s",
+		"unknown key":      "timout: 5s",
+		"negative timeout": "timeout: -1s",
+		"quality range":    "thresholds:\n  min_signal_quality: 150",
+		"bad size":         "speed:\n  upload_size: lots",
+		"bad cooldown":     "alerts:\n  cooldown: -1m",
+		"bad output":       "output: xml",
+		"ttl range":        "trace:\n  max_ttl: 0",
+		"zero probes":      "trace:\n  probes: 0",
+		"trace mode":       "trace:\n  mode: sctp",
+		"zero regression":  "thresholds:\n  baseline_regression: 0",
+		"zero exponent":    "wifi:\n  path_loss_exponent: 0",
+		"negative samples": "ping:\n  samples: -1",
+		"inverted tiers":   "thresholds:\n  latency_good: 200ms",
+		"wan hostname":     "targets:\n  wan_host: one.one.one.one",
+		"portal scheme":    "targets:\n  captive_portal_url: captive.example.com",
+		"empty resolver":   "dns:\n  resolvers: [\"\"]",
+		"empty tls host":   "targets:\n  tls_inspect_hosts: [apple.com, \"\"]",
+		"positive rssi":    "thresholds:\n  min_rssi: 10",
+		"zero dns_slow":    "thresholds:\n  dns_slow: 0s",
+		"zero clock skew":  "thresholds:\n  max_clock_skew: 0s",
+		"service no port":  "services: [github.com]",
+		"service scheme":   "services: [\"ftp://files.example.com\"]",
+		"single burst":     "ping:\n  burst: 1",
+		"loss range":       "thresholds:\n  max_loss: 120",
+		"speed url scheme": "speed:\n  download_url: speed.example.com/down",
+		"negative floor":   "thresholds:\n  min_upload_mbps: -1",
+	}
+	for name, content := range tests {
+		if _, err := LoadConfig(writeConfig(t, content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadConfigTargets(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, `
+targets:
+  wan_host: 10.0.0.1
+  captive_portal_url: http://portal.corp.example/check
+dns:
+  resolvers: [10.0.0.53, "10.0.1.53:5353"]
+services: [github.com:443, "https://intranet.corp.example/health"]
+thresholds:
+  min_rssi: -72
+  dns_slow: 50ms
+  wan_slow: 40ms
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	d := c.Diagnostic
+	if d.WANHost != "10.0.0.1" || d.CaptivePortalURL != "http://portal.corp.example/check" {
+		t.Errorf("Expected the targets to be applied, got %q and %q", d.WANHost, d.CaptivePortalURL)
+	}
+	if strings.Join(d.DNSResolvers, "|") != "10.0.0.53|10.0.1.53:5353" {
+		t.Errorf("Expected the resolvers to be applied, got %v", d.DNSResolvers)
+	}
+	if strings.Join(d.Services, "|") != "github.com:443|https://intranet.corp.example/health" {
+		t.Errorf("Expected the services to be applied, got %v", d.Services)
+	}
+	if d.MinRSSI != -72 || d.DNSSlow != 50*time.Millisecond || d.WANSlow != 40*time.Millisecond {
+		t.Errorf("Expected the thresholds to be applied, got %+v", d)
+	}
+}
+
+func TestLoadConfigSpeed(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, `
+thresholds:
+  min_download_mbps: 100
+  min_upload_mbps: 20
+speed:
+  download_url: https://speed.corp.example/down
+  upload_url: https://speed.corp.example/up
+  iperf_server: iperf.corp.example:5202
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	d := c.Diagnostic
+	if d.SpeedDownloadURL != "https://speed.corp.example/down" || d.SpeedUploadURL != "https://speed.corp.example/up" {
+		t.Errorf("Expected the speed endpoints to be applied, got %q and %q", d.SpeedDownloadURL, d.SpeedUploadURL)
+	}
+	if d.IperfServer != "iperf.corp.example:5202" || d.MinDownloadMbps != 100 || d.MinUploadMbps != 20 {
+		t.Errorf("Expected the iperf server and floors to be applied, got %+v", d)
+	}
+}
+
+func TestDefaultPaths(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	want := []string{"/home/me/.config/wtfi/config.yaml", "/home/me/.wtfi.yaml"}
+	if got := DefaultPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := DefaultPaths(); got[0] != "/xdg/wtfi/config.yaml" {
+		t.Errorf("Expected XDG_CONFIG_HOME to be honoured, got %v", got)
+	}
+}
+
+func TestValidatePing(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		interval time.Duration
+		root     bool
+		ok       bool
+	}{
+		{"defaults", 0, 0, false, true},
+		{"full mtu", 1472, time.Second, false, true},
+		{"fragmenting size", 1473, 0, false, false},
+		{"no room for timestamp", 4, 0, false, false},
+		{"negative size", -1, 0, false, false},
+		{"minimum interval", 0, 100 * time.Millisecond, false, true},
+		{"fast interval as user", 0, 10 * time.Millisecond, false, false},
+		{"fast interval as root", 0, 10 * time.Millisecond, true, true},
+		{"negative interval", 0, -time.Second, true, false},
+	}
+	for _, tt := range tests {
+		err := validatePing(tt.size, tt.interval, tt.root)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tt.name, tt.ok, err)
+		}
+	}
+}
+
+func TestLoadConfigEmptyFile(t *testing.T) {
+	c, err := LoadConfig(writeConfig(t, ""))
+	if err != nil {
+		t.Fatalf("Expected an empty file to yield defaults, got %v", err)
+	}
+	if !reflect.DeepEqual(c.Diagnostic, Default().Diagnostic) {
+		t.Errorf("Expected defaults, got %+v", c.Diagnostic)
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("Expected error naming the missing file, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+// Package dashboard implements the full-screen interactive wtfi dashboard.
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// historySize is how many samples each panel's graph keeps.
+const historySize = 30
+
+// ErrNotTTY is returned when the dashboard is started without a terminal.
+var ErrNotTTY = errors.New("dashboard requires an interactive terminal; use -w for plain watch mode")
+
+// Run starts the dashboard, re-running steps every interval until the user quits.
+func Run(steps []func() diagnostic.Result, interval time.Duration) error {
+	if !isatty.IsTerminal(os.Stdout.Fd()) || !isatty.IsTerminal(os.Stdin.Fd()) {
+		return ErrNotTTY
+	}
+	_, err := tea.NewProgram(newModel(steps, interval), tea.WithAltScreen()).Run()
+	return err
+}
+
+// RunTUI starts the dashboard on its overview of the Wi-Fi signal, the
+// gateway and WAN latency, DNS timings and the traceroute path; tab
+// switches to the list of every check.
+func RunTUI(steps []func() diagnostic.Result, interval time.Duration) error {
+	if !isatty.IsTerminal(os.Stdout.Fd()) || !isatty.IsTerminal(os.Stdin.Fd()) {
+		return ErrNotTTY
+	}
+	m := newModel(steps, interval)
+	m.overview = true
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// panel is the live state of one check.
+type panel struct {
+	result   diagnostic.Result
+	latency  []float64
+	rssi     []float64
+	expanded bool
+}
+
+type model struct {
+	steps       []func() diagnostic.Result
+	interval    time.Duration
+	panels      []panel
+	cursor      int
+	problemOnly bool
+	running     bool
+	updated     time.Time
+	// overview shows the panels of the key checks instead of the list.
+	overview bool
+	width    int
+}
+
+type resultsMsg []diagnostic.Result
+
+// stepMsg is the result of the step at index i, so panels update as each
+// check finishes rather than once per run.
+type stepMsg struct {
+	i int
+	r diagnostic.Result
+}
+
+type tickMsg struct{}
+
+func newModel(steps []func() diagnostic.Result, interval time.Duration) model {
+	return model{steps: steps, interval: interval, panels: make([]panel, len(steps)), running: true}
+}
+
+func (m model) Init() tea.Cmd {
+	return m.runChecks()
+}
+
+// runChecks starts a run; each step is started when the one before it has
+// reported, so checks never overlap.
+func (m model) runChecks() tea.Cmd {
+	if len(m.steps) == 0 {
+		return func() tea.Msg { return resultsMsg(nil) }
+	}
+	return m.runStep(0)
+}
+
+func (m model) runStep(i int) tea.Cmd {
+	step := m.steps[i]
+	return func() tea.Msg {
+		if i == 0 {
+			diagnostic.ResetCache()
+		}
+		return stepMsg{i, step()}
+	}
+}
+
+// finish ends a run and schedules the next one.
+func (m model) finish() (tea.Model, tea.Cmd) {
+	m.running = false
+	m.updated = time.Now()
+	return m, tea.Tick(m.interval, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case resultsMsg:
+		for i, r := range msg {
+			m.panels[i] = m.panels[i].record(r)
+		}
+		return m.finish()
+	case stepMsg:
+		m.panels[msg.i] = m.panels[msg.i].record(msg.r)
+		if msg.i+1 < len(m.steps) {
+			return m, m.runStep(msg.i + 1)
+		}
+		return m.finish()
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tickMsg:
+		if m.running {
+			return m, nil
+		}
+		m.running = true
+		return m, m.runChecks()
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	visible := m.visible()
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+	case "r":
+		if !m.running {
+			m.running = true
+			return m, m.runChecks()
+		}
+	case "f":
+		m.problemOnly = !m.problemOnly
+		m.cursor = 0
+	case "tab":
+		m.overview = !m.overview
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(visible)-1 {
+			m.cursor++
+		}
+	case "enter", " ":
+		if m.cursor < len(visible) {
+			idx := visible[m.cursor]
+			m.panels[idx].expanded = !m.panels[idx].expanded
+		}
+	}
+	return m, nil
+}
+
+// visible returns the indexes of the panels shown under the current filter.
+func (m model) visible() []int {
+	var idx []int
+	for i, p := range m.panels {
+		if p.result.Name == "" {
+			continue
+		}
+		if m.problemOnly && p.result.Status == diagnostic.StatusOk {
+			continue
+		}
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// record stores r and appends its metrics to the panel's graphs.
+func (p panel) record(r diagnostic.Result) panel {
+	p.result = r
+	p.latency = appendSample(p.latency, float64(r.Latency)/float64(time.Millisecond))
+	if v, err := strconv.Atoi(r.Facts[diagnostic.FactRSSI]); err == nil {
+		p.rssi = appendSample(p.rssi, float64(v))
+	}
+	return p
+}
+
+func appendSample(samples []float64, v float64) []float64 {
+	samples = append(samples, v)
+	if len(samples) > historySize {
+		samples = samples[len(samples)-historySize:]
+	}
+	return samples
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	okStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	warnStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	errStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	faintStyle    = lipgloss.NewStyle().Faint(true)
+	selectedStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("12")).Padding(0, 1)
+	panelStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1)
+)
+
+func (m model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("🚀 wtfi dashboard"))
+	state := "updated " + m.updated.Format(time.TimeOnly)
+	if m.running {
+		state = "running checks..."
+	}
+	b.WriteString(faintStyle.Render("  " + state))
+	b.WriteString("\n")
+
+	if m.overview {
+		b.WriteString(m.overviewView())
+		b.WriteString(faintStyle.Render("tab all checks · r re-run · q quit"))
+		return b.String()
+	}
+	for pos, idx := range m.visible() {
+		style := panelStyle
+		if pos == m.cursor {
+			style = selectedStyle
+		}
+		b.WriteString(style.Render(m.panels[idx].view()))
+		b.WriteString("\n")
+	}
+
+	filter := "all"
+	if m.problemOnly {
+		filter = "problems"
+	}
+	b.WriteString(faintStyle.Render(fmt.Sprintf("↑/↓ select · enter expand · r re-run · f filter (%s) · tab overview · q quit", filter)))
+	return b.String()
+}
+
+func (p panel) view() string {
+	r := p.result
+	status := okStyle.Render("OK")
+	switch r.Status {
+	case diagnostic.StatusWarning:
+		status = warnStyle.Render("WARN")
+	case diagnostic.StatusError:
+		status = errStyle.Render("ERROR")
+	case diagnostic.StatusSkipped:
+		status = faintStyle.Render("SKIP")
+	case diagnostic.StatusOk:
+	}
+
+	value := ""
+	if r.Latency > 0 {
+		value = r.Latency.Round(time.Millisecond).String()
+	}
+	graph := ui.Sparkline(p.latency)
+	if len(p.rssi) > 0 {
+		value = fmt.Sprintf("%.0f dBm", p.rssi[len(p.rssi)-1])
+		graph = ui.Sparkline(p.rssi)
+	}
+
+	line := fmt.Sprintf("%s %-28s %-6s %8s  %s", r.Emoji, r.Name, status, value, graph)
+	if r.Message != "" {
+		line += "\n" + faintStyle.Render(r.Message)
+	}
+	if p.expanded {
+		for _, d := range r.Details {
+			line += "\n" + faintStyle.Render(d)
+		}
+		if r.Fix != "" {
+			line += "\n" + warnStyle.Render("Fix: "+r.Fix)
+		}
+	}
+	return line
+}
@@ -0,0 +1,57 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestModelFilterAndHistory(t *testing.T) {
+	m := newModel(make([]func() diagnostic.Result, 2), time.Second)
+	for i := 0; i < historySize+5; i++ {
+		next, _ := m.Update(resultsMsg{
+			{Name: "Wi-Fi", Status: diagnostic.StatusOk, Facts: map[string]string{diagnostic.FactRSSI: "-60"}},
+			{Name: "Gateway", Status: diagnostic.StatusWarning, Latency: 40 * time.Millisecond},
+		})
+		m = next.(model)
+	}
+
+	if got := len(m.panels[1].latency); got != historySize {
+		t.Errorf("Expected latency history capped at %d, got %d", historySize, got)
+	}
+	if got := len(m.panels[0].rssi); got != historySize {
+		t.Errorf("Expected RSSI history capped at %d, got %d", historySize, got)
+	}
+
+	if got := len(m.visible()); got != 2 {
+		t.Errorf("Expected 2 visible panels, got %d", got)
+	}
+	m.problemOnly = true
+	if got := m.visible(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Expected only the warning panel, got %v", got)
+	}
+}
+
+func TestModelStreamsSteps(t *testing.T) {
+	m := newModel(make([]func() diagnostic.Result, 2), time.Second)
+	next, cmd := m.Update(stepMsg{0, diagnostic.Result{Check: "gateway", Name: "Gateway", Status: diagnostic.StatusOk, Latency: 3 * time.Millisecond}})
+	m = next.(model)
+	if m.panels[0].result.Name != "Gateway" || !m.running || cmd == nil {
+		t.Fatalf("Expected the first result shown while the run goes on, got %+v", m.panels[0].result)
+	}
+	next, _ = m.Update(stepMsg{1, diagnostic.Result{Check: "dns", Name: "DNS Benchmark", Status: diagnostic.StatusWarning, Message: "High DNS latency detected", Details: []string{"System    : 250ms (OK)"}}})
+	m = next.(model)
+	if m.running || m.updated.IsZero() {
+		t.Error("Expected the run to finish with its last step")
+	}
+
+	m.overview = true
+	view := m.View()
+	for _, want := range []string{"Gateway", "3ms", "System    : 250ms", "Health 75/100", "DNS needs attention"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the overview to show %q, got:\n%s", want, view)
+		}
+	}
+}
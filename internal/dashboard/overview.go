@@ -0,0 +1,167 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// defaultWidth lays out the overview until the terminal reports its size.
+const defaultWidth = 100
+
+// maxPanelLines caps the DNS and traceroute panels, whose details grow with
+// the number of resolvers and hops.
+const maxPanelLines = 12
+
+// find returns the panel of the check called name.
+func (m model) find(name string) (panel, bool) {
+	for _, p := range m.panels {
+		if p.result.Check == name {
+			return p, true
+		}
+	}
+	return panel{}, false
+}
+
+// overviewView lays out the Wi-Fi, gateway and WAN panels in a row, DNS and
+// the traceroute below, then the health verdict and a strip with the
+// status of every check.
+func (m model) overviewView() string {
+	width := m.width
+	if width <= 0 {
+		width = defaultWidth
+	}
+	box := func(cols int, title, body string) string {
+		// The border adds two columns to the width of the content.
+		return panelStyle.Width(width/cols - 2).Render(titleStyle.Render(title) + "\n" + body)
+	}
+
+	var b strings.Builder
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+		box(3, "📶 Wi-Fi", m.wifiBody()),
+		box(3, "🏠 Gateway", m.latencyBody("gateway")),
+		box(3, "🌍 Internet", m.latencyBody("wan")),
+	))
+	b.WriteString("\n")
+	dns, path := m.latencyBody("dns"), m.detailsBody("trace")
+	if details := m.detailsBody("dns"); details != "" {
+		dns += "\n" + details
+	}
+	if path == "" {
+		path = m.latencyBody("trace")
+	}
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+		box(2, "🚦 DNS", dns),
+		box(2, "🗺️  Path", path),
+	))
+	b.WriteString("\n")
+
+	var results []diagnostic.Result
+	var strip []string
+	for _, p := range m.panels {
+		if p.result.Name == "" {
+			continue
+		}
+		results = append(results, p.result)
+		strip = append(strip, statusMark(p.result.Status)+" "+ui.BaseName(p.result.Name))
+	}
+	if len(results) > 0 {
+		h := diagnostic.AssessHealth(results)
+		b.WriteString(fmt.Sprintf("%s  %s\n", scoreStyle(h.Score).Render(fmt.Sprintf("Health %d/100", h.Score)), h.Verdict))
+	}
+	b.WriteString(lipgloss.NewStyle().Width(width).Render(strings.Join(strip, "  ")))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// wifiBody shows the network, the signal with its graph and the radio.
+func (m model) wifiBody() string {
+	p, ok := m.find("wifi")
+	if !ok || p.result.Name == "" {
+		return faintStyle.Render("waiting...")
+	}
+	r := p.result
+	if len(p.rssi) == 0 {
+		return statusMark(r.Status) + " " + r.Message
+	}
+	lines := []string{
+		statusMark(r.Status) + " " + r.Facts[diagnostic.FactSSID],
+		fmt.Sprintf("%.0f dBm  %s", p.rssi[len(p.rssi)-1], ui.Sparkline(p.rssi)),
+	}
+	var radio []string
+	if ch := r.Facts[diagnostic.FactChannel]; ch != "" {
+		radio = append(radio, "channel "+ch)
+	}
+	if rate := r.Facts[diagnostic.FactTxRate]; rate != "" {
+		radio = append(radio, rate+" Mbps")
+	}
+	if len(radio) > 0 {
+		lines = append(lines, faintStyle.Render(strings.Join(radio, " · ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// latencyBody shows the latency of the check called name with its graph,
+// and the loss and jitter of its ping burst.
+func (m model) latencyBody(name string) string {
+	p, ok := m.find(name)
+	if !ok || p.result.Name == "" {
+		return faintStyle.Render("waiting...")
+	}
+	r := p.result
+	line := statusMark(r.Status) + " "
+	if r.Latency > 0 {
+		line += r.Latency.Round(100*time.Microsecond).String() + "  " + ui.Sparkline(p.latency)
+	} else {
+		line += r.Message
+	}
+	lines := []string{line}
+	if loss, ok := r.Facts[diagnostic.FactLoss]; ok {
+		lines = append(lines, faintStyle.Render(fmt.Sprintf("loss %s%% · jitter %sms", loss, r.Facts[diagnostic.FactJitter])))
+	}
+	if r.Status != diagnostic.StatusOk && r.Latency > 0 {
+		lines = append(lines, r.Message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// detailsBody lists the details of the check called name, such as the
+// resolver timings or the hops of the path, or returns "" when it has none.
+func (m model) detailsBody(name string) string {
+	p, _ := m.find(name)
+	details := p.result.Details
+	if len(details) == 0 {
+		return ""
+	}
+	if len(details) > maxPanelLines {
+		details = append(details[:maxPanelLines:maxPanelLines], fmt.Sprintf("… %d more", len(details)-maxPanelLines))
+	}
+	return faintStyle.Render(strings.Join(details, "\n"))
+}
+
+func statusMark(s diagnostic.Status) string {
+	switch s {
+	case diagnostic.StatusWarning:
+		return warnStyle.Render("●")
+	case diagnostic.StatusError:
+		return errStyle.Render("●")
+	case diagnostic.StatusSkipped:
+		return faintStyle.Render("○")
+	}
+	return okStyle.Render("●")
+}
+
+func scoreStyle(score int) lipgloss.Style {
+	switch {
+	case score >= 90:
+		return okStyle
+	case score >= 60:
+		return warnStyle
+	}
+	return errStyle
+}
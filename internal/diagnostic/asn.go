@@ -0,0 +1,135 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// Team Cymru's IP-to-ASN service answers TXT queries over plain DNS, so no
+// database has to be bundled.
+const (
+	cymruOriginZone = "origin.asn.cymru.com"
+	cymruASNZone    = "asn.cymru.com"
+)
+
+// lookupTXT resolves TXT records for the ASN lookups; tests replace it.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// annotateASNs fills in the origin AS number and name of every public hop,
+// within Config.Timeout. Hops that cannot be looked up are left blank.
+func annotateASNs(ctx context.Context, hops []traceHop) {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	names := map[string]string{}
+	asName := func(asn string) string {
+		mu.Lock()
+		name, ok := names[asn]
+		mu.Unlock()
+		if !ok {
+			name, _ = lookupASName(ctx, asn)
+			mu.Lock()
+			names[asn] = name
+			mu.Unlock()
+		}
+		return name
+	}
+
+	var wg sync.WaitGroup
+	for i := range hops {
+		addr, err := netip.ParseAddr(hops[i].Router)
+		if err != nil || !addr.Is4() || !addr.IsGlobalUnicast() || addr.IsPrivate() || cgnatPrefix.Contains(addr) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			asn, err := lookupOriginASN(ctx, addr)
+			if err != nil {
+				return
+			}
+			hops[i].ASN = asn
+			hops[i].ASName = asName(asn)
+		}()
+	}
+	wg.Wait()
+}
+
+// lookupOriginASN returns the number of the AS announcing addr.
+func lookupOriginASN(ctx context.Context, addr netip.Addr) (string, error) {
+	asn, _, err := lookupOrigin(ctx, addr)
+	return asn, err
+}
+
+// lookupOrigin returns the number of the AS announcing addr and the country
+// its block is registered in. The answer looks like
+// "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11"; when several ASes
+// announce the prefix the first is used.
+func lookupOrigin(ctx context.Context, addr netip.Addr) (asn, country string, err error) {
+	b := addr.As4()
+	name := fmt.Sprintf("%d.%d.%d.%d.%s", b[3], b[2], b[1], b[0], cymruOriginZone)
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	for _, r := range records {
+		if asns := strings.Fields(cymruField(r, 0)); len(asns) > 0 {
+			return asns[0], cymruField(r, 2), nil
+		}
+	}
+	return "", "", fmt.Errorf("no origin AS for %s", addr)
+}
+
+// lookupASName returns the registered name of AS asn, such as
+// "CLOUDFLARENET", from an answer like
+// "13335 | US | arin | 2010-07-14 | CLOUDFLARENET - Cloudflare, Inc., US".
+func lookupASName(ctx context.Context, asn string) (string, error) {
+	records, err := lookupTXT(ctx, "AS"+asn+"."+cymruASNZone)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range records {
+		if desc := cymruField(r, 4); desc != "" {
+			name, _, _ := strings.Cut(desc, " - ")
+			return strings.TrimSuffix(name, ","), nil
+		}
+	}
+	return "", fmt.Errorf("no name for AS%s", asn)
+}
+
+// cymruField returns the i-th "|"-separated field of a Team Cymru answer.
+func cymruField(record string, i int) string {
+	fields := strings.Split(record, "|")
+	if i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// asPath lists the ASes the hops cross, in order, such as
+// "AS7922 COMCAST-7922 → AS13335 CLOUDFLARENET".
+func asPath(hops []traceHop) string {
+	var path []string
+	last := ""
+	for _, h := range hops {
+		if h.ASN == "" || h.ASN == last {
+			continue
+		}
+		last = h.ASN
+		path = append(path, formatAS(h))
+	}
+	return strings.Join(path, " → ")
+}
+
+// formatAS renders a hop's AS as "AS13335 CLOUDFLARENET".
+func formatAS(h traceHop) string {
+	if h.ASName == "" {
+		return "AS" + h.ASN
+	}
+	return "AS" + h.ASN + " " + h.ASName
+}
@@ -0,0 +1,59 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// withTXT answers TXT lookups from records for the duration of the test.
+func withTXT(t *testing.T, records map[string]string) {
+	t.Helper()
+	prev := lookupTXT
+	lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return []string{r}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookupTXT = prev })
+}
+
+func TestAnnotateASNs(t *testing.T) {
+	withTXT(t, map[string]string{
+		"1.113.0.203.origin.asn.cymru.com": "64500 | 203.0.113.0/24 | US | arin | 2001-01-01",
+		"2.113.0.203.origin.asn.cymru.com": "64500 | 203.0.113.0/24 | US | arin | 2001-01-01",
+		"1.1.1.1.origin.asn.cymru.com":     "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11",
+		"AS64500.asn.cymru.com":            "64500 | US | arin | 2001-01-01 | EXAMPLE-ISP - Example ISP, Inc., US",
+		"AS13335.asn.cymru.com":            "13335 | US | arin | 2010-07-14 | CLOUDFLARENET - Cloudflare, Inc., US",
+	})
+	hops := []traceHop{
+		{Router: "192.168.1.1"},
+		{Router: "100.64.0.1"},
+		{Router: "203.0.113.1"},
+		{},
+		{Router: "203.0.113.2"},
+		{Router: "1.1.1.1"},
+	}
+	annotateASNs(context.Background(), hops)
+
+	if hops[0].ASN != "" || hops[1].ASN != "" {
+		t.Errorf("Expected private and CGNAT hops to be skipped, got %+v", hops[:2])
+	}
+	if hops[2].ASN != "64500" || hops[2].ASName != "EXAMPLE-ISP" || hops[5].ASName != "CLOUDFLARENET" {
+		t.Errorf("Unexpected annotations %+v", hops)
+	}
+	if got, want := asPath(hops), "AS64500 EXAMPLE-ISP → AS13335 CLOUDFLARENET"; got != want {
+		t.Errorf("Expected path %q, got %q", want, got)
+	}
+}
+
+func TestCymruField(t *testing.T) {
+	r := "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11"
+	if got := cymruField(r, 1); got != "1.1.1.0/24" {
+		t.Errorf("Expected the prefix, got %q", got)
+	}
+	if got := cymruField(r, 9); got != "" {
+		t.Errorf("Expected no field, got %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// bufferbloatSamples is how many pings each phase takes, at sampleInterval.
+	bufferbloatSamples = 20
+	// bufferbloatStreams transfers run at once to saturate the link.
+	bufferbloatStreams = 4
+	// bufferbloatRampUp lets the transfers fill the queues before sampling.
+	bufferbloatRampUp = 500 * time.Millisecond
+	// bufferbloatWarning is the added latency from grade C on.
+	bufferbloatWarning = 60 * time.Millisecond
+)
+
+// FactBufferbloatGrade is the Result.Facts key of the letter grade.
+const FactBufferbloatGrade = "bufferbloat_grade"
+
+// bufferbloatGrades map the latency added under load onto Waveform's
+// letter grades; anything above the last limit is an F.
+var bufferbloatGrades = []struct {
+	limit time.Duration
+	grade string
+}{
+	{5 * time.Millisecond, "A+"},
+	{30 * time.Millisecond, "A"},
+	{bufferbloatWarning, "B"},
+	{200 * time.Millisecond, "C"},
+	{400 * time.Millisecond, "D"},
+}
+
+// bufferbloatGrade returns the letter grade for added latency.
+func bufferbloatGrade(added time.Duration) string {
+	for _, g := range bufferbloatGrades {
+		if added <= g.limit {
+			return g.grade
+		}
+	}
+	return "F"
+}
+
+// CheckBufferbloat compares the median WAN latency at rest with the median
+// while the download and then the upload is saturated. A big difference
+// means the router queues traffic instead of pacing it, which is why
+// "ping is fine but video calls stutter".
+func CheckBufferbloat(ctx context.Context) Result {
+	res := Result{Name: "Bufferbloat", Emoji: "🌊", Status: StatusOk}
+	c := activeConfig(ctx)
+
+	idle, err := sampleLatency(ctx, c.WANHost, bufferbloatSamples)
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "Could not measure idle latency to " + c.WANHost
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	details := []string{"Idle: " + idle.String()}
+
+	phases := []struct {
+		name string
+		load func(context.Context) error
+	}{
+		{"Download", func(ctx context.Context) error {
+			_, err := measureDownload(ctx, c.SpeedDownloadURL, c.DownloadBytes, c.SpeedTimeout)
+			return err
+		}},
+		{"Upload", func(ctx context.Context) error {
+			_, err := measureUpload(ctx, c.SpeedUploadURL, c.UploadBytes, c.SpeedTimeout)
+			return err
+		}},
+	}
+	var added time.Duration
+	measured := false
+	for _, ph := range phases {
+		loaded, err := latencyUnderLoad(ctx, c.WANHost, ph.load)
+		if err != nil {
+			details = append(details, fmt.Sprintf("%s load: failed (%v)", ph.name, err))
+			continue
+		}
+		measured = true
+		delta := max(loaded.P50-idle.P50, 0)
+		added = max(added, delta)
+		details = append(details, fmt.Sprintf("%s load: %s (+%v)", ph.name, loaded, delta.Round(time.Millisecond)))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	if !measured {
+		res.Status = StatusError
+		res.Message = "Speed test endpoint unreachable"
+		res.Reason = ReasonUnreachable
+		return res
+	}
+
+	grade := bufferbloatGrade(added)
+	res.Latency = added
+	res.Facts = map[string]string{FactBufferbloatGrade: grade}
+	res.Message = fmt.Sprintf("Grade %s (+%v latency under load)", grade, added.Round(time.Millisecond))
+	if added > bufferbloatWarning {
+		res.Status = StatusWarning
+		res.Reason = ReasonBufferbloat
+		res.Fix = "Enable SQM (fq_codel or CAKE) on the router, or shape traffic slightly below your line rate."
+	}
+	return res
+}
+
+// latencyUnderLoad samples the latency to host while load runs in
+// bufferbloatStreams parallel loops. It fails if the load itself fails,
+// since the samples would then describe an idle link.
+func latencyUnderLoad(ctx context.Context, host string, load func(context.Context) error) (LatencyPercentiles, error) {
+	loadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, bufferbloatStreams)
+	for range bufferbloatStreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for loadCtx.Err() == nil {
+				if err := load(loadCtx); err != nil && loadCtx.Err() == nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	select {
+	case <-time.After(bufferbloatRampUp):
+	case <-loadCtx.Done():
+	}
+	p, err := sampleLatency(loadCtx, host, bufferbloatSamples)
+	cancel()
+	wg.Wait()
+	close(errs)
+	if errLoad, ok := <-errs; ok {
+		return LatencyPercentiles{}, errLoad
+	}
+	return p, err
+}
@@ -0,0 +1,61 @@
+package diagnostic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferbloatGrade(t *testing.T) {
+	tests := []struct {
+		added time.Duration
+		want  string
+	}{
+		{0, "A+"},
+		{5 * time.Millisecond, "A+"},
+		{12 * time.Millisecond, "A"},
+		{60 * time.Millisecond, "B"},
+		{150 * time.Millisecond, "C"},
+		{400 * time.Millisecond, "D"},
+		{time.Second, "F"},
+	}
+	for _, tt := range tests {
+		if got := bufferbloatGrade(tt.added); got != tt.want {
+			t.Errorf("bufferbloatGrade(%v) = %s, want %s", tt.added, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBufferbloat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_, _ = io.Copy(io.Discard, r.Body)
+			return
+		}
+		_, _ = w.Write(make([]byte, 1<<10))
+	}))
+	defer srv.Close()
+	c := DefaultConfig()
+	c.SpeedDownloadURL, c.SpeedUploadURL = srv.URL+"/__down", srv.URL+"/__up"
+	c.DownloadBytes, c.UploadBytes = 1<<10, 1<<10
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"ping -c 20 -i 0.1 1.1.1.1": "64 bytes from 1.1.1.1: icmp_seq=0 ttl=58 time=9.0 ms\n" +
+			"64 bytes from 1.1.1.1: icmp_seq=1 ttl=58 time=11.0 ms\n",
+	}})
+
+	res := CheckBufferbloat(context.Background())
+	if res.Status != StatusOk || res.Facts[FactBufferbloatGrade] != "A+" {
+		t.Fatalf("Expected grade A+, got %v/%q (%s)", res.Status, res.Facts[FactBufferbloatGrade], res.Message)
+	}
+	if !strings.Contains(strings.Join(res.Details, "\n"), "Upload load:") {
+		t.Errorf("Expected an upload phase in details, got %q", res.Details)
+	}
+}
@@ -0,0 +1,102 @@
+package diagnostic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// routeCache memoizes `route -n get default` (or its -inet6 form) so that
+// the checks sharing interface and gateway discovery spawn the command once
+// per run. With Config.Interface set it holds that interface's default
+// route instead, so every check follows the chosen interface.
+type routeCache struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	inet6     bool
+	fetchedAt time.Time
+	output    string
+	err       error
+	valid     bool
+}
+
+var (
+	defaultRoute  = &routeCache{now: time.Now}
+	defaultRoute6 = &routeCache{now: time.Now, inet6: true}
+)
+
+// get returns the cached route output, refreshing it once Config.CacheTTL elapses.
+// The lock is held while fetching so concurrent callers share a single spawn.
+// A lookup cut short by ctx is not cached.
+func (c *routeCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid && c.now().Sub(c.fetchedAt) < activeConfig(ctx).CacheTTL {
+		return c.output, c.err
+	}
+	out, err := runPlatformCommand(ctx, activePlatform().routeCommand(c.inet6, activeConfig(ctx).Interface))
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	c.output, c.err = string(out), err
+	c.fetchedAt, c.valid = c.now(), true
+	return c.output, c.err
+}
+
+func (c *routeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid = false
+}
+
+// wifiCache memoizes the Wi-Fi command's output per interface, so the
+// checks that need the current network (Wi-Fi, its security, evil twins
+// and the gateway MAC record) run system_profiler, which takes seconds,
+// once per run.
+type wifiCache struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]wifiCacheEntry
+}
+
+type wifiCacheEntry struct {
+	fetchedAt time.Time
+	output    string
+	err       error
+}
+
+var wifiInfo = &wifiCache{now: time.Now}
+
+// get returns the cached Wi-Fi output of iface, refreshing it once
+// Config.CacheTTL elapses. A lookup cut short by ctx is not cached.
+func (c *wifiCache) get(ctx context.Context, iface string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[iface]; ok && c.now().Sub(e.fetchedAt) < activeConfig(ctx).CacheTTL {
+		return e.output, e.err
+	}
+	out, err := runPlatformCommand(ctx, activePlatform().wifiCommand(iface))
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if c.entries == nil {
+		c.entries = map[string]wifiCacheEntry{}
+	}
+	c.entries[iface] = wifiCacheEntry{fetchedAt: c.now(), output: string(out), err: err}
+	return string(out), err
+}
+
+func (c *wifiCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// ResetCache discards cached discovery results. Call it at the start of each run.
+func ResetCache() {
+	defaultRoute.invalidate()
+	defaultRoute6.invalidate()
+	wifiInfo.invalidate()
+}
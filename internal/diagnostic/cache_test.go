@@ -0,0 +1,81 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRouteCache(t *testing.T) {
+	fake := &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 10.0.0.1\n  interface: en0\n",
+	}}
+	withRunner(t, fake)
+
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := defaultRoute
+	defaultRoute = &routeCache{now: func() time.Time { return clock }}
+	t.Cleanup(func() { defaultRoute = prev })
+
+	if _, err := getPrimaryInterface(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gw, err := getGatewayIP(context.Background()); err != nil || gw != "10.0.0.1" {
+		t.Fatalf("Expected gateway 10.0.0.1, got %s (%v)", gw, err)
+	}
+	if len(fake.calls) != 1 {
+		t.Fatalf("Expected route to run once per run, got %d calls", len(fake.calls))
+	}
+
+	clock = clock.Add(DefaultConfig().CacheTTL)
+	if _, err := getPrimaryInterface(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(fake.calls) != 2 {
+		t.Errorf("Expected refresh after TTL, got %d calls", len(fake.calls))
+	}
+
+	ResetCache()
+	if _, err := getGatewayIP(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(fake.calls) != 3 {
+		t.Errorf("Expected refresh after ResetCache, got %d calls", len(fake.calls))
+	}
+}
+
+func TestWiFiCache(t *testing.T) {
+	fake := &fakeRunner{outputs: map[string]string{
+		"system_profiler SPAirPortDataType": "Wi-Fi:\n",
+	}}
+	withRunner(t, fake)
+
+	for range 3 {
+		if _, err := readWiFiLink(context.Background(), "en0", false); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if len(fake.calls) != 1 {
+		t.Errorf("Expected system_profiler to run once per run, got %d calls", len(fake.calls))
+	}
+	ResetCache()
+	if _, err := readWiFiLink(context.Background(), "en0", true); err != nil || len(fake.calls) != 2 {
+		t.Errorf("Expected a new run to read it again, got %d calls (%v)", len(fake.calls), err)
+	}
+}
+
+func TestRouteCacheSkipsCancelledLookups(t *testing.T) {
+	fake := &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 10.0.0.1\n  interface: en0\n",
+	}}
+	withRunner(t, fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := getGatewayIP(ctx); err == nil {
+		t.Fatal("Expected a cancelled lookup to fail")
+	}
+	if gw, err := getGatewayIP(context.Background()); err != nil || gw != "10.0.0.1" {
+		t.Errorf("Expected the cancellation not to be cached, got %s (%v)", gw, err)
+	}
+}
@@ -0,0 +1,194 @@
+package diagnostic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Config holds the tunable thresholds and targets shared by the checks.
+type Config struct {
+	// Timeout bounds individual network operations such as dials and handshakes.
+	Timeout time.Duration
+	// TLSHost is the host probed by CheckTLS when none is given.
+	TLSHost string
+	// TLSInspectHosts are the public sites whose certificate issuers
+	// CheckTLSInterception compares with the public CAs.
+	TLSInspectHosts []string
+	// QUICHost is the HTTP/3 site CheckQUIC handshakes with.
+	QUICHost string
+	// NTPServer is the time server CheckTimeSync queries; empty uses the
+	// system's.
+	NTPServer string
+	// MaxClockSkew is the clock offset above which CheckTimeSync warns.
+	MaxClockSkew time.Duration
+	// Services are the endpoints ("host:port" or an http(s) URL) whose
+	// reachability CheckServices tests.
+	Services []string
+	// Interface pins interface and gateway discovery to this interface
+	// (--interface) instead of the one carrying the default route.
+	Interface string
+	// CacheTTL is how long default route and Wi-Fi discovery are reused
+	// within a run.
+	CacheTTL time.Duration
+	// MinSignalQuality is the Wi-Fi signal quality (0-100%) below which we warn.
+	MinSignalQuality int
+	// FilterProbeHost is the known-good host used to detect content filtering.
+	FilterProbeHost string
+	// DownloadBytes and UploadBytes size the CheckThroughput transfers.
+	DownloadBytes int64
+	UploadBytes   int64
+	// SpeedTimeout bounds each throughput transfer.
+	SpeedTimeout time.Duration
+	// MinUploadRatio is the upload/download ratio below which upload is
+	// considered starved. Cable and DSL are commonly 1:10 by design.
+	MinUploadRatio float64
+	// MinDownloadMbps and MinUploadMbps are the speeds below which
+	// CheckThroughput warns; zero disables them.
+	MinDownloadMbps float64
+	MinUploadMbps   float64
+	// SpeedDownloadURL and SpeedUploadURL are the HTTP speed test endpoints.
+	// The download size is sent as the "bytes" query parameter.
+	SpeedDownloadURL string
+	SpeedUploadURL   string
+	// IperfServer ("host" or "host:port") makes CheckThroughput run iperf3
+	// against it instead of the HTTP endpoints.
+	IperfServer string
+	// TraceMaxTTL, TraceProbes and TraceConcurrency shape FastTraceroute:
+	// how many hops to map, how many probes per hop and, when it falls back
+	// to pings, how many run at once.
+	TraceMaxTTL      int
+	TraceProbes      int
+	TraceConcurrency int
+	// TraceMode is the traceroute probe protocol: TraceICMP, TraceUDP or
+	// TraceTCP (to port 443).
+	TraceMode string
+	// TxPower (RSSI at 1 m, in dBm) and PathLossExponent feed the rough
+	// distance-to-AP estimate; both depend on the AP and the building.
+	TxPower          int
+	PathLossExponent int
+	// PingSize (payload bytes) and PingInterval are passed to ping as -s and
+	// -i, for the single latency ping and the loss burst; zero keeps ping's
+	// own default size and the burst's 0.2s spacing.
+	PingSize     int
+	PingInterval time.Duration
+	// LatencySamples is how many pings CheckL3WAN sends to report latency
+	// percentiles; zero skips the sampling window.
+	LatencySamples int
+	// PingBurst is how many echo requests the gateway and WAN checks send,
+	// PingInterval or 0.2s apart, to measure loss and jitter.
+	PingBurst int
+	// MaxLoss (percent) and MaxJitter are the loss and jitter of a burst
+	// above which a warning is raised.
+	MaxLoss   float64
+	MaxJitter time.Duration
+	// WANHost is the IPv4 address pinged and traced toward as "the internet",
+	// and connected to on port 443 when ICMP is blocked.
+	WANHost string
+	// CaptivePortalURL is fetched by CheckCaptivePortal; a page without the
+	// word "Success" is taken for a login page.
+	CaptivePortalURL string
+	// DNSResolvers are the servers ("host" or "host:port") benchmarked
+	// against the system resolver; empty keeps Google and Cloudflare.
+	DNSResolvers []string
+	// MinRSSI (dBm) flags a weak Wi-Fi signal regardless of
+	// MinSignalQuality; zero disables it.
+	MinRSSI int
+	// DNSSlow and WANSlow are the latencies above which DNS resolution and
+	// WAN round trips are reported as a warning.
+	DNSSlow time.Duration
+	WANSlow time.Duration
+	// KnownAPsFile is where CheckEvilTwin records the access points seen
+	// for each SSID; empty disables the check.
+	KnownAPsFile string
+	// KnownGatewaysFile is where CheckL3Gateway records the MAC address of
+	// each network's gateway; empty disables the comparison.
+	KnownGatewaysFile string
+}
+
+// Limits for PingSize and PingInterval. The largest payload that fits a
+// StandardMTU unfragmented is the MTU minus the 20-byte IP and 8-byte ICMP
+// headers; payloads under 8 bytes leave no room for ping's timestamp, so no
+// RTT is reported. macOS refuses intervals under 0.1s unless run as root.
+const (
+	StandardMTU     = 1500
+	MinPingSize     = 8
+	MaxPingSize     = StandardMTU - 20 - 8
+	MinPingInterval = 100 * time.Millisecond
+)
+
+// DefaultConfig returns the built-in configuration.
+func DefaultConfig() Config {
+	return Config{
+		Timeout: 3 * time.Second,
+		TLSHost: "cloudflare.com",
+
+		TLSInspectHosts: []string{"apple.com", "google.com"},
+		QUICHost:        "cloudflare.com",
+		MaxClockSkew:    5 * time.Second,
+		CacheTTL:        30 * time.Second,
+
+		MinSignalQuality: 30,
+		FilterProbeHost:  "example.com",
+
+		DownloadBytes:  10 << 20,
+		UploadBytes:    2 << 20,
+		SpeedTimeout:   15 * time.Second,
+		MinUploadRatio: 0.05,
+
+		SpeedDownloadURL: speedDownloadURL,
+		SpeedUploadURL:   speedUploadURL,
+
+		TraceMaxTTL:      10,
+		TraceProbes:      3,
+		TraceConcurrency: 10,
+		TraceMode:        TraceICMP,
+
+		TxPower:          -40,
+		PathLossExponent: 3,
+
+		PingBurst: 10,
+		MaxLoss:   1,
+		MaxJitter: 30 * time.Millisecond,
+
+		WANHost:           wanTargetIPv4,
+		CaptivePortalURL:  captivePortalURL,
+		DNSSlow:           dnsSlowThreshold,
+		WANSlow:           wanSlowThreshold,
+		KnownAPsFile:      dataFile("known_aps.json"),
+		KnownGatewaysFile: dataFile("known_gateways.json"),
+	}
+}
+
+var (
+	cfgMu sync.RWMutex
+	cfg   = DefaultConfig()
+)
+
+// SetConfig replaces the configuration used by subsequent checks.
+func SetConfig(c Config) {
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+	cfg = c
+}
+
+// configKey is the context key WithConfig stores a Config under.
+type configKey struct{}
+
+// WithConfig returns a copy of ctx under which checks use c rather than
+// the configuration set with SetConfig, so runs with different settings
+// can share a process.
+func WithConfig(ctx context.Context, c Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// activeConfig returns the configuration ctx carries, or else a snapshot
+// of the one set with SetConfig.
+func activeConfig(ctx context.Context) Config {
+	if c, ok := ctx.Value(configKey{}).(Config); ok {
+		return c
+	}
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return cfg
+}
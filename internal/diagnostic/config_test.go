@@ -0,0 +1,19 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithConfig(t *testing.T) {
+	c := DefaultConfig()
+	c.Timeout = 42 * time.Second
+	ctx := WithConfig(context.Background(), c)
+	if got := activeConfig(ctx).Timeout; got != c.Timeout {
+		t.Errorf("Expected the context's timeout, got %v", got)
+	}
+	if got := activeConfig(context.Background()).Timeout; got == c.Timeout {
+		t.Errorf("Expected a plain context to keep the process-wide config, got %v", got)
+	}
+}
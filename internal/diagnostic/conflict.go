@@ -0,0 +1,177 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	reArpEntry  = regexp.MustCompile(`\((\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})\) at ([0-9a-fA-F]{1,2}(?::[0-9a-fA-F]{1,2}){5})`)
+	reArpingMAC = regexp.MustCompile(`from ([0-9a-fA-F]{1,2}(?::[0-9a-fA-F]{1,2}){5})`)
+)
+
+// CheckIPConflict detects whether another device on the LAN claims this machine's IP.
+func CheckIPConflict(ctx context.Context) Result {
+	res := Result{Name: "IP Conflict", Emoji: "👥", Status: StatusOk}
+
+	ifaceName, err := getPrimaryInterface(ctx)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "No default route found"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+
+	ip, mac, err := interfaceAddrs(ifaceName)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = fmt.Sprintf("Could not read addresses for %s", ifaceName)
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+
+	var offenders []string
+	method := "arp -a"
+	if errLook := activeRunner().LookPath("arping"); errLook == nil {
+		out, errPing := runCommand(ctx, "arping", "-d", "-c", "2", "-i", ifaceName, ip)
+		if arpingFailed(out, errPing) {
+			res.Status = StatusError
+			res.Message = "Failed to run arping"
+			res.Reason = ReasonProbeFailed
+			return res
+		}
+		offenders = findConflictingMACs(parseArpingMACs(string(out)), mac)
+		method = "arping"
+	} else {
+		out, errArp := runPlatformCommand(ctx, activePlatform().neighborTableCommand())
+		if r, ok := missingToolResult(res.Name, res.Emoji, errArp); ok {
+			return r
+		}
+		if errArp != nil {
+			res.Status = StatusError
+			res.Message = "Failed to read ARP table"
+			res.Reason = ReasonProbeFailed
+			return res
+		}
+		offenders = findConflictingMACs(activePlatform().parseNeighbors(string(out))[ip], mac)
+	}
+
+	details := []string{
+		fmt.Sprintf("Address: %s (%s)", ip, mac),
+		"Method: " + method,
+	}
+	for _, m := range offenders {
+		details = append(details, "Conflicting MAC: "+m)
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	if len(offenders) > 0 {
+		res.Status = StatusError
+		res.Message = fmt.Sprintf("%s is also claimed by %s", ip, strings.Join(offenders, ", "))
+		res.Reason = ReasonIPConflict
+		res.Fix = "Renew your DHCP lease or set a static reservation on the router."
+		res.FixCommand = dhcpRenewCommand(ctx, ifaceName)
+		return res
+	}
+	res.Message = "No duplicate address detected"
+	return res
+}
+
+// interfaceAddrs returns the first IPv4 address and the MAC of the named interface.
+func interfaceAddrs(name string) (string, string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", "", err
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.String(), normalizeMAC(iface.HardwareAddr.String()), nil
+		}
+	}
+	return "", "", fmt.Errorf("no ipv4 address on %s", name)
+}
+
+// parseArpTable maps each IPv4 address in `arp -a` output to the MACs claiming it.
+func parseArpTable(output string) map[string][]string {
+	table := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		m := reArpEntry.FindStringSubmatch(line)
+		if len(m) < 3 {
+			continue
+		}
+		table[m[1]] = appendUnique(table[m[1]], normalizeMAC(m[2]))
+	}
+	return table
+}
+
+// parseArpMAC returns the MAC that `arp -n` reports for ip, or "" if unresolved.
+func parseArpMAC(output, ip string) string {
+	if macs := parseArpTable(output)[ip]; len(macs) > 0 {
+		return macs[0]
+	}
+	return ""
+}
+
+// parseArpingMACs extracts the distinct MACs that answered an arping probe.
+func parseArpingMACs(output string) []string {
+	var macs []string
+	for _, m := range reArpingMAC.FindAllStringSubmatch(output, -1) {
+		macs = appendUnique(macs, normalizeMAC(m[1]))
+	}
+	return macs
+}
+
+// arpingFailed reports whether an arping run says nothing about the LAN.
+// arping exits non-zero when duplicates answer or nobody does, so an exit
+// status only counts as a failure when the run never printed its summary.
+func arpingFailed(out []byte, err error) bool {
+	if err == nil {
+		return false
+	}
+	var exitErr *exec.ExitError
+	return !errors.As(err, &exitErr) || !rePingCount.Match(out)
+}
+
+// findConflictingMACs returns the MACs that are not our own.
+func findConflictingMACs(macs []string, own string) []string {
+	own = normalizeMAC(own)
+	var offenders []string
+	for _, m := range macs {
+		if m != own {
+			offenders = append(offenders, m)
+		}
+	}
+	return offenders
+}
+
+// normalizeMAC lowercases a MAC and zero-pads each octet (macOS prints "0:1a:...").
+func normalizeMAC(mac string) string {
+	parts := strings.Split(strings.ToLower(mac), ":")
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,80 @@
+package diagnostic
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestParseArpTable(t *testing.T) {
+	output := `? (192.168.1.1) at 0:1a:2b:3c:4d:5e on en0 ifscope [ethernet]
+? (192.168.1.23) at a4:83:e7:1:2:3 on en0 ifscope permanent [ethernet]
+? (192.168.1.23) at 8c:85:90:aa:bb:cc on en0 ifscope [ethernet]
+? (192.168.1.50) (incomplete) on en0 ifscope [ethernet]
+`
+	table := parseArpTable(output)
+	if got := table["192.168.1.1"]; len(got) != 1 || got[0] != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("Expected normalized gateway MAC, got %v", got)
+	}
+	if got := table["192.168.1.23"]; len(got) != 2 {
+		t.Errorf("Expected 2 MACs for 192.168.1.23, got %v", got)
+	}
+	if _, ok := table["192.168.1.50"]; ok {
+		t.Error("Expected incomplete entry to be skipped")
+	}
+
+	offenders := findConflictingMACs(table["192.168.1.23"], "A4:83:E7:01:02:03")
+	if len(offenders) != 1 || offenders[0] != "8c:85:90:aa:bb:cc" {
+		t.Errorf("Expected offending MAC 8c:85:90:aa:bb:cc, got %v", offenders)
+	}
+}
+
+func TestParseArpingMACs(t *testing.T) {
+	output := `ARPING 192.168.1.23
+60 bytes from 8c:85:90:aa:bb:cc (192.168.1.23): index=0 time=1.201 msec
+60 bytes from 8c:85:90:aa:bb:cc (192.168.1.23): index=1 time=1.034 msec
+
+--- 192.168.1.23 statistics ---
+2 packets transmitted, 2 packets received,   0% unanswered (0 extra)
+`
+	macs := parseArpingMACs(output)
+	if len(macs) != 1 || macs[0] != "8c:85:90:aa:bb:cc" {
+		t.Fatalf("Expected one MAC, got %v", macs)
+	}
+	if offenders := findConflictingMACs(macs, "a4:83:e7:01:02:03"); len(offenders) != 1 {
+		t.Errorf("Expected conflict, got %v", offenders)
+	}
+	if offenders := findConflictingMACs(nil, "a4:83:e7:01:02:03"); len(offenders) != 0 {
+		t.Errorf("Expected no conflict without replies, got %v", offenders)
+	}
+}
+
+func TestParseArpMAC(t *testing.T) {
+	output := "? (192.168.1.1) at 0:1a:2b:3c:4d:5e on en0 ifscope [ethernet]\n"
+	if got := parseArpMAC(output, "192.168.1.1"); got != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("Expected 00:1a:2b:3c:4d:5e, got %q", got)
+	}
+	if got := parseArpMAC("? (192.168.1.1) (incomplete) on en0 ifscope [ethernet]\n", "192.168.1.1"); got != "" {
+		t.Errorf("Expected no MAC for an incomplete entry, got %q", got)
+	}
+}
+
+func TestArpingFailed(t *testing.T) {
+	summary := []byte("2 packets transmitted, 2 packets received,   0% unanswered (0 extra)\n")
+	tests := []struct {
+		name string
+		out  []byte
+		err  error
+		want bool
+	}{
+		{"clean", summary, nil, false},
+		{"duplicates answered", summary, &exec.ExitError{}, false},
+		{"no summary", []byte("arping: libnet_init(LIBNET_LINK, en0): permission denied\n"), &exec.ExitError{}, true},
+		{"did not start", nil, context.DeadlineExceeded, true},
+	}
+	for _, tt := range tests {
+		if got := arpingFailed(tt.out, tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
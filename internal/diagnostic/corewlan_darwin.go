@@ -0,0 +1,98 @@
+//go:build darwin && cgo && corewlan
+
+package diagnostic
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework CoreWLAN -framework Foundation
+#import <CoreWLAN/CoreWLAN.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct {
+	int ok;
+	char ssid[256];
+	char bssid[32];
+	int rssi;
+	int noise;
+	double txRate;
+	int channel;
+	int band;
+	int width;
+	int phyMode;
+	long security;
+} wtfiWiFi;
+
+static void wtfiCopy(char *dst, size_t n, NSString *s) {
+	dst[0] = 0;
+	if (s != nil) {
+		strlcpy(dst, [s UTF8String], n);
+	}
+}
+
+static wtfiWiFi wtfiCurrentWiFi(const char *name) {
+	wtfiWiFi w;
+	memset(&w, 0, sizeof(w));
+	@autoreleasepool {
+		CWInterface *iface = [[CWWiFiClient sharedWiFiClient] interfaceWithName:[NSString stringWithUTF8String:name]];
+		if (iface != nil && iface.powerOn) {
+			wtfiCopy(w.ssid, sizeof(w.ssid), iface.ssid);
+			wtfiCopy(w.bssid, sizeof(w.bssid), iface.bssid);
+			w.rssi = (int)iface.rssiValue;
+			w.noise = (int)iface.noiseMeasurement;
+			w.txRate = iface.transmitRate;
+			CWChannel *ch = iface.wlanChannel;
+			if (ch != nil) {
+				w.channel = (int)ch.channelNumber;
+				w.band = (int)ch.channelBand;
+				w.width = (int)ch.channelWidth;
+			}
+			w.phyMode = (int)iface.activePHYMode;
+			w.security = (long)iface.security;
+			w.ok = 1;
+		}
+	}
+	return w;
+}
+*/
+import "C"
+
+import "unsafe"
+
+// Built with -tags corewlan, wtfi reads Wi-Fi telemetry from CoreWLAN in
+// microseconds instead of running system_profiler, which takes seconds and
+// whose text format changes between macOS releases.
+func init() {
+	nativeWiFi = coreWLANLink
+}
+
+// coreWLANLink reads the association of iface from CoreWLAN. CoreWLAN has
+// no MCS index, and without Location Services permission macOS withholds
+// the SSID and BSSID; then system_profiler, which still reports them, is
+// used instead.
+func coreWLANLink(iface string, verbose bool) (wifiLink, bool) {
+	name := C.CString(iface)
+	defer C.free(unsafe.Pointer(name))
+	w := C.wtfiCurrentWiFi(name)
+	if w.ok == 0 || w.rssi == 0 {
+		return wifiLink{}, false
+	}
+	link := wifiLink{
+		SSID:     C.GoString(&w.ssid[0]),
+		BSSID:    normalizeMAC(C.GoString(&w.bssid[0])),
+		RSSI:     int(w.rssi),
+		Noise:    int(w.noise),
+		TxRate:   float64(w.txRate),
+		Channel:  corewlanChannel(int(w.channel), int(w.band), int(w.width)),
+		PHYMode:  corewlanName(corewlanPHYModes, int(w.phyMode)),
+		MCS:      -1,
+		Security: corewlanName(corewlanSecurity, int(w.security)),
+	}
+	if link.SSID == "" {
+		return wifiLink{}, false
+	}
+	if verbose {
+		link.Details = link.detailLines()
+	}
+	return link, true
+}
@@ -0,0 +1,370 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dhcpLease is the DHCP lease of an interface as the client holds it.
+type dhcpLease struct {
+	// Address is the address leased (yiaddr); empty when there is no lease.
+	Address string
+	// Server is the DHCP server identifier (option 54).
+	Server  string
+	Routers []string
+	DNS     []string
+	Domain  string
+	// Lease is the lease length (option 51).
+	Lease time.Duration
+	// Expires is when the lease runs out; zero when the platform does not
+	// say when it was obtained.
+	Expires time.Time
+	// Unmanaged is set when another DHCP client than the one read runs the
+	// interface (on Linux, systemd-networkd or dhclient rather than
+	// NetworkManager), so a missing lease does not mean a static address.
+	Unmanaged bool
+}
+
+// CheckDHCP reads the DHCP lease of the primary interface (`ipconfig
+// getpacket` on macOS, NetworkManager on Linux) and compares what the
+// server offered with the address, gateway and resolvers in use. A
+// self-assigned 169.254 address means no DHCP server answered at all;
+// such an interface usually has no default route either.
+func CheckDHCP(ctx context.Context) Result {
+	res := Result{Name: "DHCP Lease", Emoji: "🧾", Status: StatusOk}
+	iface, err := getPrimaryInterface(ctx)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		if name, addr, ok := selfAssignedInterface(); ok {
+			gradeDHCP(&res, dhcpLease{}, addr, "", nil, time.Now())
+			res.FixCommand = dhcpRenewCommand(ctx, name)
+			return res
+		}
+		res.Status = StatusError
+		res.Message = "No default route found"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+	addr, _, _ := interfaceAddrs(iface)
+
+	p := activePlatform()
+	var lease dhcpLease
+	out, err := runPlatformCommand(ctx, p.dhcpLeaseCommand(iface))
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	// Without a lease the command fails; the address was set by hand.
+	if err == nil {
+		lease = p.parseDHCPLease(string(out))
+	}
+	if lease.Address != "" && lease.Expires.IsZero() && lease.Lease > 0 {
+		if cmd := p.dhcpLeaseStartCommand(iface); cmd != nil {
+			if out, err := runPlatformCommand(ctx, cmd); err == nil {
+				if start := p.parseDHCPLeaseStart(string(out)); !start.IsZero() {
+					lease.Expires = start.Add(lease.Lease)
+				}
+			}
+		}
+	}
+	gateway := ""
+	if routeInfo, err := defaultRoute.get(ctx); err == nil {
+		gateway, _ = p.parseGateway(routeInfo)
+	}
+	gradeDHCP(&res, lease, addr, gateway, systemNameservers(ctx), time.Now())
+	if res.Reason == ReasonSelfAssignedIP || res.Reason == ReasonDHCPMismatch || res.Reason == ReasonDHCPLeaseExpired {
+		res.FixCommand = dhcpRenewCommand(ctx, iface)
+	}
+	return res
+}
+
+// gradeDHCP sets the verdict of CheckDHCP from lease and the configuration
+// in use: addr on the interface, the default gateway and the system's
+// nameservers, at now.
+func gradeDHCP(res *Result, lease dhcpLease, addr, gateway string, nameservers []string, now time.Time) {
+	if ip := net.ParseIP(addr); ip != nil && ip.IsLinkLocalUnicast() {
+		res.Status = StatusError
+		res.Reason = ReasonSelfAssignedIP
+		res.Message = "Self-assigned address " + addr + ": no DHCP server answered"
+		res.Fix = "Check the cable or Wi-Fi association and that the router's DHCP server is on, then renew the lease."
+		return
+	}
+	if lease.Address == "" && lease.Unmanaged {
+		res.Status = StatusSkipped
+		res.Reason = ReasonProbeFailed
+		res.Message = "Could not read the DHCP lease: NetworkManager does not manage the interface"
+		res.Fix = "Leases held by systemd-networkd or dhclient are not read; check them with networkctl status or in /var/lib/dhcp."
+		return
+	}
+	if lease.Address == "" {
+		res.Message = "No DHCP lease; the address is set by hand"
+		return
+	}
+
+	details := []string{"Server: " + orUnknown(lease.Server), "Address: " + lease.Address}
+	if lease.Lease > 0 {
+		details = append(details, "Lease: "+formatLease(lease.Lease))
+	}
+	if !lease.Expires.IsZero() {
+		details = append(details, "Remaining: "+formatLease(max(lease.Expires.Sub(now), 0)))
+	}
+	if len(lease.Routers) > 0 {
+		details = append(details, "Router: "+strings.Join(lease.Routers, ", "))
+	}
+	if len(lease.DNS) > 0 {
+		details = append(details, "DNS: "+strings.Join(lease.DNS, ", "))
+	}
+	if lease.Domain != "" {
+		details = append(details, "Domain: "+lease.Domain)
+	}
+	// Resolvers set by hand or pushed by a VPN are deliberate, so a
+	// difference is only noted.
+	dnsOverridden := len(lease.DNS) > 0 && len(nameservers) > 0 &&
+		!slices.ContainsFunc(lease.DNS, func(s string) bool { return slices.Contains(nameservers, s) })
+	if dnsOverridden {
+		details = append(details, "System resolvers: "+strings.Join(nameservers, ", ")+" (not the ones DHCP offered)")
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	switch {
+	case !lease.Expires.IsZero() && now.After(lease.Expires):
+		res.Status = StatusWarning
+		res.Reason = ReasonDHCPLeaseExpired
+		res.Message = fmt.Sprintf("DHCP lease expired %s ago and was not renewed", formatLease(now.Sub(lease.Expires)))
+		res.Fix = "The router may hand the address to another device: renew the lease."
+	case addr != "" && addr != lease.Address:
+		res.Status = StatusWarning
+		res.Reason = ReasonDHCPMismatch
+		res.Message = fmt.Sprintf("Interface uses %s but the DHCP lease is for %s", addr, lease.Address)
+		res.Fix = "The address was changed by hand or the lease is stale: renew the lease."
+	case gateway != "" && len(lease.Routers) > 0 && !slices.Contains(lease.Routers, gateway):
+		res.Status = StatusWarning
+		res.Reason = ReasonDHCPMismatch
+		res.Message = fmt.Sprintf("Default gateway %s is not the router DHCP offered (%s)", gateway, strings.Join(lease.Routers, ", "))
+		res.Fix = "A VPN, a static route or a second DHCP server on the network may be at play: renew the lease and check for a rogue DHCP server."
+	default:
+		res.Message = "Lease from " + orUnknown(lease.Server)
+		if !lease.Expires.IsZero() {
+			res.Message += ", " + formatLease(lease.Expires.Sub(now)) + " left"
+		} else if lease.Lease > 0 {
+			res.Message += " (" + formatLease(lease.Lease) + " lease)"
+		}
+		if dnsOverridden {
+			res.Message += "; DNS servers differ from the ones offered"
+		}
+	}
+}
+
+// formatLease rounds a lease duration to minutes.
+func formatLease(d time.Duration) string {
+	s := d.Round(time.Minute).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// selfAssignedInterface finds an up interface whose only IPv4 addresses
+// are self-assigned (169.254/16), for when it left no default route. It
+// only sees this machine, so finds none with --remote.
+func selfAssignedInterface() (name, addr string, ok bool) {
+	if remoteTarget() != "" {
+		return "", "", false
+	}
+	ifaces, err := localInterfaces()
+	if err != nil {
+		return "", "", false
+	}
+	for _, iface := range ifaces {
+		if !iface.Up || iface.Loopback || vpnKind(iface) != "" {
+			continue
+		}
+		addr, assigned := "", true
+		for _, a := range iface.Addrs {
+			if !a.Addr().Is4() {
+				continue
+			}
+			assigned = assigned && a.Addr().IsLinkLocalUnicast()
+			if addr == "" {
+				addr = a.Addr().String()
+			}
+		}
+		if addr != "" && assigned {
+			return iface.Name, addr, true
+		}
+	}
+	return "", "", false
+}
+
+// DHCP clients parseDHCPClient tells apart.
+const (
+	dhcpClientNetworkManager = "NetworkManager"
+	dhcpClientDhclient       = "dhclient"
+)
+
+// dhcpClient names the DHCP client holding iface's lease, "" where the
+// platform has one client or the client is not one wtfi knows. A running
+// NetworkManager only counts when nmcli says it manages iface.
+func dhcpClient(ctx context.Context, iface string) string {
+	p := activePlatform()
+	cmd := p.dhcpClientCommand()
+	if cmd == nil {
+		return ""
+	}
+	out, err := runPlatformCommand(ctx, cmd)
+	if err != nil {
+		return ""
+	}
+	client := p.parseDHCPClient(string(out), iface)
+	if client == dhcpClientNetworkManager {
+		out, err := runPlatformCommand(ctx, p.dhcpLeaseCommand(iface))
+		if err != nil || p.parseDHCPLease(string(out)).Unmanaged {
+			return ""
+		}
+	}
+	return client
+}
+
+// parseDHCPClient reads `ps -eo args=`: a dhclient started for iface by
+// hand or by ifupdown, or else NetworkManager, which also runs dhclient
+// with its own helper on some distributions.
+func parseDHCPClient(out, iface string) string {
+	nm := false
+	for line := range strings.Lines(out) {
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if strings.Contains(line, "NetworkManager") {
+			nm = nm || path.Base(args[0]) == "NetworkManager"
+			continue
+		}
+		if path.Base(args[0]) == "dhclient" && slices.Contains(args[1:], iface) {
+			return dhcpClientDhclient
+		}
+	}
+	if nm {
+		return dhcpClientNetworkManager
+	}
+	return ""
+}
+
+// parseGetpacket reads `ipconfig getpacket`, whose options look like
+// "lease_time (uint32): 0x15180" and "router (ip_mult): {192.168.1.1}".
+// The packet does not say when the lease was obtained, so Expires is zero;
+// parseGetsummaryLeaseStart fills the gap.
+func parseGetpacket(out string) dhcpLease {
+	var l dhcpLease
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "yiaddr = "); ok && v != "0.0.0.0" {
+			l.Address = v
+			continue
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(key, " (")
+		switch name {
+		case "server_identifier":
+			l.Server = value
+		case "router":
+			l.Routers = parseIPList(value)
+		case "domain_name_server":
+			l.DNS = parseIPList(value)
+		case "domain_name":
+			l.Domain = value
+		case "lease_time":
+			if secs, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32); err == nil {
+				l.Lease = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return l
+}
+
+// getsummaryTimeLayouts are the ways `ipconfig getsummary` has printed
+// times across macOS releases.
+var getsummaryTimeLayouts = []string{"01/02/2006 15:04:05", "2006-01-02 15:04:05 -0700"}
+
+// parseGetsummaryLeaseStart reads "LeaseStartTime : 10/16/2026 09:12:44"
+// from `ipconfig getsummary`, in local time; zero when it is missing, as
+// on releases before macOS 12.
+func parseGetsummaryLeaseStart(out string) time.Time {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " : ")
+		if !ok || key != "LeaseStartTime" {
+			continue
+		}
+		for _, layout := range getsummaryTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseIPList reads "{192.168.1.1, 8.8.8.8}".
+func parseIPList(s string) []string {
+	var ips []string
+	for _, f := range strings.Split(strings.Trim(s, "{}"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ips = append(ips, f)
+		}
+	}
+	return ips
+}
+
+// parseNMCLIDHCP reads `nmcli -t -f GENERAL.STATE,DHCP4 device show`,
+// whose lines look like "GENERAL.STATE:10 (unmanaged)" and
+// "DHCP4.OPTION[3]:dhcp_lease_time = 86400".
+func parseNMCLIDHCP(out string) dhcpLease {
+	var l dhcpLease
+	for _, line := range strings.Split(out, "\n") {
+		field, opt, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		if field == "GENERAL.STATE" {
+			l.Unmanaged = strings.Contains(opt, "(unmanaged)")
+			continue
+		}
+		key, value, ok := strings.Cut(opt, " = ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "ip_address":
+			l.Address = value
+		case "dhcp_server_identifier":
+			l.Server = value
+		case "routers":
+			l.Routers = strings.Fields(value)
+		case "domain_name_servers":
+			l.DNS = strings.Fields(value)
+		case "domain_name":
+			l.Domain = value
+		case "dhcp_lease_time":
+			if secs, err := strconv.Atoi(value); err == nil {
+				l.Lease = time.Duration(secs) * time.Second
+			}
+		case "expiry":
+			if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+				l.Expires = time.Unix(unix, 0)
+			}
+		}
+	}
+	return l
+}
@@ -0,0 +1,167 @@
+package diagnostic
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGetpacket(t *testing.T) {
+	out := `op = BOOTREPLY
+htype = 1
+ciaddr = 0.0.0.0
+yiaddr = 192.168.1.23
+siaddr = 0.0.0.0
+options:
+Options count is 7
+dhcp_message_type (uint8): ACK 0x5
+server_identifier (ip): 192.168.1.1
+lease_time (uint32): 0x15180
+subnet_mask (ip): 255.255.255.0
+router (ip_mult): {192.168.1.1}
+domain_name_server (ip_mult): {192.168.1.1, 8.8.8.8}
+domain_name (string): lan
+end (none):
+`
+	l := parseGetpacket(out)
+	if l.Address != "192.168.1.23" || l.Server != "192.168.1.1" || l.Lease != 24*time.Hour || l.Domain != "lan" {
+		t.Errorf("unexpected lease %+v", l)
+	}
+	if !slices.Equal(l.Routers, []string{"192.168.1.1"}) || !slices.Equal(l.DNS, []string{"192.168.1.1", "8.8.8.8"}) {
+		t.Errorf("unexpected options %+v", l)
+	}
+	if !l.Expires.IsZero() {
+		t.Errorf("expected no expiry from a packet, got %v", l.Expires)
+	}
+}
+
+func TestParseNMCLIDHCP(t *testing.T) {
+	out := `DHCP4.OPTION[1]:dhcp_lease_time = 3600
+DHCP4.OPTION[2]:dhcp_server_identifier = 10.0.0.1
+DHCP4.OPTION[3]:domain_name_servers = 10.0.0.1 1.1.1.1
+DHCP4.OPTION[4]:expiry = 1700000000
+DHCP4.OPTION[5]:ip_address = 10.0.0.42
+DHCP4.OPTION[6]:routers = 10.0.0.1
+`
+	l := parseNMCLIDHCP(out)
+	if l.Address != "10.0.0.42" || l.Server != "10.0.0.1" || l.Lease != time.Hour || !l.Expires.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected lease %+v", l)
+	}
+	if !slices.Equal(l.DNS, []string{"10.0.0.1", "1.1.1.1"}) || !slices.Equal(l.Routers, []string{"10.0.0.1"}) {
+		t.Errorf("unexpected options %+v", l)
+	}
+	if l.Unmanaged {
+		t.Error("expected a device with a lease to be managed")
+	}
+	if l := parseNMCLIDHCP("GENERAL.STATE:10 (unmanaged)\n"); !l.Unmanaged || l.Address != "" {
+		t.Errorf("expected an unmanaged device, got %+v", l)
+	}
+}
+
+func TestParseGetsummaryLeaseStart(t *testing.T) {
+	out := "<dictionary> {\n  LeaseExpirationTime : 10/17/2026 09:12:44\n  LeaseStartTime : 10/16/2026 09:12:44\n}\n"
+	want := time.Date(2026, 10, 16, 9, 12, 44, 0, time.Local)
+	if got := parseGetsummaryLeaseStart(out); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := parseGetsummaryLeaseStart("<dictionary> {\n}\n"); !got.IsZero() {
+		t.Errorf("expected no start, got %v", got)
+	}
+}
+
+func TestCheckDHCPSelfAssignedWithoutRoute(t *testing.T) {
+	withRunner(t, &fakeRunner{})
+	prev := localInterfaces
+	localInterfaces = func() ([]hostInterface, error) {
+		return []hostInterface{
+			{Name: "lo0", Up: true, Loopback: true, Addrs: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/8")}},
+			iface("en0", "169.254.10.20/16"),
+		}, nil
+	}
+	t.Cleanup(func() { localInterfaces = prev })
+
+	res := CheckDHCP(context.Background())
+	if res.Status != StatusError || res.Reason != ReasonSelfAssignedIP || res.FixCommand == "" {
+		t.Errorf("expected a self-assigned error with a renew command, got %v/%s %q", res.Status, res.Reason, res.FixCommand)
+	}
+}
+
+func TestGradeDHCP(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lease := dhcpLease{Address: "192.168.1.23", Server: "192.168.1.1", Routers: []string{"192.168.1.1"}, DNS: []string{"192.168.1.1"}, Lease: 24 * time.Hour}
+	expiring := lease
+	expiring.Expires = now.Add(3*time.Hour + 12*time.Minute)
+	expired := lease
+	expired.Expires = now.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name        string
+		lease       dhcpLease
+		addr, gw    string
+		nameservers []string
+		status      Status
+		reason      Reason
+		message     string
+	}{
+		{"healthy", lease, "192.168.1.23", "192.168.1.1", []string{"192.168.1.1"}, StatusOk, "", "Lease from 192.168.1.1 (24h lease)"},
+		{"time left", expiring, "192.168.1.23", "192.168.1.1", nil, StatusOk, "", "3h12m left"},
+		{"dns overridden", lease, "192.168.1.23", "192.168.1.1", []string{"1.1.1.1"}, StatusOk, "", "DNS servers differ"},
+		{"self-assigned", dhcpLease{}, "169.254.10.20", "", nil, StatusError, ReasonSelfAssignedIP, "Self-assigned address 169.254.10.20"},
+		{"static", dhcpLease{}, "192.168.1.50", "192.168.1.1", nil, StatusOk, "", "set by hand"},
+		{"unmanaged", dhcpLease{Unmanaged: true}, "192.168.1.50", "192.168.1.1", nil, StatusSkipped, ReasonProbeFailed, "does not manage"},
+		{"expired", expired, "192.168.1.23", "192.168.1.1", nil, StatusWarning, ReasonDHCPLeaseExpired, "expired 10m ago"},
+		{"address changed", lease, "192.168.1.99", "192.168.1.1", nil, StatusWarning, ReasonDHCPMismatch, "uses 192.168.1.99"},
+		{"other gateway", lease, "192.168.1.23", "192.168.1.254", nil, StatusWarning, ReasonDHCPMismatch, "not the router DHCP offered"},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeDHCP(&res, tt.lease, tt.addr, tt.gw, tt.nameservers, now)
+		if res.Status != tt.status || res.Reason != tt.reason || !strings.Contains(res.Message, tt.message) {
+			t.Errorf("%s: got %v %q %q", tt.name, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestParseDHCPClient(t *testing.T) {
+	const nm = "/usr/sbin/NetworkManager --no-daemon\n"
+	const nmDhclient = "/sbin/dhclient -d -q -sf /usr/libexec/nm-dhcp-helper -pf /run/NetworkManager/dhclient-eth0.pid eth0\n"
+	const dhclient = "/sbin/dhclient -4 -v -i -pf /run/dhclient.eth0.pid -lf /var/lib/dhcp/dhclient.eth0.leases eth0\n"
+	tests := []struct {
+		name, out, want string
+	}{
+		{"networkmanager", "/sbin/init\n" + nm, dhcpClientNetworkManager},
+		{"networkmanager's dhclient", nm + nmDhclient, dhcpClientNetworkManager},
+		{"ifupdown", dhclient, dhcpClientDhclient},
+		{"dhclient on another interface", strings.ReplaceAll(dhclient, "eth0", "wlan0"), ""},
+		{"networkd", "/lib/systemd/systemd-networkd\n", ""},
+	}
+	for _, tt := range tests {
+		if got := parseDHCPClient(tt.out, "eth0"); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDHCPRenewCommandLinux(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"ps -eo args=": "/usr/sbin/NetworkManager --no-daemon\n",
+		"nmcli -t -f GENERAL.STATE,DHCP4 device show eth0": "GENERAL.STATE:100 (connected)\n",
+	}}
+	withRunner(t, runner)
+	setPlatform(linuxPlatform{})
+
+	if got := dhcpRenewCommand(context.Background(), "eth0"); got != "sudo nmcli device connect eth0" {
+		t.Errorf("expected a NetworkManager renew, got %q", got)
+	}
+	runner.outputs["nmcli -t -f GENERAL.STATE,DHCP4 device show eth0"] = "GENERAL.STATE:10 (unmanaged)\n"
+	if got := dhcpRenewCommand(context.Background(), "eth0"); got != "" {
+		t.Errorf("expected no renew for an interface NetworkManager leaves alone, got %q", got)
+	}
+	runner.outputs["ps -eo args="] = "dhclient eth0\n"
+	if got := dhcpRenewCommand(context.Background(), "eth0"); got != "sudo dhclient -r eth0 && sudo dhclient eth0" {
+		t.Errorf("expected a dhclient renew, got %q", got)
+	}
+}
@@ -2,13 +2,16 @@
 package diagnostic
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
 	"net"
 	"net/http"
-	"os/exec"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,13 +22,15 @@ import (
 var (
 	reSignalNoise  = regexp.MustCompile(`(-?\d+) dBm / (-?\d+) dBm`)
 	reMTU          = regexp.MustCompile(`mtu (\d+)`)
-	rePingStat     = regexp.MustCompile(`min/avg/max/std-?dev = \d+(?:\.\d*)?/(\d+(?:\.\d*)?)`)
-	rePingRoute    = regexp.MustCompile(`from (\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}):`)
+	rePingStat     = regexp.MustCompile(`min/avg/max/(?:std-?dev|mdev) = \d+(?:\.\d*)?/(\d+(?:\.\d*)?)`)
+	rePingRoute    = regexp.MustCompile(`(?i)from (\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})[: ]`)
 	reRouteIface   = regexp.MustCompile(`interface: (\w+)`)
 	reRouteGw      = regexp.MustCompile(`gateway: (\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})`)
-	reLoss         = regexp.MustCompile(`(\d+\.?\d*)% packet loss`)
-	reJitter       = regexp.MustCompile(`min/avg/max/std-?dev = \d+(?:\.\d*)?/\d+(?:\.\d*)?/\d+(?:\.\d*)?/(\d+(?:\.\d*)?)`)
+	reRouteGw6     = regexp.MustCompile(`gateway: ([0-9A-Fa-f]*:[0-9A-Fa-f:]*(?:%\w+)?)`)
+	rePingCount    = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	rePingSummary  = regexp.MustCompile(`min/avg/max/(?:std-?dev|mdev) = (\d+(?:\.\d*)?)/(\d+(?:\.\d*)?)/(\d+(?:\.\d*)?)/(\d+(?:\.\d*)?)`)
 	reSanitizeHTTP = regexp.MustCompile(`[\x00-\x1F\x7F-\x9F]`)
+	rePortalURL    = regexp.MustCompile(`(?i)(?:url=|location(?:\.href)?\s*=\s*)["']?(https?://[^"'\s>;]+)`)
 )
 
 // Status represents the health status of a diagnostic step.
@@ -38,47 +43,171 @@ const (
 	StatusWarning
 	// StatusError indicates a failure state.
 	StatusError
+	// StatusSkipped indicates the check could not run in this environment,
+	// e.g. because a required tool is missing.
+	StatusSkipped
+)
+
+// String returns the lowercase name of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusOk:
+		return "ok"
+	case StatusWarning:
+		return "warning"
+	case StatusError:
+		return "error"
+	case StatusSkipped:
+		return "skipped"
+	default:
+		return fmt.Sprintf("status(%d)", int(s))
+	}
+}
+
+const captivePortalURL = "http://captive.apple.com/hotspot-detect.html"
+
+// Default latencies above which DNS and WAN round trips are reported as a
+// warning; see Config.DNSSlow and Config.WANSlow.
+const (
+	dnsSlowThreshold = 200 * time.Millisecond
+	wanSlowThreshold = 150 * time.Millisecond
 )
 
 const (
 	wanTargetIPv4 = "1.1.1.1"
 	wanTargetIPv6 = "2606:4700:4700::1111"
-	wanTargetTCP  = "1.1.1.1:443"
+	wanTargetTCP6 = "[2606:4700:4700::1111]:443"
 )
 
 // Result holds the outcome of a diagnostic check.
 type Result struct {
-	Name    string
+	Name string
+	// Check is the registered name of the check that produced the result.
+	Check   string
 	Latency time.Duration
 	Status  Status
 	Message string
-	Fix     string
-	Emoji   string
-	Details []string
+	// Reason is the stable code behind Message when Status is not StatusOk,
+	// or when an OK result had to be measured in a degraded way.
+	Reason Reason
+	Fix    string
+	// FixCommand is a copy-pasteable shell command implementing Fix, set
+	// only when a safe and specific one exists.
+	FixCommand string
+	Emoji      string
+	Details    []string
+	// Facts holds raw observations (see the Fact* keys) for consumers that
+	// track state across runs, such as watch mode.
+	Facts map[string]string
+	// Percentiles is set when a latency sampling window was measured.
+	Percentiles *LatencyPercentiles
 }
 
+// Well-known keys of Result.Facts.
+const (
+	FactSSID  = "ssid"
+	FactBSSID = "bssid"
+	FactRSSI  = "rssi_dbm"
+	// FactSignalQuality is the RSSI mapped onto 0-100 by signalQuality.
+	FactSignalQuality = "signal_quality"
+	// Radio details of the Wi-Fi link, when the platform reports them.
+	FactNoise   = "noise_dbm"
+	FactTxRate  = "tx_rate_mbps"
+	FactChannel = "channel"
+	FactPHYMode = "phy_mode"
+	FactMCS     = "mcs_index"
+	// FactGatewayMAC is the gateway's MAC address from the ARP table.
+	FactGatewayMAC = "gateway_mac"
+	// FactLoss and FactJitter are the packet loss (percent) and jitter
+	// (milliseconds) of the gateway and WAN ping bursts.
+	FactLoss   = "loss_pct"
+	FactJitter = "jitter_ms"
+	// FactPortalURL is the login page a captive portal redirected to.
+	FactPortalURL = "portal_url"
+	// FactNameservers lists the system resolvers, comma-separated.
+	FactNameservers = "nameservers"
+	// FactFastestResolver is the address of the quickest benchmarked
+	// resolver other than the system's.
+	FactFastestResolver = "fastest_resolver"
+	// FactTraceHops lists the routers on the path in order, comma-separated,
+	// with "*" for hops that did not answer.
+	FactTraceHops = "trace_hops"
+	// FactDefaultRoutes lists the interfaces of the competing default
+	// routes, comma-separated.
+	FactDefaultRoutes = "default_routes"
+)
+
 // CheckL2WiFi performs Layer 2 (Wi-Fi) diagnostics.
-func CheckL2WiFi(verbose bool) Result {
-	iface, err := getPrimaryInterface()
+func CheckL2WiFi(ctx context.Context, verbose bool) Result {
+	iface, err := getPrimaryInterface(ctx)
+	if r, ok := missingToolResult("Wi-Fi", "📡", err); ok {
+		return r
+	}
+	if err != nil {
+		return Result{Name: "Connectivity", Emoji: "📡", Status: StatusError, Message: "No default route found", Reason: ReasonNoRoute, Fix: "Check your network hardware."}
+	}
+
+	link, err := readWiFiLink(ctx, iface, verbose)
+	if r, ok := missingToolResult("Wi-Fi", "📡", err); ok {
+		return r
+	}
 	if err != nil {
-		return Result{Name: "Connectivity", Emoji: "📡", Status: StatusError, Message: "No default route found", Fix: "Check your network hardware."}
+		return Result{Name: "Wi-Fi", Emoji: "📡", Status: StatusError, Message: "Failed to retrieve Wi-Fi telemetry", Reason: ReasonProbeFailed}
 	}
 
-	cmd := exec.Command("system_profiler", "SPAirPortDataType")
-	out, err := cmd.Output()
+	return wifiResult(ctx, link, iface, verbose)
+}
 
+// readWiFiLink returns the Wi-Fi association of iface, from the native
+// backend when one is compiled in, or else from the platform's command.
+func readWiFiLink(ctx context.Context, iface string, verbose bool) (wifiLink, error) {
+	// The native reader is instant but only sees this machine.
+	if _, local := activeRunner().(localRunner); local && nativeWiFi != nil {
+		if link, ok := nativeWiFi(iface, verbose); ok {
+			return link, nil
+		}
+	}
+	out, err := wifiInfo.get(ctx, iface)
 	if err != nil {
-		return Result{Name: "Wi-Fi", Emoji: "📡", Status: StatusError, Message: "Failed to retrieve Wi-Fi telemetry"}
+		return wifiLink{}, err
 	}
+	return activePlatform().parseWiFi(out, verbose), nil
+}
 
-	return parseWiFiInfo(string(out), iface, verbose)
+// wifiLink is the current Wi-Fi association as reported by the platform.
+type wifiLink struct {
+	SSID  string
+	BSSID string
+	// RSSI is zero when not associated.
+	RSSI int
+	// Noise is the noise floor in dBm, zero when unknown.
+	Noise int
+	// TxRate is the transmit rate in Mbps, zero when unknown.
+	TxRate float64
+	// Channel reads like "36 (5GHz, 80MHz)".
+	Channel string
+	// PHYMode reads like "802.11ax".
+	PHYMode string
+	// MCS is the modulation and coding scheme index, -1 when unknown.
+	MCS int
+	// Security is the security mode as the platform names it, such as
+	// "WPA2 Personal", "SAE" or "None"; empty when not reported.
+	Security string
+	// PMF is "required", "optional" or "disabled" for protected management
+	// frames, or empty when the platform does not say.
+	PMF string
+	// Details are the raw property lines shown in verbose mode.
+	Details []string
 }
 
 func parseWiFiInfo(output string, iface string, verbose bool) Result {
-	res := Result{Name: "Wi-Fi", Emoji: "📡", Status: StatusOk}
-	ssid, rssi := "", 0
-	var details []string
+	return wifiResult(context.Background(), parseSystemProfiler(output, verbose), iface, verbose)
+}
 
+// parseSystemProfiler reads the current network from system_profiler
+// SPAirPortDataType output.
+func parseSystemProfiler(output string, verbose bool) wifiLink {
+	link := wifiLink{MCS: -1}
 	lines := strings.Split(output, "\n")
 	isCurrent := false
 	for _, line := range lines {
@@ -88,54 +217,161 @@ func parseWiFiInfo(output string, iface string, verbose bool) Result {
 			continue
 		}
 		if isCurrent {
-			if strings.HasSuffix(trimmed, ":") && ssid == "" {
-				ssid = strings.TrimSuffix(trimmed, ":")
-				res.Name = fmt.Sprintf("Wi-Fi (%s)", reSanitizeHTTP.ReplaceAllString(ssid, ""))
+			if strings.HasSuffix(trimmed, ":") && link.SSID == "" {
+				link.SSID = strings.TrimSuffix(trimmed, ":")
+			}
+			if strings.HasPrefix(trimmed, "BSSID:") && link.BSSID == "" {
+				link.BSSID = normalizeMAC(strings.TrimSpace(strings.TrimPrefix(trimmed, "BSSID:")))
 			}
 			if strings.Contains(line, "Signal / Noise") {
 				m := reSignalNoise.FindStringSubmatch(line)
 				if len(m) > 1 {
-					rssi, _ = strconv.Atoi(m[1])
+					link.RSSI, _ = strconv.Atoi(m[1])
+					link.Noise, _ = strconv.Atoi(m[2])
+				}
+			}
+			key, value, _ := strings.Cut(trimmed, ": ")
+			switch key {
+			case "PHY Mode":
+				link.PHYMode = value
+			case "Channel":
+				link.Channel = value
+			case "Transmit Rate":
+				link.TxRate, _ = strconv.ParseFloat(value, 64)
+			case "Security":
+				link.Security = value
+			case "MCS Index":
+				if mcs, err := strconv.Atoi(value); err == nil {
+					link.MCS = mcs
 				}
 			}
 			if verbose && strings.Contains(line, ":") {
-				details = append(details, trimmed)
+				link.Details = append(link.Details, trimmed)
 			}
 			if strings.Contains(line, "Other Local Wi-Fi Networks") {
 				break
 			}
 		}
 	}
+	return link
+}
+
+// wifiResult grades link, adding the MTU of iface and, when verbose, the
+// distance estimate.
+func wifiResult(ctx context.Context, link wifiLink, iface string, verbose bool) Result {
+	res := Result{Name: "Wi-Fi", Emoji: "📡", Status: StatusOk}
+	ssid, bssid, rssi, details := link.SSID, link.BSSID, link.RSSI, link.Details
+	if ssid != "" {
+		res.Name = fmt.Sprintf("Wi-Fi (%s)", reSanitizeHTTP.ReplaceAllString(ssid, ""))
+	}
 
 	if rssi == 0 {
 		res.Message = "Wired connection (or Wi-Fi disabled)"
 	} else {
-		res.Message = fmt.Sprintf("Interface: %s, Signal: %d dBm", iface, rssi)
+		res.Message = fmt.Sprintf("Interface: %s, Signal: %d dBm (%d%%)", iface, rssi, signalQuality(rssi))
+		res.Facts = map[string]string{
+			FactSSID:          reSanitizeHTTP.ReplaceAllString(ssid, ""),
+			FactRSSI:          strconv.Itoa(rssi),
+			FactSignalQuality: strconv.Itoa(signalQuality(rssi)),
+		}
+		if bssid != "" {
+			res.Facts[FactBSSID] = bssid
+		}
+		for k, v := range link.facts() {
+			res.Facts[k] = v
+		}
 	}
 
 	// Unify details for consistent prefixing
 	var allDetails []string
 
 	// Extract MTU size
-	outIf, err := exec.Command("ifconfig", iface).Output()
+	outIf, err := runPlatformCommand(ctx, activePlatform().linkCommand(iface))
 	if err != nil {
 		allDetails = append(allDetails, fmt.Sprintf("MTU: unavailable (%v)", err))
 	} else {
 		if m := reMTU.FindStringSubmatch(string(outIf)); len(m) > 1 {
-			allDetails = append(allDetails, fmt.Sprintf("MTU: %s (Standard is 1500)", m[1]))
+			allDetails = append(allDetails, fmt.Sprintf("MTU: %s (Standard is %d)", m[1], StandardMTU))
 		}
 	}
 
 	allDetails = append(allDetails, details...)
+	if verbose && rssi != 0 {
+		c := activeConfig(ctx)
+		d := estimateDistanceMeters(rssi, c.TxPower, c.PathLossExponent)
+		allDetails = append(allDetails, fmt.Sprintf("Distance to AP: ~%.1f m (rough estimate; walls and interference skew it)", d))
+	}
 
 	res.Details = append(res.Details, formatDetailsWithPrefixes(allDetails)...)
-	if rssi < -80 && rssi != 0 {
+	if c := activeConfig(ctx); rssi != 0 && (signalQuality(rssi) < c.MinSignalQuality || (c.MinRSSI != 0 && rssi < c.MinRSSI)) {
 		res.Status = StatusWarning
+		res.Reason = ReasonWeakSignal
 		res.Fix = "Weak signal. Move closer to the Access Point."
 	}
 	return res
 }
 
+// signalQuality maps RSSI onto a 0-100% score: linear from -90 dBm (0%) to
+// -50 dBm (100%), clamped outside that range.
+func signalQuality(rssi int) int {
+	switch {
+	case rssi <= -90:
+		return 0
+	case rssi >= -50:
+		return 100
+	}
+	return (rssi + 90) * 100 / 40
+}
+
+// warnIfSlow raises res to a warning with reason and message when its latency
+// exceeds limit, and reports whether it did.
+func warnIfSlow(res *Result, limit time.Duration, reason Reason, message string) bool {
+	if res.Latency <= limit {
+		return false
+	}
+	res.Status = max(res.Status, StatusWarning)
+	res.Reason = reason
+	res.Message = message
+	return true
+}
+
+// estimateDistanceMeters applies the log-distance path loss model, where
+// txPower is the RSSI expected at 1 m and pathLossExponent ranges from 2 in
+// free space to about 4 indoors through walls.
+func estimateDistanceMeters(rssi, txPower, pathLossExponent int) float64 {
+	if pathLossExponent <= 0 {
+		return 0
+	}
+	return math.Pow(10, float64(txPower-rssi)/float64(10*pathLossExponent))
+}
+
+// AddDetails appends lines to r.Details, keeping the tree prefixes consistent.
+func (r *Result) AddDetails(lines ...string) {
+	plain := make([]string, 0, len(r.Details)+len(lines))
+	for _, d := range r.Details {
+		plain = append(plain, stripDetailPrefix(d))
+	}
+	r.Details = formatDetailsWithPrefixes(append(plain, lines...))
+}
+
+// SetFact records one of r's Facts.
+func (r *Result) SetFact(key, value string) {
+	if r.Facts == nil {
+		r.Facts = map[string]string{}
+	}
+	r.Facts[key] = value
+}
+
+// stripDetailPrefix removes the tree prefix added by formatDetailsWithPrefixes.
+func stripDetailPrefix(detail string) string {
+	for _, p := range []string{"├─ ", "└─ "} {
+		if strings.HasPrefix(detail, p) {
+			return strings.TrimPrefix(detail, p)
+		}
+	}
+	return detail
+}
+
 // formatDetailsWithPrefixes applies the correct UI tree prefixes to a slice of strings.
 func formatDetailsWithPrefixes(details []string) []string {
 	if len(details) == 0 {
@@ -153,24 +389,93 @@ func formatDetailsWithPrefixes(details []string) []string {
 }
 
 // CheckL3Gateway performs Layer 3 diagnostics for the local gateway.
-func CheckL3Gateway(verbose bool) Result {
-	gw, err := getGatewayIP()
+func CheckL3Gateway(ctx context.Context, verbose bool) Result {
+	gw, err := getGatewayIP(ctx)
+	if r, ok := missingToolResult("Gateway", "🏠", err); ok {
+		return r
+	}
+	v6only := false
 	if err != nil {
-		return Result{Name: "Gateway", Emoji: "🏠", Status: StatusError, Message: "Gateway IP discovery failed"}
+		if gw6, err6 := getGatewayIP6(ctx); err6 == nil {
+			gw, err, v6only = gw6, nil, true
+		}
+	}
+	if err != nil {
+		return Result{Name: "Gateway", Emoji: "🏠", Status: StatusError, Message: "Gateway IP discovery failed", Reason: ReasonNoRoute}
 	}
 
-	lat, err := ping(gw)
+	c := activeConfig(ctx)
+	var lat time.Duration
+	if v6only {
+		lat, err = ping6(ctx, gw)
+	} else {
+		lat, err = ping(ctx, gw, c.PingSize, c.PingInterval)
+	}
+	if r, ok := missingToolResult("Gateway ("+gw+")", "🏠", err); ok {
+		return r
+	}
 	res := Result{Name: "Gateway (" + gw + ")", Emoji: "🏠", Latency: lat, Status: StatusOk, Message: "Reachable"}
+	if v6only {
+		res.Message = "Reachable (IPv6-only network detected)"
+		res.Reason = ReasonIPv6Only
+	}
+	if isPermissionError(err) {
+		res.Reason = ReasonICMPUnavailable
+		tcpLat, port, errTCP := tcpProbe(ctx, gw, gatewayTCPPorts...)
+		if errTCP != nil {
+			res.Status = StatusWarning
+			res.Message = "ICMP not permitted and no TCP service answered; reachability unknown"
+			res.Fix = "Run wtfi with permission to use ICMP, or check the gateway from another device."
+			return res
+		}
+		res.Latency = tcpLat
+		res.Message = "Reachable (ICMP unavailable; measured with TCP :" + port + ")"
+		err = nil
+	}
 	if err != nil {
 		res.Status = StatusError
 		res.Message = "Unreachable"
+		res.Reason = ReasonGatewayUnreachable
 		res.Fix = "Check local cables or restart your router."
+		if iface, errIface := getPrimaryInterface(ctx); errIface == nil {
+			res.FixCommand = dhcpRenewCommand(ctx, iface)
+		}
+		if res.FixCommand != "" {
+			res.Fix = "Check local cables, then renew the DHCP lease; if that fails, restart your router."
+		}
 		return res
 	}
 
+	// A burst catches the intermittent loss a single ping hides. Without
+	// ICMP permission there is nothing to send it with.
+	var details []string
+	if res.Reason != ReasonICMPUnavailable {
+		if stats, errBurst := MeasureLossAndJitter(ctx, gw, v6only); errBurst == nil {
+			proto := "IPv4"
+			if v6only {
+				proto = "IPv6"
+			}
+			details = append(details, burstDetails(proto, stats)...)
+			warnIfLossy(ctx, &res, stats)
+		}
+	}
+
+	// The ARP entry is cheap and shows the gateway MAC changing between runs
+	// or shared with other hosts, as under ARP spoofing. IPv6 neighbors are
+	// not in the ARP table.
+	var out []byte
+	errArp := errors.New("no ARP entry for an IPv6 gateway")
+	if !v6only {
+		out, errArp = runPlatformCommand(ctx, activePlatform().neighborCommand(gw))
+	}
+	if errArp == nil {
+		if macs := activePlatform().parseNeighbors(string(out))[gw]; len(macs) > 0 {
+			res.SetFact(FactGatewayMAC, macs[0])
+			details = append(details, checkGatewayMAC(ctx, &res, gw, macs[0])...)
+		}
+	}
+
 	if verbose {
-		var details []string
-		out, errArp := exec.Command("arp", "-n", gw).Output()
 		details = append(details, "--- ARP Entry ---")
 		if errArp != nil {
 			details = append(details, fmt.Sprintf("Failed: %v", errArp))
@@ -178,12 +483,12 @@ func CheckL3Gateway(verbose bool) Result {
 			details = append(details, strings.TrimSpace(string(out)))
 		}
 
-		iface, errIface := getPrimaryInterface()
+		iface, errIface := getPrimaryInterface(ctx)
 		details = append(details, "--- Interface Details ---")
 		if errIface != nil {
 			details = append(details, fmt.Sprintf("Failed to get interface: %v", errIface))
 		} else {
-			outIf, errIf := exec.Command("ifconfig", iface).Output()
+			outIf, errIf := runCommand(ctx, "ifconfig", iface)
 			if errIf != nil {
 				details = append(details, fmt.Sprintf("Failed ifconfig: %v", errIf))
 			} else {
@@ -195,29 +500,32 @@ func CheckL3Gateway(verbose bool) Result {
 				}
 			}
 		}
-		res.Details = formatDetailsWithPrefixes(details)
 	}
+	res.Details = formatDetailsWithPrefixes(details)
 	return res
 }
 
 // CheckRoutingTable checks active network routing and Virtual Networks (VPNs/Docker).
-func CheckRoutingTable() Result {
+func CheckRoutingTable(ctx context.Context) Result {
 	res := Result{Name: "Routing Table & VPNs", Emoji: "🛣️", Status: StatusOk}
 
-	// Get default route
 	// Get default route info in a single pass to save a process spawn
-	out, err := exec.Command("route", "-n", "get", "default").Output()
+	routeInfo, err := defaultRoute.get(ctx)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
 	if err != nil {
 		res.Status = StatusError
 		res.Message = "No Default Route"
+		res.Reason = ReasonNoRoute
 		return res
 	}
-	routeInfo := string(out)
 
-	iface, err := parseInterface(routeInfo)
+	iface, err := activePlatform().parseInterface(routeInfo)
 	if err != nil {
 		res.Status = StatusError
 		res.Message = "Failed to parse default interface"
+		res.Reason = ReasonProbeFailed
 		return res
 	}
 
@@ -232,6 +540,7 @@ func CheckRoutingTable() Result {
 	interfaces, errNet := net.Interfaces()
 	if errNet != nil {
 		res.Status = StatusWarning
+		res.Reason = ReasonProbeFailed
 		virtuals = append(virtuals, fmt.Sprintf("Warning: could not list network interfaces: %v", errNet))
 	} else {
 		for _, ifaceObj := range interfaces {
@@ -257,6 +566,7 @@ func CheckRoutingTable() Result {
 			addrs, errAddrs := ifaceObj.Addrs()
 			if errAddrs != nil {
 				res.Status = StatusWarning
+				res.Reason = ReasonProbeFailed
 				virtuals = append(virtuals, fmt.Sprintf("Warning: could not get addresses for interface %s: %v", ifaceObj.Name, errAddrs))
 				continue
 			}
@@ -305,60 +615,214 @@ func CheckRoutingTable() Result {
 	return res
 }
 
-// CheckDNSBenchmark compares performance across multiple DNS resolvers.
-func CheckDNSBenchmark() Result {
-	resolvers := map[string]string{
-		"System":     "",
-		"Google":     "8.8.8.8:53",
-		"Cloudflare": "1.1.1.1:53",
-	}
+// dnsResolver is one resolver raced by CheckDNSBenchmark.
+type dnsResolver struct {
+	name string
+	// server is host:port, or "" for the system resolver.
+	server string
+}
 
-	res := Result{Name: "DNS Benchmark", Emoji: "🚦", Status: StatusOk}
-	var details []string
+// dnsResolvers are benchmarked in this order; tests replace them.
+var dnsResolvers = []dnsResolver{
+	{"System", ""},
+	{"Google", "8.8.8.8:53"},
+	{"Cloudflare", "1.1.1.1:53"},
+}
 
-	for name, addr := range resolvers {
-		start := time.Now()
-		var err error
-		if addr == "" {
-			_, err = net.LookupIP("google.com")
+// benchmarkResolvers returns the system resolver followed by the configured
+// servers, or dnsResolvers when none are configured. A server without a port
+// is queried on 53.
+func benchmarkResolvers(servers []string) []dnsResolver {
+	if len(servers) == 0 {
+		return dnsResolvers
+	}
+	resolvers := []dnsResolver{{"System", ""}}
+	for _, server := range servers {
+		name := server
+		if host, _, err := net.SplitHostPort(server); err == nil {
+			name = host
 		} else {
-			r := &net.Resolver{
-				PreferGo: true,
-				Dial: func(ctx context.Context, _, address string) (net.Conn, error) {
-					d := net.Dialer{Timeout: 2 * time.Second}
-					return d.DialContext(ctx, "udp", address)
-				},
-			}
-			_, err = r.LookupIP(context.Background(), "ip", "google.com")
+			server = net.JoinHostPort(server, "53")
 		}
-		dur := time.Since(start)
+		resolvers = append(resolvers, dnsResolver{name, server})
+	}
+	return resolvers
+}
+
+// dnsOutcome is the result of probing one dnsResolver.
+type dnsOutcome struct {
+	dur     time.Duration
+	err     error
+	tcpOnly bool
+}
 
+// CheckDNSBenchmark compares performance across multiple DNS resolvers.
+func CheckDNSBenchmark(ctx context.Context) Result {
+	res := Result{Name: "DNS Benchmark", Emoji: "🚦", Status: StatusOk}
+	c := activeConfig(ctx)
+	resolvers := benchmarkResolvers(c.DNSResolvers)
+
+	// Each resolver runs concurrently under its own timeout, so a blocked
+	// one neither delays the others nor hides their results.
+	outcomes := make([]dnsOutcome, len(resolvers))
+	encrypted := encryptedResolvers
+	encOutcomes := make([]dnsOutcome, len(encrypted))
+	var wg sync.WaitGroup
+	for i, r := range resolvers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcomes[i] = probeResolver(ctx, r.server)
+		}()
+	}
+	for i, r := range encrypted {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			encOutcomes[i] = probeEncrypted(ctx, r)
+		}()
+	}
+	wg.Wait()
+
+	var details, udpBroken []string
+	var systemErr error
+	var plainFastest time.Duration
+	// fastest is the quickest resolver other than the system's, which the
+	// guided fix can switch to.
+	fastest := -1
+	for i, r := range resolvers {
+		o := outcomes[i]
 		status := "OK"
-		if err != nil {
+		switch {
+		case o.err != nil:
 			status = "FAIL"
+		case o.tcpOnly:
+			status = "UDP FAIL, TCP OK"
+			udpBroken = append(udpBroken, r.name)
 		}
-		details = append(details, fmt.Sprintf("%-10s: %s (%s)", name, dur.Round(time.Microsecond), status))
-
-		if name == "System" {
-			res.Latency = dur
+		details = append(details, fmt.Sprintf("%-10s: %s (%s)", r.name, o.dur.Round(time.Microsecond), status))
+		if r.server == "" {
+			res.Latency, systemErr = o.dur, o.err
+		}
+		if o.err == nil && (plainFastest == 0 || o.dur < plainFastest) {
+			plainFastest = o.dur
 		}
+		if o.err == nil && !o.tcpOnly && r.server != "" && (fastest < 0 || o.dur < outcomes[fastest].dur) {
+			fastest = i
+		}
+	}
+	for i, r := range encrypted {
+		status := "OK"
+		if encOutcomes[i].err != nil {
+			status = "FAIL"
+		}
+		details = append(details, fmt.Sprintf("%s %-10s: %s (%s)", r.proto, r.name, encOutcomes[i].dur.Round(time.Microsecond), status))
 	}
+	comparison, blocked := encryptedSummary(encrypted, encOutcomes, plainFastest)
+	details = append(details, comparison...)
 
 	res.Details = formatDetailsWithPrefixes(details)
-	if res.Latency > 200*time.Millisecond {
-		res.Status = StatusWarning
-		res.Message = "High DNS latency detected"
-		res.Fix = "Switch to a faster DNS provider like Cloudflare (1.1.1.1)."
-	} else {
-		res.Message = "Fast and healthy"
+	if ns := systemNameservers(ctx); len(ns) > 0 {
+		res.Facts = map[string]string{FactNameservers: strings.Join(ns, ",")}
+	}
+	if fastest >= 0 {
+		if host, port, err := net.SplitHostPort(resolvers[fastest].server); err == nil && port == "53" {
+			if res.Facts == nil {
+				res.Facts = map[string]string{}
+			}
+			res.Facts[FactFastestResolver] = host
+		}
+	}
+	res.Message = "Fast and healthy"
+	if len(blocked) > 0 && plainFastest > 0 {
+		// Informational: plain DNS works, but the network stops encrypted DNS.
+		res.Reason = ReasonEncryptedDNSBlocked
+		res.Message += "; " + blockedMessage(blocked)
+	}
+	if warnIfSlow(&res, c.DNSSlow, ReasonDNSSlow, "High DNS latency detected") {
+		res.Fix = "Flush the DNS cache; if lookups stay slow, switch to a faster DNS provider like Cloudflare (1.1.1.1)."
+		res.FixCommand = shellLine(activePlatform().flushDNSCommands(), true)
+	}
+	if systemErr != nil {
+		// The time a failed lookup took is no latency worth reporting.
+		res.Status = StatusError
+		res.Reason = ReasonDNSFailed
+		res.Latency = 0
+		res.Message = "No resolver answers; names cannot be resolved"
+		res.Fix = "Check the connection; if it works, restart the router."
+		res.FixCommand = ""
+		if plainFastest > 0 {
+			res.Message = "The system resolver fails while public resolvers answer"
+			res.Fix = "Flush the DNS cache; if lookups still fail, switch to a public resolver such as Cloudflare (1.1.1.1)."
+			res.FixCommand = shellLine(activePlatform().flushDNSCommands(), true)
+		}
+	}
+	if len(udpBroken) > 0 {
+		// A broken UDP path explains slow or failing lookups better than the resolver does.
+		res.Status = max(res.Status, StatusWarning)
+		res.Reason = ReasonDNSUDPBlocked
+		res.Message = "DNS over UDP fails but TCP works (" + strings.Join(udpBroken, ", ") + ")"
+		res.Fix = "Large UDP replies are likely dropped: check the MTU, fragmentation, or a firewall filtering UDP/53."
+		res.FixCommand = ""
 	}
 	return res
 }
 
+// probeResolver times a lookup through server ("" for the system resolver),
+// retrying a failed UDP query over TCP/53 to tell a broken UDP path from a
+// dead resolver.
+func probeResolver(ctx context.Context, server string) dnsOutcome {
+	start := time.Now()
+	if server == "" {
+		ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+		defer cancel()
+		err := systemLookup(ctx, "google.com")
+		return dnsOutcome{dur: time.Since(start), err: err}
+	}
+
+	err := lookupDirect(ctx, server, "udp")
+	tcpOnly := false
+	if needsTCPFallback(err) {
+		if errTCP := lookupDirect(ctx, server, "tcp"); errTCP == nil {
+			err, tcpOnly = nil, true
+		}
+	}
+	return dnsOutcome{dur: time.Since(start), err: err, tcpOnly: tcpOnly}
+}
+
+// systemLookup resolves host through the system resolver; tests replace it.
+var systemLookup = func(ctx context.Context, host string) error {
+	_, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	return err
+}
+
+// lookupDirect resolves a probe name through server over network only.
+func lookupDirect(ctx context.Context, server, network string) error {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	_, err := newDirectResolver(server, network).LookupIP(ctx, "ip", "google.com")
+	return err
+}
+
+// needsTCPFallback reports whether a failed UDP lookup is worth retrying over
+// TCP. A definitive answer such as NXDOMAIN shows UDP works, so it is not.
+func needsTCPFallback(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	return true
+}
+
 // CheckPrivateRelay detects the state of Apple's iCloud Private Relay.
-func CheckPrivateRelay(verbose bool) Result {
+func CheckPrivateRelay(ctx context.Context, verbose bool) Result {
 	start := time.Now()
-	ips, err := net.LookupIP("mask.icloud.com")
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", "mask.icloud.com")
 	dur := time.Since(start)
 
 	res := Result{Name: "iCloud Private Relay", Emoji: "🛡️", Latency: dur, Status: StatusOk}
@@ -377,53 +841,61 @@ func CheckPrivateRelay(verbose bool) Result {
 	return res
 }
 
-// FastTraceroute performs a concurrent traceroute to visualize the network path.
-func FastTraceroute(verbose bool) Result {
-	target := "1.1.1.1"
+// FastTraceroute traces the path to the WAN host with ICMP, UDP or TCP
+// probes and lists each hop with its name, network (AS) and round trip
+// times, so it shows where the path leaves the ISP.
+func FastTraceroute(ctx context.Context, verbose bool) Result {
 	res := Result{Name: "Fast Trace", Emoji: "📍", Status: StatusOk}
 	if !verbose {
+		res.Status = StatusSkipped
 		res.Message = "Use -v flag to view the network path"
 		return res
 	}
 
+	c := activeConfig(ctx)
+	hops, via := traceRoute(ctx, c, c.WANHost)
 	var wg sync.WaitGroup
-	hops := make([]string, 11)
-	for i := 1; i <= 10; i++ {
-		wg.Add(1)
-		go func(ttl int) {
-			defer wg.Done()
-			out, _ := exec.Command("ping", "-c", "1", "-t", strconv.Itoa(ttl), target).Output()
-			m := rePingRoute.FindStringSubmatch(string(out))
-			if len(m) > 1 {
-				hops[ttl] = fmt.Sprintf("Hop %2d: %s", ttl, m[1])
-			} else {
-				hops[ttl] = fmt.Sprintf("Hop %2d: * (Request timed out)", ttl)
-			}
-		}(i)
-	}
+	wg.Add(2)
+	go func() { defer wg.Done(); resolveHopNames(ctx, hops) }()
+	go func() { defer wg.Done(); annotateASNs(ctx, hops) }()
 	wg.Wait()
 
-	var details []string
-	for _, h := range hops {
-		if h != "" {
-			details = append(details, h)
-		}
+	details := []string{"Method: " + via}
+	if path := asPath(hops); path != "" {
+		details = append(details, "Networks: "+path)
 	}
-	res.Details = formatDetailsWithPrefixes(details)
+	answeredLater := false
+	lines := make([]string, len(hops))
+	routers := make([]string, len(hops))
+	for i := len(hops) - 1; i >= 0; i-- {
+		lines[i] = formatHop(i+1, hops[i], answeredLater)
+		answeredLater = answeredLater || hops[i].Router != ""
+		routers[i] = cmp.Or(hops[i].Router, "*")
+	}
+	res.Details = formatDetailsWithPrefixes(append(details, lines...))
+	if len(routers) > 0 {
+		res.Facts = map[string]string{FactTraceHops: strings.Join(routers, ",")}
+	}
+	gradeTrace(&res, hops, c.WANHost, c.TraceMode)
 	return res
 }
 
 // CheckCaptivePortal verifies if the user is behind a captive portal.
-func CheckCaptivePortal(verbose bool) Result {
+func CheckCaptivePortal(ctx context.Context, verbose bool) Result {
 	start := time.Now()
+	portalURL := activeConfig(ctx).CaptivePortalURL
 	client := http.Client{Timeout: 3 * time.Second}
-	resp, err := client.Get("http://captive.apple.com/hotspot-detect.html")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, portalURL, nil)
+	var resp *http.Response
+	if err == nil {
+		resp, err = client.Do(req)
+	}
 	if err != nil {
-		return Result{Name: "Captive Portal", Emoji: "🍎", Status: StatusError, Message: "HTTP health check failed"}
+		return Result{Name: "Captive Portal", Emoji: "🍎", Status: StatusError, Message: "HTTP health check failed", Reason: ReasonProbeFailed}
 	}
 	defer func() {
 		if errClose := resp.Body.Close(); errClose != nil {
-			log.Printf("Network Error: Failed to close response body: %v", errClose)
+			slog.Debug("could not close response body", "err", errClose)
 		}
 	}()
 	dur := time.Since(start)
@@ -441,22 +913,72 @@ func CheckCaptivePortal(verbose bool) Result {
 		res.Details = formatDetailsWithPrefixes(details)
 	}
 
-	lr := io.LimitReader(resp.Body, 1024)
+	lr := io.LimitReader(resp.Body, 4096)
 	body, _ := io.ReadAll(lr)
 	if !strings.Contains(string(body), "Success") {
 		res.Status = StatusWarning
 		res.Message = "Login Required (Captive Portal detected)"
+		res.Reason = ReasonCaptivePortal
 		res.Fix = "Open your browser to sign in to the network."
+		loginURL := portalLoginURL(portalURL, resp, body)
+		if loginURL != "" {
+			res.Facts = map[string]string{FactPortalURL: loginURL}
+			res.Fix = "Sign in to the network at " + loginURL + " (or rerun with --open-portal)."
+		} else {
+			loginURL = portalURL
+		}
+		// The URL comes from the portal, so it is quoted for the shell.
+		res.FixCommand = shellLine([][]string{activePlatform().openURLCommand(loginURL)}, false)
 	}
 	return res
 }
 
-func getPrimaryInterface() (string, error) {
-	out, err := exec.Command("route", "-n", "get", "default").Output()
+// portalLoginURL returns the login page a captive portal sent the probe to:
+// the last redirect the client followed, or else a meta refresh or script
+// redirect in body. Only http and https URLs are returned, since the result
+// may be handed to the browser.
+func portalLoginURL(probeURL string, resp *http.Response, body []byte) string {
+	if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != probeURL {
+		if u := resp.Request.URL; u.Scheme == "http" || u.Scheme == "https" {
+			return reSanitizeHTTP.ReplaceAllString(u.String(), "")
+		}
+	}
+	if m := rePortalURL.FindSubmatch(body); m != nil {
+		if u, err := url.Parse(string(m[1])); err == nil && u.Host != "" {
+			return reSanitizeHTTP.ReplaceAllString(u.String(), "")
+		}
+	}
+	return ""
+}
+
+// dhcpRenewCommand returns the command that renews the DHCP lease on iface,
+// or "" when the client holding it is not one wtfi can renew through.
+func dhcpRenewCommand(ctx context.Context, iface string) string {
+	cmds := activePlatform().renewDHCPCommands(dhcpClient(ctx, iface), iface)
+	if cmds == nil {
+		return ""
+	}
+	return shellLine(cmds, true)
+}
+
+func getPrimaryInterface(ctx context.Context) (string, error) {
+	p := activePlatform()
+	out, err := defaultRoute.get(ctx)
+	if err == nil {
+		if iface, errParse := p.parseInterface(out); errParse == nil {
+			return iface, nil
+		}
+	}
+	// IPv6-only networks have no IPv4 default route at all.
+	if out6, err6 := defaultRoute6.get(ctx); err6 == nil {
+		if iface, errParse := p.parseInterface(out6); errParse == nil {
+			return iface, nil
+		}
+	}
 	if err != nil {
 		return "", err
 	}
-	return parseInterface(string(out))
+	return p.parseInterface(out)
 }
 
 func parseInterface(output string) (string, error) {
@@ -467,12 +989,12 @@ func parseInterface(output string) (string, error) {
 	return "", fmt.Errorf("no primary interface found")
 }
 
-func getGatewayIP() (string, error) {
-	out, err := exec.Command("route", "-n", "get", "default").Output()
+func getGatewayIP(ctx context.Context) (string, error) {
+	out, err := defaultRoute.get(ctx)
 	if err != nil {
 		return "", err
 	}
-	return parseGateway(string(out))
+	return activePlatform().parseGateway(out)
 }
 
 func parseGateway(output string) (string, error) {
@@ -483,11 +1005,43 @@ func parseGateway(output string) (string, error) {
 	return "", fmt.Errorf("no gateway ip found")
 }
 
-func ping(ip string) (time.Duration, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func getGatewayIP6(ctx context.Context) (string, error) {
+	out, err := defaultRoute6.get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return activePlatform().parseGateway6(out)
+}
+
+// parseGateway6 extracts the IPv6 default gateway, keeping the zone of a
+// link-local address (fe80::1%en0) since ping6 needs it.
+func parseGateway6(output string) (string, error) {
+	m := reRouteGw6.FindStringSubmatch(output)
+	if len(m) > 1 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("no ipv6 gateway found")
+}
+
+// isIPv6Only reports whether there is an IPv6 default gateway but no IPv4 one.
+func isIPv6Only(ctx context.Context) bool {
+	if _, err := getGatewayIP(ctx); err == nil {
+		return false
+	}
+	_, err := getGatewayIP6(ctx)
+	return err == nil
+}
+
+// ping sends a single echo request with the given payload size and interval;
+// zero values keep ping's defaults.
+func ping(ctx context.Context, ip string, size int, interval time.Duration) (time.Duration, error) {
+	if stats, err := nativeEcho(ctx, ip, false, 1, interval, size, pingTimeout); !errors.Is(err, errICMPUnavailable) {
+		return echoLatency(ip, stats, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "ping", "-c", "1", ip)
-	out, err := cmd.Output()
+	cmd := activePlatform().pingCommand(ip, size, interval, 0)
+	out, err := runCommand(ctx, cmd[0], cmd[1:]...)
 	if err != nil {
 		return 0, err
 	}
@@ -507,92 +1061,177 @@ func parsePing(output string) (time.Duration, error) {
 }
 
 // ping6 executes an IPv6 ping command.
-func ping6(ip string) (time.Duration, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func ping6(ctx context.Context, ip string) (time.Duration, error) {
+	if stats, err := nativeEcho(ctx, ip, true, 1, 0, 0, pingTimeout); !errors.Is(err, errICMPUnavailable) {
+		return echoLatency(ip, stats, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "ping6", "-c", "1", ip)
-	out, err := cmd.Output()
+	cmd := activePlatform().ping6Command(ip)
+	out, err := runCommand(ctx, cmd[0], cmd[1:]...)
 	if err != nil {
 		return 0, err
 	}
 	return parsePing(string(out))
 }
 
+// echoLatency turns a single native echo into ping's result.
+func echoLatency(ip string, stats PingStats, err error) (time.Duration, error) {
+	if err != nil {
+		return 0, err
+	}
+	if stats.Received == 0 {
+		return 0, fmt.Errorf("no reply from %s", ip)
+	}
+	return stats.Avg, nil
+}
+
 // tcpPing attempts to establish a TCP connection to the specified address.
-func tcpPing(address string) (time.Duration, error) {
+func tcpPing(ctx context.Context, address string) (time.Duration, error) {
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+	d := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", address)
 	if err != nil {
 		return 0, err
 	}
 	if errClose := conn.Close(); errClose != nil {
-		log.Printf("diagnostic: could not close tcpPing connection: %v", errClose)
+		slog.Debug("could not close tcpPing connection", "err", errClose)
 	}
 	return time.Since(start), nil
 }
 
-// MeasureLossAndJitter performs a 5-packet ping with 0.2s interval to calculate loss and jitter.
-func MeasureLossAndJitter(ip string, isIPv6 bool) (float64, float64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	cmdName := "ping"
-	if isIPv6 {
-		cmdName = "ping6"
+// burstInterval spaces the echo requests of a loss and jitter burst.
+const burstInterval = 200 * time.Millisecond
+
+// MeasureLossAndJitter sends Config.PingBurst echo requests of
+// Config.PingSize, Config.PingInterval or 0.2s apart, and summarizes them;
+// jitter is the standard deviation of the round trips.
+func MeasureLossAndJitter(ctx context.Context, ip string, isIPv6 bool) (PingStats, error) {
+	c := activeConfig(ctx)
+	count, interval := c.PingBurst, cmp.Or(c.PingInterval, burstInterval)
+	stats, err := nativeEcho(ctx, ip, isIPv6, count, interval, c.PingSize, pingTimeout)
+	if !errors.Is(err, errICMPUnavailable) {
+		return stats, err
 	}
 
-	cmd := exec.CommandContext(ctx, cmdName, "-c", "5", "-i", "0.2", ip)
-	out, err := cmd.Output()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(count)*interval+4*time.Second)
+	defer cancel()
+
+	cmd := activePlatform().statsPingCommand(ip, isIPv6, count, c.PingSize, interval)
+	out, err := runCommand(ctx, cmd[0], cmd[1:]...)
 	// Ignore errors like exit status 68 if some packets drop, we still parse the output
 	if err != nil && len(out) == 0 {
-		return 0, 0, err
+		return PingStats{}, err
 	}
+	return parsePingStats(string(out))
+}
 
-	output := string(out)
-
-	lossStr := "0"
-	if m := reLoss.FindStringSubmatch(output); len(m) > 1 {
-		lossStr = m[1]
+// parsePingStats reads the summary ping prints after a series. Only the
+// summary is available, so RTTs is left empty.
+func parsePingStats(output string) (PingStats, error) {
+	m := rePingCount.FindStringSubmatch(output)
+	if len(m) < 3 {
+		return PingStats{}, fmt.Errorf("failed to parse ping statistics")
 	}
-
-	jitterStr := "0.0"
-	if m := reJitter.FindStringSubmatch(output); len(m) > 1 {
-		jitterStr = m[1]
+	var s PingStats
+	s.Sent, _ = strconv.Atoi(m[1])
+	s.Received, _ = strconv.Atoi(m[2])
+	if m := rePingSummary.FindStringSubmatch(output); len(m) > 4 {
+		for i, dst := range []*time.Duration{&s.Min, &s.Avg, &s.Max, &s.StdDev} {
+			ms, err := strconv.ParseFloat(m[i+1], 64)
+			if err != nil {
+				return PingStats{}, fmt.Errorf("failed to parse ping statistics: %w", err)
+			}
+			*dst = time.Duration(ms * float64(time.Millisecond))
+		}
 	}
+	return s, nil
+}
 
-	loss, err := strconv.ParseFloat(lossStr, 64)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to parse loss: %w", err)
+// warnIfLossy records the loss and jitter of s as facts and flags them
+// above the configured limits.
+// A burst with no replies at all is ICMP filtering or an outage, which the
+// caller grades, and an error result is left alone.
+func warnIfLossy(ctx context.Context, res *Result, s PingStats) {
+	c := activeConfig(ctx)
+	if s.Received > 0 {
+		res.SetFact(FactLoss, strconv.FormatFloat(s.Loss(), 'f', 1, 64))
+		res.SetFact(FactJitter, strconv.FormatFloat(float64(s.StdDev)/float64(time.Millisecond), 'f', 2, 64))
 	}
-	jitter, err := strconv.ParseFloat(jitterStr, 64)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to parse jitter: %w", err)
+	switch {
+	case s.Received == 0 || res.Status == StatusError:
+	case s.Loss() > c.MaxLoss:
+		res.Status = StatusWarning
+		res.Reason = ReasonPacketLoss
+		res.Message = fmt.Sprintf("Packet loss detected (%.1f%%)", s.Loss())
+		res.Fix = "Intermittent loss usually means Wi-Fi interference, a bad cable, or a saturated link."
+		if c.PingSize > 0 {
+			res.Message = fmt.Sprintf("Packet loss detected (%.1f%% of %d-byte pings)", s.Loss(), c.PingSize)
+			res.Fix += " If only large pings are lost, run the path-mtu check."
+		}
+	case s.StdDev > c.MaxJitter:
+		res.Status = StatusWarning
+		res.Reason = ReasonHighJitter
+		res.Message = fmt.Sprintf("High jitter detected (%v)", s.StdDev.Round(100*time.Microsecond))
+		res.Fix = "Unstable latency hurts calls and games; look for interference or uploads saturating the link."
 	}
+}
 
-	return loss, jitter, nil
+// burstDetails describes a loss and jitter burst for Result.Details.
+func burstDetails(proto string, s PingStats) []string {
+	details := []string{fmt.Sprintf("Quality (%s): Loss: %.1f%% (%d/%d), Jitter: %.2fms",
+		proto, s.Loss(), s.Sent-s.Received, s.Sent, float64(s.StdDev)/float64(time.Millisecond))}
+	if s.Received > 0 {
+		details = append(details, fmt.Sprintf("RTT (%s): min %v, avg %v, max %v", proto,
+			s.Min.Round(100*time.Microsecond), s.Avg.Round(100*time.Microsecond), s.Max.Round(100*time.Microsecond)))
+	}
+	return details
 }
 
 // CheckL3WAN verifies WAN backbone reachability across IPv4, IPv6, and TCP.
-func CheckL3WAN() Result {
+func CheckL3WAN(ctx context.Context) Result {
 	var wg sync.WaitGroup
 	var latIPv4, latIPv6, latTCP time.Duration
 	var errIPv4, errIPv6, errTCP error
-	var loss, jitter float64
+	var qos PingStats
 	var errQoS error
+	var pct LatencyPercentiles
+	var errPct error
+	c := activeConfig(ctx)
+	// Without any IPv4 route, grade the IPv6 path instead of reporting
+	// IPv4 failures as an outage.
+	v6only := isIPv6Only(ctx)
+	tcpTarget := net.JoinHostPort(c.WANHost, "443")
+	if v6only {
+		tcpTarget = wanTargetTCP6
+	}
 
+	if c.LatencySamples > 0 && !v6only {
+		wg.Add(1)
+		go func() { defer wg.Done(); pct, errPct = sampleLatency(ctx, c.WANHost, c.LatencySamples) }()
+	}
 	wg.Add(4)
-	go func() { defer wg.Done(); latIPv4, errIPv4 = ping(wanTargetIPv4) }()
-	go func() { defer wg.Done(); latIPv6, errIPv6 = ping6(wanTargetIPv6) }()
-	go func() { defer wg.Done(); latTCP, errTCP = tcpPing(wanTargetTCP) }()
+	go func() {
+		defer wg.Done()
+		if v6only {
+			errIPv4 = errors.New("no IPv4 route")
+			return
+		}
+		latIPv4, errIPv4 = ping(ctx, c.WANHost, c.PingSize, c.PingInterval)
+	}()
+	go func() { defer wg.Done(); latIPv6, errIPv6 = ping6(ctx, wanTargetIPv6) }()
+	go func() { defer wg.Done(); latTCP, errTCP = tcpPing(ctx, tcpTarget) }()
 	var qosProto = "IPv4"
 	go func() {
 		defer wg.Done()
-		loss, jitter, errQoS = MeasureLossAndJitter(wanTargetIPv4, false)
-		if errQoS != nil || loss == 100 {
+		if !v6only {
+			qos, errQoS = MeasureLossAndJitter(ctx, c.WANHost, false)
+		}
+		if v6only || errQoS != nil || qos.Received == 0 {
 			// Fallback conditionally to IPv6 if IPv4 is impaired
-			lossIPv6, jitterIPv6, errQoSV6 := MeasureLossAndJitter(wanTargetIPv6, true)
-			if errQoSV6 == nil && lossIPv6 < 100 {
-				loss, jitter, errQoS = lossIPv6, jitterIPv6, errQoSV6
+			qosIPv6, errQoSV6 := MeasureLossAndJitter(ctx, wanTargetIPv6, true)
+			if errQoSV6 == nil && qosIPv6.Received > 0 {
+				qos, errQoS = qosIPv6, nil
 				qosProto = "IPv6"
 			}
 		}
@@ -602,33 +1241,52 @@ func CheckL3WAN() Result {
 	res := Result{Name: "Internet Reachability", Emoji: "🌐", Status: StatusOk}
 
 	// Overall Status Determination
-	if errIPv4 != nil && errTCP != nil {
+	primaryLat, errPrimary := latIPv4, errIPv4
+	if v6only {
+		primaryLat, errPrimary = latIPv6, errIPv6
+	}
+	if errPrimary != nil && errTCP != nil {
 		res.Status = StatusError
 		res.Message = "Offline (Both ICMP and TCP failed)"
-	} else if errIPv4 != nil && errTCP == nil {
+		res.Reason = ReasonOffline
+	} else if isPermissionError(errPrimary) {
+		res.Message = "Routing operational (ICMP unavailable; measured with TCP :443)"
+		res.Reason = ReasonICMPUnavailable
+		res.Latency = latTCP
+	} else if errPrimary != nil && errTCP == nil {
 		res.Message = "Firewalled ICMP detected"
 		res.Latency = latTCP
 	} else {
 		res.Message = "Routing operational"
-		res.Latency = latIPv4
+		res.Latency = primaryLat
+	}
+	if v6only && res.Status == StatusOk {
+		res.Message += " (IPv6-only network detected)"
+		if res.Reason == "" {
+			res.Reason = ReasonIPv6Only
+		}
 	}
 
-	if res.Latency > 150*time.Millisecond {
-		res.Status = StatusWarning
-		res.Message = "High WAN latency"
+	warnIfSlow(&res, c.WANSlow, ReasonHighLatency, "High WAN latency")
+	if errQoS == nil {
+		warnIfLossy(ctx, &res, qos)
 	}
 
 	// Format Details
 	var details []string
 	var ipv4Status string
-	if errIPv4 == nil {
+	if v6only {
+		ipv4Status = "No IPv4 route (IPv6-only network)"
+	} else if errIPv4 == nil {
 		ipv4Status = fmt.Sprintf("%v (Reachable)", latIPv4.Round(time.Millisecond))
+	} else if isPermissionError(errIPv4) {
+		ipv4Status = "ICMP not permitted"
 	} else if errTCP == nil {
 		ipv4Status = "TIMEOUT (Dropped)"
 	} else {
 		ipv4Status = "TIMEOUT (Unreachable)"
 	}
-	details = append(details, fmt.Sprintf("IPv4 (%s): %s", wanTargetIPv4, ipv4Status))
+	details = append(details, fmt.Sprintf("IPv4 (%s): %s", c.WANHost, ipv4Status))
 
 	ipv6Status := "TIMEOUT (Unreachable)"
 	if errIPv6 == nil {
@@ -642,13 +1300,22 @@ func CheckL3WAN() Result {
 	} else {
 		tcpStatus = "TIMEOUT (Failed)"
 	}
-	details = append(details, fmt.Sprintf("TCP 443 (%s): %s", wanTargetIPv4, tcpStatus))
+	tcpHost, _, _ := net.SplitHostPort(tcpTarget)
+	details = append(details, fmt.Sprintf("TCP 443 (%s): %s", tcpHost, tcpStatus))
 
 	if errQoS == nil {
-		details = append(details, fmt.Sprintf("Quality (%s): Loss: %.1f%%, Jitter: %.2fms", qosProto, loss, jitter))
+		details = append(details, burstDetails(qosProto, qos)...)
 	} else {
 		details = append(details, "Quality: Measurement failed or timed out")
 	}
+	if c.LatencySamples > 0 {
+		if errPct == nil {
+			res.Percentiles = &pct
+			details = append(details, "Percentiles: "+pct.String())
+		} else {
+			details = append(details, "Percentiles: sampling failed")
+		}
+	}
 
 	res.Details = formatDetailsWithPrefixes(details)
 
@@ -1,6 +1,14 @@
 package diagnostic
 
 import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -30,6 +38,23 @@ round-trip min/avg/max/stddev = 12.345/12.345/12.345/0.000 ms
 	}
 }
 
+func TestPingArgs(t *testing.T) {
+	tests := []struct {
+		size     int
+		interval time.Duration
+		want     string
+	}{
+		{0, 0, "-c 1 1.1.1.1"},
+		{1472, 0, "-c 1 -s 1472 1.1.1.1"},
+		{0, 250 * time.Millisecond, "-c 1 -i 0.25 1.1.1.1"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(pingArgs("1.1.1.1", tt.size, tt.interval), " "); got != tt.want {
+			t.Errorf("pingArgs(%d, %v) = %q, want %q", tt.size, tt.interval, got, tt.want)
+		}
+	}
+}
+
 func TestParseInterface(t *testing.T) {
 	output := `   route to: default
 destination: default
@@ -70,6 +95,48 @@ func TestParseWiFiInfo(t *testing.T) {
 	}
 }
 
+func TestParseWiFiInfoFacts(t *testing.T) {
+	output := `      Current Network Information:
+        MyHomeWiFi:
+          PHY Mode: 802.11ax
+          BSSID: a4:83:e7:1:2:3
+          Signal / Noise: -61 dBm / -92 dBm
+`
+	res := parseWiFiInfo(output, "en0", false)
+	if res.Facts[FactBSSID] != "a4:83:e7:01:02:03" {
+		t.Errorf("Expected normalized BSSID, got %q", res.Facts[FactBSSID])
+	}
+	if res.Facts[FactRSSI] != "-61" {
+		t.Errorf("Expected RSSI -61, got %q", res.Facts[FactRSSI])
+	}
+	if res.Facts[FactSSID] != "MyHomeWiFi" {
+		t.Errorf("Expected SSID MyHomeWiFi, got %q", res.Facts[FactSSID])
+	}
+}
+
+func TestParseSystemProfilerRadio(t *testing.T) {
+	output := `      Current Network Information:
+        MyHomeWiFi:
+          PHY Mode: 802.11ax
+          Channel: 36 (5GHz, 80MHz)
+          Signal / Noise: -50 dBm / -92 dBm
+          Transmit Rate: 1200
+          MCS Index: 11
+      Other Local Wi-Fi Networks:
+        Neighbor:
+          Channel: 1 (2GHz, 20MHz)
+`
+	link := parseSystemProfiler(output, false)
+	want := wifiLink{SSID: "MyHomeWiFi", RSSI: -50, Noise: -92, TxRate: 1200, Channel: "36 (5GHz, 80MHz)", PHYMode: "802.11ax", MCS: 11}
+	if !reflect.DeepEqual(link, want) {
+		t.Errorf("Expected %+v, got %+v", want, link)
+	}
+	facts := parseWiFiInfo(output, "en0", false).Facts
+	if facts[FactChannel] != "36 (5GHz, 80MHz)" || facts[FactMCS] != "11" || facts[FactNoise] != "-92" || facts[FactTxRate] != "1200" {
+		t.Errorf("Expected the radio details as facts, got %v", facts)
+	}
+}
+
 func TestParseGateway(t *testing.T) {
 	output := `   route to: default
 destination: default
@@ -88,6 +155,45 @@ destination: default
 	}
 }
 
+func TestParseGateway6(t *testing.T) {
+	output := `   route to: ::
+destination: ::
+       mask: default
+    gateway: fe80::1%en0
+  interface: en0
+      flags: <UP,GATEWAY,DONE,STATIC,PRCLONING,GLOBAL>
+ recvpipe  sendpipe  ssthresh  rtt,msec    rttvar  hopcount      mtu     expire
+       0         0         0         0         0         0      1500         0 `
+	gw, err := parseGateway6(output)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gw != "fe80::1%en0" {
+		t.Errorf("Expected fe80::1%%en0, got %s", gw)
+	}
+	if _, err := parseGateway6("    gateway: 192.168.1.1\n"); err == nil {
+		t.Error("Expected an IPv4 gateway to be rejected")
+	}
+}
+
+func TestCheckL3GatewayIPv6Only(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get -inet6 default": "    gateway: fe80::1%en0\n  interface: en0\n",
+		"ping6 -c 1 fe80::1%en0":      "round-trip min/avg/max/std-dev = 3.0/3.0/3.0/0.000 ms\n",
+	}})
+
+	res := CheckL3Gateway(context.Background(), false)
+	if res.Status != StatusOk || res.Reason != ReasonIPv6Only {
+		t.Errorf("Expected an OK ipv6_only result, got %v/%s (%s)", res.Status, res.Reason, res.Message)
+	}
+	if !strings.Contains(res.Message, "IPv6-only network detected") {
+		t.Errorf("Expected the IPv6-only network to be reported, got %q", res.Message)
+	}
+	if iface, err := getPrimaryInterface(context.Background()); err != nil || iface != "en0" {
+		t.Errorf("Expected the interface from the IPv6 route, got %q (%v)", iface, err)
+	}
+}
+
 func TestParsePingError(t *testing.T) {
 	output := `ping: cannot resolve 1.1.1.1: Unknown host`
 	_, err := parsePing(output)
@@ -95,3 +201,187 @@ func TestParsePingError(t *testing.T) {
 		t.Fatal("Expected error, got nil")
 	}
 }
+
+func TestSignalQuality(t *testing.T) {
+	tests := map[int]int{
+		-30:  100,
+		-50:  100,
+		-60:  75,
+		-70:  50,
+		-78:  30,
+		-90:  0,
+		-100: 0,
+	}
+	for rssi, want := range tests {
+		if got := signalQuality(rssi); got != want {
+			t.Errorf("signalQuality(%d): expected %d%%, got %d%%", rssi, want, got)
+		}
+	}
+}
+
+func TestParseWiFiInfoWeakSignal(t *testing.T) {
+	output := `      Current Network Information:
+        FarAway:
+          Signal / Noise: -82 dBm / -95 dBm
+`
+	res := parseWiFiInfo(output, "en0", false)
+	if res.Status != StatusWarning || res.Reason != ReasonWeakSignal {
+		t.Errorf("Expected a weak_signal warning for 20%% signal, got %v/%s", res.Status, res.Reason)
+	}
+	if !strings.Contains(res.Message, "(20%)") {
+		t.Errorf("Expected percentage in message, got %s", res.Message)
+	}
+}
+
+func TestAddDetails(t *testing.T) {
+	r := Result{Details: formatDetailsWithPrefixes([]string{"first", "second"})}
+	r.AddDetails("third")
+	want := []string{"├─ first", "├─ second", "└─ third"}
+	if strings.Join(r.Details, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, r.Details)
+	}
+}
+
+func TestEstimateDistanceMeters(t *testing.T) {
+	tests := []struct {
+		rssi, txPower, exponent int
+		want                    float64
+	}{
+		{-40, -40, 2, 1},
+		{-60, -40, 2, 10},
+		{-70, -40, 3, 10},
+		{-80, -40, 2, 100},
+		{-60, -40, 0, 0},
+	}
+	for _, tt := range tests {
+		got := estimateDistanceMeters(tt.rssi, tt.txPower, tt.exponent)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("estimateDistanceMeters(%d, %d, %d) = %v, want %v", tt.rssi, tt.txPower, tt.exponent, got, tt.want)
+		}
+	}
+}
+
+func TestWarnIfSlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency time.Duration
+		limit   time.Duration
+		reason  Reason
+		warned  bool
+	}{
+		{"fast dns", 20 * time.Millisecond, dnsSlowThreshold, ReasonDNSSlow, false},
+		{"slow dns", 350 * time.Millisecond, dnsSlowThreshold, ReasonDNSSlow, true},
+		{"slow wan", 180 * time.Millisecond, wanSlowThreshold, ReasonHighLatency, true},
+	}
+	for _, tt := range tests {
+		res := Result{Latency: tt.latency, Status: StatusOk, Message: "fine"}
+		if got := warnIfSlow(&res, tt.limit, tt.reason, "slow"); got != tt.warned {
+			t.Errorf("%s: expected warned=%v, got %v", tt.name, tt.warned, got)
+		}
+		if tt.warned && (res.Status != StatusWarning || res.Reason != tt.reason) {
+			t.Errorf("%s: expected %s warning, got %v/%s", tt.name, tt.reason, res.Status, res.Reason)
+		}
+		if !tt.warned && res.Reason != "" {
+			t.Errorf("%s: expected no reason, got %s", tt.name, res.Reason)
+		}
+	}
+}
+
+func TestCheckCaptivePortalReason(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "<HTML><BODY>Please log in</BODY></HTML>")
+	}))
+	defer srv.Close()
+	c := DefaultConfig()
+	c.CaptivePortalURL = srv.URL
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	res := CheckCaptivePortal(context.Background(), false)
+	if res.Status != StatusWarning || res.Reason != ReasonCaptivePortal {
+		t.Errorf("Expected a captive_portal warning, got %v/%s", res.Status, res.Reason)
+	}
+}
+
+func TestCheckCaptivePortalLoginURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hotspot", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login?mac=x", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "<HTML><BODY>Sign in</BODY></HTML>")
+	})
+	mux.HandleFunc("/refresh", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `<html><head><meta http-equiv="refresh" content="0; url=https://portal.example/auth?x=1"></head></html>`)
+	})
+	mux.HandleFunc("/inject", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `<html><head><meta http-equiv="refresh" content="0; url=https://portal.example/a$(id)"></head></html>`)
+	})
+	mux.HandleFunc("/script", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `<script>window.location.href = "javascript:alert(1)";</script>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/hotspot", srv.URL + "/login?mac=x"},
+		{"/refresh", "https://portal.example/auth?x=1"},
+		{"/inject", "https://portal.example/a$(id)"},
+		{"/script", ""},
+	}
+	prevCfg := activeConfig(context.Background())
+	t.Cleanup(func() { SetConfig(prevCfg) })
+	for _, tt := range tests {
+		c := DefaultConfig()
+		c.CaptivePortalURL = srv.URL + tt.path
+		SetConfig(c)
+
+		res := CheckCaptivePortal(context.Background(), false)
+		if got := res.Facts[FactPortalURL]; got != tt.want {
+			t.Errorf("%s: expected login URL %q, got %q", tt.path, tt.want, got)
+		}
+		if want := shellLine([][]string{activePlatform().openURLCommand(tt.want)}, false); tt.want != "" && res.FixCommand != want {
+			t.Errorf("%s: expected fix command %q, got %q", tt.path, want, res.FixCommand)
+		}
+		if strings.Contains(tt.want, "$") && !strings.HasSuffix(res.FixCommand, shellQuote(tt.want)) {
+			t.Errorf("%s: expected shell metacharacters quoted, got %q", tt.path, res.FixCommand)
+		}
+	}
+}
+
+func TestCheckL3GatewayUnreachableReason(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 192.168.1.1\n  interface: en0\n",
+	}})
+
+	res := CheckL3Gateway(context.Background(), false)
+	if res.Status != StatusError || res.Reason != ReasonGatewayUnreachable {
+		t.Errorf("Expected a gateway_unreachable error, got %v/%s", res.Status, res.Reason)
+	}
+	if res.FixCommand != "sudo ipconfig set en0 DHCP" || !strings.Contains(res.Fix, "renew the DHCP lease") {
+		t.Errorf("Expected the fix to describe its command, got %q / %q", res.Fix, res.FixCommand)
+	}
+}
+
+func TestNeedsTCPFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"nxdomain", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{"truncated or malformed", &net.DNSError{Err: "cannot unmarshal DNS message"}, true},
+		{"dial failure", errors.New("connection refused"), true},
+	}
+	for _, tt := range tests {
+		if got := needsTCPFallback(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package diagnostic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFakeDNS answers length-prefixed (stream) DNS queries on conn with
+// 192.0.2.1 for A questions and an empty answer otherwise.
+func serveFakeDNS(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	for {
+		var size uint16
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		query := make([]byte, size)
+		if _, err := io.ReadFull(conn, query); err != nil || len(query) < 12 {
+			return
+		}
+		// The question runs from the header to the end of QNAME plus QTYPE and QCLASS.
+		end := 12
+		for end < len(query) && query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		qtype := binary.BigEndian.Uint16(query[end-4:])
+
+		resp := append([]byte(nil), query[:end]...)
+		binary.BigEndian.PutUint16(resp[2:], 0x8180) // response, recursion available
+		binary.BigEndian.PutUint16(resp[4:], 1)
+		binary.BigEndian.PutUint16(resp[8:], 0)
+		binary.BigEndian.PutUint16(resp[10:], 0)
+		if qtype == 1 {
+			binary.BigEndian.PutUint16(resp[6:], 1)
+			resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1)
+		} else {
+			binary.BigEndian.PutUint16(resp[6:], 0)
+		}
+
+		if err := binary.Write(conn, binary.BigEndian, uint16(len(resp))); err != nil {
+			return
+		}
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestCheckDNSBenchmarkIsolatesSlowResolver(t *testing.T) {
+	prevResolvers, prevEncrypted, prevDial := dnsResolvers, encryptedResolvers, dnsDial
+	t.Cleanup(func() { dnsResolvers, encryptedResolvers, dnsDial = prevResolvers, prevEncrypted, prevDial })
+	encryptedResolvers = nil
+
+	c := DefaultConfig()
+	c.Timeout = 300 * time.Millisecond
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	dnsResolvers = []dnsResolver{{"Slow", "slow:53"}, {"Fast", "fast:53"}}
+	dnsDial = func(ctx context.Context, _, server string) (net.Conn, error) {
+		if server == "slow:53" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		client, srv := net.Pipe()
+		go serveFakeDNS(srv)
+		return client, nil
+	}
+
+	start := time.Now()
+	res := CheckDNSBenchmark(context.Background())
+	elapsed := time.Since(start)
+
+	if len(res.Details) != 2 {
+		t.Fatalf("Expected 2 detail lines, got %v", res.Details)
+	}
+	if !strings.Contains(res.Details[0], "Slow") || !strings.Contains(res.Details[0], "FAIL") {
+		t.Errorf("Expected the slow resolver first and failed, got %s", res.Details[0])
+	}
+	if !strings.Contains(res.Details[1], "Fast") || !strings.Contains(res.Details[1], "(OK)") {
+		t.Errorf("Expected the fast resolver second and OK, got %s", res.Details[1])
+	}
+	// The slow resolver uses its UDP and TCP timeouts; serial probing would add the fast one on top.
+	if limit := 3 * c.Timeout; elapsed > limit {
+		t.Errorf("Expected resolvers to run concurrently within %v, took %v", limit, elapsed)
+	}
+}
+
+// benchmarkFailingSystem runs CheckDNSBenchmark with a system resolver that
+// cannot resolve anything and a public one that answers when publicWorks.
+func benchmarkFailingSystem(t *testing.T, publicWorks bool) Result {
+	t.Helper()
+	prevResolvers, prevEncrypted, prevDial, prevLookup := dnsResolvers, encryptedResolvers, dnsDial, systemLookup
+	t.Cleanup(func() {
+		dnsResolvers, encryptedResolvers, dnsDial, systemLookup = prevResolvers, prevEncrypted, prevDial, prevLookup
+	})
+	encryptedResolvers = nil
+	dnsResolvers = []dnsResolver{{"System", ""}, {"Public", "192.0.2.53:53"}}
+	systemLookup = func(_ context.Context, host string) error {
+		return &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+	}
+	dnsDial = func(context.Context, string, string) (net.Conn, error) {
+		if !publicWorks {
+			return nil, &net.OpError{Op: "dial", Net: "udp", Err: errors.New("network is unreachable")}
+		}
+		client, srv := net.Pipe()
+		go serveFakeDNS(srv)
+		return client, nil
+	}
+	res := CheckDNSBenchmark(context.Background())
+	res.Check = "dns"
+	return res
+}
+
+func TestCheckDNSBenchmarkSystemFailure(t *testing.T) {
+	for _, publicWorks := range []bool{true, false} {
+		res := benchmarkFailingSystem(t, publicWorks)
+		if res.Status != StatusError || res.Reason != ReasonDNSFailed {
+			t.Errorf("public resolver works=%v: expected a dns_failed error, got %v/%s %q", publicWorks, res.Status, res.Reason, res.Message)
+		}
+		if res.Latency != 0 {
+			t.Errorf("Expected no latency for a failed lookup, got %v", res.Latency)
+		}
+		if got := res.FixCommand != ""; got != publicWorks {
+			t.Errorf("public resolver works=%v: expected a flush command only then, got %q", publicWorks, res.FixCommand)
+		}
+	}
+}
+
+func TestBenchmarkResolvers(t *testing.T) {
+	if got := benchmarkResolvers(nil); len(got) != len(dnsResolvers) {
+		t.Errorf("Expected the built-in resolvers without configuration, got %v", got)
+	}
+
+	got := benchmarkResolvers([]string{"9.9.9.9", "10.0.0.53:5353", "2620:fe::fe"})
+	want := []dnsResolver{
+		{"System", ""},
+		{"9.9.9.9", "9.9.9.9:53"},
+		{"10.0.0.53", "10.0.0.53:5353"},
+		{"2620:fe::fe", "[2620:fe::fe]:53"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("resolver %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
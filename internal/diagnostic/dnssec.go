@@ -0,0 +1,115 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// DNSSEC test domains: the first is validly signed, the second deliberately
+// carries broken signatures so that validating resolvers refuse it.
+const (
+	dnssecGoodDomain = "internetsociety.org"
+	dnssecBadDomain  = "dnssec-failed.org"
+)
+
+// dnssecVerdict is the conclusion drawn from the two test lookups.
+type dnssecVerdict int
+
+const (
+	dnssecValidating dnssecVerdict = iota
+	dnssecNotValidating
+	dnssecInconclusive
+)
+
+// inferDNSSEC decides whether the resolver validates DNSSEC. Only a resolver
+// that answers the signed domain but refuses the broken one is validating;
+// if the signed domain fails too, the resolver is just not working.
+func inferDNSSEC(goodErr, badErr error) dnssecVerdict {
+	switch {
+	case goodErr != nil:
+		return dnssecInconclusive
+	case badErr != nil:
+		return dnssecValidating
+	}
+	return dnssecNotValidating
+}
+
+// CheckDNSSEC reports whether the system resolver enforces DNSSEC validation,
+// and whether each resolver of the DNS benchmark does, so a validating
+// alternative can be suggested.
+func CheckDNSSEC(ctx context.Context) Result {
+	res := Result{Name: "DNSSEC", Emoji: "🔏", Status: StatusOk}
+	resolvers := benchmarkResolvers(activeConfig(ctx).DNSResolvers)
+	verdicts := make([]dnssecVerdict, len(resolvers))
+	var wg sync.WaitGroup
+	for i, r := range resolvers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			verdicts[i] = resolverDNSSEC(ctx, r.server)
+		}()
+	}
+	wg.Wait()
+
+	details := make([]string, 0, len(resolvers))
+	var system dnssecVerdict
+	var alternative string
+	for i, r := range resolvers {
+		details = append(details, fmt.Sprintf("%-10s: %s", r.name, verdicts[i]))
+		if r.server == "" {
+			system = verdicts[i]
+		} else if alternative == "" && verdicts[i] == dnssecValidating {
+			alternative = r.name
+		}
+	}
+	details = append(details, "Tested with "+dnssecGoodDomain+" (signed) and "+dnssecBadDomain+" (broken signature)")
+	res.Details = formatDetailsWithPrefixes(details)
+
+	switch system {
+	case dnssecValidating:
+		res.Message = "Resolver validates DNSSEC"
+	case dnssecNotValidating:
+		res.Status = StatusWarning
+		res.Reason = ReasonDNSSECNotValidated
+		res.Message = "Resolver does not validate DNSSEC (accepted a forged signature)"
+		res.Fix = "Use a validating resolver such as 1.1.1.1 or 9.9.9.9 if spoofed DNS answers are a concern."
+		if alternative != "" {
+			res.Fix = "Use a validating resolver such as " + alternative + ", which passed this test, if spoofed DNS answers are a concern."
+		}
+	case dnssecInconclusive:
+		res.Status = StatusWarning
+		res.Reason = ReasonProbeFailed
+		res.Message = "Inconclusive: the signed test domain did not resolve"
+	}
+	return res
+}
+
+// resolverDNSSEC tests whether the resolver at server (host:port, or "" for
+// the system resolver) validates DNSSEC.
+func resolverDNSSEC(ctx context.Context, server string) dnssecVerdict {
+	r := net.DefaultResolver
+	if server != "" {
+		r = newDirectResolver(server, "udp")
+	}
+	return inferDNSSEC(lookupWith(ctx, r, dnssecGoodDomain), lookupWith(ctx, r, dnssecBadDomain))
+}
+
+func (v dnssecVerdict) String() string {
+	switch v {
+	case dnssecValidating:
+		return "validates"
+	case dnssecNotValidating:
+		return "does not validate"
+	}
+	return "inconclusive"
+}
+
+// lookupWith resolves host through r within Config.Timeout.
+func lookupWith(ctx context.Context, r *net.Resolver, host string) error {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	_, err := r.LookupHost(ctx, host)
+	return err
+}
@@ -0,0 +1,48 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestInferDNSSEC(t *testing.T) {
+	servfail := errors.New("server misbehaving")
+	tests := []struct {
+		name            string
+		goodErr, badErr error
+		want            dnssecVerdict
+	}{
+		{"validating", nil, servfail, dnssecValidating},
+		{"not validating", nil, nil, dnssecNotValidating},
+		{"resolver down", servfail, servfail, dnssecInconclusive},
+		{"signed domain only fails", servfail, nil, dnssecInconclusive},
+	}
+	for _, tt := range tests {
+		if got := inferDNSSEC(tt.goodErr, tt.badErr); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestResolverDNSSEC(t *testing.T) {
+	prevDial := dnsDial
+	t.Cleanup(func() { dnsDial = prevDial })
+	dnsDial = func(_ context.Context, _, server string) (net.Conn, error) {
+		if server == "down:53" {
+			return nil, errors.New("connection refused")
+		}
+		client, srv := net.Pipe()
+		go serveFakeDNS(srv)
+		return client, nil
+	}
+
+	// The fake server answers every name, the broken one included.
+	if got := resolverDNSSEC(context.Background(), "fake:53"); got != dnssecNotValidating {
+		t.Errorf("Expected a resolver answering the broken domain not to validate, got %v", got)
+	}
+	if got := resolverDNSSEC(context.Background(), "down:53"); got != dnssecInconclusive {
+		t.Errorf("Expected an unreachable resolver to be inconclusive, got %v", got)
+	}
+}
@@ -0,0 +1,163 @@
+package diagnostic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Encrypted DNS transports.
+const (
+	protoDoH = "DoH"
+	protoDoT = "DoT"
+)
+
+// encryptedResolver is a DNS-over-HTTPS or DNS-over-TLS endpoint raced by
+// CheckDNSBenchmark.
+type encryptedResolver struct {
+	name  string
+	proto string
+	// server is the DoH URL, or host:port for DoT.
+	server string
+	// tlsName is the name on a DoT server's certificate.
+	tlsName string
+}
+
+// encryptedResolvers are benchmarked after the plain ones; tests replace them.
+var encryptedResolvers = []encryptedResolver{
+	{"Cloudflare", protoDoH, "https://cloudflare-dns.com/dns-query", ""},
+	{"Google", protoDoH, "https://dns.google/dns-query", ""},
+	{"Quad9", protoDoH, "https://dns.quad9.net/dns-query", ""},
+	{"Cloudflare", protoDoT, "1.1.1.1:853", "cloudflare-dns.com"},
+	{"Google", protoDoT, "8.8.8.8:853", "dns.google"},
+	{"Quad9", protoDoT, "9.9.9.9:853", "dns.quad9.net"},
+}
+
+// dohClient sends DoH queries; tests replace it.
+var dohClient = &http.Client{}
+
+// probeEncrypted times a lookup of the probe name through r within
+// Config.Timeout.
+func probeEncrypted(ctx context.Context, r encryptedResolver) dnsOutcome {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	start := time.Now()
+	var err error
+	if r.proto == protoDoH {
+		err = lookupDoH(ctx, r.server)
+	} else {
+		err = lookupDoT(ctx, r.server, r.tlsName)
+	}
+	return dnsOutcome{dur: time.Since(start), err: err}
+}
+
+// lookupDoH POSTs an A query for google.com to url as RFC 8484 describes.
+func lookupDoH(ctx context.Context, url string) error {
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName("google.com."),
+			Type:  dnsmessage.TypeA,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(packed))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DoH server answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	var reply dnsmessage.Message
+	if err := reply.Unpack(body); err != nil {
+		return fmt.Errorf("malformed DoH answer: %w", err)
+	}
+	if !reply.Response || reply.RCode != dnsmessage.RCodeSuccess {
+		return fmt.Errorf("DoH query failed: %v", reply.RCode)
+	}
+	return nil
+}
+
+// lookupDoT resolves google.com through server over TLS, checking the
+// certificate against tlsName.
+func lookupDoT(ctx context.Context, server, tlsName string) error {
+	dial := dnsDial
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			conn, err := dial(ctx, "tcp", server)
+			if err != nil {
+				return nil, err
+			}
+			return tls.Client(conn, &tls.Config{ServerName: tlsName}), nil
+		},
+	}
+	_, err := r.LookupIP(ctx, "ip4", "google.com")
+	return err
+}
+
+// encryptedSummary compares the fastest encrypted lookup of each transport
+// with the fastest plain one, and lists the transports that failed
+// everywhere.
+func encryptedSummary(resolvers []encryptedResolver, outcomes []dnsOutcome, plain time.Duration) (details, blocked []string) {
+	for _, proto := range []string{protoDoH, protoDoT} {
+		var fastest time.Duration
+		tried := false
+		for i, r := range resolvers {
+			if r.proto != proto {
+				continue
+			}
+			tried = true
+			if o := outcomes[i]; o.err == nil && (fastest == 0 || o.dur < fastest) {
+				fastest = o.dur
+			}
+		}
+		switch {
+		case !tried:
+		case fastest == 0:
+			blocked = append(blocked, proto)
+		case plain > 0:
+			details = append(details, fmt.Sprintf("%s vs plain: %s (fastest) vs %s", proto,
+				fastest.Round(time.Millisecond), plain.Round(time.Millisecond)))
+		}
+	}
+	return details, blocked
+}
+
+// blockedMessage names the encrypted transports that are blocked.
+func blockedMessage(blocked []string) string {
+	for i, proto := range blocked {
+		if proto == protoDoT {
+			blocked[i] = "DoT (port 853)"
+		}
+	}
+	return "encrypted DNS blocked: " + strings.Join(blocked, ", ")
+}
@@ -0,0 +1,75 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestLookupDoH(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var query dnsmessage.Message
+		if r.Header.Get("Content-Type") != "application/dns-message" || query.Unpack(body) != nil {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+			}},
+		}
+		packed, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(packed)
+	}))
+	defer srv.Close()
+	prev := dohClient
+	dohClient = srv.Client()
+	t.Cleanup(func() { dohClient = prev })
+
+	if err := lookupDoH(context.Background(), srv.URL+"/dns-query"); err != nil {
+		t.Errorf("Expected the DoH lookup to succeed, got %v", err)
+	}
+
+	notFound := httptest.NewTLSServer(http.NotFoundHandler())
+	defer notFound.Close()
+	dohClient = notFound.Client()
+	if err := lookupDoH(context.Background(), notFound.URL); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected an HTTP error to fail the lookup, got %v", err)
+	}
+}
+
+func TestEncryptedSummary(t *testing.T) {
+	fail := errors.New("blocked")
+	resolvers := []encryptedResolver{
+		{"A", protoDoH, "", ""},
+		{"B", protoDoH, "", ""},
+		{"A", protoDoT, "", ""},
+	}
+	outcomes := []dnsOutcome{
+		{dur: 40 * time.Millisecond},
+		{dur: 25 * time.Millisecond},
+		{dur: time.Second, err: fail},
+	}
+	details, blocked := encryptedSummary(resolvers, outcomes, 10*time.Millisecond)
+	if len(details) != 1 || !strings.Contains(details[0], "DoH vs plain: 25ms (fastest) vs 10ms") {
+		t.Errorf("Expected a DoH comparison against the fastest answer, got %v", details)
+	}
+	if strings.Join(blocked, ",") != protoDoT {
+		t.Errorf("Expected DoT to be reported blocked, got %v", blocked)
+	}
+	if msg := blockedMessage(blocked); msg != "encrypted DNS blocked: DoT (port 853)" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
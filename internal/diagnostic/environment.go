@@ -0,0 +1,44 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// ProbeEnvironment checks once for the tools, operating system and privileges
+// the checks depend on, and returns a warning for each problem found. With
+// --remote the tools, OS and privileges are those of the remote Mac.
+func ProbeEnvironment() []string {
+	r := activeRunner()
+	var missing []string
+	for _, tool := range activePlatform().tools() {
+		if r.LookPath(tool) != nil {
+			missing = append(missing, tool)
+		}
+	}
+
+	goos := runtime.GOOS
+	ctx, cancel := context.WithTimeout(context.Background(), activeConfig(context.Background()).Timeout)
+	defer cancel()
+	if out, err := r.Output(ctx, "uname", "-s"); err == nil {
+		goos = strings.ToLower(strings.TrimSpace(string(out)))
+	}
+	return environmentWarnings(goos, IsRoot(), missing)
+}
+
+// environmentWarnings turns the probed facts into user-facing warnings.
+func environmentWarnings(goos string, root bool, missing []string) []string {
+	var warnings []string
+	if goos != "darwin" && goos != "linux" {
+		warnings = append(warnings, fmt.Sprintf("Running on %s: wtfi supports macOS and Linux, so most checks will likely be skipped", goos))
+	}
+	for _, tool := range missing {
+		warnings = append(warnings, fmt.Sprintf("%q not found in PATH: checks that need it will be skipped", tool))
+	}
+	if !root {
+		warnings = append(warnings, "Not running as root: arping and ping intervals under 100ms are unavailable")
+	}
+	return warnings
+}
@@ -0,0 +1,64 @@
+package diagnostic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		root    bool
+		missing []string
+		want    []string
+	}{
+		{"healthy", "darwin", true, nil, nil},
+		{"unprivileged", "darwin", false, nil, []string{"Not running as root"}},
+		{"linux without iw", "linux", true, []string{"iw"}, []string{`"iw" not found`}},
+		{"unsupported os", "windows", true, nil, []string{"Running on windows"}},
+	}
+	for _, tt := range tests {
+		got := environmentWarnings(tt.goos, tt.root, tt.missing)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %d warnings, got %v", tt.name, len(tt.want), got)
+			continue
+		}
+		for i, prefix := range tt.want {
+			if !strings.HasPrefix(got[i], prefix) {
+				t.Errorf("%s: expected warning starting %q, got %q", tt.name, prefix, got[i])
+			}
+		}
+	}
+}
+
+func TestProbeEnvironmentUsesRunner(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"uname -s":              "Darwin\n",
+		"ping -c 1 1.1.1.1":     "",
+		"route -n get default":  "",
+		"system_profiler -json": "",
+		"ifconfig en0":          "",
+	}})
+	got := strings.Join(ProbeEnvironment(), "\n")
+	if strings.Contains(got, "Running on") {
+		t.Errorf("Expected the runner's OS to be used, got %q", got)
+	}
+	if !strings.Contains(got, `"arp" not found`) || strings.Contains(got, `"ping" not found`) {
+		t.Errorf("Expected only arp to be missing, got %q", got)
+	}
+}
+
+func TestRemotePrivileges(t *testing.T) {
+	withRunner(t, sshRunner{target: "admin@studio.local", root: true})
+	if !IsRoot() {
+		t.Error("Expected the remote user's privileges to count")
+	}
+	if got := environmentWarnings("darwin", IsRoot(), nil); len(got) != 0 {
+		t.Errorf("Expected no root warning for a remote root user, got %q", got)
+	}
+	if res := CheckVPN(context.Background()); res.Status != StatusSkipped {
+		t.Errorf("Expected the VPN check to skip this machine's tunnels, got %v %q", res.Status, res.Message)
+	}
+}
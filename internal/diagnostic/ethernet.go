@@ -0,0 +1,178 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reMediaLine = regexp.MustCompile(`(?m)^\s*media:\s*(.+)$`)
+	// reBaseSpeed matches Ethernet media subtypes such as 100baseTX,
+	// 1000baseT or 10Gbase-T.
+	reBaseSpeed = regexp.MustCompile(`(?i)^(\d+)(G?)base`)
+	// reSupportedMedia matches the "media 1000baseT mediaopt full-duplex"
+	// lines ifconfig -m lists under "supported media".
+	reSupportedMedia = regexp.MustCompile(`(?m)^\s*media (\d+G?base\S*)`)
+	// reEthtoolSpeed and reEthtoolDuplex match ethtool's "Speed: 1000Mb/s"
+	// and "Duplex: Full" lines.
+	reEthtoolSpeed  = regexp.MustCompile(`(?m)^\s*Speed:\s*(\d+)Mb/s`)
+	reEthtoolDuplex = regexp.MustCompile(`(?m)^\s*Duplex:\s*(\w+)`)
+	// reEthtoolModes matches link modes such as "1000baseT/Full".
+	reEthtoolModes = regexp.MustCompile(`\b(\d+)base[A-Za-z0-9]*/(?:Full|Half)\b`)
+)
+
+// ethernetMedia is the active media of a wired interface.
+type ethernetMedia struct {
+	// Subtype is the negotiated media, e.g. "1000baseT".
+	Subtype    string
+	SpeedMbps  int
+	HalfDuplex bool
+	// MaxMbps is the fastest media the adapter supports; 0 when unknown.
+	MaxMbps int
+}
+
+// parseMedia reads the media line of ifconfig output. The active media is
+// the parenthesized part when autoselect is on ("autoselect (1000baseT
+// <full-duplex>)"), otherwise the line itself. ok is false for non-Ethernet
+// media, such as Wi-Fi's bare "autoselect".
+func parseMedia(output string) (m ethernetMedia, ok bool) {
+	line := reMediaLine.FindStringSubmatch(output)
+	if line == nil {
+		return m, false
+	}
+	active := line[1]
+	if open := strings.Index(active, "("); open >= 0 {
+		active = strings.TrimSuffix(active[open+1:], ")")
+	}
+
+	subtype, options, _ := strings.Cut(active, "<")
+	m.Subtype = strings.TrimSpace(subtype)
+	m.SpeedMbps = baseSpeed(m.Subtype)
+	if m.SpeedMbps == 0 {
+		return m, false
+	}
+	m.HalfDuplex = strings.Contains(options, "half-duplex")
+	for _, supported := range reSupportedMedia.FindAllStringSubmatch(output, -1) {
+		m.MaxMbps = max(m.MaxMbps, baseSpeed(supported[1]))
+	}
+	return m, true
+}
+
+// baseSpeed returns the speed in Mb/s of a media subtype such as 100baseTX
+// or 10Gbase-T, or 0 when it is not one.
+func baseSpeed(subtype string) int {
+	speed := reBaseSpeed.FindStringSubmatch(subtype)
+	if speed == nil {
+		return 0
+	}
+	mbps, _ := strconv.Atoi(speed[1])
+	if speed[2] != "" {
+		mbps *= 1000
+	}
+	return mbps
+}
+
+// parseEthtool reads `ethtool <iface>`. Wi-Fi and unplugged links report
+// no speed ("Speed: Unknown!"), so ok is false for them.
+func parseEthtool(output string) (m ethernetMedia, ok bool) {
+	speed := reEthtoolSpeed.FindStringSubmatch(output)
+	if speed == nil {
+		return m, false
+	}
+	m.SpeedMbps, _ = strconv.Atoi(speed[1])
+	m.Subtype = speed[1] + "baseT"
+	if duplex := reEthtoolDuplex.FindStringSubmatch(output); duplex != nil {
+		m.HalfDuplex = strings.EqualFold(duplex[1], "half")
+	}
+	// Only the adapter's own modes count, not what the partner advertises.
+	supported, _, _ := strings.Cut(output, "Advertised link modes:")
+	for _, mode := range reEthtoolModes.FindAllStringSubmatch(supported, -1) {
+		mbps, _ := strconv.Atoi(mode[1])
+		m.MaxMbps = max(m.MaxMbps, mbps)
+	}
+	return m, true
+}
+
+// gradeEthernet warns about links that negotiated below gigabit, when the
+// adapter could do better, or at half duplex; both usually point at a
+// damaged cable or a bad port.
+func gradeEthernet(res *Result, m ethernetMedia) {
+	res.Message = fmt.Sprintf("Link at %s", formatLinkSpeed(m.SpeedMbps))
+	capable := ""
+	if m.MaxMbps > m.SpeedMbps {
+		capable = fmt.Sprintf(" on a %s adapter", formatLinkSpeed(m.MaxMbps))
+	}
+	switch {
+	case m.HalfDuplex:
+		res.Status = StatusWarning
+		res.Reason = ReasonEthernetHalfDuplex
+		res.Message += ", half duplex" + capable
+		res.Fix = "Half duplex causes collisions; replace the cable or try another switch port."
+	case m.SpeedMbps < 1000 && m.MaxMbps != 0 && m.MaxMbps <= m.SpeedMbps:
+		res.Message += " (the adapter's maximum)"
+	case m.SpeedMbps < 1000:
+		res.Status = StatusWarning
+		res.Reason = ReasonEthernetSlowLink
+		res.Message += " (below gigabit" + capable + ")"
+		res.Fix = "Use a Cat5e or better cable; a damaged pair often forces 100 Mb/s."
+	}
+}
+
+func formatLinkSpeed(mbps int) string {
+	if mbps >= 1000 && mbps%1000 == 0 {
+		return fmt.Sprintf("%d Gb/s", mbps/1000)
+	}
+	if mbps > 1000 {
+		return fmt.Sprintf("%.1f Gb/s", float64(mbps)/1000)
+	}
+	return fmt.Sprintf("%d Mb/s", mbps)
+}
+
+// CheckL2Ethernet reports link speed and duplex when the primary interface
+// is wired; on Wi-Fi it is skipped.
+func CheckL2Ethernet(ctx context.Context) Result {
+	res := Result{Name: "Ethernet", Emoji: "🔌", Status: StatusOk}
+	iface, err := getPrimaryInterface(ctx)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Reason = ReasonNoRoute
+		res.Message = "No default route found"
+		return res
+	}
+	res.Name = "Ethernet (" + iface + ")"
+
+	p := activePlatform()
+	out, err := runPlatformCommand(ctx, p.mediaCommand(iface))
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Reason = ReasonProbeFailed
+		res.Message = "Could not read interface media"
+		return res
+	}
+	m, wired := p.parseMedia(string(out))
+	if !wired {
+		res.Status = StatusSkipped
+		res.Message = "Primary interface is not wired Ethernet"
+		return res
+	}
+	gradeEthernet(&res, m)
+	duplex := "full"
+	if m.HalfDuplex {
+		duplex = "half"
+	}
+	details := []string{"Media: " + m.Subtype, "Duplex: " + duplex}
+	if m.MaxMbps > 0 {
+		details = append(details, "Adapter supports: up to "+formatLinkSpeed(m.MaxMbps))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	return res
+}
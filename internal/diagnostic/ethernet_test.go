@@ -0,0 +1,93 @@
+package diagnostic
+
+import "testing"
+
+func TestParseMedia(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		wired bool
+		want  ethernetMedia
+	}{
+		{"gigabit", "media: autoselect (1000baseT <full-duplex,flow-control,energy-efficient-ethernet>)", true, ethernetMedia{"1000baseT", 1000, false, 0}},
+		{"fast ethernet", "media: autoselect (100baseTX <full-duplex>)", true, ethernetMedia{"100baseTX", 100, false, 0}},
+		{"half duplex", "media: autoselect (100baseTX <half-duplex>)", true, ethernetMedia{"100baseTX", 100, true, 0}},
+		{"10 gigabit", "media: autoselect (10Gbase-T <full-duplex>)", true, ethernetMedia{"10Gbase-T", 10000, false, 0}},
+		{"manual", "media: 2500Base-T <full-duplex>", true, ethernetMedia{"2500Base-T", 2500, false, 0}},
+		{"wifi", "media: autoselect", false, ethernetMedia{}},
+		{"no carrier", "media: autoselect (none)", false, ethernetMedia{}},
+	}
+	for _, tt := range tests {
+		output := "en0: flags=8863<UP,BROADCAST,SMART,RUNNING,SIMPLEX,MULTICAST> mtu 1500\n\t" + tt.line + "\n\tstatus: active\n"
+		got, wired := parseMedia(output)
+		if wired != tt.wired {
+			t.Errorf("%s: expected wired=%v, got %v", tt.name, tt.wired, wired)
+			continue
+		}
+		if wired && got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+	if _, wired := parseMedia("lo0: flags=8049<UP,LOOPBACK,RUNNING,MULTICAST> mtu 16384\n"); wired {
+		t.Error("Expected no media line to mean not wired")
+	}
+}
+
+func TestGradeEthernet(t *testing.T) {
+	tests := []struct {
+		name   string
+		media  ethernetMedia
+		status Status
+		reason Reason
+	}{
+		{"gigabit", ethernetMedia{"1000baseT", 1000, false, 0}, StatusOk, ""},
+		{"slow link", ethernetMedia{"100baseTX", 100, false, 0}, StatusWarning, ReasonEthernetSlowLink},
+		{"half duplex", ethernetMedia{"1000baseT", 1000, true, 0}, StatusWarning, ReasonEthernetHalfDuplex},
+		{"slow on gigabit hardware", ethernetMedia{"100baseTX", 100, true, 1000}, StatusWarning, ReasonEthernetHalfDuplex},
+		{"fast ethernet adapter", ethernetMedia{"100baseTX", 100, false, 100}, StatusOk, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeEthernet(&res, tt.media)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%s, got %v/%s", tt.name, tt.status, tt.reason, res.Status, res.Reason)
+		}
+	}
+}
+
+func TestParseMediaSupported(t *testing.T) {
+	out := `en7: flags=8863<UP,BROADCAST,SMART,RUNNING,SIMPLEX,MULTICAST> mtu 1500
+	media: autoselect (100baseTX <half-duplex>)
+	status: active
+	supported media:
+		media autoselect
+		media 10baseT/UTP mediaopt full-duplex
+		media 100baseTX mediaopt full-duplex
+		media 1000baseT mediaopt full-duplex
+`
+	m, wired := parseMedia(out)
+	if !wired || m.SpeedMbps != 100 || !m.HalfDuplex || m.MaxMbps != 1000 {
+		t.Errorf("unexpected media %+v", m)
+	}
+}
+
+func TestParseEthtool(t *testing.T) {
+	out := `Settings for eth0:
+	Supported ports: [ TP ]
+	Supported link modes:   10baseT/Half 10baseT/Full
+	                        100baseT/Half 100baseT/Full
+	                        1000baseT/Full
+	Advertised link modes:  10baseT/Half 10baseT/Full
+	                        100baseT/Half 100baseT/Full
+	Speed: 100Mb/s
+	Duplex: Half
+	Link detected: yes
+`
+	m, wired := parseEthtool(out)
+	if !wired || m.SpeedMbps != 100 || !m.HalfDuplex || m.MaxMbps != 1000 {
+		t.Errorf("unexpected media %+v", m)
+	}
+	if _, wired := parseEthtool("Settings for wlan0:\n\tSpeed: Unknown!\n\tLink detected: yes\n"); wired {
+		t.Error("expected a link without speed not to count as wired")
+	}
+}
@@ -0,0 +1,195 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knownAP is an access point seen serving a network.
+type knownAP struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// knownNetwork is what earlier runs saw of one SSID.
+type knownNetwork struct {
+	// Security is the strongest security mode seen, one of the security*
+	// names, or empty when never reported.
+	Security string `json:"security,omitempty"`
+	// APs maps each BSSID seen to when.
+	APs map[string]*knownAP `json:"aps"`
+}
+
+// knownAPs maps SSIDs to what earlier runs saw of them.
+type knownAPs map[string]*knownNetwork
+
+// securityRank orders the security* names from weakest to strongest; a
+// switch to a lower rank is a downgrade. OWE encrypts but authenticates no
+// one, so it ranks with WEP.
+func securityRank(mode string) int {
+	switch mode {
+	case securityOpen:
+		return 0
+	case securityWEP, securityOWE:
+		return 1
+	case securityWPA:
+		return 2
+	case securityWPA2:
+		return 3
+	case securityTransition:
+		return 4
+	}
+	return 5
+}
+
+// apVendor returns the vendor prefix (OUI) of bssid with the locally
+// administered bit cleared, so virtual BSSIDs an access point derives from
+// its own address count as the same vendor.
+func apVendor(bssid string) string {
+	parts := strings.Split(bssid, ":")
+	if len(parts) != 6 {
+		return bssid
+	}
+	first, err := strconv.ParseUint(parts[0], 16, 8)
+	if err != nil {
+		return bssid
+	}
+	return fmt.Sprintf("%02x:%s:%s", first&^0x02, parts[1], parts[2])
+}
+
+// locallyAdministered reports whether bssid is not a vendor-assigned
+// address, as phones and laptops use when sharing a hotspot.
+func locallyAdministered(bssid string) bool {
+	first, err := strconv.ParseUint(strings.SplitN(bssid, ":", 2)[0], 16, 8)
+	return err == nil && first&0x02 != 0
+}
+
+// CheckEvilTwin records the access points (BSSIDs) and security mode seen
+// for the current SSID across runs and warns when the SSID appears on an
+// access point of an unknown vendor or with weaker security than before:
+// the marks of an evil twin, a rogue access point copying the name of a
+// public network to intercept its users. It only runs with -v.
+func CheckEvilTwin(ctx context.Context, verbose bool) Result {
+	res := Result{Name: "Evil Twin", Emoji: "👯", Status: StatusOk}
+	if !verbose {
+		res.Status = StatusSkipped
+		res.Message = "Use -v flag to check for rogue access points"
+		return res
+	}
+	path := activeConfig(ctx).KnownAPsFile
+	if path == "" {
+		res.Status = StatusSkipped
+		res.Message = "No file to record access points in"
+		return res
+	}
+	iface, err := getPrimaryInterface(ctx)
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "No default route found"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+	link, err := readWiFiLink(ctx, iface, false)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil || link.RSSI == 0 {
+		res.Status = StatusSkipped
+		res.Message = "Not connected to Wi-Fi"
+		return res
+	}
+	if link.SSID == "" || link.BSSID == "" {
+		res.Status = StatusSkipped
+		res.Message = "SSID or BSSID not reported"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	readWiFiSecurity(ctx, iface, &link)
+	// The SSID fact lets redaction mask the name in the message.
+	res.Facts = map[string]string{FactSSID: reSanitizeHTTP.ReplaceAllString(link.SSID, ""), FactBSSID: link.BSSID}
+
+	known := knownAPs{}
+	if err := loadState(path, &known); err != nil {
+		slog.Warn("could not access the known access points", "err", err)
+		res.Status = StatusSkipped
+		res.Message = "Cannot read the known access points"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	assessAP(&res, known, link.SSID, link.BSSID, classifySecurity(link.Security), time.Now())
+	if err := saveState(path, known); err != nil {
+		slog.Warn("could not access the known access points", "err", err)
+	}
+	if res.Status == StatusWarning {
+		res.Fix = "Disconnect unless you expected this change, use a VPN, and confirm the network name with the venue. If the network was really changed, delete its entry in " + path + "."
+	}
+	return res
+}
+
+// assessAP grades ssid being served by bssid with security mode (possibly
+// empty) against known, then records the sighting in known.
+func assessAP(res *Result, known knownAPs, ssid, bssid, mode string, now time.Time) {
+	net := known[ssid]
+	if net == nil {
+		net = &knownNetwork{APs: map[string]*knownAP{}}
+		known[ssid] = net
+	}
+	ap := net.APs[bssid]
+	first := len(net.APs) == 0
+
+	switch {
+	case first:
+		res.Message = "First visit to " + ssid + "; recorded " + bssid
+	case mode != "" && net.Security != "" && securityRank(mode) < securityRank(net.Security):
+		res.Status = StatusWarning
+		res.Reason = ReasonEvilTwin
+		res.Message = fmt.Sprintf("%s downgraded from %s to %s", ssid, net.Security, mode)
+	case ap == nil && !knownVendor(net, bssid):
+		res.Status = StatusWarning
+		res.Reason = ReasonEvilTwin
+		res.Message = fmt.Sprintf("%s on %s, from a vendor never seen on this network", ssid, bssid)
+		if locallyAdministered(bssid) {
+			res.Message += " (a locally administered address, as hotspots use)"
+		}
+	case ap == nil:
+		res.Message = fmt.Sprintf("New access point %s of a known vendor", bssid)
+	default:
+		res.Message = fmt.Sprintf("Known access point (%d recorded for %s)", len(net.APs), ssid)
+	}
+
+	if ap == nil {
+		ap = &knownAP{FirstSeen: now}
+		net.APs[bssid] = ap
+	}
+	ap.LastSeen = now
+	if mode != "" && (net.Security == "" || securityRank(mode) > securityRank(net.Security)) {
+		net.Security = mode
+	}
+
+	var details []string
+	for _, b := range slices.Sorted(maps.Keys(net.APs)) {
+		details = append(details, fmt.Sprintf("%s: first seen %s, last seen %s", b,
+			net.APs[b].FirstSeen.Local().Format("2006-01-02"), net.APs[b].LastSeen.Local().Format("2006-01-02")))
+	}
+	if net.Security != "" {
+		details = append(details, "Strongest security seen: "+net.Security)
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+}
+
+// knownVendor reports whether an access point of the vendor of bssid has
+// served net before.
+func knownVendor(net *knownNetwork, bssid string) bool {
+	for b := range net.APs {
+		if apVendor(b) == apVendor(bssid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package diagnostic
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAssessAP(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	known := func() knownAPs {
+		return knownAPs{"Cafe": {
+			Security: securityWPA2,
+			APs:      map[string]*knownAP{"a4:83:e7:01:02:03": {FirstSeen: now, LastSeen: now}},
+		}}
+	}
+	tests := []struct {
+		name, ssid, bssid, mode string
+		status                  Status
+	}{
+		{"first visit", "Airport", "00:11:22:33:44:55", securityOpen, StatusOk},
+		{"known access point", "Cafe", "a4:83:e7:01:02:03", securityWPA2, StatusOk},
+		{"security unknown", "Cafe", "a4:83:e7:01:02:03", "", StatusOk},
+		{"same vendor", "Cafe", "a4:83:e7:09:09:09", securityWPA2, StatusOk},
+		{"virtual BSSID", "Cafe", "a6:83:e7:01:02:04", securityWPA2, StatusOk},
+		{"stronger security", "Cafe", "a4:83:e7:01:02:03", securityWPA3, StatusOk},
+		{"unknown vendor", "Cafe", "3c:22:fb:01:02:03", securityWPA2, StatusWarning},
+		{"hotspot", "Cafe", "3e:22:fb:01:02:03", securityWPA2, StatusWarning},
+		{"downgraded", "Cafe", "a4:83:e7:01:02:03", securityOpen, StatusWarning},
+	}
+	for _, tt := range tests {
+		k := known()
+		res := Result{Status: StatusOk}
+		assessAP(&res, k, tt.ssid, tt.bssid, tt.mode, now.Add(time.Hour))
+		if res.Status != tt.status {
+			t.Errorf("%s: expected %v, got %v (%s)", tt.name, tt.status, res.Status, res.Message)
+		}
+		if tt.status == StatusWarning && res.Reason != ReasonEvilTwin {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, ReasonEvilTwin, res.Reason)
+		}
+		if ap := k[tt.ssid].APs[tt.bssid]; ap == nil || !ap.LastSeen.Equal(now.Add(time.Hour)) {
+			t.Errorf("%s: expected the sighting to be recorded, got %+v", tt.name, ap)
+		}
+	}
+
+	// A downgrade keeps warning on later runs.
+	k := known()
+	assessAP(&Result{}, k, "Cafe", "a4:83:e7:01:02:03", securityOpen, now)
+	if k["Cafe"].Security != securityWPA2 {
+		t.Errorf("Expected the strongest security to be kept, got %q", k["Cafe"].Security)
+	}
+}
+
+func TestCheckEvilTwin(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 192.168.1.1\n  interface: en0\n",
+		"system_profiler SPAirPortDataType": `      Current Network Information:
+        Cafe:
+          PHY Mode: 802.11ax
+          BSSID: a4:83:e7:01:02:03
+          Security: WPA2 Personal
+          Signal / Noise: -58 dBm / -90 dBm
+`,
+	}})
+	c := DefaultConfig()
+	c.KnownAPsFile = filepath.Join(t.TempDir(), "wtfi", "known_aps.json")
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	if res := CheckEvilTwin(context.Background(), false); res.Status != StatusSkipped || res.Details != nil {
+		t.Errorf("Expected a hint without -v, got %v (%s)", res.Status, res.Message)
+	}
+	for run := 1; run <= 2; run++ {
+		res := CheckEvilTwin(context.Background(), true)
+		if res.Status != StatusOk {
+			t.Errorf("Run %d: expected OK, got %v (%s)", run, res.Status, res.Message)
+		}
+		if res.Facts[FactBSSID] != "a4:83:e7:01:02:03" {
+			t.Errorf("Run %d: expected the BSSID fact, got %v", run, res.Facts)
+		}
+	}
+
+	known := knownAPs{}
+	if err := loadState(c.KnownAPsFile, &known); err != nil {
+		t.Fatal(err)
+	}
+	if net := known["Cafe"]; net == nil || net.Security != securityWPA2 || len(net.APs) != 1 {
+		t.Errorf("Expected one WPA2 access point recorded for Cafe, got %+v", net)
+	}
+}
+
+func TestAPVendor(t *testing.T) {
+	tests := map[string]string{
+		"a4:83:e7:01:02:03": "a4:83:e7",
+		"a6:83:e7:01:02:03": "a4:83:e7",
+		"not a mac":         "not a mac",
+	}
+	for bssid, want := range tests {
+		if got := apVendor(bssid); got != want {
+			t.Errorf("apVendor(%q): expected %q, got %q", bssid, want, got)
+		}
+	}
+	if !locallyAdministered("3e:22:fb:01:02:03") || locallyAdministered("3c:22:fb:01:02:03") {
+		t.Error("Expected only 3e:... to be locally administered")
+	}
+}
@@ -0,0 +1,233 @@
+package diagnostic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// portRange is the ports from First to Last, inclusive.
+type portRange struct{ First, Last int }
+
+func (r portRange) contains(port int) bool { return port >= r.First && port <= r.Last }
+
+// parsePortRange reads two port numbers separated by white space, as
+// sysctl and /proc print them, with ok false when out has anything else.
+func parsePortRange(out string) (r portRange, ok bool) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return portRange{}, false
+	}
+	first, err1 := strconv.Atoi(fields[0])
+	last, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || first <= 0 || first > last {
+		return portRange{}, false
+	}
+	return portRange{first, last}, true
+}
+
+// expectedListeners are the services every machine on a network runs, as
+// "proto/port": mDNS and the DHCP clients. They are listed but not flagged.
+var expectedListeners = []string{"udp/5353", "udp/68", "udp/546"}
+
+// listener is a socket this machine accepts connections or datagrams on.
+type listener struct {
+	// Proto is "tcp" or "udp".
+	Proto string
+	// Addr is the local address, "*" for every interface.
+	Addr string
+	Port int
+	// Process is the program listening, when the platform tells.
+	Process string
+}
+
+// scope says who can reach l: everyone on the network, only this machine,
+// or the network of one address.
+func (l listener) scope() string {
+	if l.Addr == "*" {
+		return "all interfaces"
+	}
+	if addr, err := netip.ParseAddr(l.Addr); err == nil && addr.IsLoopback() {
+		return "this machine only"
+	}
+	return "on " + l.Addr
+}
+
+// flagged reports whether l is a service others on the network can reach,
+// leaving out expectedListeners and the UDP sockets clients send from,
+// which the system binds in the ephemeral range.
+func (l listener) flagged(ephemeral portRange) bool {
+	if l.Addr != "*" {
+		return false
+	}
+	if l.Proto == "udp" && ephemeral.contains(l.Port) {
+		return false
+	}
+	return !slices.Contains(expectedListeners, l.Proto+"/"+strconv.Itoa(l.Port))
+}
+
+func (l listener) String() string {
+	name := cmp.Or(l.Process, "unknown process")
+	return fmt.Sprintf("%s (%s %d)", name, strings.ToUpper(l.Proto), l.Port)
+}
+
+// parseListenAddr splits a local address such as "*:22", "[::1]:631",
+// "0.0.0.0:68" or "127.0.0.53%lo:53", mapping the wildcards to "*".
+func parseListenAddr(s string) (addr string, port int, ok bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	addr = strings.Trim(s[:i], "[]")
+	addr, _, _ = strings.Cut(addr, "%")
+	switch addr {
+	case "*", "0.0.0.0", "::":
+		addr = "*"
+	}
+	return addr, port, true
+}
+
+// parseLsofListeners reads `lsof -nP -iTCP -sTCP:LISTEN -iUDP`, skipping
+// UDP sockets connected to a peer.
+func parseLsofListeners(output string) []listener {
+	var listeners []listener
+	for line := range strings.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || strings.Contains(fields[8], "->") {
+			continue
+		}
+		proto := strings.ToLower(fields[7])
+		if proto != "tcp" && proto != "udp" {
+			continue
+		}
+		addr, port, ok := parseListenAddr(fields[8])
+		if !ok {
+			continue
+		}
+		process := strings.ReplaceAll(fields[0], `\x20`, " ")
+		listeners = append(listeners, listener{Proto: proto, Addr: addr, Port: port, Process: process})
+	}
+	return listeners
+}
+
+// parseSSListeners reads `ss -Htulnp`. Without root, ss leaves out the
+// processes of other users.
+func parseSSListeners(output string) []listener {
+	var listeners []listener
+	for line := range strings.Lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || (fields[0] != "tcp" && fields[0] != "udp") {
+			continue
+		}
+		addr, port, ok := parseListenAddr(fields[4])
+		if !ok {
+			continue
+		}
+		l := listener{Proto: fields[0], Addr: addr, Port: port}
+		if _, users, found := strings.Cut(line, `users:(("`); found {
+			l.Process, _, _ = strings.Cut(users, `"`)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// untrustedWiFi reports whether mode lets strangers join the network:
+// open and Enhanced Open hotspots, and passwords too weak to keep anyone
+// out.
+func untrustedWiFi(mode string) bool {
+	switch mode {
+	case securityOpen, securityOWE, securityWEP, securityWPA:
+		return true
+	}
+	return false
+}
+
+// CheckExposure lists the TCP and UDP ports this machine listens on and
+// warns when services listen on every interface while on untrusted Wi-Fi,
+// where anyone nearby can connect to them. "wtfi audit" runs it alongside
+// the other security checks.
+func CheckExposure(ctx context.Context) Result {
+	res := Result{Name: "Exposed Services", Emoji: "🚪", Status: StatusOk}
+	p := activePlatform()
+	out, err := runPlatformCommand(ctx, p.listenersCommand())
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	// lsof exits with 1 when nothing matched.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && len(out) == 0) {
+		res.Status = StatusError
+		res.Message = "Could not list listening ports"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	// An unreadable range leaves parseEphemeralPorts the platform default.
+	ports, _ := runPlatformCommand(ctx, p.ephemeralPortsCommand())
+	gradeExposure(&res, p.parseListeners(string(out)), p.parseEphemeralPorts(string(ports)), currentWiFiSecurity(ctx))
+	return res
+}
+
+// gradeExposure sets the verdict of CheckExposure from listeners, the
+// system's ephemeral port range and the security mode of the Wi-Fi
+// network, "" when not on Wi-Fi.
+func gradeExposure(res *Result, listeners []listener, ephemeral portRange, wifiMode string) {
+	// One line per service: IPv4 and IPv6 sockets of the same port, and
+	// the same process listening on several addresses, collapse into the
+	// widest scope.
+	wildcard := func(l listener) int {
+		if l.Addr == "*" {
+			return 0
+		}
+		return 1
+	}
+	slices.SortFunc(listeners, func(a, b listener) int {
+		return cmp.Or(cmp.Compare(a.Proto, b.Proto), cmp.Compare(a.Port, b.Port),
+			cmp.Compare(a.Process, b.Process), cmp.Compare(wildcard(a), wildcard(b)))
+	})
+	listeners = slices.CompactFunc(listeners, func(a, b listener) bool {
+		return a.Proto == b.Proto && a.Port == b.Port && a.Process == b.Process
+	})
+
+	var details []string
+	var exposed []string
+	for _, l := range listeners {
+		details = append(details, fmt.Sprintf("%s %s %s (%s)", strings.ToUpper(l.Proto), net.JoinHostPort(l.Addr, strconv.Itoa(l.Port)), cmp.Or(l.Process, "?"), l.scope()))
+		if l.flagged(ephemeral) {
+			exposed = append(exposed, l.String())
+		}
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	switch {
+	case len(listeners) == 0:
+		res.Message = "Nothing is listening"
+		return
+	case len(exposed) == 0:
+		res.Message = fmt.Sprintf("%d listening, none open to the network", len(listeners))
+		return
+	}
+	named := strings.Join(exposed[:min(len(exposed), 3)], ", ")
+	if len(exposed) > 3 {
+		named += fmt.Sprintf(" and %d more", len(exposed)-3)
+	}
+	if !untrustedWiFi(wifiMode) {
+		res.Message = named + " open to the network"
+		return
+	}
+	res.Status = StatusWarning
+	res.Reason = ReasonExposedService
+	res.Message = fmt.Sprintf("%s open to everyone on this Wi-Fi (%s)", named, wifiMode)
+	res.Fix = "Stop the services you do not need, or turn on the firewall and block incoming connections while on public Wi-Fi."
+}
@@ -0,0 +1,122 @@
+package diagnostic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseLsofListeners(t *testing.T) {
+	out := "COMMAND     PID  USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME\n" +
+		"rapportd    512 alice    4u  IPv4 0x1e2c3f4a5b6c7d8e      0t0  TCP *:49152 (LISTEN)\n" +
+		"ControlCe   530 alice    9u  IPv6 0x1e2c3f4a5b6c7d8f      0t0  TCP [::1]:7000 (LISTEN)\n" +
+		"Code\\x20H  801 alice   21u  IPv4 0x1e2c3f4a5b6c7d90      0t0  TCP 127.0.0.1:3000 (LISTEN)\n" +
+		"Spotify    900 alice   80u  IPv4 0x1e2c3f4a5b6c7d91      0t0  UDP *:57621\n" +
+		"Slack      910 alice   33u  IPv4 0x1e2c3f4a5b6c7d92      0t0  UDP 192.168.1.23:60000->17.0.0.1:443\n"
+	got := parseLsofListeners(out)
+	want := []listener{
+		{"tcp", "*", 49152, "rapportd"},
+		{"tcp", "::1", 7000, "ControlCe"},
+		{"tcp", "127.0.0.1", 3000, "Code H"},
+		{"udp", "*", 57621, "Spotify"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d listeners, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listener %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParseSSListeners(t *testing.T) {
+	out := "tcp   LISTEN 0      128          0.0.0.0:22        0.0.0.0:*    users:((\"sshd\",pid=812,fd=3))\n" +
+		"tcp   LISTEN 0      4096            [::]:22           [::]:*    users:((\"sshd\",pid=812,fd=4))\n" +
+		"udp   UNCONN 0      0      127.0.0.53%lo:53        0.0.0.0:*\n"
+	got := parseSSListeners(out)
+	want := []listener{
+		{"tcp", "*", 22, "sshd"},
+		{"tcp", "*", 22, "sshd"},
+		{"udp", "127.0.0.53", 53, ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d listeners, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listener %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestGradeExposure(t *testing.T) {
+	sshd := listener{"tcp", "*", 22, "sshd"}
+	sshd6 := listener{"tcp", "*", 22, "sshd"}
+	local := listener{"tcp", "127.0.0.1", 5432, "postgres"}
+	mdns := listener{"udp", "*", 5353, "mDNSResponder"}
+	client := listener{"udp", "*", 57621, "Spotify"}
+	ephemeral := portRange{49152, 65535}
+
+	tests := []struct {
+		name      string
+		listeners []listener
+		mode      string
+		status    Status
+		message   string
+	}{
+		{"nothing", nil, securityOpen, StatusOk, "Nothing is listening"},
+		{"local only", []listener{local, mdns, client}, securityOpen, StatusOk, "3 listening, none open to the network"},
+		{"home network", []listener{sshd, local}, securityWPA2, StatusOk, "sshd (TCP 22) open to the network"},
+		{"not on wi-fi", []listener{sshd}, "", StatusOk, "sshd (TCP 22) open to the network"},
+		{"open network", []listener{sshd, sshd6, local}, securityOpen, StatusWarning, "sshd (TCP 22) open to everyone on this Wi-Fi (Open)"},
+		{"weak network", []listener{sshd}, securityWEP, StatusWarning, "sshd (TCP 22) open to everyone on this Wi-Fi (WEP)"},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeExposure(&res, tt.listeners, ephemeral, tt.mode)
+		if res.Status != tt.status || res.Message != tt.message {
+			t.Errorf("%s: expected %v %q, got %v %q", tt.name, tt.status, tt.message, res.Status, res.Message)
+		}
+		if tt.status == StatusWarning && res.Reason != ReasonExposedService {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, ReasonExposedService, res.Reason)
+		}
+	}
+}
+
+func TestEphemeralPorts(t *testing.T) {
+	tests := []struct {
+		name string
+		p    platform
+		out  string
+		want portRange
+	}{
+		{"darwin", darwinPlatform{}, "49152\n65535\n", portRange{49152, 65535}},
+		{"linux", linuxPlatform{}, "32768\t60999\n", portRange{32768, 60999}},
+		{"linux tuned", linuxPlatform{}, "1024\t65000\n", portRange{1024, 65000}},
+		{"darwin unread", darwinPlatform{}, "", portRange{49152, 65535}},
+		{"linux unread", linuxPlatform{}, "", portRange{32768, 60999}},
+		{"garbage", linuxPlatform{}, "60999 32768", portRange{32768, 60999}},
+	}
+	for _, tt := range tests {
+		if got := tt.p.parseEphemeralPorts(tt.out); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	// A Linux client socket below the macOS range is not a service.
+	res := Result{Status: StatusOk}
+	client := listener{"udp", "*", 40123, "firefox"}
+	gradeExposure(&res, []listener{client}, portRange{32768, 60999}, securityOpen)
+	if res.Status != StatusOk {
+		t.Errorf("expected a client socket in the ephemeral range to pass, got %v %q", res.Status, res.Message)
+	}
+}
+
+func TestCheckExposureNothingListening(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{}})
+	res := CheckExposure(context.Background())
+	if res.Status != StatusOk || !strings.Contains(res.Message, "Nothing is listening") {
+		t.Errorf("expected lsof's empty exit to mean nothing listens, got %v %q", res.Status, res.Message)
+	}
+}
@@ -0,0 +1,195 @@
+package diagnostic
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var reAirportNetwork = regexp.MustCompile(`Current Wi-Fi Network: (.+)`)
+
+// filterVerdict is the conclusion drawn from a filterProbe.
+type filterVerdict int
+
+const (
+	filterNone filterVerdict = iota
+	filterDNS
+	filterConnection
+	filterUnreachable
+)
+
+// filterProbe captures what happened when we tried to reach a known-good host.
+type filterProbe struct {
+	// SystemAnswers are the addresses the system resolver returned.
+	SystemAnswers []string
+	// DirectAnswers are the addresses a public resolver (1.1.1.1) returned.
+	DirectAnswers []string
+	// Connected reports whether TCP 443 to a directly resolved address worked.
+	Connected bool
+	// BaselineConnected reports whether TCP 443 to the WAN host worked.
+	BaselineConnected bool
+}
+
+// CheckContentFilter reports Low Data Mode and whether traffic looks filtered.
+func CheckContentFilter(ctx context.Context) Result {
+	res := Result{Name: "Low Data Mode & Filtering", Emoji: "🚧", Status: StatusOk}
+	var details []string
+
+	lowData := "Low Data Mode: not readable (inferring from behavior)"
+	if iface, err := getPrimaryInterface(ctx); err == nil {
+		if out, err := runCommand(ctx, "networksetup", "-getairportnetwork", iface); err == nil {
+			if m := reAirportNetwork.FindStringSubmatch(string(out)); len(m) > 1 {
+				ssid := strings.TrimSpace(m[1])
+				if prefs, err := runCommand(ctx, "defaults", "read", "/Library/Preferences/com.apple.wifi.known-networks"); err == nil {
+					if enabled, found := parseLowDataMode(string(prefs), ssid); found {
+						lowData = "Low Data Mode: off"
+						if enabled {
+							lowData = "Low Data Mode: on"
+						}
+					}
+				}
+			}
+		}
+	}
+	details = append(details, lowData)
+
+	host := activeConfig(ctx).FilterProbeHost
+	probe := runFilterProbe(ctx, host)
+	details = append(details,
+		"System DNS: "+answersOrFail(probe.SystemAnswers),
+		"Direct DNS (1.1.1.1): "+answersOrFail(probe.DirectAnswers),
+	)
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeFiltering(&res, inferFiltering(probe), host)
+	if strings.HasSuffix(lowData, "on") {
+		res.Message += "; Low Data Mode is on"
+	}
+	return res
+}
+
+// gradeFiltering sets the verdict of CheckContentFilter for host.
+func gradeFiltering(res *Result, v filterVerdict, host string) {
+	switch v {
+	case filterDNS:
+		res.Status = StatusWarning
+		res.Reason = ReasonDNSFiltered
+		res.Message = host + " is blocked at the DNS level (filtered)"
+		res.Fix = "Check Screen Time content restrictions, a DNS filter, or network policy."
+	case filterConnection:
+		res.Status = StatusWarning
+		res.Reason = ReasonConnectionFiltered
+		res.Message = host + " resolves but connections are blocked (filtered)"
+		res.Fix = "A firewall or content filter is blocking this destination."
+	case filterUnreachable:
+		res.Status = StatusError
+		res.Reason = ReasonUnreachable
+		res.Message = host + " is unreachable (not filtered; no connectivity)"
+	case filterNone:
+		res.Message = "No filtering detected"
+	}
+}
+
+// runFilterProbe resolves host both ways and attempts the connections.
+func runFilterProbe(ctx context.Context, host string) filterProbe {
+	c := activeConfig(ctx)
+	timeout := c.Timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var p filterProbe
+	if ips, err := net.DefaultResolver.LookupHost(ctx, host); err == nil {
+		p.SystemAnswers = ips
+	}
+	if ips, err := newDirectResolver(wanTargetIPv4+":53", "udp").LookupHost(ctx, host); err == nil {
+		p.DirectAnswers = ips
+	}
+	if len(p.DirectAnswers) > 0 {
+		p.Connected = canConnect(ctx, net.JoinHostPort(p.DirectAnswers[0], "443"), timeout)
+	}
+	p.BaselineConnected = canConnect(ctx, net.JoinHostPort(c.WANHost, "443"), timeout)
+	return p
+}
+
+// inferFiltering decides whether a failure is filtering or plain unreachability.
+func inferFiltering(p filterProbe) filterVerdict {
+	if !p.BaselineConnected && !p.Connected {
+		return filterUnreachable
+	}
+	if len(p.DirectAnswers) > 0 && (len(p.SystemAnswers) == 0 || allSinkholed(p.SystemAnswers)) {
+		return filterDNS
+	}
+	if len(p.DirectAnswers) > 0 && !p.Connected {
+		return filterConnection
+	}
+	return filterNone
+}
+
+// allSinkholed reports whether every answer is a typical block-page address.
+func allSinkholed(answers []string) bool {
+	for _, a := range answers {
+		ip := net.ParseIP(a)
+		if ip == nil || !(ip.IsUnspecified() || ip.IsLoopback()) {
+			return false
+		}
+	}
+	return len(answers) > 0
+}
+
+// parseLowDataMode finds ssid's entry in the known-networks preferences and
+// reports its LowDataMode flag.
+func parseLowDataMode(prefs, ssid string) (enabled bool, found bool) {
+	start := strings.Index(prefs, `"wifi.network.ssid.`+ssid+`"`)
+	if start < 0 {
+		return false, false
+	}
+	block := prefs[start:]
+	// Each network entry is a top-level dictionary closed by "};".
+	if end := strings.Index(block, "\n    };"); end >= 0 {
+		block = block[:end]
+	}
+	for _, line := range strings.Split(block, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "LowDataMode = ") {
+			return strings.HasPrefix(strings.TrimPrefix(trimmed, "LowDataMode = "), "1"), true
+		}
+	}
+	return false, true
+}
+
+// newDirectResolver returns a resolver that queries server (host:port) over
+// network ("udp" or "tcp") only, whatever transport the resolver asks for.
+func newDirectResolver(server, network string) *net.Resolver {
+	// Lookups may outlive their caller's context, so bind the dialer now.
+	dial := dnsDial
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, network, server)
+		},
+	}
+}
+
+// dnsDial connects direct resolvers to their server; tests replace it.
+var dnsDial = func(ctx context.Context, network, server string) (net.Conn, error) {
+	d := net.Dialer{Timeout: activeConfig(ctx).Timeout}
+	return d.DialContext(ctx, network, server)
+}
+
+func canConnect(ctx context.Context, address string, timeout time.Duration) bool {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func answersOrFail(answers []string) string {
+	if len(answers) == 0 {
+		return "FAIL"
+	}
+	return strings.Join(answers, ", ")
+}
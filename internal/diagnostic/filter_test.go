@@ -0,0 +1,65 @@
+package diagnostic
+
+import "testing"
+
+func TestInferFiltering(t *testing.T) {
+	good := []string{"93.184.215.14"}
+	tests := []struct {
+		name  string
+		probe filterProbe
+		want  filterVerdict
+	}{
+		{"healthy", filterProbe{SystemAnswers: good, DirectAnswers: good, Connected: true, BaselineConnected: true}, filterNone},
+		{"nxdomain from system", filterProbe{DirectAnswers: good, Connected: true, BaselineConnected: true}, filterDNS},
+		{"sinkholed", filterProbe{SystemAnswers: []string{"0.0.0.0"}, DirectAnswers: good, Connected: true, BaselineConnected: true}, filterDNS},
+		{"connection blocked", filterProbe{SystemAnswers: good, DirectAnswers: good, BaselineConnected: true}, filterConnection},
+		{"offline", filterProbe{}, filterUnreachable},
+		{"offline with cached dns", filterProbe{SystemAnswers: good, DirectAnswers: good}, filterUnreachable},
+	}
+	for _, tt := range tests {
+		if got := inferFiltering(tt.probe); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestParseLowDataMode(t *testing.T) {
+	prefs := `{
+    "wifi.network.ssid.HomeWiFi" =     {
+        AddedAt = "2024-01-01 00:00:00 +0000";
+        LowDataMode = 1;
+    };
+    "wifi.network.ssid.Office" =     {
+        AddedAt = "2024-01-01 00:00:00 +0000";
+    };
+}`
+	if enabled, found := parseLowDataMode(prefs, "HomeWiFi"); !found || !enabled {
+		t.Errorf("Expected Low Data Mode on for HomeWiFi, got enabled=%v found=%v", enabled, found)
+	}
+	if enabled, found := parseLowDataMode(prefs, "Office"); !found || enabled {
+		t.Errorf("Expected Low Data Mode off for Office, got enabled=%v found=%v", enabled, found)
+	}
+	if _, found := parseLowDataMode(prefs, "Cafe"); found {
+		t.Error("Expected unknown SSID to be reported as not found")
+	}
+}
+
+func TestGradeFiltering(t *testing.T) {
+	tests := []struct {
+		verdict filterVerdict
+		status  Status
+		reason  Reason
+	}{
+		{filterNone, StatusOk, ""},
+		{filterDNS, StatusWarning, ReasonDNSFiltered},
+		{filterConnection, StatusWarning, ReasonConnectionFiltered},
+		{filterUnreachable, StatusError, ReasonUnreachable},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeFiltering(&res, tt.verdict, "example.com")
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("verdict %v: expected %v/%s, got %v/%s", tt.verdict, tt.status, tt.reason, res.Status, res.Reason)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// knownGateway is the MAC address a gateway answered ARP with.
+type knownGateway struct {
+	MAC       string    `json:"mac"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// knownGateways maps gatewayKey values to what earlier runs saw.
+type knownGateways map[string]*knownGateway
+
+// macSeverity ranks the reasons checkGatewayMAC flags; any other warning
+// ranks below both.
+var macSeverity = map[Reason]int{ReasonGatewayMACChanged: 1, ReasonARPSpoofing: 2}
+
+// gatewayKey names the gateway gw of the network iface is on. 192.168.1.1
+// is the gateway of most home networks, so it is qualified by the Wi-Fi
+// network name, or by the interface when wired.
+func gatewayKey(ctx context.Context, iface, gw string) string {
+	if link, err := readWiFiLink(ctx, iface, false); err == nil && link.RSSI != 0 && link.SSID != "" {
+		return "wifi:" + link.SSID + "/" + gw
+	}
+	return "wired:" + iface + "/" + gw
+}
+
+// checkGatewayMAC looks for signs of ARP spoofing around the gateway gw,
+// which answered ARP with mac: other MACs claiming gw, other addresses
+// claiming mac in the neighbor table, and mac differing from the one
+// recorded on an earlier run. It flags res and returns detail lines.
+func checkGatewayMAC(ctx context.Context, res *Result, gw, mac string) []string {
+	details := []string{"Gateway MAC: " + describeMAC(mac)}
+	// flag keeps what res already reports when it is an error or at least
+	// as severe, so the first and worst sign stays the message.
+	flag := func(reason Reason, msg string) {
+		if res.Status == StatusError || res.Status == StatusWarning && macSeverity[reason] <= macSeverity[res.Reason] {
+			return
+		}
+		res.Status = StatusWarning
+		res.Reason = reason
+		res.Message = msg
+	}
+
+	var table map[string][]string
+	if out, err := runPlatformCommand(ctx, activePlatform().neighborTableCommand()); err == nil {
+		table = activePlatform().parseNeighbors(string(out))
+	}
+	claims, sharers := spoofingSigns(table, gw, mac)
+	if len(sharers) > 0 {
+		details = append(details, "Also claimed by: "+strings.Join(sharers, ", "))
+		flag(ReasonARPSpoofing, "Gateway MAC "+mac+" also answers for "+strings.Join(sharers, ", "))
+	}
+	if len(claims) > 1 {
+		details = append(details, "Claimed by: "+strings.Join(claims, ", "))
+		flag(ReasonARPSpoofing, fmt.Sprintf("%d MAC addresses claim the gateway %s", len(claims), gw))
+	}
+
+	path := activeConfig(ctx).KnownGatewaysFile
+	if path != "" {
+		iface, err := getPrimaryInterface(ctx)
+		if err != nil {
+			return details
+		}
+		known := knownGateways{}
+		if err := loadState(path, &known); err != nil {
+			slog.Warn("could not access the known gateways", "err", err)
+			return details
+		}
+		if msg, changed := recordGatewayMAC(known, gatewayKey(ctx, iface, gw), mac, time.Now()); changed {
+			details = append(details, msg)
+			flag(ReasonGatewayMACChanged, "Gateway MAC address changed since the last run")
+		}
+		if err := saveState(path, known); err != nil {
+			slog.Warn("could not access the known gateways", "err", err)
+		}
+	}
+
+	if res.Reason == ReasonARPSpoofing || res.Reason == ReasonGatewayMACChanged {
+		res.Fix = "On an untrusted network this may be ARP spoofing: use a VPN and avoid logging in anywhere. A new router, or a Wi-Fi extender that rewrites MACs, looks the same."
+	}
+	return details
+}
+
+// spoofingSigns returns every MAC that table has for gw, and the other
+// addresses whose entry has mac; broadcast entries are ignored.
+func spoofingSigns(table map[string][]string, gw, mac string) (claims, sharers []string) {
+	claims = table[gw]
+	for ip, macs := range table {
+		if ip != gw && slices.Contains(macs, mac) && mac != "ff:ff:ff:ff:ff:ff" {
+			sharers = append(sharers, ip)
+		}
+	}
+	slices.Sort(sharers)
+	return claims, sharers
+}
+
+// recordGatewayMAC records that the gateway known as key answered with mac
+// at now. When an earlier run saw another MAC it returns a line describing
+// the change and true.
+func recordGatewayMAC(known knownGateways, key, mac string, now time.Time) (string, bool) {
+	prev := known[key]
+	if prev == nil || prev.MAC != mac {
+		known[key] = &knownGateway{MAC: mac, FirstSeen: now, LastSeen: now}
+	} else {
+		prev.LastSeen = now
+	}
+	if prev == nil || prev.MAC == mac {
+		return "", false
+	}
+	return fmt.Sprintf("Was %s (last seen %s), now %s", describeMAC(prev.MAC),
+		prev.LastSeen.Local().Format("2006-01-02 15:04"), describeMAC(mac)), true
+}
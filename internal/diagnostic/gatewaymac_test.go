@@ -0,0 +1,108 @@
+package diagnostic
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSpoofingSigns(t *testing.T) {
+	table := map[string][]string{
+		"192.168.1.1":     {"a4:83:e7:01:02:03", "3e:22:fb:01:02:03"},
+		"192.168.1.20":    {"3e:22:fb:01:02:03"},
+		"192.168.1.30":    {"00:11:22:33:44:55"},
+		"192.168.1.255":   {"ff:ff:ff:ff:ff:ff"},
+		"224.0.0.251":     {"01:00:5e:00:00:fb"},
+		"192.168.1.40":    {"a4:83:e7:01:02:03"},
+		"169.254.169.254": {},
+	}
+	claims, sharers := spoofingSigns(table, "192.168.1.1", "a4:83:e7:01:02:03")
+	if len(claims) != 2 {
+		t.Errorf("Expected two MACs claiming the gateway, got %v", claims)
+	}
+	if !slices.Equal(sharers, []string{"192.168.1.40"}) {
+		t.Errorf("Expected 192.168.1.40 to share the gateway MAC, got %v", sharers)
+	}
+
+	if claims, sharers := spoofingSigns(nil, "192.168.1.1", "a4:83:e7:01:02:03"); claims != nil || sharers != nil {
+		t.Errorf("Expected no signs without a table, got %v %v", claims, sharers)
+	}
+}
+
+func TestRecordGatewayMAC(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	known := knownGateways{}
+	if _, changed := recordGatewayMAC(known, "wired:en0/192.168.1.1", "a4:83:e7:01:02:03", now); changed {
+		t.Error("Expected the first sighting not to count as a change")
+	}
+	if _, changed := recordGatewayMAC(known, "wired:en0/192.168.1.1", "a4:83:e7:01:02:03", now.Add(time.Hour)); changed {
+		t.Error("Expected the same MAC not to count as a change")
+	}
+	if !known["wired:en0/192.168.1.1"].LastSeen.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected LastSeen to advance, got %+v", known["wired:en0/192.168.1.1"])
+	}
+	if _, changed := recordGatewayMAC(known, "wifi:Cafe/192.168.1.1", "00:11:22:33:44:55", now); changed {
+		t.Error("Expected another network's gateway not to count as a change")
+	}
+	if _, changed := recordGatewayMAC(known, "wired:en0/192.168.1.1", "3e:22:fb:01:02:03", now.Add(2*time.Hour)); !changed {
+		t.Error("Expected a new MAC to count as a change")
+	}
+	if known["wired:en0/192.168.1.1"].MAC != "3e:22:fb:01:02:03" {
+		t.Errorf("Expected the new MAC to be recorded, got %+v", known["wired:en0/192.168.1.1"])
+	}
+}
+
+func TestCheckGatewayMAC(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 192.168.1.1\n  interface: en0\n",
+		"arp -a -n":            "? (192.168.1.1) at a4:83:e7:1:2:3 on en0 ifscope [ethernet]\n",
+	}}
+	withRunner(t, runner)
+	c := DefaultConfig()
+	c.KnownGatewaysFile = filepath.Join(t.TempDir(), "known_gateways.json")
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	res := Result{Status: StatusOk}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "a4:83:e7:01:02:03")
+	if res.Status != StatusOk {
+		t.Errorf("Expected the first run to pass, got %v (%s)", res.Status, res.Message)
+	}
+
+	res = Result{Status: StatusOk}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "3e:22:fb:01:02:03")
+	if res.Status != StatusWarning || res.Reason != ReasonGatewayMACChanged {
+		t.Errorf("Expected a gateway_mac_changed warning, got %v/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+
+	runner.outputs["arp -a -n"] += "? (192.168.1.7) at 3e:22:fb:1:2:3 on en0 ifscope [ethernet]\n"
+	res = Result{Status: StatusOk}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "3e:22:fb:01:02:03")
+	if res.Status != StatusWarning || res.Reason != ReasonARPSpoofing {
+		t.Errorf("Expected an arp_spoofing warning, got %v/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+
+	// A MAC change outranks an ordinary warning but not an error.
+	res = Result{Status: StatusWarning, Reason: ReasonPacketLoss, Message: "loss"}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "a4:83:e7:01:02:03")
+	if res.Reason != ReasonGatewayMACChanged {
+		t.Errorf("Expected the MAC change to replace the loss warning, got %q (%s)", res.Reason, res.Message)
+	}
+	res = Result{Status: StatusError, Reason: ReasonPacketLoss, Message: "loss"}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "3e:22:fb:01:02:03")
+	if res.Status != StatusError || res.Message != "loss" {
+		t.Errorf("Expected the error to stay, got %v/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+
+	// Spoofing found first is kept over the MAC change found after it.
+	res = Result{Status: StatusOk}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "a4:83:e7:01:02:03")
+	res = Result{Status: StatusOk}
+	checkGatewayMAC(context.Background(), &res, "192.168.1.1", "3e:22:fb:01:02:03")
+	if res.Reason != ReasonARPSpoofing {
+		t.Errorf("Expected the spoofing sign to stay, got %q (%s)", res.Reason, res.Message)
+	}
+}
@@ -0,0 +1,163 @@
+package diagnostic
+
+import (
+	"slices"
+	"strings"
+)
+
+// Health is the conclusion of a run for people who want one.
+type Health struct {
+	// Score is 0-100: the weighted share of checks that passed.
+	Score int
+	// Verdict is a one-line conclusion such as "Your Wi-Fi is fine; DNS is
+	// the problem".
+	Verdict string
+	// Blamed is the index of the result the verdict blames, or -1 when
+	// everything that ran passed.
+	Blamed int
+	// Graded is how many results ran, skipped ones aside; with none the
+	// verdict says nothing about the network.
+	Graded int
+}
+
+// healthArea groups the checks of one part of the path, bottom up, with how
+// a verdict talks about it.
+type healthArea struct {
+	// checks[0] is the area's primary check: the area only counts as fine
+	// when that one ran and passed.
+	checks []string
+	// fine, attention and problem are the phrases for an area whose checks
+	// all passed, that warned and that failed.
+	fine, attention, problem string
+}
+
+// healthAreas are in the order packets cross them, so the verdict can say
+// which part works below the one that does not.
+var healthAreas = []healthArea{
+	{[]string{"wifi", "wifi-security", "evil-twin", "exposure"}, "your Wi-Fi is fine", "your Wi-Fi needs attention", "your Wi-Fi is the problem"},
+	{[]string{"ethernet"}, "your Ethernet link is fine", "your Ethernet link needs attention", "your Ethernet link is the problem"},
+	{[]string{"gateway", "routes", "default-routes", "interfaces", "ip-conflict", "dhcp", "gateway-security", "router-admin", "mtu", "mdns"}, "your router is fine", "your router needs attention", "your router is the problem"},
+	{[]string{"vpn", "split-dns"}, "your VPN is fine", "your VPN needs attention", "your VPN is the problem"},
+	{[]string{"wan", "ipv6", "trace", "double-nat", "nat-type", "public-ip", "top-talkers", "speed", "bufferbloat"}, "your internet connection is fine", "your internet connection needs attention", "your ISP is the problem"},
+	{[]string{"dns", "dnssec", "dns-hijack"}, "DNS is fine", "DNS needs attention", "DNS is the problem"},
+	{[]string{"captive"}, "no sign-in is required", "the network's sign-in check needs attention", "the internet cannot be reached"},
+	{[]string{"tls", "relay", "filter", "proxy", "tls-intercept", "quic", "services"}, "web access is fine", "web access needs attention", "web access is the problem"},
+	{[]string{"time"}, "your clock is fine", "your clock needs attention", "your clock is the problem"},
+}
+
+// reasonVerdicts phrase problems whose Reason says more than their area:
+// a captive check fails with an error when nothing answers at all, which
+// is no sign-in page.
+var reasonVerdicts = map[Reason]string{
+	ReasonCaptivePortal: "the network wants you to sign in",
+}
+
+// checkWeight is how much the check called name counts in the health score.
+func checkWeight(name string) int {
+	if c, ok := LookupCheck(name); ok && c.Weight > 0 {
+		return c.Weight
+	}
+	return 1
+}
+
+// areaOf returns the index of the healthArea holding check, or -1.
+func areaOf(check string) int {
+	return slices.IndexFunc(healthAreas, func(a healthArea) bool { return slices.Contains(a.checks, check) })
+}
+
+// AssessHealth scores results and names the part of the path most likely at
+// fault. Each check that ran counts its Weight: fully when OK, half when it
+// warned and not at all on an error; skipped checks do not count, and a run
+// where nothing ran scores 100.
+func AssessHealth(results []Result) Health {
+	var total, earned, graded int
+	// worst is the result the verdict blames: the worst status, then the
+	// heaviest check, then the lowest layer.
+	worst := -1
+	for i, r := range results {
+		if r.Status == StatusSkipped {
+			continue
+		}
+		graded++
+		w := checkWeight(r.Check)
+		total += 2 * w
+		switch r.Status {
+		case StatusOk:
+			earned += 2 * w
+		case StatusWarning:
+			earned += w
+		}
+		if r.Status == StatusOk {
+			continue
+		}
+		if worst < 0 || r.Status > results[worst].Status ||
+			(r.Status == results[worst].Status && w > checkWeight(results[worst].Check)) {
+			worst = i
+		}
+	}
+	h := Health{Score: 100, Blamed: worst, Graded: graded}
+	if total > 0 {
+		h.Score = 100 * earned / total
+	}
+	if worst < 0 {
+		if graded == 0 {
+			h.Verdict = "No checks ran"
+		} else {
+			h.Verdict = "Everything looks healthy"
+		}
+		return h
+	}
+
+	blamed := results[worst]
+	var problem string
+	area := areaOf(blamed.Check)
+	switch {
+	case reasonVerdicts[blamed.Reason] != "":
+		problem = reasonVerdicts[blamed.Reason]
+	case area < 0:
+		problem = blamed.Name + " reports a problem"
+	case blamed.Status == StatusError:
+		problem = healthAreas[area].problem
+	default:
+		problem = healthAreas[area].attention
+	}
+	// Name the lowest area that ran and passed below the blamed one, so the
+	// verdict also says what to stop suspecting.
+	if fine := fineArea(results, area); fine >= 0 {
+		problem = healthAreas[fine].fine + "; " + problem
+	}
+	h.Verdict = capitalize(problem)
+	return h
+}
+
+// fineArea returns the index of the first healthArea below limit (any area
+// when limit is -1) whose primary check ran and passed along with every
+// other check of it that ran, or -1.
+func fineArea(results []Result, limit int) int {
+	primaryOK := make([]bool, len(healthAreas))
+	failed := make([]bool, len(healthAreas))
+	for _, r := range results {
+		a := areaOf(r.Check)
+		if a < 0 || r.Status == StatusSkipped {
+			continue
+		}
+		primaryOK[a] = primaryOK[a] || (r.Check == healthAreas[a].checks[0] && r.Status == StatusOk)
+		failed[a] = failed[a] || r.Status != StatusOk
+	}
+	for i := range healthAreas {
+		if limit >= 0 && i >= limit {
+			break
+		}
+		if primaryOK[i] && !failed[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,80 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAssessHealth(t *testing.T) {
+	ok := func(check string) Result { return Result{Check: check, Status: StatusOk} }
+	stamp := func(check string, r Result) Result { r.Check = check; return r }
+	tests := []struct {
+		name    string
+		results []Result
+		score   int
+		verdict string
+	}{
+		{"nothing ran", []Result{{Check: "wifi", Status: StatusSkipped}}, 100, "No checks ran"},
+		{"healthy", []Result{ok("wifi"), ok("gateway"), ok("dns")}, 100, "Everything looks healthy"},
+		{
+			"dns down",
+			[]Result{ok("wifi"), ok("gateway"), ok("wan"), {Check: "dns", Status: StatusError}},
+			75, "Your Wi-Fi is fine; DNS is the problem",
+		},
+		{
+			// The heavier WAN error is blamed over the lighter trace error.
+			"isp down",
+			[]Result{ok("wifi"), {Check: "trace", Status: StatusError}, {Check: "wan", Status: StatusError}},
+			42, "Your Wi-Fi is fine; your ISP is the problem",
+		},
+		{
+			"weak wifi",
+			[]Result{{Check: "wifi", Status: StatusWarning}, ok("gateway"), ok("dns")},
+			83, "Your Wi-Fi needs attention",
+		},
+		{
+			"sign-in",
+			[]Result{ok("wifi"), ok("dns"), {Check: "captive", Status: StatusWarning, Reason: ReasonCaptivePortal}},
+			83, "Your Wi-Fi is fine; the network wants you to sign in",
+		},
+		{
+			// The placeholders of checks that only run with -v do not count,
+			// and Wi-Fi is not fine when its own check was skipped.
+			"portal probe failed",
+			[]Result{
+				{Check: "wifi", Status: StatusSkipped}, ok("gateway"), stamp("evil-twin", CheckEvilTwin(context.Background(), false)),
+				stamp("trace", FastTraceroute(context.Background(), false)), {Check: "captive", Status: StatusError, Reason: ReasonProbeFailed},
+			},
+			50, "Your router is fine; the internet cannot be reached",
+		},
+		{
+			"wifi check skipped",
+			[]Result{{Check: "wifi", Status: StatusSkipped}, ok("wifi-security"), {Check: "dns", Status: StatusError}},
+			25, "DNS is the problem",
+		},
+		{
+			"plugin",
+			[]Result{ok("wifi"), {Name: "Printer", Check: "printer", Status: StatusWarning}},
+			87, "Your Wi-Fi is fine; Printer reports a problem",
+		},
+	}
+	for _, tt := range tests {
+		h := AssessHealth(tt.results)
+		if h.Score != tt.score || h.Verdict != tt.verdict {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.name, tt.score, tt.verdict, h.Score, h.Verdict)
+		}
+		if (h.Blamed < 0) != (tt.score == 100) || (h.Blamed >= 0 && tt.results[h.Blamed].Status == StatusOk) {
+			t.Errorf("%s: unexpected blamed result %d", tt.name, h.Blamed)
+		}
+		if (h.Graded == 0) != (tt.name == "nothing ran") {
+			t.Errorf("%s: unexpected graded count %d", tt.name, h.Graded)
+		}
+	}
+}
+
+func TestCheckerStampsCheck(t *testing.T) {
+	c := Check{Name: "fake", Run: func(context.Context, bool) Result { return Result{Name: "Fake (detail)"} }}
+	if r := c.Checker().Run(context.Background(), Options{}); r.Check != "fake" {
+		t.Errorf("Expected the result to name its check, got %q", r.Check)
+	}
+}
@@ -0,0 +1,149 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// hijackNXZone has no wildcard record, so a random name under it does
+	// not exist and any address returned for one was made up on the way.
+	hijackNXZone = "example.com"
+	// hijackCanary always resolves to Cloudflare's resolver addresses.
+	hijackCanary = "one.one.one.one"
+	// hijackDecoy is in TEST-NET-1 (RFC 5737): no DNS server runs there, so
+	// an answer from it can only come from a box intercepting port 53.
+	hijackDecoy = "192.0.2.1:53"
+)
+
+// hijackCanaryAddrs are the only addresses hijackCanary resolves to.
+var hijackCanaryAddrs = []string{"1.1.1.1", "1.0.0.1", "2606:4700:4700::1111", "2606:4700:4700::1001"}
+
+// hijackProbe captures the answers to the hijack lookups; nil means the
+// lookup failed or, for the nonexistent name, returned NXDOMAIN.
+type hijackProbe struct {
+	// SystemNX and DirectNX answer a random nonexistent name through the
+	// system resolver and 1.1.1.1.
+	SystemNX []string
+	DirectNX []string
+	// SystemCanary and DirectCanary answer hijackCanary.
+	SystemCanary []string
+	DirectCanary []string
+	// Decoy are the answers received from hijackDecoy.
+	Decoy []string
+}
+
+// CheckDNSHijack looks for DNS answers rewritten on the way: a resolver that
+// turns NXDOMAIN into an ad or search page, a resolver that answers a
+// well-known name with the wrong address, and middleboxes that intercept
+// queries to port 53 and answer in place of the server asked.
+func CheckDNSHijack(ctx context.Context) Result {
+	res := Result{Name: "DNS Hijack", Emoji: "🕵️", Status: StatusOk}
+	nx := randomLabel() + "." + hijackNXZone
+	p := runHijackProbe(ctx, nx)
+	res.Details = formatDetailsWithPrefixes([]string{
+		"System DNS, " + nx + ": " + nxOrAnswers(p.SystemNX),
+		"Direct DNS (1.1.1.1), nonexistent name: " + nxOrAnswers(p.DirectNX),
+		"System DNS, " + hijackCanary + ": " + answersOrFail(p.SystemCanary),
+		"Direct DNS (1.1.1.1), " + hijackCanary + ": " + answersOrFail(p.DirectCanary),
+		"Decoy server (192.0.2.1, no DNS there): " + decoyResult(p.Decoy),
+	})
+	gradeHijack(&res, p)
+	return res
+}
+
+// runHijackProbe runs the lookups of CheckDNSHijack in parallel, each under
+// Config.Timeout; nx is the nonexistent name.
+func runHijackProbe(ctx context.Context, nx string) hijackProbe {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	system := net.DefaultResolver
+	direct := newDirectResolver(wanTargetIPv4+":53", "udp")
+	decoy := newDirectResolver(hijackDecoy, "udp")
+
+	var p hijackProbe
+	var wg sync.WaitGroup
+	lookup := func(dst *[]string, r *net.Resolver, host string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ips, err := r.LookupHost(ctx, host); err == nil {
+				*dst = ips
+			}
+		}()
+	}
+	lookup(&p.SystemNX, system, nx)
+	lookup(&p.DirectNX, direct, nx)
+	lookup(&p.SystemCanary, system, hijackCanary)
+	lookup(&p.DirectCanary, direct, hijackCanary)
+	lookup(&p.Decoy, decoy, hijackCanary)
+	wg.Wait()
+	return p
+}
+
+// gradeHijack sets the verdict of CheckDNSHijack. Interception is graded
+// first, since it also explains rewritten answers from the system resolver.
+func gradeHijack(res *Result, p hijackProbe) {
+	switch {
+	case len(p.Decoy) > 0 || len(p.DirectNX) > 0 || wrongCanary(p.DirectCanary):
+		res.Status = StatusWarning
+		res.Reason = ReasonDNSIntercepted
+		res.Message = "DNS queries to port 53 are intercepted and answered by the network"
+		res.Fix = "A transparent DNS proxy sees and can rewrite every lookup; use DoH or DoT (e.g. 1.1.1.1 in encrypted mode) or a VPN."
+	case wrongCanary(p.SystemCanary):
+		res.Status = StatusWarning
+		res.Reason = ReasonDNSHijacked
+		res.Message = "System resolver answers " + hijackCanary + " with " + strings.Join(p.SystemCanary, ", ")
+		res.Fix = "The resolver forges answers; switch to a trusted resolver with encrypted DNS, or use a VPN."
+	case len(p.SystemNX) > 0:
+		res.Status = StatusWarning
+		res.Reason = ReasonNXDOMAINRewritten
+		res.Message = "Resolver rewrites nonexistent names to " + strings.Join(p.SystemNX, ", ")
+		res.Fix = "Typos lead to the ISP's search or ad page, and apps cannot tell a missing host from a working one; opt out with the ISP or use another resolver."
+	case len(p.SystemCanary) == 0 && len(p.DirectCanary) == 0:
+		res.Status = StatusWarning
+		res.Reason = ReasonProbeFailed
+		res.Message = "Inconclusive: " + hijackCanary + " did not resolve"
+	case len(p.DirectCanary) == 0:
+		res.Message = "System resolver answers truthfully (1.1.1.1 unreachable on port 53)"
+	default:
+		res.Message = "No DNS hijacking or interception detected"
+	}
+}
+
+// wrongCanary reports whether answers for hijackCanary include an address
+// it does not have.
+func wrongCanary(answers []string) bool {
+	for _, a := range answers {
+		if !slices.Contains(hijackCanaryAddrs, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomLabel returns a DNS label no one has registered or cached.
+func randomLabel() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "wtfi-" + hex.EncodeToString(b)
+}
+
+func nxOrAnswers(answers []string) string {
+	if len(answers) == 0 {
+		return "does not exist"
+	}
+	return strings.Join(answers, ", ")
+}
+
+func decoyResult(answers []string) string {
+	if len(answers) == 0 {
+		return "no answer"
+	}
+	return "answered " + strings.Join(answers, ", ")
+}
@@ -0,0 +1,39 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGradeHijack(t *testing.T) {
+	canary := []string{"1.1.1.1", "1.0.0.1", "2606:4700:4700::1111"}
+	tests := []struct {
+		name   string
+		probe  hijackProbe
+		status Status
+		reason Reason
+	}{
+		{"clean", hijackProbe{SystemCanary: canary, DirectCanary: canary}, StatusOk, ""},
+		{"nxdomain rewritten", hijackProbe{SystemNX: []string{"92.242.140.21"}, SystemCanary: canary, DirectCanary: canary}, StatusWarning, ReasonNXDOMAINRewritten},
+		{"forged canary", hijackProbe{SystemCanary: []string{"10.0.0.5"}, DirectCanary: canary}, StatusWarning, ReasonDNSHijacked},
+		{"direct nxdomain answered", hijackProbe{DirectNX: []string{"10.0.0.5"}, SystemCanary: canary, DirectCanary: canary}, StatusWarning, ReasonDNSIntercepted},
+		{"direct canary forged", hijackProbe{SystemCanary: canary, DirectCanary: []string{"10.0.0.5"}}, StatusWarning, ReasonDNSIntercepted},
+		{"decoy answered", hijackProbe{SystemCanary: canary, DirectCanary: canary, Decoy: canary}, StatusWarning, ReasonDNSIntercepted},
+		{"port 53 blocked", hijackProbe{SystemCanary: canary}, StatusOk, ""},
+		{"nothing resolves", hijackProbe{}, StatusWarning, ReasonProbeFailed},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeHijack(&res, tt.probe)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestRandomLabel(t *testing.T) {
+	a, b := randomLabel(), randomLabel()
+	if a == b || !strings.HasPrefix(a, "wtfi-") || len(a) > 63 {
+		t.Errorf("Expected distinct valid labels, got %q and %q", a, b)
+	}
+}
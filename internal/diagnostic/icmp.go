@@ -0,0 +1,45 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// gatewayTCPPorts are tried, in order, when ICMP to the gateway is not permitted.
+var gatewayTCPPorts = []string{"443", "80"}
+
+// isPermissionError reports whether a ping subprocess failed because the
+// system refused it an ICMP socket, rather than because of the network.
+func isPermissionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM) {
+		return true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stderr := string(exitErr.Stderr)
+		return strings.Contains(stderr, "Operation not permitted") || strings.Contains(stderr, "Permission denied")
+	}
+	return false
+}
+
+// tcpProbe measures the connect latency to the first of ports accepting on
+// host, and returns that port.
+func tcpProbe(ctx context.Context, host string, ports ...string) (time.Duration, string, error) {
+	err := errors.New("no ports to probe")
+	for _, port := range ports {
+		var lat time.Duration
+		if lat, err = tcpPing(ctx, net.JoinHostPort(host, port)); err == nil {
+			return lat, port, nil
+		}
+	}
+	return 0, "", err
+}
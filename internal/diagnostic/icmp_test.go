@@ -0,0 +1,62 @@
+package diagnostic
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsPermissionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"ping stderr", &exec.ExitError{Stderr: []byte("ping: socket: Operation not permitted\n")}, true},
+		{"timeout", &exec.ExitError{Stderr: []byte("")}, false},
+		{"exec denied", &os.PathError{Op: "fork/exec", Path: "/sbin/ping", Err: os.ErrPermission}, true},
+	}
+	for _, tt := range tests {
+		if got := isPermissionError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCheckL3GatewayICMPFallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	prev := gatewayTCPPorts
+	gatewayTCPPorts = []string{port}
+	t.Cleanup(func() { gatewayTCPPorts = prev })
+
+	withRunner(t, &fakeRunner{
+		outputs: map[string]string{"route -n get default": "    gateway: 127.0.0.1\n  interface: en0\n"},
+		errs: map[string]error{
+			"ping -c 1 127.0.0.1": &exec.ExitError{Stderr: []byte("ping: socket: Operation not permitted\n")},
+		},
+	})
+
+	res := CheckL3Gateway(context.Background(), false)
+	if res.Status != StatusOk || res.Reason != ReasonICMPUnavailable {
+		t.Fatalf("Expected an OK result measured over TCP, got %v/%s (%s)", res.Status, res.Reason, res.Message)
+	}
+	if !strings.Contains(res.Message, "TCP :"+port) {
+		t.Errorf("Expected the message to name the TCP probe, got %s", res.Message)
+	}
+
+	_ = ln.Close()
+	res = CheckL3Gateway(context.Background(), false)
+	if res.Status != StatusWarning || res.Reason != ReasonICMPUnavailable {
+		t.Errorf("Expected an unknown-reachability warning, not unreachable, got %v/%s", res.Status, res.Reason)
+	}
+}
@@ -0,0 +1,213 @@
+package diagnostic
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Kinds of network interface, as CheckInterfaces names them.
+const (
+	kindEthernet  = "Ethernet"
+	kindWiFi      = "Wi-Fi"
+	kindTethering = "USB tethering"
+	kindBluetooth = "Bluetooth"
+	kindBridge    = "Thunderbolt Bridge"
+	kindVPN       = "VPN"
+	kindOther     = "Other"
+)
+
+// kindSpeed ranks the kinds that carry traffic to the internet by how fast
+// they usually are. VPNs ride on one of them and bridges rarely lead
+// anywhere, so they are not ranked.
+var kindSpeed = map[string]int{kindEthernet: 4, kindWiFi: 3, kindTethering: 2, kindBluetooth: 1}
+
+// netInterface is an interface that is up and has an address.
+type netInterface struct {
+	Name string
+	// Addr is the first IPv4 address, else the first global IPv6 one.
+	Addr string
+	Kind string
+	// Order is the position in the network service order, from 1; 0 where
+	// the platform has none or the interface is not a service.
+	Order int
+	// Default reports whether the interface has a default route, scoped or not.
+	Default bool
+}
+
+// systemInterfaces lists the interfaces that are up, have an address to
+// talk to the internet with and are not loopback; tests replace it.
+var systemInterfaces = func() ([]netInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var active []netInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		var v4, v6 string
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			switch {
+			case !ok:
+			case ipnet.IP.To4() != nil && !ipnet.IP.IsLinkLocalUnicast():
+				v4 = cmp.Or(v4, ipnet.IP.String())
+			case ipnet.IP.To4() == nil && ipnet.IP.IsGlobalUnicast():
+				v6 = cmp.Or(v6, ipnet.IP.String())
+			}
+		}
+		if addr := cmp.Or(v4, v6); addr != "" {
+			active = append(active, netInterface{Name: iface.Name, Addr: addr})
+		}
+	}
+	return active, nil
+}
+
+// interfaceKind names the kind of interface name from its hardware port in
+// the service order (macOS) or, without one, from its name.
+func interfaceKind(name, port string) string {
+	has := func(prefixes ...string) bool {
+		return slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(name, p) })
+	}
+	switch {
+	case port == "Wi-Fi" || has("wl", "ath"):
+		return kindWiFi
+	case strings.Contains(port, "iPhone") || strings.Contains(port, "iPad") || has("usb", "rndis"):
+		return kindTethering
+	case strings.Contains(port, "Bluetooth") || has("bnep"):
+		return kindBluetooth
+	case port == "Thunderbolt Bridge" || has("bridge"):
+		return kindBridge
+	case has("utun", "ipsec", "ppp", "tun", "tap", "wg", "tailscale", "zt"):
+		return kindVPN
+	case port != "" || has("en", "eth"):
+		return kindEthernet
+	}
+	return kindOther
+}
+
+// CheckInterfaces lists every active interface with its kind, address,
+// service order position and default route, and warns when traffic leaves
+// over a slower kind of link than another one that could carry it, such as
+// Wi-Fi while a cable is plugged in.
+func CheckInterfaces(ctx context.Context) Result {
+	res := Result{Name: "Interfaces", Emoji: "🔌", Status: StatusOk}
+	ifaces, err := systemInterfaces()
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "Could not list network interfaces"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+
+	p := activePlatform()
+	var order []networkService
+	if cmd := p.serviceOrderCommand(); cmd != nil {
+		if out, err := runPlatformCommand(ctx, cmd); err == nil {
+			order = parseNetworkServiceOrder(string(out))
+		}
+	}
+	out, err := runPlatformCommand(ctx, p.defaultRoutesCommand())
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	var routed []string
+	for _, r := range p.parseDefaultRoutes(string(out)) {
+		routed = append(routed, r.Iface)
+	}
+	for i := range ifaces {
+		port := ""
+		if j := slices.IndexFunc(order, func(s networkService) bool { return s.Device == ifaces[i].Name }); j >= 0 {
+			port, ifaces[i].Order = order[j].Port, j+1
+		}
+		ifaces[i].Kind = interfaceKind(ifaces[i].Name, port)
+		ifaces[i].Default = slices.Contains(routed, ifaces[i].Name)
+	}
+	primary, _ := getPrimaryInterface(ctx)
+	gradeInterfaces(&res, ifaces, primary, activeConfig(ctx).Interface, order != nil)
+	return res
+}
+
+// gradeInterfaces sets the verdict of CheckInterfaces. primary carries the
+// traffic; selected is the interface chosen with --interface, if any, and
+// serviceOrder tells whether the platform orders interfaces by service.
+func gradeInterfaces(res *Result, ifaces []netInterface, primary, selected string, serviceOrder bool) {
+	var details, routed []string
+	for _, iface := range ifaces {
+		var tags []string
+		if iface.Order > 0 {
+			tags = append(tags, fmt.Sprintf("service #%d", iface.Order))
+		}
+		if iface.Default {
+			tags = append(tags, "default route")
+			routed = append(routed, iface.Name)
+		}
+		switch {
+		case iface.Name == selected:
+			tags = append(tags, "selected")
+		case iface.Name == primary:
+			tags = append(tags, "in use")
+		}
+		line := fmt.Sprintf("%s (%s): %s", iface.Name, iface.Kind, iface.Addr)
+		if len(tags) > 0 {
+			line += " [" + strings.Join(tags, ", ") + "]"
+		}
+		details = append(details, line)
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	if len(routed) > 1 {
+		res.Facts = map[string]string{FactDefaultRoutes: strings.Join(routed, ",")}
+	}
+
+	i := slices.IndexFunc(ifaces, func(n netInterface) bool { return n.Name == primary })
+	if selected != "" {
+		if i < 0 {
+			res.Status = StatusError
+			res.Reason = ReasonNoRoute
+			res.Message = selected + " is not up or has no address"
+			res.Fix = "Pick one of the active interfaces listed with --interface, or leave it out to follow the default route."
+			return
+		}
+		res.Message = fmt.Sprintf("Route discovery uses %s (%s), selected with --interface", selected, ifaces[i].Kind)
+		return
+	}
+	if i < 0 {
+		res.Message = fmt.Sprintf("%d active interfaces", len(ifaces))
+		return
+	}
+	used := ifaces[i]
+	// The fastest other interface that could carry the traffic instead.
+	var faster *netInterface
+	for j := range ifaces {
+		n := &ifaces[j]
+		if n.Default && n.Name != used.Name && kindSpeed[n.Kind] > kindSpeed[used.Kind] &&
+			(faster == nil || kindSpeed[n.Kind] > kindSpeed[faster.Kind]) {
+			faster = n
+		}
+	}
+	if kindSpeed[used.Kind] == 0 || faster == nil {
+		if len(ifaces) == 1 {
+			res.Message = fmt.Sprintf("Only %s (%s) is active", used.Name, used.Kind)
+		} else {
+			res.Message = fmt.Sprintf("%d active interfaces; traffic uses %s (%s)", len(ifaces), used.Name, used.Kind)
+		}
+		return
+	}
+	res.Status = StatusWarning
+	res.Reason = ReasonSlowerInterface
+	res.Message = fmt.Sprintf("Traffic goes over %s (%s) although %s (%s) is connected", used.Kind, used.Name, faster.Kind, faster.Name)
+	if serviceOrder {
+		res.Fix = fmt.Sprintf("Move %s above %s in the network service order (wtfi fix can do it), or turn %s off. Run wtfi --interface %s to test that link.", faster.Name, used.Name, used.Kind, faster.Name)
+	} else {
+		res.Fix = fmt.Sprintf("Give %s a lower route metric than %s (nmcli connection modify <name> ipv4.route-metric 100). Run wtfi --interface %s to test that link.", faster.Name, used.Name, faster.Name)
+	}
+}
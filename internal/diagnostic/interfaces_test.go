@@ -0,0 +1,101 @@
+package diagnostic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInterfaceKind(t *testing.T) {
+	tests := []struct {
+		name, port, want string
+	}{
+		{"en0", "Wi-Fi", kindWiFi},
+		{"en7", "USB 10/100/1000 LAN", kindEthernet},
+		{"en8", "Thunderbolt Ethernet Slot 1", kindEthernet},
+		{"en9", "iPhone USB", kindTethering},
+		{"bridge0", "Thunderbolt Bridge", kindBridge},
+		{"en3", "Bluetooth PAN", kindBluetooth},
+		{"utun4", "", kindVPN},
+		{"wlp2s0", "", kindWiFi},
+		{"enp3s0", "", kindEthernet},
+		{"usb0", "", kindTethering},
+		{"wg0", "", kindVPN},
+		{"docker0", "", kindOther},
+	}
+	for _, tt := range tests {
+		if got := interfaceKind(tt.name, tt.port); got != tt.want {
+			t.Errorf("%s (%q): expected %s, got %s", tt.name, tt.port, tt.want, got)
+		}
+	}
+}
+
+func TestGradeInterfaces(t *testing.T) {
+	wifi := netInterface{Name: "en0", Addr: "192.168.1.23", Kind: kindWiFi, Order: 1, Default: true}
+	wired := netInterface{Name: "en7", Addr: "192.168.1.24", Kind: kindEthernet, Order: 2, Default: true}
+	unrouted := wired
+	unrouted.Default = false
+	vpn := netInterface{Name: "utun4", Addr: "100.101.1.2", Kind: kindVPN, Default: true}
+	phone := netInterface{Name: "en9", Addr: "172.20.10.2", Kind: kindTethering, Order: 3, Default: true}
+
+	tests := []struct {
+		name     string
+		ifaces   []netInterface
+		primary  string
+		selected string
+		status   Status
+		reason   Reason
+	}{
+		{"wi-fi only", []netInterface{wifi}, "en0", "", StatusOk, ""},
+		{"wired wins", []netInterface{wired, wifi}, "en7", "", StatusOk, ""},
+		{"wi-fi over cable", []netInterface{wifi, wired}, "en0", "", StatusWarning, ReasonSlowerInterface},
+		{"cable without route", []netInterface{wifi, unrouted}, "en0", "", StatusOk, ""},
+		{"tethering over wi-fi", []netInterface{phone, wifi}, "en9", "", StatusWarning, ReasonSlowerInterface},
+		{"vpn", []netInterface{vpn, wifi, wired}, "utun4", "", StatusOk, ""},
+		{"selected", []netInterface{wifi, wired}, "en0", "en0", StatusOk, ""},
+		{"selected but down", []netInterface{wifi}, "", "en7", StatusError, ReasonNoRoute},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeInterfaces(&res, tt.ifaces, tt.primary, tt.selected, true)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestCheckInterfaces(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "   route to: default\ndestination: default\n    gateway: 192.168.1.1\n  interface: en0\n",
+		"networksetup -listnetworkserviceorder": "(1) Wi-Fi\n(Hardware Port: Wi-Fi, Device: en0)\n\n" +
+			"(2) USB 10/100/1000 LAN\n(Hardware Port: USB 10/100/1000 LAN, Device: en7)\n",
+		"netstat -rn -f inet": "Destination        Gateway            Flags           Netif Expire\n" +
+			"default            192.168.1.1        UGScg             en0\n" +
+			"default            192.168.1.1        UGScIg            en7\n",
+	}})
+	prev := systemInterfaces
+	systemInterfaces = func() ([]netInterface, error) {
+		return []netInterface{{Name: "en0", Addr: "192.168.1.23"}, {Name: "en7", Addr: "192.168.1.24"}}, nil
+	}
+	t.Cleanup(func() { systemInterfaces = prev })
+
+	res := CheckInterfaces(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonSlowerInterface {
+		t.Fatalf("expected a slower_interface warning, got %v/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+	if res.Facts[FactDefaultRoutes] != "en0,en7" {
+		t.Errorf("expected both routed interfaces, got %v", res.Facts)
+	}
+	if !strings.Contains(strings.Join(res.Details, "\n"), "en7 (Ethernet): 192.168.1.24 [service #2, default route]") {
+		t.Errorf("unexpected details %q", res.Details)
+	}
+}
+
+func TestRouteCommandInterface(t *testing.T) {
+	if got := strings.Join(darwinPlatform{}.routeCommand(false, "en7"), " "); got != "route -n get -ifscope en7 default" {
+		t.Errorf("unexpected darwin command %q", got)
+	}
+	if got := strings.Join(linuxPlatform{}.routeCommand(true, "eth1"), " "); got != "ip -6 route show default dev eth1" {
+		t.Errorf("unexpected linux command %q", got)
+	}
+}
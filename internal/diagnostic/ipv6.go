@@ -0,0 +1,128 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ipv6ProbeHost is resolved for AAAA records by CheckIPv6.
+const ipv6ProbeHost = "google.com"
+
+// ipv6Probe captures what CheckIPv6 observed.
+type ipv6Probe struct {
+	// Gateway is the IPv6 default gateway, or "" when there is no IPv6 route.
+	Gateway string
+	// IPv4 reports whether an IPv4 default route exists to fall back on.
+	IPv4 bool
+	// Ping and TCP are the ICMPv6 round trip and the TCP connect time to
+	// the well-known IPv6 target; their errors are set when they failed.
+	Ping    time.Duration
+	PingErr error
+	TCP     time.Duration
+	TCPErr  error
+	// AAAA are the IPv6 addresses the system resolver returned.
+	AAAA    []string
+	AAAAErr error
+}
+
+// CheckIPv6 tests the IPv6 path end to end: the default route, an ICMPv6
+// ping and a TCP connect to a well-known IPv6 host, and AAAA resolution.
+// IPv6 that is configured but goes nowhere makes every dual-stack
+// connection wait for the Happy Eyeballs fallback to IPv4.
+func CheckIPv6(ctx context.Context) Result {
+	res := Result{Name: "IPv6", Emoji: "6️⃣", Status: StatusOk}
+	p := runIPv6Probe(ctx)
+
+	gateway := "none (no IPv6 default route)"
+	if p.Gateway != "" {
+		gateway = p.Gateway
+	}
+	details := []string{"Default Route: " + gateway}
+	if p.Gateway != "" {
+		details = append(details,
+			fmt.Sprintf("ICMPv6 (%s): %s", wanTargetIPv6, durationOrFail(p.Ping, p.PingErr)),
+			fmt.Sprintf("TCP 443 (%s): %s", wanTargetIPv6, durationOrFail(p.TCP, p.TCPErr)),
+		)
+	}
+	aaaa := "FAIL"
+	if p.AAAAErr == nil {
+		aaaa = strings.Join(p.AAAA, ", ")
+	}
+	details = append(details, fmt.Sprintf("AAAA (%s): %s", ipv6ProbeHost, aaaa))
+	res.Details = formatDetailsWithPrefixes(details)
+
+	gradeIPv6(&res, p)
+	return res
+}
+
+// runIPv6Probe gathers the observations graded by gradeIPv6. The network
+// probes only run when there is an IPv6 default route.
+func runIPv6Probe(ctx context.Context) ipv6Probe {
+	var p ipv6Probe
+	p.Gateway, _ = getGatewayIP6(ctx)
+	_, errIPv4 := getGatewayIP(ctx)
+	p.IPv4 = errIPv4 == nil
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+		defer cancel()
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip6", ipv6ProbeHost)
+		for _, ip := range ips {
+			p.AAAA = append(p.AAAA, ip.String())
+		}
+		p.AAAAErr = err
+	}()
+	if p.Gateway != "" {
+		wg.Add(2)
+		go func() { defer wg.Done(); p.Ping, p.PingErr = ping6(ctx, wanTargetIPv6) }()
+		go func() { defer wg.Done(); p.TCP, p.TCPErr = tcpPing(ctx, wanTargetTCP6) }()
+	}
+	wg.Wait()
+	return p
+}
+
+// gradeIPv6 sets the verdict of CheckIPv6. A missing IPv6 route is fine;
+// a route that carries nothing is not, and is fatal without IPv4.
+func gradeIPv6(res *Result, p ipv6Probe) {
+	switch {
+	case p.Gateway == "":
+		res.Message = "Not configured (IPv4 only)"
+	case p.PingErr != nil && p.TCPErr != nil:
+		res.Reason = ReasonIPv6Blackholed
+		if p.IPv4 {
+			res.Status = StatusWarning
+			res.Message = "Broken dual-stack: IPv6 is configured but blackholed"
+			res.Fix = "Connections stall before falling back to IPv4. Fix IPv6 on the router, or turn IPv6 off on this interface."
+		} else {
+			res.Status = StatusError
+			res.Message = "IPv6 is configured but blackholed, and there is no IPv4 route"
+			res.Fix = "Restart the router or contact the ISP; this network depends on IPv6."
+		}
+	default:
+		res.Message = "Operational"
+		res.Latency = p.TCP
+		if p.PingErr == nil {
+			res.Latency = p.Ping
+		}
+		if p.AAAAErr != nil {
+			res.Status = StatusWarning
+			res.Reason = ReasonNoAAAA
+			res.Message = "Connectivity works but the resolver returns no AAAA records"
+			res.Fix = "The DNS server drops AAAA queries, so applications will not use IPv6."
+		}
+	}
+}
+
+func durationOrFail(d time.Duration, err error) string {
+	if err != nil {
+		return "FAIL"
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,33 @@
+package diagnostic
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGradeIPv6(t *testing.T) {
+	fail := errors.New("timeout")
+	aaaa := []string{"2607:f8b0:4004:800::200e"}
+	tests := []struct {
+		name    string
+		probe   ipv6Probe
+		status  Status
+		reason  Reason
+		latency time.Duration
+	}{
+		{"ipv4 only", ipv6Probe{IPv4: true, AAAA: aaaa}, StatusOk, "", 0},
+		{"healthy", ipv6Probe{Gateway: "fe80::1%en0", IPv4: true, Ping: 9 * time.Millisecond, TCP: 12 * time.Millisecond, AAAA: aaaa}, StatusOk, "", 9 * time.Millisecond},
+		{"icmpv6 filtered", ipv6Probe{Gateway: "fe80::1%en0", IPv4: true, PingErr: fail, TCP: 12 * time.Millisecond, AAAA: aaaa}, StatusOk, "", 12 * time.Millisecond},
+		{"broken dual-stack", ipv6Probe{Gateway: "fe80::1%en0", IPv4: true, PingErr: fail, TCPErr: fail, AAAA: aaaa}, StatusWarning, ReasonIPv6Blackholed, 0},
+		{"broken ipv6-only", ipv6Probe{Gateway: "fe80::1%en0", PingErr: fail, TCPErr: fail, AAAA: aaaa}, StatusError, ReasonIPv6Blackholed, 0},
+		{"no aaaa", ipv6Probe{Gateway: "fe80::1%en0", IPv4: true, Ping: 9 * time.Millisecond, AAAAErr: fail}, StatusWarning, ReasonNoAAAA, 9 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeIPv6(&res, tt.probe)
+		if res.Status != tt.status || res.Reason != tt.reason || res.Latency != tt.latency {
+			t.Errorf("%s: expected %v/%q/%v, got %v/%q/%v (%s)", tt.name, tt.status, tt.reason, tt.latency, res.Status, res.Reason, res.Latency, res.Message)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package diagnostic
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	reIPRouteDefault = regexp.MustCompile(`(?m)^default via (\S+) dev (\S+)`)
+	reIPNeighbor     = regexp.MustCompile(`(?m)^(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}) dev \S+ lladdr ([0-9a-fA-F]{2}(?::[0-9a-fA-F]{2}){5})`)
+	reIWConnected    = regexp.MustCompile(`Connected to ([0-9a-fA-F]{2}(?::[0-9a-fA-F]{2}){5})`)
+	reIWSignal       = regexp.MustCompile(`signal: (-?\d+) dBm`)
+	reIWBitrate      = regexp.MustCompile(`tx bitrate: (\d+(?:\.\d+)?) MBit/s(?:.*?\bMCS (\d+))?`)
+	reIPRouteDev     = regexp.MustCompile(`\bdev (\S+)`)
+)
+
+// linuxPingTimeout bounds each single ping with -W, in whole seconds.
+const linuxPingTimeout = "2"
+
+// linuxPlatform drives iproute2 (ip route, ip neigh), iputils ping and iw.
+type linuxPlatform struct{}
+
+func (linuxPlatform) routeCommand(inet6 bool, iface string) []string {
+	family := "-4"
+	if inet6 {
+		family = "-6"
+	}
+	cmd := []string{"ip", family, "route", "show", "default"}
+	if iface != "" {
+		cmd = append(cmd, "dev", iface)
+	}
+	return cmd
+}
+
+func (linuxPlatform) defaultRoutesCommand() []string {
+	return []string{"ip", "-4", "route", "show", "default"}
+}
+
+// parseDefaultRoutes reads `ip route show default`, ordered by metric since
+// the lowest one wins. Linux has no interface-scoped defaults, so Flags is
+// empty.
+func (linuxPlatform) parseDefaultRoutes(out string) []defaultRouteEntry {
+	type metricRoute struct {
+		defaultRouteEntry
+		metric int
+	}
+	var routes []metricRoute
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "default" {
+			continue
+		}
+		var r metricRoute
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "via":
+				r.Gateway = fields[i+1]
+			case "dev":
+				r.Iface = fields[i+1]
+			case "metric":
+				r.metric, _ = strconv.Atoi(fields[i+1])
+			}
+		}
+		if r.Iface != "" {
+			routes = append(routes, r)
+		}
+	}
+	slices.SortStableFunc(routes, func(a, b metricRoute) int { return cmp.Compare(a.metric, b.metric) })
+	entries := make([]defaultRouteEntry, len(routes))
+	for i, r := range routes {
+		entries[i] = r.defaultRouteEntry
+	}
+	return entries
+}
+
+func (linuxPlatform) parseInterface(out string) (string, error) {
+	if m := reIPRouteDefault.FindStringSubmatch(out); m != nil {
+		return m[2], nil
+	}
+	return "", fmt.Errorf("no primary interface found")
+}
+
+func (linuxPlatform) parseGateway(out string) (string, error) {
+	if m := reIPRouteDefault.FindStringSubmatch(out); m != nil && strings.Count(m[1], ".") == 3 {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("no gateway ip found")
+}
+
+// parseGateway6 appends the interface as the zone of a link-local gateway,
+// as macOS route prints it, so ping can use it.
+func (linuxPlatform) parseGateway6(out string) (string, error) {
+	m := reIPRouteDefault.FindStringSubmatch(out)
+	if m == nil || !strings.Contains(m[1], ":") {
+		return "", fmt.Errorf("no ipv6 gateway found")
+	}
+	if strings.HasPrefix(strings.ToLower(m[1]), "fe80:") {
+		return m[1] + "%" + m[2], nil
+	}
+	return m[1], nil
+}
+
+func (linuxPlatform) pingCommand(ip string, size int, interval time.Duration, ttl int) []string {
+	cmd := []string{"ping", "-c", "1", "-W", linuxPingTimeout}
+	if size > 0 {
+		cmd = append(cmd, "-s", strconv.Itoa(size))
+	}
+	if interval > 0 {
+		cmd = append(cmd, "-i", formatSeconds(interval))
+	}
+	if ttl > 0 {
+		cmd = append(cmd, "-t", strconv.Itoa(ttl))
+	}
+	return append(cmd, ip)
+}
+
+func (linuxPlatform) routeGetCommand(ip string) []string {
+	return []string{"ip", "route", "get", ip}
+}
+
+func (linuxPlatform) parseRouteInterface(out string) (string, error) {
+	if m := reIPRouteDev.FindStringSubmatch(out); m != nil {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("no interface in route")
+}
+
+func (linuxPlatform) pingViaCommand(ip, iface string) []string {
+	return []string{"ping", "-c", "1", "-W", linuxPingTimeout, "-I", iface, ip}
+}
+
+func (linuxPlatform) ping6Command(ip string) []string {
+	return []string{"ping", "-6", "-c", "1", "-W", linuxPingTimeout, ip}
+}
+
+func (linuxPlatform) statsPingCommand(ip string, ipv6 bool, count, size int, interval time.Duration) []string {
+	cmd := []string{"ping"}
+	if ipv6 {
+		cmd = append(cmd, "-6")
+	}
+	cmd = append(cmd, "-c", strconv.Itoa(count), "-i", formatSeconds(interval), "-W", linuxPingTimeout)
+	if size > 0 {
+		cmd = append(cmd, "-s", strconv.Itoa(size))
+	}
+	return append(cmd, ip)
+}
+
+func (linuxPlatform) dfPingCommand(ip string, size int) []string {
+	return []string{"ping", "-M", "do", "-c", "1", "-W", linuxPingTimeout, "-s", strconv.Itoa(size), ip}
+}
+
+func (linuxPlatform) setMTUCommand(iface string, mtu int) []string {
+	return []string{"ip", "link", "set", "dev", iface, "mtu", strconv.Itoa(mtu)}
+}
+
+func (linuxPlatform) openURLCommand(u string) []string { return []string{"xdg-open", u} }
+
+func (linuxPlatform) tracerouteCommand(target, mode string, maxTTL, probes int) []string {
+	cmd := tracerouteArgs(maxTTL, probes)
+	switch mode {
+	case TraceICMP:
+		cmd = append(cmd, "-I")
+	case TraceTCP:
+		cmd = append(cmd, "-T", "-p", tracePortTCP)
+	}
+	return append(cmd, target)
+}
+
+func (linuxPlatform) neighborCommand(ip string) []string {
+	return []string{"ip", "-4", "neigh", "show", ip}
+}
+
+func (linuxPlatform) neighborTableCommand() []string {
+	return []string{"ip", "-4", "neigh", "show"}
+}
+
+// parseNeighbors maps each IPv4 address in `ip neigh` output to its MACs.
+func (linuxPlatform) parseNeighbors(out string) map[string][]string {
+	table := make(map[string][]string)
+	for _, m := range reIPNeighbor.FindAllStringSubmatch(out, -1) {
+		table[m[1]] = appendUnique(table[m[1]], normalizeMAC(m[2]))
+	}
+	return table
+}
+
+func (linuxPlatform) wifiCommand(iface string) []string {
+	return []string{"iw", "dev", iface, "link"}
+}
+
+func (linuxPlatform) parseWiFi(out string, verbose bool) wifiLink {
+	return parseIWLink(out, verbose)
+}
+
+// securityCommand asks wpa_supplicant, since `iw dev link` does not show
+// the key management in use.
+func (linuxPlatform) securityCommand(iface string) []string {
+	return []string{"wpa_cli", "-i", iface, "status"}
+}
+
+func (linuxPlatform) parseSecurity(out string) (security, pmf string) {
+	return parseWPAStatus(out)
+}
+
+func (linuxPlatform) linkCommand(iface string) []string {
+	return []string{"ip", "link", "show", iface}
+}
+
+func (linuxPlatform) mediaCommand(iface string) []string { return []string{"ethtool", iface} }
+func (linuxPlatform) parseMedia(out string) (ethernetMedia, bool) {
+	return parseEthtool(out)
+}
+
+func (linuxPlatform) listenersCommand() []string           { return []string{"ss", "-Htulnp"} }
+func (linuxPlatform) parseListeners(out string) []listener { return parseSSListeners(out) }
+
+func (linuxPlatform) ephemeralPortsCommand() []string {
+	return []string{"cat", "/proc/sys/net/ipv4/ip_local_port_range"}
+}
+func (linuxPlatform) parseEphemeralPorts(out string) portRange {
+	if r, ok := parsePortRange(out); ok {
+		return r
+	}
+	return portRange{32768, 60999}
+}
+
+// talkersCommand is nil: Linux has no stock tool that counts traffic per
+// process.
+func (linuxPlatform) talkersCommand(time.Duration) []string       { return nil }
+func (linuxPlatform) parseTalkers(string, time.Duration) []talker { return nil }
+
+// proxyCommand is nil, since Linux has no system-wide proxy setting beyond
+// the http_proxy family of variables.
+func (linuxPlatform) proxyCommand() []string { return nil }
+
+func (linuxPlatform) parseProxies(out string) proxySettings { return parseProxyEnv(out) }
+
+// dnsConfigCommand asks systemd-resolved, which alone knows per-link and
+// split DNS; /etc/resolv.conf lists only its stub.
+func (linuxPlatform) dnsConfigCommand() []string { return []string{"resolvectl", "status"} }
+
+func (linuxPlatform) parseDNSConfig(out string) []dnsScope { return parseResolvectl(out) }
+
+// ntpServerCommand asks systemd-timesyncd; hosts running chrony or ntpd
+// fall back to ntpFallbackServer.
+func (linuxPlatform) ntpServerCommand() []string { return []string{"timedatectl", "show-timesync"} }
+
+func (linuxPlatform) parseNTPServer(out string) string { return parseTimesync(out) }
+
+// dhcpLeaseCommand asks NetworkManager, which, unlike systemd-networkd,
+// reports when the lease expires. Its device state tells an interface it
+// does not manage from one without a lease.
+func (linuxPlatform) dhcpLeaseCommand(iface string) []string {
+	return []string{"nmcli", "-t", "-f", "GENERAL.STATE,DHCP4", "device", "show", iface}
+}
+func (linuxPlatform) parseDHCPLease(out string) dhcpLease { return parseNMCLIDHCP(out) }
+
+// dhcpLeaseStartCommand is nil: NetworkManager reports the expiry itself.
+func (linuxPlatform) dhcpLeaseStartCommand(string) []string { return nil }
+func (linuxPlatform) parseDHCPLeaseStart(string) time.Time  { return time.Time{} }
+
+func (linuxPlatform) flushDNSCommands() [][]string { return [][]string{{"resolvectl", "flush-caches"}} }
+
+func (linuxPlatform) dhcpClientCommand() []string { return []string{"ps", "-eo", "args="} }
+
+func (linuxPlatform) parseDHCPClient(out, iface string) string { return parseDHCPClient(out, iface) }
+
+// renewDHCPCommands renews through NetworkManager or dhclient; leases held
+// by another client, such as systemd-networkd, are left alone.
+func (linuxPlatform) renewDHCPCommands(client, iface string) [][]string {
+	switch client {
+	case dhcpClientNetworkManager:
+		return [][]string{{"nmcli", "device", "connect", iface}}
+	case dhcpClientDhclient:
+		return [][]string{{"dhclient", "-r", iface}, {"dhclient", iface}}
+	}
+	return nil
+}
+
+// wifiPowerCommand toggles every Wi-Fi radio; NetworkManager has no
+// per-device switch.
+func (linuxPlatform) wifiPowerCommand(_ string, on bool) []string {
+	return []string{"nmcli", "radio", "wifi", onOff(on)}
+}
+
+// Linux orders default routes by metric rather than by a service order.
+func (linuxPlatform) serviceOrderCommand() []string            { return nil }
+func (linuxPlatform) setServiceOrderCommand([]string) []string { return nil }
+
+// systemd-resolved forgets servers set with resolvectl on revert, so the
+// previous ones need not be read.
+func (linuxPlatform) dnsServersCommand(iface string) []string {
+	return []string{"resolvectl", "dns", iface}
+}
+
+func (linuxPlatform) parseDNSServers(out string) []string { return parseResolvectlDNS(out) }
+
+func (linuxPlatform) setDNSServersCommand(iface string, servers []string) []string {
+	if len(servers) == 0 {
+		return []string{"resolvectl", "revert", iface}
+	}
+	return append([]string{"resolvectl", "dns", iface}, servers...)
+}
+
+func (linuxPlatform) tools() []string {
+	return []string{"ping", "ip", "iw"}
+}
+
+// parseWPAStatus reads key_mgmt and, where wpa_supplicant reports it, pmf
+// (0, 1 or 2) from `wpa_cli status`. key_mgmt NONE with a WEP group cipher
+// is WEP.
+func parseWPAStatus(out string) (security, pmf string) {
+	values := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[k] = v
+		}
+	}
+	security = values["key_mgmt"]
+	if security == "NONE" && strings.HasPrefix(values["group_cipher"], "WEP") {
+		security = "WEP"
+	}
+	switch values["pmf"] {
+	case "0":
+		pmf = "disabled"
+	case "1":
+		pmf = "optional"
+	case "2":
+		pmf = "required"
+	}
+	return security, pmf
+}
+
+// parseIWLink reads `iw dev <iface> link`, which prints "Not connected."
+// when the interface is not associated.
+func parseIWLink(output string, verbose bool) wifiLink {
+	link := wifiLink{MCS: -1}
+	if m := reIWConnected.FindStringSubmatch(output); m != nil {
+		link.BSSID = normalizeMAC(m[1])
+	}
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if ssid, ok := strings.CutPrefix(trimmed, "SSID: "); ok {
+			link.SSID = ssid
+		}
+		if m := reIWSignal.FindStringSubmatch(trimmed); m != nil {
+			link.RSSI, _ = strconv.Atoi(m[1])
+		}
+		if m := reIWBitrate.FindStringSubmatch(trimmed); m != nil {
+			link.TxRate, _ = strconv.ParseFloat(m[1], 64)
+			if m[2] != "" {
+				link.MCS, _ = strconv.Atoi(m[2])
+			}
+		}
+		if verbose && strings.Contains(trimmed, ": ") && !strings.HasPrefix(trimmed, "SSID") {
+			link.Details = append(link.Details, trimmed)
+		}
+	}
+	return link
+}
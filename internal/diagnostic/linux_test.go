@@ -0,0 +1,116 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinuxParseRoute(t *testing.T) {
+	p := linuxPlatform{}
+	out := "default via 192.168.1.1 dev wlan0 proto dhcp src 192.168.1.23 metric 600 \n"
+	if iface, err := p.parseInterface(out); err != nil || iface != "wlan0" {
+		t.Errorf("Expected wlan0, got %q (%v)", iface, err)
+	}
+	if gw, err := p.parseGateway(out); err != nil || gw != "192.168.1.1" {
+		t.Errorf("Expected 192.168.1.1, got %q (%v)", gw, err)
+	}
+	out6 := "default via fe80::1 dev wlan0 proto ra metric 600 pref medium\n"
+	if gw, err := p.parseGateway6(out6); err != nil || gw != "fe80::1%wlan0" {
+		t.Errorf("Expected fe80::1%%wlan0, got %q (%v)", gw, err)
+	}
+	if _, err := p.parseGateway(""); err == nil {
+		t.Error("Expected an error without a default route")
+	}
+}
+
+func TestLinuxParseDefaultRoutes(t *testing.T) {
+	out := `default via 192.168.1.1 dev wlan0 proto dhcp src 192.168.1.23 metric 600
+default via 192.168.1.1 dev eth0 proto dhcp src 192.168.1.24 metric 100
+default dev wg0 scope link
+`
+	var ifaces []string
+	for _, r := range (linuxPlatform{}).parseDefaultRoutes(out) {
+		ifaces = append(ifaces, r.Iface)
+	}
+	if got := strings.Join(ifaces, ","); got != "wg0,eth0,wlan0" {
+		t.Errorf("Expected routes by metric, got %q", got)
+	}
+}
+
+func TestLinuxParseNeighbors(t *testing.T) {
+	out := `192.168.1.1 dev wlan0 lladdr a4:83:e7:01:02:03 REACHABLE
+192.168.1.1 dev eth0 lladdr 00:11:22:33:44:55 STALE
+192.168.1.40 dev wlan0 FAILED
+`
+	table := linuxPlatform{}.parseNeighbors(out)
+	if got := strings.Join(table["192.168.1.1"], ","); got != "a4:83:e7:01:02:03,00:11:22:33:44:55" {
+		t.Errorf("Expected both MACs for the gateway, got %q", got)
+	}
+	if _, ok := table["192.168.1.40"]; ok {
+		t.Error("Expected an incomplete entry to be ignored")
+	}
+}
+
+func TestParseIWLink(t *testing.T) {
+	out := `Connected to A4:83:E7:01:02:03 (on wlan0)
+	SSID: MyHomeWiFi
+	freq: 5180
+	signal: -61 dBm
+	tx bitrate: 866.7 MBit/s VHT-MCS 9 80MHz short GI VHT-NSS 2
+`
+	link := parseIWLink(out, false)
+	if link.SSID != "MyHomeWiFi" || link.BSSID != "a4:83:e7:01:02:03" || link.RSSI != -61 || link.TxRate != 866.7 || link.MCS != 9 {
+		t.Errorf("Unexpected link %+v", link)
+	}
+	if link := parseIWLink("Not connected.\n", false); link.RSSI != 0 || link.SSID != "" {
+		t.Errorf("Expected no association, got %+v", link)
+	}
+}
+
+func TestLinuxPingCommand(t *testing.T) {
+	got := strings.Join(linuxPlatform{}.pingCommand("1.1.1.1", 1472, 0, 3), " ")
+	if want := "ping -c 1 -W 2 -s 1472 -t 3 1.1.1.1"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestTracerouteCommands(t *testing.T) {
+	tests := []struct {
+		plat platform
+		mode string
+		want string
+	}{
+		{darwinPlatform{}, TraceICMP, "traceroute -n -w 1 -q 3 -m 10 -I 1.1.1.1"},
+		{darwinPlatform{}, TraceTCP, "traceroute -n -w 1 -q 3 -m 10 -P tcp -p 443 1.1.1.1"},
+		{linuxPlatform{}, TraceTCP, "traceroute -n -w 1 -q 3 -m 10 -T -p 443 1.1.1.1"},
+		{linuxPlatform{}, TraceUDP, "traceroute -n -w 1 -q 3 -m 10 1.1.1.1"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.plat.tracerouteCommand("1.1.1.1", tt.mode, 10, 3), " "); got != tt.want {
+			t.Errorf("%T %s: expected %q, got %q", tt.plat, tt.mode, tt.want, got)
+		}
+	}
+}
+
+func TestLinuxParseRouteInterface(t *testing.T) {
+	got, err := linuxPlatform{}.parseRouteInterface("10.8.0.1 dev wg0 src 10.8.0.2 uid 501 \n    cache \n")
+	if err != nil || got != "wg0" {
+		t.Errorf("Expected wg0, got %q (%v)", got, err)
+	}
+}
+
+func TestParseWPAStatus(t *testing.T) {
+	tests := []struct {
+		out, security, pmf string
+	}{
+		{"bssid=a4:83:e7:01:02:03\nkey_mgmt=WPA2-PSK\npmf=0\nwpa_state=COMPLETED\n", "WPA2-PSK", "disabled"},
+		{"key_mgmt=SAE\npmf=2\n", "SAE", "required"},
+		{"key_mgmt=NONE\ngroup_cipher=WEP-104\n", "WEP", ""},
+		{"key_mgmt=NONE\n", "NONE", ""},
+	}
+	for _, tt := range tests {
+		if security, pmf := parseWPAStatus(tt.out); security != tt.security || pmf != tt.pmf {
+			t.Errorf("parseWPAStatus(%q): expected %q/%q, got %q/%q", tt.out, tt.security, tt.pmf, security, pmf)
+		}
+	}
+}
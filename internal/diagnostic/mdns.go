@@ -0,0 +1,198 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// mdnsServices asks every responder which service types it offers
+	// (RFC 6763 section 9).
+	mdnsServices = "_services._dns-sd._udp.local."
+	// mdnsWait is how long replies are collected; responders delay theirs
+	// by up to 120ms for shared records.
+	mdnsWait = time.Second
+)
+
+// mdnsAddr is where the query is sent; tests replace it.
+var mdnsAddr = "224.0.0.251:5353"
+
+// mdnsFriendly names the service types people know by their product.
+var mdnsFriendly = map[string]string{
+	"_ipp._tcp":             "AirPrint",
+	"_ipps._tcp":            "AirPrint",
+	"_printer._tcp":         "printer",
+	"_airplay._tcp":         "AirPlay",
+	"_raop._tcp":            "AirPlay audio",
+	"_googlecast._tcp":      "Chromecast",
+	"_spotify-connect._tcp": "Spotify Connect",
+	"_hap._tcp":             "HomeKit",
+	"_smb._tcp":             "file sharing",
+	"_companion-link._tcp":  "Apple device",
+}
+
+// mdnsReply is the service types one responder advertised.
+type mdnsReply struct {
+	From     netip.Addr
+	Services []string
+}
+
+// CheckMDNS sends a multicast DNS query for the service types on the LAN and
+// reports which devices answered; with verbose it lists their services.
+// Multicast blocked by the access point (client isolation, IGMP snooping
+// gone wrong) hides printers, AirPlay and Chromecast while the internet
+// works.
+func CheckMDNS(ctx context.Context, verbose bool) Result {
+	res := Result{Name: "Local Discovery (mDNS)", Emoji: "📻", Status: StatusOk}
+	replies, err := queryMDNS(ctx, mdnsWait)
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "mDNS query failed: " + err.Error()
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	// The host's own responder answers over the loopback of the multicast
+	// group; only other devices prove the LAN carries multicast.
+	own := ownAddrs()
+	replies = slices.DeleteFunc(replies, func(r mdnsReply) bool { return own[r.From] })
+	gradeMDNS(&res, replies)
+	if verbose {
+		var details []string
+		for _, r := range replies {
+			details = append(details, r.From.String()+": "+strings.Join(r.Services, ", "))
+		}
+		res.Details = formatDetailsWithPrefixes(details)
+	}
+	return res
+}
+
+// gradeMDNS sets the verdict of CheckMDNS from the other devices' replies.
+func gradeMDNS(res *Result, replies []mdnsReply) {
+	if len(replies) == 0 {
+		res.Status = StatusWarning
+		res.Reason = ReasonNoMDNS
+		res.Message = "No device answered mDNS: multicast is blocked, or nothing here advertises services"
+		res.Fix = "If printers, AirPlay or Chromecast devices are on this network, the router drops multicast: disable client/AP isolation, or enable mDNS (Bonjour) forwarding between its bands and VLANs."
+		return
+	}
+	var known []string
+	for _, r := range replies {
+		for _, s := range r.Services {
+			if name, ok := mdnsFriendly[s]; ok {
+				known = appendUnique(known, name)
+			}
+		}
+	}
+	noun := "devices"
+	if len(replies) == 1 {
+		noun = "device"
+	}
+	res.Message = fmt.Sprintf("%d %s answer mDNS", len(replies), noun)
+	if len(known) > 0 {
+		slices.Sort(known)
+		res.Message += " (" + strings.Join(known, ", ") + ")"
+	}
+}
+
+// queryMDNS sends one legacy unicast query (RFC 6762 section 6.7) from an
+// ephemeral port, so responders answer that port directly, and collects
+// the replies for wait.
+func queryMDNS(ctx context.Context, wait time.Duration) ([]mdnsReply, error) {
+	dst, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	query := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(mdnsServices),
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(packed, dst); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var replies []mdnsReply
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			// The deadline ends the collection.
+			break
+		}
+		services := parseMDNSServices(buf[:n])
+		if len(services) == 0 {
+			continue
+		}
+		addr := from.Addr().Unmap()
+		if i := slices.IndexFunc(replies, func(r mdnsReply) bool { return r.From == addr }); i >= 0 {
+			for _, s := range services {
+				replies[i].Services = appendUnique(replies[i].Services, s)
+			}
+			continue
+		}
+		replies = append(replies, mdnsReply{From: addr, Services: services})
+	}
+	slices.SortFunc(replies, func(a, b mdnsReply) int { return a.From.Compare(b.From) })
+	return replies, nil
+}
+
+// parseMDNSServices returns the service types, such as "_ipp._tcp", that
+// the PTR answers of an mDNS response list for mdnsServices.
+func parseMDNSServices(packet []byte) []string {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || !msg.Response {
+		return nil
+	}
+	var services []string
+	for _, rr := range slices.Concat(msg.Answers, msg.Additionals) {
+		ptr, ok := rr.Body.(*dnsmessage.PTRResource)
+		if !ok || !strings.EqualFold(rr.Header.Name.String(), mdnsServices) {
+			continue
+		}
+		s := strings.TrimSuffix(strings.TrimSuffix(ptr.PTR.String(), "."), ".local")
+		services = appendUnique(services, s)
+	}
+	slices.Sort(services)
+	return services
+}
+
+// ownAddrs returns the addresses of this machine's interfaces.
+func ownAddrs() map[netip.Addr]bool {
+	own := map[netip.Addr]bool{}
+	ifaces, err := localInterfaces()
+	if err != nil {
+		return own
+	}
+	for _, iface := range ifaces {
+		for _, a := range iface.Addrs {
+			own[a.Addr()] = true
+		}
+	}
+	return own
+}
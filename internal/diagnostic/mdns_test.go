@@ -0,0 +1,85 @@
+package diagnostic
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsAnswer packs a response listing services under mdnsServices.
+func mdnsAnswer(t *testing.T, services ...string) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+	for _, s := range services {
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(mdnsServices), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: 4500},
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(s + ".local.")},
+		})
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packed
+}
+
+func TestParseMDNSServices(t *testing.T) {
+	got := parseMDNSServices(mdnsAnswer(t, "_ipp._tcp", "_airplay._tcp", "_ipp._tcp"))
+	if strings.Join(got, ",") != "_airplay._tcp,_ipp._tcp" {
+		t.Errorf("Expected the two service types sorted, got %v", got)
+	}
+	if got := parseMDNSServices([]byte("junk")); got != nil {
+		t.Errorf("Expected nothing from a malformed packet, got %v", got)
+	}
+}
+
+func TestGradeMDNS(t *testing.T) {
+	res := Result{Status: StatusOk}
+	gradeMDNS(&res, nil)
+	if res.Status != StatusWarning || res.Reason != ReasonNoMDNS {
+		t.Errorf("Expected a no_mdns warning without replies, got %v/%q", res.Status, res.Reason)
+	}
+
+	res = Result{Status: StatusOk}
+	gradeMDNS(&res, []mdnsReply{{Services: []string{"_googlecast._tcp"}}, {Services: []string{"_ipp._tcp", "_ipps._tcp"}}})
+	if res.Status != StatusOk || res.Message != "2 devices answer mDNS (AirPrint, Chromecast)" {
+		t.Errorf("Unexpected verdict %v (%s)", res.Status, res.Message)
+	}
+}
+
+func TestQueryMDNS(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip("UDP unavailable:", err)
+	}
+	defer func() { _ = conn.Close() }()
+	answer := mdnsAnswer(t, "_raop._tcp")
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var q dnsmessage.Message
+		if q.Unpack(buf[:n]) != nil || len(q.Questions) != 1 || q.Questions[0].Name.String() != mdnsServices {
+			return
+		}
+		_, _ = conn.WriteToUDP(answer, from)
+	}()
+
+	prev := mdnsAddr
+	mdnsAddr = conn.LocalAddr().String()
+	t.Cleanup(func() { mdnsAddr = prev })
+
+	replies, err := queryMDNS(context.Background(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0].From.String() != "127.0.0.1" || strings.Join(replies[0].Services, ",") != "_raop._tcp" {
+		t.Errorf("Expected one reply from the fake responder, got %+v", replies)
+	}
+}
@@ -0,0 +1,200 @@
+package diagnostic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+const (
+	// natTraceDepth is how many hops CheckDoubleNAT inspects.
+	natTraceDepth = 4
+	// natPMPPort is where routers answer NAT-PMP (RFC 6886) requests.
+	natPMPPort = 5351
+	// natPMPTimeout bounds the wait for a router that does not speak
+	// NAT-PMP, which simply never answers.
+	natPMPTimeout = time.Second
+)
+
+// natEvidence is what CheckDoubleNAT learned about the address layers
+// between this machine and the internet.
+type natEvidence struct {
+	// Hops are the first routers toward the WAN host, "" where they timed out.
+	Hops []string
+	// RouterWAN is the gateway's external address from NAT-PMP; invalid
+	// when the gateway did not say.
+	RouterWAN netip.Addr
+	// Public is the address STUN saw the traffic come from; invalid when
+	// STUN failed.
+	Public netip.Addr
+	// Tunnel is set when the default route goes through a VPN, so the hops
+	// and the public address belong to the tunnel, not the local network.
+	Tunnel bool
+	// Proxy is set when a proxy is configured, whose address the public
+	// one may be.
+	Proxy bool
+}
+
+// CheckDoubleNAT flags a second private router in front of the first public
+// hop, or carrier-grade NAT beyond the router. It compares the first hops
+// toward the WAN host, the router's own WAN address (NAT-PMP) and the
+// public address seen by a STUN server: a private or shared (100.64.0.0/10)
+// WAN address, or one other than the public address, means another NAT
+// sits upstream. Behind a VPN or proxy only the router's address counts.
+func CheckDoubleNAT(ctx context.Context) Result {
+	res := Result{Name: "Double NAT", Emoji: "🔁", Status: StatusOk}
+	if r, ok := missingToolResult(res.Name, res.Emoji, activeRunner().LookPath("ping")); ok {
+		return r
+	}
+
+	ev := natEvidence{Hops: traceHops(ctx, activeConfig(ctx).WANHost, natTraceDepth)}
+	var details []string
+	for i, hop := range ev.Hops {
+		switch {
+		case hop == "":
+			details = append(details, fmt.Sprintf("Hop %d: *", i+1))
+		case isPrivate(hop):
+			details = append(details, fmt.Sprintf("Hop %d: %s (private)", i+1, hop))
+		default:
+			details = append(details, fmt.Sprintf("Hop %d: %s (public)", i+1, hop))
+		}
+	}
+	if routeInfo, err := defaultRoute.get(ctx); err == nil {
+		if iface, err := activePlatform().parseInterface(routeInfo); err == nil && isTunnelName(iface) {
+			ev.Tunnel = true
+			details = append(details, "Default route: "+iface+" (VPN)")
+		}
+		if gw, err := activePlatform().parseGateway(routeInfo); err == nil {
+			if ev.RouterWAN, err = natPMPExternalAddress(ctx, gw); err == nil {
+				details = append(details, "Router WAN address (NAT-PMP): "+ev.RouterWAN.String())
+			}
+		}
+	}
+	if public, err := stunPublicIP(ctx); err == nil {
+		ev.Public = public
+		details = append(details, "Public IP (STUN): "+public.String())
+	}
+	if proxies, err := readProxySettings(ctx); err == nil && (len(proxies.Proxies) > 0 || proxies.PACURL != "") {
+		ev.Proxy = true
+		details = append(details, "Proxy configured")
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeDoubleNAT(&res, ev)
+	return res
+}
+
+// gradeDoubleNAT sets the verdict of CheckDoubleNAT from ev.
+func gradeDoubleNAT(res *Result, ev natEvidence) {
+	hops := ev.Hops
+	if ev.Tunnel {
+		hops = nil
+	}
+	private, double := classifyNATHops(hops)
+	cgnat := ev.RouterWAN.IsValid() && cgnatPrefix.Contains(ev.RouterWAN)
+	for _, hop := range hops {
+		if addr, err := netip.ParseAddr(hop); err == nil && cgnatPrefix.Contains(addr) {
+			cgnat = true
+		}
+	}
+	const doubleFix = "Port forwarding, UPnP, and peer-to-peer games and calls break across two NATs. Put one of the routers (usually the ISP modem) into bridge mode."
+	switch {
+	case cgnat:
+		res.Status = StatusWarning
+		res.Reason = ReasonCGNAT
+		res.Message = "Carrier-grade NAT: the ISP shares your public address with other customers"
+		res.Fix = "Inbound connections, port forwarding and hosting cannot work, and games and calls need relays. Ask the ISP for a public IPv4 address, or use IPv6."
+	case double:
+		res.Status = StatusWarning
+		res.Reason = ReasonDoubleNAT
+		res.Message = "Likely double NAT (" + strings.Join(private, " → ") + ")"
+		res.Fix = doubleFix
+	case ev.RouterWAN.IsValid() && ev.RouterWAN.IsPrivate():
+		res.Status = StatusWarning
+		res.Reason = ReasonDoubleNAT
+		res.Message = "Double NAT: the router's WAN address " + ev.RouterWAN.String() + " is private"
+		res.Fix = doubleFix
+	case ev.RouterWAN.IsValid() && ev.Public.IsValid() && ev.RouterWAN != ev.Public && !ev.Tunnel && !ev.Proxy:
+		res.Status = StatusWarning
+		res.Reason = ReasonDoubleNAT
+		res.Message = fmt.Sprintf("Another NAT beyond the router: its WAN address %s is not the public IP %s", ev.RouterWAN, ev.Public)
+		res.Fix = "The ISP (or a VPN on the router) translates addresses again, so port forwarding on your router cannot reach you from the internet. Ask the ISP for a public IPv4 address."
+	case ev.Tunnel:
+		res.Message = "Traffic goes through a VPN; only the router's own address was checked"
+	case len(private) == 0:
+		res.Message = "Could not identify a private gateway hop"
+	default:
+		res.Message = "Single NAT layer"
+	}
+}
+
+// natPMPExternalAddress asks gateway for its external address with
+// NAT-PMP, which Apple routers and many UPnP-capable ones answer.
+func natPMPExternalAddress(ctx context.Context, gateway string) (netip.Addr, error) {
+	gw, err := netip.ParseAddr(gateway)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp4", netip.AddrPortFrom(gw, natPMPPort).String())
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(min(natPMPTimeout, activeConfig(ctx).Timeout))); err != nil {
+		return netip.Addr{}, err
+	}
+	// Version 0, opcode 0: external address request.
+	if _, err := conn.Write([]byte{0, 0}); err != nil {
+		return netip.Addr{}, err
+	}
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return parseNATPMPResponse(buf[:n])
+}
+
+// parseNATPMPResponse reads the external address from a NAT-PMP reply:
+// version 0, opcode 128, a result code, the epoch and the address.
+func parseNATPMPResponse(b []byte) (netip.Addr, error) {
+	if len(b) < 12 || b[0] != 0 || b[1] != 128 {
+		return netip.Addr{}, errors.New("not a NAT-PMP external address response")
+	}
+	if code := binary.BigEndian.Uint16(b[2:4]); code != 0 {
+		return netip.Addr{}, fmt.Errorf("NAT-PMP result code %d", code)
+	}
+	return netip.AddrFrom4([4]byte(b[8:12])), nil
+}
+
+// classifyNATHops returns the private addresses seen before the first public hop,
+// and whether there are at least two of them starting at the first hop.
+// Timed-out hops are skipped since they reveal nothing about address space.
+func classifyNATHops(hops []string) ([]string, bool) {
+	var private []string
+	for i, hop := range hops {
+		if hop == "" {
+			continue
+		}
+		if !isPrivate(hop) {
+			break
+		}
+		if len(private) == 0 && i != 0 {
+			// The gateway itself did not answer; we cannot tell where NAT begins.
+			return nil, false
+		}
+		private = appendUnique(private, hop)
+	}
+	return private, len(private) >= 2
+}
+
+// isPrivate reports whether ip is an RFC 1918 (or IPv6 ULA) address.
+func isPrivate(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.IsPrivate()
+}
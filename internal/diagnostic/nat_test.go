@@ -0,0 +1,103 @@
+package diagnostic
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPrivate(t *testing.T) {
+	tests := map[string]bool{
+		"10.0.0.1":      true,
+		"172.16.5.4":    true,
+		"172.32.0.1":    false,
+		"192.168.1.1":   true,
+		"100.64.0.1":    false,
+		"1.1.1.1":       false,
+		"fd00::1":       true,
+		"not-an-ip":     false,
+		"203.0.113.254": false,
+	}
+	for ip, want := range tests {
+		if got := isPrivate(ip); got != want {
+			t.Errorf("isPrivate(%s): expected %v, got %v", ip, want, got)
+		}
+	}
+}
+
+func TestClassifyNATHops(t *testing.T) {
+	tests := []struct {
+		name   string
+		hops   []string
+		double bool
+	}{
+		{"single", []string{"192.168.1.1", "203.0.113.1", "1.1.1.1"}, false},
+		{"double", []string{"192.168.1.1", "192.168.0.1", "203.0.113.1"}, true},
+		{"double with silent hop", []string{"192.168.1.1", "", "10.0.0.1", "203.0.113.1"}, true},
+		{"gateway silent", []string{"", "192.168.0.1", "10.0.0.1"}, false},
+		{"repeated gateway", []string{"192.168.1.1", "192.168.1.1", "203.0.113.1"}, false},
+		{"no data", []string{"", "", ""}, false},
+	}
+	for _, tt := range tests {
+		if _, got := classifyNATHops(tt.hops); got != tt.double {
+			t.Errorf("%s: expected double=%v, got %v", tt.name, tt.double, got)
+		}
+	}
+}
+
+func TestCheckDoubleNATReason(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"ping -c 1 -t 1 1.1.1.1": "92 bytes from 192.168.1.1: Time to live exceeded\n",
+		"ping -c 1 -t 2 1.1.1.1": "92 bytes from 10.0.0.1: Time to live exceeded\n",
+		"ping -c 1 -t 3 1.1.1.1": "92 bytes from 203.0.113.1: Time to live exceeded\n",
+	}})
+	withSTUNServers(t)
+
+	res := CheckDoubleNAT(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonDoubleNAT {
+		t.Errorf("Expected a double_nat warning, got %v/%s", res.Status, res.Reason)
+	}
+}
+
+func TestGradeDoubleNAT(t *testing.T) {
+	addr := netip.MustParseAddr
+	tests := []struct {
+		name string
+		ev   natEvidence
+		want Reason
+	}{
+		{"single", natEvidence{Hops: []string{"192.168.1.1", "203.0.113.1"}, RouterWAN: addr("203.0.113.7"), Public: addr("203.0.113.7")}, Reason("")},
+		{"private hops", natEvidence{Hops: []string{"192.168.1.1", "10.0.0.1", "203.0.113.1"}}, ReasonDoubleNAT},
+		{"private wan address", natEvidence{Hops: []string{"192.168.1.1", "*"}, RouterWAN: addr("192.168.0.23")}, ReasonDoubleNAT},
+		{"cgnat wan address", natEvidence{Hops: []string{"192.168.1.1"}, RouterWAN: addr("100.72.1.9"), Public: addr("203.0.113.7")}, ReasonCGNAT},
+		{"cgnat hop", natEvidence{Hops: []string{"192.168.1.1", "100.64.0.1"}}, ReasonCGNAT},
+		{"nat beyond the router", natEvidence{Hops: []string{"192.168.1.1"}, RouterWAN: addr("198.51.100.4"), Public: addr("203.0.113.7")}, ReasonDoubleNAT},
+		{"no stun", natEvidence{Hops: []string{"192.168.1.1"}, RouterWAN: addr("198.51.100.4")}, Reason("")},
+		{"vpn exit", natEvidence{Hops: []string{"10.8.0.1", "10.20.0.1"}, RouterWAN: addr("198.51.100.4"), Public: addr("203.0.113.7"), Tunnel: true}, Reason("")},
+		{"proxy exit", natEvidence{Hops: []string{"192.168.1.1"}, RouterWAN: addr("198.51.100.4"), Public: addr("203.0.113.7"), Proxy: true}, Reason("")},
+		{"private wan address under a vpn", natEvidence{Hops: []string{"10.8.0.1"}, RouterWAN: addr("192.168.0.23"), Tunnel: true}, ReasonDoubleNAT},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeDoubleNAT(&res, tt.ev)
+		if res.Reason != tt.want {
+			t.Errorf("%s: expected %q, got %q (%s)", tt.name, tt.want, res.Reason, res.Message)
+		}
+		if tt.want != Reason("") && (res.Status != StatusWarning || res.Fix == "") {
+			t.Errorf("%s: expected a warning with a fix, got %v", tt.name, res.Status)
+		}
+	}
+}
+
+func TestParseNATPMPResponse(t *testing.T) {
+	got, err := parseNATPMPResponse([]byte{0, 128, 0, 0, 0, 0, 0x1c, 0x20, 203, 0, 113, 7})
+	if err != nil || got != netip.MustParseAddr("203.0.113.7") {
+		t.Errorf("expected 203.0.113.7, got %v (%v)", got, err)
+	}
+	if _, err := parseNATPMPResponse([]byte{0, 128, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0}); err == nil {
+		t.Error("expected an error for a failed result code")
+	}
+	if _, err := parseNATPMPResponse([]byte{0, 129}); err == nil {
+		t.Error("expected an error for a short reply")
+	}
+}
@@ -0,0 +1,55 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+)
+
+// Network identifies the network this machine is on by its default route,
+// cheaply enough to poll between runs: a laptop moving from the office to
+// home changes the interface or the gateway.
+type Network struct {
+	Interface  string
+	Gateway    string
+	GatewayMAC string
+}
+
+// String describes n as "en0 via 192.168.1.1", or "offline" without a
+// default route.
+func (n Network) String() string {
+	if n.Gateway == "" {
+		return "offline"
+	}
+	return fmt.Sprintf("%s via %s", n.Interface, n.Gateway)
+}
+
+// Same reports whether n and o are the same network: the same interface and
+// gateway. The gateway's MAC is left out, since a MAC that changes behind
+// the same address is what ARP spoofing looks like, not a move, and an ARP
+// entry can briefly go missing.
+func (n Network) Same(o Network) bool {
+	return n.Interface == o.Interface && n.Gateway == o.Gateway
+}
+
+// CurrentNetwork looks up the default route afresh, bypassing the cache the
+// checks share, and the gateway's ARP entry. Without a default route it
+// returns the zero Network.
+func CurrentNetwork(ctx context.Context) Network {
+	p := activePlatform()
+	out, err := runPlatformCommand(ctx, p.routeCommand(false, activeConfig(ctx).Interface))
+	if err != nil {
+		return Network{}
+	}
+	gw, err := p.parseGateway(string(out))
+	if err != nil {
+		return Network{}
+	}
+	n := Network{Gateway: gw}
+	n.Interface, _ = p.parseRouteInterface(string(out))
+	if out, err := runPlatformCommand(ctx, p.neighborCommand(gw)); err == nil {
+		if macs := p.parseNeighbors(string(out))[gw]; len(macs) > 0 {
+			n.GatewayMAC = macs[0]
+		}
+	}
+	return n
+}
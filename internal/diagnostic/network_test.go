@@ -0,0 +1,31 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCurrentNetwork(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "   route to: default\n    gateway: 192.168.1.1\n  interface: en0\n",
+		"arp -n 192.168.1.1":   "? (192.168.1.1) at 0:1a:2b:3c:4d:5e on en0 ifscope [ethernet]\n",
+	}})
+	n := CurrentNetwork(context.Background())
+	if n.Interface != "en0" || n.Gateway != "192.168.1.1" || n.GatewayMAC != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("Unexpected network %+v", n)
+	}
+	if n.String() != "en0 via 192.168.1.1" {
+		t.Errorf("Unexpected description %q", n.String())
+	}
+
+	spoofed := n
+	spoofed.GatewayMAC = "66:77:88:99:aa:bb"
+	if !n.Same(spoofed) || n.Same(Network{Interface: "en7", Gateway: n.Gateway}) {
+		t.Errorf("Expected only the interface and gateway to tell networks apart")
+	}
+
+	withRunner(t, &fakeRunner{})
+	if n := CurrentNetwork(context.Background()); n != (Network{}) || n.String() != "offline" {
+		t.Errorf("Expected no network without a default route, got %+v", n)
+	}
+}
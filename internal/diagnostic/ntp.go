@@ -0,0 +1,235 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// ntpFallbackServer is queried when the system's NTP server is unknown
+	// or does not answer, so the clock offset is still measured.
+	ntpFallbackServer = "pool.ntp.org"
+	// ntpEpoch is the NTP era 0 epoch (1900-01-01) in Unix seconds.
+	ntpEpoch = -2208988800
+	// clockSkewCritical is the offset beyond which Kerberos and most SSO
+	// providers reject tickets and assertions.
+	clockSkewCritical = 5 * time.Minute
+)
+
+// ntpSample is the outcome of one SNTP query (RFC 4330).
+type ntpSample struct {
+	Server string
+	// Offset is how far the local clock is ahead of the server's
+	// (negative when behind).
+	Offset  time.Duration
+	RTT     time.Duration
+	Stratum int
+	Err     error
+}
+
+// CheckTimeSync queries the NTP server the system synchronizes with
+// (systemsetup on macOS, timedatectl on Linux) or Config.NTPServer, and
+// reports the clock offset and whether the server is reachable. A skewed
+// clock breaks TLS certificate validation, SSO and one-time codes, and
+// users never suspect it.
+func CheckTimeSync(ctx context.Context) Result {
+	res := Result{Name: "Time Sync", Emoji: "⏰", Status: StatusOk}
+	server, source := activeConfig(ctx).NTPServer, "configured"
+	if server == "" {
+		server, source = systemNTPServer(ctx), "system"
+	}
+	if server == "" {
+		server, source = ntpFallbackServer, "fallback"
+	}
+	samples := []ntpSample{queryNTP(ctx, server)}
+	if samples[0].Err != nil && server != ntpFallbackServer {
+		samples = append(samples, queryNTP(ctx, ntpFallbackServer))
+	}
+
+	details := []string{"NTP server (" + source + "): " + server}
+	for _, s := range samples {
+		details = append(details, s.Server+": "+describeNTPSample(s))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeTimeSync(&res, samples, activeConfig(ctx).MaxClockSkew)
+	return res
+}
+
+// systemNTPServer returns the NTP server the system is configured with, or
+// "" when it cannot be read.
+func systemNTPServer(ctx context.Context) string {
+	p := activePlatform()
+	out, err := runPlatformCommand(ctx, p.ntpServerCommand())
+	if err != nil {
+		return ""
+	}
+	return p.parseNTPServer(string(out))
+}
+
+// gradeTimeSync sets the verdict of CheckTimeSync. samples holds the query
+// to the chosen server, followed by one to ntpFallbackServer when it failed.
+func gradeTimeSync(res *Result, samples []ntpSample, maxSkew time.Duration) {
+	first := samples[0]
+	var ref *ntpSample
+	for i := range samples {
+		if samples[i].Err == nil {
+			ref = &samples[i]
+			break
+		}
+	}
+	if ref == nil {
+		res.Status = StatusWarning
+		res.Reason = ReasonNTPUnreachable
+		res.Message = "NTP server " + first.Server + " unreachable; clock offset unknown"
+		res.Fix = "The clock cannot synchronize and will drift: allow outbound UDP/123, or set a reachable time server."
+		return
+	}
+	res.Latency = ref.RTT
+	skew := ref.Offset.Abs()
+	direction := "ahead"
+	if ref.Offset < 0 {
+		direction = "behind"
+	}
+	switch {
+	case skew >= clockSkewCritical:
+		res.Status = StatusError
+		res.Reason = ReasonClockSkew
+		res.Message = fmt.Sprintf("Clock is %s %s %s", formatSkew(skew), direction, ref.Server)
+		res.Fix = "TLS certificates, SSO logins and one-time codes fail with a clock this far off: enable automatic date & time, or set the clock by hand."
+	case skew > maxSkew:
+		res.Status = StatusWarning
+		res.Reason = ReasonClockSkew
+		res.Message = fmt.Sprintf("Clock is %s %s %s", formatSkew(skew), direction, ref.Server)
+		res.Fix = "Enable automatic date & time so the clock synchronizes; one-time codes and some logins already fail at this offset."
+	case first.Err != nil:
+		res.Status = StatusWarning
+		res.Reason = ReasonNTPUnreachable
+		res.Message = fmt.Sprintf("NTP server %s unreachable; clock within %s of %s", first.Server, formatSkew(skew), ref.Server)
+		res.Fix = "The clock cannot synchronize and will drift: allow outbound UDP/123, or set a reachable time server."
+	default:
+		res.Message = fmt.Sprintf("Clock within %s of %s (stratum %d)", formatSkew(skew), ref.Server, ref.Stratum)
+	}
+}
+
+// queryNTP sends one SNTP request to server ("host" or "host:port") and
+// computes the clock offset and round trip from its timestamps.
+func queryNTP(ctx context.Context, server string) ntpSample {
+	s := ntpSample{Server: server}
+	addr := server
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		s.Server = host
+	} else {
+		addr = net.JoinHostPort(server, "123")
+	}
+	d := net.Dialer{Timeout: activeConfig(ctx).Timeout}
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(activeConfig(ctx).Timeout)); err != nil {
+		s.Err = err
+		return s
+	}
+
+	// LI 0, version 4, mode 3 (client). The transmit timestamp is random,
+	// as RFC 9109 recommends, and must come back as the origin timestamp.
+	req := make([]byte, 48)
+	req[0] = 4<<3 | 3
+	_, _ = rand.Read(req[40:48])
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		s.Err = err
+		return s
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	switch {
+	case err != nil:
+		s.Err = err
+		return s
+	case n < 48:
+		s.Err = errors.New("short NTP response")
+		return s
+	case resp[0]&7 != 4 || string(resp[24:32]) != string(req[40:48]):
+		s.Err = errors.New("unexpected NTP response")
+		return s
+	case resp[1] == 0:
+		s.Err = fmt.Errorf("NTP server refused: %s", strings.TrimRight(string(resp[12:16]), "\x00"))
+		return s
+	}
+	t2 := ntpTime(resp[32:40])
+	t3 := ntpTime(resp[40:48])
+	s.Stratum = int(resp[1])
+	// The server's clock is t2-t1 ahead on the way out and t3-t4 on the
+	// way back, each skewed by half the network delay in opposite ways.
+	s.Offset = -(t2.Sub(t1) + t3.Sub(t4)) / 2
+	s.RTT = max(t4.Sub(t1)-t3.Sub(t2), 0)
+	return s
+}
+
+// ntpTime decodes a 64-bit NTP timestamp in era 0.
+func ntpTime(b []byte) time.Time {
+	sec := int64(binary.BigEndian.Uint32(b[:4]))
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(sec+ntpEpoch, frac*int64(time.Second)>>32)
+}
+
+// formatSkew rounds an offset to the precision that matters for its size.
+func formatSkew(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Hour:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Minute).String()
+	}
+}
+
+func describeNTPSample(s ntpSample) string {
+	if s.Err != nil {
+		var netErr net.Error
+		if errors.As(s.Err, &netErr) && netErr.Timeout() {
+			return "no response"
+		}
+		return s.Err.Error()
+	}
+	return fmt.Sprintf("offset %+.3fs, round trip %s, stratum %d", s.Offset.Seconds(), s.RTT.Round(time.Millisecond), s.Stratum)
+}
+
+// parseSystemsetupNTP reads `systemsetup -getnetworktimeserver`, which
+// prints "Network Time Server: time.apple.com".
+func parseSystemsetupNTP(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Network Time Server:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// parseTimesync reads `timedatectl show-timesync`: the server
+// systemd-timesyncd is using, else the first configured or fallback one.
+func parseTimesync(out string) string {
+	props := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[k] = v
+		}
+	}
+	for _, key := range []string{"ServerName", "SystemNTPServers", "LinkNTPServers", "FallbackNTPServers"} {
+		if f := strings.Fields(props[key]); len(f) > 0 {
+			return f[0]
+		}
+	}
+	return ""
+}
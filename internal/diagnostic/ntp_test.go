@@ -0,0 +1,99 @@
+package diagnostic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseNTPServer(t *testing.T) {
+	if got := parseSystemsetupNTP("Network Time Server: time.apple.com\n"); got != "time.apple.com" {
+		t.Errorf("systemsetup: expected time.apple.com, got %q", got)
+	}
+	if got := parseSystemsetupNTP("You need administrator access to run this tool... exiting!\n"); got != "" {
+		t.Errorf("systemsetup without admin: expected none, got %q", got)
+	}
+	tests := map[string]string{
+		"SystemNTPServers=\nFallbackNTPServers=ntp.ubuntu.com\nServerName=ntp.ubuntu.com\nServerAddress=185.125.190.56\n": "ntp.ubuntu.com",
+		"SystemNTPServers=time.corp.example\nFallbackNTPServers=ntp.ubuntu.com\nServerName=\n":                            "time.corp.example",
+		"SystemNTPServers=\nFallbackNTPServers=0.debian.pool.ntp.org 1.debian.pool.ntp.org\n":                             "0.debian.pool.ntp.org",
+		"": "",
+	}
+	for out, want := range tests {
+		if got := parseTimesync(out); got != want {
+			t.Errorf("parseTimesync(%q): expected %q, got %q", out, want, got)
+		}
+	}
+}
+
+func TestGradeTimeSync(t *testing.T) {
+	down := ntpSample{Server: "time.corp.example", Err: errors.New("timeout")}
+	synced := ntpSample{Server: "time.apple.com", Offset: 12 * time.Millisecond, Stratum: 1}
+	tests := []struct {
+		name    string
+		samples []ntpSample
+		status  Status
+		reason  Reason
+	}{
+		{"synced", []ntpSample{synced}, StatusOk, ""},
+		{"drifted", []ntpSample{{Server: "time.apple.com", Offset: -40 * time.Second}}, StatusWarning, ReasonClockSkew},
+		{"hours off", []ntpSample{{Server: "time.apple.com", Offset: 3 * time.Hour}}, StatusError, ReasonClockSkew},
+		{"server down", []ntpSample{down, synced}, StatusWarning, ReasonNTPUnreachable},
+		{"udp/123 blocked", []ntpSample{down, {Server: ntpFallbackServer, Err: errors.New("timeout")}}, StatusWarning, ReasonNTPUnreachable},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeTimeSync(&res, tt.samples, 5*time.Second)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+// serveFakeNTP answers one SNTP request from a clock ahead by offset.
+func serveFakeNTP(t *testing.T, offset time.Duration) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback UDP: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		req := make([]byte, 48)
+		_, from, err := conn.ReadFrom(req)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 4<<3 | 4
+		resp[1] = 2
+		copy(resp[24:32], req[40:48])
+		now := time.Now().Add(offset)
+		putNTPTime(resp[32:40], now)
+		putNTPTime(resp[40:48], now)
+		_, _ = conn.WriteTo(resp, from)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func putNTPTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()-ntpEpoch))
+	binary.BigEndian.PutUint32(b[4:], uint32(int64(t.Nanosecond())<<32/int64(time.Second)))
+}
+
+func TestQueryNTP(t *testing.T) {
+	addr := serveFakeNTP(t, time.Hour)
+	s := queryNTP(context.Background(), addr)
+	if s.Err != nil {
+		t.Fatalf("Expected an answer, got %v", s.Err)
+	}
+	if s.Server != "127.0.0.1" || s.Stratum != 2 {
+		t.Errorf("Expected server 127.0.0.1 at stratum 2, got %q at %d", s.Server, s.Stratum)
+	}
+	if d := s.Offset + time.Hour; d.Abs() > time.Second {
+		t.Errorf("Expected the local clock an hour behind, got offset %v", s.Offset)
+	}
+}
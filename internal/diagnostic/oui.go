@@ -0,0 +1,93 @@
+package diagnostic
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ouiFiles are the vendor databases looked for, in order: the IEEE registry
+// as Debian's ieee-data and Fedora's hwdata ship it, and Wireshark's manuf
+// file, which Homebrew and the macOS app bundle carry.
+var ouiFiles = []string{
+	"/usr/share/ieee-data/oui.txt",
+	"/usr/share/hwdata/oui.txt",
+	"/usr/share/misc/oui.txt",
+	"/usr/share/wireshark/manuf",
+	"/opt/homebrew/share/wireshark/manuf",
+	"/usr/local/share/wireshark/manuf",
+	"/Applications/Wireshark.app/Contents/Resources/share/wireshark/manuf",
+}
+
+var (
+	// reIEEEOUI matches "A4-83-E7   (hex)		Apple, Inc." in oui.txt.
+	reIEEEOUI = regexp.MustCompile(`^([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})-([0-9A-Fa-f]{2})\s+\(hex\)\s+(.+)$`)
+	// reManufOUI matches "A4:83:E7	Apple	Apple, Inc." in manuf; longer
+	// prefixes such as "00:1B:C5:00:00/36" are skipped.
+	reManufOUI = regexp.MustCompile(`^([0-9A-Fa-f]{2}):([0-9A-Fa-f]{2}):([0-9A-Fa-f]{2})\s+(\S+)(?:\s+(.+))?$`)
+)
+
+// parseOUIs reads an oui.txt or manuf file into a map from lowercase
+// "a4:83:e7" prefixes to vendor names.
+func parseOUIs(r io.Reader) map[string]string {
+	vendors := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		m := reIEEEOUI.FindStringSubmatch(line)
+		name := ""
+		if m != nil {
+			name = m[4]
+		} else if m = reManufOUI.FindStringSubmatch(line); m != nil {
+			name = m[4]
+			if m[5] != "" {
+				name = m[5]
+			}
+		}
+		if m != nil {
+			vendors[strings.ToLower(m[1]+":"+m[2]+":"+m[3])] = strings.TrimSpace(name)
+		}
+	}
+	return vendors
+}
+
+// ouiVendors loads the first vendor database found, once; it is empty when
+// none is installed.
+var ouiVendors = sync.OnceValue(func() map[string]string {
+	for _, path := range ouiFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		vendors := parseOUIs(f)
+		_ = f.Close()
+		if len(vendors) > 0 {
+			return vendors
+		}
+	}
+	return map[string]string{}
+})
+
+// macVendor names the manufacturer of mac from vendors, or returns "" when
+// the prefix is unknown or mac is locally administered.
+func macVendor(vendors map[string]string, mac string) string {
+	if len(mac) < 8 || locallyAdministered(mac) {
+		return ""
+	}
+	return vendors[strings.ToLower(mac[:8])]
+}
+
+// describeMAC returns mac followed by its vendor, or by a note that it is
+// locally administered, for messages.
+func describeMAC(mac string) string {
+	if locallyAdministered(mac) {
+		return mac + " (locally administered)"
+	}
+	if v := macVendor(ouiVendors(), mac); v != "" {
+		return mac + " (" + v + ")"
+	}
+	return mac
+}
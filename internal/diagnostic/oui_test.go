@@ -0,0 +1,43 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOUIs(t *testing.T) {
+	ieee := `OUI/MA-L			Organization
+company_id			Organization
+				Address
+
+A4-83-E7   (hex)		Apple, Inc.
+A483E7     (base 16)		Apple, Inc.
+				1 Infinite Loop
+`
+	manuf := `# Wireshark manuf
+00:00:0C	Cisco	Cisco Systems, Inc
+3C:22:FB	Apple
+00:1B:C5:00:00/36	Convergi	Converging Systems Inc.
+`
+	vendors := parseOUIs(strings.NewReader(ieee + manuf))
+	tests := map[string]string{
+		"a4:83:e7": "Apple, Inc.",
+		"00:00:0c": "Cisco Systems, Inc",
+		"3c:22:fb": "Apple",
+	}
+	for prefix, want := range tests {
+		if got := vendors[prefix]; got != want {
+			t.Errorf("%s: expected %q, got %q", prefix, want, got)
+		}
+	}
+	if len(vendors) != len(tests) {
+		t.Errorf("Expected %d vendors, got %v", len(tests), vendors)
+	}
+
+	if got := macVendor(vendors, "a4:83:e7:01:02:03"); got != "Apple, Inc." {
+		t.Errorf("Expected Apple, got %q", got)
+	}
+	if got := macVendor(vendors, "a6:83:e7:01:02:03"); got != "" {
+		t.Errorf("Expected no vendor for a locally administered MAC, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// sampleInterval spaces the percentile pings at the fastest rate macOS
+// allows without root.
+const sampleInterval = MinPingInterval
+
+// LatencyPercentiles summarizes a window of latency samples.
+type LatencyPercentiles struct {
+	// Samples is the number of replies the percentiles were computed from.
+	Samples int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// String renders the percentiles for a detail line.
+func (p LatencyPercentiles) String() string {
+	return fmt.Sprintf("p50 %v, p90 %v, p99 %v, max %v (%d samples)",
+		p.P50.Round(100*time.Microsecond), p.P90.Round(100*time.Microsecond),
+		p.P99.Round(100*time.Microsecond), p.Max.Round(100*time.Microsecond), p.Samples)
+}
+
+// computePercentiles uses the nearest-rank method, so every percentile is an
+// observed sample: with fewer than 100 samples p99 is the maximum, and a
+// single sample yields that sample for all of them. samples is not modified.
+func computePercentiles(samples []time.Duration) LatencyPercentiles {
+	if len(samples) == 0 {
+		return LatencyPercentiles{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return LatencyPercentiles{
+		Samples: len(sorted),
+		P50:     rank(50),
+		P90:     rank(90),
+		P99:     rank(99),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// parsePingSamples returns the RTT of every reply in ping's output.
+func parsePingSamples(output string) []time.Duration {
+	var samples []time.Duration
+	for _, m := range reProbeRTT.FindAllStringSubmatch(output, -1) {
+		ms, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, time.Duration(ms*float64(time.Millisecond)))
+	}
+	return samples
+}
+
+// sampleLatency pings ip count times at sampleInterval and returns the
+// percentiles of the replies. Lost packets are simply absent from the window.
+func sampleLatency(ctx context.Context, ip string, count int) (LatencyPercentiles, error) {
+	stats, err := nativeEcho(ctx, ip, false, count, sampleInterval, 0, activeConfig(ctx).Timeout)
+	if err == nil {
+		if stats.Received == 0 {
+			return LatencyPercentiles{}, fmt.Errorf("no replies from %s", ip)
+		}
+		return computePercentiles(stats.RTTs), nil
+	}
+	if !errors.Is(err, errICMPUnavailable) {
+		return LatencyPercentiles{}, err
+	}
+
+	window := time.Duration(count)*sampleInterval + activeConfig(ctx).Timeout
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	cmd := activePlatform().statsPingCommand(ip, false, count, 0, sampleInterval)
+	out, err := runCommand(ctx, cmd[0], cmd[1:]...)
+	samples := parsePingSamples(string(out))
+	if len(samples) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no replies from %s", ip)
+		}
+		return LatencyPercentiles{}, err
+	}
+	return computePercentiles(samples), nil
+}
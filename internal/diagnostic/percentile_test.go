@@ -0,0 +1,57 @@
+package diagnostic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputePercentiles(t *testing.T) {
+	ms := func(vals ...int) []time.Duration {
+		out := make([]time.Duration, len(vals))
+		for i, v := range vals {
+			out[i] = time.Duration(v) * time.Millisecond
+		}
+		return out
+	}
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i
+	}
+
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    LatencyPercentiles
+	}{
+		{"empty", nil, LatencyPercentiles{}},
+		{"single", ms(12), LatencyPercentiles{1, 12 * time.Millisecond, 12 * time.Millisecond, 12 * time.Millisecond, 12 * time.Millisecond}},
+		{"two", ms(30, 10), LatencyPercentiles{2, 10 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond}},
+		{"spike", ms(10, 11, 12, 10, 11, 13, 12, 10, 11, 250), LatencyPercentiles{10, 11 * time.Millisecond, 13 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond}},
+		{"hundred", ms(hundred...), LatencyPercentiles{100, 50 * time.Millisecond, 90 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		if got := computePercentiles(tt.samples); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestComputePercentilesKeepsInput(t *testing.T) {
+	samples := []time.Duration{3, 1, 2}
+	computePercentiles(samples)
+	if samples[0] != 3 || samples[1] != 1 || samples[2] != 2 {
+		t.Errorf("Expected input order to be preserved, got %v", samples)
+	}
+}
+
+func TestParsePingSamples(t *testing.T) {
+	output := `PING 1.1.1.1 (1.1.1.1): 56 data bytes
+64 bytes from 1.1.1.1: icmp_seq=0 ttl=58 time=12.345 ms
+Request timeout for icmp_seq 1
+64 bytes from 1.1.1.1: icmp_seq=2 ttl=58 time=80.1 ms
+`
+	got := parsePingSamples(output)
+	if len(got) != 2 || got[0] != 12345*time.Microsecond || got[1] != 80100*time.Microsecond {
+		t.Errorf("Expected two samples, got %v", got)
+	}
+}
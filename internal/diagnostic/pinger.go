@@ -0,0 +1,195 @@
+package diagnostic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// errICMPUnavailable means the in-process pinger cannot be used, either
+// because checks run on a remote host or no ICMP socket could be opened.
+// Callers then fall back to exec'ing ping.
+var errICMPUnavailable = errors.New("native ICMP unavailable")
+
+const (
+	// defaultPingPayload matches ping's default of 56 data bytes.
+	defaultPingPayload = 56
+	// pingTimeout bounds a single ping and the wait after a series.
+	pingTimeout = 2 * time.Second
+)
+
+// PingStats summarizes a series of echo requests.
+type PingStats struct {
+	Sent     int
+	Received int
+	// RTTs are the round-trip times of the replies, in arrival order.
+	RTTs   []time.Duration
+	Min    time.Duration
+	Avg    time.Duration
+	Max    time.Duration
+	StdDev time.Duration
+}
+
+// Loss is the percentage of requests that got no reply.
+func (s PingStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return 100 * float64(s.Sent-s.Received) / float64(s.Sent)
+}
+
+// newPingStats computes the summary of rtts out of sent requests. StdDev is
+// the population standard deviation, as ping reports it.
+func newPingStats(sent int, rtts []time.Duration) PingStats {
+	s := PingStats{Sent: sent, Received: len(rtts), RTTs: rtts}
+	if len(rtts) == 0 {
+		return s
+	}
+	var sum float64
+	s.Min, s.Max = rtts[0], rtts[0]
+	for _, d := range rtts {
+		s.Min, s.Max = min(s.Min, d), max(s.Max, d)
+		sum += float64(d)
+	}
+	mean := sum / float64(len(rtts))
+	var sq float64
+	for _, d := range rtts {
+		sq += (float64(d) - mean) * (float64(d) - mean)
+	}
+	s.Avg = time.Duration(mean)
+	s.StdDev = time.Duration(math.Sqrt(sq / float64(len(rtts))))
+	return s
+}
+
+// icmpEndpoint is an open echo socket and the address to send to.
+type icmpEndpoint struct {
+	conn  *icmp.PacketConn
+	dst   net.Addr
+	proto int
+	echo  icmp.Type
+	reply icmp.Type
+	// udp sockets have their echo ID rewritten by the kernel.
+	udp bool
+}
+
+// listenICMP opens a raw ICMP socket, or an unprivileged datagram one when
+// raw sockets need root.
+func listenICMP(ip string, ipv6Target bool) (*icmpEndpoint, error) {
+	addr, err := net.ResolveIPAddr("ip", ip)
+	if err != nil {
+		return nil, err
+	}
+	ep := &icmpEndpoint{proto: 1, echo: ipv4.ICMPTypeEcho, reply: ipv4.ICMPTypeEchoReply}
+	rawNet, udpNet, wildcard := "ip4:icmp", "udp4", "0.0.0.0"
+	if ipv6Target {
+		ep.proto, ep.echo, ep.reply = 58, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		rawNet, udpNet, wildcard = "ip6:ipv6-icmp", "udp6", "::"
+	}
+
+	if conn, err := icmp.ListenPacket(rawNet, wildcard); err == nil {
+		ep.conn, ep.dst = conn, addr
+		return ep, nil
+	}
+	conn, err := icmp.ListenPacket(udpNet, wildcard)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	ep.conn, ep.dst, ep.udp = conn, &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}, true
+	return ep, nil
+}
+
+// nativeEcho sends count echo requests of size payload bytes to ip, interval
+// apart, and waits up to timeout after the last one for replies. A zero size
+// uses ping's default payload. Cancelling ctx ends the wait early.
+func nativeEcho(ctx context.Context, ip string, ipv6Target bool, count int, interval time.Duration, size int, timeout time.Duration) (PingStats, error) {
+	if _, local := activeRunner().(localRunner); !local {
+		return PingStats{}, errICMPUnavailable
+	}
+	ep, err := listenICMP(ip, ipv6Target)
+	if err != nil {
+		return PingStats{}, err
+	}
+	defer func() { _ = ep.conn.Close() }()
+
+	if size <= 0 {
+		size = defaultPingPayload
+	}
+	// The send time travels in the payload, so replies need no bookkeeping.
+	size = max(size, 8)
+	id := os.Getpid() & 0xffff
+	deadline := time.Now().Add(time.Duration(max(count-1, 0))*interval + timeout)
+	if err := ep.conn.SetReadDeadline(deadline); err != nil {
+		return PingStats{}, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = ep.conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		for seq := 0; seq < count; seq++ {
+			if seq > 0 {
+				select {
+				case <-time.After(interval):
+				case <-ctx.Done():
+					sendErr <- ctx.Err()
+					return
+				}
+			}
+			data := make([]byte, size)
+			binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+			msg := icmp.Message{Type: ep.echo, Body: &icmp.Echo{ID: id, Seq: seq, Data: data}}
+			b, err := msg.Marshal(nil)
+			if err == nil {
+				_, err = ep.conn.WriteTo(b, ep.dst)
+			}
+			if err != nil {
+				sendErr <- err
+				// Nothing more is coming; stop waiting for replies.
+				_ = ep.conn.SetReadDeadline(time.Now())
+				return
+			}
+		}
+		sendErr <- nil
+	}()
+
+	rtts := readEchoReplies(ep, id, count)
+	if err := <-sendErr; err != nil && len(rtts) == 0 {
+		return PingStats{}, err
+	}
+	return newPingStats(count, rtts), nil
+}
+
+// readEchoReplies collects the RTT of each reply to our requests until all
+// count arrive or the read deadline passes.
+func readEchoReplies(ep *icmpEndpoint, id, count int) []time.Duration {
+	var rtts []time.Duration
+	seen := make(map[int]bool)
+	buf := make([]byte, 1500)
+	for len(rtts) < count {
+		n, _, err := ep.conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		msg, err := icmp.ParseMessage(ep.proto, buf[:n])
+		if err != nil || msg.Type != ep.reply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (!ep.udp && echo.ID != id) || seen[echo.Seq] || len(echo.Data) < 8 {
+			continue
+		}
+		seen[echo.Seq] = true
+		sent := time.Unix(0, int64(binary.BigEndian.Uint64(echo.Data)))
+		rtts = append(rtts, time.Since(sent))
+	}
+	return rtts
+}
@@ -0,0 +1,129 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewPingStats(t *testing.T) {
+	ms := time.Millisecond
+	s := newPingStats(4, []time.Duration{10 * ms, 20 * ms, 30 * ms})
+	if s.Received != 3 || s.Loss() != 25 {
+		t.Errorf("Expected 3 replies and 25%% loss, got %d and %v", s.Received, s.Loss())
+	}
+	if s.Min != 10*ms || s.Avg != 20*ms || s.Max != 30*ms {
+		t.Errorf("Expected 10/20/30ms, got %v/%v/%v", s.Min, s.Avg, s.Max)
+	}
+	// Population standard deviation of 10, 20 and 30 is sqrt(200/3) ms.
+	if s.StdDev < 8164*time.Microsecond || s.StdDev > 8165*time.Microsecond {
+		t.Errorf("Expected a stddev of about 8.165ms, got %v", s.StdDev)
+	}
+
+	if s := newPingStats(5, nil); s.Loss() != 100 || s.Avg != 0 {
+		t.Errorf("Expected total loss with no replies, got %+v", s)
+	}
+}
+
+func TestNativeEchoLoopback(t *testing.T) {
+	s, err := nativeEcho(context.Background(), "127.0.0.1", false, 2, 10*time.Millisecond, 0, time.Second)
+	if errors.Is(err, errICMPUnavailable) {
+		t.Skip("no ICMP socket available")
+	}
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s.Sent != 2 || s.Received != 2 || len(s.RTTs) != 2 {
+		t.Errorf("Expected both echoes answered, got %+v", s)
+	}
+}
+
+func TestNativeEchoRemoteRunner(t *testing.T) {
+	withRunner(t, &fakeRunner{})
+	if _, err := nativeEcho(context.Background(), "127.0.0.1", false, 1, 0, 0, time.Second); !errors.Is(err, errICMPUnavailable) {
+		t.Errorf("Expected the exec fallback when not running locally, got %v", err)
+	}
+}
+
+func TestParsePingStats(t *testing.T) {
+	darwin := `--- 1.1.1.1 ping statistics ---
+10 packets transmitted, 9 packets received, 10.0% packet loss
+round-trip min/avg/max/stddev = 10.100/12.500/20.250/2.750 ms
+`
+	s, err := parsePingStats(darwin)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s.Sent != 10 || s.Received != 9 || s.Loss() != 10 {
+		t.Errorf("Expected 9/10 replies, got %+v", s)
+	}
+	if s.Min != 10100*time.Microsecond || s.Max != 20250*time.Microsecond || s.StdDev != 2750*time.Microsecond {
+		t.Errorf("Expected the RTT summary to be parsed, got %+v", s)
+	}
+
+	linux := `--- 1.1.1.1 ping statistics ---
+10 packets transmitted, 10 received, 0% packet loss, time 1813ms
+rtt min/avg/max/mdev = 9.000/9.500/11.000/0.400 ms
+`
+	if s, err := parsePingStats(linux); err != nil || s.Received != 10 || s.Avg != 9500*time.Microsecond {
+		t.Errorf("Expected iputils output to be parsed, got %+v (%v)", s, err)
+	}
+
+	allLost := "5 packets transmitted, 0 packets received, 100.0% packet loss\n"
+	if s, err := parsePingStats(allLost); err != nil || s.Received != 0 || s.Loss() != 100 {
+		t.Errorf("Expected total loss without an RTT line, got %+v (%v)", s, err)
+	}
+	if _, err := parsePingStats("ping: unknown host"); err == nil {
+		t.Error("Expected an error without statistics")
+	}
+}
+
+func TestWarnIfLossy(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		stats  PingStats
+		status Status
+		reason Reason
+	}{
+		{"clean", newPingStats(10, []time.Duration{10 * ms, 11 * ms, 10 * ms, 12 * ms, 10 * ms, 11 * ms, 10 * ms, 11 * ms, 10 * ms, 12 * ms}), StatusOk, ""},
+		{"one lost", newPingStats(10, []time.Duration{10 * ms, 11 * ms, 10 * ms, 12 * ms, 10 * ms, 11 * ms, 10 * ms, 11 * ms, 10 * ms}), StatusWarning, ReasonPacketLoss},
+		{"jittery", newPingStats(4, []time.Duration{10 * ms, 90 * ms, 10 * ms, 90 * ms}), StatusWarning, ReasonHighJitter},
+		{"all lost", PingStats{Sent: 10}, StatusOk, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		warnIfLossy(context.Background(), &res, tt.stats)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+
+	res := Result{Status: StatusError, Reason: ReasonOffline}
+	warnIfLossy(context.Background(), &res, PingStats{Sent: 10, Received: 5})
+	if res.Status != StatusError || res.Reason != ReasonOffline {
+		t.Errorf("Expected an error to be left alone, got %v/%q", res.Status, res.Reason)
+	}
+}
+
+func TestMeasureLossAndJitterPingSettings(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"ping -c 5 -i 0.5 -s 1400 192.168.1.1": "5 packets transmitted, 4 packets received, 20.0% packet loss\n" +
+			"round-trip min/avg/max/stddev = 1.000/2.000/3.000/0.500 ms\n",
+	}})
+	c := DefaultConfig()
+	c.PingBurst, c.PingSize, c.PingInterval = 5, 1400, 500*time.Millisecond
+	ctx := WithConfig(context.Background(), c)
+
+	stats, err := MeasureLossAndJitter(ctx, "192.168.1.1", false)
+	if err != nil || stats.Sent != 5 || stats.Received != 4 {
+		t.Fatalf("Expected the burst to use the ping size and interval, got %+v (%v)", stats, err)
+	}
+	res := Result{Status: StatusOk}
+	warnIfLossy(ctx, &res, stats)
+	if !strings.Contains(res.Message, "of 1400-byte pings") {
+		t.Errorf("Expected the loss to name the ping size, got %q", res.Message)
+	}
+}
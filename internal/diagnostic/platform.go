@@ -0,0 +1,381 @@
+package diagnostic
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// platform hides the OS-specific commands, flags and output formats behind
+// the checks. Each command is returned as name followed by its arguments.
+type platform interface {
+	// routeCommand prints the IPv4 (or, with inet6, IPv6) default route,
+	// or iface's own default route when iface is not empty.
+	routeCommand(inet6 bool, iface string) []string
+	// defaultRoutesCommand prints every IPv4 default route, including the
+	// interface-scoped ones; parseDefaultRoutes reads them in the order the
+	// system prefers them.
+	defaultRoutesCommand() []string
+	parseDefaultRoutes(output string) []defaultRouteEntry
+	parseInterface(routeOutput string) (string, error)
+	parseGateway(routeOutput string) (string, error)
+	parseGateway6(routeOutput string) (string, error)
+	// routeGetCommand prints the route the kernel picks for ip, and
+	// parseRouteInterface reads its outgoing interface.
+	routeGetCommand(ip string) []string
+	parseRouteInterface(routeOutput string) (string, error)
+
+	// pingCommand sends one echo request. Zero size and interval keep
+	// ping's defaults; a positive ttl limits the hop count.
+	pingCommand(ip string, size int, interval time.Duration, ttl int) []string
+	ping6Command(ip string) []string
+	// pingViaCommand sends one echo request out of iface, whatever the
+	// routing table says.
+	pingViaCommand(ip, iface string) []string
+	// statsPingCommand sends count echo requests of size payload bytes
+	// (ping's default when zero), interval apart.
+	statsPingCommand(ip string, ipv6 bool, count, size int, interval time.Duration) []string
+	// dfPingCommand sends one echo request of size payload bytes with the
+	// don't-fragment bit set.
+	dfPingCommand(ip string, size int) []string
+	// setMTUCommand changes iface's MTU and needs root; it is shown as a
+	// fix, never run.
+	setMTUCommand(iface string, mtu int) []string
+	// openURLCommand opens u in the default browser; it is shown as a fix,
+	// never run.
+	openURLCommand(u string) []string
+	// tracerouteCommand traces the path to target without name lookups,
+	// sending probes of the given TraceMode per hop up to maxTTL.
+	tracerouteCommand(target, mode string, maxTTL, probes int) []string
+
+	// neighborCommand lists the link-layer neighbor entry for ip, and
+	// neighborTableCommand every entry; parseNeighbors reads either.
+	neighborCommand(ip string) []string
+	neighborTableCommand() []string
+	parseNeighbors(output string) map[string][]string
+
+	// mediaCommand reports the negotiated and supported media of a wired
+	// iface; parseMedia reads them, with ok false when iface is not wired.
+	mediaCommand(iface string) []string
+	parseMedia(output string) (m ethernetMedia, ok bool)
+	// listenersCommand lists the TCP sockets listening and the UDP sockets
+	// bound on this machine; parseListeners reads them.
+	listenersCommand() []string
+	parseListeners(output string) []listener
+	// ephemeralPortsCommand prints the range client sockets are bound in;
+	// parseEphemeralPorts reads it, or returns the platform's default
+	// range when output does not hold one.
+	ephemeralPortsCommand() []string
+	parseEphemeralPorts(output string) portRange
+	// talkersCommand samples every process's byte counters twice, interval
+	// apart, and parseTalkers turns them into rates; the command is nil
+	// where there is no such tool.
+	talkersCommand(interval time.Duration) []string
+	parseTalkers(output string, interval time.Duration) []talker
+
+	// wifiCommand reports the Wi-Fi association of iface.
+	wifiCommand(iface string) []string
+	parseWiFi(output string, verbose bool) wifiLink
+	// securityCommand reports the security mode of iface's association
+	// when wifiCommand does not; it is nil when wifiCommand does.
+	securityCommand(iface string) []string
+	parseSecurity(output string) (security, pmf string)
+	// linkCommand prints iface's settings, including its MTU.
+	linkCommand(iface string) []string
+	// proxyCommand prints the system proxy settings; parseProxies reads them.
+	// It is nil where the settings are environment variables, which are read
+	// in-process so the rest of the environment stays out of the debug log.
+	proxyCommand() []string
+	parseProxies(output string) proxySettings
+	// dnsConfigCommand prints the resolver configuration of every
+	// interface; parseDNSConfig reads it.
+	dnsConfigCommand() []string
+	parseDNSConfig(output string) []dnsScope
+	// ntpServerCommand prints the NTP server the clock synchronizes with;
+	// parseNTPServer reads it, or returns "" when there is none.
+	ntpServerCommand() []string
+	parseNTPServer(output string) string
+	// dhcpLeaseCommand prints the DHCP lease of iface and fails when it has
+	// none; parseDHCPLease reads it.
+	dhcpLeaseCommand(iface string) []string
+	parseDHCPLease(output string) dhcpLease
+	// dhcpLeaseStartCommand prints when iface obtained its lease, where
+	// dhcpLeaseCommand does not say; parseDHCPLeaseStart reads it.
+	dhcpLeaseStartCommand(iface string) []string
+	parseDHCPLeaseStart(output string) time.Time
+
+	// Remedies applied by the guided fix. flushDNSCommands empties the
+	// resolver cache, renewDHCPCommands asks client, the DHCP client
+	// holding iface's lease, for a fresh one (nil when it cannot) and
+	// wifiPowerCommand turns iface's radio off or on. serviceOrderCommand
+	// lists the network service order and setServiceOrderCommand replaces
+	// it; both are nil where there is no such order.
+	flushDNSCommands() [][]string
+	// dhcpClientCommand lists the running processes, and parseDHCPClient
+	// names the DHCP client among them that holds iface's lease, "" when
+	// none it knows; the command is nil where the system has one client.
+	dhcpClientCommand() []string
+	parseDHCPClient(output, iface string) string
+	renewDHCPCommands(client, iface string) [][]string
+	wifiPowerCommand(iface string, on bool) []string
+	serviceOrderCommand() []string
+	setServiceOrderCommand(services []string) []string
+	// dnsServersCommand prints the DNS servers set on service (the network
+	// service on macOS, the interface elsewhere), and parseDNSServers reads
+	// them; setDNSServersCommand(service, nil) restores the automatic
+	// servers.
+	dnsServersCommand(service string) []string
+	parseDNSServers(output string) []string
+	setDNSServersCommand(service string, servers []string) []string
+
+	// tools are the commands the checks rely on, for ProbeEnvironment.
+	tools() []string
+}
+
+// platformFor returns the implementation for goos; macOS is the default.
+func platformFor(goos string) platform {
+	if goos == "linux" {
+		return linuxPlatform{}
+	}
+	return darwinPlatform{}
+}
+
+var (
+	platformMu sync.RWMutex
+	plat       = platformFor(runtime.GOOS)
+)
+
+func activePlatform() platform {
+	platformMu.RLock()
+	defer platformMu.RUnlock()
+	return plat
+}
+
+func setPlatform(p platform) {
+	platformMu.Lock()
+	defer platformMu.Unlock()
+	plat = p
+}
+
+// runPlatformCommand runs a command built by the active platform.
+func runPlatformCommand(ctx context.Context, cmd []string) ([]byte, error) {
+	return runCommand(ctx, cmd[0], cmd[1:]...)
+}
+
+// darwinPlatform drives the macOS tools: route, ping, arp and system_profiler.
+type darwinPlatform struct{}
+
+func (darwinPlatform) routeCommand(inet6 bool, iface string) []string {
+	cmd := []string{"route", "-n", "get"}
+	if inet6 {
+		cmd = append(cmd, "-inet6")
+	}
+	if iface != "" {
+		cmd = append(cmd, "-ifscope", iface)
+	}
+	return append(cmd, "default")
+}
+
+func (darwinPlatform) defaultRoutesCommand() []string {
+	return []string{"netstat", "-rn", "-f", "inet"}
+}
+
+func (darwinPlatform) parseDefaultRoutes(out string) []defaultRouteEntry {
+	return parseDefaultRoutes(out)
+}
+
+func (darwinPlatform) parseInterface(out string) (string, error) { return parseInterface(out) }
+func (darwinPlatform) parseGateway(out string) (string, error)   { return parseGateway(out) }
+func (darwinPlatform) parseGateway6(out string) (string, error)  { return parseGateway6(out) }
+
+func (darwinPlatform) pingCommand(ip string, size int, interval time.Duration, ttl int) []string {
+	args := pingArgs(ip, size, interval)
+	if ttl > 0 {
+		args = append(args[:len(args)-1], "-t", strconv.Itoa(ttl), ip)
+	}
+	return append([]string{"ping"}, args...)
+}
+
+func (darwinPlatform) routeGetCommand(ip string) []string             { return []string{"route", "-n", "get", ip} }
+func (darwinPlatform) parseRouteInterface(out string) (string, error) { return parseInterface(out) }
+
+func (darwinPlatform) pingViaCommand(ip, iface string) []string {
+	return []string{"ping", "-c", "1", "-b", iface, ip}
+}
+
+func (darwinPlatform) ping6Command(ip string) []string {
+	return []string{"ping6", "-c", "1", ip}
+}
+
+func (darwinPlatform) statsPingCommand(ip string, ipv6 bool, count, size int, interval time.Duration) []string {
+	cmd := []string{"ping", "-c", strconv.Itoa(count), "-i", formatSeconds(interval)}
+	if ipv6 {
+		cmd[0] = "ping6"
+	}
+	if size > 0 {
+		cmd = append(cmd, "-s", strconv.Itoa(size))
+	}
+	return append(cmd, ip)
+}
+
+func (darwinPlatform) dfPingCommand(ip string, size int) []string {
+	return []string{"ping", "-D", "-c", "1", "-W", "1000", "-s", strconv.Itoa(size), ip}
+}
+
+func (darwinPlatform) setMTUCommand(iface string, mtu int) []string {
+	return []string{"ifconfig", iface, "mtu", strconv.Itoa(mtu)}
+}
+
+func (darwinPlatform) openURLCommand(u string) []string { return []string{"open", u} }
+
+func (darwinPlatform) tracerouteCommand(target, mode string, maxTTL, probes int) []string {
+	cmd := tracerouteArgs(maxTTL, probes)
+	switch mode {
+	case TraceICMP:
+		cmd = append(cmd, "-I")
+	case TraceTCP:
+		cmd = append(cmd, "-P", "tcp", "-p", tracePortTCP)
+	}
+	return append(cmd, target)
+}
+
+func (darwinPlatform) neighborCommand(ip string) []string { return []string{"arp", "-n", ip} }
+func (darwinPlatform) neighborTableCommand() []string     { return []string{"arp", "-a", "-n"} }
+func (darwinPlatform) parseNeighbors(out string) map[string][]string {
+	return parseArpTable(out)
+}
+
+func (darwinPlatform) wifiCommand(string) []string {
+	return []string{"system_profiler", "SPAirPortDataType"}
+}
+
+func (darwinPlatform) parseWiFi(out string, verbose bool) wifiLink {
+	return parseSystemProfiler(out, verbose)
+}
+
+// securityCommand is nil since system_profiler lists the security mode,
+// though not PMF.
+func (darwinPlatform) securityCommand(string) []string             { return nil }
+func (darwinPlatform) parseSecurity(string) (security, pmf string) { return "", "" }
+
+func (darwinPlatform) linkCommand(iface string) []string { return []string{"ifconfig", iface} }
+
+// mediaCommand adds -m so ifconfig also lists the supported media.
+func (darwinPlatform) mediaCommand(iface string) []string { return []string{"ifconfig", "-m", iface} }
+func (darwinPlatform) parseMedia(out string) (ethernetMedia, bool) {
+	return parseMedia(out)
+}
+
+func (darwinPlatform) listenersCommand() []string {
+	return []string{"lsof", "-nP", "-iTCP", "-sTCP:LISTEN", "-iUDP"}
+}
+func (darwinPlatform) parseListeners(out string) []listener { return parseLsofListeners(out) }
+
+func (darwinPlatform) ephemeralPortsCommand() []string {
+	return []string{"sysctl", "-n", "net.inet.ip.portrange.first", "net.inet.ip.portrange.last"}
+}
+func (darwinPlatform) parseEphemeralPorts(out string) portRange {
+	if r, ok := parsePortRange(out); ok {
+		return r
+	}
+	return portRange{49152, 65535}
+}
+
+func (darwinPlatform) talkersCommand(interval time.Duration) []string {
+	return []string{"nettop", "-P", "-x", "-L", "2", "-s", formatSeconds(interval), "-J", "bytes_in,bytes_out"}
+}
+func (darwinPlatform) parseTalkers(out string, interval time.Duration) []talker {
+	return parseNettop(out, interval)
+}
+
+func (darwinPlatform) proxyCommand() []string                { return []string{"scutil", "--proxy"} }
+func (darwinPlatform) parseProxies(out string) proxySettings { return parseScutilProxy(out) }
+
+func (darwinPlatform) dnsConfigCommand() []string           { return []string{"scutil", "--dns"} }
+func (darwinPlatform) parseDNSConfig(out string) []dnsScope { return parseScutilDNS(out) }
+
+func (darwinPlatform) ntpServerCommand() []string {
+	return []string{"systemsetup", "-getnetworktimeserver"}
+}
+func (darwinPlatform) parseNTPServer(out string) string { return parseSystemsetupNTP(out) }
+
+func (darwinPlatform) dhcpLeaseCommand(iface string) []string {
+	return []string{"ipconfig", "getpacket", iface}
+}
+func (darwinPlatform) parseDHCPLease(out string) dhcpLease { return parseGetpacket(out) }
+
+func (darwinPlatform) dhcpLeaseStartCommand(iface string) []string {
+	return []string{"ipconfig", "getsummary", iface}
+}
+func (darwinPlatform) parseDHCPLeaseStart(out string) time.Time {
+	return parseGetsummaryLeaseStart(out)
+}
+
+func (darwinPlatform) flushDNSCommands() [][]string {
+	return [][]string{{"dscacheutil", "-flushcache"}, {"killall", "-HUP", "mDNSResponder"}}
+}
+
+// dhcpClientCommand is nil: configd holds every lease.
+func (darwinPlatform) dhcpClientCommand() []string           { return nil }
+func (darwinPlatform) parseDHCPClient(string, string) string { return "" }
+
+func (darwinPlatform) renewDHCPCommands(_, iface string) [][]string {
+	return [][]string{{"ipconfig", "set", iface, "DHCP"}}
+}
+
+func (darwinPlatform) wifiPowerCommand(iface string, on bool) []string {
+	return []string{"networksetup", "-setairportpower", iface, onOff(on)}
+}
+
+func (darwinPlatform) serviceOrderCommand() []string {
+	return []string{"networksetup", "-listnetworkserviceorder"}
+}
+
+func (darwinPlatform) setServiceOrderCommand(services []string) []string {
+	return append([]string{"networksetup", "-ordernetworkservices"}, services...)
+}
+
+func (darwinPlatform) dnsServersCommand(service string) []string {
+	return []string{"networksetup", "-getdnsservers", service}
+}
+
+func (darwinPlatform) parseDNSServers(out string) []string { return parseNetworksetupDNS(out) }
+
+// setDNSServersCommand clears the servers set by hand with "Empty", so
+// DHCP's apply again.
+func (darwinPlatform) setDNSServersCommand(service string, servers []string) []string {
+	if len(servers) == 0 {
+		servers = []string{"Empty"}
+	}
+	return append([]string{"networksetup", "-setdnsservers", service}, servers...)
+}
+
+func (darwinPlatform) tools() []string {
+	return []string{"ping", "route", "arp", "system_profiler", "ifconfig"}
+}
+
+// pingArgs builds macOS ping arguments for a single echo request.
+func pingArgs(ip string, size int, interval time.Duration) []string {
+	args := []string{"-c", "1"}
+	if size > 0 {
+		args = append(args, "-s", strconv.Itoa(size))
+	}
+	if interval > 0 {
+		args = append(args, "-i", formatSeconds(interval))
+	}
+	return append(args, ip)
+}
+
+// tracerouteArgs builds the traceroute options shared by macOS and Linux:
+// numeric output, a one-second wait per probe, probes per hop and the
+// maximum TTL.
+func tracerouteArgs(maxTTL, probes int) []string {
+	return []string{"traceroute", "-n", "-w", formatSeconds(traceWait), "-q", strconv.Itoa(probes), "-m", strconv.Itoa(maxTTL)}
+}
+
+// formatSeconds renders d the way ping's -i and -W flags expect.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
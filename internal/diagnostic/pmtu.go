@@ -0,0 +1,196 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ipICMPOverhead is the IPv4 and ICMP header bytes around a ping payload.
+	ipICMPOverhead = 28
+	// minPathMTU is the smallest MTU every IPv4 link must carry.
+	minPathMTU = 576
+	// defaultMTU is assumed when the interface MTU cannot be read.
+	defaultMTU = StandardMTU
+	// pmtuAttempts is how many pings of one size must all vanish before
+	// the size counts as dropped, so one lost packet does not shrink the
+	// path MTU.
+	pmtuAttempts = 3
+)
+
+// errPingLoss means pings vanished whatever their size, so the losses say
+// nothing about the path MTU.
+var errPingLoss = errors.New("pings are lost regardless of size")
+
+// dfPingResult is the outcome of one don't-fragment ping.
+type dfPingResult struct {
+	// ok means a reply came back.
+	ok bool
+	// tooBig means the packet was refused with "fragmentation needed" or
+	// "message too long", so path MTU discovery is getting its feedback.
+	tooBig bool
+}
+
+// CheckPathMTU compares the primary interface's MTU with the largest
+// packet that reaches the WAN host unfragmented. Oversized packets that
+// vanish without a "fragmentation needed" reply are an MTU black hole,
+// common on PPPoE and VPN links: small requests work, large ones hang.
+func CheckPathMTU(ctx context.Context) Result {
+	res := Result{Name: "Path MTU", Emoji: "📏", Status: StatusOk}
+	if r, ok := missingToolResult(res.Name, res.Emoji, activeRunner().LookPath("ping")); ok {
+		return r
+	}
+	iface, err := getPrimaryInterface(ctx)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "No Default Route"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+
+	var details []string
+	mtu, err := interfaceMTU(ctx, iface)
+	if err != nil {
+		mtu = defaultMTU
+		details = append(details, fmt.Sprintf("Interface MTU (%s): unreadable, assuming %d", iface, mtu))
+	} else {
+		details = append(details, fmt.Sprintf("Interface MTU (%s): %d", iface, mtu))
+	}
+
+	target := activeConfig(ctx).WANHost
+	var probeErr error
+	lo := minPathMTU - ipICMPOverhead
+	payload, feedback, err := searchPathMTU(lo, max(mtu-ipICMPOverhead, lo), func(size int) dfPingResult {
+		r, err := dfPing(ctx, target, size)
+		if err != nil && probeErr == nil {
+			probeErr = err
+		}
+		return r
+	})
+	if errors.Is(err, errPingLoss) {
+		res.Status = StatusSkipped
+		res.Message = "Could not measure: pings to " + target + " are being lost"
+		res.Reason = ReasonProbeFailed
+		res.Details = formatDetailsWithPrefixes(details)
+		return res
+	}
+	if payload < 0 {
+		res.Status = StatusSkipped
+		res.Message = "Could not measure: " + target + " did not answer pings"
+		res.Reason = ReasonProbeFailed
+		if isPermissionError(probeErr) {
+			res.Message = "Could not measure: ICMP not permitted"
+			res.Reason = ReasonICMPUnavailable
+		}
+		res.Details = formatDetailsWithPrefixes(details)
+		return res
+	}
+
+	details = append(details, fmt.Sprintf("Largest unfragmented payload to %s: %d bytes (path MTU %d)", target, payload, payload+ipICMPOverhead))
+	res.Details = formatDetailsWithPrefixes(details)
+	gradePathMTU(&res, iface, mtu, payload+ipICMPOverhead, feedback)
+	return res
+}
+
+// gradePathMTU sets the verdict of CheckPathMTU for a path MTU of pmtu
+// behind an interface MTU of mtu.
+func gradePathMTU(res *Result, iface string, mtu, pmtu int, feedback bool) {
+	switch {
+	case pmtu >= mtu:
+		res.Message = fmt.Sprintf("Path MTU %d matches the interface", pmtu)
+	case feedback:
+		res.Message = fmt.Sprintf("Path MTU %d is below the interface MTU %d (discovery works)", pmtu, mtu)
+	default:
+		res.Status = StatusWarning
+		res.Reason = ReasonMTUBlackHole
+		res.Message = fmt.Sprintf("MTU black hole: packets over %d bytes are silently dropped", pmtu)
+		res.Fix = fmt.Sprintf("Lower the MTU of %s to %d, or fix ICMP filtering on the PPPoE/VPN link.", iface, pmtu)
+		res.FixCommand = shellLine([][]string{activePlatform().setMTUCommand(iface, pmtu)}, true)
+	}
+}
+
+// searchPathMTU returns the largest payload in [lo, hi] that probe gets a
+// reply for, or -1 if even lo fails, and whether any failed probe drew an
+// explicit "too big" error. It tries hi first, since most paths carry it.
+// A size fails once it is refused or pmtuAttempts pings of it vanish; the
+// vanished ones only count while a control ping of lo still gets through,
+// and errPingLoss is returned otherwise.
+func searchPathMTU(lo, hi int, probe func(size int) dfPingResult) (int, bool, error) {
+	feedback, lossy := false, false
+	send := func(size int) dfPingResult {
+		r := probe(size)
+		feedback = feedback || r.tooBig
+		return r
+	}
+	control := lo
+	try := func(size int) bool {
+		for range pmtuAttempts {
+			r := send(size)
+			if r.ok || r.tooBig {
+				return r.ok
+			}
+		}
+		if size > control && !send(control).ok {
+			lossy = true
+		}
+		return false
+	}
+	if try(hi) {
+		return hi, false, nil
+	}
+	if !try(lo) {
+		return -1, feedback, nil
+	}
+	// Invariant: lo passes and hi fails.
+	for hi-lo > 1 && !lossy {
+		mid := (lo + hi) / 2
+		if try(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if lossy {
+		return -1, feedback, errPingLoss
+	}
+	return lo, feedback, nil
+}
+
+// dfPing sends one ping of size payload bytes with the don't-fragment bit set.
+func dfPing(ctx context.Context, ip string, size int) (dfPingResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	out, err := runPlatformCommand(ctx, activePlatform().dfPingCommand(ip, size))
+	text := string(out)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		text += string(exitErr.Stderr)
+	}
+	return parseDFPing(text), err
+}
+
+// parseDFPing reads the output of a don't-fragment ping on macOS or Linux.
+func parseDFPing(output string) dfPingResult {
+	lower := strings.ToLower(output)
+	return dfPingResult{
+		ok:     rePingStat.MatchString(output),
+		tooBig: strings.Contains(lower, "frag needed") || strings.Contains(lower, "message too long"),
+	}
+}
+
+// interfaceMTU reads the MTU of iface.
+func interfaceMTU(ctx context.Context, iface string) (int, error) {
+	out, err := runPlatformCommand(ctx, activePlatform().linkCommand(iface))
+	if err != nil {
+		return 0, err
+	}
+	m := reMTU.FindStringSubmatch(string(out))
+	if len(m) < 2 {
+		return 0, fmt.Errorf("no MTU for %s", iface)
+	}
+	return strconv.Atoi(m[1])
+}
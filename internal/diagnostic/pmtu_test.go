@@ -0,0 +1,99 @@
+package diagnostic
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSearchPathMTU(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        int
+		tooBig       bool
+		want         int
+		wantFeedback bool
+	}{
+		{"full path", 1472, false, 1472, false},
+		{"pppoe with feedback", 1464, true, 1464, true},
+		{"black hole", 1372, false, 1372, false},
+		{"nothing answers", 0, false, -1, false},
+	}
+	for _, tt := range tests {
+		sizes := map[int]bool{}
+		got, feedback, err := searchPathMTU(548, 1472, func(size int) dfPingResult {
+			sizes[size] = true
+			return dfPingResult{ok: size <= tt.limit, tooBig: size > tt.limit && tt.tooBig}
+		})
+		if got != tt.want || feedback != tt.wantFeedback || err != nil {
+			t.Errorf("%s: expected %d (feedback %v), got %d (feedback %v, %v)", tt.name, tt.want, tt.wantFeedback, got, feedback, err)
+		}
+		if len(sizes) > 12 {
+			t.Errorf("%s: expected a binary search, tried %d sizes", tt.name, len(sizes))
+		}
+	}
+}
+
+func TestSearchPathMTULoss(t *testing.T) {
+	// Every other ping is lost, whatever its size: a retry gets through.
+	sent := 0
+	got, _, err := searchPathMTU(548, 1472, func(size int) dfPingResult {
+		sent++
+		return dfPingResult{ok: sent%2 == 0 && size <= 1372}
+	})
+	if got != 1372 || err != nil {
+		t.Errorf("Expected a lost ping to be retried, got %d (%v)", got, err)
+	}
+
+	// Pings stop answering halfway, so the control ping fails too.
+	sent = 0
+	got, _, err = searchPathMTU(548, 1472, func(size int) dfPingResult {
+		sent++
+		return dfPingResult{ok: sent < 6 && size <= 1372}
+	})
+	if !errors.Is(err, errPingLoss) || got != -1 {
+		t.Errorf("Expected errPingLoss, got %d (%v)", got, err)
+	}
+}
+
+func TestParseDFPing(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   dfPingResult
+	}{
+		{"reply", "1 packets transmitted, 1 packets received, 0.0% packet loss\nround-trip min/avg/max/stddev = 9.1/9.1/9.1/0.000 ms\n", dfPingResult{ok: true}},
+		{"macos local mtu", "ping: sendto: Message too long\n1 packets transmitted, 0 packets received, 100.0% packet loss\n", dfPingResult{tooBig: true}},
+		{"linux frag needed", "From 192.168.1.1 icmp_seq=1 Frag needed and DF set (mtu = 1492)\n1 packets transmitted, 0 received, +1 errors, 100% packet loss\n", dfPingResult{tooBig: true}},
+		{"silent drop", "1 packets transmitted, 0 received, 100% packet loss, time 0ms\n", dfPingResult{}},
+	}
+	for _, tt := range tests {
+		if got := parseDFPing(tt.output); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGradePathMTU(t *testing.T) {
+	withRunner(t, &fakeRunner{})
+	tests := []struct {
+		name     string
+		pmtu     int
+		feedback bool
+		status   Status
+		reason   Reason
+	}{
+		{"matches", 1500, false, StatusOk, ""},
+		{"discovered", 1492, true, StatusOk, ""},
+		{"black hole", 1400, false, StatusWarning, ReasonMTUBlackHole},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradePathMTU(&res, "en0", 1500, tt.pmtu, tt.feedback)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q", tt.name, tt.status, tt.reason, res.Status, res.Reason)
+		}
+		if tt.reason == ReasonMTUBlackHole && res.FixCommand != "sudo ifconfig en0 mtu 1400" {
+			t.Errorf("%s: expected an MTU fix command, got %q", tt.name, res.FixCommand)
+		}
+	}
+}
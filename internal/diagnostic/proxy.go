@@ -0,0 +1,212 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// proxyEndpoint is one configured proxy server.
+type proxyEndpoint struct {
+	// Kind is "HTTP", "HTTPS" or "SOCKS".
+	Kind string
+	Host string
+	Port int
+}
+
+// Address returns host:port for dialing.
+func (p proxyEndpoint) Address() string {
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+// proxySettings is the system proxy configuration.
+type proxySettings struct {
+	Proxies []proxyEndpoint
+	// PACURL is the proxy auto-config file, if one is set.
+	PACURL string
+}
+
+// Default proxy ports when the configuration gives none.
+var defaultProxyPorts = map[string]int{"HTTP": 80, "HTTPS": 443, "SOCKS": 1080}
+
+// CheckProxy reads the system proxy settings and verifies that every
+// configured proxy accepts connections and that the PAC file can be
+// fetched. An unreachable proxy breaks browsing while Wi-Fi, DNS and ping
+// all look healthy.
+func CheckProxy(ctx context.Context) Result {
+	res := Result{Name: "Proxy", Emoji: "🔀", Status: StatusOk}
+	settings, err := readProxySettings(ctx)
+	if err != nil {
+		if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+			return r
+		}
+		res.Status = StatusSkipped
+		res.Message = "Could not read the proxy settings"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	if len(settings.Proxies) == 0 && settings.PACURL == "" {
+		res.Message = "No proxy configured"
+		return res
+	}
+
+	var details, broken []string
+	for _, proxy := range settings.Proxies {
+		if _, err := tcpPing(ctx, proxy.Address()); err != nil {
+			broken = append(broken, proxy.Kind+" proxy "+proxy.Address())
+			details = append(details, fmt.Sprintf("%s proxy %s: unreachable (%v)", proxy.Kind, proxy.Address(), err))
+		} else {
+			details = append(details, fmt.Sprintf("%s proxy %s: reachable", proxy.Kind, proxy.Address()))
+		}
+	}
+	if settings.PACURL != "" {
+		if err := fetchPAC(ctx, settings.PACURL); err != nil {
+			broken = append(broken, "PAC file")
+			details = append(details, fmt.Sprintf("PAC %s: %v", settings.PACURL, err))
+		} else {
+			details = append(details, fmt.Sprintf("PAC %s: OK", settings.PACURL))
+		}
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	if len(broken) > 0 {
+		res.Status = StatusWarning
+		res.Reason = ReasonProxyUnreachable
+		res.Message = "Unreachable: " + strings.Join(broken, ", ")
+		res.Fix = "Connect to the network or VPN the proxy belongs to, or turn the proxy off in System Settings > Network > Details > Proxies."
+		return res
+	}
+	res.Message = fmt.Sprintf("%d proxy setting(s) reachable", len(settings.Proxies)+min(len(settings.PACURL), 1))
+	return res
+}
+
+// fetchPAC downloads the proxy auto-config file at pacURL and checks that it
+// defines FindProxyForURL.
+func fetchPAC(ctx context.Context, pacURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pacURL, nil)
+	if err != nil {
+		return err
+	}
+	// The PAC file is fetched directly, as the system would.
+	client := http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(body), "FindProxyForURL") {
+		return fmt.Errorf("not a PAC file")
+	}
+	return nil
+}
+
+// parseScutilProxy reads `scutil --proxy` output:
+//
+//	HTTPEnable : 1
+//	HTTPPort : 8080
+//	HTTPProxy : proxy.corp.example
+//	ProxyAutoConfigEnable : 1
+//	ProxyAutoConfigURLString : http://wpad.corp.example/proxy.pac
+func parseScutilProxy(out string) proxySettings {
+	values := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " : ")
+		if ok {
+			values[key] = strings.TrimSpace(value)
+		}
+	}
+	var s proxySettings
+	for _, kind := range []string{"HTTP", "HTTPS", "SOCKS"} {
+		host := values[kind+"Proxy"]
+		if values[kind+"Enable"] != "1" || host == "" {
+			continue
+		}
+		port, err := strconv.Atoi(values[kind+"Port"])
+		if err != nil || port == 0 {
+			port = defaultProxyPorts[kind]
+		}
+		s.Proxies = append(s.Proxies, proxyEndpoint{Kind: kind, Host: host, Port: port})
+	}
+	if values["ProxyAutoConfigEnable"] == "1" {
+		s.PACURL = values["ProxyAutoConfigURLString"]
+	}
+	return s
+}
+
+// readProxySettings returns the system proxy settings.
+func readProxySettings(ctx context.Context) (proxySettings, error) {
+	p := activePlatform()
+	out := []byte(proxyEnv())
+	if cmd := p.proxyCommand(); cmd != nil {
+		var err error
+		if out, err = runPlatformCommand(ctx, cmd); err != nil {
+			return proxySettings{}, err
+		}
+	}
+	return p.parseProxies(string(out)), nil
+}
+
+// proxyEnv prints this process's proxy variables the way `env` would,
+// lowercase first as curl prefers them.
+func proxyEnv() string {
+	var b strings.Builder
+	for _, key := range []string{"http_proxy", "https_proxy", "all_proxy", "HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY"} {
+		if v := os.Getenv(key); v != "" {
+			fmt.Fprintf(&b, "%s=%s\n", key, v)
+		}
+	}
+	return b.String()
+}
+
+// parseProxyEnv reads http_proxy, https_proxy and all_proxy (in either case)
+// from `env` output.
+func parseProxyEnv(out string) proxySettings {
+	kinds := map[string]string{"http_proxy": "HTTP", "https_proxy": "HTTPS", "all_proxy": "SOCKS"}
+	var s proxySettings
+	seen := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		key = strings.ToLower(key)
+		kind := kinds[key]
+		if !ok || kind == "" || value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !strings.Contains(value, "://") {
+			value = "http://" + value
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if strings.HasPrefix(u.Scheme, "socks") {
+			kind = "SOCKS"
+		}
+		port, err := strconv.Atoi(u.Port())
+		if err != nil {
+			port = defaultProxyPorts[kind]
+		}
+		s.Proxies = append(s.Proxies, proxyEndpoint{Kind: kind, Host: u.Hostname(), Port: port})
+	}
+	return s
+}
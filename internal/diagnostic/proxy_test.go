@@ -0,0 +1,117 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const scutilProxySample = `<dictionary> {
+  ExceptionsList : <array> {
+    0 : *.local
+    1 : 169.254/16
+  }
+  FTPPassive : 1
+  HTTPEnable : 1
+  HTTPPort : 8080
+  HTTPProxy : proxy.corp.example
+  HTTPSEnable : 1
+  HTTPSProxy : proxy.corp.example
+  HTTPSPort : 8443
+  SOCKSEnable : 0
+  SOCKSProxy : socks.corp.example
+  ProxyAutoConfigEnable : 1
+  ProxyAutoConfigURLString : http://wpad.corp.example/proxy.pac
+}
+`
+
+func TestParseScutilProxy(t *testing.T) {
+	got := parseScutilProxy(scutilProxySample)
+	want := proxySettings{
+		Proxies: []proxyEndpoint{
+			{Kind: "HTTP", Host: "proxy.corp.example", Port: 8080},
+			{Kind: "HTTPS", Host: "proxy.corp.example", Port: 8443},
+		},
+		PACURL: "http://wpad.corp.example/proxy.pac",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := parseScutilProxy("<dictionary> {\n  HTTPEnable : 0\n}\n"); len(got.Proxies) != 0 || got.PACURL != "" {
+		t.Errorf("Expected no proxies, got %+v", got)
+	}
+}
+
+func TestParseProxyEnv(t *testing.T) {
+	out := "HOME=/root\nhttp_proxy=http://proxy.corp.example:3128\nHTTP_PROXY=http://other:1\nHTTPS_PROXY=proxy.corp.example\nALL_PROXY=socks5://127.0.0.1:9050\n"
+	want := []proxyEndpoint{
+		{Kind: "HTTP", Host: "proxy.corp.example", Port: 3128},
+		{Kind: "HTTPS", Host: "proxy.corp.example", Port: 443},
+		{Kind: "SOCKS", Host: "127.0.0.1", Port: 9050},
+	}
+	if got := parseProxyEnv(out).Proxies; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	for _, key := range []string{"http_proxy", "https_proxy", "all_proxy", "HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HTTPS_PROXY", "proxy.corp.example:8443")
+	t.Setenv("WTFI_SECRET", "hunter2")
+	if got, want := proxyEnv(), "HTTPS_PROXY=proxy.corp.example:8443\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCheckProxy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	live := ln.Addr().(*net.TCPAddr)
+
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	deadPort := dead.Addr().(*net.TCPAddr).Port
+	_ = dead.Close()
+
+	pac := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `function FindProxyForURL(url, host) { return "DIRECT"; }`)
+	}))
+	defer pac.Close()
+
+	scutil := func(port int) string {
+		return fmt.Sprintf("<dictionary> {\n  HTTPEnable : 1\n  HTTPPort : %d\n  HTTPProxy : 127.0.0.1\n  ProxyAutoConfigEnable : 1\n  ProxyAutoConfigURLString : %s\n}\n", port, pac.URL)
+	}
+	tests := []struct {
+		name   string
+		out    string
+		status Status
+		reason Reason
+	}{
+		{"no proxy", "<dictionary> {\n}\n", StatusOk, ""},
+		{"reachable", scutil(live.Port), StatusOk, ""},
+		{"unreachable", scutil(deadPort), StatusWarning, ReasonProxyUnreachable},
+	}
+	for _, tt := range tests {
+		withRunner(t, &fakeRunner{outputs: map[string]string{"scutil --proxy": tt.out}})
+		res := CheckProxy(context.Background())
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+		if tt.status == StatusOk && tt.name != "no proxy" && !strings.Contains(strings.Join(res.Details, "\n"), "PAC") {
+			t.Errorf("%s: expected the PAC file in details, got %v", tt.name, res.Details)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package diagnostic
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ipEchoURL answers with the address a request came from and the country
+// it geolocates to, as "ip=203.0.113.7" and "loc=US" lines; tests replace
+// it.
+var ipEchoURL = "https://www.cloudflare.com/cdn-cgi/trace"
+
+// Well-known keys of Result.Facts set by CheckPublicIP, besides
+// FactPublicIP.
+const (
+	FactPublicIPv6 = "public_ipv6"
+	// FactISP is the AS announcing the public address, as "AS7922 COMCAST-7922".
+	FactISP = "isp"
+	// FactCountry is the ISO 3166 code the public address geolocates to.
+	FactCountry = "country"
+)
+
+// publicIdentity is how the internet sees this machine.
+type publicIdentity struct {
+	IPv4, IPv6 netip.Addr
+	// Reverse is the PTR name of the IPv4 address, or of the IPv6 one
+	// without IPv4.
+	Reverse string
+	ASN     string
+	ASName  string
+	// Country is an ISO 3166 code: where the address geolocates to, or
+	// where its block is registered when no geolocation is known.
+	Country string
+}
+
+// CheckPublicIP finds the public IPv4 address (STUN, else the echo
+// endpoint) and IPv6 address (echo endpoint), then its reverse DNS name,
+// the AS that announces it and its country, for tickets with the ISP. It
+// only runs with -v, since it tells third parties who is asking.
+func CheckPublicIP(ctx context.Context, verbose bool) Result {
+	res := Result{Name: "Public IP", Emoji: "🌍", Status: StatusOk}
+	if !verbose {
+		res.Status = StatusSkipped
+		res.Message = "Use -v flag to look up your public IP and ISP"
+		return res
+	}
+
+	var id publicIdentity
+	country := ""
+	if addr, err := stunPublicIP(ctx); err == nil {
+		id.IPv4 = addr
+	}
+	if addr, loc, err := echoPublicIP(ctx, "tcp4"); err == nil {
+		id.IPv4 = cmpAddr(id.IPv4, addr)
+		country = loc
+	}
+	if addr, loc, err := echoPublicIP(ctx, "tcp6"); err == nil {
+		id.IPv6 = addr
+		country = cmp.Or(country, loc)
+	}
+	if !id.IPv4.IsValid() && !id.IPv6.IsValid() {
+		res.Status = StatusWarning
+		res.Reason = ReasonProbeFailed
+		res.Message = "Could not discover the public IP"
+		res.Fix = "STUN (UDP) and " + ipEchoURL + " were unreachable; check the WAN result."
+		return res
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	primary := cmpAddr(id.IPv4, id.IPv6)
+	if names, err := reverseLookup(lookupCtx, primary.String()); err == nil && len(names) > 0 {
+		id.Reverse = strings.TrimSuffix(names[0], ".")
+	}
+	if id.IPv4.IsValid() {
+		asn, registered, err := lookupOrigin(lookupCtx, id.IPv4)
+		if err == nil {
+			id.ASN = asn
+			id.ASName, _ = lookupASName(lookupCtx, asn)
+			country = cmp.Or(country, registered)
+		}
+	}
+	id.Country = country
+	describePublicIP(&res, id)
+	return res
+}
+
+// describePublicIP fills in res from id.
+func describePublicIP(res *Result, id publicIdentity) {
+	res.Facts = map[string]string{}
+	var details []string
+	if id.IPv4.IsValid() {
+		details = append(details, "IPv4: "+id.IPv4.String())
+		res.Facts[FactPublicIP] = id.IPv4.String()
+	}
+	if id.IPv6.IsValid() {
+		details = append(details, "IPv6: "+id.IPv6.String())
+		res.Facts[FactPublicIPv6] = id.IPv6.String()
+	}
+	res.Message = cmpAddr(id.IPv4, id.IPv6).String()
+	if id.Reverse != "" {
+		details = append(details, "Reverse DNS: "+id.Reverse)
+	}
+	if id.ASN != "" {
+		isp := formatAS(traceHop{ASN: id.ASN, ASName: id.ASName})
+		details = append(details, "ISP: "+isp)
+		res.Facts[FactISP] = isp
+		res.Message += " on " + isp
+	}
+	if id.Country != "" {
+		details = append(details, "Country: "+id.Country)
+		res.Facts[FactCountry] = id.Country
+		res.Message += " (" + id.Country + ")"
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+}
+
+// echoPublicIP asks ipEchoURL over network ("tcp4" or "tcp6") which
+// address the request came from, and where that address geolocates.
+func echoPublicIP(ctx context.Context, network string) (netip.Addr, string, error) {
+	dialer := &net.Dialer{}
+	client := http.Client{
+		Timeout: activeConfig(ctx).Timeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ipEchoURL, nil)
+	if err != nil {
+		return netip.Addr{}, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return netip.Addr{}, "", err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	return parseIPEcho(io.LimitReader(resp.Body, 4096))
+}
+
+// parseIPEcho reads the "key=value" lines of the echo endpoint.
+func parseIPEcho(r io.Reader) (netip.Addr, string, error) {
+	var addr netip.Addr
+	loc := ""
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), "=")
+		switch key {
+		case "ip":
+			addr, _ = netip.ParseAddr(value)
+		case "loc":
+			// Cloudflare answers "XX" and "T1" (Tor) when it cannot tell.
+			if len(value) == 2 && value != "XX" && value != "T1" {
+				loc = value
+			}
+		}
+	}
+	if !addr.IsValid() {
+		return netip.Addr{}, "", errors.New("no address in the echo response")
+	}
+	return addr.Unmap(), loc, nil
+}
+
+// cmpAddr returns the first valid address of a and b.
+func cmpAddr(a, b netip.Addr) netip.Addr {
+	if a.IsValid() {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseIPEcho(t *testing.T) {
+	tests := []struct {
+		body    string
+		addr    string
+		loc     string
+		wantErr bool
+	}{
+		{"fl=12f\nh=www.cloudflare.com\nip=203.0.113.7\nts=1700000000.1\nloc=JP\n", "203.0.113.7", "JP", false},
+		{"ip=2001:db8::1\nloc=XX\n", "2001:db8::1", "", false},
+		{"ip=::ffff:203.0.113.7\nloc=T1\n", "203.0.113.7", "", false},
+		{"<html>blocked</html>", "", "", true},
+	}
+	for _, tt := range tests {
+		addr, loc, err := parseIPEcho(strings.NewReader(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error %v", tt.body, err)
+			continue
+		}
+		if !tt.wantErr && (addr.String() != tt.addr || loc != tt.loc) {
+			t.Errorf("%q: expected %s/%q, got %s/%q", tt.body, tt.addr, tt.loc, addr, loc)
+		}
+	}
+}
+
+func TestCheckPublicIP(t *testing.T) {
+	if res := CheckPublicIP(context.Background(), false); res.Status != StatusSkipped || res.Facts != nil {
+		t.Errorf("expected no lookups without -v, got %+v", res)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ip=203.0.113.7\nloc=JP\n"))
+	}))
+	defer srv.Close()
+	prev := ipEchoURL
+	ipEchoURL = srv.URL
+	t.Cleanup(func() { ipEchoURL = prev })
+	withSTUNServers(t)
+	prevReverse := reverseLookup
+	reverseLookup = func(_ context.Context, addr string) ([]string, error) {
+		if addr == "203.0.113.7" {
+			return []string{"p1234-ipoe.example.ne.jp."}, nil
+		}
+		return nil, errors.New("no PTR record")
+	}
+	t.Cleanup(func() { reverseLookup = prevReverse })
+	withTXT(t, map[string]string{
+		"7.113.0.203.origin.asn.cymru.com": "64500 | 203.0.113.0/24 | US | arin | 2001-01-01",
+		"AS64500.asn.cymru.com":            "64500 | US | arin | 2001-01-01 | EXAMPLE-ISP - Example ISP, Inc., US",
+	})
+
+	res := CheckPublicIP(context.Background(), true)
+	if want := "203.0.113.7 on AS64500 EXAMPLE-ISP (JP)"; res.Status != StatusOk || res.Message != want {
+		t.Errorf("expected %q, got %v %q", want, res.Status, res.Message)
+	}
+	if res.Facts[FactPublicIP] != "203.0.113.7" || res.Facts[FactISP] != "AS64500 EXAMPLE-ISP" || res.Facts[FactCountry] != "JP" {
+		t.Errorf("unexpected facts %v", res.Facts)
+	}
+	if !strings.Contains(strings.Join(res.Details, "\n"), "Reverse DNS: p1234-ipoe.example.ne.jp") {
+		t.Errorf("expected the reverse DNS name, got %q", res.Details)
+	}
+}
+
+func TestDescribePublicIPv6Only(t *testing.T) {
+	var res Result
+	describePublicIP(&res, publicIdentity{IPv6: netip.MustParseAddr("2001:db8::1"), Country: "DE"})
+	if res.Message != "2001:db8::1 (DE)" || res.Facts[FactPublicIPv6] != "2001:db8::1" || res.Facts[FactPublicIP] != "" {
+		t.Errorf("unexpected result %q %v", res.Message, res.Facts)
+	}
+}
@@ -0,0 +1,134 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"golang.org/x/net/quic"
+)
+
+// protoProbe is the outcome of one handshake of CheckQUIC.
+type protoProbe struct {
+	Latency time.Duration
+	// ALPN is the application protocol the server agreed to.
+	ALPN string
+	Err  error
+}
+
+// CheckQUIC completes an HTTP/3 handshake (QUIC over UDP/443, ALPN "h3")
+// and an HTTP/2 handshake (TLS over TCP/443, ALPN "h2") with
+// Config.QUICHost and compares them. Browsers fall back to HTTP/2 silently
+// when UDP/443 is blocked, but video calls and games that need QUIC or
+// other UDP degrade.
+func CheckQUIC(ctx context.Context) Result {
+	res := Result{Name: "QUIC / HTTP3", Emoji: "🚀", Status: StatusOk}
+	host := activeConfig(ctx).QUICHost
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+
+	var h3, h2 protoProbe
+	done := make(chan struct{})
+	go func() {
+		h3 = probeHTTP3(ctx, host)
+		close(done)
+	}()
+	h2 = probeHTTP2(ctx, host)
+	<-done
+
+	res.Details = formatDetailsWithPrefixes([]string{
+		"HTTP/3 (UDP/443): " + describeProtoProbe(h3),
+		"HTTP/2 (TCP/443): " + describeProtoProbe(h2),
+	})
+	gradeQUIC(&res, host, h3, h2)
+	return res
+}
+
+// gradeQUIC sets the verdict of CheckQUIC from the HTTP/3 and HTTP/2
+// handshakes with host.
+func gradeQUIC(res *Result, host string, h3, h2 protoProbe) {
+	switch {
+	case h3.Err != nil && h2.Err != nil:
+		res.Status = StatusError
+		res.Reason = ReasonUnreachable
+		res.Message = "Neither HTTP/3 nor HTTP/2 reached " + host
+		res.Fix = "Check your internet connection, firewall or proxy settings."
+	case h3.Err != nil:
+		res.Status = StatusWarning
+		res.Reason = ReasonQUICBlocked
+		res.Latency = h2.Latency
+		res.Message = "UDP/443 is blocked: HTTP/3 fails, browsers fall back to HTTP/2"
+		res.Fix = "A firewall drops QUIC. Web pages still load over TCP, but video calls and streaming lose QUIC's faster setup and recovery; allow outbound UDP/443 if you control the firewall."
+	case h2.Err != nil:
+		res.Status = StatusWarning
+		res.Reason = ReasonTLSFailed
+		res.Latency = h3.Latency
+		res.Message = "HTTP/3 works but HTTP/2 over TCP/443 fails"
+		res.Fix = "Sites and apps without HTTP/3 cannot connect; check your firewall or proxy settings."
+	default:
+		res.Latency = h3.Latency
+		res.Message = fmt.Sprintf("HTTP/3 works (%s, HTTP/2 %s)", h3.Latency.Round(time.Millisecond), h2.Latency.Round(time.Millisecond))
+	}
+}
+
+// probeHTTP3 completes a QUIC handshake with host:443 offering HTTP/3.
+func probeHTTP3(ctx context.Context, host string) protoProbe {
+	var p protoProbe
+	ep, err := quic.Listen("udp", ":0", nil)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = ep.Close(closeCtx)
+	}()
+	config := &quic.Config{TLSConfig: &tls.Config{ServerName: host, NextProtos: []string{"h3"}, MinVersion: tls.VersionTLS13}}
+	start := time.Now()
+	conn, err := ep.Dial(ctx, "udp", net.JoinHostPort(host, "443"), config)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	p.Latency = time.Since(start)
+	p.ALPN = conn.ConnectionState().NegotiatedProtocol
+	if err := conn.Close(); err != nil {
+		slog.Debug("could not close QUIC connection", "err", err)
+	}
+	return p
+}
+
+// probeHTTP2 completes a TLS handshake with host:443 offering HTTP/2.
+func probeHTTP2(ctx context.Context, host string) protoProbe {
+	var p protoProbe
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{}, Config: &tls.Config{ServerName: host, NextProtos: []string{"h2", "http/1.1"}}}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	p.Latency = time.Since(start)
+	p.ALPN = conn.(*tls.Conn).ConnectionState().NegotiatedProtocol
+	if err := conn.Close(); err != nil {
+		slog.Debug("could not close TLS connection", "err", err)
+	}
+	return p
+}
+
+// describeProtoProbe renders p as "35ms (h3)" or the reason it failed.
+func describeProtoProbe(p protoProbe) string {
+	if p.Err != nil {
+		msg, _ := classifyTLSError(p.Err)
+		return msg
+	}
+	s := p.Latency.Round(time.Millisecond).String()
+	if p.ALPN != "" {
+		s += " (" + p.ALPN + ")"
+	}
+	return s
+}
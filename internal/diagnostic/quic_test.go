@@ -0,0 +1,41 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGradeQUIC(t *testing.T) {
+	ok := protoProbe{Latency: 30 * time.Millisecond}
+	failed := protoProbe{Err: context.DeadlineExceeded}
+	tests := []struct {
+		name    string
+		h3, h2  protoProbe
+		status  Status
+		reason  Reason
+		latency time.Duration
+	}{
+		{"both work", ok, protoProbe{Latency: 45 * time.Millisecond}, StatusOk, "", 30 * time.Millisecond},
+		{"udp blocked", failed, protoProbe{Latency: 45 * time.Millisecond}, StatusWarning, ReasonQUICBlocked, 45 * time.Millisecond},
+		{"tcp blocked", ok, protoProbe{Err: errors.New("reset")}, StatusWarning, ReasonTLSFailed, 30 * time.Millisecond},
+		{"offline", failed, failed, StatusError, ReasonUnreachable, 0},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeQUIC(&res, "cloudflare.com", tt.h3, tt.h2)
+		if res.Status != tt.status || res.Reason != tt.reason || res.Latency != tt.latency {
+			t.Errorf("%s: expected %v/%q/%v, got %v/%q/%v (%s)", tt.name, tt.status, tt.reason, tt.latency, res.Status, res.Reason, res.Latency, res.Message)
+		}
+	}
+}
+
+func TestDescribeProtoProbe(t *testing.T) {
+	if got := describeProtoProbe(protoProbe{Latency: 35 * time.Millisecond, ALPN: "h3"}); got != "35ms (h3)" {
+		t.Errorf("Expected latency and protocol, got %q", got)
+	}
+	if got := describeProtoProbe(protoProbe{Err: context.DeadlineExceeded}); got != "Handshake timed out" {
+		t.Errorf("Expected a timeout, got %q", got)
+	}
+}
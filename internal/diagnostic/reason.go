@@ -0,0 +1,149 @@
+package diagnostic
+
+// Reason is a stable, machine-readable code for why a check is not OK.
+// Messages are for people and may change; reasons are for automation.
+type Reason string
+
+// Reasons set alongside Result.Message on warning, error, and skipped results,
+// and on OK results that were measured in a degraded way.
+const (
+	ReasonWeakSignal Reason = "weak_signal"
+	// ReasonOpenWiFi means the Wi-Fi network has no encryption.
+	ReasonOpenWiFi Reason = "open_wifi"
+	// ReasonWeakWiFiSecurity means the Wi-Fi network uses WEP or WPA.
+	ReasonWeakWiFiSecurity Reason = "weak_wifi_security"
+	// ReasonPMFDisabled means the Wi-Fi network does not protect management
+	// frames, so clients can be disconnected by forged frames.
+	ReasonPMFDisabled Reason = "pmf_disabled"
+	// ReasonEvilTwin means the Wi-Fi network appeared on an access point of
+	// an unknown vendor or with weaker security than on earlier runs.
+	ReasonEvilTwin Reason = "evil_twin"
+	// ReasonExposedService means a service of this machine listens on
+	// every interface while on Wi-Fi that strangers can join.
+	ReasonExposedService Reason = "exposed_service"
+	// ReasonEthernetSlowLink and ReasonEthernetHalfDuplex flag a wired link
+	// that negotiated below gigabit or at half duplex.
+	ReasonEthernetSlowLink   Reason = "ethernet_slow_link"
+	ReasonEthernetHalfDuplex Reason = "ethernet_half_duplex"
+	ReasonHighLatency        Reason = "high_latency"
+	// ReasonPacketLoss and ReasonHighJitter flag a ping burst with loss or
+	// round-trip variation above the configured limits.
+	ReasonPacketLoss Reason = "packet_loss"
+	ReasonHighJitter Reason = "high_jitter"
+	ReasonDNSSlow    Reason = "dns_slow"
+	// ReasonDNSFailed means the system resolver could not resolve a
+	// well-known name at all.
+	ReasonDNSFailed Reason = "dns_failed"
+	// ReasonDNSUDPBlocked means a resolver only answered over TCP/53.
+	ReasonDNSUDPBlocked Reason = "dns_udp_blocked"
+	// ReasonEncryptedDNSBlocked marks an OK DNS result where DoH or DoT
+	// failed with every provider while plain DNS worked.
+	ReasonEncryptedDNSBlocked Reason = "encrypted_dns_blocked"
+	// ReasonDNSSECNotValidated means the resolver accepted a forged signature.
+	ReasonDNSSECNotValidated Reason = "dnssec_not_validated"
+	// ReasonNXDOMAINRewritten means the resolver answers nonexistent names
+	// with an address, typically of an ISP search or ad page.
+	ReasonNXDOMAINRewritten Reason = "nxdomain_rewritten"
+	// ReasonDNSHijacked means the resolver answered a well-known name with
+	// an address it does not have.
+	ReasonDNSHijacked Reason = "dns_hijacked"
+	// ReasonDNSIntercepted means queries to port 53 of a public resolver
+	// were answered by something else on the path.
+	ReasonDNSIntercepted Reason = "dns_intercepted"
+	// ReasonDNSShadowed means a VPN's resolvers answer every name, so the
+	// local network's resolvers are never asked.
+	ReasonDNSShadowed Reason = "dns_shadowed"
+	// ReasonVPNDNSUnused means a VPN pushed resolvers that no query reaches.
+	ReasonVPNDNSUnused Reason = "vpn_dns_unused"
+	// ReasonNoMDNS means no other device answered a multicast DNS query.
+	ReasonNoMDNS        Reason = "no_mdns"
+	ReasonCaptivePortal Reason = "captive_portal"
+	ReasonNoRoute       Reason = "no_default_route"
+	// ReasonMultipleDefaultRoutes means several unscoped default routes compete.
+	ReasonMultipleDefaultRoutes Reason = "multiple_default_routes"
+	// ReasonSlowerInterface means traffic leaves over a slower kind of link
+	// than another connected one, such as Wi-Fi while a cable is plugged in.
+	ReasonSlowerInterface    Reason = "slower_interface"
+	ReasonGatewayUnreachable Reason = "gateway_unreachable"
+	// ReasonMTUBlackHole means large packets are dropped on the path
+	// without the ICMP feedback path MTU discovery needs.
+	ReasonMTUBlackHole Reason = "mtu_black_hole"
+	// ReasonICMPUnavailable means ping was not permitted, so latency was
+	// measured with a TCP connect instead; it is not unreachability.
+	ReasonICMPUnavailable Reason = "icmp_unavailable"
+	// ReasonIPv6Only means there is no IPv4 route, so the IPv6 path was
+	// measured instead.
+	ReasonIPv6Only Reason = "ipv6_only"
+	// ReasonIPv6Blackholed means IPv6 has a default route but carries no
+	// traffic, so dual-stack connections stall before falling back to IPv4.
+	ReasonIPv6Blackholed Reason = "ipv6_blackholed"
+	// ReasonNoAAAA means IPv6 works but the resolver returns no AAAA records.
+	ReasonNoAAAA            Reason = "dns_no_aaaa"
+	ReasonGatewayMACChanged Reason = "gateway_mac_changed"
+	// ReasonARPSpoofing means several MACs claim the gateway's address, or
+	// the gateway's MAC also answers for other addresses.
+	ReasonARPSpoofing Reason = "arp_spoofing"
+	ReasonOffline     Reason = "offline"
+	ReasonIPConflict  Reason = "ip_conflict"
+	// ReasonSelfAssignedIP means the interface gave itself a 169.254
+	// address because no DHCP server answered.
+	ReasonSelfAssignedIP Reason = "self_assigned_ip"
+	// ReasonDHCPMismatch means the address or gateway in use is not what
+	// the DHCP lease says.
+	ReasonDHCPMismatch Reason = "dhcp_mismatch"
+	// ReasonDHCPLeaseExpired means the lease ran out without being renewed.
+	ReasonDHCPLeaseExpired Reason = "dhcp_lease_expired"
+	ReasonDoubleNAT        Reason = "double_nat"
+	// ReasonPathFiltered means a router answered traceroute probes with
+	// "administratively prohibited" before the destination.
+	ReasonPathFiltered Reason = "path_filtered"
+	// ReasonCGNAT means the ISP shares one public address between customers.
+	ReasonCGNAT Reason = "cgnat"
+	// ReasonSymmetricNAT means the NAT maps each destination to a new port,
+	// which defeats peer-to-peer hole punching.
+	ReasonSymmetricNAT       Reason = "symmetric_nat"
+	ReasonDNSFiltered        Reason = "dns_filtered"
+	ReasonConnectionFiltered Reason = "connection_filtered"
+	ReasonUnreachable        Reason = "unreachable"
+	ReasonUploadStarved      Reason = "upload_starved"
+	// ReasonBandwidthHog means one process moves enough data to crowd out
+	// everything else, such as a backup uploading during a call.
+	ReasonBandwidthHog Reason = "bandwidth_hog"
+	// ReasonSlowThroughput means download or upload is below its floor.
+	ReasonSlowThroughput Reason = "slow_throughput"
+	// ReasonVPNOverhead means a full-tunnel VPN adds much latency over the
+	// direct path.
+	ReasonVPNOverhead Reason = "vpn_full_tunnel"
+	// ReasonBufferbloat means latency rises sharply while the link is busy.
+	ReasonBufferbloat Reason = "bufferbloat"
+	// ReasonProxyUnreachable means a configured proxy or PAC file cannot be
+	// reached, so browsers fail while the network itself works.
+	ReasonProxyUnreachable Reason = "proxy_unreachable"
+	ReasonUPnPEnabled      Reason = "upnp_enabled"
+	ReasonTelnetOpen       Reason = "telnet_open"
+	ReasonTLSFailed        Reason = "tls_handshake_failed"
+	ReasonCertExpired      Reason = "cert_expired"
+	ReasonCertNotYetValid  Reason = "cert_not_yet_valid"
+	ReasonCertSelfSigned   Reason = "cert_self_signed"
+	ReasonCertExpiring     Reason = "cert_expiring"
+	// ReasonTLSIntercepted means a public site's certificate was issued by
+	// something other than a public CA, as by a TLS-inspecting proxy.
+	ReasonTLSIntercepted Reason = "tls_intercepted"
+	// ReasonQUICBlocked means HTTP/3 fails where HTTP/2 to the same host
+	// works: UDP/443 is filtered.
+	ReasonQUICBlocked Reason = "quic_blocked"
+	// ReasonClockSkew means the local clock is off from an NTP server by
+	// more than max_clock_skew; ReasonNTPUnreachable that the NTP server
+	// did not answer.
+	ReasonClockSkew      Reason = "clock_skew"
+	ReasonNTPUnreachable Reason = "ntp_unreachable"
+	// ReasonServiceUnreachable means a configured service failed at the
+	// TCP, TLS or HTTP layer.
+	ReasonServiceUnreachable Reason = "service_unreachable"
+	ReasonBaselineRegression Reason = "baseline_regression"
+	// ReasonToolMissing marks a skipped check whose command is not installed.
+	ReasonToolMissing Reason = "tool_missing"
+	// ReasonProbeFailed covers local failures to gather data, such as
+	// unreadable interfaces or unparsable command output.
+	ReasonProbeFailed Reason = "probe_failed"
+)
@@ -0,0 +1,451 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options are the per-run settings passed to a Checker.
+type Options struct {
+	// Verbose requests protocol details in Result.Details.
+	Verbose bool
+}
+
+// Checker is a diagnostic that can be enumerated, selected by name and run.
+type Checker interface {
+	// Name is the stable identifier used by --only, --skip and the config.
+	Name() string
+	Run(ctx context.Context, opts Options) Result
+}
+
+// Check is a registered diagnostic step.
+type Check struct {
+	// Name is the stable identifier used by --config checks and the CLI.
+	Name string
+	// Explain says what the check measures and how it grades the outcome.
+	Explain string
+	// OptIn checks only run when selected explicitly, e.g. because they
+	// transfer data.
+	OptIn bool
+	// Run performs the check within ctx; verbose requests protocol details.
+	Run func(ctx context.Context, verbose bool) Result
+	// Fields lists the JSON result fields the check may populate beyond
+	// name, status and message.
+	Fields []string
+	// Thresholds maps what grades the check to its default value.
+	Thresholds map[string]string
+	// Reasons lists every Reason the check can report; empty when it is
+	// purely informational.
+	Reasons []Reason
+	// Weight is how much the check counts in the health score, relative to
+	// the default of 1 (zero).
+	Weight int
+}
+
+// Checker adapts c to the Checker interface.
+func (c Check) Checker() Checker {
+	return checkFunc{c}
+}
+
+type checkFunc struct {
+	c Check
+}
+
+func (f checkFunc) Name() string { return f.c.Name }
+
+// Run skips the check when ctx is already done rather than starting probes
+// that would fail at once, and stamps the result with the check's name.
+func (f checkFunc) Run(ctx context.Context, opts Options) Result {
+	if err := ctx.Err(); err != nil {
+		return Result{Name: f.c.Name, Check: f.c.Name, Status: StatusSkipped, Message: err.Error()}
+	}
+	r := f.c.Run(ctx, opts.Verbose)
+	r.Check = f.c.Name
+	return r
+}
+
+var registryMu sync.RWMutex
+
+// registry lists every check in display order.
+var registry = []Check{
+	{
+		Name:       "wifi",
+		Explain:    "Reads SSID, BSSID, RSSI, noise, transmit rate, channel, PHY mode and MCS index from system_profiler SPAirPortDataType (or CoreWLAN in builds with the corewlan tag, which has no MCS index) and the MTU from ifconfig. RSSI maps to 0-100% between -90 and -50 dBm; warning below min_signal_quality (30% by default).",
+		Run:        CheckL2WiFi,
+		Fields:     []string{"details", "fix", "facts." + FactSSID, "facts." + FactBSSID, "facts." + FactRSSI, "facts." + FactSignalQuality, "facts." + FactNoise, "facts." + FactTxRate, "facts." + FactChannel, "facts." + FactPHYMode, "facts." + FactMCS},
+		Thresholds: map[string]string{"min_signal_quality": strconv.Itoa(DefaultConfig().MinSignalQuality) + "%"},
+		Reasons:    []Reason{ReasonWeakSignal, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+		Weight:     3,
+	},
+	{
+		Name:    "wifi-security",
+		Explain: "Reads the security mode of the current Wi-Fi network (system_profiler or CoreWLAN on macOS, wpa_cli status on Linux) and protected management frames where reported; WPA3 and OWE always have them. Warning on open, WEP and WPA networks, and on WPA2 with PMF disabled.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckWiFiSecurity(ctx) },
+		Fields:  []string{"details", "fix", "facts." + FactWiFiSecurity},
+		Reasons: []Reason{ReasonOpenWiFi, ReasonWeakWiFiSecurity, ReasonPMFDisabled, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "evil-twin",
+		Explain: "With -v, records the access points (BSSIDs) and strongest security mode seen for the current SSID in wifi.known_aps_file. Warning when the SSID appears on a BSSID whose vendor prefix (OUI) it was never seen on, or with weaker security than before, as an evil twin copying a public network would.",
+		Run:     CheckEvilTwin,
+		Fields:  []string{"details", "fix", "facts." + FactSSID, "facts." + FactBSSID},
+		Reasons: []Reason{ReasonEvilTwin, ReasonNoRoute, ReasonToolMissing, ReasonProbeFailed},
+	},
+	{
+		Name:    "exposure",
+		Explain: "Run by wtfi audit. Lists the TCP ports this machine listens on and the UDP ports it has bound with lsof (ss on Linux), which only shows other users' processes as root. Warning when a service listens on all interfaces while on open, Enhanced Open, WEP or WPA Wi-Fi, where anyone nearby can connect; mDNS, the DHCP clients and UDP client sockets (ports 49152 and up) are not counted.",
+		OptIn:   true,
+		Run:     func(ctx context.Context, _ bool) Result { return CheckExposure(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonExposedService, ReasonToolMissing, ReasonProbeFailed},
+	},
+	{
+		Name:       "ethernet",
+		Explain:    "Reads the negotiated and supported media of the primary interface with ifconfig -m (ethtool on Linux). Skipped unless it is wired Ethernet; warning when the link negotiated half duplex, or below 1000baseT unless that is the adapter's maximum.",
+		Run:        func(ctx context.Context, _ bool) Result { return CheckL2Ethernet(ctx) },
+		Fields:     []string{"details", "fix"},
+		Thresholds: map[string]string{"min_link_speed": "1000 Mb/s"},
+		Reasons:    []Reason{ReasonEthernetSlowLink, ReasonEthernetHalfDuplex, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+		Weight:     3,
+	},
+	{
+		Name:    "routes",
+		Explain: "Reads the default route with route -n get default and lists interfaces that are up and look like VPNs or bridges (utun, wg, tun, bridge). Informational unless the interfaces cannot be read.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckRoutingTable(ctx) },
+		Fields:  []string{"details"},
+		Reasons: []Reason{ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+		Weight:  2,
+	},
+	{
+		Name:       "vpn",
+		Explain:    "Lists up tunnel interfaces (utun, wg, tun, tap, ipsec, ppp, tailscale) with a routable address, checks whether the default route and each nameserver in /etc/resolv.conf go through them, and pings wan_host once through the tunnel and once out of the physical interface. Warning when a full tunnel adds more than 50ms or passes no traffic.",
+		Run:        func(ctx context.Context, _ bool) Result { return CheckVPN(ctx) },
+		Fields:     []string{"latency_ms", "details", "fix"},
+		Thresholds: map[string]string{"overhead": vpnOverheadWarning.String()},
+		Reasons:    []Reason{ReasonVPNOverhead, ReasonUnreachable, ReasonProbeFailed},
+		Weight:     2,
+	},
+	{
+		Name:    "default-routes",
+		Explain: "Parses every default route from netstat -rn -f inet. Warning when more than one default is not interface-scoped, since only the first one wins.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckDefaultRoutes(ctx) },
+		Fields:  []string{"details", "fix", "facts." + FactDefaultRoutes},
+		Reasons: []Reason{ReasonMultipleDefaultRoutes, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "interfaces",
+		Explain: "Lists the interfaces that are up with an address, their kind (from the network service order on macOS, else the name), service order position and whether netstat -rn -f inet (ip route on Linux) shows a default route for them. Warning when traffic leaves over a slower kind of link (Ethernet, then Wi-Fi, USB tethering, Bluetooth) than another one with a default route. With --interface, every check follows that interface instead; error when it is not active.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckInterfaces(ctx) },
+		Fields:  []string{"details", "fix", "facts." + FactDefaultRoutes},
+		Reasons: []Reason{ReasonSlowerInterface, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "gateway",
+		Explain: "Pings the default gateway once with ICMP (2s timeout); latency is that round trip. Error when no reply arrives. A burst of ping.burst (10 by default) pings of ping.size, ping.interval (0.2s by default) apart, then measures loss and jitter; warning above max_loss (1%) or max_jitter (30ms). If ICMP is not permitted, latency is a TCP connect to port 443 or 80 instead. With no IPv4 default route, the IPv6 gateway from route -n get -inet6 default is pinged with ping6. Reads the gateway MAC from arp and names its vendor from an installed OUI database (ieee-data, hwdata or Wireshark's manuf); warning when several MACs claim the gateway or its MAC also answers for other addresses, as under ARP spoofing, or when it differs from the MAC recorded for this network on an earlier run (gateway.known_macs_file).",
+		Run:     CheckL3Gateway,
+		Fields:  []string{"latency_ms", "details", "fix", "fix_command", "facts." + FactGatewayMAC, "facts." + FactLoss, "facts." + FactJitter},
+		Thresholds: map[string]string{
+			"max_loss":   strconv.FormatFloat(DefaultConfig().MaxLoss, 'g', -1, 64) + "%",
+			"max_jitter": DefaultConfig().MaxJitter.String(),
+		},
+		Reasons: []Reason{ReasonGatewayUnreachable, ReasonPacketLoss, ReasonHighJitter, ReasonICMPUnavailable, ReasonIPv6Only, ReasonGatewayMACChanged, ReasonARPSpoofing, ReasonNoRoute, ReasonToolMissing},
+		Weight:  3,
+	},
+	{
+		Name:    "ip-conflict",
+		Explain: "Probes this machine's own IPv4 address with arping (or reads arp -a -n). Error when another MAC address answers for it.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckIPConflict(ctx) },
+		Fields:  []string{"details", "fix", "fix_command"},
+		Reasons: []Reason{ReasonIPConflict, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "dhcp",
+		Explain: "Reads the DHCP lease of the primary interface with ipconfig getpacket and getsummary (nmcli on Linux): server, lease length, time remaining, offered routers, DNS servers and domain. On Linux only NetworkManager leases are read; skipped when systemd-networkd or dhclient holds the lease. Error on a self-assigned 169.254 address, even without a default route; warning when the lease expired, or when the interface address or default gateway differs from the lease. Resolvers that differ from the offered ones are only noted.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckDHCP(ctx) },
+		Fields:  []string{"details", "fix", "fix_command"},
+		Reasons: []Reason{ReasonSelfAssignedIP, ReasonDHCPMismatch, ReasonDHCPLeaseExpired, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "gateway-security",
+		Explain: "Sends one SSDP M-SEARCH to 239.255.255.250:1900 and listens 2s for replies from the gateway, then tries TCP connects to ports 22, 23, 80, 443, 8080 and 8443 on it. Warning when UPnP answers or Telnet is open.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckGatewaySecurity(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonUPnPEnabled, ReasonTelnetOpen, ReasonNoRoute},
+	},
+	{
+		Name:    "router-admin",
+		Explain: "With -v, fetches https:// and then http:// on the gateway without verifying its certificate, and names the router's maker from the page title, Server header, authentication realm, certificate subject and the OUI vendor of its MAC. Informational: the message links to the web interface.",
+		Run:     CheckRouterAdmin,
+		Fields:  []string{"details", "fix_command", "facts." + FactRouterURL, "facts." + FactRouterVendor},
+		Reasons: []Reason{ReasonNoRoute},
+	},
+	{
+		Name:    "wan",
+		Explain: "Pings wan_host (1.1.1.1 by default) and 2606:4700:4700::1111 once each and connects to wan_host:443 in parallel, plus a burst of ping.burst (10 by default) ICMP packets of ping.size, ping.interval (0.2s by default) apart, for loss, jitter and min/avg/max RTT (and with --samples, a window of pings at 10/s for p50/p90/p99/max). Latency is the IPv4 RTT (TCP if ICMP is blocked or not permitted); warning above 150ms, above max_loss (1%) loss or above max_jitter (30ms) jitter, error when both ICMP and TCP fail. On IPv6-only networks the IPv6 ping and a TCP connect to [2606:4700:4700::1111]:443 are graded instead.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckL3WAN(ctx) },
+		Fields:  []string{"latency_ms", "details", "fix", "latency_percentiles", "facts." + FactLoss, "facts." + FactJitter},
+		Thresholds: map[string]string{
+			"high_latency": wanSlowThreshold.String(),
+			"max_loss":     strconv.FormatFloat(DefaultConfig().MaxLoss, 'g', -1, 64) + "%",
+			"max_jitter":   DefaultConfig().MaxJitter.String(),
+		},
+		Reasons: []Reason{ReasonHighLatency, ReasonPacketLoss, ReasonHighJitter, ReasonOffline, ReasonICMPUnavailable, ReasonIPv6Only},
+		Weight:  3,
+	},
+	{
+		Name:    "ipv6",
+		Explain: "Reads the IPv6 default route, then pings 2606:4700:4700::1111 with ICMPv6 and connects to it on port 443 in parallel with an AAAA lookup of google.com. OK without an IPv6 route; warning when the route exists but both probes fail while IPv4 works (broken dual-stack, which stalls Happy Eyeballs), error when there is no IPv4 to fall back on, and warning when IPv6 works but no AAAA records resolve.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckIPv6(ctx) },
+		Fields:  []string{"latency_ms", "details", "fix"},
+		Reasons: []Reason{ReasonIPv6Blackholed, ReasonNoAAAA},
+	},
+	{
+		Name:    "mtu",
+		Explain: "Reads the primary interface's MTU, then binary-searches the largest ping payload that reaches wan_host with the don't-fragment bit set, from the 576-byte IPv4 minimum up to the interface MTU (2s per ping). OK when the path carries the interface MTU, or a smaller one with \"fragmentation needed\" feedback; warning (MTU black hole) when larger packets vanish silently.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckPathMTU(ctx) },
+		Fields:  []string{"details", "fix", "fix_command"},
+		Reasons: []Reason{ReasonMTUBlackHole, ReasonICMPUnavailable, ReasonNoRoute, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:       "dns",
+		Explain:    "Resolves google.com through the system resolver and the dns resolvers (8.8.8.8 and 1.1.1.1 by default) in parallel, each under its own timeout, over UDP with a TCP retry when UDP fails, alongside DoH (RFC 8484 POST) and DoT (port 853) queries to Cloudflare, Google and Quad9. Latency is the system resolver's; warning above 200ms or when only TCP works. When every DoH or every DoT query fails while plain DNS works, the result stays OK with reason encrypted_dns_blocked.",
+		Run:        func(ctx context.Context, _ bool) Result { return CheckDNSBenchmark(ctx) },
+		Fields:     []string{"latency_ms", "details", "fix", "fix_command", "facts." + FactNameservers, "facts." + FactFastestResolver},
+		Thresholds: map[string]string{"slow_resolution": dnsSlowThreshold.String()},
+		Reasons:    []Reason{ReasonDNSFailed, ReasonDNSSlow, ReasonDNSUDPBlocked, ReasonEncryptedDNSBlocked},
+		Weight:     3,
+	},
+	{
+		Name:    "dnssec",
+		Explain: "Resolves internetsociety.org (validly signed) and dnssec-failed.org (deliberately broken signatures) through the system resolver and, over UDP, through each dns resolver of the benchmark. Validating when only the broken one fails; warning when both resolve through the system resolver, naming a benchmarked resolver that validates.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckDNSSEC(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonDNSSECNotValidated, ReasonProbeFailed},
+	},
+	{
+		Name:    "dns-hijack",
+		Explain: "Resolves a random name under example.com, which cannot exist, and one.one.one.one, which only resolves to Cloudflare's addresses, through the system resolver and directly through 1.1.1.1, and sends one query to 192.0.2.1 (TEST-NET-1), where no DNS server runs. Warning when a direct query or the decoy gets an answer it should not (port 53 intercepted), when the system resolver answers one.one.one.one wrongly (hijacked), or when it answers the nonexistent name (NXDOMAIN rewritten).",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckDNSHijack(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonDNSIntercepted, ReasonDNSHijacked, ReasonNXDOMAINRewritten, ReasonProbeFailed},
+	},
+	{
+		Name:    "split-dns",
+		Explain: "Reads the resolver configuration of every interface (scutil --dns on macOS, resolvectl status on Linux) and lists its nameservers, search domains and match domains. Warning when a VPN tunnel's resolvers answer every name while a local interface has other resolvers (shadowed), or when a tunnel has resolvers but neither match domains nor the default role (unused).",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckSplitDNS(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonDNSShadowed, ReasonVPNDNSUnused, ReasonProbeFailed, ReasonToolMissing},
+	},
+	{
+		Name:    "mdns",
+		Explain: "Sends a multicast DNS query for _services._dns-sd._udp.local to 224.0.0.251:5353 and collects replies for 1s, ignoring this machine's own. Warning when no other device answers, which hides printers, AirPlay and Chromecast; with -v, lists each device's service types.",
+		Run:     CheckMDNS,
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonNoMDNS, ReasonProbeFailed},
+	},
+	{
+		Name:    "relay",
+		Explain: "Resolves mask.icloud.com; an answer means iCloud Private Relay is active. Informational only.",
+		Run:     CheckPrivateRelay,
+		Fields:  []string{"latency_ms", "details"},
+		Reasons: []Reason{},
+	},
+	{
+		Name:       "trace",
+		Explain:    "With -v, runs traceroute to wan_host with ICMP, UDP or TCP/443 probes (trace mode, icmp by default), probes per hop up to max_ttl, and lists each hop's router, reverse DNS name, origin AS (from Team Cymru's DNS service) and best and average RTT. Without traceroute it falls back to TTL-limited pings. Warning when a router answers \"administratively prohibited\" before the destination.",
+		Run:        FastTraceroute,
+		Fields:     []string{"details"},
+		Thresholds: map[string]string{"max_ttl": strconv.Itoa(DefaultConfig().TraceMaxTTL), "mode": DefaultConfig().TraceMode},
+		Reasons:    []Reason{ReasonPathFiltered},
+	},
+	{
+		Name:    "double-nat",
+		Explain: "Maps the first 4 hops toward wan_host with TTL-limited pings, asks the gateway for its WAN address with NAT-PMP (UDP 5351) and the first STUN server for the public address. Warning for double NAT when two private routers precede the first public hop, when the router's WAN address is private or when it differs from the public address; warning for carrier-grade NAT when 100.64.0.0/10 shows up as the WAN address or in the hops.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckDoubleNAT(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonDoubleNAT, ReasonCGNAT, ReasonToolMissing},
+	},
+	{
+		Name:    "nat-type",
+		Explain: "Sends STUN binding requests from one UDP socket to stun.l.google.com and stun.cloudflare.com, then asks the first to answer from its other address. The same public port for both servers is a cone NAT, different ports a symmetric NAT. Warning for a symmetric NAT, or for carrier-grade NAT when 100.64.0.0/10 shows up on the interface, in the public address or in the first 4 hops toward wan_host.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckNATType(ctx) },
+		Fields:  []string{"details", "fix", "facts." + FactPublicIP, "facts." + FactNATType},
+		Reasons: []Reason{ReasonCGNAT, ReasonSymmetricNAT, ReasonUnreachable},
+	},
+	{
+		Name:    "public-ip",
+		Explain: "With -v, finds the public IPv4 address with STUN and the IPv4 and IPv6 addresses with an HTTPS echo endpoint (Cloudflare's /cdn-cgi/trace), then the reverse DNS name, the announcing AS and ISP (Team Cymru) and the country the address geolocates to. Informational; warning only when no public address can be found.",
+		Run:     CheckPublicIP,
+		Fields:  []string{"details", "facts." + FactPublicIP, "facts." + FactPublicIPv6, "facts." + FactISP, "facts." + FactCountry},
+		Reasons: []Reason{ReasonProbeFailed},
+	},
+	{
+		Name:    "filter",
+		Explain: "Reads the network's Low Data Mode flag, resolves filter_probe_host through the system resolver and 1.1.1.1, and connects to it on port 443. Warning when only the system resolver fails or the connection is blocked.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckContentFilter(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonDNSFiltered, ReasonConnectionFiltered, ReasonUnreachable},
+		Weight:  2,
+	},
+	{
+		Name:    "captive",
+		Explain: "Fetches captive.apple.com/hotspot-detect.html over HTTP (3s timeout). Warning when the page does not say Success, which means a login page intercepted it.",
+		Run:     CheckCaptivePortal,
+		Fields:  []string{"latency_ms", "details", "fix", "fix_command"},
+		Reasons: []Reason{ReasonCaptivePortal, ReasonProbeFailed},
+		Weight:  3,
+	},
+	{
+		Name:    "proxy",
+		Explain: "Reads the system proxy settings (scutil --proxy on macOS, the http_proxy family of variables on Linux), opens a TCP connection to every HTTP, HTTPS and SOCKS proxy and fetches the PAC file. Warning when a proxy refuses connections or the PAC file cannot be fetched.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckProxy(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonProxyUnreachable, ReasonToolMissing, ReasonProbeFailed},
+		Weight:  2,
+	},
+	{
+		Name:       "tls",
+		Explain:    "Completes a TLS handshake with tls_host:443 using the system trust store. Error on a failed handshake or expired certificate; warning when it expires within 14 days or is self-signed.",
+		Run:        func(ctx context.Context, verbose bool) Result { return CheckTLS(ctx, "", verbose) },
+		Fields:     []string{"latency_ms", "details", "fix"},
+		Thresholds: map[string]string{"cert_expiring": strconv.Itoa(int(certExpiryWarning.Hours()/24)) + " days"},
+		Reasons:    []Reason{ReasonTLSFailed, ReasonCertExpired, ReasonCertNotYetValid, ReasonCertSelfSigned, ReasonCertExpiring},
+		Weight:     2,
+	},
+	{
+		Name:       "time",
+		Explain:    "Reads the NTP server the clock synchronizes with from systemsetup -getnetworktimeserver (timedatectl show-timesync on Linux), or uses ntp_server, and sends it one SNTP query; when it does not answer, pool.ntp.org is queried instead. Latency is the NTP round trip. Warning when the clock is off by more than max_clock_skew or the server is unreachable; error when it is off by 5 minutes or more, where TLS, Kerberos and SSO start failing.",
+		Run:        func(ctx context.Context, _ bool) Result { return CheckTimeSync(ctx) },
+		Fields:     []string{"latency_ms", "details", "fix"},
+		Thresholds: map[string]string{"max_clock_skew": DefaultConfig().MaxClockSkew.String(), "critical": clockSkewCritical.String()},
+		Reasons:    []Reason{ReasonClockSkew, ReasonNTPUnreachable},
+	},
+	{
+		Name:    "tls-intercept",
+		Explain: "Completes a TLS handshake with each of tls_inspect_hosts (apple.com and google.com by default) in parallel, then verifies the chain against the system roots, and lists the protocol, handshake time and certificate issuer of each; latency is the slowest handshake. Warning when an issuer is not a well-known public CA, trusted or not, the sign of HTTPS inspection by a proxy or security agent, or when a handshake fails or a public CA's chain does not verify; error when all handshakes fail.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckTLSInterception(ctx) },
+		Fields:  []string{"latency_ms", "details", "fix"},
+		Reasons: []Reason{ReasonTLSIntercepted, ReasonTLSFailed},
+	},
+	{
+		Name:    "quic",
+		Explain: "Completes an HTTP/3 handshake (QUIC over UDP/443) and an HTTP/2 handshake (TLS over TCP/443) with quic_host (cloudflare.com by default) in parallel; latency is the HTTP/3 handshake. Warning when HTTP/3 fails while HTTP/2 works, meaning UDP/443 is blocked, or the other way round; error when both fail.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckQUIC(ctx) },
+		Fields:  []string{"latency_ms", "details", "fix"},
+		Reasons: []Reason{ReasonQUICBlocked, ReasonTLSFailed, ReasonUnreachable},
+	},
+	{
+		Name:    "services",
+		Explain: "Skipped unless services lists endpoints, as \"host:port\" or an http(s) URL. Connects to each over TCP in parallel, completes a TLS handshake on TLS ports (443, 465, 636, 853, 993, 995, 8443...) and https URLs, then sends a GET on web ports and to URLs; any status below 500 passes. Details are a pass/fail matrix per layer. Warning when a service fails a layer; error when all do.",
+		Run:     func(ctx context.Context, _ bool) Result { return CheckServices(ctx) },
+		Fields:  []string{"details", "fix"},
+		Reasons: []Reason{ReasonServiceUnreachable},
+	},
+	{
+		Name:    "top-talkers",
+		Explain: "With -v, or as wtfi top, reads every process's byte counters twice, 2s apart, with nettop (macOS only) and lists the five processes moving the most data. Warning when one process downloads or uploads at 10 Mbps or more, enough to make calls choppy on a typical home line.",
+		Run:     CheckTalkers,
+		Fields:  []string{"details", "fix", "facts." + FactTopTalker},
+		Reasons: []Reason{ReasonBandwidthHog, ReasonToolMissing, ReasonProbeFailed},
+	},
+	{
+		Name:       "speed",
+		Explain:    "Downloads download_size from speed.download_url and uploads upload_size to speed.upload_url (speed.cloudflare.com by default), or runs iperf3 for 5s each way when speed.iperf_server is set. Warning when download or upload is below min_download_mbps or min_upload_mbps, or upload is below min_upload_ratio of download, and when one direction fails; error when both fail.",
+		OptIn:      true,
+		Run:        func(ctx context.Context, _ bool) Result { return CheckThroughput(ctx) },
+		Fields:     []string{"details", "fix", "facts." + FactDownloadMbps, "facts." + FactUploadMbps},
+		Thresholds: map[string]string{"min_upload_ratio": strconv.FormatFloat(DefaultConfig().MinUploadRatio, 'g', -1, 64)},
+		Reasons:    []Reason{ReasonSlowThroughput, ReasonUploadStarved, ReasonUnreachable, ReasonToolMissing},
+	},
+	{
+		Name:       "bufferbloat",
+		Explain:    "Pings wan_host 20 times at 10/s while idle, then again while 4 parallel transfers saturate the download and then the upload through the HTTP speed endpoints. Latency is the largest rise of the median; it is graded like Waveform's test (A+ up to 5ms, A 30ms, B 60ms, C 200ms, D 400ms, F beyond) with a warning from C on.",
+		OptIn:      true,
+		Run:        func(ctx context.Context, _ bool) Result { return CheckBufferbloat(ctx) },
+		Fields:     []string{"latency_ms", "details", "fix", "facts." + FactBufferbloatGrade},
+		Thresholds: map[string]string{"bufferbloat": bufferbloatWarning.String()},
+		Reasons:    []Reason{ReasonBufferbloat, ReasonUnreachable, ReasonProbeFailed},
+	},
+}
+
+// Checks returns every registered check in display order.
+func Checks() []Check {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Check(nil), registry...)
+}
+
+// LookupCheck returns the registered check called name.
+func LookupCheck(name string) (Check, bool) {
+	for _, c := range Checks() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Check{}, false
+}
+
+// SelectChecks returns the registered checks in display order, limited to
+// only when it is non-empty, plus those in optIn, and without those in skip.
+// Opt-in checks therefore run only when named. Unknown names are an error.
+func SelectChecks(only, skip, optIn []string) ([]Check, error) {
+	all := Checks()
+	for _, name := range slices.Concat(only, skip, optIn) {
+		if !slices.ContainsFunc(all, func(c Check) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("unknown check %q (valid checks: %s)", name, strings.Join(checkNames(all), ", "))
+		}
+	}
+
+	var selected []Check
+	for _, c := range all {
+		on := !c.OptIn
+		if len(only) > 0 {
+			on = slices.Contains(only, c.Name)
+		}
+		if (on || slices.Contains(optIn, c.Name)) && !slices.Contains(skip, c.Name) {
+			selected = append(selected, c)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no checks left to run")
+	}
+	return selected, nil
+}
+
+func checkNames(checks []Check) []string {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Register adds ch after the built-in checks, described by explain. It
+// fails when the name is empty or already taken.
+func Register(ch Checker, explain string) error {
+	name := ch.Name()
+	if name == "" {
+		return fmt.Errorf("check name must not be empty")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, c := range registry {
+		if c.Name == name {
+			return fmt.Errorf("check %q is already registered", name)
+		}
+	}
+	registry = append(registry, Check{
+		Name:    name,
+		Explain: explain,
+		Run: func(ctx context.Context, verbose bool) Result {
+			return ch.Run(ctx, Options{Verbose: verbose})
+		},
+		Fields:  []string{"details"},
+		Reasons: []Reason{},
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range Checks() {
+		if c.Name == "" || c.Explain == "" || c.Run == nil {
+			t.Errorf("Incomplete registration %+v", c)
+		}
+		// Informational checks declare an empty, non-nil Reasons list.
+		if len(c.Fields) == 0 || c.Reasons == nil {
+			t.Errorf("Check %q is missing metadata (fields %v, reasons %v)", c.Name, c.Fields, c.Reasons)
+		}
+		for _, r := range c.Reasons {
+			if r == "" {
+				t.Errorf("Check %q lists an empty reason", c.Name)
+			}
+		}
+		if seen[c.Name] {
+			t.Errorf("Duplicate check name %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+}
+
+type staticChecker struct {
+	name string
+}
+
+func (s staticChecker) Name() string { return s.name }
+
+func (s staticChecker) Run(_ context.Context, opts Options) Result {
+	res := Result{Name: "Static", Status: StatusOk, Message: "fine"}
+	if opts.Verbose {
+		res.Details = []string{"verbose"}
+	}
+	return res
+}
+
+func TestRegister(t *testing.T) {
+	prev := Checks()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = prev
+		registryMu.Unlock()
+	})
+
+	if err := Register(staticChecker{"static"}, "Always fine."); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := Register(staticChecker{"wifi"}, ""); err == nil {
+		t.Error("Expected a duplicate name to be rejected")
+	}
+	if err := Register(staticChecker{""}, ""); err == nil {
+		t.Error("Expected an empty name to be rejected")
+	}
+
+	c, ok := LookupCheck("static")
+	if !ok {
+		t.Fatal("Expected the registered check to be found")
+	}
+	if res := c.Checker().Run(context.Background(), Options{Verbose: true}); len(res.Details) != 1 {
+		t.Errorf("Expected the options to reach the checker, got %+v", res)
+	}
+	if all := Checks(); all[len(all)-1].Name != "static" {
+		t.Errorf("Expected the check to follow the built-ins, got %q last", all[len(all)-1].Name)
+	}
+}
+
+func TestCheckerCancelled(t *testing.T) {
+	c, _ := LookupCheck("wifi")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := c.Checker().Run(ctx, Options{}); res.Status != StatusSkipped {
+		t.Errorf("Expected a cancelled run to be skipped, got %v", res.Status)
+	}
+}
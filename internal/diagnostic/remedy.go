@@ -0,0 +1,292 @@
+package diagnostic
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Remedy is one step of the guided fix: commands that may cure a problem a
+// run found, and the checks that show whether they did.
+type Remedy struct {
+	Title string
+	// Why is the result that calls for the remedy, as "Name: Message".
+	Why string
+	// Commands run in order; Privileged ones need root.
+	Commands   [][]string
+	Privileged bool
+	// Settle is how long the network needs to recover before Verify runs.
+	Settle time.Duration
+	// Verify names the checks re-run to confirm the remedy worked.
+	Verify []string
+	// Undo restores what Commands changed; nil when there is nothing to
+	// restore, as after flushing a cache.
+	Undo [][]string
+}
+
+// Shell renders the commands as one copy-pasteable line.
+func (r Remedy) Shell() string {
+	return shellLine(r.Commands, r.Privileged)
+}
+
+// shellLine joins cmds with &&, prefixing each with sudo when privileged.
+func shellLine(cmds [][]string, privileged bool) string {
+	lines := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		words := make([]string, len(cmd))
+		for j, w := range cmd {
+			words[j] = w
+			if !shellSafe.MatchString(w) {
+				words[j] = shellQuote(w)
+			}
+		}
+		lines[i] = strings.Join(words, " ")
+		if privileged {
+			lines[i] = "sudo " + lines[i]
+		}
+	}
+	return strings.Join(lines, " && ")
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+-]+$`)
+
+// networkService is one entry of the macOS network service order.
+type networkService struct {
+	Name string
+	// Port is the hardware port, such as "Wi-Fi" or "USB 10/100/1000 LAN".
+	Port   string
+	Device string
+}
+
+// systemState is what PlanRemedies reads from the system beyond the results.
+type systemState struct {
+	iface string
+	// order is the network service order, where the platform has one.
+	order []networkService
+	// dnsServers are the DNS servers set on iface's service; they are only
+	// known when dnsServersRead, since nil also means none are set.
+	dnsServers     []string
+	dnsServersRead bool
+	// dhcpClient holds iface's lease, as dhcpClient names it.
+	dhcpClient string
+}
+
+// PlanRemedies returns the remedies wtfi can apply for the problems in
+// results, lowest layer first. It consults the system for the primary
+// interface, the DHCP client holding its lease, the network service order
+// and the DNS servers set by hand.
+func PlanRemedies(ctx context.Context, results []Result) []Remedy {
+	v := newRunView(results)
+	p := activePlatform()
+	var sys systemState
+	sys.iface, _ = getPrimaryInterface(ctx)
+	if sys.iface != "" {
+		sys.dhcpClient = dhcpClient(ctx, sys.iface)
+	}
+	if cmd := p.serviceOrderCommand(); cmd != nil {
+		if out, err := runPlatformCommand(ctx, cmd); err == nil {
+			sys.order = parseNetworkServiceOrder(string(out))
+		}
+	}
+	if service, ok := serviceOf(p, sys); ok && resolverFailing(v) {
+		if out, err := runPlatformCommand(ctx, p.dnsServersCommand(service)); err == nil {
+			sys.dnsServers, sys.dnsServersRead = p.parseDNSServers(string(out)), true
+		}
+	}
+	return planRemedies(p, v, sys)
+}
+
+// serviceOf names what the DNS servers of sys.iface are set on: its network
+// service where the platform has a service order, else the interface.
+func serviceOf(p platform, sys systemState) (string, bool) {
+	if sys.iface == "" {
+		return "", false
+	}
+	if p.serviceOrderCommand() == nil {
+		return sys.iface, true
+	}
+	i := slices.IndexFunc(sys.order, func(s networkService) bool { return s.Device == sys.iface })
+	if i < 0 {
+		return "", false
+	}
+	return sys.order[i].Name, true
+}
+
+// planRemedies is PlanRemedies once the system has been consulted.
+func planRemedies(p platform, v runView, sys systemState) []Remedy {
+	var remedies []Remedy
+	iface := sys.iface
+	associated := v.ran("wifi") && v["wifi"].Facts[FactSSID] != ""
+	if associated && iface != "" && v.reason("gateway", ReasonGatewayUnreachable, ReasonPacketLoss) {
+		remedies = append(remedies, Remedy{
+			Title:    "Turn Wi-Fi off and on to rejoin the access point",
+			Why:      v.describe("gateway"),
+			Commands: [][]string{p.wifiPowerCommand(iface, false), p.wifiPowerCommand(iface, true)},
+			Settle:   10 * time.Second,
+			Verify:   []string{"wifi", "gateway"},
+		})
+	}
+	renew := v.reason("gateway", ReasonGatewayUnreachable) || v.reason("ip-conflict", ReasonIPConflict) ||
+		v.reason("dhcp", ReasonSelfAssignedIP, ReasonDHCPMismatch, ReasonDHCPLeaseExpired)
+	if cmds := p.renewDHCPCommands(sys.dhcpClient, iface); iface != "" && renew && cmds != nil {
+		remedies = append(remedies, Remedy{
+			Title:      "Renew the DHCP lease on " + iface,
+			Why:        v.describe(firstFailed(v, "dhcp", "ip-conflict", "gateway")),
+			Commands:   cmds,
+			Privileged: true,
+			Settle:     5 * time.Second,
+			Verify:     []string{"dhcp", "gateway", "ip-conflict"},
+		})
+	}
+	routesCheck := firstFailed(v, "default-routes", "interfaces")
+	routes := strings.Split(v[routesCheck].Facts[FactDefaultRoutes], ",")
+	wifiWins := v.reason("default-routes", ReasonMultipleDefaultRoutes) || v.reason("interfaces", ReasonSlowerInterface)
+	if promoted, ok := promoteWired(sys.order, routes); ok && wifiWins {
+		remedies = append(remedies, Remedy{
+			Title:      "Prefer " + promoted[0].Name + " over Wi-Fi in the network service order",
+			Why:        v.describe(routesCheck),
+			Commands:   [][]string{p.setServiceOrderCommand(serviceNames(promoted))},
+			Privileged: true,
+			Settle:     3 * time.Second,
+			Verify:     []string{"default-routes", "interfaces"},
+			Undo:       [][]string{p.setServiceOrderCommand(serviceNames(sys.order))},
+		})
+	}
+	// Lookups that fail because a portal holds them are not cured by a
+	// fresh cache or another resolver.
+	if !resolverFailing(v) || v.reason("captive", ReasonCaptivePortal) {
+		return remedies
+	}
+	remedies = append(remedies, Remedy{
+		Title:      "Flush the DNS cache",
+		Why:        v.describe("dns"),
+		Commands:   p.flushDNSCommands(),
+		Privileged: true,
+		Settle:     time.Second,
+		Verify:     []string{"dns"},
+	})
+	// Switching is only offered when the current servers are known, or its
+	// undo would erase servers set by hand or pushed by the network manager.
+	faster := v["dns"].Facts[FactFastestResolver]
+	if service, ok := serviceOf(p, sys); ok && sys.dnsServersRead && faster != "" && !slices.Contains(sys.dnsServers, faster) {
+		remedies = append(remedies, Remedy{
+			Title:      "Switch the DNS servers of " + service + " to " + faster,
+			Why:        v.describe("dns"),
+			Commands:   [][]string{p.setDNSServersCommand(service, []string{faster})},
+			Privileged: true,
+			Settle:     time.Second,
+			Verify:     []string{"dns"},
+			Undo:       [][]string{p.setDNSServersCommand(service, sys.dnsServers)},
+		})
+	}
+	return remedies
+}
+
+// resolverFailing reports whether the system resolver was slow or failed
+// lookups outright. Lookups that fail because the path does not carry UDP
+// report ReasonDNSUDPBlocked instead, which no resolver change cures.
+func resolverFailing(v runView) bool {
+	return v.reason("dns", ReasonDNSSlow, ReasonDNSFailed)
+}
+
+func serviceNames(services []networkService) []string {
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// firstFailed returns the first of checks that failed, or the last one.
+func firstFailed(v runView, checks ...string) string {
+	for _, c := range checks {
+		if v.failed(c) {
+			return c
+		}
+	}
+	return checks[len(checks)-1]
+}
+
+// promoteWired moves the first wired service with a default route among
+// devices ahead of the services in order, when Wi-Fi currently outranks
+// it. It reports false when there is nothing to reorder.
+func promoteWired(order []networkService, devices []string) ([]networkService, bool) {
+	routed := func(s networkService) bool { return s.Device != "" && slices.Contains(devices, s.Device) }
+	first := slices.IndexFunc(order, routed)
+	if first < 0 || order[first].Port != "Wi-Fi" {
+		return nil, false
+	}
+	wired := slices.IndexFunc(order, func(s networkService) bool { return routed(s) && s.Port != "Wi-Fi" })
+	if wired < 0 {
+		return nil, false
+	}
+	promoted := append([]networkService{order[wired]}, order[:wired]...)
+	return append(promoted, order[wired+1:]...), true
+}
+
+// parseResolvectlDNS reads `resolvectl dns <iface>`, which lists the
+// link's servers after its name: "Link 2 (wlan0): 192.168.1.1 1.1.1.1".
+func parseResolvectlDNS(out string) []string {
+	var servers []string
+	for _, line := range strings.Split(out, "\n") {
+		_, list, ok := strings.Cut(line, "):")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(list) {
+			if ip := net.ParseIP(strings.SplitN(f, "%", 2)[0]); ip != nil {
+				servers = append(servers, f)
+			}
+		}
+	}
+	return servers
+}
+
+// parseNetworksetupDNS reads `networksetup -getdnsservers`, which lists one
+// address per line or explains that none are set.
+func parseNetworksetupDNS(out string) []string {
+	var servers []string
+	for _, line := range strings.Split(out, "\n") {
+		if ip := net.ParseIP(strings.TrimSpace(line)); ip != nil {
+			servers = append(servers, ip.String())
+		}
+	}
+	return servers
+}
+
+var serviceEntry = regexp.MustCompile(`^\((?:\d+|\*)\)\s+(.+)$`)
+var servicePort = regexp.MustCompile(`^\(Hardware Port: (.*), Device: (.*)\)$`)
+
+// parseNetworkServiceOrder reads `networksetup -listnetworkserviceorder`:
+//
+//	(1) Wi-Fi
+//	(Hardware Port: Wi-Fi, Device: en0)
+//
+// Disabled services are numbered "(*)" and are kept, since the new order
+// must name every service.
+func parseNetworkServiceOrder(out string) []networkService {
+	var services []networkService
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if m := servicePort.FindStringSubmatch(line); m != nil {
+			if len(services) > 0 {
+				services[len(services)-1].Port, services[len(services)-1].Device = m[1], m[2]
+			}
+			continue
+		}
+		if m := serviceEntry.FindStringSubmatch(line); m != nil {
+			services = append(services, networkService{Name: m[1]})
+		}
+	}
+	return services
+}
@@ -0,0 +1,181 @@
+package diagnostic
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanRemedies(t *testing.T) {
+	wifi := Result{Check: "wifi", Name: "Wi-Fi", Status: StatusOk, Facts: map[string]string{FactSSID: "home"}}
+	ok := func(check string) Result { return Result{Check: check, Name: check, Status: StatusOk} }
+	bad := func(check string, reason Reason) Result {
+		return Result{Check: check, Name: check, Status: StatusWarning, Reason: reason, Message: string(reason)}
+	}
+	routes := bad("default-routes", ReasonMultipleDefaultRoutes)
+	routes.Facts = map[string]string{FactDefaultRoutes: "en0,en7"}
+	order := []networkService{{"Wi-Fi", "Wi-Fi", "en0"}, {"Bluetooth PAN", "Bluetooth PAN", "en3"}, {"USB LAN", "USB 10/100/1000 LAN", "en7"}}
+	slower := bad("interfaces", ReasonSlowerInterface)
+	slower.Facts = map[string]string{FactDefaultRoutes: "en0,en7"}
+	slowDNS := bad("dns", ReasonDNSSlow)
+	slowDNS.Facts = map[string]string{FactFastestResolver: "1.1.1.1"}
+
+	tests := []struct {
+		name    string
+		results []Result
+		want    []string
+	}{
+		{"healthy", []Result{wifi, ok("gateway"), ok("dns")}, nil},
+		{"slow dns", []Result{wifi, ok("gateway"), bad("dns", ReasonDNSSlow)}, []string{"Flush the DNS cache"}},
+		{"faster resolver", []Result{wifi, ok("gateway"), slowDNS}, []string{"Flush the DNS cache", "Switch the DNS servers of Wi-Fi to 1.1.1.1"}},
+		{"udp blocked", []Result{wifi, ok("gateway"), bad("dns", ReasonDNSUDPBlocked)}, nil},
+		{
+			"gateway unreachable on wi-fi",
+			[]Result{wifi, bad("gateway", ReasonGatewayUnreachable)},
+			[]string{"Turn Wi-Fi off and on to rejoin the access point", "Renew the DHCP lease on en0"},
+		},
+		{"ip conflict on ethernet", []Result{ok("ethernet"), bad("ip-conflict", ReasonIPConflict)}, []string{"Renew the DHCP lease on en0"}},
+		{"wi-fi outranks wired", []Result{wifi, routes}, []string{"Prefer USB LAN over Wi-Fi in the network service order"}},
+		{"wi-fi slower than cable", []Result{wifi, slower}, []string{"Prefer USB LAN over Wi-Fi in the network service order"}},
+		{"skipped", []Result{{Check: "dns", Status: StatusSkipped, Reason: ReasonDNSSlow}}, nil},
+	}
+	for _, tt := range tests {
+		remedies := planRemedies(darwinPlatform{}, newRunView(tt.results), systemState{iface: "en0", order: order, dnsServersRead: true})
+		var titles []string
+		for _, r := range remedies {
+			titles = append(titles, r.Title)
+		}
+		if !slices.Equal(titles, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, titles)
+		}
+	}
+}
+
+func TestPlanRemediesLinuxRenew(t *testing.T) {
+	gateway := Result{Check: "gateway", Name: "Gateway", Status: StatusError, Reason: ReasonGatewayUnreachable}
+	tests := []struct {
+		client string
+		want   string
+	}{
+		{dhcpClientNetworkManager, "sudo nmcli device connect eth0"},
+		{dhcpClientDhclient, "sudo dhclient -r eth0 && sudo dhclient eth0"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		remedies := planRemedies(linuxPlatform{}, newRunView([]Result{gateway}), systemState{iface: "eth0", dhcpClient: tt.client})
+		got := ""
+		if len(remedies) > 0 {
+			got = remedies[0].Shell()
+		}
+		if got != tt.want {
+			t.Errorf("client %q: expected %q, got %q", tt.client, tt.want, got)
+		}
+	}
+}
+
+func TestPlanRemediesFailingResolver(t *testing.T) {
+	sys := systemState{iface: "en0", order: []networkService{{"Wi-Fi", "Wi-Fi", "en0"}}, dnsServersRead: true}
+	dns := benchmarkFailingSystem(t, true)
+	remedies := planRemedies(darwinPlatform{}, newRunView([]Result{dns}), sys)
+	var titles []string
+	for _, r := range remedies {
+		titles = append(titles, r.Title)
+	}
+	want := []string{"Flush the DNS cache", "Switch the DNS servers of Wi-Fi to 192.0.2.53"}
+	if !slices.Equal(titles, want) {
+		t.Errorf("expected %q for a failing system resolver, got %q", want, titles)
+	}
+}
+
+func TestPlanRemediesUndo(t *testing.T) {
+	dns := Result{Check: "dns", Name: "DNS", Status: StatusWarning, Reason: ReasonDNSSlow, Facts: map[string]string{FactFastestResolver: "1.1.1.1"}}
+	tests := []struct {
+		name    string
+		p       platform
+		servers []string
+		want    string
+	}{
+		{"macos automatic", darwinPlatform{}, nil, "networksetup -setdnsservers Wi-Fi Empty"},
+		{"macos by hand", darwinPlatform{}, []string{"9.9.9.9"}, "networksetup -setdnsservers Wi-Fi 9.9.9.9"},
+		{"linux", linuxPlatform{}, nil, "resolvectl revert en0"},
+	}
+	for _, tt := range tests {
+		sys := systemState{iface: "en0", order: []networkService{{"Wi-Fi", "Wi-Fi", "en0"}}, dnsServers: tt.servers, dnsServersRead: true}
+		remedies := planRemedies(tt.p, newRunView([]Result{dns}), sys)
+		last := remedies[len(remedies)-1]
+		if got := shellLine(last.Undo, false); got != tt.want {
+			t.Errorf("%s: expected undo %q, got %q", tt.name, tt.want, got)
+		}
+	}
+	sys := systemState{iface: "en0", order: []networkService{{"Wi-Fi", "Wi-Fi", "en0"}}, dnsServers: []string{"1.1.1.1"}, dnsServersRead: true}
+	if remedies := planRemedies(darwinPlatform{}, newRunView([]Result{dns}), sys); len(remedies) != 1 {
+		t.Errorf("expected no switch to the resolver already set, got %+v", remedies)
+	}
+	sys = systemState{iface: "en0", order: []networkService{{"Wi-Fi", "Wi-Fi", "en0"}}}
+	if remedies := planRemedies(darwinPlatform{}, newRunView([]Result{dns}), sys); len(remedies) != 1 {
+		t.Errorf("expected no switch when the current servers are unknown, got %+v", remedies)
+	}
+}
+
+func TestParseResolvectlDNS(t *testing.T) {
+	if got := parseResolvectlDNS("Link 2 (wlan0):\n"); got != nil {
+		t.Errorf("expected no servers, got %q", got)
+	}
+	if got := parseResolvectlDNS("Link 2 (wlan0): 192.168.1.1 fe80::1%wlan0 2606:4700:4700::1111\n"); !slices.Equal(got, []string{"192.168.1.1", "fe80::1%wlan0", "2606:4700:4700::1111"}) {
+		t.Errorf("unexpected servers %q", got)
+	}
+}
+
+func TestParseNetworksetupDNS(t *testing.T) {
+	if got := parseNetworksetupDNS("There aren't any DNS Servers set on Wi-Fi.\n"); got != nil {
+		t.Errorf("expected no servers, got %q", got)
+	}
+	if got := parseNetworksetupDNS("1.1.1.1\n2606:4700:4700::1111\n"); !slices.Equal(got, []string{"1.1.1.1", "2606:4700:4700::1111"}) {
+		t.Errorf("unexpected servers %q", got)
+	}
+}
+
+func TestRemedyShell(t *testing.T) {
+	r := Remedy{Commands: darwinPlatform{}.flushDNSCommands(), Privileged: true}
+	if got, want := r.Shell(), "sudo dscacheutil -flushcache && sudo killall -HUP mDNSResponder"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	r = Remedy{Commands: [][]string{darwinPlatform{}.setServiceOrderCommand([]string{"USB LAN", "Wi-Fi"})}}
+	if got, want := r.Shell(), "networksetup -ordernetworkservices 'USB LAN' Wi-Fi"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPromoteWired(t *testing.T) {
+	order := []networkService{{"Wi-Fi", "Wi-Fi", "en0"}, {"VPN", "", ""}, {"Dock", "Thunderbolt Ethernet", "en8"}}
+	got, ok := promoteWired(order, []string{"en0", "en8"})
+	if !ok || got[0].Name != "Dock" || len(got) != 3 || got[1].Name != "Wi-Fi" || got[2].Name != "VPN" {
+		t.Errorf("expected Dock first, got %+v (%v)", got, ok)
+	}
+	if _, ok := promoteWired(order, []string{"en0"}); ok {
+		t.Error("expected no reordering without a wired default route")
+	}
+	if _, ok := promoteWired(got, []string{"en0", "en8"}); ok {
+		t.Error("expected no reordering when the wired service already leads")
+	}
+}
+
+func TestParseNetworkServiceOrder(t *testing.T) {
+	out := `An asterisk (*) denotes that a network service is disabled.
+(1) Wi-Fi
+(Hardware Port: Wi-Fi, Device: en0)
+
+(*) Bluetooth PAN
+(Hardware Port: Bluetooth PAN, Device: en3)
+
+(2) USB 10/100/1000 LAN
+(Hardware Port: USB 10/100/1000 LAN, Device: en7)
+`
+	want := []networkService{
+		{"Wi-Fi", "Wi-Fi", "en0"},
+		{"Bluetooth PAN", "Bluetooth PAN", "en3"},
+		{"USB 10/100/1000 LAN", "USB 10/100/1000 LAN", "en7"},
+	}
+	if got := parseNetworkServiceOrder(out); !slices.Equal(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
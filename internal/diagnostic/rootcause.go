@@ -0,0 +1,328 @@
+package diagnostic
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Confidence is how strongly the results point at a Cause.
+type Confidence int
+
+// Confidence levels, weakest first.
+const (
+	ConfidenceLow Confidence = iota
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceHigh:
+		return "high"
+	case ConfidenceMedium:
+		return "medium"
+	}
+	return "low"
+}
+
+// Cause is a probable root cause drawn from several results at once.
+type Cause struct {
+	Summary    string
+	Confidence Confidence
+	// Evidence are the results behind the conclusion, as "Name: Message".
+	Evidence []string
+	Fix      string
+}
+
+// runView indexes the results of a run by check name.
+type runView map[string]Result
+
+func newRunView(results []Result) runView {
+	v := runView{}
+	for _, r := range results {
+		if r.Check != "" {
+			v[r.Check] = r
+		}
+	}
+	return v
+}
+
+func (v runView) ran(check string) bool {
+	r, ok := v[check]
+	return ok && r.Status != StatusSkipped
+}
+
+func (v runView) ok(check string) bool { return v.ran(check) && v[check].Status == StatusOk }
+
+func (v runView) failed(check string) bool {
+	return v.ran(check) && (v[check].Status == StatusWarning || v[check].Status == StatusError)
+}
+
+func (v runView) errored(check string) bool { return v.ran(check) && v[check].Status == StatusError }
+
+// reason reports whether check ran and reported one of reasons.
+func (v runView) reason(check string, reasons ...Reason) bool {
+	return v.ran(check) && slices.Contains(reasons, v[check].Reason)
+}
+
+// evidence describes the results of checks that ran.
+func (v runView) evidence(checks ...string) []string {
+	var lines []string
+	for _, c := range checks {
+		if v.ran(c) {
+			lines = append(lines, v.describe(c))
+		}
+	}
+	return lines
+}
+
+// describe renders the result of check as "Name: Message", or "" when it
+// did not run.
+func (v runView) describe(check string) string {
+	if !v.ran(check) {
+		return ""
+	}
+	return v[check].Name + ": " + v[check].Message
+}
+
+// routerLink appends the router's settings page to fix, when the
+// router-admin check found one.
+func (v runView) routerLink(fix string) string {
+	if url := v["router-admin"].Facts[FactRouterURL]; url != "" {
+		return fix + " The router's settings are at " + url + "."
+	}
+	return fix
+}
+
+// causeRule draws at most one Cause from a run.
+type causeRule func(v runView) (Cause, bool)
+
+// causeRules correlate results across layers, lowest layer first; that
+// order breaks ties between causes of equal confidence.
+var causeRules = []causeRule{
+	func(v runView) (Cause, bool) {
+		if !v.reason("routes", ReasonNoRoute) && !v.reason("gateway", ReasonNoRoute) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "Not connected to any network",
+			Confidence: ConfidenceHigh,
+			Evidence:   v.evidence("wifi", "ethernet", "routes", "gateway"),
+			Fix:        "Join a Wi-Fi network or plug in the cable; without a default route nothing else can work.",
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("dhcp", ReasonSelfAssignedIP) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "No DHCP server answered, so the machine has no usable address",
+			Confidence: ConfidenceHigh,
+			Evidence:   v.evidence("wifi", "ethernet", "dhcp", "gateway"),
+			Fix:        "Restart the router and renew the lease; on Wi-Fi, forget and rejoin the network.",
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("gateway", ReasonGatewayUnreachable) {
+			return Cause{}, false
+		}
+		if v.reason("wifi", ReasonWeakSignal) {
+			return Cause{
+				Summary:    "Weak Wi-Fi signal: packets do not reach the router",
+				Confidence: ConfidenceHigh,
+				Evidence:   v.evidence("wifi", "gateway"),
+				Fix:        "Move closer to the access point or switch to its 5 GHz band.",
+			}, true
+		}
+		return Cause{
+			Summary:    "The router is down or not answering",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("wifi", "ethernet", "gateway", "wan"),
+			Fix:        "Restart the router; if other devices are fine, rejoin the network.",
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.ok("gateway") || !v.errored("wan") {
+			return Cause{}, false
+		}
+		if v.failed("dns") {
+			return Cause{
+				Summary:    "ISP or modem outage: the router answers but nothing beyond it does",
+				Confidence: ConfidenceHigh,
+				Evidence:   v.evidence("gateway", "wan", "dns"),
+				Fix:        v.routerLink("Restart the modem and check the ISP's status page; the local network is fine."),
+			}, true
+		}
+		return Cause{
+			Summary:    "The path beyond the router is broken or filtered",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("gateway", "wan", "dns"),
+			Fix:        v.routerLink("Restart the modem; if names still resolve, a firewall may drop pings and port 443."),
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		// A captive portal or dropped large replies explain failing
+		// lookups better; their own rules report them.
+		if !v.failed("dns") || v.failed("wan") || v.reason("dns", ReasonDNSUDPBlocked) || v.reason("captive", ReasonCaptivePortal) {
+			return Cause{}, false
+		}
+		c := Cause{
+			Summary:    "The DNS resolver is slow or failing while the connection works",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("wan", "dns", "split-dns"),
+			Fix:        "Switch to another resolver such as 1.1.1.1, or flush the DNS cache.",
+		}
+		if v.ok("wan") {
+			c.Confidence = ConfidenceHigh
+		}
+		if v.failed("split-dns") {
+			c.Summary = "VPN DNS settings break name resolution"
+			c.Fix = v["split-dns"].Fix
+		}
+		return c, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("captive", ReasonCaptivePortal) {
+			return Cause{}, false
+		}
+		c := Cause{
+			Summary:    "Sign-in required: the network holds traffic behind a captive portal",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("captive", "dns", "tls"),
+			Fix:        "Open a browser and sign in to the network, or run wtfi --open-portal.",
+		}
+		if !v.errored("gateway") && !v.errored("wan") {
+			c.Confidence = ConfidenceHigh
+		}
+		return c, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("proxy", ReasonProxyUnreachable) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "A configured proxy is unreachable, so browsers fail while the network works",
+			Confidence: ConfidenceHigh,
+			Evidence:   v.evidence("proxy", "wan"),
+			Fix:        v["proxy"].Fix,
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("time", ReasonClockSkew) {
+			return Cause{}, false
+		}
+		c := Cause{
+			Summary:    "The system clock is wrong",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("time", "tls"),
+			Fix:        "Enable automatic date & time.",
+		}
+		if v.reason("tls", ReasonCertExpired, ReasonCertNotYetValid, ReasonTLSFailed) {
+			c.Summary = "The system clock is wrong, so certificates look invalid"
+			c.Confidence = ConfidenceHigh
+		}
+		return c, true
+	},
+	func(v runView) (Cause, bool) {
+		blackHole := v.reason("mtu", ReasonMTUBlackHole)
+		if !blackHole && !v.reason("dns", ReasonDNSUDPBlocked) {
+			return Cause{}, false
+		}
+		c := Cause{
+			Summary:    "Large packets are dropped (MTU black hole)",
+			Confidence: ConfidenceHigh,
+			Evidence:   v.evidence("mtu", "dns", "vpn"),
+			Fix:        "Lower the interface MTU, commonly to 1400 behind VPNs and PPPoE.",
+		}
+		// DNS over UDP failing alone is more often a firewall than the MTU.
+		if !blackHole {
+			c.Summary = "Large packets may be dropped (MTU black hole)"
+			c.Confidence = ConfidenceLow
+		}
+		return c, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("filter", ReasonDNSFiltered, ReasonConnectionFiltered) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "The network filters content",
+			Confidence: ConfidenceHigh,
+			Evidence:   v.evidence("filter", "dns-hijack"),
+			Fix:        v["filter"].Fix,
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("dns-hijack", ReasonDNSHijacked, ReasonDNSIntercepted) &&
+			!v.reason("tls-intercept", ReasonTLSIntercepted) && !v.reason("gateway", ReasonARPSpoofing) &&
+			!v.reason("evil-twin", ReasonEvilTwin) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "Traffic is intercepted on the way: a proxy, security agent or attacker",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("evil-twin", "gateway", "dns-hijack", "tls-intercept"),
+			Fix:        "On a managed machine this is expected; otherwise use a VPN and leave this network.",
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("vpn", ReasonVPNOverhead) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "The VPN's full tunnel slows every connection",
+			Confidence: ConfidenceMedium,
+			Evidence:   v.evidence("vpn", "wan"),
+			Fix:        v["vpn"].Fix,
+		}, true
+	},
+	func(v runView) (Cause, bool) {
+		congested := v.reason("wan", ReasonPacketLoss, ReasonHighJitter, ReasonHighLatency) || v.reason("bufferbloat", ReasonBufferbloat)
+		if !congested {
+			return Cause{}, false
+		}
+		if v.reason("gateway", ReasonPacketLoss, ReasonHighJitter) || v.reason("wifi", ReasonWeakSignal) {
+			return Cause{
+				Summary:    "Wi-Fi interference or distance: loss already occurs before the router",
+				Confidence: ConfidenceHigh,
+				Evidence:   v.evidence("wifi", "gateway", "wan"),
+				Fix:        "Move closer to the access point, or use the 5 GHz band or a cable.",
+			}, true
+		}
+		c := Cause{
+			Summary:    "ISP congestion or bufferbloat: the local network is clean",
+			Confidence: ConfidenceLow,
+			Evidence:   v.evidence("gateway", "wan", "bufferbloat"),
+			Fix:        "Retest off-peak; enable SQM on the router if latency rises under load.",
+		}
+		if v.ok("gateway") {
+			c.Confidence = ConfidenceMedium
+		}
+		return c, true
+	},
+	func(v runView) (Cause, bool) {
+		if !v.reason("double-nat", ReasonDoubleNAT, ReasonCGNAT) && !v.reason("nat-type", ReasonCGNAT, ReasonSymmetricNAT) {
+			return Cause{}, false
+		}
+		return Cause{
+			Summary:    "Layered NAT hampers games, calls and port forwarding",
+			Confidence: ConfidenceLow,
+			Evidence:   v.evidence("double-nat", "nat-type"),
+			Fix:        "Put the ISP's router in bridge mode, or ask the ISP for a public IPv4 address.",
+		}, true
+	},
+}
+
+// RootCauses correlates results across layers and returns the probable root
+// causes, most confident first. A run without problems has none.
+func RootCauses(results []Result) []Cause {
+	v := newRunView(results)
+	var causes []Cause
+	for _, rule := range causeRules {
+		if c, ok := rule(v); ok {
+			causes = append(causes, c)
+		}
+	}
+	slices.SortStableFunc(causes, func(a, b Cause) int { return cmp.Compare(b.Confidence, a.Confidence) })
+	return causes
+}
@@ -0,0 +1,112 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRootCauses(t *testing.T) {
+	ok := func(check string) Result { return Result{Check: check, Name: check, Status: StatusOk, Message: "OK"} }
+	bad := func(check string, s Status, reason Reason) Result {
+		return Result{Check: check, Name: check, Status: s, Reason: reason, Message: string(reason)}
+	}
+	tests := []struct {
+		name    string
+		results []Result
+		want    []string
+		top     Confidence
+	}{
+		{"healthy", []Result{ok("wifi"), ok("gateway"), ok("wan"), ok("dns")}, nil, 0},
+		{
+			"isp outage",
+			[]Result{ok("wifi"), ok("gateway"), bad("wan", StatusError, ReasonOffline), bad("dns", StatusError, "")},
+			[]string{"ISP or modem outage"}, ConfidenceHigh,
+		},
+		{
+			"dns only",
+			[]Result{ok("gateway"), ok("wan"), bad("dns", StatusWarning, ReasonDNSSlow)},
+			[]string{"DNS resolver"}, ConfidenceHigh,
+		},
+		{
+			"sign-in",
+			[]Result{ok("gateway"), ok("wan"), bad("dns", StatusWarning, ReasonDNSSlow), bad("captive", StatusError, ReasonCaptivePortal)},
+			[]string{"Sign-in required"}, ConfidenceHigh,
+		},
+		{
+			"weak signal",
+			[]Result{bad("wifi", StatusWarning, ReasonWeakSignal), bad("gateway", StatusError, ReasonGatewayUnreachable)},
+			[]string{"Weak Wi-Fi signal"}, ConfidenceHigh,
+		},
+		{
+			"clock and congestion ranked",
+			[]Result{ok("gateway"), bad("wan", StatusWarning, ReasonHighJitter), bad("time", StatusError, ReasonClockSkew), bad("tls", StatusError, ReasonCertNotYetValid)},
+			[]string{"system clock is wrong, so certificates", "ISP congestion"}, ConfidenceHigh,
+		},
+		{
+			"mtu black hole",
+			[]Result{ok("gateway"), ok("wan"), bad("mtu", StatusWarning, ReasonMTUBlackHole), bad("dns", StatusError, ReasonDNSUDPBlocked)},
+			[]string{"Large packets are dropped"}, ConfidenceHigh,
+		},
+		{
+			"udp dns blocked alone",
+			[]Result{ok("gateway"), ok("wan"), ok("mtu"), bad("dns", StatusError, ReasonDNSUDPBlocked)},
+			[]string{"Large packets may be dropped"}, ConfidenceLow,
+		},
+		{"skipped checks count for nothing", []Result{{Check: "gateway", Status: StatusSkipped, Reason: ReasonNoRoute}}, nil, 0},
+	}
+	for _, tt := range tests {
+		causes := RootCauses(tt.results)
+		if len(causes) != len(tt.want) {
+			t.Errorf("%s: expected %d causes, got %+v", tt.name, len(tt.want), causes)
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(causes[i].Summary, want) {
+				t.Errorf("%s: expected cause %d to mention %q, got %q", tt.name, i, want, causes[i].Summary)
+			}
+		}
+		if len(causes) > 0 && causes[0].Confidence != tt.top {
+			t.Errorf("%s: expected %v confidence, got %v", tt.name, tt.top, causes[0].Confidence)
+		}
+	}
+}
+
+func TestRootCauseEvidence(t *testing.T) {
+	causes := RootCauses([]Result{
+		{Check: "gateway", Name: "Gateway (192.168.1.1)", Status: StatusOk, Message: "Reachable"},
+		{Check: "wan", Name: "Internet Reachability", Status: StatusError, Message: "Offline"},
+		{Check: "dns", Name: "DNS Benchmark", Status: StatusSkipped},
+	})
+	if len(causes) != 1 {
+		t.Fatalf("Expected one cause, got %+v", causes)
+	}
+	want := "Gateway (192.168.1.1): Reachable|Internet Reachability: Offline"
+	if got := strings.Join(causes[0].Evidence, "|"); got != want {
+		t.Errorf("Expected evidence %q without skipped checks, got %q", want, got)
+	}
+}
+
+func TestRootCauseRouterLink(t *testing.T) {
+	causes := RootCauses([]Result{
+		{Check: "gateway", Name: "Gateway", Status: StatusOk},
+		{Check: "wan", Name: "Internet Reachability", Status: StatusError},
+		{Check: "router-admin", Name: "Router Admin", Status: StatusOk, Facts: map[string]string{FactRouterURL: "http://192.168.1.1/"}},
+	})
+	if len(causes) != 1 || !strings.HasSuffix(causes[0].Fix, "The router's settings are at http://192.168.1.1/.") {
+		t.Errorf("Expected the fix to link to the router, got %+v", causes)
+	}
+}
+
+func TestRootCausesFromFailingDNS(t *testing.T) {
+	ok := func(check string) Result { return Result{Check: check, Name: check, Status: StatusOk, Message: "OK"} }
+	offline := Result{Check: "wan", Name: "wan", Status: StatusError, Reason: ReasonOffline}
+
+	causes := RootCauses([]Result{ok("gateway"), ok("wan"), benchmarkFailingSystem(t, true)})
+	if len(causes) != 1 || !strings.Contains(causes[0].Summary, "DNS resolver") || causes[0].Confidence != ConfidenceHigh {
+		t.Errorf("Expected the resolver to be blamed, got %+v", causes)
+	}
+	causes = RootCauses([]Result{ok("gateway"), offline, benchmarkFailingSystem(t, false)})
+	if len(causes) != 1 || !strings.Contains(causes[0].Summary, "ISP or modem outage") {
+		t.Errorf("Expected an ISP outage, got %+v", causes)
+	}
+}
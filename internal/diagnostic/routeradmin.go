@@ -0,0 +1,224 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Well-known keys of Result.Facts set by CheckRouterAdmin.
+const (
+	// FactRouterURL is the router's web interface.
+	FactRouterURL = "router_url"
+	// FactRouterVendor is who made the router, when it could be told.
+	FactRouterVendor = "router_vendor"
+)
+
+// routerVendors are looked for, case-insensitively and as whole words, in
+// what the router's web interface and its MAC address say about it.
+var routerVendors = []string{
+	"NETGEAR", "TP-Link", "ASUS", "Linksys", "D-Link", "Ubiquiti", "UniFi", "MikroTik",
+	"FRITZ!Box", "AVM", "Synology", "OpenWrt", "pfSense", "OPNsense", "Huawei", "ZTE",
+	"Sagemcom", "Technicolor", "Arris", "eero", "Zyxel", "DrayTek", "Tenda", "Xiaomi",
+	"Buffalo", "Cisco", "Speedport", "Sercomm", "Nokia", "Actiontec", "Motorola",
+}
+
+var (
+	reHTMLTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	reAuthRealm = regexp.MustCompile(`(?i)realm="([^"]*)"`)
+)
+
+// adminPage is what the router's web interface answered at URL.
+type adminPage struct {
+	URL    string
+	Title  string
+	Server string
+	// Realm is the HTTP authentication realm, which many routers set to
+	// their model name.
+	Realm string
+	// Cert is the subject of the certificate HTTPS was served with.
+	Cert string
+}
+
+// CheckRouterAdmin finds the router's web interface on the gateway (HTTPS,
+// then HTTP) and tells who made the router from its page title, Server
+// header, login realm, certificate and MAC address, so a "restart the
+// router" advice comes with a link to its settings. It only runs with -v.
+func CheckRouterAdmin(ctx context.Context, verbose bool) Result {
+	res := Result{Name: "Router Admin", Emoji: "🧭", Status: StatusOk}
+	if !verbose {
+		res.Status = StatusSkipped
+		res.Message = "Use -v flag to find the router's web interface"
+		return res
+	}
+	gw, err := getGatewayIP(ctx)
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "Could not determine gateway"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+
+	var page adminPage
+	found := false
+	host := gw
+	if strings.Contains(gw, ":") {
+		host = "[" + gw + "]"
+	}
+	for _, scheme := range []string{"https", "http"} {
+		if page, err = fetchAdminPage(ctx, scheme+"://"+host+"/"); err == nil {
+			found = true
+			break
+		}
+	}
+	mac := ""
+	if out, err := runPlatformCommand(ctx, activePlatform().neighborCommand(gw)); err == nil {
+		if macs := activePlatform().parseNeighbors(string(out))[gw]; len(macs) > 0 {
+			mac = macs[0]
+		}
+	}
+	describeRouterAdmin(&res, gw, page, found, mac, macVendor(ouiVendors(), mac))
+	return res
+}
+
+// describeRouterAdmin fills in res from the page found on gw, if any, and
+// the gateway's MAC and its OUI vendor.
+func describeRouterAdmin(res *Result, gw string, page adminPage, found bool, mac, ouiVendor string) {
+	var details []string
+	if found {
+		details = append(details, "URL: "+page.URL)
+		for _, f := range []struct{ label, value string }{
+			{"Title", page.Title}, {"Server", page.Server}, {"Login realm", page.Realm}, {"Certificate", page.Cert},
+		} {
+			if f.value != "" {
+				details = append(details, f.label+": "+f.value)
+			}
+		}
+	}
+	if mac != "" {
+		details = append(details, "MAC: "+describeMAC(mac))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	vendor := routerVendor(page.Title, page.Realm, page.Server, page.Cert, ouiVendor)
+	if vendor == "" {
+		vendor = ouiVendor
+	}
+	res.Facts = map[string]string{}
+	if vendor != "" {
+		res.Facts[FactRouterVendor] = vendor
+	}
+	if !found {
+		res.Message = "No web interface on the gateway " + gw
+		if vendor != "" {
+			res.Message = vendor + " router without a web interface on " + gw
+		}
+		return
+	}
+	res.Facts[FactRouterURL] = page.URL
+	res.Message = "Settings at " + page.URL
+	if vendor != "" {
+		res.Message = vendor + " router, settings at " + page.URL
+	}
+	res.FixCommand = shellLine([][]string{activePlatform().openURLCommand(page.URL)}, false)
+}
+
+// routerVendor returns the first of routerVendors named in texts, which are
+// searched in order.
+func routerVendor(texts ...string) string {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		for _, v := range routerVendors {
+			if containsWord(lower, strings.ToLower(v)) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether word appears in s with no letter or digit
+// right before or after it, so "AVM" is not found in "savmode".
+func containsWord(s, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isWordRune reports whether r is a letter or a digit.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// fetchAdminPage fetches url from the gateway. Routers serve self-signed
+// certificates, so the certificate is read rather than verified; nothing
+// is sent but the request.
+func fetchAdminPage(ctx context.Context, url string) (adminPage, error) {
+	client := http.Client{
+		Timeout: activeConfig(ctx).Timeout,
+		Transport: &http.Transport{
+			Proxy:           nil,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Follow the login redirect, but stay on the router.
+			if len(via) >= 3 || req.URL.Host != via[0].URL.Host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return adminPage{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return adminPage{}, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	if resp.StatusCode >= 500 {
+		return adminPage{}, errors.New("router web interface answered " + resp.Status)
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	page := adminPage{
+		URL:    url,
+		Server: reSanitizeHTTP.ReplaceAllString(resp.Header.Get("Server"), ""),
+	}
+	if m := reHTMLTitle.FindSubmatch(body); m != nil {
+		page.Title = reSanitizeHTTP.ReplaceAllString(strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " "), "")
+	}
+	if m := reAuthRealm.FindStringSubmatch(resp.Header.Get("WWW-Authenticate")); m != nil {
+		page.Realm = reSanitizeHTTP.ReplaceAllString(m[1], "")
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		page.Cert = cert.Subject.CommonName
+		if len(cert.Subject.Organization) > 0 {
+			page.Cert = strings.TrimSpace(page.Cert + " (" + cert.Subject.Organization[0] + ")")
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,83 @@
+package diagnostic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchAdminPage(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/login.htm", http.StatusFound)
+			return
+		}
+		w.Header().Set("Server", "httpd")
+		_, _ = w.Write([]byte("<html><head><title>\n  NETGEAR Router R7000 </title></head></html>"))
+	}))
+	defer srv.Close()
+
+	page, err := fetchAdminPage(context.Background(), srv.URL+"/")
+	if err != nil {
+		t.Fatalf("expected the self-signed page to load, got %v", err)
+	}
+	if page.Title != "NETGEAR Router R7000" || page.Server != "httpd" || page.Cert == "" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
+
+func TestDescribeRouterAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      adminPage
+		found     bool
+		ouiVendor string
+		want      string
+	}{
+		{"title", adminPage{URL: "https://192.168.1.1/", Title: "NETGEAR Router R7000"}, true, "", "NETGEAR router, settings at https://192.168.1.1/"},
+		{"realm", adminPage{URL: "http://192.168.1.1/", Realm: "TP-LINK Wireless N Router WR841N"}, true, "", "TP-Link router, settings at http://192.168.1.1/"},
+		{"oui only", adminPage{URL: "http://192.168.1.1/", Title: "Login"}, true, "Sagemcom Broadband SAS", "Sagemcom router, settings at http://192.168.1.1/"},
+		{"unknown vendor", adminPage{URL: "http://192.168.1.1/"}, true, "Example Networks", "Example Networks router, settings at http://192.168.1.1/"},
+		{"vendor inside a word", adminPage{URL: "http://192.168.1.1/", Title: "Harris Savmode Gateway", Server: "Kuzte/1.0"}, true, "", "Settings at http://192.168.1.1/"},
+		{"anonymous", adminPage{URL: "http://192.168.1.1/"}, true, "", "Settings at http://192.168.1.1/"},
+		{"no web interface", adminPage{}, false, "", "No web interface on the gateway 192.168.1.1"},
+	}
+	for _, tt := range tests {
+		var res Result
+		describeRouterAdmin(&res, "192.168.1.1", tt.page, tt.found, "", tt.ouiVendor)
+		if res.Message != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, res.Message)
+		}
+		if tt.found && (res.Facts[FactRouterURL] != tt.page.URL || res.FixCommand != shellLine([][]string{activePlatform().openURLCommand(tt.page.URL)}, false)) {
+			t.Errorf("%s: expected a link to the settings, got %v %q", tt.name, res.Facts, res.FixCommand)
+		}
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		s, word string
+		want    bool
+	}{
+		{"fritz!box 7590", "fritz!box", true},
+		{"tp-link wireless n router", "tp-link", true},
+		{"(avm)", "avm", true},
+		{"savmode avm", "avm", true},
+		{"savmode", "avm", false},
+		{"harris", "arris", false},
+		{"veeroute", "eero", false},
+		{"asuswrt", "asus", false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.s, tt.word); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.s, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRouterAdminNeedsVerbose(t *testing.T) {
+	if res := CheckRouterAdmin(context.Background(), false); res.Status != StatusSkipped || res.Facts != nil {
+		t.Errorf("expected no probing without -v, got %+v", res)
+	}
+}
@@ -0,0 +1,100 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultRouteEntry is one "default" line of `netstat -rn`.
+type defaultRouteEntry struct {
+	Gateway string
+	Flags   string
+	Iface   string
+}
+
+// scoped reports whether the route is bound to its interface (flag I), in
+// which case it only serves sockets bound to that interface and does not
+// compete for the system default.
+func (e defaultRouteEntry) scoped() bool {
+	return strings.Contains(e.Flags, "I")
+}
+
+// parseDefaultRoutes returns the default routes of a `netstat -rn -f inet`
+// listing, in table order, which is the order macOS prefers them.
+func parseDefaultRoutes(output string) []defaultRouteEntry {
+	var routes []defaultRouteEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "default" {
+			continue
+		}
+		routes = append(routes, defaultRouteEntry{Gateway: fields[1], Flags: fields[2], Iface: fields[3]})
+	}
+	return routes
+}
+
+// CheckDefaultRoutes warns when several unscoped default routes compete,
+// typically a VPN and the physical interface.
+func CheckDefaultRoutes(ctx context.Context) Result {
+	res := Result{Name: "Default Routes", Emoji: "🔀", Status: StatusOk}
+	out, err := runCommand(ctx, "netstat", "-rn", "-f", "inet")
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "Failed to read routing table"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+
+	active := ""
+	if routeInfo, err := defaultRoute.get(ctx); err == nil {
+		active, _ = parseInterface(routeInfo)
+	}
+
+	routes := parseDefaultRoutes(string(out))
+	var competing []string
+	var details []string
+	for _, r := range routes {
+		var tags []string
+		if r.Iface == active {
+			tags = append(tags, "active")
+		}
+		if r.scoped() {
+			tags = append(tags, "interface-scoped")
+		} else {
+			competing = append(competing, r.Iface)
+		}
+		line := fmt.Sprintf("%s via %s", r.Iface, r.Gateway)
+		if len(tags) > 0 {
+			line += " (" + strings.Join(tags, ", ") + ")"
+		}
+		details = append(details, line)
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	switch {
+	case len(routes) == 0:
+		res.Status = StatusError
+		res.Message = "No default route"
+		res.Reason = ReasonNoRoute
+	case len(competing) > 1:
+		res.Status = StatusWarning
+		res.Reason = ReasonMultipleDefaultRoutes
+		res.Facts = map[string]string{FactDefaultRoutes: strings.Join(competing, ",")}
+		res.Message = fmt.Sprintf("%d default routes compete (%s); %s wins", len(competing), strings.Join(competing, ", "), orUnknown(active))
+		res.Fix = "Disconnect the VPN or interface you do not mean to use, or adjust the service order in Network settings."
+	default:
+		res.Message = "Single default route via " + orUnknown(active)
+	}
+	return res
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
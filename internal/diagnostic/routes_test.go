@@ -0,0 +1,52 @@
+package diagnostic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const netstatVPN = `Routing tables
+
+Internet:
+Destination        Gateway            Flags               Netif Expire
+default            link#17            UCSg                utun3
+default            192.168.1.1        UGScg                 en0
+default            10.0.0.1           UGScIg                en7
+127                127.0.0.1          UCS                   lo0
+192.168.1          link#6             UCS                   en0      !
+`
+
+func TestParseDefaultRoutes(t *testing.T) {
+	routes := parseDefaultRoutes(netstatVPN)
+	if len(routes) != 3 {
+		t.Fatalf("Expected 3 default routes, got %+v", routes)
+	}
+	if routes[0].Iface != "utun3" || routes[1].Gateway != "192.168.1.1" {
+		t.Errorf("Unexpected routes %+v", routes)
+	}
+	if routes[0].scoped() || !routes[2].scoped() {
+		t.Errorf("Expected only en7 to be interface-scoped, got %+v", routes)
+	}
+	if got := parseDefaultRoutes("Routing tables\n"); len(got) != 0 {
+		t.Errorf("Expected no routes, got %+v", got)
+	}
+}
+
+func TestCheckDefaultRoutes(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"netstat -rn -f inet":  netstatVPN,
+		"route -n get default": "    gateway: 10.8.0.1\n  interface: utun3\n",
+	}})
+
+	res := CheckDefaultRoutes(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonMultipleDefaultRoutes {
+		t.Fatalf("Expected a multiple_default_routes warning, got %v/%s", res.Status, res.Reason)
+	}
+	if !strings.Contains(res.Message, "utun3 wins") {
+		t.Errorf("Expected the active interface in the message, got %s", res.Message)
+	}
+	if !strings.Contains(res.Details[0], "utun3 via link#17 (active)") {
+		t.Errorf("Expected the active route to be marked, got %v", res.Details)
+	}
+}
@@ -0,0 +1,179 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandRunner executes the external tools the checks rely on.
+// Swapping it lets the same checks run against fixtures or a remote Mac.
+type commandRunner interface {
+	// Output runs name with args and returns its standard output.
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+	// LookPath reports whether name is available to Output.
+	LookPath(name string) error
+}
+
+// localRunner runs commands on this machine.
+type localRunner struct{}
+
+func (localRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+func (localRunner) LookPath(name string) error {
+	_, err := exec.LookPath(name)
+	return err
+}
+
+// sshRunner runs commands on a remote host through the ssh client.
+type sshRunner struct {
+	target string
+	// root is whether the ssh user is root on the remote host.
+	root bool
+}
+
+func (r sshRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	quoted := make([]string, 0, len(args)+1)
+	for _, a := range append([]string{name}, args...) {
+		quoted = append(quoted, shellQuote(a))
+	}
+	out, err := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", r.target, strings.Join(quoted, " ")).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 127 {
+		// The remote shell's "command not found".
+		return out, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	return out, err
+}
+
+func (r sshRunner) LookPath(name string) error {
+	if _, err := r.Output(context.Background(), "command", "-v", name); err != nil {
+		return &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	return nil
+}
+
+// shellQuote wraps s in single quotes for the remote login shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var (
+	runnerMu sync.RWMutex
+	runner   commandRunner = localRunner{}
+)
+
+func activeRunner() commandRunner {
+	runnerMu.RLock()
+	defer runnerMu.RUnlock()
+	return runner
+}
+
+func setRunner(r commandRunner) {
+	runnerMu.Lock()
+	defer runnerMu.Unlock()
+	runner = r
+}
+
+// commandTimeout bounds external commands whose caller set no deadline, so
+// a wedged tool cannot hang the run. It is generous because system_profiler
+// can take several seconds.
+const commandTimeout = 15 * time.Second
+
+// runCommand runs an external command through the active runner, bounded by
+// ctx and, when ctx has no deadline, by commandTimeout. At debug level the
+// command is logged with its raw output, for when a parser fails on a new
+// OS release.
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	out, err := activeRunner().Output(ctx, name, args...)
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		logCommand(ctx, append([]string{name}, args...), time.Since(start), out, err)
+	}
+	return out, err
+}
+
+// logCommand logs a command that ran for d at debug level, with its
+// standard output and, when it failed, its standard error.
+func logCommand(ctx context.Context, argv []string, d time.Duration, out []byte, err error) {
+	attrs := []any{"cmd", strings.Join(argv, " "), "duration", d.Round(time.Millisecond)}
+	if err != nil {
+		attrs = append(attrs, "err", err)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			attrs = append(attrs, "stderr", string(exitErr.Stderr))
+		}
+	}
+	attrs = append(attrs, "output", string(out))
+	slog.DebugContext(ctx, "ran command", attrs...)
+}
+
+// UseRemote routes every external command through ssh to target (user@host).
+// Checks built on Go's own networking (DNS, TCP, TLS, HTTP) still run locally.
+func UseRemote(target string) error {
+	r := sshRunner{target: target}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	out, err := r.Output(ctx, "uname", "-s")
+	if err != nil {
+		return fmt.Errorf("could not reach %s over ssh: %w", target, err)
+	}
+	if osName := strings.TrimSpace(string(out)); osName != "Darwin" {
+		return fmt.Errorf("remote host %s runs %s, but wtfi requires macOS", target, osName)
+	}
+	if out, err := r.Output(ctx, "id", "-u"); err == nil {
+		r.root = strings.TrimSpace(string(out)) == "0"
+	}
+	setRunner(r)
+	setPlatform(darwinPlatform{})
+	return nil
+}
+
+// remoteTarget returns the host commands run on with --remote, or "" when
+// they run on this machine.
+func remoteTarget() string {
+	if r, ok := activeRunner().(sshRunner); ok {
+		return r.target
+	}
+	return ""
+}
+
+// IsRoot reports whether the commands the checks run have root: this
+// process, or with --remote the ssh user on the remote Mac.
+func IsRoot() bool {
+	if r, ok := activeRunner().(sshRunner); ok {
+		return r.root
+	}
+	return os.Geteuid() == 0
+}
+
+// missingToolResult converts an error caused by a missing binary into a
+// StatusSkipped result naming the tool, so environment problems are not
+// mistaken for network failures.
+func missingToolResult(name, emoji string, err error) (Result, bool) {
+	var execErr *exec.Error
+	if !errors.As(err, &execErr) || !errors.Is(err, exec.ErrNotFound) {
+		return Result{}, false
+	}
+	return Result{
+		Name:    name,
+		Emoji:   emoji,
+		Status:  StatusSkipped,
+		Message: fmt.Sprintf("Required tool %q not found", execErr.Name),
+		Reason:  ReasonToolMissing,
+		Fix:     fmt.Sprintf("Install %s or add its directory to your PATH.", execErr.Name),
+	}, true
+}
@@ -0,0 +1,141 @@
+package diagnostic
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRunner serves canned command output keyed by the full command line.
+// It is safe for concurrent use.
+type fakeRunner struct {
+	mu      sync.Mutex
+	outputs map[string]string
+	errs    map[string]error
+	calls   []string
+}
+
+func (f *fakeRunner) Output(_ context.Context, name string, args ...string) ([]byte, error) {
+	key := strings.Join(append([]string{name}, args...), " ")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, key)
+	if err, ok := f.errs[key]; ok {
+		return nil, err
+	}
+	if out, ok := f.outputs[key]; ok {
+		return []byte(out), nil
+	}
+	return nil, &exec.ExitError{}
+}
+
+func (f *fakeRunner) LookPath(name string) error {
+	for key := range f.outputs {
+		if strings.HasPrefix(key, name+" ") {
+			return nil
+		}
+	}
+	return &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// withRunner installs r for the duration of the test, with a cold route cache.
+func withRunner(t *testing.T, r commandRunner) {
+	t.Helper()
+	prev, prevPlat := activeRunner(), activePlatform()
+	setRunner(r)
+	// The fixtures are macOS command output.
+	setPlatform(darwinPlatform{})
+	ResetCache()
+	t.Cleanup(func() {
+		setRunner(prev)
+		setPlatform(prevPlat)
+		ResetCache()
+	})
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("en0"); got != "'en0'" {
+		t.Errorf("Expected 'en0', got %s", got)
+	}
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("Expected escaped quote, got %s", got)
+	}
+}
+
+func TestRunnerIndirection(t *testing.T) {
+	fake := &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 10.0.0.1\n  interface: en7\n",
+	}}
+	withRunner(t, fake)
+
+	iface, err := getPrimaryInterface(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if iface != "en7" {
+		t.Errorf("Expected en7 from fake runner, got %s", iface)
+	}
+	if len(fake.calls) != 1 {
+		t.Errorf("Expected 1 command, got %v", fake.calls)
+	}
+}
+
+func TestRunCommandLogsAtDebug(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 10.0.0.1\n",
+	}})
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	if _, err := runCommand(context.Background(), "route", "-n", "get", "default"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing logged above debug level, got %s", buf.String())
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if _, err := runCommand(context.Background(), "route", "-n", "get", "default"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, want := range []string{`msg="ran command"`, `cmd="route -n get default"`, `output="    gateway: 10.0.0.1\n"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected the log to contain %s, got %s", want, buf.String())
+		}
+	}
+	buf.Reset()
+	if _, err := runCommand(context.Background(), "arp", "-a"); err == nil {
+		t.Fatal("Expected an error for an unknown command")
+	}
+	if !strings.Contains(buf.String(), `cmd="arp -a"`) || !strings.Contains(buf.String(), "err=") {
+		t.Errorf("Expected the failure to be logged, got %s", buf.String())
+	}
+}
+
+func TestMissingToolResult(t *testing.T) {
+	fake := &fakeRunner{errs: map[string]error{
+		"route -n get default": &exec.Error{Name: "route", Err: exec.ErrNotFound},
+	}}
+	withRunner(t, fake)
+
+	res := CheckRoutingTable(context.Background())
+	if res.Status != StatusSkipped || res.Reason != ReasonToolMissing {
+		t.Fatalf("Expected a tool_missing skip, got %v/%s", res.Status, res.Reason)
+	}
+	if !strings.Contains(res.Message, "route") {
+		t.Errorf("Expected message to name the missing tool, got %s", res.Message)
+	}
+	if !strings.Contains(res.Fix, "PATH") {
+		t.Errorf("Expected PATH hint in fix, got %s", res.Fix)
+	}
+
+	if _, ok := missingToolResult("Gateway", "🏠", &exec.ExitError{}); ok {
+		t.Error("Expected a failing command not to be treated as missing")
+	}
+}
@@ -0,0 +1,168 @@
+package diagnostic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ssdpAddr = "239.255.255.250:1900"
+	// ssdpWait is how long we listen for M-SEARCH replies.
+	ssdpWait = 2 * time.Second
+)
+
+// adminPorts are the router management services probed on the gateway.
+var adminPorts = []struct {
+	port int
+	name string
+}{
+	{22, "SSH"},
+	{23, "Telnet"},
+	{80, "HTTP admin"},
+	{443, "HTTPS admin"},
+	{8080, "HTTP alt admin"},
+	{8443, "HTTPS alt admin"},
+}
+
+// ssdpService is one reply to an SSDP M-SEARCH.
+type ssdpService struct {
+	Server   string
+	ST       string
+	Location string
+}
+
+// buildMSearch returns an SSDP discovery request for search target st.
+func buildMSearch(st string, mx int) []byte {
+	return []byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: " + strconv.Itoa(mx) + "\r\n" +
+		"ST: " + st + "\r\n\r\n")
+}
+
+// parseSSDPResponse parses an HTTP-over-UDP M-SEARCH reply.
+func parseSSDPResponse(data []byte) (ssdpService, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ssdpService{}, false
+	}
+	_ = resp.Body.Close()
+	sanitize := func(s string) string { return reSanitizeHTTP.ReplaceAllString(s, "") }
+	return ssdpService{
+		Server:   sanitize(resp.Header.Get("Server")),
+		ST:       sanitize(resp.Header.Get("St")),
+		Location: sanitize(resp.Header.Get("Location")),
+	}, true
+}
+
+// CheckGatewaySecurity looks for UPnP and reachable admin services on the
+// gateway. It only talks to the gateway and only reads.
+func CheckGatewaySecurity(ctx context.Context) Result {
+	res := Result{Name: "Gateway Security", Emoji: "🛡️", Status: StatusOk}
+	gw, err := getGatewayIP(ctx)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "Could not determine gateway"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+
+	var details []string
+	services := discoverSSDP(ctx, gw, ssdpWait)
+	for _, s := range services {
+		details = append(details, fmt.Sprintf("UPnP: %s (%s)", s.ST, s.Server))
+	}
+
+	open := probeAdminPorts(ctx, gw, activeConfig(ctx).Timeout)
+	telnet := false
+	for _, p := range adminPorts {
+		if open[p.port] {
+			details = append(details, fmt.Sprintf("Port %d open: %s", p.port, p.name))
+			telnet = telnet || p.port == 23
+		}
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeGatewaySecurity(&res, len(services) > 0, telnet)
+	return res
+}
+
+// gradeGatewaySecurity sets the verdict of CheckGatewaySecurity.
+func gradeGatewaySecurity(res *Result, upnp, telnet bool) {
+	switch {
+	case upnp:
+		res.Status = StatusWarning
+		res.Reason = ReasonUPnPEnabled
+		res.Message = "UPnP is enabled on the router"
+		res.Fix = "Disable UPnP in the router settings unless a console or app needs it; it lets LAN devices open ports to the internet."
+	case telnet:
+		res.Status = StatusWarning
+		res.Reason = ReasonTelnetOpen
+		res.Message = "Router accepts Telnet from the LAN"
+		res.Fix = "Disable Telnet on the router; it sends credentials in clear text."
+	default:
+		res.Message = "No UPnP responder on the gateway"
+	}
+}
+
+// discoverSSDP multicasts an M-SEARCH and collects the replies sent by gw.
+func discoverSSDP(ctx context.Context, gw string, wait time.Duration) []ssdpService {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = conn.Close() }()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil
+	}
+	if _, err := conn.WriteToUDP(buildMSearch("upnp:rootdevice", 1), dst); err != nil {
+		return nil
+	}
+
+	var services []ssdpService
+	_ = conn.SetReadDeadline(time.Now().Add(wait))
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return services
+		}
+		// Other LAN devices answer too; only the gateway matters here.
+		if from.IP.String() != gw {
+			continue
+		}
+		if s, ok := parseSSDPResponse(buf[:n]); ok {
+			services = append(services, s)
+		}
+	}
+}
+
+// probeAdminPorts reports which adminPorts accept TCP connections on gw.
+func probeAdminPorts(ctx context.Context, gw string, timeout time.Duration) map[int]bool {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	open := map[int]bool{}
+	for _, p := range adminPorts {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			if canConnect(ctx, net.JoinHostPort(gw, strconv.Itoa(port)), timeout) {
+				mu.Lock()
+				open[port] = true
+				mu.Unlock()
+			}
+		}(p.port)
+	}
+	wg.Wait()
+	return open
+}
@@ -0,0 +1,62 @@
+package diagnostic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMSearch(t *testing.T) {
+	req := string(buildMSearch("upnp:rootdevice", 1))
+	for _, want := range []string{"M-SEARCH * HTTP/1.1\r\n", "MAN: \"ssdp:discover\"\r\n", "MX: 1\r\n", "ST: upnp:rootdevice\r\n"} {
+		if !strings.Contains(req, want) {
+			t.Errorf("Expected %q in request %q", want, req)
+		}
+	}
+	if !strings.HasSuffix(req, "\r\n\r\n") {
+		t.Error("Expected request to end with a blank line")
+	}
+}
+
+func TestParseSSDPResponse(t *testing.T) {
+	reply := "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=120\r\n" +
+		"ST: upnp:rootdevice\r\n" +
+		"USN: uuid:1234::upnp:rootdevice\r\n" +
+		"EXT:\r\n" +
+		"SERVER: Linux/3.14 UPnP/1.0 MiniUPnPd/2.1\r\n" +
+		"LOCATION: http://192.168.1.1:5000/rootDesc.xml\r\n\r\n"
+	s, ok := parseSSDPResponse([]byte(reply))
+	if !ok {
+		t.Fatal("Expected reply to parse")
+	}
+	if s.ST != "upnp:rootdevice" || s.Server != "Linux/3.14 UPnP/1.0 MiniUPnPd/2.1" || s.Location != "http://192.168.1.1:5000/rootDesc.xml" {
+		t.Errorf("Unexpected service %+v", s)
+	}
+
+	if _, ok := parseSSDPResponse(buildMSearch("ssdp:all", 1)); ok {
+		t.Error("Expected a request, not a reply, to be rejected")
+	}
+	if _, ok := parseSSDPResponse([]byte("garbage")); ok {
+		t.Error("Expected garbage to be rejected")
+	}
+}
+
+func TestGradeGatewaySecurity(t *testing.T) {
+	tests := []struct {
+		upnp, telnet bool
+		status       Status
+		reason       Reason
+	}{
+		{false, false, StatusOk, ""},
+		{true, false, StatusWarning, ReasonUPnPEnabled},
+		{false, true, StatusWarning, ReasonTelnetOpen},
+		{true, true, StatusWarning, ReasonUPnPEnabled},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeGatewaySecurity(&res, tt.upnp, tt.telnet)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("upnp=%v telnet=%v: expected %v/%s, got %v/%s", tt.upnp, tt.telnet, tt.status, tt.reason, res.Status, res.Reason)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// tlsPorts are the ports whose services speak TLS from the first byte.
+var tlsPorts = map[string]bool{
+	"443": true, "465": true, "563": true, "636": true, "853": true, "993": true,
+	"995": true, "5061": true, "6697": true, "8443": true,
+}
+
+// httpSchemes are the ports probed with an HTTP request when a service is
+// given as host:port.
+var httpSchemes = map[string]string{"80": "http", "443": "https", "8080": "http", "8443": "https"}
+
+// serviceTarget is one entry of Config.Services.
+type serviceTarget struct {
+	// Name is the entry as configured.
+	Name string
+	Addr string
+	TLS  bool
+	// URL is requested over HTTP; empty skips that layer.
+	URL string
+}
+
+// parseService reads a Config.Services entry: "host:port", probed with TLS
+// on well-known TLS ports and with HTTP on web ports, or an http(s) URL.
+func parseService(s string) (serviceTarget, error) {
+	t := serviceTarget{Name: s}
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return t, errors.New("must be host:port or an http(s) URL")
+		}
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+		}
+		t.Addr = net.JoinHostPort(u.Hostname(), port)
+		t.TLS = u.Scheme == "https"
+		t.URL = s
+		return t, nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return t, errors.New("must be host:port or an http(s) URL")
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return t, fmt.Errorf("invalid port %q", port)
+	}
+	t.Addr = s
+	t.TLS = tlsPorts[port]
+	if scheme, ok := httpSchemes[port]; ok {
+		t.URL = scheme + "://" + s + "/"
+	}
+	return t, nil
+}
+
+// ValidateServices reports the first entry of services that parseService
+// rejects.
+func ValidateServices(services []string) error {
+	for _, s := range services {
+		if _, err := parseService(s); err != nil {
+			return fmt.Errorf("service %q: %w", s, err)
+		}
+	}
+	return nil
+}
+
+// layerProbe is the outcome of one layer of a serviceProbe; a layer that
+// was not tried has neither Err nor Latency.
+type layerProbe struct {
+	Tried   bool
+	Latency time.Duration
+	// Status is the HTTP status code of the HTTP layer.
+	Status int
+	Err    error
+}
+
+// serviceProbe is the outcome of probing one serviceTarget layer by layer.
+type serviceProbe struct {
+	Target         serviceTarget
+	TCP, TLS, HTTP layerProbe
+}
+
+// failedLayer names the first layer that failed, or "" when all passed.
+func (p serviceProbe) failedLayer() string {
+	switch {
+	case p.TCP.Err != nil:
+		return "TCP"
+	case p.TLS.Err != nil:
+		return "TLS"
+	case p.HTTP.Err != nil:
+		return "HTTP"
+	}
+	return ""
+}
+
+// CheckServices tests the TCP, TLS and HTTP reachability of each of
+// Config.Services in parallel and renders a pass/fail matrix, so teams can
+// ship wtfi with their critical endpoints baked into the config.
+func CheckServices(ctx context.Context) Result {
+	res := Result{Name: "Services", Emoji: "🧩", Status: StatusOk}
+	services := activeConfig(ctx).Services
+	if len(services) == 0 {
+		res.Status = StatusSkipped
+		res.Message = "No services configured"
+		return res
+	}
+	probes := make([]serviceProbe, len(services))
+	var wg sync.WaitGroup
+	for i, s := range services {
+		target, err := parseService(s)
+		if err != nil {
+			probes[i] = serviceProbe{Target: target, TCP: layerProbe{Tried: true, Err: err}}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i] = probeService(ctx, target)
+		}()
+	}
+	wg.Wait()
+	res.Details = formatDetailsWithPrefixes(serviceMatrix(probes))
+	gradeServices(&res, probes)
+	return res
+}
+
+// gradeServices sets the verdict of CheckServices.
+func gradeServices(res *Result, probes []serviceProbe) {
+	var failed []string
+	for _, p := range probes {
+		if layer := p.failedLayer(); layer != "" {
+			failed = append(failed, p.Target.Name+" ("+layer+")")
+		}
+	}
+	switch {
+	case len(failed) == 0:
+		res.Message = fmt.Sprintf("All %d services reachable", len(probes))
+		return
+	case len(failed) == len(probes):
+		res.Status = StatusError
+	default:
+		res.Status = StatusWarning
+	}
+	res.Reason = ReasonServiceUnreachable
+	res.Message = fmt.Sprintf("%d of %d services unreachable: %s", len(failed), len(probes), strings.Join(failed, ", "))
+	res.Fix = "A TCP failure points at a firewall, VPN or DNS; a TLS failure at a proxy or interception; an HTTP failure at the service itself."
+}
+
+// probeService connects to t, then completes a TLS handshake and an HTTP
+// request where t calls for them; each layer is tried only when the one
+// below it passed.
+func probeService(ctx context.Context, t serviceTarget) serviceProbe {
+	p := serviceProbe{Target: t}
+	timeout := activeConfig(ctx).Timeout
+	d := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", t.Addr)
+	p.TCP = layerProbe{Tried: true, Latency: time.Since(start), Err: err}
+	if err != nil {
+		return p
+	}
+	if t.TLS {
+		host, _, _ := net.SplitHostPort(t.Addr)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		hsCtx, cancel := context.WithTimeout(ctx, timeout)
+		start = time.Now()
+		err = tlsConn.HandshakeContext(hsCtx)
+		cancel()
+		p.TLS = layerProbe{Tried: true, Latency: time.Since(start), Err: err}
+	}
+	_ = conn.Close()
+	if p.TLS.Err != nil || t.URL == "" {
+		return p
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		p.HTTP = layerProbe{Tried: true, Err: err}
+		return p
+	}
+	client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	start = time.Now()
+	resp, err := client.Do(req)
+	p.HTTP = layerProbe{Tried: true, Latency: time.Since(start), Err: err}
+	if err != nil {
+		return p
+	}
+	_ = resp.Body.Close()
+	p.HTTP.Status = resp.StatusCode
+	// Any answer short of a server error proves the service is up; login
+	// walls answer 401 or 403.
+	if resp.StatusCode >= 500 {
+		p.HTTP.Err = errors.New(resp.Status)
+	}
+	return p
+}
+
+// serviceMatrix renders one aligned row per probe:
+// "github.com:443  TCP ✓ 12ms  TLS ✓ 31ms  HTTP ✓ 200".
+func serviceMatrix(probes []serviceProbe) []string {
+	width := 0
+	for _, p := range probes {
+		width = max(width, len(p.Target.Name))
+	}
+	rows := make([]string, len(probes))
+	for i, p := range probes {
+		rows[i] = fmt.Sprintf("%-*s  TCP %-9s TLS %-9s HTTP %s", width, p.Target.Name,
+			layerCell(p.TCP, ""), layerCell(p.TLS, ""), layerCell(p.HTTP, httpStatusNote(p.HTTP)))
+		if p.failedLayer() != "" {
+			rows[i] += "  (" + layerError(p) + ")"
+		}
+	}
+	return rows
+}
+
+// layerCell renders one cell of serviceMatrix: the latency, or note when
+// given, after a pass mark.
+func layerCell(l layerProbe, note string) string {
+	switch {
+	case !l.Tried:
+		return "-"
+	case l.Err != nil:
+		return "✗"
+	case note != "":
+		return "✓ " + note
+	default:
+		return "✓ " + l.Latency.Round(time.Millisecond).String()
+	}
+}
+
+func httpStatusNote(l layerProbe) string {
+	if l.Status == 0 {
+		return ""
+	}
+	return strconv.Itoa(l.Status)
+}
+
+// layerError describes why the first failed layer of p failed.
+func layerError(p serviceProbe) string {
+	switch p.failedLayer() {
+	case "TCP":
+		var netErr net.Error
+		if errors.As(p.TCP.Err, &netErr) && netErr.Timeout() {
+			return "connection timed out"
+		}
+		if errors.Is(p.TCP.Err, syscall.ECONNREFUSED) {
+			return "connection refused"
+		}
+		var dnsErr *net.DNSError
+		if errors.As(p.TCP.Err, &dnsErr) {
+			return "name not resolved"
+		}
+		return p.TCP.Err.Error()
+	case "TLS":
+		msg, _ := classifyTLSError(p.TLS.Err)
+		return msg
+	default:
+		return p.HTTP.Err.Error()
+	}
+}
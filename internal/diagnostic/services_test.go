@@ -0,0 +1,97 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseService(t *testing.T) {
+	tests := []struct {
+		in   string
+		want serviceTarget
+	}{
+		{"github.com:443", serviceTarget{Name: "github.com:443", Addr: "github.com:443", TLS: true, URL: "https://github.com:443/"}},
+		{"imap.gmail.com:993", serviceTarget{Name: "imap.gmail.com:993", Addr: "imap.gmail.com:993", TLS: true}},
+		{"git.corp.example:22", serviceTarget{Name: "git.corp.example:22", Addr: "git.corp.example:22"}},
+		{"https://intranet.corp.example/health", serviceTarget{Name: "https://intranet.corp.example/health", Addr: "intranet.corp.example:443", TLS: true, URL: "https://intranet.corp.example/health"}},
+		{"http://10.0.0.5:8080/", serviceTarget{Name: "http://10.0.0.5:8080/", Addr: "10.0.0.5:8080", URL: "http://10.0.0.5:8080/"}},
+	}
+	for _, tt := range tests {
+		got, err := parseService(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseService(%q): expected %+v, got %+v (%v)", tt.in, tt.want, got, err)
+		}
+	}
+	for _, bad := range []string{"github.com", ":443", "github.com:0", "github.com:https", "ftp://files.example.com"} {
+		if _, err := parseService(bad); err == nil {
+			t.Errorf("parseService(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestGradeServices(t *testing.T) {
+	up := serviceProbe{Target: serviceTarget{Name: "github.com:443"}, TCP: layerProbe{Tried: true}, TLS: layerProbe{Tried: true}}
+	tlsDown := serviceProbe{Target: serviceTarget{Name: "vpn.corp.example:443"}, TCP: layerProbe{Tried: true}, TLS: layerProbe{Tried: true, Err: errors.New("unknown authority")}}
+	tests := []struct {
+		name   string
+		probes []serviceProbe
+		status Status
+	}{
+		{"all up", []serviceProbe{up, up}, StatusOk},
+		{"one down", []serviceProbe{up, tlsDown}, StatusWarning},
+		{"all down", []serviceProbe{tlsDown}, StatusError},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeServices(&res, tt.probes)
+		if res.Status != tt.status {
+			t.Errorf("%s: expected %v, got %v (%s)", tt.name, tt.status, res.Status, res.Message)
+		}
+	}
+	res := Result{Status: StatusOk}
+	gradeServices(&res, []serviceProbe{up, tlsDown})
+	if res.Reason != ReasonServiceUnreachable || !strings.Contains(res.Message, "vpn.corp.example:443 (TLS)") {
+		t.Errorf("Expected the failed service and layer, got %q/%q", res.Reason, res.Message)
+	}
+}
+
+func TestProbeService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	target, _ := parseService(srv.URL + "/login")
+	p := probeService(context.Background(), target)
+	if p.failedLayer() != "" || p.HTTP.Status != http.StatusUnauthorized || p.TLS.Tried {
+		t.Errorf("Expected a login wall to pass over plain HTTP, got %+v", p)
+	}
+	target, _ = parseService(srv.URL + "/down")
+	if p := probeService(context.Background(), target); p.failedLayer() != "HTTP" {
+		t.Errorf("Expected a 503 to fail the HTTP layer, got %+v", p)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	target, _ = parseService(addr)
+	p = probeService(context.Background(), target)
+	if p.failedLayer() != "TCP" || p.HTTP.Tried {
+		t.Errorf("Expected a closed port to fail at TCP only, got %+v", p)
+	}
+	if row := serviceMatrix([]serviceProbe{p})[0]; !strings.Contains(row, "TCP ✗") || !strings.Contains(row, "connection refused") {
+		t.Errorf("Expected a refused connection in the matrix, got %q", row)
+	}
+}
@@ -0,0 +1,291 @@
+package diagnostic
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dnsScope is one resolver configuration: the nameservers that answer
+// queries for Domains, and for every other name when Default.
+type dnsScope struct {
+	// Interface is the interface the configuration belongs to, or "" for a
+	// global one.
+	Interface   string
+	Nameservers []string
+	// Search domains are appended to single-label names.
+	Search []string
+	// Domains are the match domains the nameservers answer for (split
+	// DNS).
+	Domains []string
+	// Default scopes answer names no match domain claims.
+	Default bool
+	// Scoped configurations only answer queries bound to Interface.
+	Scoped bool
+}
+
+var (
+	reScutilResolver = regexp.MustCompile(`^resolver #\d+`)
+	reScutilField    = regexp.MustCompile(`^\s+([a-z_ ]+?)(?:\[\d+\])?\s*: (.*)$`)
+	reScutilIfIndex  = regexp.MustCompile(`\((\S+)\)`)
+)
+
+// parseScutilDNS reads `scutil --dns`. Resolvers of the scoped section and
+// those flagged Scoped only serve their interface; multicast DNS and
+// reverse-lookup resolvers without nameservers are dropped, as are scoped
+// copies of an unscoped resolver.
+func parseScutilDNS(out string) []dnsScope {
+	var scopes []dnsScope
+	var cur *dnsScope
+	scopedSection := false
+	flush := func() {
+		if cur != nil && len(cur.Nameservers) > 0 {
+			cur.Default = !cur.Scoped && len(cur.Domains) == 0
+			scopes = append(scopes, *cur)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "DNS configuration"):
+			flush()
+			scopedSection = strings.Contains(line, "scoped")
+			continue
+		case reScutilResolver.MatchString(line):
+			flush()
+			cur = &dnsScope{Scoped: scopedSection}
+			continue
+		}
+		m := reScutilField.FindStringSubmatch(line)
+		if cur == nil || m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		switch strings.TrimSpace(m[1]) {
+		case "nameserver":
+			cur.Nameservers = append(cur.Nameservers, value)
+		case "search domain":
+			cur.Search = append(cur.Search, value)
+		case "domain":
+			cur.Domains = append(cur.Domains, strings.TrimSuffix(value, "."))
+		case "if_index":
+			if im := reScutilIfIndex.FindStringSubmatch(value); im != nil {
+				cur.Interface = im[1]
+			}
+		case "flags":
+			if strings.Contains(value, "Scoped") {
+				cur.Scoped = true
+			}
+		}
+	}
+	flush()
+
+	var unique []dnsScope
+	for _, s := range scopes {
+		dup := s.Scoped && slices.ContainsFunc(scopes, func(o dnsScope) bool {
+			return !o.Scoped && o.Interface == s.Interface && slices.Equal(o.Nameservers, s.Nameservers)
+		})
+		if !dup {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+var (
+	reResolvectlLink  = regexp.MustCompile(`^Link \d+ \((\S+)\)`)
+	reResolvectlField = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z ]*[A-Za-z]): (.*)$`)
+)
+
+// parseResolvectl reads `resolvectl status`. Domains prefixed with "~" only
+// route queries, others are search domains too; "~." makes a link the
+// default. Without a DefaultRoute setting, as before systemd 240, links
+// with route-only domains are not the default.
+func parseResolvectl(out string) []dnsScope {
+	var scopes []dnsScope
+	var cur *dnsScope
+	var defaultRoute *bool
+	routeOnly := false
+	key := ""
+	flush := func() {
+		if cur != nil && len(cur.Nameservers) > 0 {
+			switch {
+			case slices.Contains(cur.Domains, "."):
+				cur.Default = true
+				cur.Domains = slices.DeleteFunc(cur.Domains, func(d string) bool { return d == "." })
+			case defaultRoute != nil:
+				cur.Default = *defaultRoute
+			default:
+				cur.Default = !routeOnly
+			}
+			scopes = append(scopes, *cur)
+		}
+		cur, defaultRoute, routeOnly = nil, nil, false
+	}
+	setDefault := func(on bool) { defaultRoute = &on }
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "Global" {
+			flush()
+			cur = &dnsScope{}
+			continue
+		}
+		if m := reResolvectlLink.FindStringSubmatch(line); m != nil {
+			flush()
+			cur = &dnsScope{Interface: m[1]}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		// Long values continue on the next lines without a key; IPv6
+		// addresses hold colons but never ": ".
+		values := line
+		if strings.Contains(line, ": ") {
+			key, values = "", ""
+			if m := reResolvectlField.FindStringSubmatch(line); m != nil {
+				key, values = m[1], m[2]
+			}
+		} else if strings.TrimSpace(line) == "" {
+			key = ""
+		}
+		for _, v := range strings.Fields(values) {
+			switch key {
+			case "DNS Servers":
+				cur.Nameservers = append(cur.Nameservers, v)
+			case "DNS Domain":
+				if d, ok := strings.CutPrefix(v, "~"); ok {
+					routeOnly = true
+					if d != "." {
+						d = strings.TrimSuffix(d, ".")
+					}
+					cur.Domains = append(cur.Domains, d)
+				} else {
+					cur.Search = append(cur.Search, v)
+					cur.Domains = append(cur.Domains, v)
+				}
+			case "Protocols":
+				if v == "+DefaultRoute" || v == "-DefaultRoute" {
+					setDefault(v == "+DefaultRoute")
+				}
+			case "DefaultRoute setting":
+				setDefault(v == "yes")
+			}
+		}
+	}
+	flush()
+	return scopes
+}
+
+// CheckSplitDNS lists the resolvers of each interface with their search and
+// match domains (scutil --dns on macOS, resolvectl on Linux) and warns when
+// a VPN's resolvers shadow the local ones, or when a VPN pushes resolvers
+// that no query reaches: the usual causes of internal or local names not
+// resolving.
+func CheckSplitDNS(ctx context.Context) Result {
+	res := Result{Name: "Split DNS", Emoji: "🗺️", Status: StatusOk}
+	p := activePlatform()
+	out, err := runPlatformCommand(ctx, p.dnsConfigCommand())
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "DNS configuration not readable"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	scopes := p.parseDNSConfig(string(out))
+	if len(scopes) == 0 {
+		res.Status = StatusSkipped
+		res.Message = "No resolvers configured"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	var details []string
+	for _, s := range scopes {
+		details = append(details, describeScope(s))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeSplitDNS(&res, scopes)
+	return res
+}
+
+// describeScope renders s as "utun3 (corp.example.com): 10.8.0.1; search corp".
+func describeScope(s dnsScope) string {
+	name := s.Interface
+	if name == "" {
+		name = "global"
+	}
+	var matches []string
+	if s.Default {
+		matches = append(matches, "default")
+	}
+	matches = append(matches, s.Domains...)
+	if s.Scoped {
+		matches = append(matches, "scoped")
+	}
+	line := name
+	if len(matches) > 0 {
+		line += " (" + strings.Join(matches, ", ") + ")"
+	}
+	line += ": " + strings.Join(s.Nameservers, ", ")
+	if len(s.Search) > 0 {
+		line += "; search " + strings.Join(s.Search, " ")
+	}
+	return line
+}
+
+// isTunnelName reports whether name is a VPN tunnel interface.
+func isTunnelName(name string) bool {
+	return vpnKind(hostInterface{Name: name}) != ""
+}
+
+// gradeSplitDNS sets the verdict of CheckSplitDNS.
+func gradeSplitDNS(res *Result, scopes []dnsScope) {
+	var defaults, splits []string
+	var tunnelDefault *dnsScope
+	for i, s := range scopes {
+		if s.Default {
+			defaults = appendUnique(defaults, strings.Join(s.Nameservers, ", "))
+			if isTunnelName(s.Interface) && tunnelDefault == nil {
+				tunnelDefault = &scopes[i]
+			}
+		}
+		if len(s.Domains) > 0 && s.Interface != "" && !s.Scoped {
+			splits = append(splits, strings.Join(s.Domains, ", ")+" via "+s.Interface)
+		}
+	}
+
+	for _, s := range scopes {
+		if s.Interface == "" || isTunnelName(s.Interface) || tunnelDefault == nil {
+			continue
+		}
+		if !slices.Equal(s.Nameservers, tunnelDefault.Nameservers) && !s.Default {
+			res.Status = StatusWarning
+			res.Reason = ReasonDNSShadowed
+			res.Message = "VPN resolvers on " + tunnelDefault.Interface + " answer every query; " + s.Interface + "'s (" + strings.Join(s.Nameservers, ", ") + ") are shadowed"
+			res.Fix = "Names only the local network knows (printers, the router, *.local zones of the LAN) will not resolve; ask for split DNS that only claims the company's domains."
+			return
+		}
+	}
+	for _, s := range scopes {
+		if isTunnelName(s.Interface) && !s.Default && len(s.Domains) == 0 {
+			res.Status = StatusWarning
+			res.Reason = ReasonVPNDNSUnused
+			res.Message = "VPN resolvers on " + s.Interface + " (" + strings.Join(s.Nameservers, ", ") + ") serve no domain"
+			res.Fix = "Internal names go to the local resolver and fail; the VPN must push match domains (or be the default resolver). Reconnect, or check the VPN client's DNS settings."
+			return
+		}
+	}
+
+	switch {
+	case len(splits) > 0:
+		res.Message = "Split DNS: " + strings.Join(splits, "; ")
+	case len(defaults) == 1:
+		res.Message = "All names resolve through " + defaults[0]
+	default:
+		res.Message = strconv.Itoa(len(scopes)) + " resolver configurations"
+	}
+}
@@ -0,0 +1,169 @@
+package diagnostic
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+const scutilDNSVPN = `
+DNS configuration
+
+resolver #1
+  search domain[0] : corp.example.com
+  nameserver[0] : 10.8.0.1
+  nameserver[1] : 10.8.0.2
+  if_index : 20 (utun3)
+  flags    : Request A records
+  reach    : 0x00000003 (Reachable,Transient Connection)
+  order    : 102400
+
+resolver #2
+  domain   : local
+  options  : mdns
+  timeout  : 5
+  flags    : Request A records
+  reach    : 0x00000000 (Not Reachable)
+  order    : 300000
+
+resolver #3
+  domain   : 254.169.in-addr.arpa
+  options  : mdns
+  timeout  : 5
+  flags    : Request A records
+  reach    : 0x00000000 (Not Reachable)
+  order    : 300200
+
+DNS configuration (for scoped queries)
+
+resolver #1
+  search domain[0] : corp.example.com
+  nameserver[0] : 10.8.0.1
+  nameserver[1] : 10.8.0.2
+  if_index : 20 (utun3)
+  flags    : Scoped, Request A records
+  reach    : 0x00000003 (Reachable,Transient Connection)
+
+resolver #2
+  search domain[0] : home
+  nameserver[0] : 192.168.1.1
+  if_index : 14 (en0)
+  flags    : Scoped, Request A records
+  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+`
+
+const scutilDNSSplit = `
+DNS configuration
+
+resolver #1
+  search domain[0] : home
+  nameserver[0] : 192.168.1.1
+  if_index : 14 (en0)
+  flags    : Request A records
+  reach    : 0x00020002 (Reachable,Directly Reachable Address)
+
+resolver #2
+  domain   : corp.example.com
+  nameserver[0] : 10.8.0.1
+  if_index : 20 (utun3)
+  flags    : Supplemental, Request A records
+  reach    : 0x00000003 (Reachable,Transient Connection)
+  order    : 102600
+`
+
+func TestParseScutilDNS(t *testing.T) {
+	scopes := parseScutilDNS(scutilDNSVPN)
+	if len(scopes) != 2 {
+		t.Fatalf("Expected the VPN resolver and scoped en0, got %+v", scopes)
+	}
+	vpn, en0 := scopes[0], scopes[1]
+	if vpn.Interface != "utun3" || !vpn.Default || vpn.Scoped || !slices.Equal(vpn.Nameservers, []string{"10.8.0.1", "10.8.0.2"}) {
+		t.Errorf("Unexpected VPN scope %+v", vpn)
+	}
+	if en0.Interface != "en0" || en0.Default || !en0.Scoped || !slices.Equal(en0.Search, []string{"home"}) {
+		t.Errorf("Unexpected en0 scope %+v", en0)
+	}
+
+	scopes = parseScutilDNS(scutilDNSSplit)
+	if len(scopes) != 2 || !slices.Equal(scopes[1].Domains, []string{"corp.example.com"}) || scopes[1].Default {
+		t.Errorf("Expected a split scope for corp.example.com, got %+v", scopes)
+	}
+}
+
+func TestParseResolvectl(t *testing.T) {
+	out := `Global
+         Protocols: +LLMNR +mDNS -DNSOverTLS DNSSEC=no/unsupported
+  resolv.conf mode: stub
+
+Link 2 (wlan0)
+    Current Scopes: DNS
+         Protocols: +DefaultRoute +LLMNR -mDNS -DNSOverTLS DNSSEC=no/unsupported
+Current DNS Server: 192.168.1.1
+       DNS Servers: 192.168.1.1
+                    fd00::1
+        DNS Domain: home
+
+Link 5 (tun0)
+    Current Scopes: DNS
+         Protocols: -DefaultRoute +LLMNR -mDNS -DNSOverTLS DNSSEC=no/unsupported
+       DNS Servers: 10.8.0.1
+        DNS Domain: ~corp.example.com ~10.in-addr.arpa.
+
+Link 6 (wg0)
+    Current Scopes: DNS
+       DNS Servers: 10.9.0.1
+        DNS Domain: ~.
+`
+	scopes := parseResolvectl(out)
+	if len(scopes) != 3 {
+		t.Fatalf("Expected three links with servers, got %+v", scopes)
+	}
+	wlan, tun, wg := scopes[0], scopes[1], scopes[2]
+	if !wlan.Default || !slices.Equal(wlan.Nameservers, []string{"192.168.1.1", "fd00::1"}) || !slices.Equal(wlan.Search, []string{"home"}) {
+		t.Errorf("Unexpected wlan0 scope %+v", wlan)
+	}
+	if tun.Default || !slices.Equal(tun.Domains, []string{"corp.example.com", "10.in-addr.arpa"}) || len(tun.Search) != 0 {
+		t.Errorf("Unexpected tun0 scope %+v", tun)
+	}
+	if !wg.Default || len(wg.Domains) != 0 {
+		t.Errorf("Expected ~. to make wg0 the default, got %+v", wg)
+	}
+}
+
+func TestGradeSplitDNS(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []dnsScope
+		status Status
+		reason Reason
+	}{
+		{"single resolver", parseScutilDNS(scutilDNSSplit)[:1], StatusOk, ""},
+		{"split", parseScutilDNS(scutilDNSSplit), StatusOk, ""},
+		{"shadowed", parseScutilDNS(scutilDNSVPN), StatusWarning, ReasonDNSShadowed},
+		{"unused VPN resolvers", []dnsScope{
+			{Interface: "en0", Nameservers: []string{"192.168.1.1"}, Default: true},
+			{Interface: "tun0", Nameservers: []string{"10.8.0.1"}},
+		}, StatusWarning, ReasonVPNDNSUnused},
+		{"full tunnel, one resolver", []dnsScope{
+			{Interface: "utun3", Nameservers: []string{"10.8.0.1"}, Default: true},
+		}, StatusOk, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeSplitDNS(&res, tt.scopes)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestCheckSplitDNS(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{"scutil --dns": scutilDNSSplit}})
+	res := CheckSplitDNS(context.Background())
+	if res.Status != StatusOk || res.Message != "Split DNS: corp.example.com via utun3" {
+		t.Errorf("Expected the split scope in the message, got %v (%s)", res.Status, res.Message)
+	}
+	if len(res.Details) != 2 {
+		t.Errorf("Expected one detail per scope, got %v", res.Details)
+	}
+}
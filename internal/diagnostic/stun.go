@@ -0,0 +1,406 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// STUN (RFC 5389) message and attribute types used by CheckNATType.
+const (
+	stunBindingRequest       = 0x0001
+	stunBindingSuccess       = 0x0101
+	stunMagicCookie          = 0x2112A442
+	stunHeaderLen            = 20
+	stunAttrMappedAddress    = 0x0001
+	stunAttrChangeRequest    = 0x0003
+	stunAttrChangedAddress   = 0x0005
+	stunAttrXORMappedAddress = 0x0020
+	stunAttrOtherAddress     = 0x802C
+	// stunChangeIPAndPort asks the server to answer from its other address
+	// and port (RFC 5780), which only a full cone NAT lets through.
+	stunChangeIPAndPort = 0x06
+)
+
+// stunServers are the public STUN servers whose view of our address is
+// compared. They must be different hosts for the mapping test to mean
+// anything; tests replace them.
+var stunServers = []string{"stun.l.google.com:19302", "stun.cloudflare.com:3478"}
+
+// cgnatPrefix is the RFC 6598 shared address space used by carrier-grade NAT.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// Well-known keys of Result.Facts set by CheckNATType.
+const (
+	FactPublicIP = "public_ip"
+	FactNATType  = "nat_type"
+)
+
+// natFiltering is what the CHANGE-REQUEST probe revealed about inbound
+// filtering.
+type natFiltering int
+
+const (
+	// filteringUnknown: the server has no other address to answer from,
+	// or ignored the request and answered from the address we sent to.
+	filteringUnknown natFiltering = iota
+	// filteringOpen: a reply from another address got through.
+	filteringOpen
+	// filteringRestricted: no reply arrived.
+	filteringRestricted
+)
+
+// natObservation is what CheckNATType learned from the STUN servers.
+type natObservation struct {
+	// Local is the socket address the binding requests were sent from.
+	Local netip.AddrPort
+	// Mapped is the public address each answering server saw.
+	Mapped    []netip.AddrPort
+	Filtering natFiltering
+	// Hops are the first routers toward the WAN host, "" where they timed out.
+	Hops []string
+	// Tunnel is set when Local belongs to a VPN interface, whose addresses
+	// (Tailscale's come from 100.64.0.0/10) say nothing about the ISP.
+	Tunnel bool
+}
+
+// stunResponse is what one binding response told us.
+type stunResponse struct {
+	// Mapped is our address as the server saw it.
+	Mapped netip.AddrPort
+	// From is where the response came from.
+	From netip.AddrPort
+	// Other is set when the server names another address to answer from
+	// (OTHER-ADDRESS, or CHANGED-ADDRESS in RFC 3489), the sign that it
+	// honours CHANGE-REQUEST.
+	Other bool
+}
+
+// CheckNATType discovers the public address with STUN and classifies the NAT
+// by how it maps one local socket toward two servers: the same public port
+// for both is a cone NAT, a new port per destination is a symmetric NAT.
+// Shared address space (100.64.0.0/10) on the interface or the first hops
+// means carrier-grade NAT. Both break inbound and peer-to-peer connections,
+// which is why games and WebRTC calls need relays.
+func CheckNATType(ctx context.Context) Result {
+	res := Result{Name: "NAT Type", Emoji: "🧭", Status: StatusOk}
+
+	obs, err := observeNAT(ctx)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "STUN servers unreachable (UDP blocked?)"
+		res.Reason = ReasonUnreachable
+		res.Details = formatDetailsWithPrefixes([]string{"Error: " + err.Error()})
+		return res
+	}
+	if activeRunner().LookPath("ping") == nil {
+		obs.Hops = traceHops(ctx, activeConfig(ctx).WANHost, natTraceDepth)
+	}
+
+	obs.Tunnel = onTunnel(obs.Local.Addr())
+
+	details := []string{"Local: " + obs.Local.String()}
+	if obs.Tunnel {
+		details[0] += " (VPN)"
+	}
+	for i, m := range obs.Mapped {
+		details = append(details, fmt.Sprintf("Mapped via %s: %s", stunServers[i], m))
+	}
+	if obs.Filtering == filteringUnknown {
+		details = append(details, "Filtering: unknown, the server cannot answer from another address")
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeNAT(&res, obs)
+	return res
+}
+
+// gradeNAT sets the verdict of CheckNATType for obs, which has at least one
+// mapped address.
+func gradeNAT(res *Result, obs natObservation) {
+	public := obs.Mapped[0]
+	kind := classifyNAT(obs)
+	res.Facts = map[string]string{FactPublicIP: public.Addr().String(), FactNATType: kind}
+	res.Message = fmt.Sprintf("%s, public IP %s", kind, public.Addr())
+
+	cgnat := cgnatPrefix.Contains(public.Addr())
+	if !obs.Tunnel {
+		cgnat = cgnat || cgnatPrefix.Contains(obs.Local.Addr())
+		for _, hop := range obs.Hops {
+			if addr, err := netip.ParseAddr(hop); err == nil && cgnatPrefix.Contains(addr) {
+				cgnat = true
+			}
+		}
+	}
+	switch {
+	case cgnat:
+		res.Status = StatusWarning
+		res.Reason = ReasonCGNAT
+		res.Message = fmt.Sprintf("Carrier-grade NAT (%s), public IP %s", kind, public.Addr())
+		res.Fix = "Inbound connections and port forwarding cannot work. Ask the ISP for a public IPv4 address, or use IPv6 or a relay (TURN/VPN) for games and calls."
+	case kind == natSymmetric:
+		res.Status = StatusWarning
+		res.Reason = ReasonSymmetricNAT
+		res.Fix = "Peer-to-peer games and WebRTC calls will fall back to relays. Enable UPnP or NAT-PMP on the router, or forward the application's ports."
+	}
+}
+
+// NAT types reported by classifyNAT.
+const (
+	natNone       = "No NAT"
+	natFullCone   = "Full cone NAT"
+	natRestricted = "Restricted cone NAT"
+	natCone       = "Cone NAT"
+	natSymmetric  = "Symmetric NAT"
+)
+
+// classifyNAT names the NAT type from the mappings and the filtering probe.
+func classifyNAT(obs natObservation) string {
+	first := obs.Mapped[0]
+	if first == obs.Local {
+		return natNone
+	}
+	for _, m := range obs.Mapped[1:] {
+		if m != first {
+			return natSymmetric
+		}
+	}
+	switch obs.Filtering {
+	case filteringOpen:
+		return natFullCone
+	case filteringRestricted:
+		return natRestricted
+	}
+	return natCone
+}
+
+// observeNAT sends binding requests to every STUN server from one socket,
+// then, if the first server has another address, asks it to answer from
+// there. Most public servers have none, which leaves the filtering unknown
+// rather than making a silence look like a restricted NAT.
+func observeNAT(ctx context.Context) (natObservation, error) {
+	var obs natObservation
+	servers := make([]netip.AddrPort, 0, len(stunServers))
+	for _, s := range stunServers {
+		addr, err := resolveUDP4(ctx, s)
+		if err != nil {
+			return obs, err
+		}
+		servers = append(servers, addr)
+	}
+	if len(servers) == 0 {
+		return obs, errors.New("no STUN servers configured")
+	}
+
+	local, err := localIPv4Toward(servers[0])
+	if err != nil {
+		return obs, err
+	}
+	conn, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(netip.AddrPortFrom(local, 0)))
+	if err != nil {
+		return obs, err
+	}
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			slog.Debug("could not close STUN socket", "err", errClose)
+		}
+	}()
+	obs.Local = netip.AddrPortFrom(local, conn.LocalAddr().(*net.UDPAddr).AddrPort().Port())
+
+	other := false
+	for i, server := range servers {
+		resp, err := stunBinding(ctx, conn, server, false)
+		if err != nil {
+			return obs, fmt.Errorf("%s: %w", server, err)
+		}
+		obs.Mapped = append(obs.Mapped, resp.Mapped)
+		other = other || i == 0 && resp.Other
+	}
+	if !other {
+		return obs, nil
+	}
+
+	resp, err := stunBinding(ctx, conn, servers[0], true)
+	switch {
+	case err != nil:
+		obs.Filtering = filteringRestricted
+	case resp.From.Addr() != servers[0].Addr():
+		obs.Filtering = filteringOpen
+	}
+	return obs, nil
+}
+
+// stunPublicIP asks the first STUN server which address our traffic
+// reaches the internet from.
+func stunPublicIP(ctx context.Context) (netip.Addr, error) {
+	if len(stunServers) == 0 {
+		return netip.Addr{}, errors.New("no STUN servers configured")
+	}
+	server, err := resolveUDP4(ctx, stunServers[0])
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			slog.Debug("could not close STUN socket", "err", errClose)
+		}
+	}()
+	resp, err := stunBinding(ctx, conn, server, false)
+	return resp.Mapped.Addr(), err
+}
+
+// stunBinding sends one binding request to server and returns its answer.
+// change adds a CHANGE-REQUEST for the server's other address and port.
+func stunBinding(ctx context.Context, conn *net.UDPConn, server netip.AddrPort, change bool) (stunResponse, error) {
+	req := make([]byte, stunHeaderLen, stunHeaderLen+8)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	txn := req[8:stunHeaderLen]
+	if _, err := rand.Read(txn); err != nil {
+		return stunResponse{}, err
+	}
+	if change {
+		req = binary.BigEndian.AppendUint16(req, stunAttrChangeRequest)
+		req = binary.BigEndian.AppendUint16(req, 4)
+		req = binary.BigEndian.AppendUint32(req, stunChangeIPAndPort)
+		binary.BigEndian.PutUint16(req[2:], 8)
+	}
+
+	deadline := time.Now().Add(activeConfig(ctx).Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return stunResponse{}, err
+	}
+	if _, err := conn.WriteToUDPAddrPort(req, server); err != nil {
+		return stunResponse{}, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			return stunResponse{}, err
+		}
+		resp, err := parseSTUNResponse(buf[:n], txn)
+		if err != nil {
+			// A stray or stale packet; keep waiting for ours.
+			continue
+		}
+		resp.From = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+		return resp, nil
+	}
+}
+
+// parseSTUNResponse reads a binding success response to the transaction
+// txn: the mapped address, preferring XOR-MAPPED-ADDRESS, and whether the
+// server has another address.
+func parseSTUNResponse(msg, txn []byte) (stunResponse, error) {
+	if len(msg) < stunHeaderLen ||
+		binary.BigEndian.Uint16(msg[0:]) != stunBindingSuccess ||
+		binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie ||
+		string(msg[8:stunHeaderLen]) != string(txn) {
+		return stunResponse{}, errors.New("not a STUN binding response")
+	}
+	attrs := msg[stunHeaderLen:]
+	if n := int(binary.BigEndian.Uint16(msg[2:])); n <= len(attrs) {
+		attrs = attrs[:n]
+	}
+
+	var resp stunResponse
+	var mapped netip.AddrPort
+	for len(attrs) >= 4 {
+		typ := binary.BigEndian.Uint16(attrs[0:])
+		length := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+length > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+length]
+		switch typ {
+		case stunAttrXORMappedAddress:
+			if addr, ok := parseSTUNAddress(value, true); ok {
+				resp.Mapped = addr
+			}
+		case stunAttrMappedAddress:
+			if addr, ok := parseSTUNAddress(value, false); ok {
+				mapped = addr
+			}
+		case stunAttrOtherAddress, stunAttrChangedAddress:
+			resp.Other = true
+		}
+		// Attributes are padded to a multiple of 4 bytes.
+		attrs = attrs[min(4+(length+3)&^3, len(attrs)):]
+	}
+	if !resp.Mapped.IsValid() {
+		resp.Mapped = mapped
+	}
+	if !resp.Mapped.IsValid() {
+		return stunResponse{}, errors.New("STUN response has no mapped address")
+	}
+	return resp, nil
+}
+
+// parseSTUNAddress decodes an IPv4 (XOR-)MAPPED-ADDRESS value.
+func parseSTUNAddress(value []byte, xor bool) (netip.AddrPort, bool) {
+	const familyIPv4 = 0x01
+	if len(value) != 8 || value[1] != familyIPv4 {
+		return netip.AddrPort{}, false
+	}
+	port := binary.BigEndian.Uint16(value[2:])
+	ip := binary.BigEndian.Uint32(value[4:])
+	if xor {
+		port ^= stunMagicCookie >> 16
+		ip ^= stunMagicCookie
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], ip)
+	return netip.AddrPortFrom(netip.AddrFrom4(b), port), true
+}
+
+// resolveUDP4 resolves a "host:port" STUN server to an IPv4 address.
+func resolveUDP4(ctx context.Context, hostport string) (netip.AddrPort, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	p, err := net.LookupPort("udp", port)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(addrs[0].Unmap(), uint16(p)), nil
+}
+
+// localIPv4Toward returns the source address the kernel picks for server.
+// Connecting a UDP socket sends nothing.
+func localIPv4Toward(server netip.AddrPort) (netip.Addr, error) {
+	conn, err := net.DialUDP("udp4", nil, net.UDPAddrFromAddrPort(server))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			slog.Debug("could not close UDP socket", "err", errClose)
+		}
+	}()
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort().Addr().Unmap()
+	if local.IsUnspecified() {
+		return netip.Addr{}, errors.New("no IPv4 source address")
+	}
+	return local, nil
+}
@@ -0,0 +1,205 @@
+package diagnostic
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// stunReply builds a binding success response for txn carrying mapped as
+// XOR-MAPPED-ADDRESS, or as MAPPED-ADDRESS when xor is false.
+func stunReply(txn []byte, mapped netip.AddrPort, xor bool) []byte {
+	msg := make([]byte, stunHeaderLen)
+	binary.BigEndian.PutUint16(msg[0:], stunBindingSuccess)
+	binary.BigEndian.PutUint16(msg[2:], 12)
+	binary.BigEndian.PutUint32(msg[4:], stunMagicCookie)
+	copy(msg[8:], txn)
+
+	typ, port, ip := uint16(stunAttrMappedAddress), mapped.Port(), binary.BigEndian.Uint32(mapped.Addr().AsSlice())
+	if xor {
+		typ, port, ip = stunAttrXORMappedAddress, port^stunMagicCookie>>16, ip^stunMagicCookie
+	}
+	msg = binary.BigEndian.AppendUint16(msg, typ)
+	msg = binary.BigEndian.AppendUint16(msg, 8)
+	msg = append(msg, 0, 0x01)
+	msg = binary.BigEndian.AppendUint16(msg, port)
+	return binary.BigEndian.AppendUint32(msg, ip)
+}
+
+// withOtherAddress appends an OTHER-ADDRESS attribute to a binding response.
+func withOtherAddress(msg []byte) []byte {
+	msg = binary.BigEndian.AppendUint16(msg, stunAttrOtherAddress)
+	msg = binary.BigEndian.AppendUint16(msg, 8)
+	msg = append(msg, 0, 0x01, 0x0d, 0x97, 192, 0, 2, 1)
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)-stunHeaderLen))
+	return msg
+}
+
+// fakeSTUNServer answers every binding request on loopback with mapped.
+func fakeSTUNServer(t *testing.T, mapped netip.AddrPort) string {
+	t.Helper()
+	return fakeSTUNServerWith(t, mapped, false)
+}
+
+// fakeSTUNServerWith is fakeSTUNServer, advertising another address when
+// other is set. Its answers from that address never get through, as
+// behind a restricted NAT.
+func fakeSTUNServerWith(t *testing.T, mapped netip.AddrPort, other bool) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderLen {
+				continue
+			}
+			reply := stunReply(buf[8:stunHeaderLen], mapped, true)
+			if other {
+				if n > stunHeaderLen {
+					continue
+				}
+				reply = withOtherAddress(reply)
+			}
+			_, _ = conn.WriteToUDPAddrPort(reply, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func withSTUNServers(t *testing.T, servers ...string) {
+	t.Helper()
+	prev := stunServers
+	stunServers = servers
+	t.Cleanup(func() { stunServers = prev })
+}
+
+func TestParseSTUNResponse(t *testing.T) {
+	txn := []byte("0123456789ab")
+	want := netip.MustParseAddrPort("203.0.113.7:40000")
+	for _, xor := range []bool{true, false} {
+		got, err := parseSTUNResponse(stunReply(txn, want, xor), txn)
+		if err != nil || got.Mapped != want || got.Other {
+			t.Errorf("xor=%v: expected %v, got %+v (%v)", xor, want, got, err)
+		}
+	}
+	if got, err := parseSTUNResponse(withOtherAddress(stunReply(txn, want, true)), txn); err != nil || got.Mapped != want || !got.Other {
+		t.Errorf("Expected %v with another address, got %+v (%v)", want, got, err)
+	}
+	if _, err := parseSTUNResponse(stunReply(txn, want, true), []byte("another txn!")); err == nil {
+		t.Error("Expected a response to another transaction to be rejected")
+	}
+	if _, err := parseSTUNResponse(make([]byte, 8), txn); err == nil {
+		t.Error("Expected a short packet to be rejected")
+	}
+}
+
+func TestClassifyNAT(t *testing.T) {
+	local := netip.MustParseAddrPort("192.168.1.10:50000")
+	a := netip.MustParseAddrPort("203.0.113.7:40000")
+	b := netip.MustParseAddrPort("203.0.113.7:40001")
+	tests := []struct {
+		name string
+		obs  natObservation
+		want string
+	}{
+		{"public address", natObservation{Local: a, Mapped: []netip.AddrPort{a, a}}, natNone},
+		{"symmetric", natObservation{Local: local, Mapped: []netip.AddrPort{a, b}}, natSymmetric},
+		{"full cone", natObservation{Local: local, Mapped: []netip.AddrPort{a, a}, Filtering: filteringOpen}, natFullCone},
+		{"restricted", natObservation{Local: local, Mapped: []netip.AddrPort{a, a}, Filtering: filteringRestricted}, natRestricted},
+		{"unknown filtering", natObservation{Local: local, Mapped: []netip.AddrPort{a, a}}, natCone},
+	}
+	for _, tt := range tests {
+		if got := classifyNAT(tt.obs); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCheckNATTypeSymmetric(t *testing.T) {
+	withRunner(t, &fakeRunner{})
+	withSTUNServers(t,
+		fakeSTUNServer(t, netip.MustParseAddrPort("203.0.113.7:40000")),
+		fakeSTUNServer(t, netip.MustParseAddrPort("203.0.113.7:40001")),
+	)
+
+	res := CheckNATType(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonSymmetricNAT {
+		t.Fatalf("Expected a symmetric_nat warning, got %v/%s (%s)", res.Status, res.Reason, res.Message)
+	}
+	if res.Facts[FactPublicIP] != "203.0.113.7" {
+		t.Errorf("Expected public IP 203.0.113.7, got %q", res.Facts[FactPublicIP])
+	}
+}
+
+func TestCheckNATTypeCGNAT(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"ping -c 1 -t 1 1.1.1.1": "92 bytes from 192.168.1.1: Time to live exceeded\n",
+		"ping -c 1 -t 2 1.1.1.1": "92 bytes from 100.64.12.1: Time to live exceeded\n",
+		"ping -c 1 -t 3 1.1.1.1": "92 bytes from 203.0.113.1: Time to live exceeded\n",
+	}})
+	mapped := netip.MustParseAddrPort("203.0.113.7:40000")
+	withSTUNServers(t, fakeSTUNServer(t, mapped), fakeSTUNServer(t, mapped))
+
+	res := CheckNATType(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonCGNAT {
+		t.Errorf("Expected a cgnat warning, got %v/%s (%s)", res.Status, res.Reason, res.Message)
+	}
+	if res.Facts[FactNATType] != natCone {
+		t.Errorf("Expected %q, got %q", natCone, res.Facts[FactNATType])
+	}
+}
+
+func TestCheckNATTypeFiltering(t *testing.T) {
+	withRunner(t, &fakeRunner{})
+	c := DefaultConfig()
+	c.Timeout = 300 * time.Millisecond
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+	mapped := netip.MustParseAddrPort("203.0.113.7:40000")
+	tests := []struct {
+		name  string
+		other bool
+		want  string
+	}{
+		{"no other address", false, natCone},
+		{"change request dropped", true, natRestricted},
+	}
+	for _, tt := range tests {
+		withSTUNServers(t, fakeSTUNServerWith(t, mapped, tt.other), fakeSTUNServer(t, mapped))
+		if got := CheckNATType(context.Background()).Facts[FactNATType]; got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestGradeNATTunnel(t *testing.T) {
+	obs := natObservation{
+		Local:  netip.MustParseAddrPort("100.101.7.9:50000"),
+		Mapped: []netip.AddrPort{netip.MustParseAddrPort("203.0.113.7:40000")},
+		Hops:   []string{"100.100.0.1", "203.0.113.1"},
+		Tunnel: true,
+	}
+	res := Result{Status: StatusOk}
+	gradeNAT(&res, obs)
+	if res.Reason == ReasonCGNAT {
+		t.Errorf("Expected a Tailscale address not to count as CGNAT, got %s", res.Message)
+	}
+	obs.Tunnel = false
+	res = Result{Status: StatusOk}
+	gradeNAT(&res, obs)
+	if res.Reason != ReasonCGNAT {
+		t.Errorf("Expected a shared local address to mean CGNAT, got %q", res.Reason)
+	}
+}
@@ -0,0 +1,162 @@
+package diagnostic
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// talkersInterval is how long CheckTalkers watches the counters.
+	talkersInterval = 2 * time.Second
+	// talkersShown is how many processes the details list.
+	talkersShown = 5
+	// hogMbps is the rate, either way, from which one process is enough to
+	// make calls choppy on a typical home uplink.
+	hogMbps = 10
+	// idleMbps is the rate below which a process is not worth naming.
+	idleMbps = 0.1
+)
+
+// FactTopTalker is the process moving the most data, as "name (pid)".
+const FactTopTalker = "top_talker"
+
+// talker is the throughput of one process while it was sampled.
+type talker struct {
+	Process string
+	PID     int
+	InMbps  float64
+	OutMbps float64
+}
+
+func (t talker) total() float64 { return t.InMbps + t.OutMbps }
+
+// name is the process name with its PID, when known.
+func (t talker) name() string {
+	if t.PID == 0 {
+		return t.Process
+	}
+	return fmt.Sprintf("%s (%d)", t.Process, t.PID)
+}
+
+// parseNettop reads `nettop -P -x -L 2 -J bytes_in,bytes_out`: two CSV
+// samples of every process's byte counters, each under its own header, and
+// returns the rate of each process between them. Processes missing from
+// the first sample are left out, since their counters may span far more
+// than the interval.
+func parseNettop(output string, interval time.Duration) []talker {
+	type counters struct{ in, out int64 }
+	var samples []map[string]counters
+	inCol, outCol := -1, -1
+	for line := range strings.Lines(output) {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) > 0 && fields[0] == "time" {
+			inCol, outCol = slices.Index(fields, "bytes_in"), slices.Index(fields, "bytes_out")
+			samples = append(samples, map[string]counters{})
+			continue
+		}
+		if len(samples) == 0 || inCol < 0 || outCol < 0 || len(fields) <= max(inCol, outCol) || fields[1] == "" {
+			continue
+		}
+		in, errIn := strconv.ParseInt(fields[inCol], 10, 64)
+		out, errOut := strconv.ParseInt(fields[outCol], 10, 64)
+		if errIn == nil && errOut == nil {
+			samples[len(samples)-1][fields[1]] = counters{in, out}
+		}
+	}
+	if len(samples) < 2 {
+		return nil
+	}
+	first, last := samples[0], samples[len(samples)-1]
+
+	var talkers []talker
+	for key, end := range last {
+		start, ok := first[key]
+		if !ok {
+			continue
+		}
+		t := talker{
+			Process: key,
+			// Counters of closed sockets drop out, so a rate may go negative.
+			InMbps:  mbps(max(end.in-start.in, 0), interval),
+			OutMbps: mbps(max(end.out-start.out, 0), interval),
+		}
+		// nettop names each process "name.pid".
+		if i := strings.LastIndex(key, "."); i > 0 {
+			if pid, err := strconv.Atoi(key[i+1:]); err == nil {
+				t.Process, t.PID = key[:i], pid
+			}
+		}
+		talkers = append(talkers, t)
+	}
+	slices.SortFunc(talkers, func(a, b talker) int {
+		return cmp.Or(cmp.Compare(b.total(), a.total()), cmp.Compare(a.Process, b.Process))
+	})
+	return talkers
+}
+
+// CheckTalkers samples per-process network throughput for a couple of
+// seconds and names the processes moving the most data, so a choppy call
+// can be pinned on a backup uploading in the background. It only runs with
+// -v, or as "wtfi top".
+func CheckTalkers(ctx context.Context, verbose bool) Result {
+	res := Result{Name: "Top Talkers", Emoji: "📊", Status: StatusOk}
+	if !verbose {
+		res.Status = StatusSkipped
+		res.Message = "Use -v flag to sample per-process throughput"
+		return res
+	}
+	p := activePlatform()
+	cmd := p.talkersCommand(talkersInterval)
+	if cmd == nil {
+		res.Status = StatusSkipped
+		res.Message = "Per-process throughput is not available on this platform"
+		return res
+	}
+	out, err := runPlatformCommand(ctx, cmd)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "Could not sample per-process throughput"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	gradeTalkers(&res, p.parseTalkers(string(out), talkersInterval))
+	return res
+}
+
+// gradeTalkers sets the verdict of CheckTalkers from talkers, busiest first.
+func gradeTalkers(res *Result, talkers []talker) {
+	var details []string
+	for _, t := range talkers[:min(len(talkers), talkersShown)] {
+		if t.total() < idleMbps {
+			break
+		}
+		details = append(details, fmt.Sprintf("%s: ↓ %.1f Mbps / ↑ %.1f Mbps", t.name(), t.InMbps, t.OutMbps))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	if len(details) == 0 {
+		res.Message = "No process is using the network"
+		return
+	}
+
+	top := talkers[0]
+	res.Facts = map[string]string{FactTopTalker: top.name()}
+	verb, rate := "downloading", top.InMbps
+	if top.OutMbps > top.InMbps {
+		verb, rate = "uploading", top.OutMbps
+	}
+	res.Message = fmt.Sprintf("%s is %s at %.1f Mbps", top.Process, verb, rate)
+	if rate < hogMbps {
+		return
+	}
+	res.Status = StatusWarning
+	res.Reason = ReasonBandwidthHog
+	res.Fix = fmt.Sprintf("Pause or quit %s while on calls, or limit its bandwidth in its settings.", top.Process)
+}
@@ -0,0 +1,70 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseNettop(t *testing.T) {
+	out := "time,,bytes_in,bytes_out,\n" +
+		"10:00:00.000001,Backup.412,1000,5000000,\n" +
+		"10:00:00.000002,Safari.812,2000000,1000,\n" +
+		"10:00:00.000003,mDNSResponder.321,500,500,\n" +
+		"time,,bytes_in,bytes_out,\n" +
+		"10:00:02.000001,Backup.412,1000,15000000,\n" +
+		"10:00:02.000002,Safari.812,2500000,1000,\n" +
+		"10:00:02.000003,mDNSResponder.321,400,500,\n" +
+		"10:00:02.000004,zoom.us.990,9000000,9000000,\n"
+	got := parseNettop(out, 2*time.Second)
+	want := []talker{
+		{"Backup", 412, 0, 40},
+		{"Safari", 812, 2, 0},
+		{"mDNSResponder", 321, 0, 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d talkers, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("talker %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+	if parseNettop("time,,bytes_in,bytes_out,\n10:00:00.0,Backup.412,1,1,\n", time.Second) != nil {
+		t.Error("expected no rates from a single sample")
+	}
+}
+
+func TestGradeTalkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		talkers []talker
+		status  Status
+		message string
+	}{
+		{"idle", []talker{{"mDNSResponder", 321, 0.01, 0}}, StatusOk, "No process is using the network"},
+		{"browsing", []talker{{"Safari", 812, 2, 0.1}}, StatusOk, "Safari is downloading at 2.0 Mbps"},
+		{"backup", []talker{{"Backup", 412, 0.2, 40}, {"Safari", 812, 2, 0}}, StatusWarning, "Backup is uploading at 40.0 Mbps"},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeTalkers(&res, tt.talkers)
+		if res.Status != tt.status || res.Message != tt.message {
+			t.Errorf("%s: expected %v %q, got %v %q", tt.name, tt.status, tt.message, res.Status, res.Message)
+		}
+	}
+}
+
+func TestCheckTalkers(t *testing.T) {
+	if res := CheckTalkers(context.Background(), false); res.Status != StatusSkipped || res.Facts != nil {
+		t.Errorf("expected no sampling without -v, got %+v", res)
+	}
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"nettop -P -x -L 2 -s 2 -J bytes_in,bytes_out": "time,,bytes_in,bytes_out,\n" +
+			"10:00:00.0,Backup.412,0,0,\ntime,,bytes_in,bytes_out,\n10:00:02.0,Backup.412,0,5000000,\n",
+	}})
+	res := CheckTalkers(context.Background(), true)
+	if res.Status != StatusWarning || res.Reason != ReasonBandwidthHog || res.Facts[FactTopTalker] != "Backup (412)" {
+		t.Errorf("expected Backup flagged as a bandwidth hog, got %v %q %v", res.Status, res.Message, res.Facts)
+	}
+}
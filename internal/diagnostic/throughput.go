@@ -0,0 +1,257 @@
+package diagnostic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Default speed test endpoints: Cloudflare's speed API, which serves the
+// number of bytes asked for in the "bytes" query parameter and accepts
+// uploads of any size.
+const (
+	speedDownloadURL = "https://speed.cloudflare.com/__down"
+	speedUploadURL   = "https://speed.cloudflare.com/__up"
+)
+
+// iperfPort is iperf3's default server port; iperfSeconds is how long each
+// direction of an iperf3 test runs.
+const (
+	iperfPort    = "5201"
+	iperfSeconds = 5
+)
+
+// Well-known throughput keys of Result.Facts.
+const (
+	FactDownloadMbps = "download_mbps"
+	FactUploadMbps   = "upload_mbps"
+)
+
+// CheckThroughput measures download and upload speed against the HTTP speed
+// endpoints, or an iperf3 server when one is configured, and warns when
+// either direction is below its floor or upload is starved.
+func CheckThroughput(ctx context.Context) Result {
+	res := Result{Name: "Throughput", Emoji: "⚡", Status: StatusOk}
+	c := activeConfig(ctx)
+
+	var down, up float64
+	var errDown, errUp error
+	var via string
+	if c.IperfServer != "" {
+		if r, ok := missingToolResult(res.Name, res.Emoji, activeRunner().LookPath("iperf3")); ok {
+			return r
+		}
+		down, errDown = measureIperf(ctx, c.IperfServer, true, c.SpeedTimeout)
+		up, errUp = measureIperf(ctx, c.IperfServer, false, c.SpeedTimeout)
+		via = "iperf3 " + c.IperfServer
+	} else {
+		down, errDown = measureDownload(ctx, c.SpeedDownloadURL, c.DownloadBytes, c.SpeedTimeout)
+		up, errUp = measureUpload(ctx, c.SpeedUploadURL, c.UploadBytes, c.SpeedTimeout)
+	}
+
+	var details []string
+	if errDown != nil {
+		details = append(details, fmt.Sprintf("Download: failed (%v)", errDown))
+	} else if via != "" {
+		details = append(details, fmt.Sprintf("Download: %.1f Mbps (%s)", down, via))
+	} else {
+		details = append(details, fmt.Sprintf("Download: %.1f Mbps (%s)", down, formatBytes(c.DownloadBytes)))
+	}
+	if errUp != nil {
+		details = append(details, fmt.Sprintf("Upload: failed (%v)", errUp))
+	} else if via != "" {
+		details = append(details, fmt.Sprintf("Upload: %.1f Mbps (%s)", up, via))
+	} else {
+		details = append(details, fmt.Sprintf("Upload: %.1f Mbps (%s)", up, formatBytes(c.UploadBytes)))
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+
+	if errDown != nil && errUp != nil {
+		res.Status = StatusError
+		res.Message = "Speed test endpoint unreachable"
+		res.Reason = ReasonUnreachable
+		return res
+	}
+
+	res.Facts = map[string]string{}
+	if errDown == nil {
+		res.Facts[FactDownloadMbps] = strconv.FormatFloat(down, 'f', 1, 64)
+	}
+	if errUp == nil {
+		res.Facts[FactUploadMbps] = strconv.FormatFloat(up, 'f', 1, 64)
+	}
+	res.Message = fmt.Sprintf("↓ %s / ↑ %s", formatRate(down, errDown), formatRate(up, errUp))
+	gradeThroughput(&res, down, up, errDown == nil, errUp == nil, c)
+	if res.Status == StatusOk && (errDown != nil || errUp != nil) {
+		res.Status = StatusWarning
+		res.Reason = ReasonUnreachable
+		res.Fix = "One direction of the speed test could not reach its endpoint; rerun, or set speed.iperf_server to test against another server."
+	}
+	return res
+}
+
+// formatRate returns "failed" for a direction that could not be measured.
+func formatRate(mbps float64, err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return fmt.Sprintf("%.1f Mbps", mbps)
+}
+
+// gradeThroughput sets the warning of CheckThroughput. A direction that was
+// not measured (ok false) is not graded.
+func gradeThroughput(res *Result, down, up float64, okDown, okUp bool, c Config) {
+	switch {
+	case okDown && c.MinDownloadMbps > 0 && down < c.MinDownloadMbps:
+		res.Status = StatusWarning
+		res.Reason = ReasonSlowThroughput
+		res.Message += fmt.Sprintf(" (download below %g Mbps)", c.MinDownloadMbps)
+		res.Fix = "Move closer to the access point or use Ethernet, then rerun; if it stays slow, compare with your ISP plan."
+	case okUp && c.MinUploadMbps > 0 && up < c.MinUploadMbps:
+		res.Status = StatusWarning
+		res.Reason = ReasonSlowThroughput
+		res.Message += fmt.Sprintf(" (upload below %g Mbps)", c.MinUploadMbps)
+		res.Fix = "Pause cloud backups or uploads, then rerun; if it stays slow, compare with your ISP plan."
+	case okDown && okUp && uploadStarved(down, up, c.MinUploadRatio):
+		res.Status = StatusWarning
+		res.Reason = ReasonUploadStarved
+		res.Message += fmt.Sprintf(" (upload is %.0f%% of download)", up/down*100)
+		res.Fix = "Pause cloud backups or uploads; check for upstream congestion during calls."
+	}
+}
+
+// mbps converts a transfer of n bytes over elapsed into megabits per second.
+func mbps(n int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / elapsed.Seconds() / 1e6
+}
+
+// uploadStarved reports whether upload is implausibly low relative to download.
+func uploadStarved(down, up, minRatio float64) bool {
+	return down > 0 && up/down < minRatio
+}
+
+// measureDownload fetches size bytes from endpoint, passed as the "bytes"
+// query parameter.
+func measureDownload(ctx context.Context, endpoint string, size int64, timeout time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	q := u.Query()
+	q.Set("bytes", strconv.FormatInt(size, 10))
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return mbps(n, time.Since(start)), nil
+}
+
+// measureUpload POSTs size bytes to endpoint.
+func measureUpload(ctx context.Context, endpoint string, size int64, timeout time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(make([]byte, size)))
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+	if errClose := resp.Body.Close(); errClose != nil {
+		slog.Debug("could not close response body", "err", errClose)
+	}
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return mbps(size, elapsed), nil
+}
+
+// measureIperf runs an iperf3 client against server ("host" or "host:port")
+// for iperfSeconds and returns the received rate; reverse makes the server
+// send, measuring download.
+func measureIperf(ctx context.Context, server string, reverse bool, timeout time.Duration) (float64, error) {
+	host, port := server, iperfPort
+	if h, p, err := net.SplitHostPort(server); err == nil {
+		host, port = h, p
+	}
+	args := []string{"-c", host, "-p", port, "-J", "-t", strconv.Itoa(iperfSeconds)}
+	if reverse {
+		args = append(args, "-R")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := runCommand(ctx, "iperf3", args...)
+	rate, errParse := parseIperf(out)
+	if errParse != nil && err != nil {
+		return 0, err
+	}
+	return rate, errParse
+}
+
+// parseIperf reads the receiver's rate from iperf3's JSON report (-J). A
+// failed test still prints JSON, with the cause in "error".
+func parseIperf(out []byte) (float64, error) {
+	var report struct {
+		End struct {
+			SumReceived struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return 0, fmt.Errorf("unreadable iperf3 report: %w", err)
+	}
+	if report.Error != "" {
+		return 0, errors.New("iperf3: " + report.Error)
+	}
+	if report.End.SumReceived.BitsPerSecond <= 0 {
+		return 0, errors.New("iperf3 reported no throughput")
+	}
+	return report.End.SumReceived.BitsPerSecond / 1e6, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,153 @@
+package diagnostic
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMbps(t *testing.T) {
+	tests := []struct {
+		bytes   int64
+		elapsed time.Duration
+		want    float64
+	}{
+		{1_250_000, time.Second, 10},
+		{12_500_000, 2 * time.Second, 50},
+		{125_000, 100 * time.Millisecond, 10},
+		{1_000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := mbps(tt.bytes, tt.elapsed); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("mbps(%d, %v): expected %.2f, got %.2f", tt.bytes, tt.elapsed, tt.want, got)
+		}
+	}
+}
+
+func TestUploadStarved(t *testing.T) {
+	if !uploadStarved(500, 2, 0.05) {
+		t.Error("Expected 2 Mbps up on 500 Mbps down to be starved")
+	}
+	if uploadStarved(100, 10, 0.05) {
+		t.Error("Expected a 1:10 cable link not to be starved")
+	}
+	if uploadStarved(0, 0, 0.05) {
+		t.Error("Expected no verdict without a download measurement")
+	}
+}
+
+func TestCheckThroughputStarvedReason(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			// A slow upload against a fast download.
+			_, _ = io.Copy(io.Discard, r.Body)
+			time.Sleep(200 * time.Millisecond)
+			return
+		}
+		_, _ = w.Write(make([]byte, 1<<20))
+	}))
+	defer srv.Close()
+	c := DefaultConfig()
+	c.SpeedDownloadURL, c.SpeedUploadURL = srv.URL+"/__down", srv.URL+"/__up"
+	c.DownloadBytes, c.UploadBytes = 1<<20, 1<<10
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	res := CheckThroughput(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonUploadStarved {
+		t.Errorf("Expected an upload_starved warning, got %v/%s (%s)", res.Status, res.Reason, res.Message)
+	}
+}
+
+func TestCheckThroughputOneDirectionFailed(t *testing.T) {
+	c := DefaultConfig()
+	c.IperfServer = "iperf.example.net:5202"
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"iperf3 -c iperf.example.net -p 5202 -J -t 5 -R": `{"end":{"sum_received":{"bits_per_second":1.2e8}}}`,
+	}})
+
+	res := CheckThroughput(context.Background())
+	if res.Message != "↓ 120.0 Mbps / ↑ failed" {
+		t.Errorf("Expected the failed upload to read as failed, got %q", res.Message)
+	}
+	if res.Status != StatusWarning || res.Reason != ReasonUnreachable {
+		t.Errorf("Expected an unreachable warning, got %v/%s", res.Status, res.Reason)
+	}
+	if _, ok := res.Facts[FactUploadMbps]; ok {
+		t.Errorf("Expected no upload fact, got %v", res.Facts)
+	}
+}
+
+func TestParseIperf(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    float64
+		wantErr bool
+	}{
+		{"report", `{"end":{"sum_sent":{"bits_per_second":9.6e7},"sum_received":{"bits_per_second":9.4e7}}}`, 94, false},
+		{"server busy", `{"start":{},"end":{},"error":"the server is busy running a test. try again later"}`, 0, true},
+		{"no result", `{"end":{}}`, 0, true},
+		{"not json", "iperf3: error - unable to connect to server", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIperf([]byte(tt.out))
+		if (err != nil) != tt.wantErr || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected %.1f (error %v), got %.1f (%v)", tt.name, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestGradeThroughput(t *testing.T) {
+	c := DefaultConfig()
+	c.MinDownloadMbps, c.MinUploadMbps = 50, 10
+	tests := []struct {
+		name         string
+		down, up     float64
+		okDown, okUp bool
+		want         Reason
+	}{
+		{"fast", 200, 40, true, true, ""},
+		{"slow download", 20, 20, true, true, ReasonSlowThroughput},
+		{"slow upload", 200, 5, true, true, ReasonSlowThroughput},
+		{"starved", 900, 30, true, true, ReasonUploadStarved},
+		{"download failed", 0, 40, false, true, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeThroughput(&res, tt.down, tt.up, tt.okDown, tt.okUp, c)
+		if res.Reason != tt.want {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, tt.want, res.Reason)
+		}
+		if (res.Status == StatusWarning) != (tt.want != "") {
+			t.Errorf("%s: unexpected status %v", tt.name, res.Status)
+		}
+	}
+}
+
+func TestCheckThroughputIperf(t *testing.T) {
+	c := DefaultConfig()
+	c.IperfServer = "iperf.example.net:5202"
+	prevCfg := activeConfig(context.Background())
+	SetConfig(c)
+	t.Cleanup(func() { SetConfig(prevCfg) })
+
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"iperf3 -c iperf.example.net -p 5202 -J -t 5 -R": `{"end":{"sum_received":{"bits_per_second":1.2e8}}}`,
+		"iperf3 -c iperf.example.net -p 5202 -J -t 5":    `{"end":{"sum_received":{"bits_per_second":2.0e7}}}`,
+	}})
+
+	res := CheckThroughput(context.Background())
+	if res.Status != StatusOk || res.Facts[FactDownloadMbps] != "120.0" || res.Facts[FactUploadMbps] != "20.0" {
+		t.Errorf("Expected 120/20 Mbps, got %v %v (%s)", res.Status, res.Facts, res.Message)
+	}
+}
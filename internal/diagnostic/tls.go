@@ -0,0 +1,107 @@
+package diagnostic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// certExpiryWarning is how close to expiry a certificate may get before we warn.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// CheckTLS performs a TLS handshake with host:443 and inspects the served certificate.
+func CheckTLS(ctx context.Context, host string, verbose bool) Result {
+	if host == "" {
+		host = activeConfig(ctx).TLSHost
+	}
+	res := Result{Name: "TLS (" + host + ")", Emoji: "🔒", Status: StatusOk}
+
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: activeConfig(ctx).Timeout}, Config: &tls.Config{ServerName: host}}
+	start := time.Now()
+	netConn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		res.Status = StatusError
+		res.Reason = ReasonTLSFailed
+		res.Message, res.Fix = classifyTLSError(err)
+		return res
+	}
+	res.Latency = time.Since(start)
+	conn := netConn.(*tls.Conn)
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			slog.Debug("could not close TLS connection", "err", errClose)
+		}
+	}()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		res.Status = StatusError
+		res.Message = "Server presented no certificate"
+		res.Reason = ReasonTLSFailed
+		return res
+	}
+	leaf := state.PeerCertificates[0]
+
+	res.Status, res.Reason, res.Message, res.Fix = evaluateCertificate(leaf, len(state.PeerCertificates), time.Now())
+	if res.Status == StatusOk {
+		res.Message = fmt.Sprintf("%s, expires in %d days", tls.VersionName(state.Version), daysUntil(leaf.NotAfter, time.Now()))
+	}
+
+	if verbose {
+		details := []string{
+			"Version: " + tls.VersionName(state.Version),
+			"Cipher: " + tls.CipherSuiteName(state.CipherSuite),
+			"Issuer: " + leaf.Issuer.String(),
+			"Subject: " + leaf.Subject.String(),
+			fmt.Sprintf("Expires: %s (%d days)", leaf.NotAfter.Format(time.DateOnly), daysUntil(leaf.NotAfter, time.Now())),
+		}
+		res.Details = formatDetailsWithPrefixes(details)
+	}
+	return res
+}
+
+// evaluateCertificate grades a verified leaf certificate as of now.
+func evaluateCertificate(leaf *x509.Certificate, chainLen int, now time.Time) (Status, Reason, string, string) {
+	switch {
+	case now.After(leaf.NotAfter):
+		return StatusError, ReasonCertExpired, "Certificate expired", "Check that your system clock is correct."
+	case now.Before(leaf.NotBefore):
+		return StatusError, ReasonCertNotYetValid, "Certificate not yet valid", "Check that your system clock is correct."
+	case chainLen == 1 && bytes.Equal(leaf.RawIssuer, leaf.RawSubject):
+		// Only trusted because a local root was installed, typical of TLS-inspecting proxies.
+		return StatusWarning, ReasonCertSelfSigned, "Self-signed certificate (possible interception)", "Verify whether a proxy or security tool is intercepting HTTPS."
+	case leaf.NotAfter.Sub(now) < certExpiryWarning:
+		return StatusWarning, ReasonCertExpiring, fmt.Sprintf("Certificate expires in %d days", daysUntil(leaf.NotAfter, now)), ""
+	}
+	return StatusOk, "", "", ""
+}
+
+// classifyTLSError maps a failed handshake to a message and fix.
+func classifyTLSError(err error) (string, string) {
+	var unknownAuth x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &unknownAuth):
+		return "Untrusted certificate (possible interception)", "Verify whether a proxy, captive portal, or security tool is intercepting HTTPS."
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "Certificate expired or clock skewed", "Check that your system clock is correct."
+	case errors.As(err, &hostname):
+		return "Certificate does not match host", "A middlebox may be answering for this host."
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "Handshake timed out", "Check your firewall or proxy settings."
+	}
+	return "Handshake failed", ""
+}
+
+func daysUntil(t, now time.Time) int {
+	return int(t.Sub(now).Hours() / 24)
+}
@@ -0,0 +1,38 @@
+package diagnostic
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestEvaluateCertificate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	cert := func(notBefore, notAfter time.Time, selfSigned bool) *x509.Certificate {
+		c := &x509.Certificate{NotBefore: notBefore, NotAfter: notAfter, RawSubject: []byte("leaf"), RawIssuer: []byte("ca")}
+		if selfSigned {
+			c.RawIssuer = c.RawSubject
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		chainLen int
+		want     Status
+		reason   Reason
+	}{
+		{"healthy", cert(now.AddDate(0, -1, 0), now.AddDate(0, 3, 0), false), 2, StatusOk, ""},
+		{"expired", cert(now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1), false), 2, StatusError, ReasonCertExpired},
+		{"not yet valid", cert(now.AddDate(0, 0, 1), now.AddDate(1, 0, 0), false), 2, StatusError, ReasonCertNotYetValid},
+		{"near expiry", cert(now.AddDate(0, -3, 0), now.AddDate(0, 0, 5), false), 2, StatusWarning, ReasonCertExpiring},
+		{"self-signed", cert(now.AddDate(0, -1, 0), now.AddDate(1, 0, 0), true), 1, StatusWarning, ReasonCertSelfSigned},
+	}
+	for _, tt := range tests {
+		got, reason, msg, _ := evaluateCertificate(tt.cert, tt.chainLen, now)
+		if got != tt.want || reason != tt.reason {
+			t.Errorf("%s: expected %v/%s, got %v/%s (%s)", tt.name, tt.want, tt.reason, got, reason, msg)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publicCAs are the organizations behind the certificate authorities that
+// sign the certificates of large public sites. A leaf issued by anyone else
+// was most likely minted on the fly by a TLS-inspecting proxy whose root
+// was installed on this machine.
+var publicCAs = []string{
+	"DigiCert", "Google Trust Services", "Let's Encrypt", "GlobalSign",
+	"Sectigo", "COMODO", "USERTrust", "Entrust", "Amazon", "Apple Inc.",
+	"Microsoft Corporation", "GoDaddy", "Starfield", "IdenTrust",
+	"Baltimore", "Certum", "Asseco", "SSL Corporation", "Buypass", "QuoVadis",
+	"HARICA", "Cybertrust", "Thawte", "GeoTrust", "Actalis", "Certainly",
+}
+
+// tlsHandshake is the outcome of one handshake of CheckTLSInterception.
+type tlsHandshake struct {
+	Host    string
+	Latency time.Duration
+	Version string
+	// Issuer is the organization of the leaf's issuer, or its common name
+	// when it has none.
+	Issuer string
+	// VerifyErr is why the chain does not verify against the system roots;
+	// nil when it does. An inspecting proxy whose root is not installed
+	// fails here rather than in the handshake.
+	VerifyErr error
+	Err       error
+}
+
+// CheckTLSInterception completes a TLS handshake with each of
+// Config.TLSInspectHosts and reports the protocol, handshake time and
+// certificate issuer of each, warning when an issuer is not a public CA: the
+// sign of SSL inspection by a corporate proxy, antivirus or captive
+// middlebox, whether or not its root is installed.
+func CheckTLSInterception(ctx context.Context) Result {
+	res := Result{Name: "TLS Interception", Emoji: "🪪", Status: StatusOk}
+	hosts := activeConfig(ctx).TLSInspectHosts
+	if len(hosts) == 0 {
+		res.Status = StatusSkipped
+		res.Message = "No hosts configured"
+		return res
+	}
+	shakes := make([]tlsHandshake, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shakes[i] = inspectTLS(ctx, host)
+		}()
+	}
+	wg.Wait()
+
+	var details []string
+	for _, h := range shakes {
+		if h.Err != nil {
+			msg, _ := classifyTLSError(h.Err)
+			details = append(details, h.Host+": "+msg)
+			continue
+		}
+		res.Latency = max(res.Latency, h.Latency)
+		line := fmt.Sprintf("%s: %s, %s, issued by %s", h.Host, h.Version, h.Latency.Round(time.Millisecond), h.Issuer)
+		if h.VerifyErr != nil {
+			msg, _ := classifyTLSError(h.VerifyErr)
+			line += "; " + msg
+		}
+		details = append(details, line)
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeTLSInterception(&res, shakes)
+	return res
+}
+
+// gradeTLSInterception sets the verdict of CheckTLSInterception.
+func gradeTLSInterception(res *Result, shakes []tlsHandshake) {
+	var intercepted, failed []string
+	for _, h := range shakes {
+		switch {
+		case h.Err != nil:
+			failed = append(failed, h.Host)
+		case !isPublicCA(h.Issuer) && h.VerifyErr != nil:
+			intercepted = append(intercepted, h.Host+" ("+h.Issuer+", not trusted)")
+		case !isPublicCA(h.Issuer):
+			intercepted = append(intercepted, h.Host+" ("+h.Issuer+")")
+		case h.VerifyErr != nil:
+			failed = append(failed, h.Host)
+		}
+	}
+	switch {
+	case len(intercepted) > 0:
+		res.Status = StatusWarning
+		res.Reason = ReasonTLSIntercepted
+		res.Message = "HTTPS is intercepted: certificates issued by " + strings.Join(intercepted, ", ")
+		res.Fix = "A proxy, antivirus or security agent decrypts your HTTPS traffic. Expected on a managed work machine; otherwise remove its root certificate and find what installed it."
+	case len(failed) == len(shakes):
+		res.Status = StatusError
+		res.Reason = ReasonTLSFailed
+		res.Message = "No TLS handshake succeeded"
+		res.Fix = "Check your firewall or proxy settings."
+	case len(failed) > 0:
+		res.Status = StatusWarning
+		res.Reason = ReasonTLSFailed
+		res.Message = "TLS handshake failed with " + strings.Join(failed, ", ")
+	default:
+		res.Message = "Certificates come from public CAs; no interception"
+	}
+}
+
+// inspectTLS completes a TLS handshake with host:443.
+func inspectTLS(ctx context.Context, host string) tlsHandshake {
+	return inspectTLSAddr(ctx, host, net.JoinHostPort(host, "443"))
+}
+
+// inspectTLSAddr completes a TLS handshake with host at addr. The chain is
+// verified after the handshake rather than during it, so an untrusted
+// certificate still shows who issued it.
+func inspectTLSAddr(ctx context.Context, host, addr string) tlsHandshake {
+	h := tlsHandshake{Host: host}
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no certificate")
+			}
+			leaf := cs.PeerCertificates[0]
+			h.Issuer = issuerName(leaf)
+			opts := x509.VerifyOptions{DNSName: host, Intermediates: x509.NewCertPool()}
+			for _, c := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(c)
+			}
+			_, h.VerifyErr = leaf.Verify(opts)
+			return nil
+		},
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: activeConfig(ctx).Timeout}, Config: cfg}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		h.Err = err
+		return h
+	}
+	h.Latency = time.Since(start)
+	defer func() {
+		if errClose := conn.Close(); errClose != nil {
+			slog.Debug("could not close TLS connection", "err", errClose)
+		}
+	}()
+	h.Version = tls.VersionName(conn.(*tls.Conn).ConnectionState().Version)
+	return h
+}
+
+// issuerName returns the organization of cert's issuer, or its common name.
+func issuerName(cert *x509.Certificate) string {
+	if len(cert.Issuer.Organization) > 0 {
+		return cert.Issuer.Organization[0]
+	}
+	return cert.Issuer.CommonName
+}
+
+// isPublicCA reports whether issuer is one of publicCAs.
+func isPublicCA(issuer string) bool {
+	for _, ca := range publicCAs {
+		if strings.Contains(strings.ToLower(issuer), strings.ToLower(ca)) {
+			return true
+		}
+	}
+	return false
+}
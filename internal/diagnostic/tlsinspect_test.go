@@ -0,0 +1,80 @@
+package diagnostic
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicCA(t *testing.T) {
+	tests := map[string]bool{
+		"DigiCert Inc":               true,
+		"Google Trust Services":      true,
+		"Let's Encrypt":              true,
+		"Apple Inc.":                 true,
+		"Zscaler Inc.":               false,
+		"Fortinet":                   false,
+		"Palo Alto Networks":         false,
+		"Avast Web/Mail Shield Root": false,
+		"Sectigo Limited":            true,
+		"":                           false,
+	}
+	for issuer, want := range tests {
+		if got := isPublicCA(issuer); got != want {
+			t.Errorf("isPublicCA(%q): expected %v, got %v", issuer, want, got)
+		}
+	}
+}
+
+func TestIssuerName(t *testing.T) {
+	cert := &x509.Certificate{Issuer: pkix.Name{Organization: []string{"DigiCert Inc"}, CommonName: "DigiCert Global G2"}}
+	if got := issuerName(cert); got != "DigiCert Inc" {
+		t.Errorf("Expected the organization, got %q", got)
+	}
+	cert = &x509.Certificate{Issuer: pkix.Name{CommonName: "Fortinet CA"}}
+	if got := issuerName(cert); got != "Fortinet CA" {
+		t.Errorf("Expected the common name without an organization, got %q", got)
+	}
+}
+
+func TestGradeTLSInterception(t *testing.T) {
+	ok := tlsHandshake{Host: "apple.com", Issuer: "Apple Inc."}
+	tests := []struct {
+		name   string
+		shakes []tlsHandshake
+		status Status
+		reason Reason
+	}{
+		{"public CAs", []tlsHandshake{ok, {Host: "google.com", Issuer: "Google Trust Services"}}, StatusOk, ""},
+		{"intercepted", []tlsHandshake{ok, {Host: "google.com", Issuer: "Zscaler Inc."}}, StatusWarning, ReasonTLSIntercepted},
+		{"intercepted, root not installed", []tlsHandshake{ok, {Host: "google.com", Issuer: "Zscaler Inc.", VerifyErr: x509.UnknownAuthorityError{}}}, StatusWarning, ReasonTLSIntercepted},
+		{"public CA, bad chain", []tlsHandshake{ok, {Host: "google.com", Issuer: "Google Trust Services", VerifyErr: x509.HostnameError{}}}, StatusWarning, ReasonTLSFailed},
+		{"one failed", []tlsHandshake{ok, {Host: "google.com", Err: errors.New("timeout")}}, StatusWarning, ReasonTLSFailed},
+		{"all failed", []tlsHandshake{{Host: "apple.com", Err: errors.New("timeout")}}, StatusError, ReasonTLSFailed},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeTLSInterception(&res, tt.shakes)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestInspectTLSUntrusted(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	h := inspectTLSAddr(context.Background(), "example.com", srv.Listener.Addr().String())
+	if h.Err != nil {
+		t.Fatalf("Expected the handshake to complete, got %v", h.Err)
+	}
+	var unknown x509.UnknownAuthorityError
+	if h.Issuer != "Acme Co" || !errors.As(h.VerifyErr, &unknown) {
+		t.Errorf("Expected an untrusted certificate from Acme Co, got %q (%v)", h.Issuer, h.VerifyErr)
+	}
+}
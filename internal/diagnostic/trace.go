@@ -0,0 +1,345 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var reProbeRTT = regexp.MustCompile(`time=(\d+(?:\.\d*)?) ms`)
+
+// Probe protocols for Config.TraceMode.
+const (
+	TraceICMP = "icmp"
+	TraceUDP  = "udp"
+	TraceTCP  = "tcp"
+)
+
+const (
+	// traceWait is how long traceroute waits for each probe's answer.
+	traceWait = time.Second
+	// tracePortTCP is the port TCP probes are sent to, as it is rarely filtered.
+	tracePortTCP = "443"
+)
+
+// reverseLookup resolves hop addresses to names; tests replace it.
+var reverseLookup = net.DefaultResolver.LookupAddr
+
+// unreachableFlags explains the annotations traceroute prints after a
+// probe's time when a router answers with "destination unreachable".
+var unreachableFlags = map[string]string{
+	"!H": "host unreachable",
+	"!N": "network unreachable",
+	"!P": "protocol unreachable",
+	"!S": "source route failed",
+	"!F": "fragmentation needed",
+	"!X": "administratively prohibited",
+	"!A": "administratively prohibited",
+	"!C": "administratively prohibited",
+	"!Z": "administratively prohibited",
+}
+
+// hopProbe is the outcome of a single TTL-limited ping.
+type hopProbe struct {
+	Router string
+	// RTT is only known when the probe reached the destination; routers that
+	// answer with "Time to live exceeded" report no timing.
+	RTT time.Duration
+	// Flag is traceroute's annotation (such as "!X") for an answer that was
+	// "destination unreachable" rather than "time exceeded".
+	Flag string
+}
+
+// traceHop summarizes the probes sent with one TTL.
+type traceHop struct {
+	Router  string
+	Replies int
+	Probes  int
+	Best    time.Duration
+	Avg     time.Duration
+	Median  time.Duration
+	// Flag is the first unreachable annotation among the probes.
+	Flag string
+	// Name is the router's reverse DNS name, when it has one.
+	Name string
+	// ASN and ASName identify the network announcing Router.
+	ASN    string
+	ASName string
+}
+
+// parseHopReply extracts the responding router and, when present, the round
+// trip time from the output of a single ping.
+func parseHopReply(output string) (hopProbe, bool) {
+	m := rePingRoute.FindStringSubmatch(output)
+	if len(m) < 2 {
+		return hopProbe{}, false
+	}
+	p := hopProbe{Router: m[1]}
+	if t := reProbeRTT.FindStringSubmatch(output); len(t) > 1 {
+		if ms, err := strconv.ParseFloat(t[1], 64); err == nil {
+			p.RTT = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	return p, true
+}
+
+// summarizeHop reduces the probes of one TTL to the most frequent router and
+// the best, average and median round trip times. Failed probes have an empty Router.
+func summarizeHop(probes []hopProbe) traceHop {
+	hop := traceHop{Probes: len(probes)}
+	counts := map[string]int{}
+	var rtts []time.Duration
+	for _, p := range probes {
+		if p.Router == "" {
+			continue
+		}
+		hop.Replies++
+		if hop.Flag == "" {
+			hop.Flag = p.Flag
+		}
+		counts[p.Router]++
+		if counts[p.Router] > counts[hop.Router] {
+			hop.Router = p.Router
+		}
+		if p.RTT > 0 {
+			rtts = append(rtts, p.RTT)
+		}
+	}
+	if len(rtts) > 0 {
+		slices.Sort(rtts)
+		var sum time.Duration
+		for _, rtt := range rtts {
+			sum += rtt
+		}
+		hop.Avg = sum / time.Duration(len(rtts))
+		hop.Best = rtts[0]
+		hop.Median = rtts[len(rtts)/2]
+		if len(rtts)%2 == 0 {
+			hop.Median = (rtts[len(rtts)/2-1] + rtts[len(rtts)/2]) / 2
+		}
+	}
+	return hop
+}
+
+// probeHops pings target with TTL 1..maxTTL, probes times per TTL, running at
+// most concurrency pings at once. Probes not yet sent when ctx is done count
+// as timed out.
+func probeHops(ctx context.Context, target string, maxTTL, probes, concurrency int) []traceHop {
+	if maxTTL < 1 {
+		return nil
+	}
+	probes = max(probes, 1)
+	concurrency = max(concurrency, 1)
+
+	type job struct{ ttl, probe int }
+	jobs := make(chan job)
+	results := make([][]hopProbe, maxTTL)
+	for i := range results {
+		results[i] = make([]hopProbe, probes)
+	}
+
+	var wg sync.WaitGroup
+	for range min(concurrency, maxTTL*probes) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pctx, cancel := context.WithTimeout(ctx, pingTimeout)
+				out, _ := runPlatformCommand(pctx, activePlatform().pingCommand(target, 0, 0, j.ttl))
+				cancel()
+				if p, ok := parseHopReply(string(out)); ok {
+					// Each job owns its slot, so no locking is needed.
+					results[j.ttl-1][j.probe] = p
+				}
+			}
+		}()
+	}
+feed:
+	for ttl := 1; ttl <= maxTTL; ttl++ {
+		for p := range probes {
+			select {
+			case jobs <- job{ttl: ttl, probe: p}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	hops := make([]traceHop, maxTTL)
+	for i, r := range results {
+		hops[i] = summarizeHop(r)
+	}
+	return hops
+}
+
+// traceHops pings target with TTL 1..maxTTL and returns the responding router
+// per hop, or "" where the probe timed out.
+func traceHops(ctx context.Context, target string, maxTTL int) []string {
+	c := activeConfig(ctx)
+	var routers []string
+	for _, h := range probeHops(ctx, target, maxTTL, 1, c.TraceConcurrency) {
+		routers = append(routers, h.Router)
+	}
+	return routers
+}
+
+// traceRoute maps the path to target with traceroute, sending probes of
+// c.TraceMode. Without traceroute, or when it fails outright (Linux needs
+// root for ICMP and TCP probes), it falls back to TTL-limited pings. It also
+// returns how the path was probed. Hops past target are dropped.
+func traceRoute(ctx context.Context, c Config, target string) ([]traceHop, string) {
+	var hops []traceHop
+	via := strings.ToUpper(c.TraceMode) + " probes via traceroute"
+	if activeRunner().LookPath("traceroute") == nil {
+		tctx, cancel := context.WithTimeout(ctx, time.Duration(c.TraceMaxTTL*c.TraceProbes)*traceWait+c.Timeout)
+		out, err := runPlatformCommand(tctx, activePlatform().tracerouteCommand(target, c.TraceMode, c.TraceMaxTTL, c.TraceProbes))
+		cancel()
+		if hops = parseTraceroute(string(out)); len(hops) == 0 && err != nil {
+			via = fmt.Sprintf("ICMP probes via ping (traceroute failed: %v)", err)
+		}
+	} else {
+		via = "ICMP probes via ping (traceroute not installed)"
+	}
+	if len(hops) == 0 {
+		hops = probeHops(ctx, target, c.TraceMaxTTL, c.TraceProbes, c.TraceConcurrency)
+	}
+	for i, h := range hops {
+		if h.Router == target {
+			return hops[:i+1], via
+		}
+	}
+	return hops, via
+}
+
+// parseTraceroute reads `traceroute -n` output from macOS or Linux into one
+// traceHop per TTL. A line lists the answering router before its times, "*"
+// for each lost probe and annotations such as "!X" after a time:
+//
+//	3  10.0.0.1  5.123 ms *  5.301 ms !X
+func parseTraceroute(output string) []traceHop {
+	var hops []traceHop
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ttl, err := strconv.Atoi(fields[0])
+		if err != nil || ttl < 1 {
+			continue
+		}
+		var probes []hopProbe
+		router := ""
+		for i := 1; i < len(fields); i++ {
+			f := fields[i]
+			switch {
+			case f == "*":
+				probes = append(probes, hopProbe{})
+			case strings.HasPrefix(f, "!"):
+				if n := len(probes); n > 0 {
+					probes[n-1].Flag = f
+				}
+			case net.ParseIP(f) != nil:
+				router = f
+			case i+1 < len(fields) && fields[i+1] == "ms":
+				if ms, err := strconv.ParseFloat(f, 64); err == nil {
+					probes = append(probes, hopProbe{Router: router, RTT: time.Duration(ms * float64(time.Millisecond))})
+				}
+				i++
+			}
+		}
+		for len(hops) < ttl-1 {
+			hops = append(hops, traceHop{})
+		}
+		hops = append(hops[:ttl-1], summarizeHop(probes))
+	}
+	return hops
+}
+
+// resolveHopNames fills in the reverse DNS name of each answering hop,
+// looking them up concurrently within Config.Timeout.
+func resolveHopNames(ctx context.Context, hops []traceHop) {
+	ctx, cancel := context.WithTimeout(ctx, activeConfig(ctx).Timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range hops {
+		if hops[i].Router == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if names, err := reverseLookup(ctx, hops[i].Router); err == nil && len(names) > 0 {
+				hops[i].Name = strings.TrimSuffix(names[0], ".")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// gradeTrace sets the verdict of FastTraceroute. Unanswered probes are not
+// a fault, since many hosts ignore them; an explicit "prohibited" answer
+// short of the destination means a firewall is rejecting the probes.
+func gradeTrace(res *Result, hops []traceHop, target, mode string) {
+	if n := len(hops); n > 0 && hops[n-1].Router == target {
+		res.Message = fmt.Sprintf("Reached %s in %d hops", target, n)
+		return
+	}
+	for i, h := range hops {
+		if unreachableFlags[h.Flag] == "administratively prohibited" {
+			res.Status = StatusWarning
+			res.Reason = ReasonPathFiltered
+			res.Message = fmt.Sprintf("%s probes filtered at hop %d (%s)", strings.ToUpper(mode), i+1, h.Router)
+			res.Fix = "A firewall on the path rejects these probes; retry with --trace-mode tcp."
+			return
+		}
+	}
+	res.Message = fmt.Sprintf("%s did not answer within %d hops", target, len(hops))
+}
+
+// formatHop renders one traceHop for FastTraceroute's details. A hop that
+// stays silent while a later one answers is a router that does not reply to
+// probes, not packet loss.
+func formatHop(ttl int, h traceHop, answeredLater bool) string {
+	if h.Router == "" {
+		if answeredLater {
+			return fmt.Sprintf("Hop %2d: * (No reply; forwards traffic but ignores probes)", ttl)
+		}
+		return fmt.Sprintf("Hop %2d: * (Request timed out)", ttl)
+	}
+	line := fmt.Sprintf("Hop %2d: %s", ttl, h.Router)
+	if h.Name != "" {
+		line += " [" + h.Name + "]"
+	}
+	if h.ASN != "" {
+		line += " {" + formatAS(h) + "}"
+	}
+	if h.Probes > 1 {
+		line += fmt.Sprintf(" (%d/%d replies", h.Replies, h.Probes)
+		if h.Best > 0 {
+			line += fmt.Sprintf(", best %s, avg %s", formatRTT(h.Best), formatRTT(h.Avg))
+		}
+		line += ")"
+	} else if h.Best > 0 {
+		line += " (" + formatRTT(h.Best) + ")"
+	}
+	if h.Flag != "" {
+		meaning := unreachableFlags[strings.SplitN(h.Flag, "-", 2)[0]]
+		if meaning == "" {
+			meaning = "unreachable"
+		}
+		line += fmt.Sprintf(" %s %s", h.Flag, meaning)
+	}
+	return line
+}
+
+func formatRTT(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+}
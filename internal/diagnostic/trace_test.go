@@ -0,0 +1,150 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHopReply(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		router string
+		rtt    time.Duration
+		ok     bool
+	}{
+		{"ttl exceeded", "PING 1.1.1.1 (1.1.1.1): 56 data bytes\n92 bytes from 192.168.1.1: Time to live exceeded\n", "192.168.1.1", 0, true},
+		{"echo reply", "64 bytes from 1.1.1.1: icmp_seq=0 ttl=58 time=12.5 ms\n", "1.1.1.1", 12500 * time.Microsecond, true},
+		{"timeout", "Request timeout for icmp_seq 0\n", "", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := parseHopReply(tt.output)
+		if ok != tt.ok || p.Router != tt.router || p.RTT != tt.rtt {
+			t.Errorf("%s: got %+v, %v", tt.name, p, ok)
+		}
+	}
+}
+
+func TestSummarizeHop(t *testing.T) {
+	ms := time.Millisecond
+	hop := summarizeHop([]hopProbe{
+		{Router: "1.1.1.1", RTT: 30 * ms},
+		{},
+		{Router: "1.1.1.1", RTT: 10 * ms},
+		{Router: "1.0.0.1", RTT: 20 * ms},
+	})
+	if hop.Router != "1.1.1.1" || hop.Replies != 3 || hop.Probes != 4 {
+		t.Errorf("Unexpected summary %+v", hop)
+	}
+	if hop.Best != 10*ms || hop.Median != 20*ms {
+		t.Errorf("Expected best 10ms and median 20ms, got %v and %v", hop.Best, hop.Median)
+	}
+	if h := summarizeHop([]hopProbe{{}, {}}); h.Router != "" || h.Replies != 0 {
+		t.Errorf("Expected a silent hop, got %+v", h)
+	}
+}
+
+func TestProbeHops(t *testing.T) {
+	fake := &fakeRunner{outputs: map[string]string{
+		"ping -c 1 -t 1 1.1.1.1": "92 bytes from 192.168.1.1: Time to live exceeded\n",
+		"ping -c 1 -t 3 1.1.1.1": "64 bytes from 1.1.1.1: icmp_seq=0 ttl=58 time=9.0 ms\n",
+	}}
+	withRunner(t, fake)
+
+	hops := probeHops(context.Background(), "1.1.1.1", 3, 2, 2)
+	if len(hops) != 3 {
+		t.Fatalf("Expected 3 hops, got %d", len(hops))
+	}
+	if hops[0].Router != "192.168.1.1" || hops[1].Router != "" || hops[2].Best != 9*time.Millisecond {
+		t.Errorf("Unexpected hops %+v", hops)
+	}
+	if len(fake.calls) != 6 {
+		t.Errorf("Expected 2 probes per TTL, got %d calls", len(fake.calls))
+	}
+	if hops := probeHops(context.Background(), "1.1.1.1", 0, 1, 1); hops != nil {
+		t.Errorf("Expected no hops for max TTL 0, got %v", hops)
+	}
+}
+
+const tracerouteOutput = `traceroute to 1.1.1.1 (1.1.1.1), 10 hops max, 60 byte packets
+ 1  192.168.1.1  1.000 ms  2.000 ms  3.000 ms
+ 2  * * *
+ 3  203.0.113.1  8.100 ms *  9.900 ms
+ 4  1.1.1.1  10.000 ms  10.200 ms  10.400 ms
+`
+
+func TestParseTraceroute(t *testing.T) {
+	hops := parseTraceroute(tracerouteOutput)
+	if len(hops) != 4 {
+		t.Fatalf("Expected 4 hops, got %d: %+v", len(hops), hops)
+	}
+	if hops[0].Router != "192.168.1.1" || hops[0].Avg != 2*time.Millisecond || hops[0].Replies != 3 {
+		t.Errorf("Unexpected first hop %+v", hops[0])
+	}
+	if hops[1].Router != "" || hops[1].Probes != 3 {
+		t.Errorf("Expected a silent second hop, got %+v", hops[1])
+	}
+	if hops[2].Replies != 2 || hops[2].Probes != 3 || hops[2].Avg != 9*time.Millisecond {
+		t.Errorf("Unexpected third hop %+v", hops[2])
+	}
+
+	filtered := parseTraceroute(" 1  192.168.1.1  1.0 ms  1.1 ms\n 2  10.0.0.1  4.0 ms !X  *\n")
+	if len(filtered) != 2 || filtered[1].Flag != "!X" || filtered[1].Replies != 1 {
+		t.Errorf("Expected an !X hop, got %+v", filtered)
+	}
+}
+
+func TestGradeTrace(t *testing.T) {
+	reached := []traceHop{{Router: "192.168.1.1"}, {}, {Router: "1.1.1.1"}}
+	filtered := []traceHop{{Router: "192.168.1.1"}, {Router: "10.0.0.1", Flag: "!X"}}
+	silent := []traceHop{{Router: "192.168.1.1"}, {}, {}}
+	tests := []struct {
+		name   string
+		hops   []traceHop
+		status Status
+		reason Reason
+	}{
+		{"reached", reached, StatusOk, ""},
+		{"filtered", filtered, StatusWarning, ReasonPathFiltered},
+		{"silent destination", silent, StatusOk, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeTrace(&res, tt.hops, "1.1.1.1", TraceUDP)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestFastTraceroute(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"traceroute -n -w 1 -q 3 -m 10 -I 1.1.1.1": tracerouteOutput,
+	}})
+	prev := reverseLookup
+	reverseLookup = func(_ context.Context, addr string) ([]string, error) {
+		if addr == "1.1.1.1" {
+			return []string{"one.one.one.one."}, nil
+		}
+		return nil, errors.New("no PTR record")
+	}
+	t.Cleanup(func() { reverseLookup = prev })
+	withTXT(t, map[string]string{
+		"1.1.1.1.origin.asn.cymru.com": "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11",
+		"AS13335.asn.cymru.com":        "13335 | US | arin | 2010-07-14 | CLOUDFLARENET - Cloudflare, Inc., US",
+	})
+
+	res := FastTraceroute(context.Background(), true)
+	if res.Status != StatusOk || res.Message != "Reached 1.1.1.1 in 4 hops" {
+		t.Fatalf("Unexpected result %v: %s", res.Status, res.Message)
+	}
+	details := strings.Join(res.Details, "\n")
+	for _, want := range []string{"ICMP probes via traceroute", "ignores probes", "[one.one.one.one]", "avg 10.2 ms", "Networks: AS13335 CLOUDFLARENET"} {
+		if !strings.Contains(details, want) {
+			t.Errorf("Expected %q in details, got:\n%s", want, details)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// vpnOverheadWarning is how much latency a full-tunnel VPN may add over the
+// direct path before it is blamed for a slow connection.
+const vpnOverheadWarning = 50 * time.Millisecond
+
+// hostInterface is a network interface and its addresses.
+type hostInterface struct {
+	Name     string
+	Up       bool
+	Loopback bool
+	Addrs    []netip.Prefix
+}
+
+// localInterfaces lists the interfaces of this machine; tests replace it.
+var localInterfaces = func() ([]hostInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var list []hostInterface
+	for _, iface := range ifaces {
+		h := hostInterface{
+			Name:     iface.Name,
+			Up:       iface.Flags&net.FlagUp != 0,
+			Loopback: iface.Flags&net.FlagLoopback != 0,
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("addresses of %s: %w", iface.Name, err)
+		}
+		for _, a := range addrs {
+			if p, err := netip.ParsePrefix(a.String()); err == nil {
+				h.Addrs = append(h.Addrs, netip.PrefixFrom(p.Addr().Unmap(), p.Bits()))
+			}
+		}
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+// vpnKind names the VPN behind a tunnel interface, or returns "" when the
+// interface is not one. Tailscale hands out addresses from 100.64.0.0/10.
+func vpnKind(iface hostInterface) string {
+	name := iface.Name
+	tunnel := strings.HasPrefix(name, "utun") || strings.HasPrefix(name, "tun")
+	for _, a := range iface.Addrs {
+		if tunnel && cgnatPrefix.Contains(a.Addr()) {
+			return "Tailscale"
+		}
+	}
+	switch {
+	case strings.HasPrefix(name, "tailscale"):
+		return "Tailscale"
+	case strings.HasPrefix(name, "wg"):
+		return "WireGuard"
+	case strings.HasPrefix(name, "ipsec"):
+		return "IPsec"
+	case strings.HasPrefix(name, "ppp"):
+		return "PPP/L2TP"
+	case strings.HasPrefix(name, "utun"):
+		return "utun VPN"
+	case strings.HasPrefix(name, "tun"), strings.HasPrefix(name, "tap"):
+		return "OpenVPN"
+	}
+	return ""
+}
+
+// onTunnel reports whether addr belongs to a VPN tunnel interface of this
+// machine; with --remote the remote interfaces are unknown.
+func onTunnel(addr netip.Addr) bool {
+	if remoteTarget() != "" {
+		return false
+	}
+	ifaces, err := localInterfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		for _, a := range iface.Addrs {
+			if a.Addr() == addr {
+				return vpnKind(iface) != ""
+			}
+		}
+	}
+	return false
+}
+
+// routableAddr returns the first address of iface that is not link-local,
+// preferring IPv4. macOS keeps several utun interfaces up for system
+// services with link-local addresses only; they are not VPNs.
+func routableAddr(iface hostInterface) (netip.Addr, bool) {
+	var v6 netip.Addr
+	for _, a := range iface.Addrs {
+		addr := a.Addr()
+		switch {
+		case addr.IsLoopback() || addr.IsLinkLocalUnicast():
+		case addr.Is4():
+			return addr, true
+		case !v6.IsValid():
+			v6 = addr
+		}
+	}
+	return v6, v6.IsValid()
+}
+
+// vpnProbe captures what CheckVPN observed.
+type vpnProbe struct {
+	// Tunnels are the up VPN interfaces with a routable address.
+	Tunnels []hostInterface
+	// Route is the interface of the default route; Physical is the
+	// non-VPN interface used for the direct comparison.
+	Route    string
+	Physical string
+	// Tunnel and Direct are the round trips to the WAN host through the
+	// first tunnel and the physical interface.
+	Tunnel, Direct       time.Duration
+	TunnelErr, DirectErr error
+}
+
+// isTunnel reports whether name is one of p's tunnels.
+func (p vpnProbe) isTunnel(name string) bool {
+	for _, t := range p.Tunnels {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVPN detects active VPN tunnels, tells a full tunnel (the default
+// route goes through the VPN) from a split one, shows whether DNS goes
+// through the tunnel, and compares the latency through the tunnel with
+// the direct path. A full tunnel to a distant gateway slows every
+// connection down.
+func CheckVPN(ctx context.Context) Result {
+	res := Result{Name: "VPN", Emoji: "🔐", Status: StatusOk}
+	if target := remoteTarget(); target != "" {
+		res.Status = StatusSkipped
+		res.Message = "Tunnels on " + target + " are not seen with --remote"
+		return res
+	}
+	ifaces, err := localInterfaces()
+	if err != nil {
+		res.Status = StatusError
+		res.Message = "Could not list network interfaces"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+
+	var p vpnProbe
+	for _, iface := range ifaces {
+		if _, ok := routableAddr(iface); !ok || !iface.Up {
+			continue
+		}
+		if vpnKind(iface) != "" {
+			p.Tunnels = append(p.Tunnels, iface)
+		} else if !iface.Loopback && p.Physical == "" && isPhysicalName(iface.Name) {
+			p.Physical = iface.Name
+		}
+	}
+	if len(p.Tunnels) == 0 {
+		res.Message = "No active VPN"
+		return res
+	}
+
+	var details []string
+	for _, t := range p.Tunnels {
+		addr, _ := routableAddr(t)
+		details = append(details, fmt.Sprintf("Tunnel: %s (%s, %s)", t.Name, vpnKind(t), addr))
+	}
+	p.Route, _ = getPrimaryInterface(ctx)
+	if p.Route != "" && !p.isTunnel(p.Route) {
+		p.Physical = p.Route
+	}
+	switch {
+	case p.Route == "":
+		details = append(details, "Default Route: none")
+	case p.isTunnel(p.Route):
+		details = append(details, "Default Route: "+p.Route+" (full tunnel)")
+	default:
+		details = append(details, "Default Route: "+p.Route+" (split tunnel)")
+	}
+	details = append(details, dnsRouteDetails(ctx, p)...)
+
+	target := activeConfig(ctx).WANHost
+	p.Tunnel, p.TunnelErr = pingVia(ctx, target, p.Tunnels[0].Name)
+	if p.Physical != "" {
+		p.Direct, p.DirectErr = pingVia(ctx, target, p.Physical)
+	} else {
+		p.DirectErr = fmt.Errorf("no physical interface")
+	}
+	details = append(details, fmt.Sprintf("Latency to %s: %s via %s, %s direct",
+		target, durationOrFail(p.Tunnel, p.TunnelErr), p.Tunnels[0].Name, durationOrFail(p.Direct, p.DirectErr)))
+	res.Details = formatDetailsWithPrefixes(details)
+
+	gradeVPN(&res, p)
+	return res
+}
+
+// gradeVPN sets the verdict of CheckVPN. Only a full tunnel is graded,
+// since a split tunnel leaves ordinary traffic on the direct path.
+func gradeVPN(res *Result, p vpnProbe) {
+	t := p.Tunnels[0]
+	if !p.isTunnel(p.Route) {
+		res.Message = fmt.Sprintf("Split tunnel via %s (%s)", t.Name, vpnKind(t))
+		return
+	}
+	res.Message = fmt.Sprintf("Full tunnel via %s (%s)", t.Name, vpnKind(t))
+	switch {
+	case p.TunnelErr != nil && p.DirectErr == nil:
+		res.Status = StatusWarning
+		res.Reason = ReasonUnreachable
+		res.Message = fmt.Sprintf("Full tunnel via %s is not passing traffic", t.Name)
+		res.Fix = "Reconnect the VPN, or disconnect it; every connection is routed into it."
+	case p.TunnelErr == nil && p.DirectErr == nil && p.Tunnel-p.Direct > vpnOverheadWarning:
+		res.Status = StatusWarning
+		res.Reason = ReasonVPNOverhead
+		res.Latency = p.Tunnel - p.Direct
+		res.Message += fmt.Sprintf(" adds %v to every connection", res.Latency.Round(time.Millisecond))
+		res.Fix = "All traffic detours through the VPN. Ask for split tunneling, or connect to a closer VPN gateway."
+	}
+}
+
+// dnsRouteDetails says which interface each system nameserver is reached
+// through, since DNS outside a full tunnel leaks and DNS inside a split
+// tunnel adds the tunnel's latency to every lookup.
+func dnsRouteDetails(ctx context.Context, p vpnProbe) []string {
+	var details []string
+	for _, ns := range systemNameservers(ctx) {
+		rout, err := runPlatformCommand(ctx, activePlatform().routeGetCommand(ns))
+		if err != nil {
+			continue
+		}
+		iface, err := activePlatform().parseRouteInterface(string(rout))
+		if err != nil {
+			continue
+		}
+		where := "direct"
+		if p.isTunnel(iface) {
+			where = "tunnel"
+		}
+		details = append(details, fmt.Sprintf("DNS %s: via %s (%s)", ns, iface, where))
+	}
+	return details
+}
+
+// systemNameservers returns the resolvers in /etc/resolv.conf, or nil when
+// it cannot be read.
+func systemNameservers(ctx context.Context) []string {
+	out, err := runCommand(ctx, "cat", "/etc/resolv.conf")
+	if err != nil {
+		return nil
+	}
+	return parseNameservers(string(out))
+}
+
+// parseNameservers returns the nameserver addresses in resolv.conf content.
+func parseNameservers(conf string) []string {
+	var servers []string
+	for _, line := range strings.Split(conf, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// isPhysicalName reports whether name looks like a wired or wireless
+// adapter rather than a bridge or container interface.
+func isPhysicalName(name string) bool {
+	for _, prefix := range []string{"bridge", "docker", "veth", "br-", "virbr", "awdl", "llw", "anpi", "ap"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// pingVia sends one echo request to ip out of iface.
+func pingVia(ctx context.Context, ip, iface string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	out, err := runPlatformCommand(ctx, activePlatform().pingViaCommand(ip, iface))
+	if err != nil {
+		return 0, err
+	}
+	return parsePing(string(out))
+}
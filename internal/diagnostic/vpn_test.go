@@ -0,0 +1,92 @@
+package diagnostic
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func iface(name string, addrs ...string) hostInterface {
+	h := hostInterface{Name: name, Up: true}
+	for _, a := range addrs {
+		h.Addrs = append(h.Addrs, netip.MustParsePrefix(a))
+	}
+	return h
+}
+
+func TestVPNKind(t *testing.T) {
+	tests := []struct {
+		iface hostInterface
+		want  string
+	}{
+		{iface("utun4", "100.101.102.103/32"), "Tailscale"},
+		{iface("utun5", "10.8.0.2/24"), "utun VPN"},
+		{iface("wg0", "10.8.0.2/24"), "WireGuard"},
+		{iface("ipsec0", "10.9.0.2/32"), "IPsec"},
+		{iface("tun0", "10.8.0.6/24"), "OpenVPN"},
+		{iface("en0", "192.168.1.20/24"), ""},
+	}
+	for _, tt := range tests {
+		if got := vpnKind(tt.iface); got != tt.want {
+			t.Errorf("vpnKind(%s) = %q, want %q", tt.iface.Name, got, tt.want)
+		}
+	}
+	if _, ok := routableAddr(iface("utun0", "fe80::1/64")); ok {
+		t.Error("Expected a link-local-only utun not to count as a tunnel")
+	}
+}
+
+func TestGradeVPN(t *testing.T) {
+	tunnels := []hostInterface{iface("utun4", "10.8.0.2/24")}
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		probe  vpnProbe
+		status Status
+		reason Reason
+	}{
+		{"split", vpnProbe{Tunnels: tunnels, Route: "en0", Tunnel: 200 * ms, Direct: 10 * ms}, StatusOk, ""},
+		{"fast full tunnel", vpnProbe{Tunnels: tunnels, Route: "utun4", Tunnel: 30 * ms, Direct: 10 * ms}, StatusOk, ""},
+		{"slow full tunnel", vpnProbe{Tunnels: tunnels, Route: "utun4", Tunnel: 180 * ms, Direct: 10 * ms}, StatusWarning, ReasonVPNOverhead},
+		{"dead tunnel", vpnProbe{Tunnels: tunnels, Route: "utun4", TunnelErr: errors.New("timeout"), Direct: 10 * ms}, StatusWarning, ReasonUnreachable},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeVPN(&res, tt.probe)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s: expected %v/%q, got %v/%q (%s)", tt.name, tt.status, tt.reason, res.Status, res.Reason, res.Message)
+		}
+	}
+}
+
+func TestCheckVPN(t *testing.T) {
+	prev := localInterfaces
+	localInterfaces = func() ([]hostInterface, error) {
+		return []hostInterface{
+			{Name: "lo0", Up: true, Loopback: true, Addrs: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/8")}},
+			iface("en0", "192.168.1.20/24"),
+			iface("utun0", "fe80::1/64"),
+			iface("utun4", "10.8.0.2/24"),
+		}, nil
+	}
+	t.Cleanup(func() { localInterfaces = prev })
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default":       "  interface: utun4\n",
+		"cat /etc/resolv.conf":       "nameserver 10.8.0.1\n",
+		"route -n get 10.8.0.1":      "  interface: utun4\n",
+		"ping -c 1 -b utun4 1.1.1.1": "round-trip min/avg/max/stddev = 120.0/120.0/120.0/0.000 ms\n",
+		"ping -c 1 -b en0 1.1.1.1":   "round-trip min/avg/max/stddev = 10.0/10.0/10.0/0.000 ms\n",
+	}})
+
+	res := CheckVPN(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonVPNOverhead || res.Latency != 110*time.Millisecond {
+		t.Fatalf("Expected a vpn_full_tunnel warning adding 110ms, got %v/%s %v (%s)", res.Status, res.Reason, res.Latency, res.Message)
+	}
+	details := strings.Join(res.Details, "\n")
+	if !strings.Contains(details, "DNS 10.8.0.1: via utun4 (tunnel)") || strings.Contains(details, "utun0") {
+		t.Errorf("Unexpected details:\n%s", details)
+	}
+}
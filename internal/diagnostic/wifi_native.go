@@ -0,0 +1,92 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// nativeWiFi reads the association of iface straight from the OS instead
+// of a command. It is nil unless a native backend is compiled in (see
+// corewlan_darwin.go); ok is false when it has nothing usable, and the
+// platform's command is run instead.
+var nativeWiFi func(iface string, verbose bool) (link wifiLink, ok bool)
+
+// facts returns the radio details of l that are known, as Result facts.
+func (l wifiLink) facts() map[string]string {
+	facts := map[string]string{}
+	if l.Noise != 0 {
+		facts[FactNoise] = strconv.Itoa(l.Noise)
+	}
+	if l.TxRate > 0 {
+		facts[FactTxRate] = strconv.FormatFloat(l.TxRate, 'f', -1, 64)
+	}
+	if l.Channel != "" {
+		facts[FactChannel] = l.Channel
+	}
+	if l.PHYMode != "" {
+		facts[FactPHYMode] = l.PHYMode
+	}
+	if l.MCS >= 0 {
+		facts[FactMCS] = strconv.Itoa(l.MCS)
+	}
+	return facts
+}
+
+// detailLines renders the structured fields of l the way system_profiler
+// lists them, for native backends that have no raw output to show.
+func (l wifiLink) detailLines() []string {
+	var lines []string
+	if l.PHYMode != "" {
+		lines = append(lines, "PHY Mode: "+l.PHYMode)
+	}
+	if l.Channel != "" {
+		lines = append(lines, "Channel: "+l.Channel)
+	}
+	if l.BSSID != "" {
+		lines = append(lines, "BSSID: "+l.BSSID)
+	}
+	if l.Noise != 0 {
+		lines = append(lines, fmt.Sprintf("Signal / Noise: %d dBm / %d dBm", l.RSSI, l.Noise))
+	}
+	if l.TxRate > 0 {
+		lines = append(lines, "Transmit Rate: "+strconv.FormatFloat(l.TxRate, 'f', -1, 64))
+	}
+	if l.MCS >= 0 {
+		lines = append(lines, "MCS Index: "+strconv.Itoa(l.MCS))
+	}
+	return lines
+}
+
+// CoreWLAN's CWPHYMode, CWChannelBand, CWChannelWidth and CWSecurity
+// values.
+var (
+	corewlanSecurity = []string{"None", "WEP", "WPA Personal", "WPA/WPA2 Personal", "WPA2 Personal", "Personal",
+		"Dynamic WEP", "WPA Enterprise", "WPA/WPA2 Enterprise", "WPA2 Enterprise", "Enterprise",
+		"WPA3 Personal", "WPA3 Enterprise", "WPA2/WPA3 Personal", "OWE", "OWE Transition"}
+	corewlanPHYModes = []string{"", "802.11a", "802.11b", "802.11g", "802.11n", "802.11ac", "802.11ax", "802.11be"}
+	corewlanBands    = []string{"", "2GHz", "5GHz", "6GHz"}
+	corewlanWidths   = []string{"", "20MHz", "40MHz", "80MHz", "160MHz"}
+)
+
+// corewlanName returns names[i], or "" when i is out of range.
+func corewlanName(names []string, i int) string {
+	if i < 0 || i >= len(names) {
+		return ""
+	}
+	return names[i]
+}
+
+// corewlanChannel formats a CWChannel like system_profiler: "36 (5GHz, 80MHz)".
+func corewlanChannel(number, band, width int) string {
+	if number == 0 {
+		return ""
+	}
+	b, w := corewlanName(corewlanBands, band), corewlanName(corewlanWidths, width)
+	switch {
+	case b != "" && w != "":
+		return fmt.Sprintf("%d (%s, %s)", number, b, w)
+	case b != "" || w != "":
+		return fmt.Sprintf("%d (%s%s)", number, b, w)
+	}
+	return strconv.Itoa(number)
+}
@@ -0,0 +1,37 @@
+package diagnostic
+
+import "testing"
+
+func TestCorewlanChannel(t *testing.T) {
+	tests := []struct {
+		number, band, width int
+		want                string
+	}{
+		{36, 2, 3, "36 (5GHz, 80MHz)"},
+		{1, 1, 0, "1 (2GHz)"},
+		{37, 9, 9, "37"},
+		{0, 1, 1, ""},
+	}
+	for _, tt := range tests {
+		if got := corewlanChannel(tt.number, tt.band, tt.width); got != tt.want {
+			t.Errorf("corewlanChannel(%d, %d, %d): expected %q, got %q", tt.number, tt.band, tt.width, tt.want, got)
+		}
+	}
+}
+
+func TestWiFiLinkDetailLines(t *testing.T) {
+	link := wifiLink{RSSI: -50, Noise: -92, TxRate: 573.5, PHYMode: "802.11ax", MCS: -1}
+	got := link.detailLines()
+	want := []string{"PHY Mode: 802.11ax", "Signal / Noise: -50 dBm / -92 dBm", "Transmit Rate: 573.5"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if f := link.facts(); f[FactMCS] != "" || f[FactTxRate] != "573.5" {
+		t.Errorf("Unexpected facts %v", f)
+	}
+}
@@ -0,0 +1,152 @@
+package diagnostic
+
+import (
+	"context"
+	"strings"
+)
+
+// Wi-Fi security modes, from weakest to strongest.
+const (
+	securityOpen       = "Open"
+	securityWEP        = "WEP"
+	securityWPA        = "WPA-Personal"
+	securityWPA2       = "WPA2-Personal"
+	securityTransition = "WPA2/WPA3-Personal"
+	securityWPA3       = "WPA3-Personal"
+	securityOWE        = "Enhanced Open (OWE)"
+	securityEnterprise = "802.1X (Enterprise)"
+)
+
+// FactWiFiSecurity is the security mode of the Wi-Fi network, one of the
+// security* names.
+const FactWiFiSecurity = "wifi_security"
+
+// classifySecurity maps the security mode as a platform names it, such as
+// "WPA2 Personal" (system_profiler), "SAE" or "WPA2/IEEE 802.1X/EAP"
+// (wpa_supplicant), onto one of the security* names, or "" if unknown.
+func classifySecurity(raw string) string {
+	s := strings.ToUpper(raw)
+	has := func(subs ...string) bool {
+		for _, sub := range subs {
+			if strings.Contains(s, sub) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has("OWE", "ENHANCED OPEN"):
+		return securityOWE
+	case has("ENTERPRISE", "802.1X", "EAP"):
+		return securityEnterprise
+	case has("WEP"):
+		return securityWEP
+	case has("WPA3", "SAE"):
+		if has("WPA2", "PSK") {
+			return securityTransition
+		}
+		return securityWPA3
+	case has("WPA2"):
+		return securityWPA2
+	case has("WPA", "PSK"):
+		return securityWPA
+	case has("NONE", "OPEN"):
+		return securityOpen
+	}
+	return ""
+}
+
+// CheckWiFiSecurity reports the security mode of the current Wi-Fi network
+// and warns on open, WEP and WPA networks, and on WPA2 without protected
+// management frames, which leaves clients open to forged disconnects.
+func CheckWiFiSecurity(ctx context.Context) Result {
+	res := Result{Name: "Wi-Fi Security", Emoji: "🔑", Status: StatusOk}
+	iface, err := getPrimaryInterface(ctx)
+	if err != nil {
+		res.Status = StatusSkipped
+		res.Message = "No default route found"
+		res.Reason = ReasonNoRoute
+		return res
+	}
+	link, err := readWiFiLink(ctx, iface, false)
+	if r, ok := missingToolResult(res.Name, res.Emoji, err); ok {
+		return r
+	}
+	if err != nil || link.RSSI == 0 {
+		res.Status = StatusSkipped
+		res.Message = "Not connected to Wi-Fi"
+		return res
+	}
+	readWiFiSecurity(ctx, iface, &link)
+
+	mode := classifySecurity(link.Security)
+	if mode == "" {
+		res.Status = StatusSkipped
+		res.Message = "Security mode not reported"
+		res.Reason = ReasonProbeFailed
+		return res
+	}
+	// WPA3 and OWE make protected management frames mandatory.
+	pmf := link.PMF
+	if pmf == "" && (mode == securityWPA3 || mode == securityOWE) {
+		pmf = "required"
+	}
+	res.Facts = map[string]string{FactWiFiSecurity: mode}
+	details := []string{"Mode: " + mode + " (" + link.Security + ")"}
+	if pmf != "" {
+		details = append(details, "PMF: "+pmf)
+	} else {
+		details = append(details, "PMF: not reported")
+	}
+	res.Details = formatDetailsWithPrefixes(details)
+	gradeWiFiSecurity(&res, mode, pmf)
+	return res
+}
+
+// readWiFiSecurity fills in the security mode and PMF of link from the
+// platform's supplicant when the link itself did not report them.
+func readWiFiSecurity(ctx context.Context, iface string, link *wifiLink) {
+	p := activePlatform()
+	if cmd := p.securityCommand(iface); link.Security == "" && cmd != nil {
+		if out, err := runPlatformCommand(ctx, cmd); err == nil {
+			link.Security, link.PMF = p.parseSecurity(string(out))
+		}
+	}
+}
+
+// currentWiFiSecurity returns the security mode of the Wi-Fi network in
+// use, one of the security* names, or "" when not on Wi-Fi or unknown.
+func currentWiFiSecurity(ctx context.Context) string {
+	iface, err := getPrimaryInterface(ctx)
+	if err != nil {
+		return ""
+	}
+	link, err := readWiFiLink(ctx, iface, false)
+	if err != nil || link.RSSI == 0 {
+		return ""
+	}
+	readWiFiSecurity(ctx, iface, &link)
+	return classifySecurity(link.Security)
+}
+
+// gradeWiFiSecurity sets the verdict of CheckWiFiSecurity.
+func gradeWiFiSecurity(res *Result, mode, pmf string) {
+	res.Message = mode
+	switch {
+	case mode == securityOpen:
+		res.Status = StatusWarning
+		res.Reason = ReasonOpenWiFi
+		res.Message = "Open network: anyone nearby can read unencrypted traffic"
+		res.Fix = "Use a VPN on this network, or stick to HTTPS; prefer a WPA2/WPA3 network."
+	case mode == securityWEP || mode == securityWPA:
+		res.Status = StatusWarning
+		res.Reason = ReasonWeakWiFiSecurity
+		res.Message = mode + " can be cracked in minutes"
+		res.Fix = "Use a VPN on this network; if it is yours, switch the router to WPA2 or WPA3."
+	case pmf == "disabled":
+		res.Status = StatusWarning
+		res.Reason = ReasonPMFDisabled
+		res.Message = mode + " without protected management frames"
+		res.Fix = "Forged deauthentication frames can knock you off or lure you to an evil twin; use a VPN, or enable PMF (802.11w) on the router."
+	}
+}
@@ -0,0 +1,75 @@
+package diagnostic
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClassifySecurity(t *testing.T) {
+	tests := map[string]string{
+		"None":                 securityOpen,
+		"WEP":                  securityWEP,
+		"WPA Personal":         securityWPA,
+		"WPA/WPA2 Personal":    securityWPA2,
+		"WPA2 Personal":        securityWPA2,
+		"WPA2/WPA3 Personal":   securityTransition,
+		"WPA3 Personal":        securityWPA3,
+		"WPA2 Enterprise":      securityEnterprise,
+		"OWE Transition":       securityOWE,
+		"WPA2-PSK":             securityWPA2,
+		"SAE":                  securityWPA3,
+		"WPA2/IEEE 802.1X/EAP": securityEnterprise,
+		"NONE":                 securityOpen,
+		"":                     "",
+		"something else":       "",
+	}
+	for raw, want := range tests {
+		if got := classifySecurity(raw); got != want {
+			t.Errorf("classifySecurity(%q): expected %q, got %q", raw, want, got)
+		}
+	}
+}
+
+func TestGradeWiFiSecurity(t *testing.T) {
+	tests := []struct {
+		mode, pmf string
+		status    Status
+		reason    Reason
+	}{
+		{securityOpen, "", StatusWarning, ReasonOpenWiFi},
+		{securityWEP, "", StatusWarning, ReasonWeakWiFiSecurity},
+		{securityWPA, "", StatusWarning, ReasonWeakWiFiSecurity},
+		{securityWPA2, "disabled", StatusWarning, ReasonPMFDisabled},
+		{securityWPA2, "", StatusOk, ""},
+		{securityWPA3, "required", StatusOk, ""},
+		{securityOWE, "required", StatusOk, ""},
+		{securityEnterprise, "optional", StatusOk, ""},
+	}
+	for _, tt := range tests {
+		res := Result{Status: StatusOk}
+		gradeWiFiSecurity(&res, tt.mode, tt.pmf)
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%s/%s: expected %v/%q, got %v/%q", tt.mode, tt.pmf, tt.status, tt.reason, res.Status, res.Reason)
+		}
+	}
+}
+
+func TestCheckWiFiSecurity(t *testing.T) {
+	withRunner(t, &fakeRunner{outputs: map[string]string{
+		"route -n get default": "    gateway: 192.168.1.1\n  interface: en0\n",
+		"system_profiler SPAirPortDataType": `      Current Network Information:
+        Hotel Guest:
+          PHY Mode: 802.11n
+          Security: None
+          Signal / Noise: -58 dBm / -90 dBm
+`,
+	}})
+
+	res := CheckWiFiSecurity(context.Background())
+	if res.Status != StatusWarning || res.Reason != ReasonOpenWiFi {
+		t.Errorf("Expected an open_wifi warning, got %v/%q (%s)", res.Status, res.Reason, res.Message)
+	}
+	if res.Facts[FactWiFiSecurity] != securityOpen {
+		t.Errorf("Expected the security fact, got %v", res.Facts)
+	}
+}
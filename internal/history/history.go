@@ -0,0 +1,250 @@
+// Package history keeps the results of past runs and turns them into trends,
+// so an intermittent problem can be seen across many runs instead of one.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/logfile"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+// maxFileSize caps the history file; past it the file is rotated and the
+// oldest runs eventually drop out.
+const maxFileSize = 10 << 20
+
+// DefaultPath returns $XDG_DATA_HOME/wtfi/history.jsonl, which is usually
+// ~/.local/share/wtfi/history.jsonl.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "wtfi", "history.jsonl")
+}
+
+// Append adds rec to the history at path as one JSON line, creating the
+// directory if needed.
+func Append(path string, rec ui.JSONRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	w, err := logfile.New(path, maxFileSize)
+	if err != nil {
+		return err
+	}
+	if err := ui.WriteNDJSON(w, rec); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Load returns the last n runs recorded at path, oldest first. The most
+// recent rotated file is read too, so a rotation does not cut the trend
+// short. Lines that do not parse are skipped.
+func Load(path string, n int) ([]ui.JSONRecord, error) {
+	var records []ui.JSONRecord
+	for _, p := range []string{path + ".1", path} {
+		recs, err := readFile(p)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	if len(records) > n {
+		records = records[len(records)-n:]
+	}
+	return records, nil
+}
+
+func readFile(path string) ([]ui.JSONRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []ui.JSONRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for sc.Scan() {
+		var rec ui.JSONRecord
+		if json.Unmarshal(sc.Bytes(), &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
+
+// Trend is one metric across runs. Values has an entry per run; NaN marks a
+// run where the metric was not measured.
+type Trend struct {
+	Metric string
+	Unit   string
+	Values []float64
+}
+
+// Stats summarizes the measured values of t.
+func (t Trend) Stats() (lo, avg, hi, last float64, n int) {
+	lo, hi, last = math.Inf(1), math.Inf(-1), math.NaN()
+	sum := 0.0
+	for _, v := range t.Values {
+		if math.IsNaN(v) {
+			continue
+		}
+		lo, hi, last = min(lo, v), max(hi, v), v
+		sum += v
+		n++
+	}
+	if n == 0 {
+		return 0, 0, 0, 0, 0
+	}
+	return lo, sum / float64(n), hi, last, n
+}
+
+// Median returns the typical measured value of t, or NaN when it was never
+// measured.
+func (t Trend) Median() float64 {
+	vs := t.Measured()
+	if len(vs) == 0 {
+		return math.NaN()
+	}
+	slices.Sort(vs)
+	if len(vs)%2 == 1 {
+		return vs[len(vs)/2]
+	}
+	return (vs[len(vs)/2-1] + vs[len(vs)/2]) / 2
+}
+
+// Measured returns t's values without the runs where it was not measured.
+func (t Trend) Measured() []float64 {
+	var vs []float64
+	for _, v := range t.Values {
+		if !math.IsNaN(v) {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// Trends extracts every check's latency and the Wi-Fi signal and throughput
+// facts from records, in order of first appearance. Checks are matched by
+// name without the parenthesized suffix, such as the SSID in "Wi-Fi (Home)",
+// so a trend survives a change of network.
+func Trends(records []ui.JSONRecord) []Trend {
+	var trends []Trend
+	index := map[string]int{}
+	add := func(run int, metric, unit string, v float64) {
+		i, ok := index[metric]
+		if !ok {
+			i = len(trends)
+			index[metric] = i
+			values := make([]float64, len(records))
+			for j := range values {
+				values[j] = math.NaN()
+			}
+			trends = append(trends, Trend{Metric: metric, Unit: unit, Values: values})
+		}
+		trends[i].Values[run] = v
+	}
+	facts := []struct{ key, metric, unit string }{
+		{diagnostic.FactRSSI, "Wi-Fi RSSI", "dBm"},
+		{diagnostic.FactDownloadMbps, "Download", "Mbps"},
+		{diagnostic.FactUploadMbps, "Upload", "Mbps"},
+	}
+
+	for run, rec := range records {
+		for _, r := range rec.Results {
+			if r.LatencyMs > 0 {
+				add(run, ui.BaseName(r.Name)+" latency", "ms", r.LatencyMs)
+			}
+			if v, err := strconv.ParseFloat(r.Facts[diagnostic.FactLoss], 64); err == nil {
+				add(run, ui.BaseName(r.Name)+" loss", "%", v)
+			}
+			if v, err := strconv.ParseFloat(r.Facts[diagnostic.FactJitter], 64); err == nil {
+				add(run, ui.BaseName(r.Name)+" jitter", "ms", v)
+			}
+			for _, f := range facts {
+				if v, err := strconv.ParseFloat(r.Facts[f.key], 64); err == nil {
+					add(run, f.metric, f.unit, v)
+				}
+			}
+		}
+	}
+	return trends
+}
+
+// Profile summarizes the runs recorded on one network.
+type Profile struct {
+	// Name is the network's SSID, or its route for a wired network.
+	Name string
+	Runs int
+	// Score is the average health score, and Problems the runs that had a
+	// warning or error.
+	Score    float64
+	Problems int
+	Last     time.Time
+}
+
+// Profiles groups records by the network they ran on, most recently seen
+// first. A Wi-Fi network is one profile across its access points. Runs
+// recorded offline or before networks were recorded are left out.
+func Profiles(records []ui.JSONRecord) []Profile {
+	var profiles []Profile
+	index := map[ui.JSONNetwork]int{}
+	for _, rec := range records {
+		if rec.Network == nil {
+			continue
+		}
+		key := *rec.Network
+		if key.SSID != "" {
+			key = ui.JSONNetwork{SSID: key.SSID}
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(profiles)
+			index[key] = i
+			profiles = append(profiles, Profile{Name: rec.Network.Name()})
+		}
+		p := &profiles[i]
+		p.Score += (float64(rec.Health.Score) - p.Score) / float64(p.Runs+1)
+		p.Runs++
+		if rec.Summary.Warning+rec.Summary.Error > 0 {
+			p.Problems++
+		}
+		p.Last = rec.Timestamp
+	}
+	slices.SortStableFunc(profiles, func(a, b Profile) int { return b.Last.Compare(a.Last) })
+	return profiles
+}
+
+// OnNetwork returns the records that ran on a network for which match is
+// true.
+func OnNetwork(records []ui.JSONRecord, match func(ui.JSONNetwork) bool) []ui.JSONRecord {
+	var out []ui.JSONRecord
+	for _, rec := range records {
+		if rec.Network != nil && match(*rec.Network) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/ui"
+)
+
+func record(wanMs float64, rssi string) ui.JSONRecord {
+	results := []diagnostic.Result{{Name: "Internet Reachability", Latency: time.Duration(wanMs * float64(time.Millisecond))}}
+	if rssi != "" {
+		results = append(results, diagnostic.Result{Name: "Wi-Fi (Home)", Facts: map[string]string{diagnostic.FactRSSI: rssi}})
+	}
+	return ui.NewJSONRecord(time.Now(), results)
+}
+
+func TestAppendLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtfi", "history.jsonl")
+	for _, ms := range []float64{10, 20, 30} {
+		if err := Append(path, record(ms, "")); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("not json\n")
+	_ = f.Close()
+
+	recs, err := Load(path, 2)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Results[0].LatencyMs != 20 || recs[1].Results[0].LatencyMs != 30 {
+		t.Errorf("Expected the last two runs, got %+v", recs)
+	}
+	if recs, err := Load(filepath.Join(t.TempDir(), "missing.jsonl"), 5); err != nil || len(recs) != 0 {
+		t.Errorf("Expected an empty history for a missing file, got %v (%v)", recs, err)
+	}
+}
+
+func TestTrends(t *testing.T) {
+	trends := Trends([]ui.JSONRecord{record(10, "-60"), record(30, ""), record(20, "-70")})
+	if len(trends) != 2 {
+		t.Fatalf("Expected latency and RSSI trends, got %+v", trends)
+	}
+	if trends[0].Metric != "Internet Reachability latency" || trends[1].Metric != "Wi-Fi RSSI" {
+		t.Errorf("Unexpected metrics %q and %q", trends[0].Metric, trends[1].Metric)
+	}
+	if !math.IsNaN(trends[1].Values[1]) || len(trends[1].Measured()) != 2 {
+		t.Errorf("Expected a gap where RSSI was not measured, got %v", trends[1].Values)
+	}
+	lo, avg, hi, last, n := trends[0].Stats()
+	if lo != 10 || avg != 20 || hi != 30 || last != 20 || n != 3 {
+		t.Errorf("Unexpected stats %v %v %v %v %d", lo, avg, hi, last, n)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	home := &ui.JSONNetwork{Interface: "en0", Gateway: "192.168.1.1", GatewayMAC: "aa:bb:cc:dd:ee:ff", SSID: "Home", BSSID: "aa:bb:cc:dd:ee:01"}
+	// The other access point of the same network.
+	home5 := &ui.JSONNetwork{Interface: "en0", Gateway: "192.168.1.1", GatewayMAC: "aa:bb:cc:dd:ee:ff", SSID: "Home", BSSID: "aa:bb:cc:dd:ee:02"}
+	cafe := &ui.JSONNetwork{Interface: "en7", Gateway: "10.0.0.1"}
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := func(minute int, n *ui.JSONNetwork, status diagnostic.Status) ui.JSONRecord {
+		rec := ui.NewJSONRecord(t0.Add(time.Duration(minute)*time.Minute), []diagnostic.Result{{Check: "wan", Status: status}})
+		rec.Network = n
+		return rec
+	}
+	records := []ui.JSONRecord{
+		run(0, home, diagnostic.StatusOk),
+		run(5, cafe, diagnostic.StatusError),
+		run(10, cafe, diagnostic.StatusOk),
+		run(15, nil, diagnostic.StatusError),
+		run(20, home5, diagnostic.StatusWarning),
+	}
+
+	got := Profiles(records)
+	if len(got) != 2 {
+		t.Fatalf("Expected a profile per network, got %+v", got)
+	}
+	if got[0].Name != "Home" || got[0].Runs != 2 || got[0].Score != 75 || got[0].Problems != 1 || !got[0].Last.Equal(t0.Add(20*time.Minute)) {
+		t.Errorf("Unexpected home profile %+v", got[0])
+	}
+	if got[1].Name != "en7 via 10.0.0.1" || got[1].Runs != 2 || got[1].Score != 50 || got[1].Problems != 1 {
+		t.Errorf("Unexpected cafe profile %+v", got[1])
+	}
+}
+
+func TestOnNetworkMedian(t *testing.T) {
+	var records []ui.JSONRecord
+	for i, ms := range []float64{10, 40, 20, 30} {
+		rec := record(ms, "")
+		rec.Network = &ui.JSONNetwork{SSID: []string{"Home", "Office"}[i%2]}
+		records = append(records, rec)
+	}
+	records = append(records, record(99, ""))
+
+	home := OnNetwork(records, func(n ui.JSONNetwork) bool { return n.SSID == "Home" })
+	if len(home) != 2 {
+		t.Fatalf("Expected the two runs at home, got %d", len(home))
+	}
+	if m := Trends(home)[0].Median(); m != 15 {
+		t.Errorf("Expected a median of 15ms, got %v", m)
+	}
+	if m := (Trend{Values: []float64{math.NaN()}}).Median(); !math.IsNaN(m) {
+		t.Errorf("Expected NaN without measurements, got %v", m)
+	}
+}
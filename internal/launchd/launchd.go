@@ -0,0 +1,93 @@
+// Package launchd writes the property list of a macOS launch agent, the
+// per-user background job "wtfi daemon install" registers with launchd.
+package launchd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Label identifies the wtfi agent to launchd.
+const Label = "io.github.kanywst.wtfi"
+
+// Agent is a launch agent that keeps one program running.
+type Agent struct {
+	Label string
+	// Args is the program and its arguments.
+	Args []string
+	// ErrorLogPath receives the program's stderr. Its stdout is discarded:
+	// launchd never rotates the file, so the program keeps its own log.
+	ErrorLogPath string
+	// ThrottleInterval is the minimum number of seconds between two starts,
+	// so a program that keeps exiting does not spin.
+	ThrottleInterval int
+}
+
+// DefaultPath returns ~/Library/LaunchAgents/<label>.plist, where launchd
+// looks for the agents of the logged-in user.
+func DefaultPath(label string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", label+".plist")
+}
+
+// DefaultLogPath returns ~/Library/Logs/wtfi/wtfi.log, which Console.app
+// lists under Log Reports.
+func DefaultLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Library", "Logs", "wtfi", "wtfi.log")
+}
+
+// DefaultErrorLogPath returns ~/Library/Logs/wtfi/wtfi.err.log, next to
+// DefaultLogPath.
+func DefaultErrorLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Library", "Logs", "wtfi", "wtfi.err.log")
+}
+
+// Plist returns a as a property list. The agent starts at login and is
+// restarted whenever it exits, at a low scheduling priority.
+func (a Agent) Plist() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	str := func(key, value string) {
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, escape(value))
+	}
+	str("Label", a.Label)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range a.Args {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", escape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	b.WriteString("\t<key>KeepAlive</key>\n\t<true/>\n")
+	str("ProcessType", "Background")
+	if a.ThrottleInterval > 0 {
+		fmt.Fprintf(&b, "\t<key>ThrottleInterval</key>\n\t<integer>%d</integer>\n", a.ThrottleInterval)
+	}
+	str("StandardOutPath", os.DevNull)
+	if a.ErrorLogPath != "" {
+		str("StandardErrorPath", a.ErrorLogPath)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package launchd
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPlist(t *testing.T) {
+	a := Agent{
+		Label:            Label,
+		Args:             []string{"/opt/homebrew/bin/wtfi", "-w", "--only=wifi,dns", "--webhook=https://example.com/?a=1&b=2"},
+		ErrorLogPath:     "/Users/me/Library/Logs/wtfi/wtfi.err.log",
+		ThrottleInterval: 30,
+	}
+	out := string(a.Plist())
+
+	for _, want := range []string{
+		"<key>Label</key>\n\t<string>io.github.kanywst.wtfi</string>",
+		"<string>/opt/homebrew/bin/wtfi</string>",
+		"<string>--webhook=https://example.com/?a=1&amp;b=2</string>",
+		"<key>KeepAlive</key>\n\t<true/>",
+		"<key>ThrottleInterval</key>\n\t<integer>30</integer>",
+		"<key>StandardOutPath</key>\n\t<string>/dev/null</string>",
+		"<key>StandardErrorPath</key>\n\t<string>/Users/me/Library/Logs/wtfi/wtfi.err.log</string>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the plist to contain %q, got:\n%s", want, out)
+		}
+	}
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err != io.EOF {
+				t.Errorf("Expected well-formed XML, got %v", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package logfile provides a size-capped, rotating log file writer.
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxBackups is how many rotated files (path.1 ... path.N) are kept.
+const maxBackups = 5
+
+// Writer appends to a file and rotates it once a write would exceed the size cap.
+type Writer struct {
+	path    string
+	maxSize int64
+	size    int64
+	f       *os.File
+
+	// shouldRotate decides whether writing n more bytes to a file of the
+	// given size requires a rotation first. Tests replace it.
+	shouldRotate func(size, n int64) bool
+}
+
+// New opens (or creates) path for appending, rotating at maxSize bytes.
+func New(path string, maxSize int64) (*Writer, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("max size must be positive, got %d", maxSize)
+	}
+	w := &Writer{path: path, maxSize: maxSize}
+	w.shouldRotate = func(size, n int64) bool {
+		// A single oversized record still gets written to a fresh file.
+		return size > 0 && size+n > w.maxSize
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p, rotating beforehand if the cap would be exceeded.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.shouldRotate(w.size, int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file.
+func (w *Writer) Close() error {
+	return w.f.Close()
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f, w.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts path.N-1 → path.N, ..., path → path.1 and reopens path.
+// When the shift fails, path is reopened as it is, so later writes keep
+// appending to it instead of failing on the closed file.
+func (w *Writer) rotate() error {
+	if err := errors.Join(w.f.Close(), w.shift()); err != nil {
+		return errors.Join(err, w.open())
+	}
+	return w.open()
+}
+
+// shift renames the rotated files and path up by one.
+func (w *Writer) shift() error {
+	for i := maxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", w.path, i)
+		if _, err := os.Stat(src); err == nil {
+			if err := os.Rename(src, fmt.Sprintf("%s.%d", w.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(w.path, w.path+".1")
+}
+
+// ParseSize parses a human-readable size such as "512KB", "10MB" or "1GB".
+// A bare number is taken as bytes.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, mult = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,115 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtfi.log")
+	w, err := New(path, 1024)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	// Rotate before every write after the first to exercise the shifting.
+	writes := 0
+	w.shouldRotate = func(_, _ int64) bool {
+		writes++
+		return writes > 1
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "third\n",
+		path + ".1": "second\n",
+		path + ".2": "first\n",
+	}
+	for p, content := range want {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", p, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected %q, got %q", p, content, got)
+		}
+	}
+}
+
+func TestWriterSizeCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtfi.log")
+	w, err := New(path, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	for _, line := range []string{"12345\n", "1234\n", "x\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if got, _ := os.ReadFile(path + ".1"); string(got) != "12345\n" {
+		t.Errorf("Expected rotation before exceeding the cap, got %q", got)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "1234\nx\n" {
+		t.Errorf("Expected writes under the cap to share a file, got %q", got)
+	}
+}
+
+func TestWriterSurvivesFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtfi.log")
+	w, err := New(path, 1024)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	// A file cannot be renamed over a non-empty directory.
+	if err := os.WriteFile(path+".4", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path+".5", "taken"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	rotate := true
+	w.shouldRotate = func(_, _ int64) bool { return rotate }
+	if _, err := w.Write([]byte("dropped\n")); err == nil {
+		t.Fatal("Expected the failed rotation to be reported")
+	}
+
+	rotate = false
+	if _, err := w.Write([]byte("kept\n")); err != nil {
+		t.Fatalf("Expected writes to go on after a failed rotation, got %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "kept\n" {
+		t.Errorf("Expected the log to be appended to, got %q", got)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"10MB":  10 << 20,
+		"512kb": 512 << 10,
+		"1GB":   1 << 30,
+		"2048":  2048,
+	}
+	for in, want := range tests {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q): expected %d, got %d (%v)", in, want, got, err)
+		}
+	}
+	for _, bad := range []string{"", "MB", "-1MB", "ten", "99999999999GB", "9223372036854775807KB"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q): expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package otlp exports diagnostic runs to an OpenTelemetry collector over
+// OTLP/HTTP with JSON encoding: each run becomes a trace whose child spans
+// are the checks, plus gauges of check latency, status and the health score.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// scopeName identifies wtfi as the instrumentation scope.
+const scopeName = "github.com/kanywst/wtfi"
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	statusUnset      = 0
+	statusOK         = 1
+	statusError      = 2
+)
+
+// Check is one check of a run with when it started and finished.
+type Check struct {
+	Start, End time.Time
+	Result     diagnostic.Result
+}
+
+// Run is one diagnostic run to export.
+type Run struct {
+	Start, End time.Time
+	Checks     []Check
+}
+
+// Exporter posts runs to the collector at Endpoint, the base URL the OTLP
+// paths /v1/traces and /v1/metrics are appended to, as with
+// OTEL_EXPORTER_OTLP_ENDPOINT.
+type Exporter struct {
+	Endpoint string
+	// Headers are sent with every request, e.g. an API key.
+	Headers map[string]string
+	// Version is reported as service.version.
+	Version string
+	Client  *http.Client
+}
+
+// Export sends run as a trace and as metrics.
+func (e *Exporter) Export(ctx context.Context, run Run) error {
+	res := e.resource()
+	if err := e.post(ctx, "/v1/traces", tracesPayload(res, run)); err != nil {
+		return fmt.Errorf("traces: %w", err)
+	}
+	if err := e.post(ctx, "/v1/metrics", metricsPayload(res, run)); err != nil {
+		return fmt.Errorf("metrics: %w", err)
+	}
+	return nil
+}
+
+func (e *Exporter) resource() resource {
+	attrs := []keyValue{str("service.name", "wtfi"), str("service.version", e.Version)}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, str("host.name", host))
+	}
+	return resource{Attributes: attrs}
+}
+
+func (e *Exporter) post(ctx context.Context, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON message types, limited to the fields wtfi fills in.
+type (
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	anyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+
+	tracesRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	scopeSpans struct {
+		Scope scope  `json:"scope"`
+		Spans []span `json:"spans"`
+	}
+	span struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            spanStatus `json:"status"`
+	}
+	spanStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+
+	metricsRequest struct {
+		ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+	}
+	resourceMetrics struct {
+		Resource     resource       `json:"resource"`
+		ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+	}
+	scopeMetrics struct {
+		Scope   scope    `json:"scope"`
+		Metrics []metric `json:"metrics"`
+	}
+	metric struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Unit        string `json:"unit,omitempty"`
+		Gauge       gauge  `json:"gauge"`
+	}
+	gauge struct {
+		DataPoints []dataPoint `json:"dataPoints"`
+	}
+	dataPoint struct {
+		Attributes   []keyValue `json:"attributes,omitempty"`
+		TimeUnixNano string     `json:"timeUnixNano"`
+		AsDouble     float64    `json:"asDouble"`
+	}
+)
+
+func str(key, value string) keyValue { return keyValue{key, anyValue{StringValue: &value}} }
+
+func integer(key string, value int) keyValue {
+	s := strconv.Itoa(value)
+	return keyValue{key, anyValue{IntValue: &s}}
+}
+
+func double(key string, value float64) keyValue { return keyValue{key, anyValue{DoubleValue: &value}} }
+
+func nanos(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+// randomID returns n random bytes in hex, as trace and span IDs are.
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// tracesPayload turns run into a root span with one child span per check.
+// Checks that errored have an error status, and OK ones an OK status;
+// warnings and skipped checks leave it unset and say so in wtfi.status.
+func tracesPayload(res resource, run Run) tracesRequest {
+	traceID, rootID := randomID(16), randomID(8)
+	h := diagnostic.AssessHealth(results(run))
+	spans := []span{{
+		TraceID:           traceID,
+		SpanID:            rootID,
+		Name:              "wtfi run",
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: nanos(run.Start),
+		EndTimeUnixNano:   nanos(run.End),
+		Attributes:        []keyValue{integer("wtfi.health.score", h.Score), str("wtfi.health.verdict", h.Verdict)},
+		Status:            spanStatus{Code: statusOK},
+	}}
+	for _, c := range run.Checks {
+		r := c.Result
+		attrs := []keyValue{str("wtfi.check", r.Check), str("wtfi.status", r.Status.String())}
+		if r.Latency > 0 {
+			attrs = append(attrs, double("wtfi.latency_ms", ms(r.Latency)))
+		}
+		if r.Message != "" {
+			attrs = append(attrs, str("wtfi.message", r.Message))
+		}
+		if r.Reason != "" {
+			attrs = append(attrs, str("wtfi.reason", string(r.Reason)))
+		}
+		status := spanStatus{Code: statusUnset}
+		switch r.Status {
+		case diagnostic.StatusOk:
+			status.Code = statusOK
+		case diagnostic.StatusError:
+			status = spanStatus{Code: statusError, Message: r.Message}
+			spans[0].Status = spanStatus{Code: statusError, Message: h.Verdict}
+		}
+		spans = append(spans, span{
+			TraceID:           traceID,
+			SpanID:            randomID(8),
+			ParentSpanID:      rootID,
+			Name:              r.Check,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: nanos(c.Start),
+			EndTimeUnixNano:   nanos(c.End),
+			Attributes:        attrs,
+			Status:            status,
+		})
+	}
+	return tracesRequest{[]resourceSpans{{res, []scopeSpans{{scope{scopeName}, spans}}}}}
+}
+
+// metricsPayload turns run into gauges: the latency and status (0 OK,
+// 1 warning, 2 error) of each check that ran, and the health score.
+func metricsPayload(res resource, run Run) metricsRequest {
+	at := nanos(run.End)
+	var latency, status []dataPoint
+	for _, c := range run.Checks {
+		r := c.Result
+		if r.Status == diagnostic.StatusSkipped {
+			continue
+		}
+		attrs := []keyValue{str("wtfi.check", r.Check)}
+		status = append(status, dataPoint{attrs, at, float64(r.Status)})
+		if r.Latency > 0 {
+			latency = append(latency, dataPoint{attrs, at, ms(r.Latency)})
+		}
+	}
+	h := diagnostic.AssessHealth(results(run))
+	metrics := []metric{
+		{"wtfi.check.latency", "Latency each check measured", "ms", gauge{latency}},
+		{"wtfi.check.status", "Status of each check: 0 OK, 1 warning, 2 error", "1", gauge{status}},
+		{"wtfi.health.score", "Share of checks that passed, weighted", "1", gauge{[]dataPoint{{nil, at, float64(h.Score)}}}},
+	}
+	return metricsRequest{[]resourceMetrics{{res, []scopeMetrics{{scope{scopeName}, metrics}}}}}
+}
+
+func results(run Run) []diagnostic.Result {
+	out := make([]diagnostic.Result, len(run.Checks))
+	for i, c := range run.Checks {
+		out[i] = c.Result
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func testRun() Run {
+	start := time.Unix(1700000000, 0)
+	return Run{
+		Start: start,
+		End:   start.Add(3 * time.Second),
+		Checks: []Check{
+			{start, start.Add(time.Second), diagnostic.Result{Check: "gateway", Status: diagnostic.StatusOk, Latency: 4 * time.Millisecond}},
+			{start, start.Add(2 * time.Second), diagnostic.Result{Check: "dns", Status: diagnostic.StatusError, Message: "No answer", Reason: diagnostic.ReasonUnreachable}},
+			{start, start, diagnostic.Result{Check: "speed", Status: diagnostic.StatusSkipped}},
+		},
+	}
+}
+
+func TestTracesPayload(t *testing.T) {
+	req := tracesPayload(resource{}, testRun())
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 4 {
+		t.Fatalf("Expected a root span and one per check, got %d", len(spans))
+	}
+	root := spans[0]
+	if root.Status.Code != statusError || root.StartTimeUnixNano != "1700000000000000000" {
+		t.Errorf("Expected the root span to fail with the run, got %+v", root)
+	}
+	for _, s := range spans[1:] {
+		if s.TraceID != root.TraceID || s.ParentSpanID != root.SpanID || len(s.SpanID) != 16 || len(s.TraceID) != 32 {
+			t.Errorf("Expected %s to be a child of the run, got %+v", s.Name, s)
+		}
+	}
+	if dns := spans[2]; dns.Name != "dns" || dns.Status.Code != statusError || dns.EndTimeUnixNano != "1700000002000000000" {
+		t.Errorf("Unexpected dns span %+v", dns)
+	}
+	if spans[1].Status.Code != statusOK || spans[3].Status.Code != statusUnset {
+		t.Errorf("Expected OK and unset statuses, got %d and %d", spans[1].Status.Code, spans[3].Status.Code)
+	}
+}
+
+func TestMetricsPayload(t *testing.T) {
+	req := metricsPayload(resource{}, testRun())
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	points := map[string]int{}
+	for _, m := range metrics {
+		points[m.Name] = len(m.Gauge.DataPoints)
+	}
+	// Only the gateway measured a latency, and the skipped check has no status.
+	if points["wtfi.check.latency"] != 1 || points["wtfi.check.status"] != 2 || points["wtfi.health.score"] != 1 {
+		t.Errorf("Unexpected data points %v", points)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string]map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Api-Key") != "secret" {
+			http.Error(w, "bad headers", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var v map[string]any
+		if err := json.Unmarshal(body, &v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		got[r.URL.Path] = v
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e := &Exporter{Endpoint: srv.URL + "/", Headers: map[string]string{"Api-Key": "secret"}, Version: "1.0.0"}
+	if err := e.Export(context.Background(), testRun()); err != nil {
+		t.Fatalf("Expected the export to succeed, got %v", err)
+	}
+	if got["/v1/traces"]["resourceSpans"] == nil || got["/v1/metrics"]["resourceMetrics"] == nil {
+		t.Errorf("Expected traces and metrics to reach the collector, got %v", got)
+	}
+
+	e.Headers = nil
+	if err := e.Export(context.Background(), testRun()); err == nil {
+		t.Error("Expected a rejected export to fail")
+	}
+}
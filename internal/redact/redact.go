@@ -0,0 +1,138 @@
+// Package redact masks identifying data in results so output can be shared.
+package redact
+
+import (
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+var (
+	reMAC  = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{1,2}:){5}[0-9a-f]{1,2}\b`)
+	reIPv4 = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	reIPv6 = regexp.MustCompile(`(?i)\b[0-9a-f]{1,4}(?::[0-9a-f]{0,4}){2,7}\b`)
+)
+
+// wellKnown addresses are public services wtfi probes; they reveal nothing.
+var wellKnown = map[string]bool{
+	"1.1.1.1":              true,
+	"1.0.0.1":              true,
+	"8.8.8.8":              true,
+	"8.8.4.4":              true,
+	"239.255.255.250":      true,
+	"2606:4700:4700::1111": true,
+}
+
+// SSID keeps the first and last character of ssid and masks the rest.
+func SSID(ssid string) string {
+	r := []rune(ssid)
+	if len(r) <= 2 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[0]) + strings.Repeat("*", len(r)-2) + string(r[len(r)-1])
+}
+
+// MAC keeps the vendor prefix (OUI) of mac and masks the device part.
+func MAC(mac string) string {
+	parts := strings.Split(mac, ":")
+	if len(parts) != 6 {
+		return mac
+	}
+	return strings.Join(parts[:3], ":") + ":xx:xx:xx"
+}
+
+// IP masks an address: private IPv4 keeps its /24, public IPv4 only its
+// first octet, and global IPv6 its first two groups. Well-known service
+// addresses and strings that are not IPs are returned unchanged.
+func IP(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil || wellKnown[s] || ip.IsLoopback() || ip.IsUnspecified() {
+		return s
+	}
+	if v4 := ip.To4(); v4 != nil {
+		octets := strings.Split(v4.String(), ".")
+		if v4.IsPrivate() || v4.IsLinkLocalUnicast() {
+			return strings.Join(octets[:3], ".") + ".x"
+		}
+		return octets[0] + ".x.x.x"
+	}
+	groups := strings.Split(ip.String(), ":")
+	if len(groups) < 2 {
+		return s
+	}
+	return groups[0] + ":" + groups[1] + ":x::x"
+}
+
+// Text masks every MAC and IP address in s, and each of ssids.
+func Text(s string, ssids ...string) string {
+	for _, ssid := range ssids {
+		if ssid != "" {
+			s = strings.ReplaceAll(s, ssid, SSID(ssid))
+		}
+	}
+	s = reMAC.ReplaceAllStringFunc(s, MAC)
+	s = reIPv4.ReplaceAllStringFunc(s, IP)
+	return reIPv6.ReplaceAllStringFunc(s, IP)
+}
+
+// Redactor masks results one at a time. It remembers the SSIDs it has seen,
+// so later results that mention them are masked too. A nil Redactor returns
+// results unchanged. It is safe for concurrent use.
+type Redactor struct {
+	mu    sync.Mutex
+	ssids []string
+}
+
+// Result returns a masked copy of r.
+func (rd *Redactor) Result(r diagnostic.Result) diagnostic.Result {
+	if rd == nil {
+		return r
+	}
+	rd.AddSSID(r.Facts[diagnostic.FactSSID])
+
+	r.Name = rd.Text(r.Name)
+	r.Message = rd.Text(r.Message)
+	r.Fix = rd.Text(r.Fix)
+	r.FixCommand = rd.Text(r.FixCommand)
+	details := make([]string, len(r.Details))
+	for i, d := range r.Details {
+		details[i] = rd.Text(d)
+	}
+	r.Details = details
+	if r.Facts != nil {
+		facts := make(map[string]string, len(r.Facts))
+		for k, v := range r.Facts {
+			facts[k] = rd.Text(v)
+		}
+		r.Facts = facts
+	}
+	return r
+}
+
+// AddSSID masks ssid in everything redacted from now on, for text that
+// mentions a network the current run did not see, such as a baseline's.
+func (rd *Redactor) AddSSID(ssid string) {
+	if rd == nil || ssid == "" {
+		return
+	}
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if !slices.Contains(rd.ssids, ssid) {
+		rd.ssids = append(rd.ssids, ssid)
+	}
+}
+
+// Text masks s with the SSIDs seen so far.
+func (rd *Redactor) Text(s string) string {
+	if rd == nil {
+		return s
+	}
+	rd.mu.Lock()
+	ssids := slices.Clone(rd.ssids)
+	rd.mu.Unlock()
+	return Text(s, ssids...)
+}
@@ -0,0 +1,88 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestSSID(t *testing.T) {
+	tests := map[string]string{
+		"HomeNet": "H*****t",
+		"Café-5G": "C*****G",
+		"ab":      "**",
+		"":        "",
+	}
+	for in, want := range tests {
+		if got := SSID(in); got != want {
+			t.Errorf("SSID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	if got := MAC("a4:83:e7:01:02:03"); got != "a4:83:e7:xx:xx:xx" {
+		t.Errorf("Expected the OUI to be kept, got %s", got)
+	}
+	if got := MAC("not-a-mac"); got != "not-a-mac" {
+		t.Errorf("Expected non-MACs to be unchanged, got %s", got)
+	}
+}
+
+func TestIP(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.1":          "192.168.1.x",
+		"10.0.0.254":           "10.0.0.x",
+		"203.0.113.7":          "203.x.x.x",
+		"1.1.1.1":              "1.1.1.1",
+		"127.0.0.1":            "127.0.0.1",
+		"2001:db8:1234::5":     "2001:db8:x::x",
+		"2606:4700:4700::1111": "2606:4700:4700::1111",
+		"12:30:45":             "12:30:45",
+	}
+	for in, want := range tests {
+		if got := IP(in); got != want {
+			t.Errorf("IP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	in := "Gateway 192.168.1.1 at a4:83:e7:01:02:03 on HomeNet via 1.1.1.1, seen 12:30:45"
+	want := "Gateway 192.168.1.x at a4:83:e7:xx:xx:xx on H*****t via 1.1.1.1, seen 12:30:45"
+	if got := Text(in, "HomeNet"); got != want {
+		t.Errorf("Text() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRedactorResult(t *testing.T) {
+	var rd Redactor
+	wifi := diagnostic.Result{
+		Name:    "Wi-Fi (HomeNet)",
+		Status:  diagnostic.StatusWarning,
+		Latency: 5,
+		Facts:   map[string]string{diagnostic.FactSSID: "HomeNet", diagnostic.FactBSSID: "a4:83:e7:01:02:03"},
+		Details: []string{"└─ BSSID: a4:83:e7:01:02:03"},
+	}
+	got := rd.Result(wifi)
+	if got.Name != "Wi-Fi (H*****t)" || got.Facts[diagnostic.FactBSSID] != "a4:83:e7:xx:xx:xx" || got.Details[0] != "└─ BSSID: a4:83:e7:xx:xx:xx" {
+		t.Errorf("Unexpected redaction %+v", got)
+	}
+	if got.Status != wifi.Status || got.Latency != wifi.Latency {
+		t.Error("Expected measurements to be untouched")
+	}
+	if wifi.Facts[diagnostic.FactSSID] != "HomeNet" || wifi.Details[0] != "└─ BSSID: a4:83:e7:01:02:03" {
+		t.Error("Expected the original result not to be modified")
+	}
+
+	later := rd.Result(diagnostic.Result{Message: "Low Data Mode is on for HomeNet"})
+	if strings.Contains(later.Message, "HomeNet") {
+		t.Errorf("Expected a previously seen SSID to be masked, got %s", later.Message)
+	}
+
+	var none *Redactor
+	if r := none.Result(wifi); r.Name != wifi.Name {
+		t.Error("Expected a nil Redactor to leave results unchanged")
+	}
+}
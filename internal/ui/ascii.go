@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// ASCII makes the text output plain ASCII: symbols are spelled out and
+// emoji dropped, for terminals or fonts that render them as mojibake and
+// for output read by other tools. JSON, Markdown and HTML are unaffected.
+var ASCII bool
+
+var asciiReplacer = strings.NewReplacer(
+	"├─", "|-",
+	"└─", "`-",
+	"→", "->",
+	"↓", "down",
+	"↑", "up",
+	"▲", "^",
+	"▼", "v",
+	"✓", "ok",
+	"✗", "x",
+	"·", "-",
+	"…", "...",
+	"µs", "us",
+	"—", "-",
+	"✅", "[ok]",
+	"⚠️", "[!]",
+	"ℹ️", "(i)",
+)
+
+// asciiBlocks stand in for sparkBlocks in ASCII mode, lowest first.
+var asciiBlocks = []rune("_.:-=+*#")
+
+// Text returns s as it prints: unchanged, or in ASCII mode with the
+// symbols wtfi uses spelled out and any other emoji, with the spaces after
+// it, removed. Letters of other scripts, as in an SSID, are kept.
+func Text(s string) string {
+	if !ASCII {
+		return s
+	}
+	s = asciiReplacer.Replace(s)
+	var b strings.Builder
+	dropped := false
+	for _, r := range s {
+		switch {
+		case isEmoji(r):
+			dropped = true
+			continue
+		case dropped && r == ' ':
+			continue
+		}
+		dropped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isEmoji reports whether r is a pictograph or one of the invisible runes
+// that modify one: variation selectors, joiners and the keycap.
+func isEmoji(r rune) bool {
+	if r <= unicode.MaxASCII {
+		return false
+	}
+	return unicode.Is(unicode.So, r) || unicode.Is(unicode.Variation_Selector, r) || r == '\u200d' || r == '\u20e3'
+}
+
+// statusMark stands in for a result's emoji in ASCII mode, so its status
+// still shows without color.
+func statusMark(s diagnostic.Status) string {
+	switch s {
+	case diagnostic.StatusWarning:
+		return "!"
+	case diagnostic.StatusError:
+		return "x"
+	case diagnostic.StatusSkipped:
+		return "-"
+	}
+	return "+"
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// HealthScore rates a run from 0 to 100 from its summary alone: every check
+// that ran counts fully when OK and half when it only warned. Skipped checks
+// do not count, and a run where nothing ran scores 100. Records written
+// before JSONRecord.Health existed are scored this way.
+func HealthScore(s JSONSummary) int {
+	ran := s.OK + s.Warning + s.Error
+	if ran == 0 {
+		return 100
+	}
+	return (100*s.OK + 50*s.Warning) / ran
+}
+
+// htmlReport is the data behind reportTemplate.
+type htmlReport struct {
+	JSONRecord
+	Score   int
+	Version string
+}
+
+// reportTemplate is a single self-contained page: the styling is inline and
+// nothing is loaded from the network, so the file can be attached to a
+// ticket as is.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"latency": func(ms float64) string {
+		if ms <= 0 {
+			return ""
+		}
+		return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>wtfi network report {{.Timestamp.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; color: #1d1d1f; background: #fafafa; }
+h1 { font-size: 1.5rem; margin-bottom: 0.25rem; }
+.meta { color: #6e6e73; margin-top: 0; }
+.summary { display: flex; align-items: center; gap: 1.5rem; background: #fff; border-radius: 12px; padding: 1rem 1.5rem; box-shadow: 0 1px 3px rgba(0,0,0,0.1); }
+.score { font-size: 2.5rem; font-weight: 700; }
+.counts span { margin-right: 1rem; }
+.verdict { margin: 0.25rem 0; }
+.causes { background: #fff; border-radius: 12px; padding: 0.75rem 1.5rem; margin: 1rem 0; box-shadow: 0 1px 3px rgba(0,0,0,0.1); }
+.check { background: #fff; border-radius: 12px; padding: 0.75rem 1.5rem; margin: 1rem 0; box-shadow: 0 1px 3px rgba(0,0,0,0.1); border-left: 6px solid #34c759; }
+.check h2 { font-size: 1.1rem; display: flex; justify-content: space-between; margin: 0.5rem 0; }
+.badge { font-size: 0.8rem; padding: 0.1rem 0.6rem; border-radius: 999px; color: #fff; background: #34c759; text-transform: uppercase; }
+.warning { border-left-color: #ff9f0a; } .warning .badge { background: #ff9f0a; }
+.error { border-left-color: #ff3b30; } .error .badge { background: #ff3b30; }
+.skipped { border-left-color: #8e8e93; } .skipped .badge { background: #8e8e93; }
+.score.ok { color: #34c759; } .score.warning { color: #ff9f0a; } .score.error { color: #ff3b30; }
+ul.details { font-family: ui-monospace, Menlo, monospace; font-size: 0.85rem; color: #3a3a3c; padding-left: 1.25rem; }
+.fix { color: #0a60ff; } code { background: #f2f2f7; padding: 0.1rem 0.3rem; border-radius: 4px; }
+</style>
+</head>
+<body>
+<h1>wtfi network report</h1>
+<p class="meta">{{.Timestamp.Format "Mon, 02 Jan 2006 15:04:05 MST"}}{{if .Version}} · wtfi {{.Version}}{{end}}</p>
+<div class="summary">
+<div class="score {{.Summary.Status}}">{{.Score}}</div>
+<div>
+<strong>Health score</strong> (overall: {{.Summary.Status}})
+{{with .Health.Verdict}}<div class="verdict">{{.}}</div>{{end}}
+<div class="counts"><span>{{.Summary.OK}} OK</span><span>{{.Summary.Warning}} warning</span><span>{{.Summary.Error}} error</span><span>{{.Summary.Skipped}} skipped</span></div>
+</div>
+</div>
+{{with .Causes}}
+<div class="causes">
+<h2>Probable causes</h2>
+<ol>{{range .}}<li><strong>{{.Summary}}</strong> ({{.Confidence}} confidence){{with .Fix}}<p class="fix">Fix: {{.}}</p>{{end}}</li>{{end}}</ol>
+</div>
+{{end}}
+{{range .Results}}
+<div class="check {{.Status}}">
+<h2><span>{{.Name}}</span><span>{{with latency .LatencyMs}}{{.}} {{end}}<span class="badge">{{.Status}}</span></span></h2>
+{{with .Message}}<p>{{.}}</p>{{end}}
+{{with .Details}}<ul class="details">{{range .}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if ne .Status "ok"}}{{with .Fix}}<p class="fix">Fix: {{.}}</p>{{end}}{{with .FixCommand}}<p>Run: <code>{{.}}</code></p>{{end}}{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTMLReport renders rec as a standalone HTML page with a health
+// score and verdict, every result and its details, such as the traceroute
+// hops.
+func WriteHTMLReport(w io.Writer, rec JSONRecord, version string) error {
+	score := rec.Health.Score
+	if rec.Health.Verdict == "" {
+		score = HealthScore(rec.Summary)
+	}
+	return reportTemplate.Execute(w, htmlReport{JSONRecord: rec, Score: score, Version: version})
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestHealthScore(t *testing.T) {
+	tests := []struct {
+		s    JSONSummary
+		want int
+	}{
+		{JSONSummary{}, 100},
+		{JSONSummary{OK: 4}, 100},
+		{JSONSummary{OK: 2, Warning: 2}, 75},
+		{JSONSummary{OK: 1, Error: 1, Skipped: 5}, 50},
+		{JSONSummary{Error: 3}, 0},
+	}
+	for _, tt := range tests {
+		if got := HealthScore(tt.s); got != tt.want {
+			t.Errorf("HealthScore(%+v): expected %d, got %d", tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	rec := NewJSONRecord(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), []diagnostic.Result{
+		{Name: "Fast Trace", Status: diagnostic.StatusOk, Details: []string{"├─ Hop 1: 192.168.1.1", "└─ Hop 2: <script>"}},
+		{Name: "DNS", Status: diagnostic.StatusWarning, Latency: 250 * time.Millisecond, Message: "Slow", Fix: "Use another resolver."},
+	})
+	var b strings.Builder
+	if err := WriteHTMLReport(&b, rec, "1.2.3"); err != nil {
+		t.Fatalf("WriteHTMLReport: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{">75<", "Hop 1: 192.168.1.1", "Hop 2: &lt;script&gt;", "250ms", "Fix: Use another resolver.", "wtfi 1.2.3", "<style>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the report to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "<link") {
+		t.Error("Expected a self-contained report without scripts or external resources")
+	}
+}
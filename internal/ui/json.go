@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// JSONResult is the machine-readable form of a diagnostic.Result.
+type JSONResult struct {
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	LatencyMs  float64           `json:"latency_ms"`
+	Message    string            `json:"message,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Fix        string            `json:"fix,omitempty"`
+	FixCommand string            `json:"fix_command,omitempty"`
+	Details    []string          `json:"details,omitempty"`
+	Facts      map[string]string `json:"facts,omitempty"`
+	// Percentiles is present when a latency sampling window was measured.
+	Percentiles *JSONPercentiles `json:"latency_percentiles,omitempty"`
+}
+
+// JSONPercentiles is the machine-readable form of diagnostic.LatencyPercentiles.
+type JSONPercentiles struct {
+	Samples int     `json:"samples"`
+	P50Ms   float64 `json:"p50_ms"`
+	P90Ms   float64 `json:"p90_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// JSONRecord is a single timestamped run of all checks.
+type JSONRecord struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Summary   JSONSummary `json:"summary"`
+	Health    JSONHealth  `json:"health"`
+	// Causes are the probable root causes, most confident first.
+	Causes []JSONCause `json:"causes,omitempty"`
+	// Network is the network the run was on; absent when offline.
+	Network *JSONNetwork `json:"network,omitempty"`
+	Results []JSONResult `json:"results"`
+}
+
+// JSONNetwork identifies the network a run was on: its default route as
+// in diagnostic.Network, and the Wi-Fi it was joined to, if any.
+type JSONNetwork struct {
+	Interface  string `json:"interface"`
+	Gateway    string `json:"gateway"`
+	GatewayMAC string `json:"gateway_mac,omitempty"`
+	SSID       string `json:"ssid,omitempty"`
+	BSSID      string `json:"bssid,omitempty"`
+}
+
+// NewJSONNetwork describes the network of a run from n and the Wi-Fi facts
+// among results, returning nil when offline.
+func NewJSONNetwork(n diagnostic.Network, results []diagnostic.Result) *JSONNetwork {
+	jn := JSONNetwork{Interface: n.Interface, Gateway: n.Gateway, GatewayMAC: n.GatewayMAC}
+	for _, r := range results {
+		if ssid := r.Facts[diagnostic.FactSSID]; ssid != "" && jn.SSID == "" {
+			jn.SSID, jn.BSSID = ssid, r.Facts[diagnostic.FactBSSID]
+		}
+	}
+	if jn == (JSONNetwork{}) {
+		return nil
+	}
+	return &jn
+}
+
+// Name is the SSID of a Wi-Fi network, or else its route as described by
+// diagnostic.Network.
+func (n *JSONNetwork) Name() string {
+	if n == nil {
+		return diagnostic.Network{}.String()
+	}
+	if n.SSID != "" {
+		return n.SSID
+	}
+	return diagnostic.Network{Interface: n.Interface, Gateway: n.Gateway, GatewayMAC: n.GatewayMAC}.String()
+}
+
+// Types of the lines of the streaming ndjson output.
+const (
+	EventCheck = "check"
+	EventRun   = "run"
+)
+
+// JSONCheckEvent is the ndjson line written as soon as a check finishes.
+type JSONCheckEvent struct {
+	Type string `json:"type"`
+	// Timestamp is when the check finished.
+	Timestamp time.Time `json:"timestamp"`
+	// Check is the registered name, e.g. "dns", where Name is for display.
+	Check string `json:"check"`
+	JSONResult
+}
+
+// JSONRunEvent is the ndjson line that closes a run once every check is in.
+type JSONRunEvent struct {
+	Type string `json:"type"`
+	// Timestamp is when the run started, as in JSONRecord.
+	Timestamp time.Time   `json:"timestamp"`
+	Summary   JSONSummary `json:"summary"`
+	Health    JSONHealth  `json:"health"`
+	Causes    []JSONCause `json:"causes,omitempty"`
+}
+
+// JSONCause is the machine-readable form of diagnostic.Cause.
+type JSONCause struct {
+	Summary    string   `json:"summary"`
+	Confidence string   `json:"confidence"`
+	Evidence   []string `json:"evidence,omitempty"`
+	Fix        string   `json:"fix,omitempty"`
+}
+
+// JSONHealth is the machine-readable form of diagnostic.Health.
+type JSONHealth struct {
+	Score   int    `json:"score"`
+	Verdict string `json:"verdict"`
+}
+
+// JSONSummary counts the results of a run by status.
+type JSONSummary struct {
+	// Status is the worst status among the checks that ran; skipped checks
+	// do not count.
+	Status  string `json:"status"`
+	Total   int    `json:"total"`
+	OK      int    `json:"ok"`
+	Warning int    `json:"warning"`
+	Error   int    `json:"error"`
+	Skipped int    `json:"skipped"`
+}
+
+// NewJSONRecord converts the results of one run into a JSONRecord.
+func NewJSONRecord(ts time.Time, results []diagnostic.Result) JSONRecord {
+	h := diagnostic.AssessHealth(results)
+	rec := JSONRecord{
+		Timestamp: ts,
+		Summary:   summarize(results),
+		Health:    JSONHealth{Score: h.Score, Verdict: h.Verdict},
+		Results:   make([]JSONResult, 0, len(results)),
+	}
+	for _, r := range results {
+		rec.Results = append(rec.Results, ToJSONResult(r))
+	}
+	for _, c := range diagnostic.RootCauses(results) {
+		rec.Causes = append(rec.Causes, JSONCause{Summary: c.Summary, Confidence: c.Confidence.String(), Evidence: c.Evidence, Fix: c.Fix})
+	}
+	return rec
+}
+
+func summarize(results []diagnostic.Result) JSONSummary {
+	s := JSONSummary{Total: len(results)}
+	worst := diagnostic.StatusOk
+	for _, r := range results {
+		switch r.Status {
+		case diagnostic.StatusOk:
+			s.OK++
+		case diagnostic.StatusWarning:
+			s.Warning++
+		case diagnostic.StatusError:
+			s.Error++
+		case diagnostic.StatusSkipped:
+			s.Skipped++
+			continue
+		}
+		worst = max(worst, r.Status)
+	}
+	s.Status = worst.String()
+	return s
+}
+
+// ToJSONResult converts a single result to its machine-readable form.
+func ToJSONResult(r diagnostic.Result) JSONResult {
+	jr := JSONResult{
+		Name:       r.Name,
+		Status:     r.Status.String(),
+		LatencyMs:  toMs(r.Latency),
+		Message:    r.Message,
+		Reason:     string(r.Reason),
+		Fix:        r.Fix,
+		FixCommand: r.FixCommand,
+		Facts:      r.Facts,
+	}
+	if p := r.Percentiles; p != nil {
+		jr.Percentiles = &JSONPercentiles{
+			Samples: p.Samples,
+			P50Ms:   toMs(p.P50),
+			P90Ms:   toMs(p.P90),
+			P99Ms:   toMs(p.P99),
+			MaxMs:   toMs(p.Max),
+		}
+	}
+	for _, d := range r.Details {
+		// Tree prefixes only make sense in the terminal.
+		d = strings.TrimPrefix(d, "├─ ")
+		d = strings.TrimPrefix(d, "└─ ")
+		jr.Details = append(jr.Details, d)
+	}
+	return jr
+}
+
+// CheckDescription is the machine-readable catalog entry of a check.
+type CheckDescription struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	OptIn       bool              `json:"opt_in,omitempty"`
+	Fields      []string          `json:"fields"`
+	Thresholds  map[string]string `json:"thresholds,omitempty"`
+	Reasons     []string          `json:"reasons"`
+}
+
+// DescribeChecks converts the check registry into its catalog form.
+func DescribeChecks(checks []diagnostic.Check) []CheckDescription {
+	out := make([]CheckDescription, 0, len(checks))
+	for _, c := range checks {
+		d := CheckDescription{
+			Name:        c.Name,
+			Description: c.Explain,
+			OptIn:       c.OptIn,
+			Fields:      c.Fields,
+			Thresholds:  c.Thresholds,
+			Reasons:     make([]string, 0, len(c.Reasons)),
+		}
+		for _, r := range c.Reasons {
+			d.Reasons = append(d.Reasons, string(r))
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// BaseName strips the parenthesized qualifier from a result name, such as
+// the SSID in "Wi-Fi (Home)" or the address in "Gateway (192.168.1.1)", so
+// results of the same check can be matched across networks.
+func BaseName(name string) string {
+	if i := strings.Index(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+		return name[:i]
+	}
+	return name
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteNDJSON writes rec as a single newline-terminated JSON line.
+// Encode issues exactly one Write, so an unbuffered writer such as os.Stdout
+// delivers each line to the consumer immediately.
+func WriteNDJSON(w io.Writer, rec JSONRecord) error {
+	return json.NewEncoder(w).Encode(rec)
+}
+
+// WriteRunEvent writes the summary of rec as the JSONRunEvent line that
+// ends a streamed run; its results went out as JSONCheckEvents already.
+func WriteRunEvent(w io.Writer, rec JSONRecord) error {
+	return json.NewEncoder(w).Encode(JSONRunEvent{
+		Type:      EventRun,
+		Timestamp: rec.Timestamp,
+		Summary:   rec.Summary,
+		Health:    rec.Health,
+		Causes:    rec.Causes,
+	})
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// markdownStatus labels a status in the Markdown table; the emoji keep the
+// column readable where tables render without color.
+var markdownStatus = map[string]string{
+	"ok":      "✅ OK",
+	"warning": "⚠️ Warning",
+	"error":   "❌ Error",
+	"skipped": "⏭️ Skipped",
+}
+
+// WriteMarkdown renders rec as a GitHub-flavored Markdown table with one row
+// per check, followed by a collapsible section with the details of every
+// check that has any, ready to paste into an issue or a wiki.
+func WriteMarkdown(w io.Writer, rec JSONRecord) error {
+	bw := bufio.NewWriter(w)
+	s := rec.Summary
+	fmt.Fprintf(bw, "### wtfi results (%s)\n\n", rec.Timestamp.Format(time.DateTime))
+	fmt.Fprintf(bw, "**Overall: %s** · %d OK, %d warning, %d error, %d skipped\n\n",
+		markdownStatus[s.Status], s.OK, s.Warning, s.Error, s.Skipped)
+	if h := rec.Health; h.Verdict != "" {
+		fmt.Fprintf(bw, "**Health: %d/100** · %s\n\n", h.Score, h.Verdict)
+	}
+	if len(rec.Causes) > 0 {
+		fmt.Fprintln(bw, "**Probable causes:**")
+		fmt.Fprintln(bw)
+		for i, c := range rec.Causes {
+			fmt.Fprintf(bw, "%d. %s (%s confidence)\n", i+1, c.Summary, c.Confidence)
+		}
+		fmt.Fprintln(bw)
+	}
+
+	fmt.Fprintln(bw, "| Check | Status | Latency | Info | Fix |")
+	fmt.Fprintln(bw, "|---|---|---:|---|---|")
+	for _, r := range rec.Results {
+		latency := ""
+		if r.LatencyMs > 0 {
+			latency = time.Duration(r.LatencyMs * float64(time.Millisecond)).Round(time.Millisecond).String()
+		}
+		fix := ""
+		if r.Status != "ok" {
+			fix = r.Fix
+			if r.FixCommand != "" {
+				fix = strings.TrimSpace(fix + " `" + strings.ReplaceAll(r.FixCommand, "`", "'") + "`")
+			}
+		}
+		status, ok := markdownStatus[r.Status]
+		if !ok {
+			status = r.Status
+		}
+		fmt.Fprintf(bw, "| %s | %s | %s | %s | %s |\n",
+			markdownCell(r.Name), status, latency, markdownCell(r.Message), markdownCell(fix))
+	}
+
+	for _, r := range rec.Results {
+		if len(r.Details) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n<details><summary>%s</summary>\n\n```\n", markdownCell(r.Name))
+		for _, d := range r.Details {
+			fmt.Fprintln(bw, strings.ReplaceAll(d, "```", "'''"))
+		}
+		fmt.Fprint(bw, "```\n\n</details>\n")
+	}
+	return bw.Flush()
+}
+
+// markdownCell makes s safe inside a table cell, where a pipe would start
+// a new column and a newline would end the row.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	rec := NewJSONRecord(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), []diagnostic.Result{
+		{Name: "Wi-Fi (a|b)", Check: "wifi", Status: diagnostic.StatusOk, Latency: 3 * time.Millisecond, Message: "Signal\nGood", Fix: "ignored when OK"},
+		{Name: "DNS", Check: "dns", Status: diagnostic.StatusWarning, Message: "Slow", Fix: "Flush the cache.", FixCommand: "sudo dscacheutil -flushcache",
+			Details: []string{"├─ Resolver: 1.1.1.1", "└─ Time: 250ms"}},
+	})
+	var b strings.Builder
+	if err := WriteMarkdown(&b, rec); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"**Overall: ⚠️ Warning** · 1 OK, 1 warning, 0 error, 0 skipped",
+		"**Health: 75/100** · Your Wi-Fi is fine; DNS needs attention",
+		"1. The DNS resolver is slow or failing while the connection works (medium confidence)",
+		`| Wi-Fi (a\|b) | ✅ OK | 3ms | Signal Good |  |`,
+		"| DNS | ⚠️ Warning |  | Slow | Flush the cache. `sudo dscacheutil -flushcache` |",
+		"<details><summary>DNS</summary>\n\n```\nResolver: 1.1.1.1\nTime: 250ms\n```\n\n</details>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<summary>Wi-Fi") {
+		t.Error("Expected no details section for a check without details")
+	}
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// OutputSink receives check results as they become available.
+type OutputSink interface {
+	Result(r diagnostic.Result)
+}
+
+// SinkFunc adapts a function to OutputSink.
+type SinkFunc func(r diagnostic.Result)
+
+// Result calls f(r).
+func (f SinkFunc) Result(r diagnostic.Result) { f(r) }
+
+// UnorderedSink is an OutputSink that takes results as the checks finish
+// rather than in display order, so a slow check holds none back.
+type UnorderedSink interface {
+	OutputSink
+	Unordered()
+}
+
+// OrderedSink forwards results to Next in index order, holding back only
+// those that arrive before a predecessor. It is not safe for concurrent use.
+type OrderedSink struct {
+	Next    OutputSink
+	pending map[int]diagnostic.Result
+	next    int
+}
+
+// Put delivers the result with index i, followed by any held-back results
+// it unblocks.
+func (o *OrderedSink) Put(i int, r diagnostic.Result) {
+	if o.pending == nil {
+		o.pending = make(map[int]diagnostic.Result)
+	}
+	o.pending[i] = r
+	for {
+		r, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		delete(o.pending, o.next)
+		o.next++
+		o.Next.Result(r)
+	}
+}
+
+// TextSink renders each result to the terminal as it arrives.
+type TextSink struct {
+	Options Options
+	// Explain holds each check's methodology in the order results arrive.
+	Explain []string
+	// Previous holds the results shown by the last watch refresh, in the
+	// same order, so changed values can be highlighted.
+	Previous []diagnostic.Result
+	// Shown collects the results as printed, to become the next Previous.
+	Shown []diagnostic.Result
+}
+
+// Result prints r and, with Options.Explain, its methodology.
+func (s *TextSink) Result(r diagnostic.Result) {
+	n := len(s.Shown)
+	opts := s.Options
+	if n < len(s.Previous) {
+		opts.Previous = &s.Previous[n]
+	}
+	if opts.Samples != nil {
+		opts.Graph = opts.Samples.Add(r)
+	}
+	PrintResult(r, opts)
+	if opts.Explain && n < len(s.Explain) {
+		PrintExplanation(s.Explain[n])
+	}
+	s.Shown = append(s.Shown, r)
+}
+
+// BufferSink keeps every result, for outputs such as JSON that are written
+// once the whole set is known.
+type BufferSink struct {
+	Results []diagnostic.Result
+}
+
+// Result appends r.
+func (s *BufferSink) Result(r diagnostic.Result) {
+	s.Results = append(s.Results, r)
+}
+
+// NDJSONSink writes each result to W as a JSONCheckEvent line the moment it
+// arrives; WriteRunEvent ends the run. It takes results in completion order.
+type NDJSONSink struct {
+	W io.Writer
+}
+
+// Result writes r as one line. Encode issues a single Write, so the line
+// reaches a pipe whole and at once.
+func (s *NDJSONSink) Result(r diagnostic.Result) {
+	ev := JSONCheckEvent{Type: EventCheck, Timestamp: time.Now(), Check: r.Check, JSONResult: ToJSONResult(r)}
+	if err := json.NewEncoder(s.W).Encode(ev); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// Unordered marks NDJSONSink as an UnorderedSink.
+func (s *NDJSONSink) Unordered() {}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestOrderedSink(t *testing.T) {
+	var buf BufferSink
+	o := OrderedSink{Next: &buf}
+
+	o.Put(2, diagnostic.Result{Name: "c"})
+	o.Put(1, diagnostic.Result{Name: "b"})
+	if len(buf.Results) != 0 {
+		t.Fatalf("Expected results to wait for index 0, got %v", buf.Results)
+	}
+	o.Put(0, diagnostic.Result{Name: "a"})
+	o.Put(3, diagnostic.Result{Name: "d"})
+
+	var got string
+	for _, r := range buf.Results {
+		got += r.Name
+	}
+	if got != "abcd" {
+		t.Errorf("Expected results in index order, got %q", got)
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	var out bytes.Buffer
+	s := NDJSONSink{W: &out}
+	s.Result(diagnostic.Result{Check: "dns", Name: "DNS", Status: diagnostic.StatusWarning, Message: "slow"})
+	s.Result(diagnostic.Result{Check: "wifi", Name: "Wi-Fi", Status: diagnostic.StatusOk})
+	rec := NewJSONRecord(time.Now(), []diagnostic.Result{{Check: "wifi", Status: diagnostic.StatusOk}})
+	if err := WriteRunEvent(&out, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected one line per check plus the run, got %q", out.String())
+	}
+	var ev JSONCheckEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != EventCheck || ev.Check != "dns" || ev.Status != "warning" || ev.Message != "slow" || ev.Timestamp.IsZero() {
+		t.Errorf("Unexpected check event %+v", ev)
+	}
+	var run JSONRunEvent
+	if err := json.Unmarshal([]byte(lines[2]), &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Type != EventRun || run.Summary.Total != 1 {
+		t.Errorf("Unexpected run event %+v", run)
+	}
+}
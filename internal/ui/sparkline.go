@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a row of block characters, or ASCII ones in
+// ASCII mode, scaled between their minimum and maximum. A flat series
+// renders at the lowest level.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	blocks := sparkBlocks
+	if ASCII {
+		blocks = asciiBlocks
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) / (hi - lo) * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[idx])
+	}
+	return b.String()
+}
+
+// SparkSamples is how many recent values a sparkline next to a result shows.
+const SparkSamples = 20
+
+// Samples keeps the recent values of each check's key metric, the RSSI for
+// Wi-Fi and the latency otherwise, for the sparklines next to results.
+// Checks are keyed by BaseName, so a Wi-Fi series survives a change of SSID.
+type Samples struct {
+	series map[string][]float64
+}
+
+// Add appends the key metric of r and returns the check's recent values,
+// or nil when r measured none.
+func (s *Samples) Add(r diagnostic.Result) []float64 {
+	if v, err := strconv.Atoi(r.Facts[diagnostic.FactRSSI]); err == nil {
+		return s.add(r.Name, float64(v))
+	}
+	if r.Latency > 0 {
+		return s.add(r.Name, toMs(r.Latency))
+	}
+	return nil
+}
+
+// AddRecord appends the key metrics of a recorded run, to start the
+// sparklines from the history.
+func (s *Samples) AddRecord(rec JSONRecord) {
+	for _, r := range rec.Results {
+		if v, err := strconv.Atoi(r.Facts[diagnostic.FactRSSI]); err == nil {
+			s.add(r.Name, float64(v))
+		} else if r.LatencyMs > 0 {
+			s.add(r.Name, r.LatencyMs)
+		}
+	}
+}
+
+func (s *Samples) add(name string, v float64) []float64 {
+	if s.series == nil {
+		s.series = map[string][]float64{}
+	}
+	key := BaseName(name)
+	series := append(s.series[key], v)
+	if len(series) > SparkSamples {
+		series = series[len(series)-SparkSamples:]
+	}
+	s.series[key] = series
+	return series
+}
@@ -3,19 +3,20 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/kanywst/wtfi/internal/diagnostic"
+	"github.com/kanywst/wtfi/internal/watch"
 
 	"github.com/fatih/color"
 )
 
 // PrintHeader prints the diagnostic start header.
 func PrintHeader() {
-	if _, err := color.New(color.Bold, color.FgCyan).Println("🚀 wtfi: Starting Network Diagnostics..."); err != nil {
-		log.Printf("UI Error: %v", err)
+	if _, err := color.New(color.Bold, color.FgCyan).Println(Text("🚀 wtfi: Starting Network Diagnostics...")); err != nil {
+		slog.Error("could not write output", "err", err)
 	}
 	fmt.Println(strings.Repeat("-", 50))
 }
@@ -30,57 +31,316 @@ func ClearScreen() {
 	fmt.Print("\033[H\033[2J")
 }
 
+// Options controls how results are rendered.
+type Options struct {
+	// Verbose shows protocol details.
+	Verbose bool
+	// FixCommands shows runnable fix commands below the prose Fix.
+	FixCommands bool
+	// Explain shows each check's methodology below its result.
+	Explain bool
+	// CheckEnv shows the Environment warnings below the header.
+	CheckEnv    bool
+	Environment []string
+	// LatencyGood and LatencyPoor bound the green, yellow and red latency
+	// tiers; zero uses DefaultLatencyGood and DefaultLatencyPoor.
+	LatencyGood time.Duration
+	LatencyPoor time.Duration
+	// Previous is the same check's result from the last watch refresh;
+	// values that changed since are highlighted.
+	Previous *diagnostic.Result
+	// Samples collects each check's recent values as results are shown;
+	// Graph holds the current check's, drawn as a sparkline after its value.
+	Samples *Samples
+	Graph   []float64
+}
+
+// change marks which displayed values of a result differ from the last run.
+type change struct {
+	Status, Latency, Message bool
+}
+
+// resultChanges compares cur with prev. Latency only counts as changed when
+// it moved by at least 5ms and 20%, so ordinary jitter is not highlighted.
+func resultChanges(prev, cur diagnostic.Result) change {
+	delta := cur.Latency - prev.Latency
+	if delta < 0 {
+		delta = -delta
+	}
+	return change{
+		Status:  cur.Status != prev.Status,
+		Latency: delta >= 5*time.Millisecond && delta*5 >= prev.Latency,
+		Message: cur.Message != prev.Message,
+	}
+}
+
+// Default latency tiers: under 20ms is green, over 100ms is red.
+const (
+	DefaultLatencyGood = 20 * time.Millisecond
+	DefaultLatencyPoor = 100 * time.Millisecond
+)
+
+// latencyColor picks the color of a latency value by its tier, independently
+// of the result's Status. fatih/color drops the attributes when color is
+// disabled, so the value then prints plain.
+func latencyColor(d time.Duration, opts *Options) *color.Color {
+	good, poor := DefaultLatencyGood, DefaultLatencyPoor
+	if opts != nil && opts.LatencyGood > 0 {
+		good = opts.LatencyGood
+	}
+	if opts != nil && opts.LatencyPoor > 0 {
+		poor = opts.LatencyPoor
+	}
+	switch {
+	case d < good:
+		return color.New(color.FgGreen)
+	case d <= poor:
+		return color.New(color.FgYellow)
+	}
+	return color.New(color.FgRed)
+}
+
 // PrintResult displays the diagnostic outcome of a single step.
-func PrintResult(r diagnostic.Result, verbose bool) {
+func PrintResult(r diagnostic.Result, opts Options) {
 	c := color.New(color.FgGreen)
 	switch r.Status {
 	case diagnostic.StatusWarning:
 		c = color.New(color.FgYellow)
 	case diagnostic.StatusError:
 		c = color.New(color.FgRed)
+	case diagnostic.StatusSkipped:
+		c = color.New(color.FgHiBlack)
 	case diagnostic.StatusOk:
 		// Default green
 	}
 
-	fmt.Printf("%s %-25s", r.Emoji, r.Name)
-	if r.Status != diagnostic.StatusError {
-		latencyStr := ""
+	var ch change
+	if opts.Previous != nil {
+		ch = resultChanges(*opts.Previous, r)
+	}
+
+	emoji := r.Emoji
+	if ASCII {
+		emoji = statusMark(r.Status)
+	}
+	if ch.Status {
+		if _, err := color.New(color.Bold, color.Underline).Printf("%s %-25s", emoji, Text(r.Name)); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	} else {
+		fmt.Printf("%s %-25s", emoji, Text(r.Name))
+	}
+	switch r.Status {
+	case diagnostic.StatusError:
+		if _, err := c.Printf("%22s", "ERROR"); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	case diagnostic.StatusSkipped:
+		if _, err := c.Printf("%22s", "SKIPPED"); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	default:
+		latencyStr := "OK"
 		if r.Latency > 0 {
 			latencyStr = r.Latency.Round(time.Millisecond).String()
-		} else {
-			latencyStr = "OK"
+			c = latencyColor(r.Latency, &opts)
+			if ch.Latency {
+				arrow := "▼ "
+				if r.Latency > opts.Previous.Latency {
+					arrow = "▲ "
+				}
+				latencyStr = Text(arrow) + latencyStr
+				c.Add(color.Bold)
+			}
 		}
-		if _, err := c.Printf("%22s\n", latencyStr); err != nil {
-			log.Printf("UI Error: %v", err)
+		if _, err := c.Printf("%22s", latencyStr); err != nil {
+			slog.Error("could not write output", "err", err)
 		}
-	} else {
-		if _, err := c.Printf("%22s\n", "ERROR"); err != nil {
-			log.Printf("UI Error: %v", err)
+	}
+	if len(opts.Graph) > 1 {
+		if _, err := color.New(color.FgHiBlack).Printf("  %s", Sparkline(opts.Graph)); err != nil {
+			slog.Error("could not write output", "err", err)
 		}
 	}
+	fmt.Println()
 
 	if r.Message != "" {
 		msgColor := color.New(color.FgWhite).Add(color.Faint)
+		if ch.Message {
+			msgColor = color.New(color.FgHiWhite, color.Bold)
+		}
 		prefix := "├─"
 		if len(r.Details) == 0 && r.Fix == "" {
 			prefix = "└─"
 		}
-		if _, err := msgColor.Printf("   %s Info: %s\n", prefix, r.Message); err != nil {
-			log.Printf("UI Error: %v", err)
+		if _, err := msgColor.Printf(Text("   %s Info: %s\n"), Text(prefix), Text(r.Message)); err != nil {
+			slog.Error("could not write output", "err", err)
 		}
 	}
 
 	if len(r.Details) > 0 {
 		for _, detail := range r.Details {
-			if _, err := color.New(color.FgHiBlack).Printf("   %s\n", detail); err != nil {
-				log.Printf("UI Error: %v", err)
+			if _, err := color.New(color.FgHiBlack).Printf("   %s\n", Text(detail)); err != nil {
+				slog.Error("could not write output", "err", err)
 			}
 		}
 	}
 
+	showCommand := opts.FixCommands && r.Status != diagnostic.StatusOk && r.FixCommand != ""
 	if r.Status != diagnostic.StatusOk && r.Fix != "" {
-		if _, err := color.New(color.FgHiBlue).Printf("   └─ Fix:  %s\n", r.Fix); err != nil {
-			log.Printf("UI Error: %v", err)
+		prefix := "└─"
+		if showCommand {
+			prefix = "├─"
+		}
+		if _, err := color.New(color.FgHiBlue).Printf("   %s Fix:  %s\n", Text(prefix), Text(r.Fix)); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	}
+	if showCommand {
+		if _, err := color.New(color.FgHiCyan, color.Bold).Printf(Text("   └─ Run:  $ %s\n"), r.FixCommand); err != nil {
+			slog.Error("could not write output", "err", err)
 		}
 	}
 }
+
+// PrintHealth prints the health score and verdict of a run, colored like
+// the status it amounts to.
+func PrintHealth(h diagnostic.Health) {
+	c := color.New(color.Bold, color.FgGreen)
+	switch {
+	case h.Score < 50:
+		c = color.New(color.Bold, color.FgRed)
+	case h.Score < 90:
+		c = color.New(color.Bold, color.FgYellow)
+	}
+	if _, err := c.Printf(Text("🩺 Health %d/100: %s\n"), h.Score, h.Verdict); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintCauses lists the probable root causes of a run with their
+// confidence, the evidence behind each and what to do about it.
+func PrintCauses(causes []diagnostic.Cause) {
+	if len(causes) == 0 {
+		return
+	}
+	if _, err := color.New(color.Bold).Println(Text("🔎 Probable causes")); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	dim := color.New(color.FgHiBlack)
+	for i, c := range causes {
+		fmt.Printf("   %d. %s (%s confidence)\n", i+1, Text(c.Summary), c.Confidence)
+		for _, e := range c.Evidence {
+			if _, err := dim.Printf(Text("      · %s\n"), Text(e)); err != nil {
+				slog.Error("could not write output", "err", err)
+			}
+		}
+		if c.Fix != "" {
+			fmt.Printf("      Fix: %s\n", Text(c.Fix))
+		}
+	}
+}
+
+// PrintRemedy presents step n of total of the guided fix: what it does, the
+// result that calls for it and the commands it runs.
+func PrintRemedy(n, total int, r diagnostic.Remedy) {
+	if _, err := color.New(color.Bold).Printf(Text("🛠️  Fix %d/%d: %s\n"), n, total, Text(r.Title)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	if r.Why != "" {
+		if _, err := color.New(color.FgHiBlack).Printf("   Because %s\n", Text(r.Why)); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	}
+	if _, err := color.New(color.FgHiCyan, color.Bold).Printf("   $ %s\n", r.Shell()); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintRemedyOutcome reports whether the checks re-run after a remedy
+// passed.
+func PrintRemedyOutcome(fixed bool) {
+	c, msg := color.New(color.Bold, color.FgGreen), "✅ Fixed: the checks pass now"
+	if !fixed {
+		c, msg = color.New(color.Bold, color.FgYellow), "⚠️  Not fixed: the checks still report a problem"
+	}
+	if _, err := c.Println(Text(msg)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintExplanation prints a check's methodology below its result.
+func PrintExplanation(text string) {
+	if text == "" {
+		return
+	}
+	if _, err := color.New(color.FgHiBlack, color.Italic).Printf(Text("   ℹ️  How: %s\n"), Text(text)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintEnvironment lists the problems found by diagnostic.ProbeEnvironment.
+func PrintEnvironment(warnings []string) {
+	if len(warnings) == 0 {
+		if _, err := color.New(color.FgGreen).Println(Text("🧰 Environment: all required tools found")); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+		return
+	}
+	if _, err := color.New(color.Bold).Println(Text("🧰 Environment")); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	hl := color.New(color.FgYellow)
+	for _, w := range warnings {
+		if _, err := hl.Printf(Text("   ⚠️  %s\n"), Text(w)); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	}
+}
+
+// PrintWake notes that watch mode resumed after the machine slept for gap.
+func PrintWake(gap time.Duration) {
+	if gap <= 0 {
+		return
+	}
+	if _, err := color.New(color.FgHiBlack).Printf(Text("💤 Resumed after %s asleep; network state was rediscovered\n"), gap.Round(time.Second)); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintNetworkChange notes that watch mode re-ran early because the
+// network changed from one described by from to one described by to.
+func PrintNetworkChange(from, to string) {
+	if from == "" {
+		return
+	}
+	if _, err := color.New(color.FgHiBlack).Printf(Text("🔀 Network changed from %s to %s; re-ran the checks\n"), from, to); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
+
+// PrintRoamEvents prints the access point transitions seen during watch mode.
+func PrintRoamEvents(events []watch.RoamEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if _, err := color.New(color.Bold).Println(Text("📶 Roaming Log")); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+	hl := color.New(color.FgHiMagenta)
+	for _, ev := range events {
+		if _, err := hl.Printf(Text("   %s  %s → %s (%d dBm)\n"), ev.At.Format(time.TimeOnly), ev.From, ev.To, ev.RSSI); err != nil {
+			slog.Error("could not write output", "err", err)
+		}
+	}
+}
+
+// PrintBaselineMissing lists baseline checks that did not run this time.
+func PrintBaselineMissing(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	if _, err := color.New(color.FgHiBlack).Printf(Text("📏 In baseline but not in this run: %s\n"), strings.Join(names, ", ")); err != nil {
+		slog.Error("could not write output", "err", err)
+	}
+}
@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+
+	"github.com/fatih/color"
+)
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline(nil); got != "" {
+		t.Errorf("Expected empty sparkline, got %q", got)
+	}
+	if got := Sparkline([]float64{5, 5, 5}); got != "▁▁▁" {
+		t.Errorf("Expected flat sparkline, got %q", got)
+	}
+	if got := Sparkline([]float64{0, 7, 14}); got != "▁▄█" {
+		t.Errorf("Expected rising sparkline, got %q", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	if got := Text("🚀 wtfi"); got != "🚀 wtfi" {
+		t.Errorf("Expected text unchanged outside ASCII mode, got %q", got)
+	}
+	ASCII = true
+	t.Cleanup(func() { ASCII = false })
+	tests := []struct {
+		in, want string
+	}{
+		{"🚀 wtfi: Starting Network Diagnostics...", "wtfi: Starting Network Diagnostics..."},
+		{"🛠️  Fix 1/2: Renew the lease", "Fix 1/2: Renew the lease"},
+		{"   ⚠️  ping not found", "   [!]  ping not found"},
+		{"├─ AS7922 → AS13335", "|- AS7922 -> AS13335"},
+		{"↓ 94.1 Mbps / ↑ 11.8 Mbps", "down 94.1 Mbps / up 11.8 Mbps"},
+		{"Café 東京 📶", "Café 東京 "},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := Sparkline([]float64{0, 7, 14}); got != "_-#" {
+		t.Errorf("Expected an ASCII sparkline, got %q", got)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	var s Samples
+	s.AddRecord(JSONRecord{Results: []JSONResult{
+		{Name: "Wi-Fi (Home)", Facts: map[string]string{diagnostic.FactRSSI: "-60"}},
+		{Name: "DNS", LatencyMs: 12},
+	}})
+	if got := s.Add(diagnostic.Result{Name: "Wi-Fi (Cafe)", Facts: map[string]string{diagnostic.FactRSSI: "-70"}}); len(got) != 2 || got[0] != -60 || got[1] != -70 {
+		t.Errorf("Expected the Wi-Fi series to carry over the SSID change, got %v", got)
+	}
+	if got := s.Add(diagnostic.Result{Name: "DNS", Latency: 20 * time.Millisecond}); len(got) != 2 || got[1] != 20 {
+		t.Errorf("Expected the DNS latency series, got %v", got)
+	}
+	if got := s.Add(diagnostic.Result{Name: "Proxy"}); got != nil {
+		t.Errorf("Expected no series without a measurement, got %v", got)
+	}
+	for range 2 * SparkSamples {
+		s.Add(diagnostic.Result{Name: "DNS", Latency: time.Millisecond})
+	}
+	if got := s.Add(diagnostic.Result{Name: "DNS", Latency: time.Millisecond}); len(got) != SparkSamples {
+		t.Errorf("Expected the series capped at %d values, got %d", SparkSamples, len(got))
+	}
+}
+
+func TestLatencyColor(t *testing.T) {
+	green, yellow, red := color.New(color.FgGreen), color.New(color.FgYellow), color.New(color.FgRed)
+	tests := []struct {
+		d    time.Duration
+		opts *Options
+		want *color.Color
+	}{
+		{2 * time.Millisecond, nil, green},
+		{19 * time.Millisecond, nil, green},
+		{20 * time.Millisecond, nil, yellow},
+		{100 * time.Millisecond, nil, yellow},
+		{101 * time.Millisecond, nil, red},
+		{30 * time.Millisecond, &Options{LatencyGood: 50 * time.Millisecond}, green},
+		{60 * time.Millisecond, &Options{LatencyPoor: 50 * time.Millisecond}, red},
+	}
+	for _, tt := range tests {
+		if got := latencyColor(tt.d, tt.opts); !got.Equals(tt.want) {
+			t.Errorf("latencyColor(%v, %+v): unexpected tier", tt.d, tt.opts)
+		}
+	}
+}
+
+func TestNewJSONRecordSummary(t *testing.T) {
+	rec := NewJSONRecord(time.Now(), []diagnostic.Result{
+		{Status: diagnostic.StatusOk},
+		{Status: diagnostic.StatusWarning},
+		{Status: diagnostic.StatusSkipped},
+		{Status: diagnostic.StatusOk},
+	})
+	want := JSONSummary{Status: "warning", Total: 4, OK: 2, Warning: 1, Skipped: 1}
+	if rec.Summary != want {
+		t.Errorf("Expected %+v, got %+v", want, rec.Summary)
+	}
+	if got := NewJSONRecord(time.Now(), []diagnostic.Result{{Status: diagnostic.StatusSkipped}}).Summary.Status; got != "ok" {
+		t.Errorf("Expected skipped checks not to degrade the summary, got %s", got)
+	}
+}
+
+func TestResultChanges(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		prev, cur diagnostic.Result
+		want      change
+	}{
+		{"unchanged", diagnostic.Result{Latency: 20 * ms, Message: "a"}, diagnostic.Result{Latency: 22 * ms, Message: "a"}, change{}},
+		{"latency jump", diagnostic.Result{Latency: 20 * ms}, diagnostic.Result{Latency: 40 * ms}, change{Latency: true}},
+		{"small relative move", diagnostic.Result{Latency: 200 * ms}, diagnostic.Result{Latency: 220 * ms}, change{}},
+		{"tiny absolute move", diagnostic.Result{Latency: 2 * ms}, diagnostic.Result{Latency: 4 * ms}, change{}},
+		{"status", diagnostic.Result{Status: diagnostic.StatusOk}, diagnostic.Result{Status: diagnostic.StatusWarning}, change{Status: true}},
+		{"message", diagnostic.Result{Message: "Signal: -50 dBm"}, diagnostic.Result{Message: "Signal: -71 dBm"}, change{Message: true}},
+	}
+	for _, tt := range tests {
+		if got := resultChanges(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestToJSONResult(t *testing.T) {
+	jr := ToJSONResult(diagnostic.Result{
+		Name:    "DNS Benchmark",
+		Latency: 250 * time.Millisecond,
+		Status:  diagnostic.StatusWarning,
+		Message: "High DNS latency detected",
+		Reason:  diagnostic.ReasonDNSSlow,
+		Details: []string{"├─ System", "└─ Google"},
+	})
+	if jr.Status != "warning" || jr.Reason != "dns_slow" || jr.LatencyMs != 250 {
+		t.Errorf("Unexpected conversion %+v", jr)
+	}
+	if len(jr.Details) != 2 || jr.Details[0] != "System" || jr.Details[1] != "Google" {
+		t.Errorf("Expected tree prefixes to be stripped, got %v", jr.Details)
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	tests := map[string]string{
+		"Wi-Fi (Home)":         "Wi-Fi",
+		"Gateway (10.0.0.1)":   "Gateway",
+		"DNS Benchmark":        "DNS Benchmark",
+		"Routing Table & VPNs": "Routing Table & VPNs",
+	}
+	for in, want := range tests {
+		if got := BaseName(in); got != want {
+			t.Errorf("BaseName(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
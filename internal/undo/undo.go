@@ -0,0 +1,82 @@
+// Package undo records how to revert the changes the guided fix makes to
+// the system, so "wtfi fix --undo" can restore the previous settings.
+package undo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry reverts one applied fix.
+type Entry struct {
+	At time.Time `json:"at"`
+	// Title names the fix being reverted.
+	Title string `json:"title"`
+	// Commands restore the previous settings, in order.
+	Commands   [][]string `json:"commands"`
+	Privileged bool       `json:"privileged,omitempty"`
+}
+
+// DefaultPath returns $XDG_DATA_HOME/wtfi/undo.json, which is usually
+// ~/.local/share/wtfi/undo.json.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "wtfi", "undo.json")
+}
+
+// Load returns the entries recorded at path, oldest first; a missing file
+// has none.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Push records e after the entries already at path, creating the directory
+// if needed.
+func Push(path string, e Entry) error {
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	return Save(path, append(entries, e))
+}
+
+// Save replaces the entries at path; with none left the file is removed.
+func Save(path string, entries []Entry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
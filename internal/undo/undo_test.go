@@ -0,0 +1,49 @@
+package undo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPushLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtfi", "undo.json")
+	if entries, err := Load(path); err != nil || entries != nil {
+		t.Fatalf("Expected no entries for a missing file, got %v (%v)", entries, err)
+	}
+	first := Entry{At: time.Unix(1, 0).UTC(), Title: "Switch DNS", Commands: [][]string{{"networksetup", "-setdnsservers", "Wi-Fi", "Empty"}}, Privileged: true}
+	second := Entry{At: time.Unix(2, 0).UTC(), Title: "Reorder services", Commands: [][]string{{"networksetup", "-ordernetworkservices", "Wi-Fi"}}}
+	for _, e := range []Entry{first, second} {
+		if err := Push(path, e); err != nil {
+			t.Fatalf("Push: %v", err)
+		}
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Title != first.Title || !entries[0].Privileged || entries[1].Commands[0][2] != "Wi-Fi" {
+		t.Errorf("Expected both entries in order, got %+v", entries)
+	}
+
+	if err := Save(path, nil); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be removed, got %v", err)
+	}
+	if err := Save(path, nil); err != nil {
+		t.Errorf("Expected clearing a missing file to succeed, got %v", err)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "undo.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for a corrupt file")
+	}
+}
@@ -0,0 +1,76 @@
+package watch
+
+import "time"
+
+// Alert reports a check degrading from OK, or recovering.
+type Alert struct {
+	At    time.Time
+	Check string
+	// Failing is true for an error, false for a warning.
+	Failing bool
+	// Recovered is true when the check is OK again after an alert.
+	Recovered bool
+	// Since is when the check stopped being OK.
+	Since time.Time
+}
+
+// alertState is what AlertTracker remembers per check.
+type alertState struct {
+	healthy bool
+	// badSince is when the current unhealthy spell began; zero when it
+	// began before the check was first seen, which never alerts.
+	badSince  time.Time
+	alerted   bool
+	lastAlert time.Time
+}
+
+// AlertTracker turns a stream of check outcomes into edge-triggered alerts:
+// it fires when a check goes from healthy to unhealthy and stays so for
+// Hold, at most once per Debounce per check, so a flapping check does not
+// spam. With Recoveries, it fires again when a check it alerted about is
+// healthy again.
+type AlertTracker struct {
+	Debounce   time.Duration
+	Hold       time.Duration
+	Recoveries bool
+
+	checks map[string]*alertState
+}
+
+// Observe feeds the outcome of check at time at. healthy is false for a
+// warning or error, and failing distinguishes the two. Skipped checks should
+// not be fed at all.
+func (t *AlertTracker) Observe(at time.Time, check string, healthy, failing bool) (Alert, bool) {
+	if t.checks == nil {
+		t.checks = map[string]*alertState{}
+	}
+	st, seen := t.checks[check]
+	if !seen {
+		// The first observation only establishes the starting state.
+		t.checks[check] = &alertState{healthy: healthy}
+		return Alert{}, false
+	}
+
+	wasHealthy := st.healthy
+	st.healthy = healthy
+	if healthy {
+		recovered := !wasHealthy && st.alerted
+		since := st.badSince
+		st.badSince, st.alerted = time.Time{}, false
+		if recovered && t.Recoveries {
+			return Alert{At: at, Check: check, Recovered: true, Since: since}, true
+		}
+		return Alert{}, false
+	}
+	if wasHealthy {
+		st.badSince = at
+	}
+	if st.alerted || st.badSince.IsZero() || at.Sub(st.badSince) < t.Hold {
+		return Alert{}, false
+	}
+	if !st.lastAlert.IsZero() && at.Sub(st.lastAlert) < t.Debounce {
+		return Alert{}, false
+	}
+	st.alerted, st.lastAlert = true, at
+	return Alert{At: at, Check: check, Failing: failing, Since: st.badSince}, true
+}
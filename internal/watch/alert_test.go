@@ -0,0 +1,81 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertTracker(t *testing.T) {
+	tr := AlertTracker{Debounce: time.Minute}
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+
+	steps := []struct {
+		sec     int
+		healthy bool
+		alert   bool
+	}{
+		{0, true, false},   // baseline
+		{2, true, false},   // still fine
+		{4, false, true},   // OK → Warning fires
+		{6, false, false},  // still bad: edge-triggered, no repeat
+		{8, true, false},   // recovered
+		{10, false, false}, // flapped within the debounce window
+		{12, true, false},
+		{70, false, true}, // debounce elapsed
+	}
+	for _, s := range steps {
+		_, fired := tr.Observe(at(s.sec), "gateway", s.healthy, !s.healthy)
+		if fired != s.alert {
+			t.Errorf("t=%ds healthy=%v: expected alert=%v, got %v", s.sec, s.healthy, s.alert, fired)
+		}
+	}
+}
+
+func TestAlertTrackerStartsUnhealthy(t *testing.T) {
+	var tr AlertTracker
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if _, fired := tr.Observe(t0, "dns", false, false); fired {
+		t.Error("Expected no alert for a check that was never OK")
+	}
+	if _, fired := tr.Observe(t0.Add(time.Second), "dns", false, false); fired {
+		t.Error("Expected no alert while still unhealthy")
+	}
+	if _, fired := tr.Observe(t0.Add(2*time.Second), "wan", true, false); fired {
+		t.Error("Expected checks to be tracked independently")
+	}
+	if a, fired := tr.Observe(t0.Add(3*time.Second), "wan", false, true); !fired || a.Check != "wan" || !a.Failing {
+		t.Errorf("Expected a failing alert for wan, got %+v, %v", a, fired)
+	}
+}
+
+func TestAlertTrackerHoldAndRecovery(t *testing.T) {
+	tr := AlertTracker{Debounce: time.Minute, Hold: 10 * time.Second, Recoveries: true}
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+
+	steps := []struct {
+		sec       int
+		healthy   bool
+		alert     bool
+		recovered bool
+	}{
+		{0, true, false, false},
+		{2, false, false, false},  // not held long enough yet
+		{4, true, false, false},   // blip: neither alert nor recovery
+		{6, false, false, false},  // a new spell starts
+		{16, false, true, false},  // held for 10s
+		{18, false, false, false}, // already alerted
+		{200, true, true, true},   // recovery after the alert
+		{202, true, false, false},
+	}
+	for _, s := range steps {
+		a, fired := tr.Observe(at(s.sec), "wan", s.healthy, !s.healthy)
+		if fired != s.alert || a.Recovered != s.recovered {
+			t.Errorf("t=%ds healthy=%v: expected alert=%v recovered=%v, got %+v, %v", s.sec, s.healthy, s.alert, s.recovered, a, fired)
+		}
+		if fired && !a.Since.Equal(at(6)) {
+			t.Errorf("t=%ds: expected the spell to date from t=6s, got %v", s.sec, a.Since)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package watch
+
+import "time"
+
+// MACChange records the gateway answering from a different MAC address.
+type MACChange struct {
+	At       time.Time
+	Old      string
+	OldSince time.Time
+	New      string
+}
+
+// MACTracker detects changes of the gateway MAC between refreshes.
+type MACTracker struct {
+	current string
+	since   time.Time
+}
+
+// Observe feeds the gateway MAC seen at time at and reports a change.
+// Empty MACs (unresolved ARP entries) are ignored.
+func (t *MACTracker) Observe(at time.Time, mac string) (MACChange, bool) {
+	if mac == "" || mac == t.current {
+		return MACChange{}, false
+	}
+	prev, prevSince := t.current, t.since
+	t.current, t.since = mac, at
+	if prev == "" {
+		return MACChange{}, false
+	}
+	return MACChange{At: at, Old: prev, OldSince: prevSince, New: mac}, true
+}
@@ -0,0 +1,29 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMACTracker(t *testing.T) {
+	var tr MACTracker
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := tr.Observe(t0, "00:1a:2b:3c:4d:5e"); ok {
+		t.Fatal("Expected first observation not to be a change")
+	}
+	if _, ok := tr.Observe(t0.Add(time.Minute), ""); ok {
+		t.Fatal("Expected unresolved MAC to be ignored")
+	}
+	if _, ok := tr.Observe(t0.Add(2*time.Minute), "00:1a:2b:3c:4d:5e"); ok {
+		t.Fatal("Expected no change for the same MAC")
+	}
+
+	ch, ok := tr.Observe(t0.Add(3*time.Minute), "de:ad:be:ef:00:01")
+	if !ok {
+		t.Fatal("Expected a MAC change")
+	}
+	if ch.Old != "00:1a:2b:3c:4d:5e" || ch.New != "de:ad:be:ef:00:01" || !ch.OldSince.Equal(t0) {
+		t.Errorf("Unexpected change %+v", ch)
+	}
+}
@@ -0,0 +1,40 @@
+// Package watch holds the state that watch mode carries between refreshes.
+package watch
+
+import "time"
+
+// RoamEvent records the Mac moving from one access point to another.
+type RoamEvent struct {
+	At   time.Time
+	From string
+	To   string
+	RSSI int
+}
+
+// RoamTracker detects BSSID transitions across successive observations.
+type RoamTracker struct {
+	current string
+	history []RoamEvent
+}
+
+// Observe feeds the BSSID seen at time at and reports a transition if it changed.
+// Empty BSSIDs (disconnected or redacted) are ignored so that a brief dropout
+// followed by a reconnect to the same AP does not count as roaming.
+func (t *RoamTracker) Observe(at time.Time, bssid string, rssi int) (RoamEvent, bool) {
+	if bssid == "" || bssid == t.current {
+		return RoamEvent{}, false
+	}
+	prev := t.current
+	t.current = bssid
+	if prev == "" {
+		return RoamEvent{}, false
+	}
+	ev := RoamEvent{At: at, From: prev, To: bssid, RSSI: rssi}
+	t.history = append(t.history, ev)
+	return ev, true
+}
+
+// History returns every transition observed so far, oldest first.
+func (t *RoamTracker) History() []RoamEvent {
+	return append([]RoamEvent(nil), t.history...)
+}
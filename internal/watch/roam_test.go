@@ -0,0 +1,36 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoamTracker(t *testing.T) {
+	var tr RoamTracker
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := tr.Observe(t0, "aa:aa:aa:aa:aa:01", -50); ok {
+		t.Fatal("Expected first observation to establish a baseline, not an event")
+	}
+	if _, ok := tr.Observe(t0.Add(2*time.Second), "aa:aa:aa:aa:aa:01", -55); ok {
+		t.Fatal("Expected no event for the same BSSID")
+	}
+	if _, ok := tr.Observe(t0.Add(4*time.Second), "", 0); ok {
+		t.Fatal("Expected no event for an empty BSSID")
+	}
+
+	ev, ok := tr.Observe(t0.Add(6*time.Second), "aa:aa:aa:aa:aa:02", -71)
+	if !ok {
+		t.Fatal("Expected a roaming event")
+	}
+	if ev.From != "aa:aa:aa:aa:aa:01" || ev.To != "aa:aa:aa:aa:aa:02" || ev.RSSI != -71 {
+		t.Errorf("Unexpected event %+v", ev)
+	}
+
+	if _, ok := tr.Observe(t0.Add(8*time.Second), "aa:aa:aa:aa:aa:01", -48); !ok {
+		t.Fatal("Expected roaming back to be reported")
+	}
+	if h := tr.History(); len(h) != 2 {
+		t.Errorf("Expected 2 transitions in history, got %d", len(h))
+	}
+}
@@ -0,0 +1,43 @@
+package watch
+
+import "time"
+
+// minSleepGap is the smallest gap treated as sleep, so that a slow run or a
+// scheduler hiccup is not mistaken for one.
+const minSleepGap = 30 * time.Second
+
+// SleepDetector notices wall-clock gaps between ticks that are far longer
+// than the refresh interval, which is what a laptop waking from sleep looks
+// like.
+type SleepDetector struct {
+	// Now returns the current time; it defaults to time.Now.
+	Now func() time.Time
+	// Threshold is the gap beyond which a tick counts as a wake-up.
+	Threshold time.Duration
+
+	last time.Time
+}
+
+// NewSleepDetector returns a detector for ticks every interval.
+func NewSleepDetector(interval time.Duration) *SleepDetector {
+	return &SleepDetector{Now: time.Now, Threshold: max(3*interval, minSleepGap)}
+}
+
+// Tick records a tick and reports the gap since the previous one when it
+// exceeds Threshold. The first tick never reports a gap.
+func (d *SleepDetector) Tick() (time.Duration, bool) {
+	now := time.Now
+	if d.Now != nil {
+		now = d.Now
+	}
+	// Strip the monotonic reading: on macOS it stops while asleep, so only
+	// the wall clock reveals the gap.
+	t := now().Round(0)
+	prev := d.last
+	d.last = t
+	if prev.IsZero() {
+		return 0, false
+	}
+	gap := t.Sub(prev)
+	return gap, gap > d.Threshold
+}
@@ -0,0 +1,33 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleepDetector(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewSleepDetector(2 * time.Second)
+	d.Now = func() time.Time { return clock }
+
+	if _, slept := d.Tick(); slept {
+		t.Fatal("Expected the first tick not to report sleep")
+	}
+	clock = clock.Add(2 * time.Second)
+	if _, slept := d.Tick(); slept {
+		t.Error("Expected a regular tick not to report sleep")
+	}
+	clock = clock.Add(20 * time.Second)
+	if _, slept := d.Tick(); slept {
+		t.Error("Expected a slow run below the minimum gap not to report sleep")
+	}
+	clock = clock.Add(45 * time.Minute)
+	gap, slept := d.Tick()
+	if !slept || gap != 45*time.Minute {
+		t.Errorf("Expected a 45m sleep, got %v, %v", gap, slept)
+	}
+	clock = clock.Add(2 * time.Second)
+	if _, slept := d.Tick(); slept {
+		t.Error("Expected ticks after waking to be regular again")
+	}
+}
@@ -0,0 +1,105 @@
+// Package webhook posts watch-mode alerts to HTTP endpoints: a generic
+// webhook receiving the alert as JSON, or a Slack incoming webhook
+// receiving it as a message.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Event is a check degrading to a warning or error, or recovering.
+type Event struct {
+	// Check is the registered name, e.g. "dns", and Name the display name.
+	Check   string `json:"check"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	// Recovered is true when the check is OK again after an alert.
+	Recovered bool `json:"recovered"`
+	// Since is when the check stopped being OK.
+	Since time.Time `json:"since"`
+	At    time.Time `json:"at"`
+	// Host is the machine that ran the check.
+	Host string `json:"host,omitempty"`
+}
+
+// Text is the one-line form of e for chat messages.
+func (e Event) Text() string {
+	prefix := "wtfi"
+	if e.Host != "" {
+		prefix += " on " + e.Host
+	}
+	if e.Recovered {
+		return fmt.Sprintf("%s: %s recovered after %s", prefix, e.Name, e.At.Sub(e.Since).Round(time.Second))
+	}
+	verb := "degraded"
+	if e.Status == "error" {
+		verb = "failed"
+	}
+	text := fmt.Sprintf("%s: %s %s", prefix, e.Name, verb)
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	return text
+}
+
+// Sink is one endpoint alerts are posted to.
+type Sink struct {
+	URL string
+	// Slack posts {"text": ...} as Slack incoming webhooks expect, instead
+	// of the Event itself.
+	Slack  bool
+	Client *http.Client
+}
+
+// Send posts e to the endpoint.
+func (s Sink) Send(ctx context.Context, e Event) error {
+	var payload any = e
+	if s.Slack {
+		payload = struct {
+			Text string `json:"text"`
+		}{e.Text()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL of a Slack webhook is its secret, so keep it out of logs.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("%s: %w", req.URL.Host, err)
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			slog.Debug("could not close response body", "err", errClose)
+		}
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEventText(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		e    Event
+		want string
+	}{
+		{Event{Name: "DNS Benchmark", Status: "error", Message: "No resolver answered", Host: "mbp"}, "wtfi on mbp: DNS Benchmark failed: No resolver answered"},
+		{Event{Name: "Wi-Fi", Status: "warning"}, "wtfi: Wi-Fi degraded"},
+		{Event{Name: "WAN", Status: "ok", Recovered: true, Since: t0, At: t0.Add(192 * time.Second)}, "wtfi: WAN recovered after 3m12s"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Text(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestSinkSend(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	e := Event{Check: "dns", Name: "DNS Benchmark", Status: "error", Message: "No resolver answered"}
+
+	if err := (Sink{URL: srv.URL}).Send(context.Background(), e); err != nil {
+		t.Fatalf("Expected the webhook to accept the event, got %v", err)
+	}
+	if got["check"] != "dns" || got["status"] != "error" {
+		t.Errorf("Expected the event as JSON, got %v", got)
+	}
+
+	if err := (Sink{URL: srv.URL, Slack: true}).Send(context.Background(), e); err != nil {
+		t.Fatalf("Expected Slack to accept the message, got %v", err)
+	}
+	if got["text"] != e.Text() || got["check"] != nil {
+		t.Errorf("Expected a Slack message, got %v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer failing.Close()
+	if err := (Sink{URL: failing.URL}).Send(context.Background(), e); err == nil {
+		t.Error("Expected a rejected post to fail")
+	}
+}
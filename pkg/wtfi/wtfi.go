@@ -0,0 +1,217 @@
+// Package wtfi runs wtfi's network diagnostics from Go programs, such as menu
+// bar apps or fleet agents, without shelling out to the CLI.
+//
+//	results, err := wtfi.Run(ctx, wtfi.Config{Checks: []string{"gateway", "dns"}})
+//
+// Concurrent calls to Run may use different Configs. Checks of your own
+// are added with Register and run alongside the built-in ones.
+package wtfi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+// Status grades a Result.
+type Status int
+
+// Statuses, from best to worst; StatusSkipped means the check could not run.
+const (
+	StatusOK      = Status(diagnostic.StatusOk)
+	StatusWarning = Status(diagnostic.StatusWarning)
+	StatusError   = Status(diagnostic.StatusError)
+	StatusSkipped = Status(diagnostic.StatusSkipped)
+)
+
+// String returns the lowercase name of the status, as used in wtfi's JSON.
+func (s Status) String() string {
+	return diagnostic.Status(s).String()
+}
+
+// Config selects and tunes the checks. The zero value runs the default set
+// with wtfi's default settings.
+type Config struct {
+	// Checks lists the check names to run, in any order; empty means every
+	// check that is not opt-in (see CheckInfo.OptIn).
+	Checks []string
+	// Skip lists check names to leave out.
+	Skip []string
+	// Verbose fills Result.Details with protocol details.
+	Verbose bool
+	// Timeout bounds individual network operations; zero keeps the default.
+	Timeout time.Duration
+	// TLSHost is the host of the TLS handshake check; empty keeps the default.
+	TLSHost string
+	// Workers is how many checks run at once; below 2 they run in order.
+	Workers int
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	// Check is the name the check was selected by, e.g. "gateway".
+	Check string
+	// Name is the display title, which may include the SSID or an address.
+	Name    string
+	Status  Status
+	Message string
+	// Reason is a stable code explaining a non-OK status, e.g. "weak_signal".
+	Reason string
+	Fix    string
+	// FixCommand is a shell command implementing Fix, when a safe one exists.
+	FixCommand string
+	Latency    time.Duration
+	Details    []string
+	// Facts holds raw observations such as "rssi_dbm" or "gateway_mac".
+	Facts map[string]string
+	// Percentiles is set when a latency sampling window was measured.
+	Percentiles *LatencyPercentiles
+}
+
+// LatencyPercentiles summarize a window of latency samples.
+type LatencyPercentiles struct {
+	Samples int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// CheckInfo describes an available check.
+type CheckInfo struct {
+	Name string
+	// Explain says what the check measures and how it grades the outcome.
+	Explain string
+	// OptIn checks, such as the throughput test, only run when named in
+	// Config.Checks.
+	OptIn bool
+}
+
+// Options are passed to a Checker's Run.
+type Options struct {
+	// Verbose requests protocol details in Result.Details.
+	Verbose bool
+}
+
+// Checker is a check of your own, added with Register.
+type Checker interface {
+	// Name is the stable identifier Config.Checks and Config.Skip select
+	// the check by.
+	Name() string
+	// Run performs the check within ctx. Result.Check is filled in by Run.
+	Run(ctx context.Context, opts Options) Result
+}
+
+// Register adds ch after the built-in checks, described by explain, so
+// Checks lists it and Run runs it. It fails when the name is empty or
+// already taken.
+func Register(ch Checker, explain string) error {
+	return diagnostic.Register(checker{ch}, explain)
+}
+
+// checker adapts a Checker to the diagnostic package.
+type checker struct {
+	ch Checker
+}
+
+func (c checker) Name() string { return c.ch.Name() }
+
+func (c checker) Run(ctx context.Context, opts diagnostic.Options) diagnostic.Result {
+	return toDiagnostic(c.ch.Run(ctx, Options{Verbose: opts.Verbose}))
+}
+
+// Checks lists every available check in display order.
+func Checks() []CheckInfo {
+	var infos []CheckInfo
+	for _, c := range diagnostic.Checks() {
+		infos = append(infos, CheckInfo{Name: c.Name, Explain: c.Explain, OptIn: c.OptIn})
+	}
+	return infos
+}
+
+// Run executes the checks selected by cfg and returns their results in
+// display order. It fails only when cfg names an unknown check. Cancelling
+// ctx stops the probes in flight; checks that had not started are reported
+// as skipped.
+func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	checks, err := diagnostic.SelectChecks(cfg.Checks, cfg.Skip, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dc := diagnostic.DefaultConfig()
+	if cfg.Timeout > 0 {
+		dc.Timeout = cfg.Timeout
+	}
+	if cfg.TLSHost != "" {
+		dc.TLSHost = cfg.TLSHost
+	}
+	ctx = diagnostic.WithConfig(ctx, dc)
+	diagnostic.ResetCache()
+
+	opts := diagnostic.Options{Verbose: cfg.Verbose}
+	results := make([]Result, len(checks))
+	slots := make(chan struct{}, max(cfg.Workers, 1))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		// The throughput test saturates the link, so it never overlaps others.
+		if c.OptIn {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = newResult(c.Name, c.Checker().Run(ctx, opts))
+		}()
+	}
+	wg.Wait()
+	for i, c := range checks {
+		if c.OptIn {
+			results[i] = newResult(c.Name, c.Checker().Run(ctx, opts))
+		}
+	}
+	return results, nil
+}
+
+func newResult(check string, r diagnostic.Result) Result {
+	res := Result{
+		Check:      check,
+		Name:       r.Name,
+		Status:     Status(r.Status),
+		Message:    r.Message,
+		Reason:     string(r.Reason),
+		Fix:        r.Fix,
+		FixCommand: r.FixCommand,
+		Latency:    r.Latency,
+		Details:    r.Details,
+		Facts:      r.Facts,
+	}
+	if p := r.Percentiles; p != nil {
+		res.Percentiles = &LatencyPercentiles{Samples: p.Samples, P50: p.P50, P90: p.P90, P99: p.P99, Max: p.Max}
+	}
+	return res
+}
+
+// toDiagnostic is the inverse of newResult, for results of a Checker.
+func toDiagnostic(r Result) diagnostic.Result {
+	res := diagnostic.Result{
+		Check:      r.Check,
+		Name:       r.Name,
+		Status:     diagnostic.Status(r.Status),
+		Message:    r.Message,
+		Reason:     diagnostic.Reason(r.Reason),
+		Fix:        r.Fix,
+		FixCommand: r.FixCommand,
+		Latency:    r.Latency,
+		Details:    r.Details,
+		Facts:      r.Facts,
+	}
+	if p := r.Percentiles; p != nil {
+		res.Percentiles = &diagnostic.LatencyPercentiles{Samples: p.Samples, P50: p.P50, P90: p.P90, P99: p.P99, Max: p.Max}
+	}
+	return res
+}
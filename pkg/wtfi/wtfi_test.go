@@ -0,0 +1,77 @@
+package wtfi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kanywst/wtfi/internal/diagnostic"
+)
+
+func TestRunUnknownCheck(t *testing.T) {
+	if _, err := Run(context.Background(), Config{Checks: []string{"wifi", "nope"}}); err == nil {
+		t.Error("Expected an unknown check to be rejected")
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := Run(ctx, Config{Checks: []string{"dns", "tls"}, Workers: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 2 || results[0].Check != "dns" || results[1].Check != "tls" {
+		t.Fatalf("Expected dns and tls results in order, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Status != StatusSkipped {
+			t.Errorf("%s: expected skipped after cancellation, got %v", r.Check, r.Status)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	r := newResult("gateway", diagnostic.Result{
+		Name:        "Gateway",
+		Status:      diagnostic.StatusWarning,
+		Reason:      diagnostic.ReasonHighLatency,
+		Latency:     40 * time.Millisecond,
+		Facts:       map[string]string{"gateway_ip": "192.168.1.1"},
+		Percentiles: &diagnostic.LatencyPercentiles{Samples: 10, P50: 5 * time.Millisecond},
+	})
+	if r.Check != "gateway" || r.Status != StatusWarning || r.Status.String() != "warning" {
+		t.Errorf("Expected a gateway warning, got %+v", r)
+	}
+	if r.Reason != string(diagnostic.ReasonHighLatency) || r.Facts["gateway_ip"] != "192.168.1.1" {
+		t.Errorf("Expected reason and facts to carry over, got %+v", r)
+	}
+	if r.Percentiles == nil || r.Percentiles.Samples != 10 || r.Percentiles.P50 != 5*time.Millisecond {
+		t.Errorf("Expected percentiles to carry over, got %+v", r.Percentiles)
+	}
+}
+
+type printerCheck struct{}
+
+func (printerCheck) Name() string { return "printer" }
+
+func (printerCheck) Run(ctx context.Context, opts Options) Result {
+	return Result{Name: "Printer", Status: StatusWarning, Message: "Out of paper", Reason: "no_paper"}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(printerCheck{}, "Asks the office printer how it is."); err != nil {
+		t.Fatalf("Expected the check to register, got %v", err)
+	}
+	if err := Register(printerCheck{}, ""); err == nil {
+		t.Error("Expected a second check named printer to be rejected")
+	}
+	results, err := Run(context.Background(), Config{Checks: []string{"printer"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(results) != 1 || results[0].Check != "printer" || results[0].Status != StatusWarning || results[0].Reason != "no_paper" {
+		t.Errorf("Expected the printer's warning, got %+v", results)
+	}
+}